@@ -1,3 +1,5 @@
 module github.com/biosbuddha/golemjs
 
 go 1.24.1
+
+require golang.org/x/text v0.25.0
@@ -1,5 +1,10 @@
 package ast
 
+import (
+	"strconv"
+	"strings"
+)
+
 // Node represents a node in the Abstract Syntax Tree (AST).
 // The AST is a tree representation of the source code where each node represents
 // a construct occurring in the source code. This is the foundation of how JavaScript
@@ -31,6 +36,10 @@ type Expression interface {
 // Think of it as the top-level container for all code in a JavaScript file.
 type Program struct {
 	Statements []Statement
+
+	// Module is set for a program parsed as an ES module, whose top level
+	// may use await.
+	Module bool
 }
 
 func (p *Program) TokenLiteral() string {
@@ -70,7 +79,12 @@ type Literal struct {
 
 func (l *Literal) expressionNode()      {}
 func (l *Literal) TokenLiteral() string { return l.Token.Literal }
-func (l *Literal) String() string       { return l.Token.Literal }
+func (l *Literal) String() string {
+	if s, ok := l.Value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return l.Token.Literal
+}
 
 // BinaryExpression represents binary operations like addition, subtraction, etc.
 // Binary expressions have a left side, an operator, and a right side.
@@ -91,9 +105,11 @@ func (b *BinaryExpression) String() string {
 // VariableDeclaration represents variable declarations using var, let, or const.
 // This node captures how variables are declared in JavaScript, including their name
 // and optional initial value. The declaration type (var/let/const) is stored in the token.
+// Name is usually an Identifier, but a destructuring declaration such as
+// const { a, b } = obj has an ObjectPattern or ArrayPattern instead.
 type VariableDeclaration struct {
 	Token Token
-	Name  *Identifier
+	Name  Expression
 	Value Expression
 }
 
@@ -114,16 +130,29 @@ func (v *VariableDeclaration) String() string {
 // This node captures the function's name, parameters, and body.
 type FunctionDeclaration struct {
 	Token      Token
-	Name       *Identifier
-	Parameters []*Identifier
+	Name       *Identifier  // nil only in export default function () {}
+	Parameters []Expression // see FunctionLiteral
 	Body       *BlockStatement
+	Generator  bool // declared with function*
+	Async      bool // declared with async function
 }
 
 func (f *FunctionDeclaration) statementNode()       {}
 func (f *FunctionDeclaration) TokenLiteral() string { return f.Token.Literal }
 func (f *FunctionDeclaration) String() string {
+	keyword := "function "
+	if f.Generator {
+		keyword = "function* "
+	}
+	if f.Async {
+		keyword = "async " + keyword
+	}
 	var out string
-	out += "function " + f.Name.String() + "("
+	if f.Name != nil {
+		out += keyword + f.Name.String() + "("
+	} else {
+		out += strings.TrimSuffix(keyword, " ") + "("
+	}
 
 	for i, p := range f.Parameters {
 		if i > 0 {
@@ -232,6 +261,614 @@ func (r *ReturnStatement) String() string {
 	return "return;"
 }
 
+// ExpressionStatement wraps an expression that is used as a statement.
+// JavaScript allows any expression to stand on its own, like a function call
+// "save();" or an assignment "x = 5;". The value is computed and discarded.
+type ExpressionStatement struct {
+	Token      Token // the first token of the expression
+	Expression Expression
+}
+
+func (e *ExpressionStatement) statementNode()       {}
+func (e *ExpressionStatement) TokenLiteral() string { return e.Token.Literal }
+func (e *ExpressionStatement) String() string {
+	if e.Expression != nil {
+		return e.Expression.String()
+	}
+	return ""
+}
+
+// UnaryExpression represents an operator applied to a single operand,
+// like -x or !done. The operator always comes before the operand.
+type UnaryExpression struct {
+	Token    Token // the operator token
+	Operator string
+	Argument Expression
+}
+
+func (u *UnaryExpression) expressionNode()      {}
+func (u *UnaryExpression) TokenLiteral() string { return u.Token.Literal }
+func (u *UnaryExpression) String() string {
+	if u.Operator == "typeof" || u.Operator == "void" || u.Operator == "delete" {
+		// Word operators need a space before their operand.
+		return "(" + u.Operator + " " + u.Argument.String() + ")"
+	}
+	return "(" + u.Operator + u.Argument.String() + ")"
+}
+
+// UpdateExpression represents the increment and decrement operators ++ and --.
+// Prefix tells whether the operator came before the operand (++i), which
+// yields the new value, or after it (i++), which yields the old value.
+type UpdateExpression struct {
+	Token    Token
+	Operator string
+	Prefix   bool
+	Argument Expression
+}
+
+func (u *UpdateExpression) expressionNode()      {}
+func (u *UpdateExpression) TokenLiteral() string { return u.Token.Literal }
+func (u *UpdateExpression) String() string {
+	if u.Prefix {
+		return "(" + u.Operator + u.Argument.String() + ")"
+	}
+	return "(" + u.Argument.String() + u.Operator + ")"
+}
+
+// AssignmentExpression represents assignments like x = 5 or arr[0] += 1.
+// The left side must be something that can hold a value: a variable or a
+// property of an object.
+type AssignmentExpression struct {
+	Token    Token // the assignment operator token
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+func (a *AssignmentExpression) expressionNode()      {}
+func (a *AssignmentExpression) TokenLiteral() string { return a.Token.Literal }
+func (a *AssignmentExpression) String() string {
+	return a.Left.String() + " " + a.Operator + " " + a.Right.String()
+}
+
+// ConditionalExpression represents the ternary operator: test ? a : b.
+// Only one of the two branches is evaluated.
+type ConditionalExpression struct {
+	Token      Token // the '?' token
+	Test       Expression
+	Consequent Expression
+	Alternate  Expression
+}
+
+func (c *ConditionalExpression) expressionNode()      {}
+func (c *ConditionalExpression) TokenLiteral() string { return c.Token.Literal }
+func (c *ConditionalExpression) String() string {
+	return "(" + c.Test.String() + " ? " + c.Consequent.String() + " : " + c.Alternate.String() + ")"
+}
+
+// ArrayLiteral represents an array written directly in the code, like [1, 2, 3].
+// A nil element is a hole: [1, , 3] has three elements but only two of them
+// exist. Elements may also be SpreadElements, as in [...a, ...b].
+type ArrayLiteral struct {
+	Token    Token // the '[' token
+	Elements []Expression
+}
+
+func (a *ArrayLiteral) expressionNode()      {}
+func (a *ArrayLiteral) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		if e == nil {
+			elements = append(elements, "")
+			continue
+		}
+		elements = append(elements, e.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// SpreadElement represents ...expr inside an array literal or an argument list.
+// The values of expr are expanded in place.
+type SpreadElement struct {
+	Token    Token // the '...' token
+	Argument Expression
+}
+
+func (s *SpreadElement) expressionNode()      {}
+func (s *SpreadElement) TokenLiteral() string { return s.Token.Literal }
+func (s *SpreadElement) String() string       { return "..." + s.Argument.String() }
+
+// Property represents one key: value entry of an object literal.
+// Computed keys are written in brackets, like { [name]: value }, and are
+// evaluated; other keys are taken literally. A spread entry, { ...obj },
+// has no key and a SpreadElement as its value.
+//
+// Properties also make up ObjectPatterns, where Value is the target the
+// property's value is stored in.
+type Property struct {
+	Key      Expression
+	Value    Expression
+	Computed bool
+}
+
+func (p *Property) String() string {
+	if p.Key == nil {
+		return p.Value.String()
+	}
+	if p.Computed {
+		return "[" + p.Key.String() + "]: " + p.Value.String()
+	}
+	return p.Key.String() + ": " + p.Value.String()
+}
+
+// ArrayPattern represents array destructuring, like the [a, , ...rest] in
+// const [a, , ...rest] = list. Each element is a target that receives the
+// next value of the iterable being destructured; a nil element skips a
+// value, and a final RestElement collects whatever values are left.
+type ArrayPattern struct {
+	Token    Token // the '[' token
+	Elements []Expression
+}
+
+func (a *ArrayPattern) expressionNode()      {}
+func (a *ArrayPattern) TokenLiteral() string { return a.Token.Literal }
+func (a *ArrayPattern) String() string {
+	elements := []string{}
+	for _, e := range a.Elements {
+		if e == nil {
+			elements = append(elements, "")
+			continue
+		}
+		elements = append(elements, e.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+// ObjectPattern represents object destructuring, like the { a, b: c } in
+// const { a, b: c } = obj. Each property's value is stored in the target
+// given by the Property's Value, and Rest, if present, receives an object
+// holding the properties no other entry named.
+type ObjectPattern struct {
+	Token      Token // the '{' token
+	Properties []*Property
+	Rest       Expression
+}
+
+func (o *ObjectPattern) expressionNode()      {}
+func (o *ObjectPattern) TokenLiteral() string { return o.Token.Literal }
+func (o *ObjectPattern) String() string {
+	props := []string{}
+	for _, p := range o.Properties {
+		props = append(props, p.String())
+	}
+	if o.Rest != nil {
+		props = append(props, "..."+o.Rest.String())
+	}
+	return "{" + strings.Join(props, ", ") + "}"
+}
+
+// AssignmentPattern represents a destructuring target with a default, like
+// the b = 2 in const [a, b = 2] = list or function f(b = 2). Right is only
+// evaluated when the value would otherwise be undefined.
+type AssignmentPattern struct {
+	Token Token // the '=' token
+	Left  Expression
+	Right Expression
+}
+
+func (a *AssignmentPattern) expressionNode()      {}
+func (a *AssignmentPattern) TokenLiteral() string { return a.Token.Literal }
+func (a *AssignmentPattern) String() string {
+	return a.Left.String() + " = " + a.Right.String()
+}
+
+// RestElement represents ...target at the end of an ArrayPattern or a
+// parameter list, which collects the remaining values into an array.
+type RestElement struct {
+	Token    Token // the '...' token
+	Argument Expression
+}
+
+func (r *RestElement) expressionNode()      {}
+func (r *RestElement) TokenLiteral() string { return r.Token.Literal }
+func (r *RestElement) String() string       { return "..." + r.Argument.String() }
+
+// ObjectLiteral represents an object written directly in the code,
+// like { name: "golem", size: 3 }.
+type ObjectLiteral struct {
+	Token      Token // the '{' token
+	Properties []*Property
+}
+
+func (o *ObjectLiteral) expressionNode()      {}
+func (o *ObjectLiteral) TokenLiteral() string { return o.Token.Literal }
+func (o *ObjectLiteral) String() string {
+	props := []string{}
+	for _, p := range o.Properties {
+		props = append(props, p.String())
+	}
+	return "{" + strings.Join(props, ", ") + "}"
+}
+
+// MemberExpression represents property access: obj.name or obj[expr].
+// Computed is true for the bracket form, where Property is evaluated to
+// find the key; otherwise Property is an Identifier naming the key.
+type MemberExpression struct {
+	Token    Token // the '.' or '[' token
+	Object   Expression
+	Property Expression
+	Computed bool
+}
+
+func (m *MemberExpression) expressionNode()      {}
+func (m *MemberExpression) TokenLiteral() string { return m.Token.Literal }
+func (m *MemberExpression) String() string {
+	if m.Computed {
+		return m.Object.String() + "[" + m.Property.String() + "]"
+	}
+	return m.Object.String() + "." + m.Property.String()
+}
+
+// FunctionLiteral represents a function used as a value, like
+// function (x) { return x; } or the arrow form x => x. Arrow functions don't
+// get their own "this"; they see the "this" of the code around them.
+type FunctionLiteral struct {
+	Token      Token        // the 'function' or '=>' token
+	Name       *Identifier  // optional
+	Parameters []Expression // Identifiers, patterns, AssignmentPatterns for defaults and a final RestElement
+	Body       *BlockStatement
+	Arrow      bool
+	Generator  bool // function* or a *method() in an object literal
+	Async      bool // async function, async arrow or async method
+}
+
+func (f *FunctionLiteral) expressionNode()      {}
+func (f *FunctionLiteral) TokenLiteral() string { return f.Token.Literal }
+func (f *FunctionLiteral) String() string {
+	params := []string{}
+	for _, p := range f.Parameters {
+		params = append(params, p.String())
+	}
+	async := ""
+	if f.Async {
+		async = "async "
+	}
+	if f.Arrow {
+		return async + "(" + strings.Join(params, ", ") + ") => " + f.Body.String()
+	}
+	keyword := "function"
+	if f.Generator {
+		keyword = "function*"
+	}
+	keyword = async + keyword
+	name := ""
+	if f.Name != nil {
+		name = " " + f.Name.String()
+	}
+	return keyword + name + "(" + strings.Join(params, ", ") + ") " + f.Body.String()
+}
+
+// NewExpression represents object construction with the new operator,
+// like new Array(3). The callee is called as a constructor.
+type NewExpression struct {
+	Token     Token // the 'new' token
+	Callee    Expression
+	Arguments []Expression
+}
+
+func (n *NewExpression) expressionNode()      {}
+func (n *NewExpression) TokenLiteral() string { return n.Token.Literal }
+func (n *NewExpression) String() string {
+	args := []string{}
+	for _, a := range n.Arguments {
+		args = append(args, a.String())
+	}
+	return "new " + n.Callee.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// ThisExpression represents the "this" keyword, which refers to the object
+// a function was called on.
+type ThisExpression struct {
+	Token Token
+}
+
+func (t *ThisExpression) expressionNode()      {}
+func (t *ThisExpression) TokenLiteral() string { return t.Token.Literal }
+func (t *ThisExpression) String() string       { return "this" }
+
+// RegExpLiteral represents a regular expression literal such as /ab+c/gi.
+// Pattern is the text between the slashes, exactly as written, and Flags
+// the letters after the closing one. Each time the literal is evaluated it
+// creates a new RegExp object.
+type RegExpLiteral struct {
+	Token   Token
+	Pattern string
+	Flags   string
+}
+
+func (r *RegExpLiteral) expressionNode()      {}
+func (r *RegExpLiteral) TokenLiteral() string { return r.Token.Literal }
+func (r *RegExpLiteral) String() string       { return "/" + r.Pattern + "/" + r.Flags }
+
+// ForStatement represents the classic three-part for loop:
+// for (init; condition; update) body. Any of the three parts may be nil.
+type ForStatement struct {
+	Token     Token
+	Init      Statement
+	Condition Expression
+	Update    Expression
+	Body      *BlockStatement
+}
+
+func (f *ForStatement) statementNode()       {}
+func (f *ForStatement) TokenLiteral() string { return f.Token.Literal }
+func (f *ForStatement) String() string {
+	var out string
+	out += "for ("
+	if f.Init != nil {
+		out += f.Init.String()
+	} else {
+		out += ";"
+	}
+	if f.Condition != nil {
+		out += " " + f.Condition.String()
+	}
+	out += ";"
+	if f.Update != nil {
+		out += " " + f.Update.String()
+	}
+	return out + ") " + f.Body.String()
+}
+
+// ForOfStatement represents for (left of right) body, which runs the body
+// once for every value produced by iterating over right. Left is either a
+// VariableDeclaration without a value, declaring the loop variable, or an
+// expression that each value is assigned to. With Await set, the loop is
+// for await (left of right), which iterates asynchronously and waits for
+// each value.
+type ForOfStatement struct {
+	Token Token
+	Left  Node
+	Right Expression
+	Body  *BlockStatement
+	Await bool
+}
+
+func (f *ForOfStatement) statementNode()       {}
+func (f *ForOfStatement) TokenLiteral() string { return f.Token.Literal }
+func (f *ForOfStatement) String() string {
+	left := f.Left.String()
+	if decl, ok := f.Left.(*VariableDeclaration); ok {
+		left = decl.TokenLiteral() + " " + decl.Name.String()
+	}
+	keyword := "for ("
+	if f.Await {
+		keyword = "for await ("
+	}
+	return keyword + left + " of " + f.Right.String() + ") " + f.Body.String()
+}
+
+// YieldExpression represents yield inside a generator function. It pauses
+// the generator, handing Argument to whoever asked for the next value.
+// With Delegate set (yield*), every value of the iterable Argument is
+// handed on in turn.
+type YieldExpression struct {
+	Token    Token // the 'yield' token
+	Argument Expression
+	Delegate bool
+}
+
+func (y *YieldExpression) expressionNode()      {}
+func (y *YieldExpression) TokenLiteral() string { return y.Token.Literal }
+func (y *YieldExpression) String() string {
+	out := "yield"
+	if y.Delegate {
+		out += "*"
+	}
+	if y.Argument != nil {
+		out += " " + y.Argument.String()
+	}
+	return out
+}
+
+// AwaitExpression represents await inside an async function. It pauses
+// the function until the promise Argument settles, and evaluates to the
+// value the promise is fulfilled with.
+type AwaitExpression struct {
+	Token    Token // the 'await' token
+	Argument Expression
+}
+
+func (a *AwaitExpression) expressionNode()      {}
+func (a *AwaitExpression) TokenLiteral() string { return a.Token.Literal }
+func (a *AwaitExpression) String() string       { return "await " + a.Argument.String() }
+
+// ImportDeclaration represents an import at the top level of a module,
+// which binds names to the exports of another module:
+//
+//	import theDefault, { a, b as c } from "./lib.js";
+//	import * as lib from "./lib.js";
+//	import "./setup.js";
+//
+// Default and Namespace are nil unless the import has them. Source is the
+// module specifier, the string naming the module to import from.
+type ImportDeclaration struct {
+	Token      Token // the 'import' token
+	Default    *Identifier
+	Namespace  *Identifier
+	Specifiers []*ImportSpecifier
+	Source     string
+}
+
+func (i *ImportDeclaration) statementNode()       {}
+func (i *ImportDeclaration) TokenLiteral() string { return i.Token.Literal }
+func (i *ImportDeclaration) String() string {
+	var bindings []string
+	if i.Default != nil {
+		bindings = append(bindings, i.Default.String())
+	}
+	if i.Namespace != nil {
+		bindings = append(bindings, "* as "+i.Namespace.String())
+	}
+	if i.Specifiers != nil {
+		names := []string{}
+		for _, s := range i.Specifiers {
+			names = append(names, s.String())
+		}
+		bindings = append(bindings, "{"+strings.Join(names, ", ")+"}")
+	}
+	if bindings == nil {
+		return "import " + strconv.Quote(i.Source) + ";"
+	}
+	return "import " + strings.Join(bindings, ", ") + " from " + strconv.Quote(i.Source) + ";"
+}
+
+// ImportSpecifier is one of the names in the braces of an import: the
+// export Imported of the other module is bound to the variable Local.
+type ImportSpecifier struct {
+	Imported string
+	Local    *Identifier
+}
+
+func (s *ImportSpecifier) String() string {
+	if s.Imported == s.Local.Value {
+		return s.Imported
+	}
+	return exportName(s.Imported) + " as " + s.Local.String()
+}
+
+// ExportNamedDeclaration represents an export of names: either a
+// declaration, whose names are exported as they are, or a list of names,
+// which may be renamed and may come from another module:
+//
+//	export const pi = 3.14;
+//	export function area(r) { return pi * r * r; }
+//	export { pi as PI, area };
+//	export { sin, cos as cosine } from "./trig.js";
+//
+// Declaration is nil for a list of names. Source is empty unless the
+// names are exported from another module.
+type ExportNamedDeclaration struct {
+	Token       Token     // the 'export' token
+	Declaration Statement // a VariableDeclaration or FunctionDeclaration
+	Specifiers  []*ExportSpecifier
+	Source      string
+}
+
+func (e *ExportNamedDeclaration) statementNode()       {}
+func (e *ExportNamedDeclaration) TokenLiteral() string { return e.Token.Literal }
+func (e *ExportNamedDeclaration) String() string {
+	if e.Declaration != nil {
+		return "export " + e.Declaration.String()
+	}
+	names := []string{}
+	for _, s := range e.Specifiers {
+		names = append(names, s.String())
+	}
+	out := "export {" + strings.Join(names, ", ") + "}"
+	if e.Source != "" {
+		out += " from " + strconv.Quote(e.Source)
+	}
+	return out + ";"
+}
+
+// ExportSpecifier is one of the names in the braces of an export: the
+// variable (or, with a Source, the export of the other module) Local is
+// exported as Exported.
+type ExportSpecifier struct {
+	Local    string
+	Exported string
+}
+
+func (s *ExportSpecifier) String() string {
+	if s.Local == s.Exported {
+		return exportName(s.Local)
+	}
+	return exportName(s.Local) + " as " + exportName(s.Exported)
+}
+
+// exportName writes the name of an export, quoting it if it isn't an
+// identifier, as in export { x as "a-b" }.
+func exportName(name string) string {
+	for idx, ch := range name {
+		if !(ch == '_' || ch == '$' || 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || idx > 0 && '0' <= ch && ch <= '9') {
+			return strconv.Quote(name)
+		}
+	}
+	if name == "" {
+		return `""`
+	}
+	return name
+}
+
+// ExportDefaultDeclaration represents export default, which exports a
+// value under the name "default". Declaration is either a
+// FunctionDeclaration, whose Name is nil for an anonymous function, or an
+// expression whose value is exported.
+type ExportDefaultDeclaration struct {
+	Token       Token // the 'export' token
+	Declaration Node
+}
+
+func (e *ExportDefaultDeclaration) statementNode()       {}
+func (e *ExportDefaultDeclaration) TokenLiteral() string { return e.Token.Literal }
+func (e *ExportDefaultDeclaration) String() string {
+	if _, ok := e.Declaration.(*FunctionDeclaration); ok {
+		return "export default " + e.Declaration.String()
+	}
+	return "export default " + e.Declaration.String() + ";"
+}
+
+// ExportAllDeclaration represents export * from "./m.js", which exports
+// every name module m exports except its default, or, with Exported set,
+// export * as name from "./m.js", which exports m's namespace object.
+type ExportAllDeclaration struct {
+	Token    Token // the 'export' token
+	Exported string
+	Source   string
+}
+
+func (e *ExportAllDeclaration) statementNode()       {}
+func (e *ExportAllDeclaration) TokenLiteral() string { return e.Token.Literal }
+func (e *ExportAllDeclaration) String() string {
+	out := "export *"
+	if e.Exported != "" {
+		out += " as " + exportName(e.Exported)
+	}
+	return out + " from " + strconv.Quote(e.Source) + ";"
+}
+
+// ImportCall represents import(specifier), which loads a module while the
+// program runs and evaluates to a promise for its namespace object.
+type ImportCall struct {
+	Token  Token // the 'import' token
+	Source Expression
+}
+
+func (i *ImportCall) expressionNode()      {}
+func (i *ImportCall) TokenLiteral() string { return i.Token.Literal }
+func (i *ImportCall) String() string       { return "import(" + i.Source.String() + ")" }
+
+// BreakStatement represents "break", which leaves the innermost loop.
+type BreakStatement struct {
+	Token Token
+}
+
+func (b *BreakStatement) statementNode()       {}
+func (b *BreakStatement) TokenLiteral() string { return b.Token.Literal }
+func (b *BreakStatement) String() string       { return "break;" }
+
+// ContinueStatement represents "continue", which skips to the next
+// iteration of the innermost loop.
+type ContinueStatement struct {
+	Token Token
+}
+
+func (c *ContinueStatement) statementNode()       {}
+func (c *ContinueStatement) TokenLiteral() string { return c.Token.Literal }
+func (c *ContinueStatement) String() string       { return "continue;" }
+
 // Helper functions for type checking
 func IsExpression(node Node) bool {
 	_, ok := node.(Expression)
@@ -268,6 +905,60 @@ func GetNodeType(node Node) string {
 		return "WhileStatement"
 	case *ReturnStatement:
 		return "ReturnStatement"
+	case *ExpressionStatement:
+		return "ExpressionStatement"
+	case *UnaryExpression:
+		return "UnaryExpression"
+	case *UpdateExpression:
+		return "UpdateExpression"
+	case *AssignmentExpression:
+		return "AssignmentExpression"
+	case *ConditionalExpression:
+		return "ConditionalExpression"
+	case *ArrayLiteral:
+		return "ArrayLiteral"
+	case *SpreadElement:
+		return "SpreadElement"
+	case *ObjectLiteral:
+		return "ObjectLiteral"
+	case *ArrayPattern:
+		return "ArrayPattern"
+	case *ObjectPattern:
+		return "ObjectPattern"
+	case *AssignmentPattern:
+		return "AssignmentPattern"
+	case *RestElement:
+		return "RestElement"
+	case *MemberExpression:
+		return "MemberExpression"
+	case *FunctionLiteral:
+		return "FunctionLiteral"
+	case *NewExpression:
+		return "NewExpression"
+	case *ThisExpression:
+		return "ThisExpression"
+	case *ForStatement:
+		return "ForStatement"
+	case *ForOfStatement:
+		return "ForOfStatement"
+	case *YieldExpression:
+		return "YieldExpression"
+	case *AwaitExpression:
+		return "AwaitExpression"
+	case *ImportDeclaration:
+		return "ImportDeclaration"
+	case *ExportNamedDeclaration:
+		return "ExportNamedDeclaration"
+	case *ExportDefaultDeclaration:
+		return "ExportDefaultDeclaration"
+	case *ExportAllDeclaration:
+		return "ExportAllDeclaration"
+	case *ImportCall:
+		return "ImportCall"
+	case *BreakStatement:
+		return "BreakStatement"
+	case *ContinueStatement:
+		return "ContinueStatement"
 	default:
 		return "Unknown"
 	}
@@ -0,0 +1,358 @@
+// Package bytecode defines the instructions the compiler produces and the
+// virtual machine runs.
+//
+// An instruction is a one-byte opcode followed by its operands, each of
+// which is one or two bytes wide, in big-endian order:
+//
+//	OpConstant 3    03 00 03
+//	OpGetLocal 1    0e 00 01
+//	OpAdd           14
+//
+// Operands are mostly indexes: into the function's constant pool, its
+// local variable slots, or the cells it closes over. Jump operands are
+// offsets from the start of the function's instructions.
+package bytecode
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strings"
+)
+
+// Instructions is the code of one function: a sequence of encoded
+// instructions.
+type Instructions []byte
+
+// Opcode identifies an instruction.
+type Opcode byte
+
+const (
+	// Values
+	OpConstant Opcode = iota // push constant [index]
+	OpUndefined
+	OpNull
+	OpTrue
+	OpFalse
+	OpHole // push the missing element of an array literal like [1, , 3]
+
+	// Stack manipulation
+	OpPop
+	OpDup
+	OpDup2 // duplicate the top two values, as a b -> a b a b
+
+	// Operators. The ones with an opcode of their own are those worth a
+	// fast path for numbers; the rest go through OpBinary, whose operand
+	// is the constant holding the operator.
+	OpAdd
+	OpSub
+	OpMul
+	OpDiv
+	OpMod
+	OpLess
+	OpGreater
+	OpLessEqual
+	OpGreaterEqual
+	OpStrictEqual
+	OpStrictNotEqual
+	OpBinary // [operator constant]
+	OpNot
+	OpNegate
+	OpPlus
+	OpTypeof
+	OpVoid
+	OpUpdate         // [flags] ++ or --: current -> result, updated value
+	OpUpdateProperty // [flags] ++ or -- on a property: obj key -> result
+
+	// Jumps
+	OpJump            // [target]
+	OpJumpIfFalse     // [target] pop the condition, and jump if it's falsy
+	OpJumpIfFalseKeep // [target] jump if falsy, keeping the value; pop it otherwise
+	OpJumpIfTrueKeep  // [target] jump if truthy, keeping the value; pop it otherwise
+
+	// Variables. Globals are looked up by name, the constant the operand
+	// refers to; locals live in the function's slots, and captured ones
+	// in cells, which closures share.
+	OpGetGlobal        // [name]
+	OpSetGlobal        // [name] assign, keeping the value
+	OpDefineGlobal     // [name] [kind] declare with var, let or const, popping the value
+	OpDeclareGlobalVar // [name] var without a value
+	OpTypeofGlobal     // [name] typeof of a global that may not exist
+	OpDeleteGlobal     // [name] delete of a global: true only if it doesn't exist
+	OpGetLocal         // [slot]
+	OpSetLocal         // [slot] keeping the value
+	OpInitLocal        // [slot] var without a value: undefined unless already set
+	OpGetCell          // [slot] the value in the cell in a slot
+	OpSetCell          // [slot]
+	OpInitCell         // [slot]
+	OpGetFree          // [index] the value in a cell the function closed over
+	OpSetFree          // [index]
+	OpNewCell          // [slot] put a new, empty cell in a slot
+	OpBoxLocal         // [slot] move the value in a slot into a new cell
+	OpCopyCell         // [slot] replace the cell in a slot with a copy
+	OpLoadCell         // [slot] push the cell in a slot itself, for a closure
+	OpLoadFreeCell     // [index] push a cell the function closed over
+	OpConstAssign      // assigning to a constant: throws
+	OpSetFunctionName  // [name] name the function on top if it's anonymous
+
+	// Objects. The instructions that access a property by name have an
+	// inline cache, the second operand, which remembers where objects of
+	// the shapes seen there keep the property.
+	OpArray          // [count] make an array from the values on top
+	OpAppend         // push the value on top onto the array below it
+	OpAppendSpread   // append every value of the iterable on top
+	OpObject         // push a new, empty object
+	OpDefineProperty // pop a key and value into the object below them
+	OpGetProperty    // obj key -> value
+	OpGetNamed       // [name] [cache] obj -> value
+	OpSetProperty    // obj key value -> value
+	OpSetNamed       // [name] [cache] obj value -> value
+	OpDeleteProperty // obj key -> whether the property was deleted
+	OpPeekProperty   // obj key -> obj key value
+	OpGetMethod      // [name] [cache] obj -> obj function
+	OpGetMethodKey   // obj key -> obj function
+	OpRegExp         // [constant] a regular expression literal
+	OpImport         // specifier -> a promise of the module's namespace, as import() gives
+
+	// Iteration. The iterators of the for...of loops running in a frame
+	// are kept aside, so that leaving the frame, by returning or with an
+	// error, can close them.
+	OpGetIterator // iterable -> (opens an iterator)
+	OpIterNext    // [target] -> value, or close the iterator and jump once it's done
+	OpIterClose   // close the innermost iterator, as a break does
+
+	// Functions
+	OpCheckCallable // [callee] throw unless the value on top can be called
+	OpCall          // [argc] this function args... -> result
+	OpCallArray     // this function array -> result
+	OpNew           // [argc] function args... -> result
+	OpNewArray      // function array -> result
+	OpClosure       // [function constant] [free count] cells... -> function
+	OpReturn
+	OpReturnUndefined
+
+	// The top level of a program
+	OpSetCompletion    // pop the program's completion value
+	OpClearCompletion  // set the completion value to nothing
+	OpReturnCompletion // end the program with its completion value
+	OpEvalNode         // [node constant] run a statement with the tree-walker
+	OpHoistNode        // [node constant] hoist a function declaration with the tree-walker
+)
+
+// The flags of OpUpdate and OpUpdateProperty. The result of an update is
+// the value from before it unless UpdatePrefix is set.
+const (
+	UpdateDecrement = 1 << iota
+	UpdatePrefix
+)
+
+// DeclarationKinds are the declarations OpDefineGlobal makes, by the
+// value of its second operand.
+var DeclarationKinds = []string{"var", "let", "const"}
+
+// Definition describes an opcode: its name in disassembly, and the width
+// in bytes of each of its operands.
+type Definition struct {
+	Name          string
+	OperandWidths []int
+}
+
+var definitions = map[Opcode]*Definition{
+	OpConstant:  {"OpConstant", []int{2}},
+	OpUndefined: {"OpUndefined", nil},
+	OpNull:      {"OpNull", nil},
+	OpTrue:      {"OpTrue", nil},
+	OpFalse:     {"OpFalse", nil},
+	OpHole:      {"OpHole", nil},
+
+	OpPop:  {"OpPop", nil},
+	OpDup:  {"OpDup", nil},
+	OpDup2: {"OpDup2", nil},
+
+	OpAdd:            {"OpAdd", nil},
+	OpSub:            {"OpSub", nil},
+	OpMul:            {"OpMul", nil},
+	OpDiv:            {"OpDiv", nil},
+	OpMod:            {"OpMod", nil},
+	OpLess:           {"OpLess", nil},
+	OpGreater:        {"OpGreater", nil},
+	OpLessEqual:      {"OpLessEqual", nil},
+	OpGreaterEqual:   {"OpGreaterEqual", nil},
+	OpStrictEqual:    {"OpStrictEqual", nil},
+	OpStrictNotEqual: {"OpStrictNotEqual", nil},
+	OpBinary:         {"OpBinary", []int{2}},
+	OpNot:            {"OpNot", nil},
+	OpNegate:         {"OpNegate", nil},
+	OpPlus:           {"OpPlus", nil},
+	OpTypeof:         {"OpTypeof", nil},
+	OpVoid:           {"OpVoid", nil},
+	OpUpdate:         {"OpUpdate", []int{1}},
+	OpUpdateProperty: {"OpUpdateProperty", []int{1}},
+
+	OpJump:            {"OpJump", []int{2}},
+	OpJumpIfFalse:     {"OpJumpIfFalse", []int{2}},
+	OpJumpIfFalseKeep: {"OpJumpIfFalseKeep", []int{2}},
+	OpJumpIfTrueKeep:  {"OpJumpIfTrueKeep", []int{2}},
+
+	OpGetGlobal:        {"OpGetGlobal", []int{2}},
+	OpSetGlobal:        {"OpSetGlobal", []int{2}},
+	OpDefineGlobal:     {"OpDefineGlobal", []int{2, 1}},
+	OpDeclareGlobalVar: {"OpDeclareGlobalVar", []int{2}},
+	OpTypeofGlobal:     {"OpTypeofGlobal", []int{2}},
+	OpDeleteGlobal:     {"OpDeleteGlobal", []int{2}},
+	OpGetLocal:         {"OpGetLocal", []int{2}},
+	OpSetLocal:         {"OpSetLocal", []int{2}},
+	OpInitLocal:        {"OpInitLocal", []int{2}},
+	OpGetCell:          {"OpGetCell", []int{2}},
+	OpSetCell:          {"OpSetCell", []int{2}},
+	OpInitCell:         {"OpInitCell", []int{2}},
+	OpGetFree:          {"OpGetFree", []int{1}},
+	OpSetFree:          {"OpSetFree", []int{1}},
+	OpNewCell:          {"OpNewCell", []int{2}},
+	OpBoxLocal:         {"OpBoxLocal", []int{2}},
+	OpCopyCell:         {"OpCopyCell", []int{2}},
+	OpLoadCell:         {"OpLoadCell", []int{2}},
+	OpLoadFreeCell:     {"OpLoadFreeCell", []int{1}},
+	OpConstAssign:      {"OpConstAssign", nil},
+	OpSetFunctionName:  {"OpSetFunctionName", []int{2}},
+
+	OpArray:          {"OpArray", []int{2}},
+	OpAppend:         {"OpAppend", nil},
+	OpAppendSpread:   {"OpAppendSpread", nil},
+	OpObject:         {"OpObject", nil},
+	OpDefineProperty: {"OpDefineProperty", nil},
+	OpGetProperty:    {"OpGetProperty", nil},
+	OpGetNamed:       {"OpGetNamed", []int{2, 2}},
+	OpSetProperty:    {"OpSetProperty", nil},
+	OpSetNamed:       {"OpSetNamed", []int{2, 2}},
+	OpDeleteProperty: {"OpDeleteProperty", nil},
+	OpPeekProperty:   {"OpPeekProperty", nil},
+	OpGetMethod:      {"OpGetMethod", []int{2, 2}},
+	OpGetMethodKey:   {"OpGetMethodKey", nil},
+	OpRegExp:         {"OpRegExp", []int{2}},
+	OpImport:         {"OpImport", nil},
+
+	OpGetIterator: {"OpGetIterator", nil},
+	OpIterNext:    {"OpIterNext", []int{2}},
+	OpIterClose:   {"OpIterClose", nil},
+
+	OpCheckCallable:   {"OpCheckCallable", []int{2}},
+	OpCall:            {"OpCall", []int{1}},
+	OpCallArray:       {"OpCallArray", nil},
+	OpNew:             {"OpNew", []int{1}},
+	OpNewArray:        {"OpNewArray", nil},
+	OpClosure:         {"OpClosure", []int{2, 1}},
+	OpReturn:          {"OpReturn", nil},
+	OpReturnUndefined: {"OpReturnUndefined", nil},
+
+	OpSetCompletion:    {"OpSetCompletion", nil},
+	OpClearCompletion:  {"OpClearCompletion", nil},
+	OpReturnCompletion: {"OpReturnCompletion", nil},
+	OpEvalNode:         {"OpEvalNode", []int{2}},
+	OpHoistNode:        {"OpHoistNode", []int{2}},
+}
+
+// Lookup returns the definition of an opcode.
+func Lookup(op Opcode) (*Definition, error) {
+	def, ok := definitions[op]
+	if !ok {
+		return nil, fmt.Errorf("opcode %d undefined", op)
+	}
+	return def, nil
+}
+
+// Make encodes an instruction. Operands that don't fit their width are
+// truncated, so the compiler has to check its limits first; an unknown
+// opcode gives an empty instruction.
+func Make(op Opcode, operands ...int) []byte {
+	def, ok := definitions[op]
+	if !ok {
+		return []byte{}
+	}
+	length := 1
+	for _, w := range def.OperandWidths {
+		length += w
+	}
+	instruction := make([]byte, length)
+	instruction[0] = byte(op)
+	offset := 1
+	for idx, o := range operands {
+		switch def.OperandWidths[idx] {
+		case 2:
+			binary.BigEndian.PutUint16(instruction[offset:], uint16(o))
+		case 1:
+			instruction[offset] = byte(o)
+		}
+		offset += def.OperandWidths[idx]
+	}
+	return instruction
+}
+
+// ReadOperands decodes the operands of an instruction whose opcode is
+// described by def, from ins, which starts right after the opcode. It
+// returns them with the number of bytes they took up.
+func ReadOperands(def *Definition, ins Instructions) ([]int, int) {
+	operands := make([]int, len(def.OperandWidths))
+	offset := 0
+	for idx, width := range def.OperandWidths {
+		switch width {
+		case 2:
+			operands[idx] = int(ReadUint16(ins[offset:]))
+		case 1:
+			operands[idx] = int(ins[offset])
+		}
+		offset += width
+	}
+	return operands, offset
+}
+
+// ReadUint16 decodes a two-byte operand.
+func ReadUint16(ins Instructions) uint16 {
+	return binary.BigEndian.Uint16(ins)
+}
+
+// String disassembles the instructions, one per line, each with its
+// offset:
+//
+//	0000 OpGetLocal 0
+//	0003 OpConstant 1
+//	0006 OpAdd
+func (ins Instructions) String() string {
+	return ins.Format(nil)
+}
+
+// Format disassembles the instructions like String. If note is not nil,
+// it is called for each instruction, and what it returns, such as the
+// name of the variable a slot holds, is added to the line as a comment.
+func (ins Instructions) Format(note func(op Opcode, operands []int) string) string {
+	var out strings.Builder
+	for i := 0; i < len(ins); {
+		def, err := Lookup(Opcode(ins[i]))
+		if err != nil {
+			fmt.Fprintf(&out, "ERROR: %s\n", err)
+			i++
+			continue
+		}
+		operands, read := ReadOperands(def, ins[i+1:])
+		line := formatInstruction(def, operands)
+		if note != nil {
+			if comment := note(Opcode(ins[i]), operands); comment != "" {
+				line += " ; " + comment
+			}
+		}
+		fmt.Fprintf(&out, "%04d %s\n", i, line)
+		i += 1 + read
+	}
+	return out.String()
+}
+
+func formatInstruction(def *Definition, operands []int) string {
+	if len(operands) != len(def.OperandWidths) {
+		return fmt.Sprintf("ERROR: operand len %d does not match defined %d\n",
+			len(operands), len(def.OperandWidths))
+	}
+	parts := []string{def.Name}
+	for _, o := range operands {
+		parts = append(parts, fmt.Sprint(o))
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,1243 @@
+// Package compiler translates the AST of a script into bytecode for the
+// virtual machine.
+//
+// Where the tree-walking interpreter looks every variable up by name, in
+// a chain of environments, the compiler works out at compile time where
+// each one lives: in a slot of the function's frame, in a cell shared
+// with the closures that capture it, or, for the variables declared at
+// the top level of the program, in the global environment, by name.
+//
+// Not every construct has bytecode of its own: generators and async
+// functions, destructuring, object spread, for await...of, and for...of
+// loops assigning to a property don't. A top-level statement using one of
+// them is left to the tree-walker whole, together with any functions it
+// defines: the VM hands it over with OpEvalNode, or OpHoistNode for a
+// function declaration. That works because the variables such a
+// statement can see from outside it are all globals. TreeWalked lists the
+// statements a program leaves to the tree-walker.
+package compiler
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/bytecode"
+)
+
+// CompiledFunction is the bytecode of a function, or of a whole program.
+//
+// The constants are plain Go values, which the VM turns into JavaScript
+// values: a float64, string or *big.Int, a nested *CompiledFunction, an
+// *ast.RegExpLiteral, or a statement for the tree-walker to run.
+type CompiledFunction struct {
+	Name         string
+	Instructions bytecode.Instructions
+	Constants    []any
+
+	// LocalNames holds the name of the variable in each slot, and
+	// FreeNames that of each cell the function closes over.
+	LocalNames []string
+	FreeNames  []string
+
+	// NumParams is the number of parameters. The arguments are stored in
+	// the first slots; if Rest is set, the last of them receives an array
+	// of the arguments left over.
+	NumParams int
+	Rest      bool
+
+	// ThisSlot and SelfSlot are the slots that receive the value of this
+	// and, for a named function expression, the function itself, or -1.
+	ThisSlot int
+	SelfSlot int
+
+	// The function's source, which the function object keeps to show
+	// when it is inspected or converted to a string.
+	Parameters []ast.Expression
+	Body       *ast.BlockStatement
+	Arrow      bool
+
+	// NumCaches is the number of inline caches the function's property
+	// accesses use, which the VM allocates for it.
+	NumCaches int
+}
+
+// NumLocals is the number of slots the function's frame needs.
+func (f *CompiledFunction) NumLocals() int {
+	return len(f.LocalNames)
+}
+
+// Disassemble lists the instructions of the function, followed by those
+// of the functions defined in it, with comments naming the constants and
+// variables that operands refer to.
+func (f *CompiledFunction) Disassemble() string {
+	var out strings.Builder
+	f.disassemble(&out)
+	return out.String()
+}
+
+// TreeWalked returns the statements of a compiled program that it leaves
+// to the tree-walker, in the order the VM hands them over, hoisted
+// function declarations first, or nil if the VM runs all of it. Only the
+// program itself has any: the functions compiled inside it never do.
+func (f *CompiledFunction) TreeWalked() []ast.Node {
+	var nodes []ast.Node
+	for i := 0; i < len(f.Instructions); {
+		op := bytecode.Opcode(f.Instructions[i])
+		def, err := bytecode.Lookup(op)
+		if err != nil {
+			return nodes
+		}
+		operands, read := bytecode.ReadOperands(def, f.Instructions[i+1:])
+		if op == bytecode.OpEvalNode || op == bytecode.OpHoistNode {
+			nodes = append(nodes, f.Constants[operands[0]].(ast.Node))
+		}
+		i += 1 + read
+	}
+	return nodes
+}
+
+func (f *CompiledFunction) disassemble(out *strings.Builder) {
+	name := f.Name
+	if name == "" {
+		name = "<anonymous>"
+	}
+	fmt.Fprintf(out, "== %s ==\n", name)
+	out.WriteString(f.Instructions.Format(f.note))
+	for _, constant := range f.Constants {
+		if fn, ok := constant.(*CompiledFunction); ok {
+			out.WriteString("\n")
+			fn.disassemble(out)
+		}
+	}
+}
+
+// note describes what the operands of an instruction refer to.
+func (f *CompiledFunction) note(op bytecode.Opcode, operands []int) string {
+	switch op {
+	case bytecode.OpConstant, bytecode.OpBinary, bytecode.OpGetGlobal, bytecode.OpSetGlobal,
+		bytecode.OpDefineGlobal, bytecode.OpDeclareGlobalVar, bytecode.OpTypeofGlobal, bytecode.OpDeleteGlobal,
+		bytecode.OpSetFunctionName, bytecode.OpGetNamed, bytecode.OpSetNamed, bytecode.OpGetMethod, bytecode.OpRegExp,
+		bytecode.OpCheckCallable, bytecode.OpClosure, bytecode.OpEvalNode, bytecode.OpHoistNode:
+		switch c := f.Constants[operands[0]].(type) {
+		case string:
+			return fmt.Sprintf("%q", c)
+		case *CompiledFunction:
+			if c.Name == "" {
+				return "<anonymous>"
+			}
+			return c.Name
+		case ast.Node:
+			return c.String()
+		default:
+			return fmt.Sprint(c)
+		}
+	case bytecode.OpGetLocal, bytecode.OpSetLocal, bytecode.OpInitLocal, bytecode.OpGetCell,
+		bytecode.OpSetCell, bytecode.OpInitCell, bytecode.OpNewCell, bytecode.OpBoxLocal,
+		bytecode.OpCopyCell, bytecode.OpLoadCell:
+		return f.LocalNames[operands[0]]
+	case bytecode.OpGetFree, bytecode.OpSetFree, bytecode.OpLoadFreeCell:
+		return f.FreeNames[operands[0]]
+	}
+	return ""
+}
+
+// funcState is the compiler's state for the function being compiled.
+type funcState struct {
+	compiled *CompiledFunction
+	parent   *funcState
+
+	// enclosing is the scope the function is defined in, which variables
+	// not declared in the function are looked up in; it is nil for the
+	// program.
+	enclosing *symbolTable
+
+	// varScope is the function's outermost scope, where var declarations
+	// and parameters go.
+	varScope *symbolTable
+
+	// free holds, for each cell the function closes over, the variable it
+	// belongs to as the enclosing function sees it.
+	free       []*Symbol
+	freeByName map[string]*Symbol
+
+	// strings holds the index of each string constant, so that a name
+	// used many times takes one constant.
+	strings map[string]int
+
+	loops []*loop
+}
+
+// loop collects the jumps that break and continue statements make, to be
+// pointed at the end of the loop and at its next iteration.
+type loop struct {
+	breaks    []int
+	continues []int
+	iterator  bool // whether it's a for...of loop, whose iterator a break closes
+}
+
+// Compiler compiles one program.
+type Compiler struct {
+	fn    *funcState
+	scope *symbolTable
+
+	// captured holds the variables closures capture. It is only complete
+	// once the whole program has been seen, so if compiling finds a
+	// variable that it has already compiled code for as an ordinary
+	// local, it sets again, and the program is compiled a second time.
+	captured map[capturedName]bool
+	again    bool
+
+	// unsupported is set when the code being compiled uses something the
+	// VM can't run, so the top-level statement has to be left to the
+	// tree-walker.
+	unsupported bool
+}
+
+// Compile compiles a program. Compiling can't fail: whatever the compiler
+// can't translate is run by the tree-walker.
+func Compile(program *ast.Program) *CompiledFunction {
+	c := &Compiler{captured: map[capturedName]bool{}}
+	for {
+		c.again = false
+		main := c.compileProgram(program)
+		if !c.again {
+			return main
+		}
+	}
+}
+
+// compileProgram compiles the statements at the top level of a program.
+// The value of the last statement that has one is the program's result,
+// which the compiled code keeps track of in the completion register.
+func (c *Compiler) compileProgram(program *ast.Program) *CompiledFunction {
+	c.fn = &funcState{
+		compiled:   &CompiledFunction{Name: "<program>", ThisSlot: -1, SelfSlot: -1},
+		freeByName: map[string]*Symbol{},
+		strings:    map[string]int{},
+	}
+	c.scope = nil
+	c.fn.varScope = c.pushScope(program)
+
+	// Function declarations at the top level are globals, hoisted before
+	// anything runs.
+	for _, statement := range program.Statements {
+		if decl, ok := statement.(*ast.FunctionDeclaration); ok && decl.Name != nil {
+			c.topLevel(func() {
+				c.compileFunction(decl.Name.Value, decl.Parameters, decl.Body, false, false, decl.Generator || decl.Async, decl)
+				c.emit(bytecode.OpDefineGlobal, c.constant(decl.Name.Value), 0)
+			}, bytecode.OpHoistNode, decl)
+		}
+	}
+	for _, statement := range program.Statements {
+		c.topLevel(func() { c.compileStatement(statement) }, bytecode.OpEvalNode, statement)
+	}
+	c.emit(bytecode.OpReturnCompletion)
+	return c.fn.compiled
+}
+
+// topLevel runs compile, which compiles a top-level statement. If the
+// statement turns out to use something the VM can't run, the code is
+// thrown away, and replaced by an instruction handing node to the
+// tree-walker.
+func (c *Compiler) topLevel(compile func(), fallback bytecode.Opcode, node ast.Node) {
+	fn := c.fn.compiled
+	instructions, constants, locals, caches := len(fn.Instructions), len(fn.Constants), len(fn.LocalNames), fn.NumCaches
+	c.unsupported = false
+	compile()
+	if !c.unsupported {
+		return
+	}
+	fn.Instructions = fn.Instructions[:instructions]
+	fn.Constants = fn.Constants[:constants]
+	fn.LocalNames = fn.LocalNames[:locals]
+	fn.NumCaches = caches
+	for s, idx := range c.fn.strings {
+		if idx >= constants {
+			delete(c.fn.strings, s)
+		}
+	}
+	c.unsupported = false
+	c.emit(fallback, c.constant(node))
+}
+
+// cache allocates an inline cache for a property access.
+func (c *Compiler) cache() int {
+	fn := c.fn.compiled
+	if fn.NumCaches > 0xffff {
+		c.unsupported = true
+		return 0
+	}
+	fn.NumCaches++
+	return fn.NumCaches - 1
+}
+
+// emit appends an instruction to the function being compiled, and returns
+// its position.
+func (c *Compiler) emit(op bytecode.Opcode, operands ...int) int {
+	fn := c.fn.compiled
+	pos := len(fn.Instructions)
+	fn.Instructions = append(fn.Instructions, bytecode.Make(op, operands...)...)
+	return pos
+}
+
+// constant adds a value to the function's constant pool and returns its
+// index. Strings, which name variables and properties, are only added
+// once.
+func (c *Compiler) constant(value any) int {
+	fn := c.fn.compiled
+	s, isString := value.(string)
+	if idx, ok := c.fn.strings[s]; ok && isString {
+		return idx
+	}
+	if len(fn.Constants) > 0xffff {
+		c.unsupported = true
+		return 0
+	}
+	fn.Constants = append(fn.Constants, value)
+	if isString {
+		c.fn.strings[s] = len(fn.Constants) - 1
+	}
+	return len(fn.Constants) - 1
+}
+
+// here returns the position of the next instruction, as a jump target.
+func (c *Compiler) here() int {
+	return len(c.fn.compiled.Instructions)
+}
+
+// patchJump points the jump at pos to target.
+func (c *Compiler) patchJump(pos, target int) {
+	if target > 0xffff {
+		c.unsupported = true
+		return
+	}
+	ins := c.fn.compiled.Instructions
+	ins[pos+1] = byte(target >> 8)
+	ins[pos+2] = byte(target)
+}
+
+// atTopLevel reports whether the code being compiled is the program's
+// own, rather than a function's, so that statements keep the completion
+// register up to date.
+func (c *Compiler) atTopLevel() bool {
+	return c.fn.parent == nil
+}
+
+// clearCompletion records that a statement that has no value ran.
+func (c *Compiler) clearCompletion() {
+	if c.atTopLevel() {
+		c.emit(bytecode.OpClearCompletion)
+	}
+}
+
+func (c *Compiler) compileStatements(statements []ast.Statement) {
+	for _, statement := range statements {
+		c.compileStatement(statement)
+		if c.unsupported {
+			return
+		}
+	}
+}
+
+func (c *Compiler) compileStatement(node ast.Statement) {
+	switch node := node.(type) {
+	case *ast.ExpressionStatement:
+		c.compileExpression(node.Expression)
+		if c.atTopLevel() {
+			c.emit(bytecode.OpSetCompletion)
+		} else {
+			c.emit(bytecode.OpPop)
+		}
+	case *ast.VariableDeclaration:
+		c.compileVariableDeclaration(node)
+		c.clearCompletion()
+	case *ast.FunctionDeclaration:
+		// Hoisted when the enclosing block started.
+		c.clearCompletion()
+	case *ast.BlockStatement:
+		c.compileBlock(node)
+	case *ast.IfStatement:
+		c.compileIf(node)
+	case *ast.WhileStatement:
+		c.compileWhile(node)
+	case *ast.ForStatement:
+		c.compileFor(node)
+	case *ast.ForOfStatement:
+		c.compileForOf(node)
+	case *ast.BreakStatement:
+		c.compileLoopJump(true)
+	case *ast.ContinueStatement:
+		c.compileLoopJump(false)
+	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			c.emit(bytecode.OpReturnUndefined)
+			return
+		}
+		c.compileExpression(node.ReturnValue)
+		c.emit(bytecode.OpReturn)
+	default:
+		c.unsupported = true
+	}
+}
+
+// compileBlock compiles a block, whose let and const declarations and
+// functions have their own scope.
+func (c *Compiler) compileBlock(block *ast.BlockStatement) {
+	c.pushScope(block)
+	defer c.popScope()
+	c.declareBlock(block.Statements)
+	c.compileStatements(block.Statements)
+	if len(block.Statements) == 0 {
+		c.clearCompletion()
+	}
+}
+
+// declareBlock declares the variables a block's own statements declare
+// with let and const, and the functions they declare, which are hoisted:
+// bound before the block's statements run. Captured variables get their
+// cells here, fresh each time the block runs.
+func (c *Compiler) declareBlock(statements []ast.Statement) {
+	var declared []*Symbol
+	var functions []*ast.FunctionDeclaration
+	for _, statement := range statements {
+		switch s := statement.(type) {
+		case *ast.VariableDeclaration:
+			if s.Token.Literal == "var" {
+				continue
+			}
+			name, ok := s.Name.(*ast.Identifier)
+			if !ok {
+				c.unsupported = true
+				return
+			}
+			declared = c.declare(declared, name.Value, s.Token.Literal)
+		case *ast.FunctionDeclaration:
+			declared = c.declare(declared, s.Name.Value, "")
+			functions = append(functions, s)
+		}
+	}
+	for _, s := range declared {
+		if s.Scope == CellScope {
+			c.emit(bytecode.OpNewCell, s.Index)
+		}
+	}
+	c.hoistFunctions(functions)
+}
+
+// declare declares name in the current scope, and adds it to declared
+// unless it was declared there already, as a function's parameters are.
+func (c *Compiler) declare(declared []*Symbol, name, kind string) []*Symbol {
+	_, exists := c.scope.store[name]
+	symbol := c.define(c.scope, name, kind)
+	if exists {
+		return declared
+	}
+	return append(declared, symbol)
+}
+
+// hoistFunctions binds the functions declared in a block to their names.
+func (c *Compiler) hoistFunctions(functions []*ast.FunctionDeclaration) {
+	for _, decl := range functions {
+		if decl.Generator || decl.Async {
+			c.unsupported = true
+			return
+		}
+		c.compileFunction(decl.Name.Value, decl.Parameters, decl.Body, false, false, false, decl)
+		c.storeVariable(c.resolve(decl.Name.Value), decl.Name.Value, true)
+		c.emit(bytecode.OpPop)
+	}
+}
+
+// compileVariableDeclaration compiles a var, let or const declaration of a
+// single name; destructuring declarations are left to the tree-walker.
+func (c *Compiler) compileVariableDeclaration(node *ast.VariableDeclaration) {
+	ident, ok := node.Name.(*ast.Identifier)
+	if !ok {
+		c.unsupported = true
+		return
+	}
+	name, kind := ident.Value, node.Token.Literal
+
+	var symbol *Symbol
+	if kind == "var" {
+		symbol = c.fn.varScope.store[name]
+	} else {
+		symbol = c.resolve(name)
+	}
+	global := c.atTopLevel() && (kind == "var" || symbol == nil)
+
+	if node.Value == nil && kind == "var" {
+		// Declaring a var again without a value keeps its value.
+		switch {
+		case global:
+			c.emit(bytecode.OpDeclareGlobalVar, c.constant(name))
+		case symbol.Scope == CellScope:
+			c.emit(bytecode.OpInitCell, symbol.Index)
+		default:
+			c.emit(bytecode.OpInitLocal, symbol.Index)
+		}
+		return
+	}
+	if node.Value == nil {
+		c.emit(bytecode.OpUndefined)
+	} else {
+		c.compileExpression(node.Value)
+		c.nameFunction(node.Value, name)
+	}
+	if global {
+		kinds := map[string]int{"var": 0, "let": 1, "const": 2}
+		c.emit(bytecode.OpDefineGlobal, c.constant(name), kinds[kind])
+		return
+	}
+	c.storeVariable(symbol, name, true)
+	c.emit(bytecode.OpPop)
+}
+
+// nameFunction emits the instruction giving the value of value, stored
+// in the variable name, that name if it is an anonymous function, unless
+// the expression can't give a function at all.
+func (c *Compiler) nameFunction(value ast.Expression, name string) {
+	switch value := value.(type) {
+	case *ast.Literal, *ast.ArrayLiteral, *ast.ObjectLiteral, *ast.UnaryExpression,
+		*ast.UpdateExpression, *ast.RegExpLiteral:
+		return
+	case *ast.BinaryExpression:
+		if value.Operator != "&&" && value.Operator != "||" {
+			return
+		}
+	}
+	c.emit(bytecode.OpSetFunctionName, c.constant(name))
+}
+
+func (c *Compiler) compileIf(node *ast.IfStatement) {
+	c.compileExpression(node.Condition)
+	jumpToElse := c.emit(bytecode.OpJumpIfFalse, 0)
+	c.compileBlock(node.Consequence)
+	jumpToEnd := c.emit(bytecode.OpJump, 0)
+	c.patchJump(jumpToElse, c.here())
+	switch {
+	case node.Alternative != nil:
+		c.compileStatement(node.Alternative)
+	case c.atTopLevel():
+		// An if statement whose condition is false and that has no else
+		// has the value undefined.
+		c.emit(bytecode.OpUndefined)
+		c.emit(bytecode.OpSetCompletion)
+	}
+	c.patchJump(jumpToEnd, c.here())
+}
+
+func (c *Compiler) compileWhile(node *ast.WhileStatement) {
+	start := c.here()
+	c.compileExpression(node.Condition)
+	exit := c.emit(bytecode.OpJumpIfFalse, 0)
+	l := c.enterLoop()
+	c.compileBlock(node.Body)
+	c.emit(bytecode.OpJump, start)
+	c.exitLoop(l, start)
+	c.patchJump(exit, c.here())
+	c.clearCompletion()
+}
+
+// compileFor compiles a three-part for loop. Variables declared in its
+// head with let or const are in a scope of their own; if closures capture
+// them, each iteration gets its own copies of their cells, so that every
+// closure remembers the values of its own iteration.
+func (c *Compiler) compileFor(node *ast.ForStatement) {
+	c.pushScope(node)
+	defer c.popScope()
+
+	var perIteration []*Symbol
+	if decl, ok := node.Init.(*ast.VariableDeclaration); ok && decl.Token.Literal != "var" {
+		c.declareBlock([]ast.Statement{decl})
+		for _, s := range c.scope.store {
+			if s.Scope == CellScope {
+				perIteration = append(perIteration, s)
+			}
+		}
+	}
+	if node.Init != nil {
+		if _, ok := node.Init.(*ast.ExpressionStatement); ok {
+			c.compileExpression(node.Init.(*ast.ExpressionStatement).Expression)
+			c.emit(bytecode.OpPop)
+		} else if decl, ok := node.Init.(*ast.VariableDeclaration); ok {
+			c.compileVariableDeclaration(decl)
+		} else {
+			c.unsupported = true
+			return
+		}
+	}
+
+	start := c.here()
+	exit := -1
+	if node.Condition != nil {
+		c.compileExpression(node.Condition)
+		exit = c.emit(bytecode.OpJumpIfFalse, 0)
+	}
+	l := c.enterLoop()
+	c.compileBlock(node.Body)
+	next := c.here()
+	for _, s := range perIteration {
+		c.emit(bytecode.OpCopyCell, s.Index)
+	}
+	if node.Update != nil {
+		c.compileExpression(node.Update)
+		c.emit(bytecode.OpPop)
+	}
+	c.emit(bytecode.OpJump, start)
+	c.exitLoop(l, next)
+	if exit >= 0 {
+		c.patchJump(exit, c.here())
+	}
+	c.clearCompletion()
+}
+
+// compileForOf compiles a for...of loop whose loop variable is a single
+// name; destructuring ones are left to the tree-walker, as are for
+// await...of loops. A variable declared with let or const gets a new cell
+// for each iteration if closures capture it.
+func (c *Compiler) compileForOf(node *ast.ForOfStatement) {
+	if node.Await {
+		c.unsupported = true
+		return
+	}
+	c.compileExpression(node.Right)
+	c.pushScope(node)
+	defer c.popScope()
+
+	var symbol *Symbol
+	var name, kind string
+	switch left := node.Left.(type) {
+	case *ast.VariableDeclaration:
+		ident, ok := left.Name.(*ast.Identifier)
+		if !ok {
+			c.unsupported = true
+			return
+		}
+		name, kind = ident.Value, left.Token.Literal
+		if kind == "var" {
+			symbol = c.fn.varScope.store[name]
+		} else {
+			symbol = c.define(c.scope, name, kind)
+		}
+	case *ast.Identifier:
+		name = left.Value
+		symbol = c.resolve(name)
+	default:
+		c.unsupported = true
+		return
+	}
+
+	c.emit(bytecode.OpGetIterator)
+	start := c.here()
+	exit := c.emit(bytecode.OpIterNext, 0)
+	switch {
+	case kind == "var" && symbol == nil:
+		c.emit(bytecode.OpDefineGlobal, c.constant(name), 0)
+	default:
+		if kind != "" && kind != "var" && symbol.Scope == CellScope {
+			c.emit(bytecode.OpNewCell, symbol.Index)
+		}
+		c.storeVariable(symbol, name, kind != "")
+		c.emit(bytecode.OpPop)
+	}
+	l := c.enterLoop()
+	l.iterator = true
+	c.compileBlock(node.Body)
+	c.emit(bytecode.OpJump, start)
+	c.exitLoop(l, start)
+	c.patchJump(exit, c.here())
+	c.clearCompletion()
+}
+
+func (c *Compiler) enterLoop() *loop {
+	l := &loop{}
+	c.fn.loops = append(c.fn.loops, l)
+	return l
+}
+
+// exitLoop points the loop's continue statements at next, and its break
+// statements at the instruction after the loop, which comes next.
+func (c *Compiler) exitLoop(l *loop, next int) {
+	c.fn.loops = c.fn.loops[:len(c.fn.loops)-1]
+	for _, pos := range l.continues {
+		c.patchJump(pos, next)
+	}
+	for _, pos := range l.breaks {
+		c.patchJump(pos, c.here())
+	}
+}
+
+// compileLoopJump compiles a break or continue statement. Outside a loop,
+// one ends the function, or the program.
+func (c *Compiler) compileLoopJump(isBreak bool) {
+	if len(c.fn.loops) == 0 {
+		if c.atTopLevel() {
+			c.emit(bytecode.OpClearCompletion)
+			c.emit(bytecode.OpReturnCompletion)
+		} else {
+			c.emit(bytecode.OpReturnUndefined)
+		}
+		return
+	}
+	l := c.fn.loops[len(c.fn.loops)-1]
+	if isBreak && l.iterator {
+		c.emit(bytecode.OpIterClose)
+	}
+	pos := c.emit(bytecode.OpJump, 0)
+	if isBreak {
+		l.breaks = append(l.breaks, pos)
+	} else {
+		l.continues = append(l.continues, pos)
+	}
+}
+
+// compileFunction compiles a function and emits the instruction creating
+// it as a closure, with the cells of the variables it captures. A
+// generator or async function is left to the tree-walker, with the rest
+// of its statement.
+func (c *Compiler) compileFunction(name string, params []ast.Expression, body *ast.BlockStatement, arrow, named, unsupported bool, node ast.Node) {
+	if unsupported {
+		c.unsupported = true
+		return
+	}
+	fn := &funcState{
+		compiled: &CompiledFunction{
+			Name: name, ThisSlot: -1, SelfSlot: -1,
+			Parameters: params, Body: body, Arrow: arrow,
+		},
+		parent:     c.fn,
+		enclosing:  c.scope,
+		freeByName: map[string]*Symbol{},
+		strings:    map[string]int{},
+	}
+	outerScope := c.scope
+	c.fn = fn
+	c.scope = nil
+
+	// The arguments go in the first slots, followed by this and, for a
+	// named function expression, which can refer to itself by name, the
+	// function itself.
+	var selfScope *symbolTable
+	if named {
+		selfScope = c.pushScope(node.(*ast.FunctionLiteral).Name)
+	}
+	fn.varScope = c.pushScope(node)
+	var boxed []*Symbol
+	for idx, param := range params {
+		target := param
+		if rest, ok := param.(*ast.RestElement); ok && idx == len(params)-1 {
+			fn.compiled.Rest = true
+			target = rest.Argument
+		} else if pattern, ok := param.(*ast.AssignmentPattern); ok {
+			target = pattern.Left
+		}
+		ident, ok := target.(*ast.Identifier)
+		if !ok {
+			c.unsupported = true
+			break
+		}
+		boxed = append(boxed, c.defineSlot(fn.varScope, ident.Value, ""))
+	}
+	fn.compiled.NumParams = len(params)
+	if !arrow {
+		this := c.defineSlot(fn.varScope, "this", "")
+		fn.compiled.ThisSlot = this.Index
+		boxed = append(boxed, this)
+	}
+	if named {
+		self := c.defineSlot(selfScope, name, "")
+		fn.compiled.SelfSlot = self.Index
+		boxed = append(boxed, self)
+	}
+
+	declared := c.declareVars(body.Statements)
+	if !c.unsupported {
+		for _, s := range boxed {
+			if s.Scope == CellScope {
+				c.emit(bytecode.OpBoxLocal, s.Index)
+			}
+		}
+		for _, s := range declared {
+			if s.Scope == CellScope {
+				c.emit(bytecode.OpNewCell, s.Index)
+			}
+		}
+		c.compileDefaults(params)
+		c.declareBlock(body.Statements)
+		c.compileStatements(body.Statements)
+		c.emit(bytecode.OpReturnUndefined)
+	}
+
+	c.fn = fn.parent
+	c.scope = outerScope
+	if len(fn.free) > 0xff {
+		c.unsupported = true
+	}
+	if c.unsupported {
+		return
+	}
+	for _, outer := range fn.free {
+		switch outer.Scope {
+		case FreeScope:
+			c.emit(bytecode.OpLoadFreeCell, outer.Index)
+		default:
+			c.emit(bytecode.OpLoadCell, outer.Index)
+		}
+	}
+	c.emit(bytecode.OpClosure, c.constant(fn.compiled), len(fn.free))
+}
+
+// compileDefaults compiles the default values of the parameters that have
+// one, which replace undefined arguments.
+func (c *Compiler) compileDefaults(params []ast.Expression) {
+	for _, param := range params {
+		pattern, ok := param.(*ast.AssignmentPattern)
+		if !ok {
+			continue
+		}
+		name := pattern.Left.(*ast.Identifier).Value
+		symbol := c.fn.varScope.store[name]
+		c.loadVariable(symbol, name)
+		c.emit(bytecode.OpUndefined)
+		c.emit(bytecode.OpStrictEqual)
+		skip := c.emit(bytecode.OpJumpIfFalse, 0)
+		c.compileExpression(pattern.Right)
+		c.nameFunction(pattern.Right, name)
+		c.storeVariable(symbol, name, true)
+		c.emit(bytecode.OpPop)
+		c.patchJump(skip, c.here())
+	}
+}
+
+// declareVars declares the variables a function body declares with var,
+// wherever they are in it, in the function's scope, and returns those it
+// declared.
+func (c *Compiler) declareVars(statements []ast.Statement) []*Symbol {
+	var declared []*Symbol
+	var walk func(node ast.Node)
+	walk = func(node ast.Node) {
+		switch node := node.(type) {
+		case *ast.VariableDeclaration:
+			if node.Token.Literal != "var" {
+				return
+			}
+			ident, ok := node.Name.(*ast.Identifier)
+			if !ok {
+				c.unsupported = true
+				return
+			}
+			if _, ok := c.fn.varScope.store[ident.Value]; !ok {
+				declared = append(declared, c.define(c.fn.varScope, ident.Value, "var"))
+			}
+		case *ast.BlockStatement:
+			for _, s := range node.Statements {
+				walk(s)
+			}
+		case *ast.IfStatement:
+			walk(node.Consequence)
+			if node.Alternative != nil {
+				walk(node.Alternative)
+			}
+		case *ast.WhileStatement:
+			walk(node.Body)
+		case *ast.ForStatement:
+			if node.Init != nil {
+				walk(node.Init)
+			}
+			walk(node.Body)
+		case *ast.ForOfStatement:
+			walk(node.Left)
+			walk(node.Body)
+		}
+	}
+	for _, s := range statements {
+		walk(s)
+	}
+	return declared
+}
+
+// loadVariable emits the instruction reading a variable, which is a global
+// if symbol is nil.
+func (c *Compiler) loadVariable(symbol *Symbol, name string) {
+	if symbol == nil {
+		c.emit(bytecode.OpGetGlobal, c.constant(name))
+		return
+	}
+	switch symbol.Scope {
+	case LocalScope:
+		c.emit(bytecode.OpGetLocal, symbol.Index)
+	case CellScope:
+		c.emit(bytecode.OpGetCell, symbol.Index)
+	case FreeScope:
+		c.emit(bytecode.OpGetFree, symbol.Index)
+	}
+}
+
+// storeVariable emits the instruction storing the value on top of the
+// stack in a variable, leaving the value there. Unless the store is the
+// variable's declaration, a constant can't be assigned to.
+func (c *Compiler) storeVariable(symbol *Symbol, name string, declaring bool) {
+	if symbol == nil {
+		c.emit(bytecode.OpSetGlobal, c.constant(name))
+		return
+	}
+	if symbol.Kind == "const" && !declaring {
+		c.emit(bytecode.OpConstAssign)
+		return
+	}
+	switch symbol.Scope {
+	case LocalScope:
+		c.emit(bytecode.OpSetLocal, symbol.Index)
+	case CellScope:
+		c.emit(bytecode.OpSetCell, symbol.Index)
+	case FreeScope:
+		c.emit(bytecode.OpSetFree, symbol.Index)
+	}
+}
+
+// Operators with an instruction of their own; the others are compiled to
+// OpBinary.
+var binaryOps = map[string]bytecode.Opcode{
+	"+":   bytecode.OpAdd,
+	"-":   bytecode.OpSub,
+	"*":   bytecode.OpMul,
+	"/":   bytecode.OpDiv,
+	"%":   bytecode.OpMod,
+	"<":   bytecode.OpLess,
+	">":   bytecode.OpGreater,
+	"<=":  bytecode.OpLessEqual,
+	">=":  bytecode.OpGreaterEqual,
+	"===": bytecode.OpStrictEqual,
+	"!==": bytecode.OpStrictNotEqual,
+}
+
+var unaryOps = map[string]bytecode.Opcode{
+	"!":      bytecode.OpNot,
+	"-":      bytecode.OpNegate,
+	"+":      bytecode.OpPlus,
+	"typeof": bytecode.OpTypeof,
+	"void":   bytecode.OpVoid,
+}
+
+// compileDelete compiles delete target. Only properties can be deleted: a
+// variable the compiler knows of is declared, so deleting it is false, and
+// one it doesn't may be a global, which is looked up when the code runs.
+// Deleting anything else only evaluates it.
+func (c *Compiler) compileDelete(target ast.Expression) {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		if c.resolve(target.Value) != nil {
+			c.emit(bytecode.OpFalse)
+			return
+		}
+		c.emit(bytecode.OpDeleteGlobal, c.constant(target.Value))
+	case *ast.MemberExpression:
+		c.compileExpression(target.Object)
+		c.compilePropertyKey(target)
+		c.emit(bytecode.OpDeleteProperty)
+	default:
+		c.compileExpression(target)
+		c.emit(bytecode.OpPop)
+		c.emit(bytecode.OpTrue)
+	}
+}
+
+// emitOperator emits the instruction for a binary operator.
+func (c *Compiler) emitOperator(operator string) {
+	if op, ok := binaryOps[operator]; ok {
+		c.emit(op)
+		return
+	}
+	c.emit(bytecode.OpBinary, c.constant(operator))
+}
+
+func (c *Compiler) compileExpression(node ast.Expression) {
+	if c.unsupported {
+		return
+	}
+	switch node := node.(type) {
+	case *ast.Literal:
+		switch value := node.Value.(type) {
+		case float64, string, *big.Int:
+			c.emit(bytecode.OpConstant, c.constant(value))
+		case bool:
+			if value {
+				c.emit(bytecode.OpTrue)
+			} else {
+				c.emit(bytecode.OpFalse)
+			}
+		default:
+			c.emit(bytecode.OpNull)
+		}
+	case *ast.Identifier:
+		c.loadVariable(c.resolve(node.Value), node.Value)
+	case *ast.ThisExpression:
+		if symbol := c.resolve("this"); symbol != nil {
+			c.loadVariable(symbol, "this")
+		} else {
+			c.emit(bytecode.OpUndefined)
+		}
+	case *ast.UnaryExpression:
+		if node.Operator == "delete" {
+			c.compileDelete(node.Argument)
+			return
+		}
+		op, ok := unaryOps[node.Operator]
+		if !ok {
+			c.unsupported = true
+			return
+		}
+		if ident, ok := node.Argument.(*ast.Identifier); ok && node.Operator == "typeof" {
+			if c.resolve(ident.Value) == nil {
+				// typeof is the one place where an undeclared variable
+				// isn't a ReferenceError.
+				c.emit(bytecode.OpTypeofGlobal, c.constant(ident.Value))
+				return
+			}
+		}
+		c.compileExpression(node.Argument)
+		c.emit(op)
+	case *ast.BinaryExpression:
+		c.compileExpression(node.Left)
+		switch node.Operator {
+		case "&&", "||":
+			op := bytecode.OpJumpIfFalseKeep
+			if node.Operator == "||" {
+				op = bytecode.OpJumpIfTrueKeep
+			}
+			end := c.emit(op, 0)
+			c.compileExpression(node.Right)
+			c.patchJump(end, c.here())
+		default:
+			c.compileExpression(node.Right)
+			c.emitOperator(node.Operator)
+		}
+	case *ast.AssignmentExpression:
+		c.compileAssignment(node)
+	case *ast.UpdateExpression:
+		c.compileUpdate(node)
+	case *ast.ConditionalExpression:
+		c.compileExpression(node.Test)
+		toAlternate := c.emit(bytecode.OpJumpIfFalse, 0)
+		c.compileExpression(node.Consequent)
+		toEnd := c.emit(bytecode.OpJump, 0)
+		c.patchJump(toAlternate, c.here())
+		c.compileExpression(node.Alternate)
+		c.patchJump(toEnd, c.here())
+	case *ast.MemberExpression:
+		c.compileExpression(node.Object)
+		if !node.Computed {
+			c.emit(bytecode.OpGetNamed, c.constant(node.Property.(*ast.Identifier).Value), c.cache())
+			return
+		}
+		c.compileExpression(node.Property)
+		c.emit(bytecode.OpGetProperty)
+	case *ast.CallExpression:
+		c.compileCall(node)
+	case *ast.NewExpression:
+		c.compileExpression(node.Callee)
+		if argc, ok := c.compileArguments(node.Arguments); ok {
+			c.emit(bytecode.OpNew, argc)
+		} else {
+			c.emit(bytecode.OpNewArray)
+		}
+	case *ast.ArrayLiteral:
+		c.compileArrayLiteral(node)
+	case *ast.ObjectLiteral:
+		c.compileObjectLiteral(node)
+	case *ast.FunctionLiteral:
+		name := ""
+		if node.Name != nil {
+			name = node.Name.Value
+		}
+		c.compileFunction(name, node.Parameters, node.Body, node.Arrow, node.Name != nil, node.Generator || node.Async, node)
+	case *ast.RegExpLiteral:
+		c.emit(bytecode.OpRegExp, c.constant(node))
+	case *ast.ImportCall:
+		c.compileExpression(node.Source)
+		c.emit(bytecode.OpImport)
+	default:
+		c.unsupported = true
+	}
+}
+
+// compileAssignment compiles "target = value" and compound forms like
+// "target += value". A compound assignment reads the target's current
+// value before it evaluates the right-hand side, as JavaScript does, so
+// in "x += f()" a change f makes to x is overwritten.
+func (c *Compiler) compileAssignment(node *ast.AssignmentExpression) {
+	operator := strings.TrimSuffix(node.Operator, "=")
+	switch target := node.Left.(type) {
+	case *ast.Identifier:
+		symbol := c.resolve(target.Value)
+		if operator != "" {
+			c.loadVariable(symbol, target.Value)
+			c.compileExpression(node.Right)
+			c.emitOperator(operator)
+		} else {
+			c.compileExpression(node.Right)
+			c.nameFunction(node.Right, target.Value)
+		}
+		c.storeVariable(symbol, target.Value, false)
+	case *ast.MemberExpression:
+		c.compileExpression(target.Object)
+		if !target.Computed && operator == "" {
+			c.compileExpression(node.Right)
+			c.emit(bytecode.OpSetNamed, c.constant(target.Property.(*ast.Identifier).Value), c.cache())
+			return
+		}
+		c.compilePropertyKey(target)
+		if operator != "" {
+			c.emit(bytecode.OpPeekProperty)
+			c.compileExpression(node.Right)
+			c.emitOperator(operator)
+		} else {
+			c.compileExpression(node.Right)
+		}
+		c.emit(bytecode.OpSetProperty)
+	default:
+		c.unsupported = true
+	}
+}
+
+// compileUpdate compiles ++ and --.
+func (c *Compiler) compileUpdate(node *ast.UpdateExpression) {
+	flags := 0
+	if node.Operator == "--" {
+		flags |= bytecode.UpdateDecrement
+	}
+	if node.Prefix {
+		flags |= bytecode.UpdatePrefix
+	}
+	switch target := node.Argument.(type) {
+	case *ast.Identifier:
+		symbol := c.resolve(target.Value)
+		c.loadVariable(symbol, target.Value)
+		c.emit(bytecode.OpUpdate, flags)
+		c.storeVariable(symbol, target.Value, false)
+		c.emit(bytecode.OpPop)
+	case *ast.MemberExpression:
+		c.compileExpression(target.Object)
+		c.compilePropertyKey(target)
+		c.emit(bytecode.OpUpdateProperty, flags)
+	default:
+		c.unsupported = true
+	}
+}
+
+// compilePropertyKey compiles the key of a property access: the name after
+// the dot, or the expression inside the brackets.
+func (c *Compiler) compilePropertyKey(node *ast.MemberExpression) {
+	if !node.Computed {
+		c.emit(bytecode.OpConstant, c.constant(node.Property.(*ast.Identifier).Value))
+		return
+	}
+	c.compileExpression(node.Property)
+}
+
+// compileCall compiles a function call. The VM expects "this" below the
+// function: the object of a method call like obj.method(), or nothing.
+func (c *Compiler) compileCall(node *ast.CallExpression) {
+	if member, ok := node.Function.(*ast.MemberExpression); ok {
+		c.compileExpression(member.Object)
+		if member.Computed {
+			c.compileExpression(member.Property)
+			c.emit(bytecode.OpGetMethodKey)
+		} else {
+			c.emit(bytecode.OpGetMethod, c.constant(member.Property.(*ast.Identifier).Value), c.cache())
+		}
+	} else {
+		c.emit(bytecode.OpHole)
+		c.compileExpression(node.Function)
+	}
+	c.emit(bytecode.OpCheckCallable, c.constant(node.Function.String()))
+	if argc, ok := c.compileArguments(node.Arguments); ok {
+		c.emit(bytecode.OpCall, argc)
+	} else {
+		c.emit(bytecode.OpCallArray)
+	}
+}
+
+// compileArguments compiles the arguments of a call. They are left on the
+// stack, unless there are too many or some are spread, in which case they
+// are collected into an array; ok reports which.
+func (c *Compiler) compileArguments(args []ast.Expression) (argc int, ok bool) {
+	spread := len(args) > 0xff
+	for _, arg := range args {
+		if _, ok := arg.(*ast.SpreadElement); ok {
+			spread = true
+		}
+	}
+	if !spread {
+		for _, arg := range args {
+			c.compileExpression(arg)
+		}
+		return len(args), true
+	}
+	c.compileElements(args)
+	return 0, false
+}
+
+// compileElements compiles the elements of an array literal, or the
+// arguments of a call, one at a time into an array.
+func (c *Compiler) compileElements(elements []ast.Expression) {
+	c.emit(bytecode.OpArray, 0)
+	for _, e := range elements {
+		switch e := e.(type) {
+		case nil:
+			c.emit(bytecode.OpHole)
+			c.emit(bytecode.OpAppend)
+		case *ast.SpreadElement:
+			c.compileExpression(e.Argument)
+			c.emit(bytecode.OpAppendSpread)
+		default:
+			c.compileExpression(e)
+			c.emit(bytecode.OpAppend)
+		}
+	}
+}
+
+func (c *Compiler) compileArrayLiteral(node *ast.ArrayLiteral) {
+	simple := len(node.Elements) <= 0xffff
+	for _, e := range node.Elements {
+		if _, ok := e.(*ast.SpreadElement); ok {
+			simple = false
+		}
+	}
+	if !simple {
+		c.compileElements(node.Elements)
+		return
+	}
+	for _, e := range node.Elements {
+		if e == nil {
+			c.emit(bytecode.OpHole)
+			continue
+		}
+		c.compileExpression(e)
+	}
+	c.emit(bytecode.OpArray, len(node.Elements))
+}
+
+// compileObjectLiteral compiles an object literal, which adds the
+// properties to a new object one by one. Spread properties are left to the
+// tree-walker.
+func (c *Compiler) compileObjectLiteral(node *ast.ObjectLiteral) {
+	c.emit(bytecode.OpObject)
+	for _, prop := range node.Properties {
+		switch prop.Value.(type) {
+		case *ast.SpreadElement, *ast.AssignmentPattern:
+			c.unsupported = true
+			return
+		}
+		if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed {
+			c.emit(bytecode.OpConstant, c.constant(ident.Value))
+		} else {
+			c.compileExpression(prop.Key)
+		}
+		c.compileExpression(prop.Value)
+		c.emit(bytecode.OpDefineProperty)
+	}
+}
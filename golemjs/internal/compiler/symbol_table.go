@@ -0,0 +1,126 @@
+package compiler
+
+import "github.com/biosbuddha/golemjs/internal/ast"
+
+// SymbolScope says where the value of a variable is kept while the
+// program runs.
+type SymbolScope int
+
+const (
+	// LocalScope variables live in a slot of the function's frame.
+	LocalScope SymbolScope = iota
+	// CellScope variables are locals that a closure captures. Their slot
+	// holds a cell, which the function shares with the closures, so that
+	// they all see each other's assignments.
+	CellScope
+	// FreeScope variables belong to an enclosing function; the function
+	// reaches them through the cells it closed over.
+	FreeScope
+)
+
+// Symbol is a variable the compiler resolved. Variables it can't find are
+// globals, which are looked up by name instead.
+type Symbol struct {
+	Name  string
+	Scope SymbolScope
+	Index int    // the slot, or for a free variable the index of its cell
+	Kind  string // "var", "let", "const", or "" for parameters and functions
+
+	// scope is the node whose scope declares the variable, which together
+	// with the name identifies it across compilations; see capturedName.
+	scope ast.Node
+}
+
+// capturedName identifies a variable by the node whose scope declares it
+// and its name.
+type capturedName struct {
+	scope ast.Node
+	name  string
+}
+
+// symbolTable holds the variables declared in one scope: a function body,
+// a block, or the head of a for loop. Scopes are nested like the code
+// they belong to; a lookup that fails in one goes on to the one outside
+// it, and past the function's own scopes into those of the function
+// around it.
+type symbolTable struct {
+	fn    *funcState
+	outer *symbolTable
+	node  ast.Node
+	store map[string]*Symbol
+}
+
+// pushScope starts a scope for node inside the current one.
+func (c *Compiler) pushScope(node ast.Node) *symbolTable {
+	c.scope = &symbolTable{fn: c.fn, outer: c.scope, node: node, store: map[string]*Symbol{}}
+	return c.scope
+}
+
+func (c *Compiler) popScope() {
+	c.scope = c.scope.outer
+}
+
+// define declares name in scope, giving it a slot in the function, unless
+// it is declared there already. A variable that a closure has been found
+// to capture, on an earlier pass over the program, is kept in a cell.
+func (c *Compiler) define(scope *symbolTable, name, kind string) *Symbol {
+	if s, ok := scope.store[name]; ok {
+		if kind == "let" || kind == "const" {
+			s.Kind = kind
+		}
+		return s
+	}
+	return c.defineSlot(scope, name, kind)
+}
+
+// defineSlot declares name in scope in a new slot, even if it is declared
+// there already. Parameters are declared this way, so that when two have
+// the same name, the last one wins.
+func (c *Compiler) defineSlot(scope *symbolTable, name, kind string) *Symbol {
+	fn := scope.fn
+	s := &Symbol{Name: name, Kind: kind, Index: len(fn.compiled.LocalNames), scope: scope.node}
+	if c.captured[capturedName{scope.node, name}] {
+		s.Scope = CellScope
+	}
+	fn.compiled.LocalNames = append(fn.compiled.LocalNames, name)
+	scope.store[name] = s
+	return s
+}
+
+// resolve finds the variable name refers to in the current scope, or
+// returns nil for a global.
+func (c *Compiler) resolve(name string) *Symbol {
+	return c.resolveIn(c.scope, name)
+}
+
+func (c *Compiler) resolveIn(scope *symbolTable, name string) *Symbol {
+	fn := scope.fn
+	for t := scope; t != nil && t.fn == fn; t = t.outer {
+		if s, ok := t.store[name]; ok {
+			return s
+		}
+	}
+	if fn.enclosing == nil {
+		return nil
+	}
+	if s, ok := fn.freeByName[name]; ok {
+		return s
+	}
+	outer := c.resolveIn(fn.enclosing, name)
+	if outer == nil {
+		return nil
+	}
+	if outer.Scope == LocalScope {
+		// The variable is captured, so it needs a cell. The code using
+		// it has been compiled already, so the program has to be
+		// compiled again.
+		c.captured[capturedName{outer.scope, outer.Name}] = true
+		outer.Scope = CellScope
+		c.again = true
+	}
+	free := &Symbol{Name: name, Scope: FreeScope, Index: len(fn.free), Kind: outer.Kind, scope: outer.scope}
+	fn.free = append(fn.free, outer)
+	fn.freeByName[name] = free
+	fn.compiled.FreeNames = append(fn.compiled.FreeNames, name)
+	return free
+}
@@ -0,0 +1,1546 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Array represents JavaScript arrays.
+// Arrays are ordered collections of values that can be of any type.
+// They are "exotic" objects: the integer-keyed properties 0, 1, 2, ... are
+// the array's elements, and the length property is always one more than
+// the largest index. A nil element is a hole - an index that has never been
+// assigned, as in [1, , 3]. Any other properties live in the embedded Hash,
+// whose prototype is Array.prototype.
+//
+// Most arrays are dense, and keep their elements in Elements. An array
+// that is mostly holes, like new Array(1e9), or one where only a[1e9] was
+// assigned, would waste memory, or run out of it, kept that way, so it's
+// sparse instead: Elements is nil, and the elements that aren't holes are
+// in the map sparse, with the length beside them. An array turns sparse
+// when growing it would leave it less than half full, and dense again once
+// it's half full; below denseArrayLength it's always dense, since the
+// holes cost little there.
+type Array struct {
+	Hash
+	Elements []Object
+
+	sparse map[int]Object // the elements of a sparse array, by index
+	length int            // the length of a sparse array
+}
+
+// denseArrayLength is the length up to which arrays are always dense.
+const denseArrayLength = 1 << 12
+
+// wantsDense reports whether an array of length elements, count of them
+// not holes, is better kept dense.
+func wantsDense(length, count int) bool {
+	return length <= denseArrayLength || count >= length/2
+}
+
+func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
+func (ao *Array) Inspect() string {
+	elements := make([]string, 0, min(ao.len(), maxArrayInspectLength))
+	for idx := range min(ao.len(), maxArrayInspectLength) {
+		if e := ao.get(idx); e != nil {
+			elements = append(elements, e.Inspect())
+		} else {
+			elements = append(elements, "")
+		}
+	}
+	if ao.len() > maxArrayInspectLength {
+		elements = append(elements, "...")
+	}
+	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
+}
+
+// newArray creates an array holding elements, inheriting from Array.prototype.
+func (i *Interpreter) newArray(elements []Object) *Array {
+	i.allocated(len(elements) * elementSize)
+	return &Array{Hash: Hash{Prototype: i.arrayPrototype}, Elements: elements}
+}
+
+// elementSize is roughly what an element takes in an array, for the heap
+// limit: an interface value, not counting what it refers to.
+const elementSize = 16
+
+// Len returns the length of the array.
+func (ao *Array) Len() int { return ao.len() }
+
+// At returns the element at idx, which must be less than the length, or
+// nil if it's a hole.
+func (ao *Array) At(idx int) Object { return ao.get(idx) }
+
+func (ao *Array) len() int {
+	if ao.sparse != nil {
+		return ao.length
+	}
+	return len(ao.Elements)
+}
+
+// get returns the element at idx, which must be less than the length, or
+// nil if it's a hole.
+func (ao *Array) get(idx int) Object {
+	if ao.sparse != nil {
+		return ao.sparse[idx]
+	}
+	return ao.Elements[idx]
+}
+
+// element returns the value at idx, reading holes as undefined.
+func (ao *Array) element(idx int) Object {
+	if e := ao.get(idx); e != nil {
+		return e
+	}
+	return UNDEFINED
+}
+
+// count returns the number of elements that aren't holes, or for a dense
+// array, the length, which is as many as there can be.
+func (ao *Array) count() int {
+	if ao.sparse != nil {
+		return len(ao.sparse)
+	}
+	return len(ao.Elements)
+}
+
+// setElement stores value at idx. Writing past the end grows the array,
+// leaving holes between the old end and idx.
+func (ao *Array) setElement(idx int64, value Object) {
+	n := int(idx)
+	if ao.sparse == nil && n >= len(ao.Elements) && !wantsDense(n+1, len(ao.Elements)+1) {
+		ao.makeSparse()
+	}
+	if ao.sparse != nil {
+		ao.sparse[n] = value
+		ao.length = max(ao.length, n+1)
+		if wantsDense(ao.length, len(ao.sparse)) {
+			ao.makeDense()
+		}
+		return
+	}
+	if n >= len(ao.Elements) {
+		ao.grow(n + 1)
+	}
+	ao.Elements[n] = value
+}
+
+// deleteElement turns the element at idx into a hole.
+func (ao *Array) deleteElement(idx int) {
+	if ao.sparse != nil {
+		delete(ao.sparse, idx)
+	} else if idx < len(ao.Elements) {
+		ao.Elements[idx] = nil
+	}
+}
+
+// grow lengthens a dense array to length, adding holes.
+func (ao *Array) grow(length int) {
+	old := len(ao.Elements)
+	ao.Elements = slices.Grow(ao.Elements, length-old)[:length]
+	clear(ao.Elements[old:])
+}
+
+// makeSparse moves the elements of a dense array into the map.
+func (ao *Array) makeSparse() {
+	ao.sparse = make(map[int]Object)
+	for idx, e := range ao.Elements {
+		if e != nil {
+			ao.sparse[idx] = e
+		}
+	}
+	ao.length = len(ao.Elements)
+	ao.Elements = nil
+}
+
+// makeDense moves the elements of a sparse array into the slice.
+func (ao *Array) makeDense() {
+	elements := make([]Object, ao.length)
+	for idx, e := range ao.sparse {
+		elements[idx] = e
+	}
+	ao.Elements = elements
+	ao.sparse = nil
+	ao.length = 0
+}
+
+// reset empties the array, leaving it length holes long.
+func (ao *Array) reset(length int) {
+	ao.Elements = nil
+	ao.sparse = nil
+	ao.length = 0
+	ao.setLength(float64(length))
+}
+
+// rearrange moves the elements of the array to new indices, for methods
+// like shift and reverse on a sparse array: the element at idx moves to
+// to(idx), or is dropped if that's negative, and the array is left length
+// long.
+func (ao *Array) rearrange(length int, to func(idx int) int) {
+	w := ao.walk(0, ao.len(), false)
+	type entry struct {
+		idx   int
+		value Object
+	}
+	var entries []entry
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		entries = append(entries, entry{idx, e})
+	}
+	ao.reset(length)
+	for _, e := range entries {
+		if idx := to(e.idx); idx >= 0 && idx < length {
+			ao.setElement(int64(idx), e.value)
+		}
+	}
+}
+
+// setArrayLength implements assignment to the length property. The new
+// length may be given as any value that converts to a whole number.
+func (i *Interpreter) setArrayLength(arr *Array, value Object) *Error {
+	n, err := i.toNumber(value)
+	if err != nil {
+		return err
+	}
+	return arr.setLength(n)
+}
+
+// setLength changes the length of the array. Shrinking the array deletes
+// the elements past the new end; growing it adds holes.
+func (ao *Array) setLength(n float64) *Error {
+	if n != math.Trunc(n) || n < 0 || n > maxArrayIndex+1 {
+		return newRangeError("Invalid array length")
+	}
+	length := int(n)
+	if ao.sparse != nil {
+		if length < ao.length {
+			for idx := range ao.sparse {
+				if idx >= length {
+					delete(ao.sparse, idx)
+				}
+			}
+		}
+		ao.length = length
+		if wantsDense(ao.length, len(ao.sparse)) {
+			ao.makeDense()
+		}
+		return nil
+	}
+	if length <= len(ao.Elements) {
+		clear(ao.Elements[length:])
+		ao.Elements = ao.Elements[:length]
+		return nil
+	}
+	if !wantsDense(length, len(ao.Elements)) {
+		ao.makeSparse()
+		ao.length = length
+		return nil
+	}
+	ao.grow(length)
+	return nil
+}
+
+// elementWalk steps through the elements of an array that aren't holes,
+// in order of index, or backwards. The array may change on the way: an
+// element deleted before the walk reaches it is skipped, and one added
+// isn't visited unless it's a dense array's and in the range walked.
+type elementWalk struct {
+	arr *Array
+
+	// For a dense array, pos is the next index and end the index the walk
+	// stops at, and step is 1 or -1. For a sparse one, keys holds the
+	// indices to visit, in order, and pos is the position in keys.
+	keys     []int
+	sparse   bool
+	pos, end int
+	step     int
+}
+
+// walk returns a walk over the elements from start up to end.
+func (ao *Array) walk(start, end int, backwards bool) *elementWalk {
+	w := &elementWalk{arr: ao, step: 1}
+	if ao.sparse != nil {
+		w.sparse = true
+		for idx := range ao.sparse {
+			if idx >= start && idx < end {
+				w.keys = append(w.keys, idx)
+			}
+		}
+		slices.Sort(w.keys)
+		if backwards {
+			slices.Reverse(w.keys)
+		}
+		w.end = len(w.keys)
+		return w
+	}
+	w.pos, w.end = start, end
+	if backwards {
+		w.pos, w.end, w.step = end-1, start-1, -1
+	}
+	return w
+}
+
+// next returns the next element and its index, or false at the end.
+func (w *elementWalk) next() (int, Object, bool) {
+	for w.pos != w.end {
+		idx := w.pos
+		if w.sparse {
+			idx = w.keys[w.pos]
+		}
+		w.pos += w.step
+		if idx < w.arr.len() {
+			if e := w.arr.get(idx); e != nil {
+				return idx, e, true
+			}
+		}
+	}
+	return 0, nil, false
+}
+
+// join converts every element of arr to a string and joins them with sep.
+// null, undefined and holes become empty strings. An array that contains itself,
+// directly or through another array, is joined as "" at the inner level.
+func (i *Interpreter) join(arr *Array, sep string) (string, *Error) {
+	return i.joinWith(arr, sep, i.toString)
+}
+
+// joinWith is join, converting the elements to strings with convert.
+func (i *Interpreter) joinWith(arr *Array, sep string, convert func(Object) (*String, *Error)) (string, *Error) {
+	if i.joining[arr] {
+		return "", nil
+	}
+	if i.joining == nil {
+		i.joining = make(map[*Array]bool)
+	}
+	i.joining[arr] = true
+	defer delete(i.joining, arr)
+
+	length := arr.len()
+	if length > 1 && float64(length-1)*float64(len(sep)) > maxStringLength {
+		return "", newRangeError("Invalid string length")
+	}
+	// The separators between holes are written in runs, so that a sparse
+	// array doesn't cost a step per hole.
+	var b strings.Builder
+	seps := 0 // the number of separators written
+	w := arr.walk(0, length, false)
+	for {
+		idx, e, ok := w.next()
+		if !ok {
+			break
+		}
+		if err := i.tick(); err != nil {
+			return "", err
+		}
+		if err := i.allocate((idx - seps) * len(sep)); err != nil {
+			return "", err
+		}
+		b.WriteString(strings.Repeat(sep, idx-seps))
+		seps = idx
+		if e == NULL || e == UNDEFINED {
+			continue
+		}
+		s, err := convert(e)
+		if err != nil {
+			return "", err
+		}
+		if b.Len()+len(s.Value) > maxStringLength {
+			return "", newRangeError("Invalid string length")
+		}
+		if err := i.allocate(len(s.Value)); err != nil {
+			return "", err
+		}
+		b.WriteString(s.Value)
+	}
+	if length > 0 {
+		if err := i.allocate((length - 1 - seps) * len(sep)); err != nil {
+			return "", err
+		}
+		b.WriteString(strings.Repeat(sep, length-1-seps))
+	}
+	return b.String(), nil
+}
+
+// indexed is a sequence an ArrayIterator can walk: an array or a typed
+// array.
+type indexed interface {
+	len() int
+	element(idx int) Object
+}
+
+// ArrayIterator is the object returned by keys(), values() and entries().
+// Each call to its next() method produces the next key, value or [key, value]
+// pair. The iterator reads the array's current length every time, so it sees
+// elements added while iterating.
+type ArrayIterator struct {
+	Hash
+	array indexed
+	kind  string // "keys", "values" or "entries"
+	index int
+	done  bool
+}
+
+func (it *ArrayIterator) Type() ObjectType { return ARRAY_ITERATOR_OBJ }
+func (it *ArrayIterator) Inspect() string  { return "Array Iterator {}" }
+
+// nextArrayIteratorValue advances the iterator and returns the next value
+// and whether the iterator is finished.
+func (i *Interpreter) nextArrayIteratorValue(it *ArrayIterator) (Object, bool) {
+	if it.done || it.index >= it.array.len() {
+		it.done = true
+		return UNDEFINED, true
+	}
+	idx := it.index
+	it.index++
+	switch it.kind {
+	case "keys":
+		return &Number{Value: float64(idx)}, false
+	case "entries":
+		return i.newArray([]Object{&Number{Value: float64(idx)}, it.array.element(idx)}), false
+	}
+	return it.array.element(idx), false
+}
+
+// iteratorResult creates the { value, done } objects returned by next().
+func (i *Interpreter) iteratorResult(value Object, done bool) *Hash {
+	result := NewHash(i.objectPrototype)
+	result.Set(&String{Value: "value"}, value)
+	result.Set(&String{Value: "done"}, nativeBoolToBooleanObject(done))
+	return result
+}
+
+// setupArray creates Array.prototype, the array iterator prototype and the
+// Array constructor, and defines Array in the global environment.
+func (i *Interpreter) setupArray() {
+	proto := i.arrayPrototype
+
+	i.defineMethod(proto, "at", i.arrayAt)
+	i.defineMethod(proto, "concat", i.arrayConcat)
+	i.defineMethod(proto, "copyWithin", i.arrayCopyWithin)
+	i.defineMethod(proto, "entries", i.arrayIteratorMethod("entries"))
+	i.defineMethod(proto, "every", i.arrayEvery)
+	i.defineMethod(proto, "fill", i.arrayFill)
+	i.defineMethod(proto, "filter", i.arrayFilter)
+	i.defineMethod(proto, "find", i.arrayFind(false, false))
+	i.defineMethod(proto, "findIndex", i.arrayFind(false, true))
+	i.defineMethod(proto, "findLast", i.arrayFind(true, false))
+	i.defineMethod(proto, "findLastIndex", i.arrayFind(true, true))
+	i.defineMethod(proto, "flat", i.arrayFlat)
+	i.defineMethod(proto, "flatMap", i.arrayFlatMap)
+	i.defineMethod(proto, "forEach", i.arrayForEach)
+	i.defineMethod(proto, "includes", i.arrayIncludes)
+	i.defineMethod(proto, "indexOf", i.arrayIndexOf)
+	i.defineMethod(proto, "join", i.arrayJoin)
+	i.defineMethod(proto, "keys", i.arrayIteratorMethod("keys"))
+	i.defineMethod(proto, "lastIndexOf", i.arrayLastIndexOf)
+	i.defineMethod(proto, "map", i.arrayMap)
+	i.defineMethod(proto, "pop", i.arrayPop)
+	i.defineMethod(proto, "push", i.arrayPush)
+	i.defineMethod(proto, "reduce", i.arrayReduce(false))
+	i.defineMethod(proto, "reduceRight", i.arrayReduce(true))
+	i.defineMethod(proto, "reverse", i.arrayReverse)
+	i.defineMethod(proto, "shift", i.arrayShift)
+	i.defineMethod(proto, "slice", i.arraySlice)
+	i.defineMethod(proto, "some", i.arraySome)
+	i.defineMethod(proto, "sort", i.arraySort)
+	i.defineMethod(proto, "splice", i.arraySplice)
+	i.defineMethod(proto, "toLocaleString", i.arrayToLocaleString)
+	i.defineMethod(proto, "toReversed", i.arrayToReversed)
+	i.defineMethod(proto, "toSorted", i.arrayToSorted)
+	i.defineMethod(proto, "toSpliced", i.arrayToSpliced)
+	i.defineMethod(proto, "toString", i.arrayToString)
+	i.defineMethod(proto, "unshift", i.arrayUnshift)
+	i.defineMethod(proto, "values", i.arrayIteratorMethod("values"))
+	i.defineMethod(proto, "with", i.arrayWith)
+	values, _ := proto.GetOwn(&String{Value: "values"})
+	proto.Set(symbolIterator, values)
+
+	i.defineMethod(i.arrayIteratorPrototype, "next", func(this Object, args ...Object) Object {
+		it, ok := this.(*ArrayIterator)
+		if !ok {
+			return newTypeError("next method called on incompatible receiver %s", inspectReceiver(this))
+		}
+		value, done := i.nextArrayIteratorValue(it)
+		return i.iteratorResult(value, done)
+	})
+	i.arrayIteratorPrototype.Set(symbolToStringTag, &String{Value: "Array Iterator"})
+
+	ctor := i.newBuiltin("Array", i.arrayConstructor)
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.defineMethod(&ctor.Hash, "from", i.arrayFrom)
+	i.defineMethod(&ctor.Hash, "of", func(this Object, args ...Object) Object {
+		return i.newArray(append([]Object{}, args...))
+	})
+	i.defineMethod(&ctor.Hash, "isArray", func(this Object, args ...Object) Object {
+		_, ok := argAt(args, 0).(*Array)
+		return nativeBoolToBooleanObject(ok)
+	})
+	i.env.Set("Array", ctor)
+}
+
+// arrayConstructor implements Array(...) and new Array(...). A single
+// integer argument is the length of a new array of holes; anything else
+// becomes the array's elements.
+func (i *Interpreter) arrayConstructor(this Object, args ...Object) Object {
+	if len(args) == 1 {
+		if n, ok := args[0].(*Number); ok {
+			arr := i.newArray(nil)
+			if err := arr.setLength(n.Value); err != nil {
+				return err
+			}
+			return arr
+		}
+	}
+	return i.newArray(append([]Object{}, args...))
+}
+
+// arrayFrom implements Array.from(items, mapFn, thisArg). items may be
+// anything iterable, or else an array-like object with a length.
+func (i *Interpreter) arrayFrom(this Object, args ...Object) Object {
+	items := argAt(args, 0)
+	mapFn := argAt(args, 1)
+	if mapFn != UNDEFINED && !isCallable(mapFn) {
+		return newTypeError("%s is not a function", mapFn.Inspect())
+	}
+	if isNullish(items) {
+		return newTypeError("%s is not iterable", items.Inspect())
+	}
+
+	// The values are mapped as they are produced, so a mapFn that fails
+	// stops an iterator from being asked for more.
+	values := []Object{}
+	add := func(value Object) *Error {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		i.allocated(elementSize)
+		if mapFn != UNDEFINED {
+			value = i.applyFunction(mapFn, argAt(args, 2), []Object{value, &Number{Value: float64(len(values))}})
+			if err, ok := value.(*Error); ok {
+				return err
+			}
+		}
+		values = append(values, value)
+		return nil
+	}
+
+	usingIterator := i.getProperty(items, symbolIterator)
+	switch {
+	case isError(usingIterator):
+		return usingIterator
+	case !isNullish(usingIterator):
+		if err := i.iterate(items, add); err != nil {
+			return err
+		}
+	default:
+		lengthValue := i.getProperty(items, &String{Value: "length"})
+		if isError(lengthValue) {
+			return lengthValue
+		}
+		length, err := i.toIntegerOrInfinity(lengthValue)
+		if err != nil {
+			return err
+		}
+		if length > maxArrayIndex+1 {
+			return newRangeError("Invalid array length")
+		}
+		for idx := 0.0; idx < length; idx++ {
+			value := i.getProperty(items, &Number{Value: idx})
+			if isError(value) {
+				return value
+			}
+			if err := add(value); err != nil {
+				return err
+			}
+		}
+	}
+	return i.newArray(values)
+}
+
+// thisArray checks that an Array.prototype method was called on an array.
+func thisArray(this Object, method string) (*Array, *Error) {
+	arr, ok := this.(*Array)
+	if !ok {
+		return nil, newTypeError("Array.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+	}
+	return arr, nil
+}
+
+// callbackArg checks the callback passed to methods like map and filter.
+func callbackArg(args []Object) (Object, *Error) {
+	fn := argAt(args, 0)
+	if !isCallable(fn) {
+		return nil, newTypeError("%s is not a function", fn.Inspect())
+	}
+	return fn, nil
+}
+
+// relativeIndex converts a start or end argument, which may count back from
+// the end when negative, into an index clamped to [0, length]. A missing
+// argument gives def.
+func (i *Interpreter) relativeIndex(arg Object, length int, def int) (int, *Error) {
+	if arg == UNDEFINED {
+		return def, nil
+	}
+	n, err := i.toIntegerOrInfinity(arg)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		n = math.Max(float64(length)+n, 0)
+	}
+	return int(math.Min(n, float64(length))), nil
+}
+
+// visit calls fn(element, index, array) for each element that isn't a hole,
+// stopping early if stop returns true for a callback result.
+func (i *Interpreter) visit(arr *Array, args []Object, stop func(result Object, idx int) bool) *Error {
+	fn, err := callbackArg(args)
+	if err != nil {
+		return err
+	}
+	thisArg := argAt(args, 1)
+	w := arr.walk(0, arr.len(), false)
+	for {
+		idx, value, ok := w.next()
+		if !ok {
+			return nil
+		}
+		result := i.applyFunction(fn, thisArg, []Object{value, &Number{Value: float64(idx)}, arr})
+		if err, ok := result.(*Error); ok {
+			return err
+		}
+		if stop(result, idx) {
+			return nil
+		}
+	}
+}
+
+func (i *Interpreter) arrayAt(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "at")
+	if err != nil {
+		return err
+	}
+	idx, err := i.toIntegerOrInfinity(argAt(args, 0))
+	if err != nil {
+		return err
+	}
+	if idx < 0 {
+		idx += float64(arr.len())
+	}
+	if idx < 0 || idx >= float64(arr.len()) {
+		return UNDEFINED
+	}
+	return arr.element(int(idx))
+}
+
+// arrayCopyWithin copies the elements from start up to end over the ones
+// starting at target, in place, as if through a temporary copy, so the two
+// ranges may overlap. A hole copied over an element leaves a hole there.
+func (i *Interpreter) arrayCopyWithin(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "copyWithin")
+	if err != nil {
+		return err
+	}
+	length := arr.len()
+	to, err := i.relativeIndex(argAt(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+	from, err := i.relativeIndex(argAt(args, 1), length, 0)
+	if err != nil {
+		return err
+	}
+	end, err := i.relativeIndex(argAt(args, 2), length, length)
+	if err != nil {
+		return err
+	}
+	count := min(end-from, length-to)
+	if count <= 0 {
+		return arr
+	}
+	if arr.sparse == nil {
+		if err := i.charge(count); err != nil {
+			return err
+		}
+		copy(arr.Elements[to:to+count], arr.Elements[from:from+count])
+		return arr
+	}
+	if err := i.charge(arr.count()); err != nil {
+		return err
+	}
+	moved := map[int]Object{}
+	w := arr.walk(from, from+count, false)
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		moved[idx-from+to] = e
+	}
+	w = arr.walk(to, to+count, false)
+	for idx, _, ok := w.next(); ok; idx, _, ok = w.next() {
+		arr.deleteElement(idx)
+	}
+	for idx, e := range moved {
+		arr.setElement(int64(idx), e)
+	}
+	return arr
+}
+
+func (i *Interpreter) arrayConcat(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "concat")
+	if err != nil {
+		return err
+	}
+	parts := append([]Object{arr}, args...)
+	// The copying is paid for up front, so that a concat too big for the
+	// limits fails before it's done.
+	size := 0
+	for _, part := range parts {
+		if other, ok := part.(*Array); ok {
+			size += other.count()
+		} else {
+			size++
+		}
+	}
+	if err := i.charge(size); err != nil {
+		return err
+	}
+	if err := i.allocate(size * elementSize); err != nil {
+		return err
+	}
+	result := i.newArray(nil)
+	for _, part := range parts {
+		other, ok := part.(*Array)
+		if !ok {
+			if result.len() > maxArrayIndex {
+				return newRangeError("Invalid array length")
+			}
+			result.setElement(int64(result.len()), part)
+			continue
+		}
+		if result.sparse == nil && other.sparse == nil {
+			result.Elements = append(result.Elements, other.Elements...)
+			continue
+		}
+		offset := result.len()
+		if err := result.setLength(float64(offset + other.len())); err != nil {
+			return err
+		}
+		w := other.walk(0, other.len(), false)
+		for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+			result.setElement(int64(offset+idx), e)
+		}
+	}
+	return result
+}
+
+func (i *Interpreter) arrayIteratorMethod(kind string) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		arr, err := thisArray(this, kind)
+		if err != nil {
+			return err
+		}
+		return &ArrayIterator{Hash: Hash{Prototype: i.arrayIteratorPrototype}, array: arr, kind: kind}
+	}
+}
+
+func (i *Interpreter) arrayEvery(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "every")
+	if err != nil {
+		return err
+	}
+	every := true
+	if err := i.visit(arr, args, func(result Object, idx int) bool {
+		every = isTruthy(result)
+		return !every
+	}); err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(every)
+}
+
+func (i *Interpreter) arraySome(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "some")
+	if err != nil {
+		return err
+	}
+	some := false
+	if err := i.visit(arr, args, func(result Object, idx int) bool {
+		some = isTruthy(result)
+		return some
+	}); err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(some)
+}
+
+func (i *Interpreter) arrayFill(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "fill")
+	if err != nil {
+		return err
+	}
+	length := arr.len()
+	start, err := i.relativeIndex(argAt(args, 1), length, 0)
+	if err != nil {
+		return err
+	}
+	end, err := i.relativeIndex(argAt(args, 2), length, length)
+	if err != nil {
+		return err
+	}
+	for idx := start; idx < end; idx++ {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		if err := i.allocate(elementSize); err != nil {
+			return err
+		}
+		arr.setElement(int64(idx), argAt(args, 0))
+	}
+	return arr
+}
+
+func (i *Interpreter) arrayFilter(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "filter")
+	if err != nil {
+		return err
+	}
+	kept := []Object{}
+	if err := i.visit(arr, args, func(result Object, idx int) bool {
+		if isTruthy(result) && idx < arr.len() {
+			kept = append(kept, arr.element(idx))
+		}
+		return false
+	}); err != nil {
+		return err
+	}
+	return i.newArray(kept)
+}
+
+// arrayFind implements find, findIndex, findLast and findLastIndex. Unlike
+// most callback methods these visit holes too, passing undefined for them.
+func (i *Interpreter) arrayFind(fromEnd bool, wantIndex bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		arr, err := thisArray(this, "find")
+		if err != nil {
+			return err
+		}
+		fn, err := callbackArg(args)
+		if err != nil {
+			return err
+		}
+		length := arr.len()
+		for n := 0; n < length; n++ {
+			idx := n
+			if fromEnd {
+				idx = length - 1 - n
+			}
+			var value Object = UNDEFINED
+			if idx < arr.len() {
+				value = arr.element(idx)
+			}
+			result := i.applyFunction(fn, argAt(args, 1), []Object{value, &Number{Value: float64(idx)}, arr})
+			if isError(result) {
+				return result
+			}
+			if isTruthy(result) {
+				if wantIndex {
+					return &Number{Value: float64(idx)}
+				}
+				return value
+			}
+		}
+		if wantIndex {
+			return &Number{Value: -1}
+		}
+		return UNDEFINED
+	}
+}
+
+func (i *Interpreter) arrayFlat(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "flat")
+	if err != nil {
+		return err
+	}
+	depth := 1.0
+	if len(args) > 0 && args[0] != UNDEFINED {
+		depth, err = i.toIntegerOrInfinity(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	flat, err := i.flatten(arr, depth)
+	if err != nil {
+		return err
+	}
+	return i.newArray(flat)
+}
+
+// flatten copies the elements of arr, expanding nested arrays up to depth
+// levels deep and dropping holes. It keeps the arrays it's in the middle
+// of on a stack of its own rather than recursing, so an array nested
+// millions deep is no danger to the Go stack, and counts each level as a
+// call, so one nested too deeply, or inside itself, is a RangeError.
+func (i *Interpreter) flatten(arr *Array, depth float64) ([]Object, *Error) {
+	result := []Object{}
+	stack := []*elementWalk{arr.walk(0, arr.len(), false)}
+	defer func() {
+		for range len(stack) - 1 {
+			i.exitCall()
+		}
+	}()
+	for len(stack) > 0 {
+		_, e, ok := stack[len(stack)-1].next()
+		if !ok {
+			stack = stack[:len(stack)-1]
+			if len(stack) > 0 {
+				i.exitCall()
+			}
+			continue
+		}
+		if err := i.tick(); err != nil {
+			return nil, err
+		}
+		if inner, ok := e.(*Array); ok && float64(len(stack)) <= depth {
+			if err := i.enterCall(); err != nil {
+				return nil, err
+			}
+			stack = append(stack, inner.walk(0, inner.len(), false))
+			continue
+		}
+		i.allocated(elementSize)
+		result = append(result, e)
+	}
+	return result, nil
+}
+
+func (i *Interpreter) arrayFlatMap(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "flatMap")
+	if err != nil {
+		return err
+	}
+	mapped := []Object{}
+	if err := i.visit(arr, args, func(result Object, idx int) bool {
+		mapped = append(mapped, result)
+		return false
+	}); err != nil {
+		return err
+	}
+	flat, err := i.flatten(i.newArray(mapped), 1)
+	if err != nil {
+		return err
+	}
+	return i.newArray(flat)
+}
+
+func (i *Interpreter) arrayForEach(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "forEach")
+	if err != nil {
+		return err
+	}
+	if err := i.visit(arr, args, func(result Object, idx int) bool { return false }); err != nil {
+		return err
+	}
+	return UNDEFINED
+}
+
+// arrayIncludes differs from indexOf in two ways: holes count as undefined,
+// and it can find NaN because it compares with SameValueZero.
+func (i *Interpreter) arrayIncludes(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "includes")
+	if err != nil {
+		return err
+	}
+	target := argAt(args, 0)
+	start, err := i.relativeIndex(argAt(args, 1), arr.len(), 0)
+	if err != nil {
+		return err
+	}
+	if arr.sparse != nil && target == UNDEFINED && arr.len()-start > len(arr.sparse) {
+		// There's a hole after start, which counts as undefined.
+		return TRUE
+	}
+	w := arr.walk(start, arr.len(), false)
+	for _, e, ok := w.next(); ok; _, e, ok = w.next() {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		if sameValueZero(e, target) {
+			return TRUE
+		}
+	}
+	if arr.sparse == nil && target == UNDEFINED && slices.Contains(arr.Elements[start:], nil) {
+		return TRUE
+	}
+	return FALSE
+}
+
+func (i *Interpreter) arrayIndexOf(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "indexOf")
+	if err != nil {
+		return err
+	}
+	target := argAt(args, 0)
+	start, err := i.relativeIndex(argAt(args, 1), arr.len(), 0)
+	if err != nil {
+		return err
+	}
+	w := arr.walk(start, arr.len(), false)
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		if strictEquals(e, target) {
+			return &Number{Value: float64(idx)}
+		}
+	}
+	return &Number{Value: -1}
+}
+
+func (i *Interpreter) arrayLastIndexOf(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "lastIndexOf")
+	if err != nil {
+		return err
+	}
+	target := argAt(args, 0)
+	start := arr.len() - 1
+	if len(args) > 1 {
+		n, err := i.toIntegerOrInfinity(args[1])
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			start = int(math.Max(float64(arr.len())+n, -1))
+		} else if n < float64(start) {
+			start = int(n)
+		}
+	}
+	w := arr.walk(0, start+1, true)
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		if strictEquals(e, target) {
+			return &Number{Value: float64(idx)}
+		}
+	}
+	return &Number{Value: -1}
+}
+
+func (i *Interpreter) arrayJoin(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "join")
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if len(args) > 0 && args[0] != UNDEFINED {
+		s, err := i.toString(args[0])
+		if err != nil {
+			return err
+		}
+		sep = s.Value
+	}
+	joined, err := i.join(arr, sep)
+	if err != nil {
+		return err
+	}
+	return &String{Value: joined}
+}
+
+func (i *Interpreter) arrayToString(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "toString")
+	if err != nil {
+		return err
+	}
+	joined, err := i.join(arr, ",")
+	if err != nil {
+		return err
+	}
+	return &String{Value: joined}
+}
+
+// arrayToLocaleString is toString, converting the elements with their own
+// toLocaleString methods, so that numbers and dates are formatted as the
+// locale has them.
+func (i *Interpreter) arrayToLocaleString(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "toLocaleString")
+	if err != nil {
+		return err
+	}
+	joined, err := i.joinWith(arr, ",", func(e Object) (*String, *Error) {
+		method := i.getProperty(e, &String{Value: "toLocaleString"})
+		if err, ok := method.(*Error); ok {
+			return nil, err
+		}
+		if !isCallable(method) {
+			return nil, newTypeError("%s is not a function", inspect(method, 0))
+		}
+		result := i.applyFunction(method, e, nil)
+		if err, ok := result.(*Error); ok {
+			return nil, err
+		}
+		return i.toString(result)
+	})
+	if err != nil {
+		return err
+	}
+	return &String{Value: joined}
+}
+
+// copyElements copies the elements of arr into a new slice, reading holes
+// as undefined, for the methods that return a changed copy of an array
+// rather than changing it: toReversed, toSorted, toSpliced and with.
+func (i *Interpreter) copyElements(arr *Array) ([]Object, *Error) {
+	length := arr.len()
+	if err := i.charge(length); err != nil {
+		return nil, err
+	}
+	if err := i.allocate(length * elementSize); err != nil {
+		return nil, err
+	}
+	elements := make([]Object, length)
+	for idx := range elements {
+		elements[idx] = UNDEFINED
+	}
+	w := arr.walk(0, length, false)
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		elements[idx] = e
+	}
+	return elements, nil
+}
+
+func (i *Interpreter) arrayToReversed(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "toReversed")
+	if err != nil {
+		return err
+	}
+	elements, err := i.copyElements(arr)
+	if err != nil {
+		return err
+	}
+	slices.Reverse(elements)
+	return i.newArray(elements)
+}
+
+// arrayToSorted, arrayToSpliced and arrayWith do what sort, splice and
+// assigning an element do, to a copy of the array.
+func (i *Interpreter) arrayToSorted(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "toSorted")
+	if err != nil {
+		return err
+	}
+	if compareFn := argAt(args, 0); compareFn != UNDEFINED && !isCallable(compareFn) {
+		return newTypeError("The comparison function must be either a function or undefined")
+	}
+	elements, err := i.copyElements(arr)
+	if err != nil {
+		return err
+	}
+	return i.arraySort(i.newArray(elements), args...)
+}
+
+func (i *Interpreter) arrayToSpliced(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "toSpliced")
+	if err != nil {
+		return err
+	}
+	elements, err := i.copyElements(arr)
+	if err != nil {
+		return err
+	}
+	result := i.newArray(elements)
+	if removed := i.arraySplice(result, args...); isError(removed) {
+		return removed
+	}
+	return result
+}
+
+func (i *Interpreter) arrayWith(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "with")
+	if err != nil {
+		return err
+	}
+	relative, err := i.toIntegerOrInfinity(argAt(args, 0))
+	if err != nil {
+		return err
+	}
+	idx := relative
+	if idx < 0 {
+		idx += float64(arr.len())
+	}
+	if idx < 0 || idx >= float64(arr.len()) {
+		return newRangeError("Invalid index : %s", numberToString(relative))
+	}
+	elements, err := i.copyElements(arr)
+	if err != nil {
+		return err
+	}
+	elements[int(idx)] = argAt(args, 1)
+	return i.newArray(elements)
+}
+
+// arrayMap creates a new array of the callback's results. Holes in the
+// original array stay holes in the result.
+func (i *Interpreter) arrayMap(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "map")
+	if err != nil {
+		return err
+	}
+	mapped := i.newArray(nil)
+	mapped.setLength(float64(arr.len()))
+	if err := i.visit(arr, args, func(result Object, idx int) bool {
+		mapped.setElement(int64(idx), result)
+		return false
+	}); err != nil {
+		return err
+	}
+	return mapped
+}
+
+func (i *Interpreter) arrayPop(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "pop")
+	if err != nil {
+		return err
+	}
+	if arr.len() == 0 {
+		return UNDEFINED
+	}
+	last := arr.element(arr.len() - 1)
+	arr.setLength(float64(arr.len() - 1))
+	return last
+}
+
+// arrayPush appends its arguments to the array in place and returns the new
+// length.
+func (i *Interpreter) arrayPush(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "push")
+	if err != nil {
+		return err
+	}
+	if arr.len()+len(args) > maxArrayIndex+1 {
+		return newTypeError("Pushing %d elements on an array-like of length %d is disallowed, as the total surpasses 2**32-1", len(args), arr.len())
+	}
+	if arr.sparse == nil {
+		arr.Elements = append(arr.Elements, args...)
+	} else {
+		for _, arg := range args {
+			arr.setElement(int64(arr.len()), arg)
+		}
+	}
+	return &Number{Value: float64(arr.len())}
+}
+
+// arrayReduce implements reduce and reduceRight. Without an initial value
+// the first element visited becomes the accumulator.
+func (i *Interpreter) arrayReduce(fromEnd bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		arr, err := thisArray(this, "reduce")
+		if err != nil {
+			return err
+		}
+		fn, err := callbackArg(args)
+		if err != nil {
+			return err
+		}
+		var acc Object
+		if len(args) > 1 {
+			acc = args[1]
+		}
+		w := arr.walk(0, arr.len(), fromEnd)
+		for idx, value, ok := w.next(); ok; idx, value, ok = w.next() {
+			if acc == nil {
+				acc = value
+				continue
+			}
+			acc = i.applyFunction(fn, UNDEFINED, []Object{acc, value, &Number{Value: float64(idx)}, arr})
+			if isError(acc) {
+				return acc
+			}
+		}
+		if acc == nil {
+			return newTypeError("Reduce of empty array with no initial value")
+		}
+		return acc
+	}
+}
+
+func (i *Interpreter) arrayReverse(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "reverse")
+	if err != nil {
+		return err
+	}
+	if err := i.charge(arr.count()); err != nil {
+		return err
+	}
+	if arr.sparse != nil {
+		length := arr.len()
+		arr.rearrange(length, func(idx int) int { return length - 1 - idx })
+		return arr
+	}
+	for a, b := 0, len(arr.Elements)-1; a < b; a, b = a+1, b-1 {
+		arr.Elements[a], arr.Elements[b] = arr.Elements[b], arr.Elements[a]
+	}
+	return arr
+}
+
+func (i *Interpreter) arrayShift(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "shift")
+	if err != nil {
+		return err
+	}
+	if arr.len() == 0 {
+		return UNDEFINED
+	}
+	first := arr.element(0)
+	if arr.sparse != nil {
+		if err := i.charge(arr.count()); err != nil {
+			return err
+		}
+		arr.rearrange(arr.len()-1, func(idx int) int { return idx - 1 })
+		return first
+	}
+	arr.Elements[0] = nil
+	arr.Elements = arr.Elements[1:]
+	return first
+}
+
+func (i *Interpreter) arrayUnshift(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "unshift")
+	if err != nil {
+		return err
+	}
+	if arr.len()+len(args) > maxArrayIndex+1 {
+		return newTypeError("Invalid array length")
+	}
+	if err := i.charge(arr.count()); err != nil {
+		return err
+	}
+	if arr.sparse != nil {
+		arr.rearrange(arr.len()+len(args), func(idx int) int { return idx + len(args) })
+		for idx, arg := range args {
+			arr.setElement(int64(idx), arg)
+		}
+		return &Number{Value: float64(arr.len())}
+	}
+	if err := i.allocate((len(args) + len(arr.Elements)) * elementSize); err != nil {
+		return err
+	}
+	arr.Elements = append(append([]Object{}, args...), arr.Elements...)
+	return &Number{Value: float64(len(arr.Elements))}
+}
+
+func (i *Interpreter) arraySlice(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "slice")
+	if err != nil {
+		return err
+	}
+	length := arr.len()
+	start, err := i.relativeIndex(argAt(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+	end, err := i.relativeIndex(argAt(args, 1), length, length)
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+	if err := i.charge(end - start); err != nil {
+		return err
+	}
+	if err := i.allocate((end - start) * elementSize); err != nil {
+		return err
+	}
+	return i.sliceArray(arr, start, end)
+}
+
+// sliceArray copies the elements of arr from start up to end into a new
+// array.
+func (i *Interpreter) sliceArray(arr *Array, start, end int) *Array {
+	if arr.sparse == nil {
+		return i.newArray(append([]Object{}, arr.Elements[start:end]...))
+	}
+	result := i.newArray(nil)
+	result.setLength(float64(end - start))
+	w := arr.walk(start, end, false)
+	for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+		result.setElement(int64(idx-start), e)
+	}
+	return result
+}
+
+// arraySplice removes deleteCount elements starting at start, inserts the
+// remaining arguments in their place, and returns the removed elements.
+func (i *Interpreter) arraySplice(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "splice")
+	if err != nil {
+		return err
+	}
+	length := arr.len()
+	start, err := i.relativeIndex(argAt(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+	deleteCount := 0
+	switch {
+	case len(args) == 0:
+	case len(args) == 1:
+		deleteCount = length - start
+	default:
+		n, err := i.toIntegerOrInfinity(args[1])
+		if err != nil {
+			return err
+		}
+		deleteCount = int(math.Min(math.Max(n, 0), float64(length-start)))
+	}
+
+	var items []Object
+	if len(args) > 2 {
+		items = args[2:]
+	}
+	if length-deleteCount+len(items) > maxArrayIndex+1 {
+		return newTypeError("Invalid array length")
+	}
+	if err := i.charge(arr.count()); err != nil {
+		return err
+	}
+	if err := i.allocate((length + len(items)) * elementSize); err != nil {
+		return err
+	}
+	removed := i.sliceArray(arr, start, start+deleteCount)
+	if arr.sparse != nil {
+		shift := len(items) - deleteCount
+		arr.rearrange(length+shift, func(idx int) int {
+			switch {
+			case idx < start:
+				return idx
+			case idx < start+deleteCount:
+				return -1
+			}
+			return idx + shift
+		})
+		for idx, item := range items {
+			arr.setElement(int64(start+idx), item)
+		}
+		return removed
+	}
+	elements := make([]Object, 0, length-deleteCount+len(items))
+	elements = append(elements, arr.Elements[:start]...)
+	elements = append(elements, items...)
+	elements = append(elements, arr.Elements[start+deleteCount:]...)
+	arr.Elements = elements
+	return removed
+}
+
+// arraySort sorts the array in place. Without a comparator, elements are
+// compared as strings, which is why [10, 9, 1].sort() gives [1, 10, 9].
+// undefined is never compared: it goes after everything else, and holes go
+// last of all. The sort is stable.
+func (i *Interpreter) arraySort(this Object, args ...Object) Object {
+	arr, err := thisArray(this, "sort")
+	if err != nil {
+		return err
+	}
+	compareFn := argAt(args, 0)
+	if compareFn != UNDEFINED && !isCallable(compareFn) {
+		return newTypeError("The comparison function must be either a function or undefined")
+	}
+
+	if err := i.allocate(arr.count() * elementSize); err != nil {
+		return err
+	}
+	values := make([]Object, 0, arr.count())
+	undefineds := 0
+	w := arr.walk(0, arr.len(), false)
+	for _, e, ok := w.next(); ok; _, e, ok = w.next() {
+		if e == UNDEFINED {
+			undefineds++
+			continue
+		}
+		values = append(values, e)
+	}
+
+	// Without a comparator the elements are compared by their string forms,
+	// which are worked out once up front.
+	var keys map[Object][]uint16
+	if compareFn == UNDEFINED {
+		keys = make(map[Object][]uint16, len(values))
+		for _, v := range values {
+			if err := i.tick(); err != nil {
+				return err
+			}
+			s, err := i.toString(v)
+			if err != nil {
+				return err
+			}
+			keys[v] = s.units()
+		}
+	}
+
+	var sortErr *Error
+	sort.SliceStable(values, func(a, b int) bool {
+		if sortErr != nil {
+			return false
+		}
+		if err := i.tick(); err != nil {
+			sortErr = err
+			return false
+		}
+		if compareFn == UNDEFINED {
+			return compareUnits(keys[values[a]], keys[values[b]]) < 0
+		}
+		result := i.applyFunction(compareFn, UNDEFINED, []Object{values[a], values[b]})
+		if err, ok := result.(*Error); ok {
+			sortErr = err
+			return false
+		}
+		n, err := i.toNumber(result)
+		if err != nil {
+			sortErr = err
+			return false
+		}
+		return n < 0
+	})
+	if sortErr != nil {
+		return sortErr
+	}
+	for range undefineds {
+		values = append(values, UNDEFINED)
+	}
+
+	if arr.sparse != nil {
+		arr.reset(arr.len())
+		for idx, v := range values {
+			arr.setElement(int64(idx), v)
+		}
+		return arr
+	}
+	for idx := range arr.Elements {
+		if idx < len(values) {
+			arr.Elements[idx] = values[idx]
+		} else {
+			arr.Elements[idx] = nil
+		}
+	}
+	return arr
+}
+
+// sameValueZero is the comparison used by includes: like ===, except that
+// NaN equals itself.
+func sameValueZero(left, right Object) bool {
+	if l, ok := left.(*Number); ok && math.IsNaN(l.Value) {
+		r, ok := right.(*Number)
+		return ok && math.IsNaN(r.Value)
+	}
+	return strictEquals(left, right)
+}
+
+// inspectReceiver describes the "this" value of a failed method call.
+func inspectReceiver(this Object) string {
+	if this == nil {
+		return "null"
+	}
+	return this.Inspect()
+}
@@ -0,0 +1,412 @@
+package interpreter
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// ArrayBuffer is a block of raw bytes. Scripts can't read or write it
+// directly; they go through a typed array or a DataView, which interpret
+// the bytes as numbers.
+//
+// A buffer created with a maxByteLength option is resizable: resize grows
+// or shrinks it in place, up to that maximum, and the views over it see
+// the change. transfer moves the bytes to a new buffer and detaches the
+// old one, leaving it, and every view over it, with no bytes at all.
+type ArrayBuffer struct {
+	Hash
+	data          []byte
+	maxByteLength int
+	resizable     bool
+	detached      bool
+}
+
+// maxArrayBufferLength is the largest buffer scripts can allocate, so
+// that new ArrayBuffer(2 ** 40) fails cleanly rather than exhausting
+// memory.
+const maxArrayBufferLength = 1 << 30
+
+func (ab *ArrayBuffer) Type() ObjectType { return ARRAY_BUFFER_OBJ }
+func (ab *ArrayBuffer) Inspect() string {
+	return fmt.Sprintf("ArrayBuffer {byteLength: %d}", len(ab.data))
+}
+
+// accessor returns the value of the accessor properties buffers inherit
+// from ArrayBuffer.prototype.
+func (ab *ArrayBuffer) accessor(name string) (Object, bool) {
+	switch name {
+	case "byteLength":
+		return &Number{Value: float64(len(ab.data))}, true
+	case "maxByteLength":
+		if ab.resizable {
+			return &Number{Value: float64(ab.maxByteLength)}, true
+		}
+		return &Number{Value: float64(len(ab.data))}, true
+	case "resizable":
+		return nativeBoolToBooleanObject(ab.resizable), true
+	case "detached":
+		return nativeBoolToBooleanObject(ab.detached), true
+	}
+	return nil, false
+}
+
+// detach empties the buffer for good, as transfer does to the buffer it
+// moves the bytes out of.
+func (ab *ArrayBuffer) detach() {
+	ab.data = nil
+	ab.detached = true
+}
+
+// newArrayBuffer creates a zero-filled buffer of length bytes. A
+// maxByteLength of -1 makes a fixed-length buffer, and any other value a
+// resizable one.
+func (i *Interpreter) newArrayBuffer(length, maxByteLength int) (*ArrayBuffer, *Error) {
+	if length > maxArrayBufferLength || maxByteLength > maxArrayBufferLength {
+		return nil, newRangeError("Array buffer allocation failed")
+	}
+	if err := i.allocate(length); err != nil {
+		return nil, err
+	}
+	ab := &ArrayBuffer{Hash: Hash{Prototype: i.arrayBufferPrototype}, data: make([]byte, length)}
+	if maxByteLength >= 0 {
+		ab.resizable = true
+		ab.maxByteLength = maxByteLength
+	}
+	return ab, nil
+}
+
+// toIndex converts a value to a byte offset or length, which must be a
+// whole number between 0 and 2^53-1. Anything else is a RangeError with
+// the given message. undefined becomes 0.
+func (i *Interpreter) toIndex(v Object, format string, args ...any) (int, *Error) {
+	n, err := i.toIntegerOrInfinity(v)
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 || n > maxSafeInteger {
+		return 0, newRangeError(format, args...)
+	}
+	return int(n), nil
+}
+
+// thisArrayBuffer checks that an ArrayBuffer.prototype method was called
+// on a buffer, and one that hasn't been detached.
+func thisArrayBuffer(this Object, method string) (*ArrayBuffer, *Error) {
+	ab, ok := this.(*ArrayBuffer)
+	if !ok {
+		return nil, newTypeError("Method ArrayBuffer.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+	}
+	if ab.detached {
+		return nil, newTypeError("Cannot perform ArrayBuffer.prototype.%s on a detached ArrayBuffer", method)
+	}
+	return ab, nil
+}
+
+// setupArrayBuffer creates ArrayBuffer.prototype and the ArrayBuffer
+// constructor.
+func (i *Interpreter) setupArrayBuffer() {
+	proto := i.arrayBufferPrototype
+
+	i.defineMethod(proto, "slice", func(this Object, args ...Object) Object {
+		ab, err := thisArrayBuffer(this, "slice")
+		if err != nil {
+			return err
+		}
+		length := len(ab.data)
+		start, err := i.relativeIndex(argAt(args, 0), length, 0)
+		if err != nil {
+			return err
+		}
+		end, err := i.relativeIndex(argAt(args, 1), length, length)
+		if err != nil {
+			return err
+		}
+		if ab.detached {
+			return newTypeError("Cannot perform ArrayBuffer.prototype.slice on a detached ArrayBuffer")
+		}
+		// The arguments may have shrunk the buffer.
+		end = min(end, len(ab.data))
+		out, err := i.newArrayBuffer(max(end-start, 0), -1)
+		if err != nil {
+			return err
+		}
+		if start < end {
+			copy(out.data, ab.data[start:end])
+		}
+		return out
+	})
+	i.defineMethod(proto, "resize", func(this Object, args ...Object) Object {
+		ab, ok := this.(*ArrayBuffer)
+		if !ok || !ab.resizable {
+			return newTypeError("Method ArrayBuffer.prototype.resize called on incompatible receiver %s", inspectReceiver(this))
+		}
+		length, err := i.toIndex(argAt(args, 0), "ArrayBuffer.prototype.resize: Invalid length parameter")
+		if err != nil {
+			return err
+		}
+		if ab.detached {
+			return newTypeError("Cannot perform ArrayBuffer.prototype.resize on a detached ArrayBuffer")
+		}
+		if length > ab.maxByteLength {
+			return newRangeError("ArrayBuffer.prototype.resize: Invalid length parameter")
+		}
+		if length <= len(ab.data) {
+			// Clear the bytes cut off, so that growing the buffer again
+			// brings back zeros rather than the old contents.
+			clear(ab.data[length:])
+			ab.data = ab.data[:length]
+		} else {
+			if err := i.allocate(length - len(ab.data)); err != nil {
+				return err
+			}
+			ab.data = append(ab.data, make([]byte, length-len(ab.data))...)
+		}
+		return UNDEFINED
+	})
+	transfer := func(method string, keepResizable bool) BuiltinFunction {
+		return func(this Object, args ...Object) Object {
+			ab, err := thisArrayBuffer(this, method)
+			if err != nil {
+				return err
+			}
+			length := len(ab.data)
+			if argAt(args, 0) != UNDEFINED {
+				if length, err = i.toIndex(args[0], "Invalid array buffer length"); err != nil {
+					return err
+				}
+			}
+			maxByteLength := -1
+			if keepResizable && ab.resizable {
+				maxByteLength = ab.maxByteLength
+				if length > maxByteLength {
+					return newRangeError("ArrayBuffer.prototype.%s: Invalid length parameter", method)
+				}
+			}
+			out, err := i.newArrayBuffer(length, maxByteLength)
+			if err != nil {
+				return err
+			}
+			copy(out.data, ab.data)
+			ab.detach()
+			return out
+		}
+	}
+	i.defineMethod(proto, "transfer", transfer("transfer", true))
+	i.defineMethod(proto, "transferToFixedLength", transfer("transferToFixedLength", false))
+	proto.Set(symbolToStringTag, &String{Value: "ArrayBuffer"})
+
+	ctor := i.newBuiltin("ArrayBuffer", func(this Object, args ...Object) Object {
+		length, err := i.toIndex(argAt(args, 0), "Invalid array buffer length")
+		if err != nil {
+			return err
+		}
+		maxByteLength := -1
+		if options := argAt(args, 1); isObject(options) {
+			value := i.getProperty(options, &String{Value: "maxByteLength"})
+			if isError(value) {
+				return value
+			}
+			if value != UNDEFINED {
+				if maxByteLength, err = i.toIndex(value, "Invalid array buffer max length"); err != nil {
+					return err
+				}
+				if length > maxByteLength {
+					return newRangeError("Invalid array buffer max length")
+				}
+			}
+		}
+		ab, err := i.newArrayBuffer(length, maxByteLength)
+		if err != nil {
+			return err
+		}
+		return ab
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.defineMethod(&ctor.Hash, "isView", func(this Object, args ...Object) Object {
+		switch argAt(args, 0).(type) {
+		case *TypedArray, *DataView:
+			return TRUE
+		}
+		return FALSE
+	})
+	i.env.Set("ArrayBuffer", ctor)
+}
+
+// DataView reads and writes numbers of any type at any byte offset of a
+// buffer, in either byte order, which is what parsing a binary file format
+// or a network protocol takes:
+//
+//	const view = new DataView(buffer);
+//	view.getUint16(0);        // big-endian, the default
+//	view.getUint16(0, true);  // little-endian
+//
+// Like a typed array, a DataView over a resizable buffer without an
+// explicit length tracks the buffer's size.
+type DataView struct {
+	Hash
+	buffer     *ArrayBuffer
+	byteOffset int
+	byteLength int
+	tracking   bool
+}
+
+func (dv *DataView) Type() ObjectType { return DATA_VIEW_OBJ }
+func (dv *DataView) Inspect() string {
+	return fmt.Sprintf("DataView {byteLength: %d, byteOffset: %d}", dv.len(), dv.byteOffset)
+}
+
+// outOfBounds reports whether the view no longer fits in its buffer.
+func (dv *DataView) outOfBounds() bool {
+	if dv.buffer.detached {
+		return true
+	}
+	if dv.tracking {
+		return dv.byteOffset > len(dv.buffer.data)
+	}
+	return dv.byteOffset+dv.byteLength > len(dv.buffer.data)
+}
+
+// len returns the number of bytes in the view, which is 0 for an
+// out-of-bounds view.
+func (dv *DataView) len() int {
+	if dv.outOfBounds() {
+		return 0
+	}
+	if dv.tracking {
+		return len(dv.buffer.data) - dv.byteOffset
+	}
+	return dv.byteLength
+}
+
+// accessor returns the value of the accessor properties views inherit from
+// DataView.prototype. The sizes of a view that no longer fits its buffer
+// can't be read.
+func (dv *DataView) accessor(name string) (Object, bool) {
+	switch name {
+	case "buffer":
+		return dv.buffer, true
+	case "byteLength", "byteOffset":
+		if dv.buffer.detached {
+			return newTypeError("Cannot perform DataView.prototype.%s on a detached ArrayBuffer", name), true
+		}
+		if dv.outOfBounds() {
+			return newTypeError("Cannot perform DataView.prototype.%s on an out of bounds DataView", name), true
+		}
+		if name == "byteOffset" {
+			return &Number{Value: float64(dv.byteOffset)}, true
+		}
+		return &Number{Value: float64(dv.len())}, true
+	}
+	return nil, false
+}
+
+// dataViewBytes checks the arguments of a DataView get or set method and
+// returns the bytes it reads or writes.
+func (i *Interpreter) dataViewBytes(this Object, method string, offsetArg Object, size int) ([]byte, *Error) {
+	dv, ok := this.(*DataView)
+	if !ok {
+		return nil, newTypeError("Method DataView.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+	}
+	offset, err := i.toIndex(offsetArg, "Offset is outside the bounds of the DataView")
+	if err != nil {
+		return nil, err
+	}
+	if dv.buffer.detached {
+		return nil, newTypeError("Cannot perform DataView.prototype.%s on a detached ArrayBuffer", method)
+	}
+	if offset+size > dv.len() {
+		return nil, newRangeError("Offset is outside the bounds of the DataView")
+	}
+	start := dv.byteOffset + offset
+	return dv.buffer.data[start : start+size], nil
+}
+
+// byteOrder returns the byte order a DataView method's littleEndian
+// argument asks for.
+func byteOrder(littleEndian Object) binary.ByteOrder {
+	if isTruthy(littleEndian) {
+		return binary.LittleEndian
+	}
+	return binary.BigEndian
+}
+
+// setupDataView creates DataView.prototype, with a get and a set method
+// for each element type, and the DataView constructor.
+func (i *Interpreter) setupDataView() {
+	proto := i.dataViewPrototype
+
+	for _, t := range elementTypes {
+		if t.name == "Uint8Clamped" {
+			continue
+		}
+		getter, setter := "get"+t.name, "set"+t.name
+		i.defineMethod(proto, getter, func(this Object, args ...Object) Object {
+			b, err := i.dataViewBytes(this, getter, argAt(args, 0), t.size)
+			if err != nil {
+				return err
+			}
+			return t.read(b, byteOrder(argAt(args, 1)))
+		})
+		i.defineMethod(proto, setter, func(this Object, args ...Object) Object {
+			if _, ok := this.(*DataView); !ok {
+				return newTypeError("Method DataView.prototype.%s called on incompatible receiver %s", setter, inspectReceiver(this))
+			}
+			value, err := i.convertElement(t, argAt(args, 1))
+			if err != nil {
+				return err
+			}
+			b, err := i.dataViewBytes(this, setter, argAt(args, 0), t.size)
+			if err != nil {
+				return err
+			}
+			t.write(b, value, byteOrder(argAt(args, 2)))
+			return UNDEFINED
+		})
+	}
+	proto.Set(symbolToStringTag, &String{Value: "DataView"})
+
+	ctor := i.newBuiltin("DataView", func(this Object, args ...Object) Object {
+		buffer, ok := argAt(args, 0).(*ArrayBuffer)
+		if !ok {
+			return newTypeError("First argument to DataView constructor must be an ArrayBuffer")
+		}
+		offsetArg := argAt(args, 1)
+		offset, err := i.toIndex(offsetArg, "Start offset %s is outside the bounds of the buffer", offsetArg.Inspect())
+		if err != nil {
+			return err
+		}
+		lengthArg := argAt(args, 2)
+		length := 0
+		if lengthArg != UNDEFINED {
+			if length, err = i.toIndex(lengthArg, "Invalid DataView length %s", lengthArg.Inspect()); err != nil {
+				return err
+			}
+		}
+		if buffer.detached {
+			return newTypeError("Cannot perform DataView constructor on a detached ArrayBuffer")
+		}
+		size := len(buffer.data)
+		if offset > size {
+			return newRangeError("Start offset %d is outside the bounds of the buffer", offset)
+		}
+		dv := &DataView{Hash: Hash{Prototype: i.dataViewPrototype}, buffer: buffer, byteOffset: offset}
+		switch {
+		case lengthArg == UNDEFINED && buffer.resizable:
+			dv.tracking = true
+		case lengthArg == UNDEFINED:
+			dv.byteLength = size - offset
+		default:
+			if offset+length > size {
+				return newRangeError("Invalid DataView length %d", length)
+			}
+			dv.byteLength = length
+		}
+		return dv
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("DataView", ctor)
+}
@@ -0,0 +1,497 @@
+package interpreter
+
+import (
+	"runtime"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+)
+
+// An async function returns a promise for what its body returns, and its
+// body can wait for other promises with await:
+//
+//	async function total(ids) {
+//	  let sum = 0;
+//	  for (const id of ids) sum += await price(id);
+//	  return sum;
+//	}
+//
+// Waiting doesn't hold anything else up. The body runs until the first
+// await, and then the call returns its promise, pending, to the caller.
+// Once the awaited promise settles, a microtask resumes the body where it
+// left off: await evaluates to the value the promise was fulfilled with, or
+// throws the reason it was rejected with.
+//
+// Pausing in the middle of a body is what generators do at yield, so async
+// bodies run on a coroutine as generator bodies do (see generator.go).
+// When the body awaits, it hands the caller the value to await rather than
+// a value to yield, and the caller registers callbacks on it that resume
+// the body. Async generators combine the two: they can both await and
+// yield, and next returns a promise for each { value, done } result.
+
+// asyncBody is an async function call or module whose body is running or
+// paused at an await.
+type asyncBody struct {
+	co *coroutine
+
+	// done is called with the result of the body once it finishes: the
+	// value it returns, or an error.
+	done func(result Object)
+}
+
+// setupAsync creates %AsyncIteratorPrototype%, which makes async iterators
+// async iterable, and %AsyncGeneratorPrototype%, which holds the methods of
+// every async generator object.
+func (i *Interpreter) setupAsync() {
+	i.asyncIteratorPrototype.Set(symbolAsyncIterator, i.newBuiltin("[Symbol.asyncIterator]", func(this Object, args ...Object) Object {
+		return this
+	}))
+
+	proto := i.asyncGeneratorPrototype
+	method := func(name string, mode resumeMode) {
+		i.defineMethod(proto, name, func(this Object, args ...Object) Object {
+			g, ok := this.(*AsyncGenerator)
+			if !ok {
+				// Like every other failure of an async generator, a
+				// wrong receiver rejects the promise returned.
+				p := i.newPromise()
+				err := newTypeError("%s method called on incompatible receiver %s", name, inspectReceiver(this))
+				i.rejectPromise(p, i.thrownValue(err))
+				return p
+			}
+			return i.enqueueAsyncGenerator(g, resumption{mode: mode, value: argAt(args, 0)})
+		})
+	}
+	method("next", resumeNext)
+	method("return", resumeReturn)
+	method("throw", resumeThrow)
+	proto.Set(symbolToStringTag, &String{Value: "AsyncGenerator"})
+}
+
+// newAsyncFunction creates the function object for an async function or an
+// async arrow function. Async functions can't be used with new, so they
+// don't get a "prototype" property.
+func (i *Interpreter) newAsyncFunction(name string, params []ast.Expression, body *ast.BlockStatement, env *Environment, arrow bool) *Function {
+	return &Function{
+		Hash:       Hash{Prototype: i.functionPrototype},
+		Name:       name,
+		Parameters: params,
+		Body:       body,
+		Env:        env,
+		Arrow:      arrow,
+		Async:      true,
+	}
+}
+
+// callAsyncFunction implements calling an async function. The body runs
+// until it first awaits, and the promise returned settles once the body
+// finishes: fulfilled with what it returns, or rejected with what it
+// throws. A failure binding the arguments rejects the promise too.
+func (i *Interpreter) callAsyncFunction(fn *Function, this Object, args []Object) Object {
+	promise := i.newPromise()
+	env, err := i.extendFunctionEnv(fn, this, args)
+	if err != nil {
+		i.rejectPromise(promise, i.thrownValue(err))
+		return promise
+	}
+	body := fn.Body
+	i.startAsync(func() Object {
+		return bodyResult(i.evalBlockStatement(body, env))
+	}, func(result Object) {
+		if err, ok := result.(*Error); ok {
+			i.rejectPromise(promise, i.thrownValue(err))
+			return
+		}
+		i.resolvePromise(promise, result)
+	})
+	return promise
+}
+
+// startAsync starts running body as an async body, and calls done with its
+// result once it finishes.
+//
+// Once the body is paused, only the callbacks waiting for the awaited
+// promise refer to the asyncBody, so if that promise can never settle,
+// the asyncBody is garbage collected and its goroutine stopped, as with a
+// generator that is dropped half-way.
+func (i *Interpreter) startAsync(body func() Object, done func(result Object)) {
+	a := &asyncBody{co: newCoroutine(body), done: done}
+	runtime.AddCleanup(a, (*coroutine).abandon, a.co)
+	i.continueAsync(a, resumption{mode: resumeNext, value: UNDEFINED})
+}
+
+// continueAsync resumes an async body and runs it until it awaits again or
+// finishes.
+func (i *Interpreter) continueAsync(a *asyncBody, r resumption) {
+	step := i.resumeCoroutine(a.co, r)
+	if step.awaiting {
+		i.onSettled(step.value, func(r resumption) { i.continueAsync(a, r) })
+		return
+	}
+	a.done(step.value)
+}
+
+// onSettled arranges for resume to be called once value settles: with the
+// value a promise is fulfilled with, or with a throw of the reason it's
+// rejected with. A value that isn't a promise counts as one fulfilled with
+// it. resume is always called from a microtask, never straight away, even
+// if value has already settled.
+func (i *Interpreter) onSettled(value Object, resume func(resumption)) {
+	promise := i.promiseResolve(i.promiseConstructor, value)
+	if err, ok := promise.(*Error); ok {
+		i.queueMicrotask(func() *Error {
+			resume(resumption{mode: resumeThrow, value: i.thrownValue(err)})
+			return nil
+		})
+		return
+	}
+	onFulfilled := i.newBuiltin("", func(this Object, args ...Object) Object {
+		resume(resumption{mode: resumeNext, value: argAt(args, 0)})
+		return UNDEFINED
+	})
+	onRejected := i.newBuiltin("", func(this Object, args ...Object) Object {
+		resume(resumption{mode: resumeThrow, value: argAt(args, 0)})
+		return UNDEFINED
+	})
+	i.performPromiseThen(promise.(*Promise), onFulfilled, onRejected, nil)
+}
+
+// evalAwaitExpression evaluates await value.
+func (i *Interpreter) evalAwaitExpression(node *ast.AwaitExpression, env *Environment) Object {
+	value := i.eval(node.Argument, env)
+	if isError(value) {
+		return value
+	}
+	return i.await(value)
+}
+
+// await pauses the running async body until value settles, and returns the
+// value it's fulfilled with, or the reason it's rejected with as an error.
+func (i *Interpreter) await(value Object) Object {
+	if i.running == nil {
+		return newSyntaxError("await is only valid in async functions and the top level bodies of modules")
+	}
+	r := i.pause(generatorStep{value: value, awaiting: true})
+	if r.mode == resumeThrow {
+		return thrownError(r.value)
+	}
+	return r.value
+}
+
+// AsyncGenerator is the object returned by calling an async generator
+// function. Its next, return and throw methods return promises, and can be
+// called again before those settle: the requests wait in a queue and are
+// served one at a time, in order.
+type AsyncGenerator struct {
+	Hash
+	co    *coroutine
+	state generatorState
+	queue []asyncGeneratorRequest
+}
+
+func (g *AsyncGenerator) Type() ObjectType { return ASYNC_GENERATOR_OBJ }
+func (g *AsyncGenerator) Inspect() string  { return "Object [AsyncGenerator] {}" }
+
+// asyncGeneratorRequest is a call of next, return or throw waiting to be
+// served, with the promise it returned.
+type asyncGeneratorRequest struct {
+	resumption
+	promise *Promise
+}
+
+// newAsyncGenerator creates the async generator for a call of an async
+// generator function whose arguments are already bound in env.
+func (i *Interpreter) newAsyncGenerator(proto *Hash, body *ast.BlockStatement, env *Environment) *AsyncGenerator {
+	co := newCoroutine(func() Object {
+		result := i.evalBlockStatement(body, env)
+		if r, ok := result.(*ReturnValue); ok {
+			// return value awaits value in an async generator.
+			return i.await(r.Value)
+		}
+		return bodyResult(result)
+	})
+	co.asyncGenerator = true
+	g := &AsyncGenerator{Hash: Hash{Prototype: proto}, co: co}
+	runtime.AddCleanup(g, (*coroutine).abandon, co)
+	return g
+}
+
+// enqueueAsyncGenerator implements next, return and throw, which queue a
+// request and return a promise for its { value, done } result. Unless the
+// body is already busy with an earlier request, it starts on this one.
+func (i *Interpreter) enqueueAsyncGenerator(g *AsyncGenerator, r resumption) Object {
+	p := i.newPromise()
+	g.queue = append(g.queue, asyncGeneratorRequest{resumption: r, promise: p})
+	if g.state != generatorExecuting {
+		i.resumeAsyncGenerator(g)
+	}
+	return p
+}
+
+// resumeAsyncGenerator serves the requests waiting in g's queue until the
+// queue is empty or the body is busy with one of them.
+func (i *Interpreter) resumeAsyncGenerator(g *AsyncGenerator) {
+	for len(g.queue) > 0 {
+		req := g.queue[0]
+		if g.state == generatorSuspendedStart && req.mode != resumeNext {
+			// As with a generator, returning from or throwing into an
+			// async generator that never started finishes it.
+			g.state = generatorCompleted
+		}
+		if g.state != generatorCompleted {
+			g.state = generatorExecuting
+			i.asyncGeneratorStep(g, i.resumeCoroutine(g.co, req.resumption))
+			return
+		}
+
+		switch req.mode {
+		case resumeReturn:
+			// The argument of return is awaited, and the generator
+			// stays busy until it settles.
+			g.state = generatorExecuting
+			i.onSettled(req.value, func(r resumption) {
+				g.state = generatorCompleted
+				if r.mode == resumeThrow {
+					i.completeAsyncGeneratorRequest(g, thrownError(r.value), true)
+				} else {
+					i.completeAsyncGeneratorRequest(g, r.value, true)
+				}
+				i.resumeAsyncGenerator(g)
+			})
+			return
+		case resumeThrow:
+			i.completeAsyncGeneratorRequest(g, thrownError(req.value), true)
+		default:
+			i.completeAsyncGeneratorRequest(g, UNDEFINED, true)
+		}
+	}
+}
+
+// asyncGeneratorStep handles what g's body handed back when it paused: a
+// value to await, after which the body goes on, or a value yielded or
+// returned, which completes the request at the head of the queue.
+func (i *Interpreter) asyncGeneratorStep(g *AsyncGenerator, step generatorStep) {
+	if step.awaiting {
+		i.onSettled(step.value, func(r resumption) {
+			i.asyncGeneratorStep(g, i.resumeCoroutine(g.co, r))
+		})
+		return
+	}
+	g.state = generatorSuspendedYield
+	if step.done {
+		g.state = generatorCompleted
+	}
+	i.completeAsyncGeneratorRequest(g, step.value, step.done)
+	i.resumeAsyncGenerator(g)
+}
+
+// completeAsyncGeneratorRequest settles the promise of the request at the
+// head of g's queue and takes the request off the queue. The promise is
+// rejected if result is an error, and otherwise fulfilled with
+// { value: result, done }.
+func (i *Interpreter) completeAsyncGeneratorRequest(g *AsyncGenerator, result Object, done bool) {
+	req := g.queue[0]
+	g.queue = g.queue[1:]
+	if err, ok := result.(*Error); ok {
+		i.rejectPromise(req.promise, i.thrownValue(err))
+		return
+	}
+	i.resolvePromise(req.promise, i.iteratorResult(result, done))
+}
+
+// asyncSuspend pauses an async generator's body at a yield, as suspend
+// does for a generator. Returning from an async generator awaits the value
+// returned, so a return(value) it's resumed with has value awaited.
+func (i *Interpreter) asyncSuspend(value Object) resumption {
+	r := i.suspend(value)
+	if r.mode == resumeReturn {
+		awaited := i.await(r.value)
+		if err, ok := awaited.(*Error); ok {
+			return resumption{mode: resumeThrow, value: i.thrownValue(err)}
+		}
+		r.value = awaited
+	}
+	return r
+}
+
+// getAsyncIterator returns the async iterator that for await...of uses to
+// iterate over value: the one its [Symbol.asyncIterator] method creates,
+// or failing that, its ordinary iterator, adapted to return promises.
+func (i *Interpreter) getAsyncIterator(value Object) (*iterator, *Error) {
+	if isNullish(value) {
+		return nil, newTypeError("%s is not async iterable", inspect(value, 0))
+	}
+	method := i.getProperty(value, symbolAsyncIterator)
+	if err, ok := method.(*Error); ok {
+		return nil, err
+	}
+	if isNullish(method) {
+		syncMethod := i.getProperty(value, symbolIterator)
+		if err, ok := syncMethod.(*Error); ok {
+			return nil, err
+		}
+		if !isCallable(syncMethod) {
+			return nil, newTypeError("%s is not async iterable", inspect(value, 0))
+		}
+		it, err := i.getIterator(value)
+		if err != nil {
+			return nil, err
+		}
+		return i.asyncFromSyncIterator(it), nil
+	}
+	if !isCallable(method) {
+		return nil, newTypeError("%s is not async iterable", inspect(value, 0))
+	}
+	object := i.applyFunction(method, value, nil)
+	if err, ok := object.(*Error); ok {
+		return nil, err
+	}
+	if !isObject(object) {
+		return nil, newTypeError("Result of the Symbol.asyncIterator method is not an object")
+	}
+	next := i.getProperty(object, &String{Value: "next"})
+	if err, ok := next.(*Error); ok {
+		return nil, err
+	}
+	return &iterator{object: object, next: next}, nil
+}
+
+// asyncFromSyncIterator wraps an ordinary iterator as an async one. Its
+// methods call those of it and return promises for their results, with
+// each value awaited, so iterating asynchronously over an array of
+// promises gives the values the promises are fulfilled with.
+func (i *Interpreter) asyncFromSyncIterator(it *iterator) *iterator {
+	obj := NewHash(i.asyncIteratorPrototype)
+
+	// continuation turns the result of a method of it into the promise the
+	// async method returns.
+	continuation := func(result Object, err *Error) Object {
+		p := i.newPromise()
+		if err == nil {
+			var done bool
+			var value Object
+			if done, err = i.iteratorComplete(result); err == nil {
+				value, err = i.iteratorValue(result)
+			}
+			if err == nil {
+				valuePromise := i.promiseResolve(i.promiseConstructor, value)
+				if e, ok := valuePromise.(*Error); ok {
+					err = e
+				} else {
+					resolve, reject := i.resolvingFunctions(p)
+					unwrap := i.newBuiltin("", func(this Object, args ...Object) Object {
+						return i.iteratorResult(argAt(args, 0), done)
+					})
+					capability := &promiseCapability{promise: p, resolve: resolve, reject: reject}
+					i.performPromiseThen(valuePromise.(*Promise), unwrap, UNDEFINED, capability)
+				}
+			}
+		}
+		if err != nil {
+			i.rejectPromise(p, i.thrownValue(err))
+		}
+		return p
+	}
+
+	next := i.newBuiltin("next", func(this Object, args ...Object) Object {
+		return continuation(i.iteratorNext(it, args...))
+	})
+	obj.Set(&String{Value: "next"}, next)
+	i.defineMethod(obj, "return", func(this Object, args ...Object) Object {
+		method := i.getProperty(it.object, &String{Value: "return"})
+		if err, ok := method.(*Error); ok {
+			return continuation(nil, err)
+		}
+		if isNullish(method) {
+			return continuation(i.iteratorResult(argAt(args, 0), true), nil)
+		}
+		return continuation(i.checkIteratorResult(i.applyFunction(method, it.object, args)))
+	})
+	i.defineMethod(obj, "throw", func(this Object, args ...Object) Object {
+		method := i.getProperty(it.object, &String{Value: "throw"})
+		if err, ok := method.(*Error); ok {
+			return continuation(nil, err)
+		}
+		if isNullish(method) {
+			// The iterator can't be told about the error, so it's
+			// closed, and the caller told it didn't get through.
+			if err := i.iteratorClose(it); err != nil {
+				return continuation(nil, err)
+			}
+			return continuation(nil, newTypeError("The iterator does not provide a 'throw' method"))
+		}
+		return continuation(i.checkIteratorResult(i.applyFunction(method, it.object, args)))
+	})
+
+	return &iterator{object: obj, next: next}
+}
+
+// asyncIteratorCall calls method of an async iterator and awaits the
+// { value, done } result it returns a promise for.
+func (i *Interpreter) asyncIteratorCall(it *iterator, method Object, args ...Object) (Object, *Error) {
+	if !isCallable(method) {
+		return nil, newTypeError("%s is not a function", inspect(method, 0))
+	}
+	result := i.applyFunction(method, it.object, args)
+	if err, ok := result.(*Error); ok {
+		return nil, err
+	}
+	return i.checkIteratorResult(i.await(result))
+}
+
+// asyncIteratorClose closes an async iterator by calling its return
+// method, if it has one, and waiting for it to finish.
+func (i *Interpreter) asyncIteratorClose(it *iterator) *Error {
+	method := i.getProperty(it.object, &String{Value: "return"})
+	if err, ok := method.(*Error); ok {
+		return err
+	}
+	if isNullish(method) {
+		return nil
+	}
+	_, err := i.asyncIteratorCall(it, method)
+	return err
+}
+
+// evalForAwaitOfStatement runs the body of a for await...of loop once for
+// every value of an async iterable, waiting for each. Leaving the loop
+// early closes the iterator, as for...of does.
+func (i *Interpreter) evalForAwaitOfStatement(node *ast.ForOfStatement, iterable Object, env *Environment) Object {
+	it, err := i.getAsyncIterator(iterable)
+	if err != nil {
+		return err
+	}
+	for {
+		result, err := i.asyncIteratorCall(it, it.next)
+		if err != nil {
+			return err
+		}
+		done, err := i.iteratorComplete(result)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		value, err := i.iteratorValue(result)
+		if err != nil {
+			return err
+		}
+		iterEnv := NewEnvironment(env)
+		if err := i.bindForOfValue(node.Left, value, iterEnv); err != nil {
+			return i.closeAsyncIterator(it, err)
+		}
+		result = i.eval(node.Body, iterEnv)
+		if stop, value := loopControl(result); stop {
+			return i.closeAsyncIterator(it, value)
+		}
+	}
+}
+
+// closeAsyncIterator is closeIterator for an async iterator.
+func (i *Interpreter) closeAsyncIterator(it *iterator, completion Object) Object {
+	err := i.asyncIteratorClose(it)
+	if err != nil && !isError(completion) {
+		return err
+	}
+	return completion
+}
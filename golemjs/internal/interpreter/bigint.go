@@ -0,0 +1,417 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+	"strings"
+)
+
+// BigInt represents JavaScript's arbitrary-precision integers, written
+// with an n suffix: 123n. Numbers lose precision past 2^53, but BigInts
+// don't, so 9007199254740993n is exact where 9007199254740993 reads back
+// as 9007199254740992.
+//
+// BigInts and numbers are different types that never mix implicitly:
+// 1n + 1 is a TypeError, because there is no way to add them without
+// losing either the fraction or the precision. Comparisons are the
+// exception, as they can be done exactly, so 1n < 1.5 and 1n == 1 are both
+// true (but 1n === 1 is false). Conversions are explicit, with BigInt(1)
+// and Number(1n).
+type BigInt struct {
+	Value *big.Int
+}
+
+// maxBigIntBits is the largest size of a BigInt, in bits.
+const maxBigIntBits = 1 << 30
+
+func (b *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (b *BigInt) Inspect() string  { return b.Value.String() + "n" }
+
+// toNumeric converts a value to either a number or a BigInt, the two kinds
+// of value arithmetic works on. Objects are converted to primitives first.
+func (i *Interpreter) toNumeric(v Object) (Object, *Error) {
+	prim := i.toPrimitive(v, "number")
+	if err, ok := prim.(*Error); ok {
+		return nil, err
+	}
+	if b, ok := prim.(*BigInt); ok {
+		return b, nil
+	}
+	n, err := i.toNumber(prim)
+	if err != nil {
+		return nil, err
+	}
+	return &Number{Value: n}, nil
+}
+
+// toBigInt converts a value to a BigInt, as BigInt.asIntN does with its
+// argument. Booleans become 0n or 1n and strings are parsed, but numbers
+// aren't accepted, even whole ones: that takes BigInt(n).
+func (i *Interpreter) toBigInt(v Object) (*big.Int, *Error) {
+	prim := i.toPrimitive(v, "number")
+	if err, ok := prim.(*Error); ok {
+		return nil, err
+	}
+	switch prim := prim.(type) {
+	case *BigInt:
+		return prim.Value, nil
+	case *Boolean:
+		if prim.Value {
+			return big.NewInt(1), nil
+		}
+		return big.NewInt(0), nil
+	case *String:
+		n, ok := stringToBigInt(prim.Value)
+		if !ok {
+			return nil, newSyntaxError("Cannot convert %s to a BigInt", prim.Value)
+		}
+		return n, nil
+	}
+	return nil, newTypeError("Cannot convert %s to a BigInt", prim.Inspect())
+}
+
+// stringToBigInt parses a string the way BigInt("...") does: like
+// Number("..."), but only integers are allowed, and a sign can't be
+// combined with a 0x, 0o or 0b prefix. It reports false if s isn't an
+// integer.
+func stringToBigInt(s string) (*big.Int, bool) {
+	units := utf16Units(s)
+	from, to := 0, len(units)
+	for from < to && isWhitespace(units[from]) {
+		from++
+	}
+	for to > from && isWhitespace(units[to-1]) {
+		to--
+	}
+	s = stringFromUnits(units[from:to])
+	if s == "" {
+		return big.NewInt(0), true
+	}
+
+	base := 10
+	if len(s) > 2 && s[0] == '0' {
+		switch s[1] {
+		case 'x', 'X':
+			base, s = 16, s[2:]
+		case 'o', 'O':
+			base, s = 8, s[2:]
+		case 'b', 'B':
+			base, s = 2, s[2:]
+		}
+	}
+	digits := s
+	if base == 10 && (s[0] == '+' || s[0] == '-') {
+		digits = s[1:]
+	}
+	// SetString would also accept underscores, and signs after a prefix.
+	if digits == "" || strings.ContainsAny(digits, "_+-") {
+		return nil, false
+	}
+	return new(big.Int).SetString(s, base)
+}
+
+// numberToBigInt converts a number to a BigInt, as BigInt(n) does. Only
+// integers can be converted.
+func numberToBigInt(f float64) (*big.Int, *Error) {
+	if !isInteger(f) {
+		return nil, newRangeError("The number %s cannot be converted to a BigInt because it is not an integer", numberToString(f))
+	}
+	n, _ := new(big.Float).SetFloat64(f).Int(nil)
+	return n, nil
+}
+
+// bigIntToNumber converts a BigInt to the nearest number, as Number(n)
+// does.
+func bigIntToNumber(n *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
+
+// compareBigIntNumber compares a BigInt with a number exactly, returning
+// -1, 0 or 1. It reports false if f is NaN, which can't be compared.
+func compareBigIntNumber(n *big.Int, f float64) (int, bool) {
+	switch {
+	case math.IsNaN(f):
+		return 0, false
+	case math.IsInf(f, 1):
+		return -1, true
+	case math.IsInf(f, -1):
+		return 1, true
+	}
+	return new(big.Float).SetInt(n).Cmp(big.NewFloat(f)), true
+}
+
+// evalBigIntInfixExpression evaluates arithmetic between BigInts. Division
+// rounds towards zero, so 7n / 2n is 3n, and dividing by zero is a
+// RangeError, as there's no BigInt Infinity to give; so is a negative
+// exponent, as 2n ** -1n would be a fraction.
+//
+// The bitwise operators treat BigInts as two's complement numbers with as
+// many bits as they need, so -1n & 0xffn is 255n and -5n >> 1n is -3n.
+// There's no >>>, as a negative BigInt has no end to shift zeros in from.
+func evalBigIntInfixExpression(operator string, left, right *big.Int) Object {
+	result := new(big.Int)
+	switch operator {
+	case "+":
+		result.Add(left, right)
+	case "-":
+		result.Sub(left, right)
+	case "*":
+		result.Mul(left, right)
+	case "/", "%":
+		if right.Sign() == 0 {
+			return newRangeError("Division by zero")
+		}
+		if operator == "/" {
+			result.Quo(left, right)
+		} else {
+			result.Rem(left, right)
+		}
+	case "**":
+		if right.Sign() < 0 {
+			return newRangeError("Exponent must be non-negative")
+		}
+		// The result has at least (bits - 1) * exponent bits, which
+		// may be too many to compute, unless the base is 0, 1 or -1.
+		if left.CmpAbs(big.NewInt(1)) > 0 {
+			if !right.IsInt64() || right.Int64() > maxBigIntBits ||
+				int64(left.BitLen()-1)*right.Int64() > maxBigIntBits {
+				return newRangeError("Maximum BigInt size exceeded")
+			}
+		}
+		result.Exp(left, right, nil)
+	case "&":
+		result.And(left, right)
+	case "|":
+		result.Or(left, right)
+	case "^":
+		result.Xor(left, right)
+	case "<<":
+		return shiftBigInt(left, right)
+	case ">>":
+		return shiftBigInt(left, new(big.Int).Neg(right))
+	case ">>>":
+		return newTypeError("BigInts have no unsigned right shift, use >> instead")
+	default:
+		return newError("unknown operator: %s %s %s", BIGINT_OBJ, operator, BIGINT_OBJ)
+	}
+	return &BigInt{Value: result}
+}
+
+// shiftBigInt shifts x left by n bits, or right if n is negative. Shifting
+// right rounds down, as shifting a two's complement number does, and once
+// every bit is shifted out that leaves 0, or -1 for a negative x.
+func shiftBigInt(x, n *big.Int) Object {
+	if x.Sign() == 0 {
+		return &BigInt{Value: new(big.Int)}
+	}
+	if n.Sign() >= 0 {
+		if !n.IsInt64() || int64(x.BitLen())+n.Int64() > maxBigIntBits {
+			return newRangeError("Maximum BigInt size exceeded")
+		}
+		return &BigInt{Value: new(big.Int).Lsh(x, uint(n.Int64()))}
+	}
+	shift := uint(x.BitLen())
+	if m := new(big.Int).Neg(n); m.Cmp(big.NewInt(int64(shift))) < 0 {
+		shift = uint(m.Int64())
+	}
+	return &BigInt{Value: new(big.Int).Rsh(x, shift)}
+}
+
+// evalBigIntComparison evaluates <, >, <= and >= when one side is a BigInt.
+// The other side is compared exactly, whether it is a BigInt, a number or
+// a string holding an integer. A string that isn't an integer can't be
+// compared, and neither can NaN, so those comparisons are false.
+func (i *Interpreter) evalBigIntComparison(operator string, left, right Object) Object {
+	toComparable := func(v Object) (Object, bool, *Error) {
+		if s, ok := v.(*String); ok {
+			n, ok := stringToBigInt(s.Value)
+			if !ok {
+				return nil, false, nil
+			}
+			return &BigInt{Value: n}, true, nil
+		}
+		n, err := i.toNumeric(v)
+		return n, err == nil, err
+	}
+	l, ok, err := toComparable(left)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return FALSE
+	}
+	r, ok, err := toComparable(right)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return FALSE
+	}
+	cmp, ok := compareNumeric(l, r)
+	if !ok {
+		return FALSE
+	}
+	return compareResult(operator, cmp)
+}
+
+// compareNumeric compares two numbers or BigInts, in any combination. It
+// reports false if either is NaN.
+func compareNumeric(left, right Object) (int, bool) {
+	switch l := left.(type) {
+	case *BigInt:
+		switch r := right.(type) {
+		case *BigInt:
+			return l.Value.Cmp(r.Value), true
+		case *Number:
+			return compareBigIntNumber(l.Value, r.Value)
+		}
+	case *Number:
+		if r, ok := right.(*BigInt); ok {
+			cmp, ok := compareBigIntNumber(r.Value, l.Value)
+			return -cmp, ok
+		}
+	}
+	return 0, false
+}
+
+// looseEqualsBigInt implements == between a BigInt and a number or string,
+// which compares their mathematical values: 1n == 1 and 1n == "1" are
+// true.
+func looseEqualsBigInt(n *big.Int, other Object) bool {
+	switch other := other.(type) {
+	case *Number:
+		cmp, ok := compareBigIntNumber(n, other.Value)
+		return ok && cmp == 0
+	case *String:
+		m, ok := stringToBigInt(other.Value)
+		return ok && n.Cmp(m) == 0
+	}
+	return false
+}
+
+// thisBigInt checks that a BigInt.prototype method was called on a BigInt.
+func thisBigInt(this Object, method string) (*BigInt, *Error) {
+	n, ok := this.(*BigInt)
+	if !ok {
+		return nil, newTypeError("BigInt.prototype.%s requires that 'this' be a BigInt", method)
+	}
+	return n, nil
+}
+
+// setupBigInt creates BigInt.prototype and the BigInt function, with
+// BigInt.asIntN and BigInt.asUintN, which wrap a BigInt around to a
+// fixed number of bits the way integer types in other languages do:
+// BigInt.asUintN(8, 257n) is 1n and BigInt.asIntN(8, 255n) is -1n.
+func (i *Interpreter) setupBigInt() {
+	proto := i.bigintPrototype
+
+	i.defineMethod(proto, "toString", func(this Object, args ...Object) Object {
+		n, err := thisBigInt(this, "toString")
+		if err != nil {
+			return err
+		}
+		radix := 10.0
+		if argAt(args, 0) != UNDEFINED {
+			radix, err = i.toIntegerOrInfinity(args[0])
+			if err != nil {
+				return err
+			}
+		}
+		if radix < 2 || radix > 36 {
+			return newRangeError("toString() radix must be between 2 and 36")
+		}
+		return &String{Value: n.Value.Text(int(radix))}
+	})
+	i.defineMethod(proto, "valueOf", func(this Object, args ...Object) Object {
+		n, err := thisBigInt(this, "valueOf")
+		if err != nil {
+			return err
+		}
+		return n
+	})
+	proto.Set(symbolToStringTag, &String{Value: "BigInt"})
+
+	// BigInt is a function but not a constructor: BigInts are primitives,
+	// so there's nothing for new to create.
+	ctor := i.newBuiltin("BigInt", func(this Object, args ...Object) Object {
+		prim := i.toPrimitive(argAt(args, 0), "number")
+		if err, ok := prim.(*Error); ok {
+			return err
+		}
+		if n, ok := prim.(*Number); ok {
+			value, err := numberToBigInt(n.Value)
+			if err != nil {
+				return err
+			}
+			return &BigInt{Value: value}
+		}
+		value, err := i.toBigInt(prim)
+		if err != nil {
+			return err
+		}
+		return &BigInt{Value: value}
+	})
+	ctor.notConstructor = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+
+	// bitsArgs reads the arguments of asIntN and asUintN.
+	bitsArgs := func(args []Object) (uint, *big.Int, *Error) {
+		bits, err := i.toIntegerOrInfinity(argAt(args, 0))
+		if err != nil {
+			return 0, nil, err
+		}
+		if bits < 0 || bits > maxSafeInteger {
+			return 0, nil, newRangeError("Invalid value: not (convertible to) a safe integer")
+		}
+		n, err := i.toBigInt(argAt(args, 1))
+		if err != nil {
+			return 0, nil, err
+		}
+		return uint(bits), n, nil
+	}
+	// powerOfTwo returns 2^bits, refusing to build one too large to
+	// store, as BigInts are limited to 2^30 bits.
+	powerOfTwo := func(bits uint) (*big.Int, *Error) {
+		if bits > maxBigIntBits {
+			return nil, newRangeError("Maximum BigInt size exceeded")
+		}
+		return new(big.Int).Lsh(big.NewInt(1), bits), nil
+	}
+	i.defineMethod(&ctor.Hash, "asUintN", func(this Object, args ...Object) Object {
+		bits, n, err := bitsArgs(args)
+		if err != nil {
+			return err
+		}
+		if n.Sign() >= 0 && uint(n.BitLen()) <= bits {
+			return &BigInt{Value: n}
+		}
+		modulus, err := powerOfTwo(bits)
+		if err != nil {
+			return err
+		}
+		return &BigInt{Value: new(big.Int).Mod(n, modulus)}
+	})
+	i.defineMethod(&ctor.Hash, "asIntN", func(this Object, args ...Object) Object {
+		bits, n, err := bitsArgs(args)
+		if err != nil {
+			return err
+		}
+		if uint(n.BitLen()) < bits {
+			return &BigInt{Value: n}
+		}
+		modulus, err := powerOfTwo(bits)
+		if err != nil {
+			return err
+		}
+		result := new(big.Int).Mod(n, modulus)
+		// The top half of the range stands for the negative numbers.
+		if bits > 0 && result.Bit(int(bits-1)) == 1 {
+			result.Sub(result, modulus)
+		}
+		return &BigInt{Value: result}
+	})
+
+	i.env.Set("BigInt", ctor)
+}
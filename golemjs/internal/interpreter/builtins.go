@@ -0,0 +1,97 @@
+package interpreter
+
+import "fmt"
+
+// setupGlobals creates the built-in prototypes and defines the global
+// functions and objects every script can use.
+func (i *Interpreter) setupGlobals() {
+	i.objectPrototype = NewHash(nil)
+	i.functionPrototype = NewHash(i.objectPrototype)
+	i.arrayPrototype = NewHash(i.objectPrototype)
+	i.iteratorPrototype = NewHash(i.objectPrototype)
+	i.arrayIteratorPrototype = NewHash(i.iteratorPrototype)
+	i.stringIteratorPrototype = NewHash(i.iteratorPrototype)
+	i.generatorPrototype = NewHash(i.iteratorPrototype)
+	i.stringPrototype = NewHash(i.objectPrototype)
+	i.numberPrototype = NewHash(i.objectPrototype)
+	i.booleanPrototype = NewHash(i.objectPrototype)
+	i.symbolPrototype = NewHash(i.objectPrototype)
+	i.mapPrototype = NewHash(i.objectPrototype)
+	i.setPrototype = NewHash(i.objectPrototype)
+	i.mapIteratorPrototype = NewHash(i.iteratorPrototype)
+	i.setIteratorPrototype = NewHash(i.iteratorPrototype)
+	i.weakMapPrototype = NewHash(i.objectPrototype)
+	i.weakSetPrototype = NewHash(i.objectPrototype)
+	i.weakRefPrototype = NewHash(i.objectPrototype)
+	i.finalizationRegistryPrototype = NewHash(i.objectPrototype)
+	i.regexpPrototype = NewHash(i.objectPrototype)
+	i.regexpStringIteratorPrototype = NewHash(i.iteratorPrototype)
+	i.datePrototype = NewHash(i.objectPrototype)
+	i.bigintPrototype = NewHash(i.objectPrototype)
+	i.arrayBufferPrototype = NewHash(i.objectPrototype)
+	i.typedArrayPrototype = NewHash(i.objectPrototype)
+	i.dataViewPrototype = NewHash(i.objectPrototype)
+	i.textEncoderPrototype = NewHash(i.objectPrototype)
+	i.textDecoderPrototype = NewHash(i.objectPrototype)
+	i.promisePrototype = NewHash(i.objectPrototype)
+	i.errorPrototype = NewHash(i.objectPrototype)
+	i.asyncIteratorPrototype = NewHash(i.objectPrototype)
+	i.asyncGeneratorPrototype = NewHash(i.asyncIteratorPrototype)
+
+	i.setupObject()
+	i.setupErrors()
+	i.setupIterator()
+	i.setupGenerator()
+	i.setupArray()
+	i.setupString()
+	i.setupNumber()
+	i.setupBoolean()
+	i.setupSymbol()
+	i.setupCollections()
+	i.setupWeakCollections()
+	i.setupRegExp()
+	i.setupDate()
+	i.setupBigInt()
+	i.setupArrayBuffer()
+	i.setupTypedArrays()
+	i.setupDataView()
+	i.setupEncoding()
+	i.setupPromise()
+	i.setupAsync()
+	i.setupMath()
+	i.setupJSON()
+	i.setupConsole()
+	i.setupEventLoop()
+
+	// undefined is a read-only global rather than a keyword.
+	i.env.SetConst("undefined", UNDEFINED)
+
+	i.env.Set("puts", i.newBuiltin("puts", i.puts))
+}
+
+// newBuiltin wraps a Go function as a JavaScript function object.
+func (i *Interpreter) newBuiltin(name string, fn BuiltinFunction) *Builtin {
+	return &Builtin{Hash: Hash{Prototype: i.functionPrototype}, Name: name, Fn: fn}
+}
+
+// defineMethod adds a built-in method to obj, typically a prototype.
+func (i *Interpreter) defineMethod(obj *Hash, name string, fn BuiltinFunction) {
+	obj.Set(&String{Value: name}, i.newBuiltin(name, fn))
+}
+
+// argAt returns the n-th argument, or undefined when fewer were passed.
+func argAt(args []Object, n int) Object {
+	if n < len(args) {
+		return args[n]
+	}
+	return UNDEFINED
+}
+
+// puts prints each of its arguments on a line of its own. It writes to the
+// same place as console.log, so SetOutput captures it too.
+func (i *Interpreter) puts(this Object, args ...Object) Object {
+	for _, arg := range args {
+		fmt.Fprintln(i.console.stdout, arg.Inspect())
+	}
+	return UNDEFINED
+}
@@ -0,0 +1,182 @@
+package interpreter
+
+import (
+	"context"
+	"time"
+)
+
+// Clock is where an interpreter gets the time from. Date reads the current
+// time from it, and the event loop goes by it to decide when timers are
+// due. The system clock is used unless SetClock replaces it, usually with
+// a FakeClock, which makes scripts that use dates and timers reproducible
+// and lets tests of timers run without waiting.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// Sleep waits for d to pass. The event loop sleeps when nothing is
+	// left to do but timers that aren't due yet. If the clock hasn't
+	// reached the first of them once Sleep returns, as a FakeClock won't
+	// have, the loop stops there and the timers wait.
+	Sleep(d time.Duration)
+}
+
+// systemClock is the real time, read from the operating system.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+// SetClock makes the interpreter take the time from c instead of the
+// system clock, both for Date and for timers.
+func (i *Interpreter) SetClock(c Clock) {
+	i.clock = c
+	if fake, ok := c.(*FakeClock); ok {
+		fake.interpreters = append(fake.interpreters, i)
+	}
+}
+
+// maxClockSteps is how many times RunAllTimers moves the clock on before
+// it concludes that the timers will go on forever.
+const maxClockSteps = 100000
+
+// FakeClock is a Clock that stands still until it's moved forward, for
+// testing code that uses dates and timers. Timers never fire by
+// themselves: Eval leaves them waiting, and AdvanceBy and RunAllTimers
+// fire them, as if the time had passed.
+//
+//	clock := interpreter.NewFakeClock(time.Now())
+//	interp.SetClock(clock)
+//	interp.Eval(program) // setTimeout(f, 1000)
+//	clock.AdvanceBy(time.Second) // f runs
+//
+// A FakeClock can be shared by several interpreters, which then all see
+// the same time.
+type FakeClock struct {
+	now time.Time
+
+	// interpreters holds the interpreters using the clock, whose timers
+	// fire as it moves.
+	interpreters []*Interpreter
+}
+
+// NewFakeClock creates a FakeClock that reads start until it's moved.
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{now: start}
+}
+
+// Now returns the time the clock has been moved to.
+func (c *FakeClock) Now() time.Time { return c.now }
+
+// Sleep returns straight away: a FakeClock only moves when it's told to.
+func (c *FakeClock) Sleep(d time.Duration) {}
+
+// AdvanceBy moves the clock forward by d. The timers that fall due on the
+// way fire in order, each with the clock showing the time it was due at,
+// so that a timer set by one of them fires too if it's due by the end.
+// The first error a timer, or other work the event loop ran, failed with
+// is returned.
+func (c *FakeClock) AdvanceBy(d time.Duration) *Error {
+	defer c.begin()()
+	end := c.now.Add(d)
+	var firstErr *Error
+	for {
+		due, ok := c.nextTimer()
+		if !ok || due.After(end) {
+			break
+		}
+		if due.After(c.now) {
+			c.now = due
+		}
+		if err := c.runUntilIdle(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	c.now = end
+	return firstErr
+}
+
+// RunAllTimers moves the clock forward until no timers are left, firing
+// each as it falls due. A setInterval that is never cleared would keep it
+// going forever, so it gives up with an error once it has moved the clock
+// on 100,000 times.
+func (c *FakeClock) RunAllTimers() *Error {
+	defer c.begin()()
+	var firstErr *Error
+	for steps := 0; ; steps++ {
+		due, ok := c.nextTimer()
+		if !ok {
+			return firstErr
+		}
+		if steps == maxClockSteps {
+			return newError("Aborting after advancing the clock %d times, assuming an infinite loop", maxClockSteps)
+		}
+		if due.After(c.now) {
+			c.now = due
+		}
+		if err := c.runUntilIdle(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+}
+
+// nextTimer returns when the first timer of any interpreter using the clock
+// is due. An interpreter stopped by an uncatchable error won't fire its
+// timers, so they're left out, or the clock would wait on them forever.
+func (c *FakeClock) nextTimer() (time.Time, bool) {
+	var next time.Time
+	found := false
+	for _, i := range c.users() {
+		if i.usage.terminated != nil {
+			continue
+		}
+		if t := i.loop.timers.peek(); t != nil && (!found || t.due.Before(next)) {
+			next, found = t.due, true
+		}
+	}
+	return next, found
+}
+
+// begin starts an evaluation in each interpreter using the clock that
+// isn't running one already, for the timers the clock is about to fire,
+// so that they're held to the interpreter's limits, and returns the
+// function that ends them. The limits count across all the timers fired
+// by one call of AdvanceBy or RunAllTimers.
+func (c *FakeClock) begin() (end func()) {
+	var started []*Interpreter
+	for _, i := range c.users() {
+		if !i.usage.running {
+			i.begin(context.Background())
+			started = append(started, i)
+		}
+	}
+	return func() {
+		for _, i := range started {
+			i.end()
+		}
+	}
+}
+
+// runUntilIdle runs the event loop of every interpreter using the clock.
+func (c *FakeClock) runUntilIdle() *Error {
+	var firstErr *Error
+	for _, i := range c.users() {
+		if err := i.runUntilIdle(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// users returns the interpreters using the clock, leaving out any that have
+// since been given another one.
+func (c *FakeClock) users() []*Interpreter {
+	users := c.interpreters[:0]
+	for _, i := range c.interpreters {
+		if i.clock == Clock(c) {
+			users = append(users, i)
+		}
+	}
+	c.interpreters = users
+	return users
+}
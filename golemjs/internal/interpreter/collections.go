@@ -0,0 +1,474 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Map and Set are collections keyed by any value, unlike plain objects,
+// whose keys are always strings or symbols:
+//
+//	const seen = new Map();
+//	seen.set(node, true);  // keyed by the object itself
+//	seen.get(node);        // true
+//
+// Keys are compared with SameValueZero, which is === except that NaN
+// equals itself, so objects are keys by identity and primitives by value.
+// Both collections remember the order in which keys were first added, and
+// iterate in that order.
+
+// orderedTable holds the entries of a Map or Set. The entries form a linked
+// list in insertion order, with an index for finding them by key.
+//
+// Iterators keep a pointer to the last entry they produced, so the list has
+// to stay walkable while it is changed underneath them. A deleted entry is
+// unlinked from the list but keeps its prev pointer, which leads an
+// iterator that is parked on it back to an entry that is still in the
+// list; from there, the iterator carries on with whatever now follows it,
+// including entries added since.
+type orderedTable struct {
+	index map[tableKey]*tableEntry
+	head  tableEntry // sentinel before the first entry
+	tail  *tableEntry
+}
+
+type tableEntry struct {
+	key, value Object
+	deleted    bool
+	prev, next *tableEntry
+}
+
+// tableKey is the form in which a key is looked up in the index: equal
+// under SameValueZero means equal as a tableKey.
+type tableKey struct {
+	kind   ObjectType
+	number float64
+	str    string
+	ref    Object
+}
+
+func newOrderedTable() *orderedTable {
+	t := &orderedTable{index: map[tableKey]*tableEntry{}}
+	t.tail = &t.head
+	return t
+}
+
+// keyFor returns the tableKey of v. Primitives are keys by value, with
+// -0 the same as 0 and every NaN the same as every other, and everything
+// else is a key by identity.
+func keyFor(v Object) tableKey {
+	switch v := v.(type) {
+	case *Number:
+		if math.IsNaN(v.Value) {
+			return tableKey{kind: NUMBER_OBJ, str: "NaN"}
+		}
+		n := v.Value
+		if n == 0 {
+			n = 0 // turns -0 into 0
+		}
+		return tableKey{kind: NUMBER_OBJ, number: n}
+	case *String:
+		return tableKey{kind: STRING_OBJ, str: v.Value}
+	case *BigInt:
+		return tableKey{kind: BIGINT_OBJ, str: v.Value.String()}
+	case *Boolean:
+		return tableKey{kind: BOOLEAN_OBJ, str: v.Inspect()}
+	case *Null, *Undefined:
+		return tableKey{kind: v.Type()}
+	}
+	return tableKey{ref: v}
+}
+
+func (t *orderedTable) size() int { return len(t.index) }
+
+func (t *orderedTable) get(key Object) (Object, bool) {
+	e, ok := t.index[keyFor(key)]
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+// set adds key or updates its value. A new key goes to the end of the
+// order; an existing one keeps its place.
+func (t *orderedTable) set(key, value Object) {
+	k := keyFor(key)
+	if e, ok := t.index[k]; ok {
+		e.value = value
+		return
+	}
+	if n, ok := key.(*Number); ok && n.Value == 0 {
+		key = &Number{Value: 0} // -0 is stored as 0
+	}
+	e := &tableEntry{key: key, value: value, prev: t.tail}
+	t.tail.next = e
+	t.tail = e
+	t.index[k] = e
+}
+
+func (t *orderedTable) delete(key Object) bool {
+	k := keyFor(key)
+	e, ok := t.index[k]
+	if !ok {
+		return false
+	}
+	delete(t.index, k)
+	t.unlink(e)
+	return true
+}
+
+// clear removes every entry. The entries are marked deleted one by one so
+// that iterators parked on any of them find their way back to the head.
+func (t *orderedTable) clear() {
+	for e := t.head.next; e != nil; {
+		next := e.next
+		t.unlink(e)
+		e = next
+	}
+	t.index = map[tableKey]*tableEntry{}
+}
+
+func (t *orderedTable) unlink(e *tableEntry) {
+	e.deleted = true
+	e.key, e.value = nil, nil
+	e.prev.next = e.next
+	if e.next != nil {
+		e.next.prev = e.prev
+	} else {
+		t.tail = e.prev
+	}
+	e.next = nil
+}
+
+// after returns the entry that follows e, which may have been deleted since
+// it was reached, or nil at the end of the table.
+func (t *orderedTable) after(e *tableEntry) *tableEntry {
+	for e.deleted {
+		e = e.prev
+	}
+	return e.next
+}
+
+// forEach calls fn with every entry, including those added while it runs,
+// until fn returns an error.
+func (t *orderedTable) forEach(fn func(key, value Object) Object) Object {
+	for e := t.after(&t.head); e != nil; e = t.after(e) {
+		if result := fn(e.key, e.value); isError(result) {
+			return result
+		}
+	}
+	return nil
+}
+
+// Map is the object created by new Map(): a collection of key-value pairs.
+type Map struct {
+	Hash
+	table *orderedTable
+}
+
+func (m *Map) Type() ObjectType { return MAP_OBJ }
+func (m *Map) Inspect() string {
+	entries := []string{}
+	m.table.forEach(func(key, value Object) Object {
+		entries = append(entries, key.Inspect()+" => "+value.Inspect())
+		return nil
+	})
+	return fmt.Sprintf("Map(%d) {%s}", m.table.size(), strings.Join(entries, ", "))
+}
+
+// Set is the object created by new Set(): a collection of distinct values.
+// Its table stores each value as both key and value.
+type Set struct {
+	Hash
+	table *orderedTable
+}
+
+func (s *Set) Type() ObjectType { return SET_OBJ }
+func (s *Set) Inspect() string {
+	entries := []string{}
+	s.table.forEach(func(key, value Object) Object {
+		entries = append(entries, key.Inspect())
+		return nil
+	})
+	return fmt.Sprintf("Set(%d) {%s}", s.table.size(), strings.Join(entries, ", "))
+}
+
+// tableIterator is the state shared by the iterators of maps and sets: the
+// table, the entry last produced, and whether keys, values or [key, value]
+// entries are produced.
+type tableIterator struct {
+	Hash
+	table *orderedTable
+	entry *tableEntry
+	kind  string // "keys", "values" or "entries"
+	done  bool
+}
+
+// MapIterator is returned by the keys, values and entries methods of maps.
+type MapIterator struct{ tableIterator }
+
+func (it *MapIterator) Type() ObjectType { return MAP_ITERATOR_OBJ }
+func (it *MapIterator) Inspect() string  { return "Map Iterator {}" }
+
+// SetIterator is returned by the keys, values and entries methods of sets.
+type SetIterator struct{ tableIterator }
+
+func (it *SetIterator) Type() ObjectType { return SET_ITERATOR_OBJ }
+func (it *SetIterator) Inspect() string  { return "Set Iterator {}" }
+
+// nextTableIteratorValue advances the iterator and returns the next value
+// and whether the iterator is finished. Once finished, it stays finished,
+// even if entries are added to the table afterwards.
+func (i *Interpreter) nextTableIteratorValue(it *tableIterator) (Object, bool) {
+	if it.done {
+		return UNDEFINED, true
+	}
+	next := it.table.after(it.entry)
+	if next == nil {
+		it.done = true
+		it.entry = nil
+		return UNDEFINED, true
+	}
+	it.entry = next
+	switch it.kind {
+	case "keys":
+		return next.key, false
+	case "entries":
+		return i.newArray([]Object{next.key, next.value}), false
+	}
+	return next.value, false
+}
+
+// setupCollections creates Map and Set with their prototypes and the
+// prototypes of their iterators.
+func (i *Interpreter) setupCollections() {
+	i.setupMap()
+	i.setupSet()
+}
+
+func (i *Interpreter) setupMap() {
+	proto := i.mapPrototype
+
+	thisMap := func(this Object, method string) (*Map, *Error) {
+		m, ok := this.(*Map)
+		if !ok {
+			return nil, newTypeError("Method Map.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return m, nil
+	}
+	i.defineMethod(proto, "get", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "get")
+		if err != nil {
+			return err
+		}
+		if value, ok := m.table.get(argAt(args, 0)); ok {
+			return value
+		}
+		return UNDEFINED
+	})
+	i.defineMethod(proto, "set", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "set")
+		if err != nil {
+			return err
+		}
+		m.table.set(argAt(args, 0), argAt(args, 1))
+		return m
+	})
+	i.defineMethod(proto, "has", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "has")
+		if err != nil {
+			return err
+		}
+		_, ok := m.table.get(argAt(args, 0))
+		return nativeBoolToBooleanObject(ok)
+	})
+	i.defineMethod(proto, "delete", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "delete")
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(m.table.delete(argAt(args, 0)))
+	})
+	i.defineMethod(proto, "clear", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "clear")
+		if err != nil {
+			return err
+		}
+		m.table.clear()
+		return UNDEFINED
+	})
+	i.defineMethod(proto, "forEach", func(this Object, args ...Object) Object {
+		m, err := thisMap(this, "forEach")
+		if err != nil {
+			return err
+		}
+		return i.tableForEach(m, m.table, args)
+	})
+	for _, kind := range []string{"keys", "values", "entries"} {
+		i.defineMethod(proto, kind, func(this Object, args ...Object) Object {
+			m, err := thisMap(this, kind)
+			if err != nil {
+				return err
+			}
+			it := &MapIterator{tableIterator{Hash: Hash{Prototype: i.mapIteratorPrototype}, table: m.table, entry: &m.table.head, kind: kind}}
+			return it
+		})
+	}
+	entries, _ := proto.GetOwn(&String{Value: "entries"})
+	proto.Set(symbolIterator, entries)
+	proto.Set(symbolToStringTag, &String{Value: "Map"})
+
+	i.defineMethod(i.mapIteratorPrototype, "next", func(this Object, args ...Object) Object {
+		it, ok := this.(*MapIterator)
+		if !ok {
+			return newTypeError("next method called on incompatible receiver %s", inspectReceiver(this))
+		}
+		value, done := i.nextTableIteratorValue(&it.tableIterator)
+		return i.iteratorResult(value, done)
+	})
+	i.mapIteratorPrototype.Set(symbolToStringTag, &String{Value: "Map Iterator"})
+
+	ctor := i.newBuiltin("Map", func(this Object, args ...Object) Object {
+		m := &Map{Hash: Hash{Prototype: proto}, table: newOrderedTable()}
+		iterable := argAt(args, 0)
+		if isNullish(iterable) {
+			return m
+		}
+		err := i.iterate(iterable, func(entry Object) *Error {
+			if !isObject(entry) {
+				return newTypeError("Iterator value %s is not an entry object", inspect(entry, 0))
+			}
+			key := i.getProperty(entry, &String{Value: "0"})
+			if err, ok := key.(*Error); ok {
+				return err
+			}
+			value := i.getProperty(entry, &String{Value: "1"})
+			if err, ok := value.(*Error); ok {
+				return err
+			}
+			m.table.set(key, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return m
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("Map", ctor)
+}
+
+func (i *Interpreter) setupSet() {
+	proto := i.setPrototype
+
+	thisSet := func(this Object, method string) (*Set, *Error) {
+		s, ok := this.(*Set)
+		if !ok {
+			return nil, newTypeError("Method Set.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return s, nil
+	}
+	i.defineMethod(proto, "add", func(this Object, args ...Object) Object {
+		s, err := thisSet(this, "add")
+		if err != nil {
+			return err
+		}
+		value := argAt(args, 0)
+		s.table.set(value, value)
+		return s
+	})
+	i.defineMethod(proto, "has", func(this Object, args ...Object) Object {
+		s, err := thisSet(this, "has")
+		if err != nil {
+			return err
+		}
+		_, ok := s.table.get(argAt(args, 0))
+		return nativeBoolToBooleanObject(ok)
+	})
+	i.defineMethod(proto, "delete", func(this Object, args ...Object) Object {
+		s, err := thisSet(this, "delete")
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(s.table.delete(argAt(args, 0)))
+	})
+	i.defineMethod(proto, "clear", func(this Object, args ...Object) Object {
+		s, err := thisSet(this, "clear")
+		if err != nil {
+			return err
+		}
+		s.table.clear()
+		return UNDEFINED
+	})
+	i.defineMethod(proto, "forEach", func(this Object, args ...Object) Object {
+		s, err := thisSet(this, "forEach")
+		if err != nil {
+			return err
+		}
+		return i.tableForEach(s, s.table, args)
+	})
+	for _, kind := range []string{"values", "entries"} {
+		i.defineMethod(proto, kind, func(this Object, args ...Object) Object {
+			s, err := thisSet(this, kind)
+			if err != nil {
+				return err
+			}
+			return &SetIterator{tableIterator{Hash: Hash{Prototype: i.setIteratorPrototype}, table: s.table, entry: &s.table.head, kind: kind}}
+		})
+	}
+	// A set's keys are its values, and keys is the same function as values.
+	values, _ := proto.GetOwn(&String{Value: "values"})
+	proto.Set(&String{Value: "keys"}, values)
+	proto.Set(symbolIterator, values)
+	proto.Set(symbolToStringTag, &String{Value: "Set"})
+
+	i.defineMethod(i.setIteratorPrototype, "next", func(this Object, args ...Object) Object {
+		it, ok := this.(*SetIterator)
+		if !ok {
+			return newTypeError("next method called on incompatible receiver %s", inspectReceiver(this))
+		}
+		value, done := i.nextTableIteratorValue(&it.tableIterator)
+		return i.iteratorResult(value, done)
+	})
+	i.setIteratorPrototype.Set(symbolToStringTag, &String{Value: "Set Iterator"})
+
+	ctor := i.newBuiltin("Set", func(this Object, args ...Object) Object {
+		s := &Set{Hash: Hash{Prototype: proto}, table: newOrderedTable()}
+		iterable := argAt(args, 0)
+		if isNullish(iterable) {
+			return s
+		}
+		err := i.iterate(iterable, func(value Object) *Error {
+			s.table.set(value, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return s
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("Set", ctor)
+}
+
+// tableForEach implements forEach(callback, thisArg) for maps and sets,
+// calling callback with each value, its key and the collection. Entries
+// added by the callback are visited too.
+func (i *Interpreter) tableForEach(collection Object, table *orderedTable, args []Object) Object {
+	callback := argAt(args, 0)
+	if !isCallable(callback) {
+		return newTypeError("%s is not a function", inspect(callback, 0))
+	}
+	result := table.forEach(func(key, value Object) Object {
+		return i.applyFunction(callback, argAt(args, 1), []Object{value, key, collection})
+	})
+	if isError(result) {
+		return result
+	}
+	return UNDEFINED
+}
@@ -0,0 +1,490 @@
+package interpreter
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// console holds the state behind the console global: where its output
+// goes, how far console.group has indented it, and the timers and counters
+// that console.time and console.count keep by label.
+type console struct {
+	stdout io.Writer
+	stderr io.Writer
+
+	indent string
+	timers map[string]time.Time
+	counts map[string]int
+}
+
+// SetOutput sends console output to the given writers instead of the
+// process's standard output and standard error. console.log, info, debug,
+// table, dir and friends write to stdout; console.error, warn, assert and
+// trace write to stderr. Embedders use this to capture what a script
+// prints.
+func (i *Interpreter) SetOutput(stdout, stderr io.Writer) {
+	i.console.stdout = stdout
+	i.console.stderr = stderr
+}
+
+// setupConsole defines the console global.
+func (i *Interpreter) setupConsole() {
+	i.console = console{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+		timers: map[string]time.Time{},
+		counts: map[string]int{},
+	}
+	obj := NewHash(i.objectPrototype)
+
+	logTo := func(stderr bool) BuiltinFunction {
+		return func(this Object, args ...Object) Object {
+			return i.consoleLog(stderr, args)
+		}
+	}
+	i.defineMethod(obj, "log", logTo(false))
+	i.defineMethod(obj, "info", logTo(false))
+	i.defineMethod(obj, "debug", logTo(false))
+	i.defineMethod(obj, "error", logTo(true))
+	i.defineMethod(obj, "warn", logTo(true))
+	i.defineMethod(obj, "trace", func(this Object, args ...Object) Object {
+		text, err := i.formatLogArgs(args)
+		if err != nil {
+			return err
+		}
+		if text != "" {
+			text = ": " + text
+		}
+		i.consolePrint(true, "Trace"+text)
+		return UNDEFINED
+	})
+	i.defineMethod(obj, "dir", func(this Object, args ...Object) Object {
+		i.consolePrint(false, inspect(argAt(args, 0), defaultInspectDepth))
+		return UNDEFINED
+	})
+	i.defineMethod(obj, "assert", func(this Object, args ...Object) Object {
+		if isTruthy(argAt(args, 0)) {
+			return UNDEFINED
+		}
+		text := "Assertion failed"
+		if len(args) > 1 {
+			msg, err := i.formatLogArgs(args[1:])
+			if err != nil {
+				return err
+			}
+			text += ": " + msg
+		}
+		i.consolePrint(true, text)
+		return UNDEFINED
+	})
+
+	group := func(this Object, args ...Object) Object {
+		if len(args) > 0 {
+			text, err := i.formatLogArgs(args)
+			if err != nil {
+				return err
+			}
+			i.consolePrint(false, text)
+		}
+		i.console.indent += "  "
+		return UNDEFINED
+	}
+	i.defineMethod(obj, "group", group)
+	i.defineMethod(obj, "groupCollapsed", group)
+	i.defineMethod(obj, "groupEnd", func(this Object, args ...Object) Object {
+		if len(i.console.indent) >= 2 {
+			i.console.indent = i.console.indent[2:]
+		}
+		return UNDEFINED
+	})
+
+	i.defineMethod(obj, "count", func(this Object, args ...Object) Object {
+		label, err := i.consoleLabel(args)
+		if err != nil {
+			return err
+		}
+		i.console.counts[label]++
+		i.consolePrint(false, fmt.Sprintf("%s: %d", label, i.console.counts[label]))
+		return UNDEFINED
+	})
+	i.defineMethod(obj, "countReset", func(this Object, args ...Object) Object {
+		label, err := i.consoleLabel(args)
+		if err != nil {
+			return err
+		}
+		if _, ok := i.console.counts[label]; !ok {
+			i.consolePrint(true, fmt.Sprintf("Count for '%s' does not exist", label))
+			return UNDEFINED
+		}
+		i.console.counts[label] = 0
+		return UNDEFINED
+	})
+
+	i.defineMethod(obj, "time", func(this Object, args ...Object) Object {
+		label, err := i.consoleLabel(args)
+		if err != nil {
+			return err
+		}
+		if _, ok := i.console.timers[label]; ok {
+			i.consolePrint(true, fmt.Sprintf("Warning: Label '%s' already exists for console.time()", label))
+			return UNDEFINED
+		}
+		i.console.timers[label] = time.Now()
+		return UNDEFINED
+	})
+	timeLog := func(method string, end bool) BuiltinFunction {
+		return func(this Object, args ...Object) Object {
+			label, err := i.consoleLabel(args)
+			if err != nil {
+				return err
+			}
+			start, ok := i.console.timers[label]
+			if !ok {
+				i.consolePrint(true, fmt.Sprintf("Warning: No such label '%s' for console.%s()", label, method))
+				return UNDEFINED
+			}
+			text := label + ": " + formatDuration(time.Since(start))
+			if end {
+				delete(i.console.timers, label)
+			} else if len(args) > 1 {
+				data, err := i.formatLogArgs(args[1:])
+				if err != nil {
+					return err
+				}
+				text += " " + data
+			}
+			i.consolePrint(false, text)
+			return UNDEFINED
+		}
+	}
+	i.defineMethod(obj, "timeEnd", timeLog("timeEnd", true))
+	i.defineMethod(obj, "timeLog", timeLog("timeLog", false))
+
+	i.defineMethod(obj, "table", i.consoleTable)
+
+	i.env.Set("console", obj)
+}
+
+// consolePrint writes one message followed by a newline, indenting every
+// line of it by the current console.group level.
+func (i *Interpreter) consolePrint(stderr bool, text string) {
+	w := i.console.stdout
+	if stderr {
+		w = i.console.stderr
+	}
+	if i.console.indent != "" {
+		text = i.console.indent + strings.ReplaceAll(text, "\n", "\n"+i.console.indent)
+	}
+	fmt.Fprintln(w, text)
+}
+
+// consoleLabel returns the label argument of console.count and
+// console.time, which defaults to "default".
+func (i *Interpreter) consoleLabel(args []Object) (string, *Error) {
+	if argAt(args, 0) == UNDEFINED {
+		return "default", nil
+	}
+	label, err := i.toString(args[0])
+	if err != nil {
+		return "", err
+	}
+	return label.Value, nil
+}
+
+// formatDuration writes an elapsed time the way console.timeEnd does:
+// milliseconds, seconds, or minutes and seconds, with three decimals.
+func formatDuration(d time.Duration) string {
+	ms := float64(d) / float64(time.Millisecond)
+	switch {
+	case ms >= 60*1000:
+		minutes := math.Floor(ms / (60 * 1000))
+		seconds := (ms - minutes*60*1000) / 1000
+		return fmt.Sprintf("%d:%06.3f (m:ss.mmm)", int(minutes), seconds)
+	case ms >= 1000:
+		return fmt.Sprintf("%.3fs", ms/1000)
+	}
+	return fmt.Sprintf("%.3fms", ms)
+}
+
+// formatLogArgs builds the message console.log prints for its arguments.
+// If the first argument is a string, it may contain format specifiers that
+// are replaced by the arguments that follow it:
+//
+//	%s  a string; objects are inspected one level deep
+//	%d  a number (%i an integer, %f a floating point number)
+//	%j  JSON, or [Circular] for values that contain themselves
+//	%o  an object inspected in detail, %O an object inspected normally
+//	%c  CSS styling, which is ignored
+//	%%  a percent sign
+//
+// Arguments left over are appended separated by spaces. Strings are written
+// as they are and everything else is inspected. A string on its own is
+// printed unchanged, without looking for specifiers.
+func (i *Interpreter) formatLogArgs(args []Object) (string, *Error) {
+	var out strings.Builder
+	rest := args
+	if format, ok := argAt(args, 0).(*String); ok && len(args) > 1 {
+		rest = args[1:]
+		s := format.Value
+		for idx := 0; idx < len(s); idx++ {
+			if s[idx] != '%' || idx+1 == len(s) {
+				out.WriteByte(s[idx])
+				continue
+			}
+			verb := s[idx+1]
+			if verb == '%' {
+				out.WriteByte('%')
+				idx++
+				continue
+			}
+			if !strings.ContainsRune("sdifjoOc", rune(verb)) || len(rest) == 0 {
+				out.WriteByte('%')
+				continue
+			}
+			text, err := i.formatSpecifier(verb, rest[0])
+			if err != nil {
+				return "", err
+			}
+			out.WriteString(text)
+			rest = rest[1:]
+			idx++
+		}
+	}
+	for idx, arg := range rest {
+		if idx > 0 || len(rest) < len(args) {
+			out.WriteByte(' ')
+		}
+		if s, ok := arg.(*String); ok {
+			out.WriteString(s.Value)
+		} else {
+			out.WriteString(inspect(arg, defaultInspectDepth))
+		}
+	}
+	return out.String(), nil
+}
+
+// formatSpecifier formats arg for a single %-specifier in a console message.
+func (i *Interpreter) formatSpecifier(verb byte, arg Object) (string, *Error) {
+	switch verb {
+	case 's':
+		switch arg := arg.(type) {
+		case *Number:
+			return formatNumber(arg.Value), nil
+		case *Symbol:
+			return arg.Inspect(), nil
+		}
+		// Objects are inspected unless they have their own toString.
+		if isObject(arg) {
+			if _, custom := i.getProperty(arg, &String{Value: "toString"}).(*Function); !custom {
+				return inspect(arg, 0), nil
+			}
+		}
+		s, err := i.toString(arg)
+		if err != nil {
+			return "", err
+		}
+		return s.Value, nil
+	case 'd':
+		if isObject(arg) {
+			return "NaN", nil
+		}
+		n, err := i.toNumber(arg)
+		if err != nil {
+			return "", err
+		}
+		return formatNumber(n), nil
+	case 'i':
+		return i.formatNumberResult(i.parseInt(nil, arg))
+	case 'f':
+		return i.formatNumberResult(i.parseFloat(nil, arg))
+	case 'j':
+		result := i.jsonStringify(nil, arg)
+		if err, ok := result.(*Error); ok {
+			if strings.HasSuffix(err.Message, "Converting circular structure to JSON") {
+				return "[Circular]", nil
+			}
+			return "", err
+		}
+		return result.Inspect(), nil
+	case 'o':
+		return inspect(arg, 4), nil
+	case 'O':
+		return inspect(arg, defaultInspectDepth), nil
+	}
+	// %c takes an argument but prints nothing.
+	return "", nil
+}
+
+func (i *Interpreter) formatNumberResult(result Object) (string, *Error) {
+	if err, ok := result.(*Error); ok {
+		return "", err
+	}
+	return formatNumber(result.(*Number).Value), nil
+}
+
+// consoleTable implements console.table(data, columns), which prints the
+// properties of an object or the elements of an array as a table. Each
+// row is one property or element; if that is itself an object, its
+// properties become the columns, and otherwise the value goes in a
+// "Values" column. columns optionally restricts which columns are shown.
+// Anything other than an object is simply logged.
+func (i *Interpreter) consoleTable(this Object, args ...Object) Object {
+	data := argAt(args, 0)
+	if !isObject(data) {
+		return i.consoleLog(false, args)
+	}
+
+	var filter []string
+	if cols, ok := argAt(args, 1).(*Array); ok {
+		for idx := range cols.len() {
+			s, err := i.toString(cols.element(idx))
+			if err != nil {
+				return err
+			}
+			filter = append(filter, s.Value)
+		}
+	}
+
+	var rowKeys []string
+	var rowValues []Object
+	if arr, ok := data.(*Array); ok {
+		w := arr.walk(0, arr.len(), false)
+		for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+			rowKeys = append(rowKeys, fmt.Sprint(idx))
+			rowValues = append(rowValues, e)
+		}
+	}
+	holder := data.(propertyHolder).properties()
+	for _, key := range visibleKeys(data) {
+		value, _ := holder.GetOwn(key)
+		rowKeys = append(rowKeys, formatTableKey(key))
+		rowValues = append(rowValues, value)
+	}
+
+	columns := filter
+	seen := map[string]bool{}
+	hasValues := false
+	cells := make([]map[string]string, len(rowValues))
+	for r, value := range rowValues {
+		cells[r] = map[string]string{}
+		if !isObject(value) || isCallable(value) {
+			cells[r][""] = inspect(value, 0)
+			hasValues = true
+			continue
+		}
+		row := value.(propertyHolder).properties()
+		var keys []Object
+		if arr, ok := value.(*Array); ok {
+			w := arr.walk(0, arr.len(), false)
+			for idx, e, ok := w.next(); ok; idx, e, ok = w.next() {
+				key := &String{Value: fmt.Sprint(idx)}
+				keys = append(keys, key)
+				cells[r][key.Value] = inspect(e, 0)
+			}
+		}
+		for _, key := range visibleKeys(value) {
+			v, _ := row.GetOwn(key)
+			cells[r][formatTableKey(key)] = inspect(v, 0)
+			keys = append(keys, key)
+		}
+		if filter != nil {
+			continue
+		}
+		for _, key := range keys {
+			name := formatTableKey(key)
+			if !seen[name] {
+				seen[name] = true
+				columns = append(columns, name)
+			}
+		}
+	}
+
+	header := append([]string{"(index)"}, columns...)
+	if hasValues && filter == nil {
+		header = append(header, "Values")
+	}
+	rows := make([][]string, len(rowKeys))
+	for r, key := range rowKeys {
+		rows[r] = []string{key}
+		for _, col := range columns {
+			rows[r] = append(rows[r], cells[r][col])
+		}
+		if hasValues && filter == nil {
+			rows[r] = append(rows[r], cells[r][""])
+		}
+	}
+	i.consolePrint(false, renderTable(header, rows))
+	return UNDEFINED
+}
+
+// consoleLog formats args as console.log does and prints them to stdout
+// or stderr.
+func (i *Interpreter) consoleLog(stderr bool, args []Object) Object {
+	text, err := i.formatLogArgs(args)
+	if err != nil {
+		return err
+	}
+	i.consolePrint(stderr, text)
+	return UNDEFINED
+}
+
+func formatTableKey(key Object) string {
+	if s, ok := key.(*String); ok {
+		return s.Value
+	}
+	return key.Inspect()
+}
+
+// renderTable draws a table with box-drawing characters, centring each
+// cell in its column:
+//
+//	┌─────────┬─────┐
+//	│ (index) │  a  │
+//	├─────────┼─────┤
+//	│    0    │  1  │
+//	└─────────┴─────┘
+func renderTable(header []string, rows [][]string) string {
+	widths := make([]int, len(header))
+	for c, h := range header {
+		widths[c] = displayWidth(h)
+	}
+	for _, row := range rows {
+		for c, cell := range row {
+			widths[c] = max(widths[c], displayWidth(cell))
+		}
+	}
+
+	divider := func(left, middle, right string) string {
+		parts := make([]string, len(widths))
+		for c, w := range widths {
+			parts[c] = strings.Repeat("─", w+2)
+		}
+		return left + strings.Join(parts, middle) + right
+	}
+	renderRow := func(row []string) string {
+		parts := make([]string, len(row))
+		for c, cell := range row {
+			needed := widths[c] - displayWidth(cell)
+			parts[c] = strings.Repeat(" ", needed/2) + cell + strings.Repeat(" ", needed-needed/2)
+		}
+		return "│ " + strings.Join(parts, " │ ") + " │"
+	}
+
+	lines := []string{divider("┌", "┬", "┐"), renderRow(header), divider("├", "┼", "┤")}
+	for _, row := range rows {
+		lines = append(lines, renderRow(row))
+	}
+	lines = append(lines, divider("└", "┴", "┘"))
+	return strings.Join(lines, "\n")
+}
+
+// displayWidth approximates how many columns s takes up in a terminal by
+// counting its characters.
+func displayWidth(s string) int {
+	return utf8.RuneCountInString(s)
+}
@@ -0,0 +1,388 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// This file implements the abstract operations the JavaScript specification
+// uses to convert values from one type to another: ToPrimitive, ToNumber,
+// ToString, ToPropertyKey and ToBoolean (isTruthy). Operators and built-in
+// functions go through these instead of inspecting types themselves, which
+// is what makes "1" + 2, "5" * "2" and [1, 2] == "1,2" behave as they do in
+// JavaScript.
+
+// isObject reports whether v is an object rather than a primitive
+// (undefined, null, a boolean, a number, a string or a symbol).
+func isObject(v Object) bool {
+	_, ok := v.(propertyHolder)
+	return ok
+}
+
+// toPrimitive converts an object to a primitive. An object can take full
+// control of this with a [Symbol.toPrimitive] method, which is passed hint;
+// otherwise its valueOf and toString methods are called. hint says which
+// kind of value the caller would prefer: with "string", toString is tried
+// first; otherwise ("number" or "default") valueOf is. Primitives are
+// returned unchanged.
+func (i *Interpreter) toPrimitive(v Object, hint string) Object {
+	if !isObject(v) {
+		return v
+	}
+	exotic := i.getProperty(v, symbolToPrimitive)
+	if isError(exotic) {
+		return exotic
+	}
+	if exotic != UNDEFINED && exotic != NULL {
+		if !isCallable(exotic) {
+			return newTypeError("Symbol.toPrimitive is not a function")
+		}
+		result := i.applyFunction(exotic, v, []Object{&String{Value: hint}})
+		if isError(result) || !isObject(result) {
+			return result
+		}
+		return newTypeError("Cannot convert object to primitive value")
+	}
+	return i.ordinaryToPrimitive(v, hint)
+}
+
+// ordinaryToPrimitive converts an object to a primitive through its
+// valueOf and toString methods, in the order hint prefers.
+func (i *Interpreter) ordinaryToPrimitive(v Object, hint string) Object {
+	methods := []string{"valueOf", "toString"}
+	if hint == "string" {
+		methods = []string{"toString", "valueOf"}
+	}
+	for _, name := range methods {
+		method := i.getProperty(v, &String{Value: name})
+		if isError(method) {
+			return method
+		}
+		if !isCallable(method) {
+			continue
+		}
+		result := i.applyFunction(method, v, nil)
+		if isError(result) || !isObject(result) {
+			return result
+		}
+	}
+	return newTypeError("Cannot convert object to primitive value")
+}
+
+// toNumber converts a value to a number: undefined becomes NaN, null
+// becomes 0, booleans become 0 or 1, strings are parsed (see
+// stringToNumber) and objects are first converted to primitives.
+func (i *Interpreter) toNumber(v Object) (float64, *Error) {
+	switch v := v.(type) {
+	case *Number:
+		return v.Value, nil
+	case *Undefined:
+		return math.NaN(), nil
+	case *Null:
+		return 0, nil
+	case *Boolean:
+		if v.Value {
+			return 1, nil
+		}
+		return 0, nil
+	case *String:
+		return stringToNumber(v.Value), nil
+	case *Symbol:
+		return 0, newTypeError("Cannot convert a Symbol value to a number")
+	case *BigInt:
+		return 0, newTypeError("Cannot convert a BigInt value to a number")
+	}
+	prim := i.toPrimitive(v, "number")
+	if err, ok := prim.(*Error); ok {
+		return 0, err
+	}
+	return i.toNumber(prim)
+}
+
+// toIntegerOrInfinity converts a value to a number and drops its fractional
+// part. NaN becomes 0. Built-ins use it for index and count arguments.
+func (i *Interpreter) toIntegerOrInfinity(v Object) (float64, *Error) {
+	n, err := i.toNumber(v)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(n) {
+		return 0, nil
+	}
+	return math.Trunc(n), nil
+}
+
+// toLength converts a value to an integer suitable as the length of an
+// array-like object or an index into a string: between 0 and 2^53-1.
+func (i *Interpreter) toLength(v Object) (int, *Error) {
+	n, err := i.toIntegerOrInfinity(v)
+	if err != nil {
+		return 0, err
+	}
+	return int(math.Max(0, math.Min(n, maxSafeInteger))), nil
+}
+
+// toString converts a value to a string. Objects are first converted to
+// primitives, preferring their toString method.
+func (i *Interpreter) toString(v Object) (*String, *Error) {
+	switch v := v.(type) {
+	case *String:
+		return v, nil
+	case *Number:
+		return &String{Value: numberToString(v.Value)}, nil
+	case *Boolean:
+		return &String{Value: strconv.FormatBool(v.Value)}, nil
+	case *BigInt:
+		return &String{Value: v.Value.String()}, nil
+	case nil, *Undefined:
+		return &String{Value: "undefined"}, nil
+	case *Null:
+		return &String{Value: "null"}, nil
+	case *Symbol:
+		return nil, newTypeError("Cannot convert a Symbol value to a string")
+	}
+	prim := i.toPrimitive(v, "string")
+	if err, ok := prim.(*Error); ok {
+		return nil, err
+	}
+	return i.toString(prim)
+}
+
+// toPropertyKey converts a value used as a property key into the string
+// or symbol that actually names the property, so that arr[1] and arr["1"]
+// are the same property.
+func (i *Interpreter) toPropertyKey(key Object) (Object, *Error) {
+	prim := i.toPrimitive(key, "string")
+	if err, ok := prim.(*Error); ok {
+		return nil, err
+	}
+	if s, ok := prim.(*Symbol); ok {
+		return s, nil
+	}
+	return i.toString(prim)
+}
+
+// keyString returns the name of a property key for error messages and for
+// comparisons with names like "length". Symbols are described as
+// Symbol(description), which no string key can be confused with in those
+// comparisons.
+func keyString(key Object) string {
+	if s, ok := key.(*String); ok {
+		return s.Value
+	}
+	return key.Inspect()
+}
+
+// stringToNumber parses a string the way Number("...") does. Surrounding
+// whitespace is ignored, the empty string is 0, "Infinity" and the 0x, 0o
+// and 0b prefixes are understood, and anything else that isn't a decimal
+// number is NaN.
+func stringToNumber(s string) float64 {
+	units := utf16Units(s)
+	from, to := 0, len(units)
+	for from < to && isWhitespace(units[from]) {
+		from++
+	}
+	for to > from && isWhitespace(units[to-1]) {
+		to--
+	}
+	s = stringFromUnits(units[from:to])
+
+	switch s {
+	case "":
+		return 0
+	case "Infinity", "+Infinity":
+		return math.Inf(1)
+	case "-Infinity":
+		return math.Inf(-1)
+	}
+	if len(s) > 2 && s[0] == '0' {
+		base := 0
+		switch s[1] {
+		case 'x', 'X':
+			base = 16
+		case 'o', 'O':
+			base = 8
+		case 'b', 'B':
+			base = 2
+		}
+		if base != 0 {
+			return parseIntegerDigits(s[2:], base)
+		}
+	}
+	// Go's ParseFloat also accepts "inf", "nan", hex floats and
+	// underscores, none of which JavaScript allows, so check the
+	// characters first.
+	for idx := 0; idx < len(s); idx++ {
+		c := s[idx]
+		if !isDigitByte(c) && c != '.' && c != 'e' && c != 'E' && c != '+' && c != '-' {
+			return math.NaN()
+		}
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil && !isRangeError(err) {
+		return math.NaN()
+	}
+	return n
+}
+
+// parseIntegerDigits parses digits in the given base, returning NaN if
+// there are none or any of them is invalid. Numbers too large for an
+// int64 are still parsed, since JavaScript numbers go up to about 1.8e308.
+func parseIntegerDigits(digits string, base int) float64 {
+	if digits == "" {
+		return math.NaN()
+	}
+	n, ok := new(big.Int).SetString(digits, base)
+	if !ok || strings.ContainsAny(digits, "_+-") {
+		return math.NaN()
+	}
+	f, _ := new(big.Float).SetInt(n).Float64()
+	return f
+}
+
+func isDigitByte(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+func isRangeError(err error) bool {
+	numErr, ok := err.(*strconv.NumError)
+	return ok && numErr.Err == strconv.ErrRange
+}
+
+// numberToString formats a number the way JavaScript does: the shortest
+// digits that read back as the same number, in plain notation for
+// magnitudes from 1e-7 up to 1e21 and in exponential notation outside
+// that range. Negative zero is printed as "0".
+func numberToString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case f == 0:
+		return "0"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	case f < 0:
+		return "-" + numberToString(-f)
+	}
+
+	// FormatFloat gives the shortest round-tripping digits as d.ddde±x.
+	// Following the specification, call the digits s, their count k, and
+	// the position of the decimal point relative to them n.
+	mantissa, exponent, _ := strings.Cut(strconv.FormatFloat(f, 'e', -1, 64), "e")
+	s := strings.Replace(mantissa, ".", "", 1)
+	k := len(s)
+	e, _ := strconv.Atoi(exponent)
+	n := e + 1
+
+	switch {
+	case k <= n && n <= 21:
+		return s + strings.Repeat("0", n-k)
+	case 0 < n && n <= 21:
+		return s[:n] + "." + s[n:]
+	case -6 < n && n <= 0:
+		return "0." + strings.Repeat("0", -n) + s
+	}
+	sign := "+"
+	if n-1 < 0 {
+		sign = "-"
+	}
+	exp := strconv.Itoa(abs(n - 1))
+	if k == 1 {
+		return s + "e" + sign + exp
+	}
+	return s[:1] + "." + s[1:] + "e" + sign + exp
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// toInt32 and toUint32 convert a number to a 32-bit integer by wrapping it
+// around, as the bitwise operators and some built-ins do.
+func toInt32(f float64) int32 {
+	return int32(toUint32(f))
+}
+
+func toUint32(f float64) uint32 {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0
+	}
+	return uint32(int64(math.Mod(math.Trunc(f), 1<<32)))
+}
+
+// isNullish reports whether v is null or undefined.
+func isNullish(v Object) bool {
+	return v == nil || v == NULL || v == UNDEFINED
+}
+
+// typeOf implements the typeof operator.
+func typeOf(v Object) string {
+	switch v.(type) {
+	case *Undefined:
+		return "undefined"
+	case *Number:
+		return "number"
+	case *String:
+		return "string"
+	case *Boolean:
+		return "boolean"
+	case *Symbol:
+		return "symbol"
+	case *BigInt:
+		return "bigint"
+	case *Function, *Builtin:
+		return "function"
+	}
+	return "object"
+}
+
+// looseEquals implements ==. Values of the same type compare as with ===.
+// null and undefined are equal to each other and to nothing else.
+// Otherwise the values are converted towards numbers until they can be
+// compared: strings and booleans become numbers, and objects become
+// primitives. A BigInt is compared with a number or string by value.
+func (i *Interpreter) looseEquals(left, right Object) (bool, *Error) {
+	if left.Type() == right.Type() {
+		return strictEquals(left, right), nil
+	}
+	if isNullish(left) || isNullish(right) {
+		return isNullish(left) && isNullish(right), nil
+	}
+	switch {
+	case left.Type() == BIGINT_OBJ && (right.Type() == NUMBER_OBJ || right.Type() == STRING_OBJ):
+		return looseEqualsBigInt(left.(*BigInt).Value, right), nil
+	case right.Type() == BIGINT_OBJ && (left.Type() == NUMBER_OBJ || left.Type() == STRING_OBJ):
+		return looseEqualsBigInt(right.(*BigInt).Value, left), nil
+	case left.Type() == NUMBER_OBJ && right.Type() == STRING_OBJ:
+		return i.looseEquals(left, &Number{Value: stringToNumber(right.(*String).Value)})
+	case left.Type() == STRING_OBJ && right.Type() == NUMBER_OBJ:
+		return i.looseEquals(&Number{Value: stringToNumber(left.(*String).Value)}, right)
+	case left.Type() == BOOLEAN_OBJ:
+		n, _ := i.toNumber(left)
+		return i.looseEquals(&Number{Value: n}, right)
+	case right.Type() == BOOLEAN_OBJ:
+		n, _ := i.toNumber(right)
+		return i.looseEquals(left, &Number{Value: n})
+	case isObject(left) && !isObject(right):
+		prim := i.toPrimitive(left, "default")
+		if err, ok := prim.(*Error); ok {
+			return false, err
+		}
+		return i.looseEquals(prim, right)
+	case !isObject(left) && isObject(right):
+		prim := i.toPrimitive(right, "default")
+		if err, ok := prim.(*Error); ok {
+			return false, err
+		}
+		return i.looseEquals(left, prim)
+	}
+	return false, nil
+}
@@ -0,0 +1,974 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// A Date holds a single number, its time value: the number of milliseconds
+// since midnight UTC at the start of 1970, ignoring leap seconds, or NaN for
+// an invalid date. Everything else — the year, the month, the hour — is
+// worked out from the time value when asked for, either in UTC or in local
+// time, which is UTC shifted by the time zone's offset at that moment:
+//
+//	const d = new Date(0);
+//	d.getUTCHours();  // 0
+//	d.getHours();     // 1 in Paris, 19 (the day before) in New York
+//
+// The arithmetic follows the specification rather than Go's time package,
+// because JavaScript dates reach further (275,760 years either side of
+// 1970) and treat out-of-range fields in their own way: new Date(2024, 0,
+// 32) is the 1st of February. Go's time package is used only to find the
+// time zone's offset, which depends on daylight saving time.
+//
+// The current time comes from the interpreter's clock, and local time
+// from its time zone. Both can be replaced with SetClock and SetTimeZone,
+// which makes scripts that use dates reproducible.
+
+const (
+	msPerSecond = 1000
+	msPerMinute = 60 * msPerSecond
+	msPerHour   = 60 * msPerMinute
+	msPerDay    = 24 * msPerHour
+
+	// maxTimeValue is the furthest from 1970 a date can be: 100 million
+	// days either way.
+	maxTimeValue = 8.64e15
+)
+
+var (
+	weekdayNames = []string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"}
+	monthNames   = []string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+)
+
+// Date is a JavaScript Date object.
+type Date struct {
+	Hash
+	Time float64 // the time value, in milliseconds since 1970 UTC, or NaN
+}
+
+func (d *Date) Type() ObjectType { return DATE_OBJ }
+func (d *Date) Inspect() string {
+	if math.IsNaN(d.Time) {
+		return "Invalid Date"
+	}
+	return isoString(d.Time)
+}
+
+// SetTimeZone sets the time zone Date uses for local time, which is
+// time.Local unless changed. It affects methods like getHours and
+// toString, and how dates without a time zone, such as new Date(2024, 0,
+// 1), are read.
+func (i *Interpreter) SetTimeZone(loc *time.Location) {
+	i.location = loc
+}
+
+func (i *Interpreter) now() float64 {
+	return float64(i.clock.Now().UnixMilli())
+}
+
+// offsetAt returns the local time zone's offset from UTC at the time value
+// t, in milliseconds.
+func (i *Interpreter) offsetAt(t float64) float64 {
+	_, offset := time.UnixMilli(int64(t)).In(i.location).Zone()
+	return float64(offset) * msPerSecond
+}
+
+// localTime converts a time value to local time.
+func (i *Interpreter) localTime(t float64) float64 {
+	if math.IsNaN(t) {
+		return t
+	}
+	return t + i.offsetAt(t)
+}
+
+// utc converts a local time back to a time value. This is harder than the
+// other way around, as the offset to subtract depends on the answer. Around
+// a daylight saving change, the offsets a day before and a day after are
+// tried. A local time that happens twice, when the clocks go back, is read
+// as the first; one that is skipped, when they go forward, is read with
+// the offset from before the change, so 2:30 becomes 3:30.
+func (i *Interpreter) utc(local float64) float64 {
+	if math.IsNaN(local) || math.IsInf(local, 0) {
+		return math.NaN()
+	}
+	before := i.offsetAt(local - msPerDay)
+	after := i.offsetAt(local + msPerDay)
+	if before == after || i.offsetAt(local-before) == before {
+		return local - before
+	}
+	if i.offsetAt(local-after) == after {
+		return local - after
+	}
+	return local - before
+}
+
+// The functions below split a time value into its fields, and put fields
+// back together into a time value, as the specification defines them.
+
+func day(t float64) float64 { return math.Floor(t / msPerDay) }
+
+func timeWithinDay(t float64) float64 { return positiveMod(t, msPerDay) }
+
+func positiveMod(a, b float64) float64 {
+	r := math.Mod(a, b)
+	if r < 0 {
+		r += b
+	}
+	return r
+}
+
+func daysInYear(y float64) float64 {
+	switch {
+	case positiveMod(y, 4) != 0:
+		return 365
+	case positiveMod(y, 100) != 0:
+		return 366
+	case positiveMod(y, 400) != 0:
+		return 365
+	}
+	return 366
+}
+
+// dayFromYear returns the number of the day on which year y starts,
+// counting from the 1st of January 1970.
+func dayFromYear(y float64) float64 {
+	return 365*(y-1970) + math.Floor((y-1969)/4) - math.Floor((y-1901)/100) + math.Floor((y-1601)/400)
+}
+
+func yearFromTime(t float64) float64 {
+	y := math.Floor(t/(msPerDay*365.2425)) + 1970
+	for dayFromYear(y)*msPerDay > t {
+		y--
+	}
+	for dayFromYear(y+1)*msPerDay <= t {
+		y++
+	}
+	return y
+}
+
+// monthStart returns the day within the year on which month m (0 for
+// January) starts.
+func monthStart(m int, leap bool) float64 {
+	start := [12]float64{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}[m]
+	if leap && m >= 2 {
+		start++
+	}
+	return start
+}
+
+// daysInMonth returns the number of days in month m of year y.
+func daysInMonth(y float64, m int) float64 {
+	leap := daysInYear(y) == 366
+	if m == 11 {
+		return 31
+	}
+	return monthStart(m+1, leap) - monthStart(m, leap)
+}
+
+func monthFromTime(t float64) float64 {
+	year := yearFromTime(t)
+	d := day(t) - dayFromYear(year)
+	leap := daysInYear(year) == 366
+	m := 11
+	for monthStart(m, leap) > d {
+		m--
+	}
+	return float64(m)
+}
+
+func dateFromTime(t float64) float64 {
+	year := yearFromTime(t)
+	d := day(t) - dayFromYear(year)
+	return d - monthStart(int(monthFromTime(t)), daysInYear(year) == 366) + 1
+}
+
+func weekDay(t float64) float64      { return positiveMod(day(t)+4, 7) }
+func hourFromTime(t float64) float64 { return positiveMod(math.Floor(t/msPerHour), 24) }
+func minFromTime(t float64) float64  { return positiveMod(math.Floor(t/msPerMinute), 60) }
+func secFromTime(t float64) float64  { return positiveMod(math.Floor(t/msPerSecond), 60) }
+func msFromTime(t float64) float64   { return positiveMod(t, msPerSecond) }
+
+func isFinite(values ...float64) bool {
+	for _, v := range values {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+// makeTime combines hours, minutes, seconds and milliseconds into a number
+// of milliseconds. The fields may be out of range, or negative.
+func makeTime(hour, min, sec, ms float64) float64 {
+	if !isFinite(hour, min, sec, ms) {
+		return math.NaN()
+	}
+	return math.Trunc(hour)*msPerHour + math.Trunc(min)*msPerMinute + math.Trunc(sec)*msPerSecond + math.Trunc(ms)
+}
+
+// makeDay returns the day number of a date. Months past December carry
+// into the next year, and days past the end of the month into the next
+// month.
+func makeDay(year, month, date float64) float64 {
+	if !isFinite(year, month, date) {
+		return math.NaN()
+	}
+	y, m, dt := math.Trunc(year), math.Trunc(month), math.Trunc(date)
+	ym := y + math.Floor(m/12)
+	// Years this far out are invalid anyway, and would take the
+	// calculation outside the range a float64 counts exactly.
+	if math.Abs(ym) > 1e6 {
+		return math.NaN()
+	}
+	mn := int(positiveMod(m, 12))
+	return dayFromYear(ym) + monthStart(mn, daysInYear(ym) == 366) + dt - 1
+}
+
+func makeDate(day, time float64) float64 {
+	if !isFinite(day, time) {
+		return math.NaN()
+	}
+	return day*msPerDay + time
+}
+
+// timeClip makes a time value valid: NaN if it's out of range, and a whole
+// number of milliseconds otherwise.
+func timeClip(t float64) float64 {
+	if !isFinite(t) || math.Abs(t) > maxTimeValue {
+		return math.NaN()
+	}
+	return math.Trunc(t) + 0 // + 0 turns -0 into 0
+}
+
+// dateFields splits a time value into year, month, date, hours, minutes,
+// seconds and milliseconds.
+func dateFields(t float64) [7]float64 {
+	return [7]float64{yearFromTime(t), monthFromTime(t), dateFromTime(t), hourFromTime(t), minFromTime(t), secFromTime(t), msFromTime(t)}
+}
+
+// fieldsToTime is the reverse of dateFields.
+func fieldsToTime(f [7]float64) float64 {
+	return makeDate(makeDay(f[0], f[1], f[2]), makeTime(f[3], f[4], f[5], f[6]))
+}
+
+// formatYear writes a year as toString does: at least four digits, with a
+// minus sign for years before year 0.
+func formatYear(y float64) string {
+	if y < 0 {
+		return fmt.Sprintf("-%04d", int(-y))
+	}
+	return fmt.Sprintf("%04d", int(y))
+}
+
+// isoString formats a valid time value as toISOString does. Years outside
+// 0 to 9999 are written with six digits and a sign.
+func isoString(t float64) string {
+	f := dateFields(t)
+	year := fmt.Sprintf("%04d", int(f[0]))
+	if f[0] < 0 || f[0] > 9999 {
+		sign := "+"
+		if f[0] < 0 {
+			sign = "-"
+		}
+		year = fmt.Sprintf("%s%06d", sign, int(math.Abs(f[0])))
+	}
+	return fmt.Sprintf("%s-%02d-%02dT%02d:%02d:%02d.%03dZ", year, int(f[1])+1, int(f[2]), int(f[3]), int(f[4]), int(f[5]), int(f[6]))
+}
+
+// dateString formats the date part of a time value as toDateString does,
+// as in "Tue Jan 02 2024".
+func dateString(t float64) string {
+	return fmt.Sprintf("%s %s %02d %s", weekdayNames[int(weekDay(t))], monthNames[int(monthFromTime(t))], int(dateFromTime(t)), formatYear(yearFromTime(t)))
+}
+
+// timeZoneString describes the local time zone at the time value t, as in
+// "GMT+0100 (CET)". JavaScript engines name the zone in full, such as
+// "Central European Standard Time"; the abbreviation is what the Go time
+// zone database has.
+func (i *Interpreter) timeZoneString(t float64) string {
+	name, offset := time.UnixMilli(int64(t)).In(i.location).Zone()
+	sign := "+"
+	if offset < 0 {
+		sign = "-"
+		offset = -offset
+	}
+	if name == "UTC" {
+		name = "Coordinated Universal Time"
+	}
+	return fmt.Sprintf("GMT%s%02d%02d (%s)", sign, offset/3600, offset/60%60, name)
+}
+
+// timeString formats the time part of a local time, as in "15:04:05".
+func timeString(local float64) string {
+	return fmt.Sprintf("%02d:%02d:%02d", int(hourFromTime(local)), int(minFromTime(local)), int(secFromTime(local)))
+}
+
+// localeDateString and localeTimeString format a local time the way the
+// en-US locale does, as in "1/2/2024" and "3:04:05 PM".
+func localeDateString(local float64) string {
+	return fmt.Sprintf("%d/%d/%d", int(monthFromTime(local))+1, int(dateFromTime(local)), int(yearFromTime(local)))
+}
+
+func localeTimeString(local float64) string {
+	hour := int(hourFromTime(local))
+	period := "AM"
+	if hour >= 12 {
+		period = "PM"
+	}
+	if hour = hour % 12; hour == 0 {
+		hour = 12
+	}
+	return fmt.Sprintf("%d:%02d:%02d %s", hour, int(minFromTime(local)), int(secFromTime(local)), period)
+}
+
+// parseDate implements Date.parse. It accepts the ISO 8601 format that
+// toISOString produces, including its shorter forms like "2024-01-02" and
+// "2024-01-02T10:00", and falls back to the looser formats of toString,
+// toUTCString and RFC 2822 ("Tue, 02 Jan 2024 15:04:05 +0100"), along
+// with things like "January 2, 2024" and "1/2/2024". It returns NaN for
+// anything else.
+//
+// A date without a time, in the ISO format, is in UTC; any other date
+// without a time zone is in local time.
+func (i *Interpreter) parseDate(s string) float64 {
+	if t, ok := i.parseISODate(s); ok {
+		return t
+	}
+	return i.parseLegacyDate(s)
+}
+
+// dateScanner reads the parts of a date string.
+type dateScanner struct {
+	s   string
+	pos int
+}
+
+func (sc *dateScanner) more() bool { return sc.pos < len(sc.s) }
+
+func (sc *dateScanner) peek() byte {
+	if sc.pos < len(sc.s) {
+		return sc.s[sc.pos]
+	}
+	return 0
+}
+
+func (sc *dateScanner) eat(c byte) bool {
+	if sc.peek() == c {
+		sc.pos++
+		return true
+	}
+	return false
+}
+
+// digits reads exactly n digits.
+func (sc *dateScanner) digits(n int) (float64, bool) {
+	if sc.pos+n > len(sc.s) {
+		return 0, false
+	}
+	value := 0.0
+	for _, c := range []byte(sc.s[sc.pos : sc.pos+n]) {
+		if !isDigitByte(c) {
+			return 0, false
+		}
+		value = value*10 + float64(c-'0')
+	}
+	sc.pos += n
+	return value, true
+}
+
+// number reads a run of digits, returning its value and length.
+func (sc *dateScanner) number() (float64, int) {
+	start := sc.pos
+	value := 0.0
+	for sc.more() && isDigitByte(sc.peek()) {
+		value = value*10 + float64(sc.s[sc.pos]-'0')
+		sc.pos++
+	}
+	return value, sc.pos - start
+}
+
+func (i *Interpreter) parseISODate(s string) (float64, bool) {
+	sc := &dateScanner{s: s}
+	var year float64
+	var ok bool
+	if c := sc.peek(); c == '+' || c == '-' {
+		sc.pos++
+		if year, ok = sc.digits(6); !ok || c == '-' && year == 0 {
+			return 0, false
+		}
+		if c == '-' {
+			year = -year
+		}
+	} else if year, ok = sc.digits(4); !ok {
+		return 0, false
+	}
+	fields := [7]float64{year, 0, 1, 0, 0, 0, 0}
+	if sc.eat('-') {
+		if fields[1], ok = sc.digits(2); !ok || fields[1] < 1 || fields[1] > 12 {
+			return 0, false
+		}
+		fields[1]--
+		if sc.eat('-') {
+			if fields[2], ok = sc.digits(2); !ok || fields[2] < 1 {
+				return 0, false
+			}
+		}
+	}
+	if fields[2] > daysInMonth(year, int(fields[1])) {
+		return 0, false
+	}
+	if !sc.more() {
+		return timeClip(fieldsToTime(fields)), true
+	}
+
+	if !sc.eat('T') && !sc.eat('t') {
+		return 0, false
+	}
+	if fields[3], ok = sc.digits(2); !ok || !sc.eat(':') {
+		return 0, false
+	}
+	if fields[4], ok = sc.digits(2); !ok {
+		return 0, false
+	}
+	if sc.eat(':') {
+		if fields[5], ok = sc.digits(2); !ok {
+			return 0, false
+		}
+		if sc.eat('.') {
+			ms, n := sc.number()
+			if n == 0 {
+				return 0, false
+			}
+			fields[6] = math.Floor(ms / math.Pow(10, float64(n-3)))
+		}
+	}
+	if fields[3] > 24 || fields[4] > 59 || fields[5] > 59 ||
+		fields[3] == 24 && (fields[4] != 0 || fields[5] != 0 || fields[6] != 0) {
+		return 0, false
+	}
+	t := fieldsToTime(fields)
+	switch c := sc.peek(); {
+	case c == 'Z' || c == 'z':
+		sc.pos++
+	case c == '+' || c == '-':
+		sc.pos++
+		hours, ok := sc.digits(2)
+		if !ok || !sc.eat(':') {
+			return 0, false
+		}
+		minutes, ok := sc.digits(2)
+		if !ok || hours > 23 || minutes > 59 {
+			return 0, false
+		}
+		offset := hours*msPerHour + minutes*msPerMinute
+		if c == '-' {
+			offset = -offset
+		}
+		t -= offset
+	case c == 0:
+		t = i.utc(t)
+	}
+	if sc.more() {
+		return 0, false
+	}
+	return timeClip(t), true
+}
+
+// parseLegacyDate reads the looser date formats. It goes through the
+// string word by word: month names give the month, weekday names are
+// ignored, a number followed by a colon starts the time, and GMT, UTC, Z
+// or a signed number after the time give the time zone. The remaining
+// numbers are the day and year, or with no month name, the month, day and
+// year in the American order, unless the first is clearly a year.
+func (i *Interpreter) parseLegacyDate(s string) float64 {
+	sc := &dateScanner{s: strings.ToLower(s)}
+	month := -1.0
+	var numbers []float64
+	var numberLengths []int
+	fields := [7]float64{0, 0, 1, 0, 0, 0, 0}
+	hasTime := false
+	pm, am := false, false
+	offset, hasOffset := 0.0, false
+
+	for sc.more() {
+		c := sc.peek()
+		switch {
+		case c == ' ' || c == ',' || c == '/' || c == '.' || c == '\t':
+			sc.pos++
+		case c == '(':
+			// A parenthesised comment, like the time zone name toString
+			// adds.
+			for sc.more() && sc.peek() != ')' {
+				sc.pos++
+			}
+			sc.pos++
+		case (c == '+' || c == '-') && (hasTime || hasOffset):
+			sc.pos++
+			value, n := sc.number()
+			if n == 0 {
+				return math.NaN()
+			}
+			hours, minutes := value, 0.0
+			if n > 2 {
+				hours, minutes = math.Floor(value/100), math.Mod(value, 100)
+			} else if sc.eat(':') {
+				if minutes, n = sc.number(); n == 0 {
+					return math.NaN()
+				}
+			}
+			offset = hours*msPerHour + minutes*msPerMinute
+			if c == '-' {
+				offset = -offset
+			}
+			hasOffset = true
+		case c == '-':
+			sc.pos++
+		case isDigitByte(c):
+			value, n := sc.number()
+			if sc.peek() != ':' || hasTime {
+				numbers = append(numbers, value)
+				numberLengths = append(numberLengths, n)
+				continue
+			}
+			hasTime = true
+			fields[3] = value
+			sc.pos++
+			if fields[4], n = sc.number(); n == 0 {
+				return math.NaN()
+			}
+			if sc.eat(':') {
+				if fields[5], n = sc.number(); n == 0 {
+					return math.NaN()
+				}
+				if sc.eat('.') {
+					ms, n := sc.number()
+					fields[6] = math.Floor(ms / math.Pow(10, float64(n-3)))
+				}
+			}
+		case 'a' <= c && c <= 'z':
+			start := sc.pos
+			for sc.more() && 'a' <= sc.peek() && sc.peek() <= 'z' {
+				sc.pos++
+			}
+			word := sc.s[start:sc.pos]
+			switch {
+			case word == "am":
+				am = true
+			case word == "pm":
+				pm = true
+			case word == "gmt" || word == "utc" || word == "ut" || word == "z":
+				hasOffset = true
+			case len(word) >= 3 && isWeekdayName(word):
+			case len(word) >= 3 && monthIndex(word) >= 0:
+				month = float64(monthIndex(word))
+			default:
+				return math.NaN()
+			}
+		default:
+			return math.NaN()
+		}
+	}
+
+	isYear := func(idx int) bool { return numberLengths[idx] > 2 || numbers[idx] > 31 }
+	switch {
+	case month >= 0 && len(numbers) == 2:
+		fields[1] = month
+		fields[2], fields[0] = numbers[0], numbers[1]
+		if isYear(0) {
+			fields[0], fields[2] = numbers[0], numbers[1]
+		}
+	case month < 0 && len(numbers) == 3:
+		if isYear(0) {
+			fields[0], fields[1], fields[2] = numbers[0], numbers[1]-1, numbers[2]
+		} else {
+			fields[1], fields[2], fields[0] = numbers[0]-1, numbers[1], numbers[2]
+		}
+	default:
+		return math.NaN()
+	}
+	if fields[0] < 50 && !isYearWritten(numbers, numberLengths, fields[0]) {
+		fields[0] += 2000
+	} else if fields[0] < 100 && !isYearWritten(numbers, numberLengths, fields[0]) {
+		fields[0] += 1900
+	}
+
+	if fields[1] < 0 || fields[1] > 11 || fields[2] < 1 || fields[2] > daysInMonth(fields[0], int(fields[1])) ||
+		fields[3] > 24 || fields[4] > 59 || fields[5] > 59 {
+		return math.NaN()
+	}
+	if pm || am {
+		if fields[3] < 1 || fields[3] > 12 {
+			return math.NaN()
+		}
+		fields[3] = math.Mod(fields[3], 12)
+		if pm {
+			fields[3] += 12
+		}
+	}
+	t := fieldsToTime(fields)
+	if hasOffset {
+		t -= offset
+	} else {
+		t = i.utc(t)
+	}
+	return timeClip(t)
+}
+
+// isYearWritten reports whether year was written with more than two
+// digits, like 0024, in which case it isn't a two-digit year to expand.
+func isYearWritten(numbers []float64, lengths []int, year float64) bool {
+	for idx, n := range numbers {
+		if n == year && lengths[idx] > 2 {
+			return true
+		}
+	}
+	return false
+}
+
+// monthIndex returns the month a word names, matching on its first three
+// letters as in "Jan" or "january", or -1.
+func monthIndex(word string) int {
+	for idx, name := range monthNames {
+		if strings.HasPrefix(word, strings.ToLower(name)) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func isWeekdayName(word string) bool {
+	for _, name := range weekdayNames {
+		if strings.HasPrefix(word, strings.ToLower(name)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (i *Interpreter) setupDate() {
+	proto := i.datePrototype
+	if i.clock == nil {
+		i.clock = systemClock{}
+	}
+	if i.location == nil {
+		i.location = time.Local
+	}
+
+	thisDate := func(this Object) (*Date, *Error) {
+		d, ok := this.(*Date)
+		if !ok {
+			return nil, newTypeError("this is not a Date object.")
+		}
+		return d, nil
+	}
+	// timeMethod defines a method that works on the date's time value,
+	// in local time or UTC.
+	timeMethod := func(name string, local bool, fn func(d *Date, t float64, args []Object) Object) {
+		i.defineMethod(proto, name, func(this Object, args ...Object) Object {
+			d, err := thisDate(this)
+			if err != nil {
+				return err
+			}
+			t := d.Time
+			if local {
+				t = i.localTime(t)
+			}
+			return fn(d, t, args)
+		})
+	}
+
+	getters := []struct {
+		name string
+		get  func(float64) float64
+	}{
+		{"FullYear", yearFromTime},
+		{"Month", monthFromTime},
+		{"Date", dateFromTime},
+		{"Day", weekDay},
+		{"Hours", hourFromTime},
+		{"Minutes", minFromTime},
+		{"Seconds", secFromTime},
+		{"Milliseconds", msFromTime},
+	}
+	for _, getter := range getters {
+		get := func(d *Date, t float64, args []Object) Object {
+			if math.IsNaN(t) {
+				return &Number{Value: math.NaN()}
+			}
+			return &Number{Value: getter.get(t)}
+		}
+		timeMethod("get"+getter.name, true, get)
+		timeMethod("getUTC"+getter.name, false, get)
+	}
+	timeValue := func(d *Date, t float64, args []Object) Object {
+		return &Number{Value: t}
+	}
+	timeMethod("getTime", false, timeValue)
+	timeMethod("valueOf", false, timeValue)
+	timeMethod("getTimezoneOffset", false, func(d *Date, t float64, args []Object) Object {
+		if math.IsNaN(t) {
+			return &Number{Value: math.NaN()}
+		}
+		return &Number{Value: (t - i.localTime(t)) / msPerMinute}
+	})
+
+	// Each setter replaces a run of fields, starting at the one it is
+	// named after: setMinutes(min, sec, ms) sets the minutes and,
+	// optionally, the seconds and milliseconds. The fields are indices
+	// into dateFields.
+	setters := []struct {
+		name         string
+		first, count int
+	}{
+		{"FullYear", 0, 3},
+		{"Month", 1, 2},
+		{"Date", 2, 1},
+		{"Hours", 3, 4},
+		{"Minutes", 4, 3},
+		{"Seconds", 5, 2},
+		{"Milliseconds", 6, 1},
+	}
+	for _, setter := range setters {
+		for _, local := range []bool{true, false} {
+			name := "setUTC" + setter.name
+			if local {
+				name = "set" + setter.name
+			}
+			timeMethod(name, local, func(d *Date, t float64, args []Object) Object {
+				values := make([]float64, 0, setter.count)
+				for k := 0; k < setter.count && (k == 0 || k < len(args)); k++ {
+					n, err := i.toNumber(argAt(args, k))
+					if err != nil {
+						return err
+					}
+					values = append(values, n)
+				}
+				if math.IsNaN(t) {
+					// Only setFullYear can make an invalid date valid,
+					// filling in the other fields from 1970-01-01.
+					if setter.first != 0 {
+						return &Number{Value: math.NaN()}
+					}
+					t = 0
+				}
+				fields := dateFields(t)
+				copy(fields[setter.first:], values)
+				u := fieldsToTime(fields)
+				if local {
+					u = i.utc(u)
+				}
+				d.Time = timeClip(u)
+				return &Number{Value: d.Time}
+			})
+		}
+	}
+	timeMethod("setTime", false, func(d *Date, t float64, args []Object) Object {
+		n, err := i.toNumber(argAt(args, 0))
+		if err != nil {
+			return err
+		}
+		d.Time = timeClip(n)
+		return &Number{Value: d.Time}
+	})
+
+	// formatter defines a method that formats a valid date, and gives
+	// "Invalid Date" for an invalid one.
+	formatter := func(name string, format func(t float64) string) {
+		timeMethod(name, false, func(d *Date, t float64, args []Object) Object {
+			if math.IsNaN(t) {
+				return &String{Value: "Invalid Date"}
+			}
+			return &String{Value: format(t)}
+		})
+	}
+	formatter("toString", func(t float64) string {
+		local := i.localTime(t)
+		return dateString(local) + " " + timeString(local) + " " + i.timeZoneString(t)
+	})
+	formatter("toDateString", func(t float64) string { return dateString(i.localTime(t)) })
+	formatter("toTimeString", func(t float64) string {
+		return timeString(i.localTime(t)) + " " + i.timeZoneString(t)
+	})
+	formatter("toUTCString", func(t float64) string {
+		return fmt.Sprintf("%s, %02d %s %s %s GMT", weekdayNames[int(weekDay(t))], int(dateFromTime(t)), monthNames[int(monthFromTime(t))], formatYear(yearFromTime(t)), timeString(t))
+	})
+	toUTCString, _ := proto.GetOwn(&String{Value: "toUTCString"})
+	proto.Set(&String{Value: "toGMTString"}, toUTCString)
+
+	// The locale methods format dates the way the en-US locale does,
+	// whatever locale is asked for. The one option they understand is
+	// timeZone, which changes the zone the date is shown in.
+	localeFormatter := func(name string, format func(local float64) string) {
+		timeMethod(name, false, func(d *Date, t float64, args []Object) Object {
+			if math.IsNaN(t) {
+				return &String{Value: "Invalid Date"}
+			}
+			loc, err := i.localeTimeZone(argAt(args, 1))
+			if err != nil {
+				return err
+			}
+			_, offset := time.UnixMilli(int64(t)).In(loc).Zone()
+			return &String{Value: format(t + float64(offset)*msPerSecond)}
+		})
+	}
+	localeFormatter("toLocaleDateString", localeDateString)
+	localeFormatter("toLocaleTimeString", localeTimeString)
+	localeFormatter("toLocaleString", func(local float64) string {
+		return localeDateString(local) + ", " + localeTimeString(local)
+	})
+
+	timeMethod("toISOString", false, func(d *Date, t float64, args []Object) Object {
+		if math.IsNaN(t) {
+			return newRangeError("Invalid time value")
+		}
+		return &String{Value: isoString(t)}
+	})
+	// toJSON works on any object, not only dates: JSON.stringify calls it
+	// for dates, and it hands over to toISOString.
+	i.defineMethod(proto, "toJSON", func(this Object, args ...Object) Object {
+		if isNullish(this) {
+			return newTypeError("Date.prototype.toJSON called on null or undefined")
+		}
+		tv := i.toPrimitive(this, "number")
+		if isError(tv) {
+			return tv
+		}
+		if n, ok := tv.(*Number); ok && !isFinite(n.Value) {
+			return NULL
+		}
+		toISOString := i.getProperty(this, &String{Value: "toISOString"})
+		if isError(toISOString) {
+			return toISOString
+		}
+		if !isCallable(toISOString) {
+			return newTypeError("toISOString is not a function")
+		}
+		return i.applyFunction(toISOString, this, nil)
+	})
+	// Dates prefer to become strings, so date + "" and `${date}` give
+	// toString's output; only arithmetic like date2 - date1 uses the time
+	// value.
+	proto.Set(symbolToPrimitive, i.newBuiltin("[Symbol.toPrimitive]", func(this Object, args ...Object) Object {
+		if !isObject(this) {
+			return newTypeError("Date.prototype[Symbol.toPrimitive] called on non-object")
+		}
+		hint, ok := argAt(args, 0).(*String)
+		if !ok || hint.Value != "string" && hint.Value != "number" && hint.Value != "default" {
+			return newTypeError("Invalid hint: %s", argAt(args, 0).Inspect())
+		}
+		if hint.Value == "default" {
+			return i.ordinaryToPrimitive(this, "string")
+		}
+		return i.ordinaryToPrimitive(this, hint.Value)
+	}))
+
+	ctor := i.newBuiltin("Date", func(this Object, args ...Object) Object {
+		d := &Date{Hash: Hash{Prototype: proto}}
+		switch len(args) {
+		case 0:
+			d.Time = timeClip(i.now())
+		case 1:
+			if other, ok := args[0].(*Date); ok {
+				d.Time = other.Time
+				break
+			}
+			value := i.toPrimitive(args[0], "default")
+			if err, ok := value.(*Error); ok {
+				return err
+			}
+			if s, ok := value.(*String); ok {
+				d.Time = i.parseDate(s.Value)
+				break
+			}
+			n, err := i.toNumber(value)
+			if err != nil {
+				return err
+			}
+			d.Time = timeClip(n)
+		default:
+			t, err := i.dateFromFields(args)
+			if err != nil {
+				return err
+			}
+			d.Time = timeClip(i.utc(t))
+		}
+		return d
+	})
+	// Called without new, Date ignores its arguments and returns the
+	// current time as a string.
+	ctor.call = func(this Object, args ...Object) Object {
+		t := timeClip(i.now())
+		local := i.localTime(t)
+		return &String{Value: dateString(local) + " " + timeString(local) + " " + i.timeZoneString(t)}
+	}
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+
+	i.defineMethod(&ctor.Hash, "now", func(this Object, args ...Object) Object {
+		return &Number{Value: i.now()}
+	})
+	i.defineMethod(&ctor.Hash, "parse", func(this Object, args ...Object) Object {
+		s, err := i.stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		return &Number{Value: i.parseDate(s.Value)}
+	})
+	i.defineMethod(&ctor.Hash, "UTC", func(this Object, args ...Object) Object {
+		if len(args) == 0 {
+			return &Number{Value: math.NaN()}
+		}
+		t, err := i.dateFromFields(args)
+		if err != nil {
+			return err
+		}
+		return &Number{Value: timeClip(t)}
+	})
+	i.env.Set("Date", ctor)
+}
+
+// dateFromFields reads the year, month, day, hours, minutes, seconds and
+// milliseconds passed to new Date or Date.UTC, and returns the time they
+// make, before any time zone is applied. Only the year is required; the
+// day defaults to 1 and the others to 0. Years 0 to 99 mean 1900 to 1999.
+func (i *Interpreter) dateFromFields(args []Object) (float64, *Error) {
+	fields := [7]float64{0, 0, 1, 0, 0, 0, 0}
+	for k := 0; k < len(fields) && k < len(args); k++ {
+		n, err := i.toNumber(args[k])
+		if err != nil {
+			return 0, err
+		}
+		fields[k] = n
+	}
+	if y := math.Trunc(fields[0]); !math.IsNaN(y) && 0 <= y && y <= 99 {
+		fields[0] = 1900 + y
+	}
+	return fieldsToTime(fields), nil
+}
+
+// localeTimeZone reads the timeZone option of the toLocale methods,
+// defaulting to the interpreter's time zone.
+func (i *Interpreter) localeTimeZone(options Object) (*time.Location, *Error) {
+	if isNullish(options) {
+		return i.location, nil
+	}
+	zone := i.getProperty(options, &String{Value: "timeZone"})
+	if err, ok := zone.(*Error); ok {
+		return nil, err
+	}
+	if zone == UNDEFINED {
+		return i.location, nil
+	}
+	name, err := i.toString(zone)
+	if err != nil {
+		return nil, err
+	}
+	loc, loadErr := time.LoadLocation(name.Value)
+	if loadErr != nil || name.Value == "" || name.Value == "Local" {
+		return nil, newRangeError("Invalid time zone specified: %s", name.Value)
+	}
+	return loc, nil
+}
@@ -0,0 +1,208 @@
+package interpreter
+
+import "github.com/biosbuddha/golemjs/internal/ast"
+
+// Destructuring takes values apart and stores the pieces in several
+// targets at once:
+//
+//	const { name, tags: [first, ...others] = [] } = config;
+//
+// The same patterns appear in declarations, assignments, function
+// parameters and for...of loops, which only differ in what storing a value
+// in a plain name means: declaring a let, const or var, or assigning to an
+// existing variable.
+
+// bindPattern stores value in target, which is a name, a property access
+// or a destructuring pattern. kind is the declaration the binding belongs
+// to, "var", "let" or "const", or "" for an assignment. Function parameters
+// are bound like let declarations.
+func (i *Interpreter) bindPattern(target ast.Expression, value Object, kind string, env *Environment) Object {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		return i.bindName(target.Value, value, kind, env)
+	case *ast.MemberExpression:
+		object := i.eval(target.Object, env)
+		if isError(object) {
+			return object
+		}
+		key := i.evalPropertyKey(target, env)
+		if isError(key) {
+			return key
+		}
+		if err := i.setProperty(object, key, value); err != nil {
+			return err
+		}
+		return nil
+	case *ast.AssignmentPattern:
+		if value == UNDEFINED {
+			value = i.eval(target.Right, env)
+			if isError(value) {
+				return value
+			}
+			if ident, ok := target.Left.(*ast.Identifier); ok {
+				nameAnonymousFunction(value, ident.Value)
+			}
+		}
+		return i.bindPattern(target.Left, value, kind, env)
+	case *ast.ArrayPattern:
+		return i.bindArrayPattern(target, value, kind, env)
+	case *ast.ObjectPattern:
+		return i.bindObjectPattern(target, value, kind, env)
+	}
+	return newSyntaxError("Invalid destructuring assignment target")
+}
+
+// bindName stores value in the variable name, declaring it first unless
+// this is an assignment.
+func (i *Interpreter) bindName(name string, value Object, kind string, env *Environment) Object {
+	switch kind {
+	case "var":
+		env.FunctionScope().Set(name, value)
+	case "let":
+		env.Set(name, value)
+	case "const":
+		env.SetConst(name, value)
+	default:
+		if result := i.assignVariable(name, value, env); isError(result) {
+			return result
+		}
+	}
+	return nil
+}
+
+// bindArrayPattern destructures an iterable: each element of the pattern
+// takes the iterable's next value, or undefined once it runs out. The
+// iterator is closed if the pattern doesn't need all of its values.
+func (i *Interpreter) bindArrayPattern(pattern *ast.ArrayPattern, value Object, kind string, env *Environment) Object {
+	it, err := i.getIterator(value)
+	if err != nil {
+		return err
+	}
+	done := false
+	next := func() (Object, *Error) {
+		if done {
+			return UNDEFINED, nil
+		}
+		value, finished, err := i.iteratorStep(it)
+		if err != nil || finished {
+			done = true
+			return UNDEFINED, err
+		}
+		return value, nil
+	}
+
+	for _, element := range pattern.Elements {
+		if rest, ok := element.(*ast.RestElement); ok {
+			values := []Object{}
+			for !done {
+				value, err := next()
+				if err != nil {
+					return err
+				}
+				if !done {
+					values = append(values, value)
+				}
+			}
+			if result := i.bindPattern(rest.Argument, i.newArray(values), kind, env); isError(result) {
+				return result
+			}
+			continue
+		}
+
+		value, err := next()
+		if err != nil {
+			return err
+		}
+		if element == nil {
+			continue
+		}
+		if result := i.bindPattern(element, value, kind, env); isError(result) {
+			if !done {
+				i.iteratorClose(it)
+			}
+			return result
+		}
+	}
+
+	if !done {
+		if err := i.iteratorClose(it); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bindObjectPattern destructures the properties of a value. Primitives
+// other than null and undefined can be destructured too, reading the
+// properties they inherit, as in const { length } = "abc".
+func (i *Interpreter) bindObjectPattern(pattern *ast.ObjectPattern, value Object, kind string, env *Environment) Object {
+	if isNullish(value) {
+		if len(pattern.Properties) == 0 {
+			return newTypeError("Cannot destructure '%s' as it is %s.", value.Inspect(), value.Inspect())
+		}
+		key := pattern.Properties[0].Key.String()
+		return newTypeError("Cannot destructure property '%s' of '%s' as it is %s.", key, value.Inspect(), value.Inspect())
+	}
+
+	used := []Object{}
+	for _, prop := range pattern.Properties {
+		key := i.evalPatternKey(prop, env)
+		if isError(key) {
+			return key
+		}
+		used = append(used, key)
+		if result := i.bindPattern(prop.Value, i.getProperty(value, key), kind, env); isError(result) {
+			return result
+		}
+	}
+
+	if pattern.Rest == nil {
+		return nil
+	}
+	rest := NewHash(i.objectPrototype)
+	for _, key := range i.ownPropertyKeys(value) {
+		if containsKey(used, key) {
+			continue
+		}
+		rest.Set(key, i.getProperty(value, key))
+	}
+	return i.bindPattern(pattern.Rest, rest, kind, env)
+}
+
+// evalPatternKey evaluates the key of an object pattern property, which is
+// written the same way as a key in an object literal.
+func (i *Interpreter) evalPatternKey(prop *ast.Property, env *Environment) Object {
+	var key Object
+	if ident, ok := prop.Key.(*ast.Identifier); ok && !prop.Computed {
+		key = &String{Value: ident.Value}
+	} else {
+		key = i.eval(prop.Key, env)
+		if isError(key) {
+			return key
+		}
+	}
+	k, err := i.toPropertyKey(key)
+	if err != nil {
+		return err
+	}
+	return k
+}
+
+// containsKey reports whether keys holds key, comparing as property keys.
+func containsKey(keys []Object, key Object) bool {
+	for _, k := range keys {
+		if k.(Hashable).HashKey() == key.(Hashable).HashKey() {
+			return true
+		}
+	}
+	return false
+}
+
+// nameAnonymousFunction gives an anonymous function the name of the
+// variable or property it is being stored in, as JavaScript does for
+// const f = function () {}.
+func nameAnonymousFunction(value Object, name string) {
+	if fn, ok := value.(*Function); ok && fn.Name == "" {
+		fn.Name = name
+	}
+}
@@ -0,0 +1,79 @@
+package interpreter
+
+import "context"
+
+// The methods here are for programs embedding the interpreter, which the
+// golemjs package wraps in a friendlier API: they let Go code read and
+// define global variables, create values scripts can use, and call
+// JavaScript functions.
+
+// Global returns the value of the global variable name.
+func (i *Interpreter) Global(name string) (Object, bool) {
+	return i.env.Get(name)
+}
+
+// SetGlobal defines the global variable name, or assigns to it if it's
+// already defined.
+func (i *Interpreter) SetGlobal(name string, value Object) {
+	i.env.Set(name, value)
+}
+
+// NewObject creates an empty object inheriting from Object.prototype, as
+// {} does.
+func (i *Interpreter) NewObject() *Hash {
+	return NewHash(i.objectPrototype)
+}
+
+// NewArray creates an array holding elements, as [a, b, c] does.
+func (i *Interpreter) NewArray(elements []Object) *Array {
+	return i.newArray(elements)
+}
+
+// NewBuiltin creates a function that calls fn. Like the functions the
+// interpreter defines itself, fn reports an error by returning one, which
+// the script calling it sees thrown; see Throw.
+func (i *Interpreter) NewBuiltin(name string, fn BuiltinFunction) *Builtin {
+	return i.newBuiltin(name, fn)
+}
+
+// Context returns the context the script being evaluated was given, for
+// the Go functions it calls, or context.Background() outside any script.
+func (i *Interpreter) Context() context.Context {
+	return i.usage.ctx
+}
+
+// CallContext calls fn with this and args, as a script calling it would,
+// and returns its result or the error it threw. this is nil for a call
+// that isn't a method call. Called from outside any script, it then runs
+// the event loop until idle, as Eval does, and stops when ctx is done;
+// called from a Go function that a script called, it's part of that
+// script, and ctx is ignored.
+func (i *Interpreter) CallContext(ctx context.Context, fn Object, this Object, args []Object) Object {
+	if !isCallable(fn) {
+		return newTypeError("%s is not a function", inspect(fn, defaultInspectDepth))
+	}
+	if i.usage.running {
+		return i.applyFunction(fn, this, args)
+	}
+	return i.runWithin(ctx, func() Object {
+		result := i.applyFunction(fn, this, args)
+		if err := i.runUntilIdle(); err != nil && !isError(result) {
+			return err
+		}
+		return result
+	})
+}
+
+// Throw returns an error carrying value, for a Go function to return as
+// if it had thrown value: a promise the error rejects has value as its
+// reason, and Thrown returns it.
+func Throw(value Object) *Error {
+	return thrownError(value)
+}
+
+// Thrown returns the value a script sees for err: what was thrown, or for
+// an error raised by the interpreter itself, an error object such as a
+// TypeError.
+func (i *Interpreter) Thrown(err *Error) Object {
+	return i.thrownValue(err)
+}
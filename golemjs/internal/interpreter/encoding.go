@@ -0,0 +1,335 @@
+package interpreter
+
+import (
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// TextEncoder and TextDecoder convert between strings and their UTF-8
+// bytes, the encoding files and network protocols almost always use:
+//
+//	new TextEncoder().encode("é");                        // Uint8Array(2) [ 195, 169 ]
+//	new TextDecoder().decode(new Uint8Array([195, 169]));  // 'é'
+//
+// JavaScript strings can hold unpaired surrogates, which UTF-8 can't
+// represent, so the encoder writes them as U+FFFD, the replacement
+// character. The decoder does the same for bytes that aren't valid UTF-8,
+// unless it was created with { fatal: true }, which makes them a
+// TypeError instead.
+
+// TextEncoder encodes strings as UTF-8.
+type TextEncoder struct {
+	Hash
+}
+
+func (te *TextEncoder) Type() ObjectType { return TEXT_ENCODER_OBJ }
+func (te *TextEncoder) Inspect() string  { return "TextEncoder {}" }
+
+func (te *TextEncoder) accessor(name string) (Object, bool) {
+	if name == "encoding" {
+		return &String{Value: "utf-8"}, true
+	}
+	return nil, false
+}
+
+// TextDecoder decodes UTF-8 bytes into strings. Passing { stream: true }
+// to decode lets the input arrive in chunks: a character split between
+// two chunks is held back until the rest of it arrives, instead of being
+// decoded as an error.
+type TextDecoder struct {
+	Hash
+	fatal     bool
+	ignoreBOM bool
+	decoder   utf8Decoder
+
+	// bomSeen records that the start of the stream has been decoded, so
+	// only a byte order mark there is removed.
+	bomSeen bool
+}
+
+func (td *TextDecoder) Type() ObjectType { return TEXT_DECODER_OBJ }
+func (td *TextDecoder) Inspect() string  { return "TextDecoder {}" }
+
+func (td *TextDecoder) accessor(name string) (Object, bool) {
+	switch name {
+	case "encoding":
+		return &String{Value: "utf-8"}, true
+	case "fatal":
+		return nativeBoolToBooleanObject(td.fatal), true
+	case "ignoreBOM":
+		return nativeBoolToBooleanObject(td.ignoreBOM), true
+	}
+	return nil, false
+}
+
+// utf8Labels are the names the Encoding Standard accepts for UTF-8.
+var utf8Labels = map[string]bool{
+	"unicode-1-1-utf-8": true,
+	"unicode11utf8":     true,
+	"unicode20utf8":     true,
+	"utf-8":             true,
+	"utf8":              true,
+	"x-unicode20utf8":   true,
+}
+
+// encodeUTF8 encodes UTF-16 code units as UTF-8, replacing unpaired
+// surrogates with U+FFFD. It stops before the first character that
+// doesn't fit in limit bytes, if limit isn't -1, and returns the bytes and
+// the number of code units encoded.
+func encodeUTF8(units []uint16, limit int) ([]byte, int) {
+	var out []byte
+	read := 0
+	for read < len(units) {
+		r, size := rune(units[read]), 1
+		if utf16.IsSurrogate(r) {
+			if r < 0xDC00 && read+1 < len(units) && units[read+1] >= 0xDC00 && units[read+1] < 0xE000 {
+				r, size = utf16.DecodeRune(r, rune(units[read+1])), 2
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		if limit >= 0 && len(out)+utf8.RuneLen(r) > limit {
+			break
+		}
+		out = utf8.AppendRune(out, r)
+		read += size
+	}
+	return out, read
+}
+
+// utf8Decoder decodes UTF-8 following the Encoding Standard. Each maximal
+// invalid sequence becomes a single error, which is what browsers and
+// Node do, while Go's own decoder reports an error per byte. The decoder
+// keeps the state of an incomplete character between calls.
+type utf8Decoder struct {
+	codePoint    rune
+	needed, seen int
+	lower, upper byte
+}
+
+// decode decodes data, appending to out. If flush is set, an incomplete
+// character at the end is an error and the decoder is reset; otherwise it
+// is kept for the next call. onError is called for each invalid sequence,
+// and decoding stops if it returns false.
+func (d *utf8Decoder) decode(out *strings.Builder, data []byte, flush bool, onError func() bool) bool {
+	if d.lower == 0 {
+		d.lower, d.upper = 0x80, 0xBF
+	}
+	for idx := 0; idx < len(data); idx++ {
+		b := data[idx]
+		if d.needed == 0 {
+			switch {
+			case b <= 0x7F:
+				out.WriteByte(b)
+			case b >= 0xC2 && b <= 0xDF:
+				d.needed, d.codePoint = 1, rune(b&0x1F)
+			case b >= 0xE0 && b <= 0xEF:
+				if b == 0xE0 {
+					d.lower = 0xA0
+				}
+				if b == 0xED {
+					// Surrogates aren't valid characters.
+					d.upper = 0x9F
+				}
+				d.needed, d.codePoint = 2, rune(b&0x0F)
+			case b >= 0xF0 && b <= 0xF4:
+				if b == 0xF0 {
+					d.lower = 0x90
+				}
+				if b == 0xF4 {
+					d.upper = 0x8F
+				}
+				d.needed, d.codePoint = 3, rune(b&0x07)
+			default:
+				if !onError() {
+					return false
+				}
+			}
+			continue
+		}
+		if b < d.lower || b > d.upper {
+			*d = utf8Decoder{lower: 0x80, upper: 0xBF}
+			if !onError() {
+				return false
+			}
+			// The byte that ended the sequence may start the next one.
+			idx--
+			continue
+		}
+		d.lower, d.upper = 0x80, 0xBF
+		d.codePoint = d.codePoint<<6 | rune(b&0x3F)
+		d.seen++
+		if d.seen == d.needed {
+			out.WriteRune(d.codePoint)
+			*d = utf8Decoder{lower: 0x80, upper: 0xBF}
+		}
+	}
+	if flush && d.needed != 0 {
+		*d = utf8Decoder{}
+		return onError()
+	}
+	return true
+}
+
+// bufferSourceBytes returns the bytes of an ArrayBuffer, typed array or
+// DataView, which is what TextDecoder.decode accepts.
+func bufferSourceBytes(v Object) ([]byte, bool) {
+	switch v := v.(type) {
+	case *ArrayBuffer:
+		return v.data, true
+	case *TypedArray:
+		if v.outOfBounds() {
+			return nil, true
+		}
+		return v.buffer.data[v.byteOffset : v.byteOffset+v.byteLength()], true
+	case *DataView:
+		if v.outOfBounds() {
+			return nil, true
+		}
+		return v.buffer.data[v.byteOffset : v.byteOffset+v.len()], true
+	}
+	return nil, false
+}
+
+// booleanOption reads a true-or-false option from an options object.
+func (i *Interpreter) booleanOption(options Object, name string) (bool, *Error) {
+	if isNullish(options) {
+		return false, nil
+	}
+	if !isObject(options) {
+		return false, newTypeError("The \"options\" argument must be of type object. Received %s", inspect(options, 0))
+	}
+	value := i.getProperty(options, &String{Value: name})
+	if err, ok := value.(*Error); ok {
+		return false, err
+	}
+	return isTruthy(value), nil
+}
+
+// setupEncoding creates TextEncoder and TextDecoder and their prototypes.
+func (i *Interpreter) setupEncoding() {
+	encoderProto := i.textEncoderPrototype
+
+	thisEncoder := func(this Object, method string) *Error {
+		if _, ok := this.(*TextEncoder); !ok {
+			return newTypeError("Method TextEncoder.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return nil
+	}
+	i.defineMethod(encoderProto, "encode", func(this Object, args ...Object) Object {
+		if err := thisEncoder(this, "encode"); err != nil {
+			return err
+		}
+		s := &String{}
+		if argAt(args, 0) != UNDEFINED {
+			var err *Error
+			if s, err = i.toString(args[0]); err != nil {
+				return err
+			}
+		}
+		encoded, _ := encodeUTF8(s.units(), -1)
+		ta, err := i.newTypedArray(uint8Type, len(encoded))
+		if err != nil {
+			return err
+		}
+		copy(ta.buffer.data, encoded)
+		return ta
+	})
+	i.defineMethod(encoderProto, "encodeInto", func(this Object, args ...Object) Object {
+		if err := thisEncoder(this, "encodeInto"); err != nil {
+			return err
+		}
+		s, err := i.toString(argAt(args, 0))
+		if err != nil {
+			return err
+		}
+		dest, ok := argAt(args, 1).(*TypedArray)
+		if !ok || dest.kind != uint8Type {
+			return newTypeError("The \"dest\" argument must be an instance of Uint8Array")
+		}
+		encoded, read := encodeUTF8(s.units(), dest.byteLength())
+		copy(dest.buffer.data[dest.byteOffset:], encoded)
+		result := NewHash(i.objectPrototype)
+		result.Set(&String{Value: "read"}, &Number{Value: float64(read)})
+		result.Set(&String{Value: "written"}, &Number{Value: float64(len(encoded))})
+		return result
+	})
+	encoderProto.Set(symbolToStringTag, &String{Value: "TextEncoder"})
+
+	encoder := i.newBuiltin("TextEncoder", func(this Object, args ...Object) Object {
+		return &TextEncoder{Hash: Hash{Prototype: i.textEncoderPrototype}}
+	})
+	encoder.requiresNew = true
+	encoder.Set(&String{Value: "prototype"}, encoderProto)
+	encoderProto.Set(&String{Value: "constructor"}, encoder)
+	i.env.Set("TextEncoder", encoder)
+
+	decoderProto := i.textDecoderPrototype
+
+	i.defineMethod(decoderProto, "decode", func(this Object, args ...Object) Object {
+		td, ok := this.(*TextDecoder)
+		if !ok {
+			return newTypeError("Method TextDecoder.prototype.decode called on incompatible receiver %s", inspectReceiver(this))
+		}
+		var data []byte
+		if input := argAt(args, 0); input != UNDEFINED {
+			if data, ok = bufferSourceBytes(input); !ok {
+				return newTypeError("The \"input\" argument must be an instance of ArrayBuffer or ArrayBufferView. Received %s", inspect(input, 0))
+			}
+		}
+		stream, err := i.booleanOption(argAt(args, 1), "stream")
+		if err != nil {
+			return err
+		}
+
+		var out strings.Builder
+		if !td.decoder.decode(&out, data, !stream, func() bool {
+			if td.fatal {
+				return false
+			}
+			out.WriteRune(utf8.RuneError)
+			return true
+		}) {
+			td.decoder, td.bomSeen = utf8Decoder{}, false
+			return newTypeError("The encoded data was not valid for encoding utf-8")
+		}
+		decoded := out.String()
+		if !td.ignoreBOM && !td.bomSeen && decoded != "" {
+			decoded = strings.TrimPrefix(decoded, "\uFEFF")
+			td.bomSeen = true
+		}
+		if !stream {
+			// The next call starts a new stream.
+			td.bomSeen = false
+		}
+		return &String{Value: decoded}
+	})
+	decoderProto.Set(symbolToStringTag, &String{Value: "TextDecoder"})
+
+	decoder := i.newBuiltin("TextDecoder", func(this Object, args ...Object) Object {
+		if label := argAt(args, 0); label != UNDEFINED {
+			s, err := i.toString(label)
+			if err != nil {
+				return err
+			}
+			name := strings.ToLower(strings.Trim(s.Value, "\t\n\f\r "))
+			if !utf8Labels[name] {
+				return newRangeError("The %q encoding is not supported", s.Value)
+			}
+		}
+		fatal, err := i.booleanOption(argAt(args, 1), "fatal")
+		if err != nil {
+			return err
+		}
+		ignoreBOM, err := i.booleanOption(argAt(args, 1), "ignoreBOM")
+		if err != nil {
+			return err
+		}
+		return &TextDecoder{Hash: Hash{Prototype: i.textDecoderPrototype}, fatal: fatal, ignoreBOM: ignoreBOM}
+	})
+	decoder.requiresNew = true
+	decoder.Set(&String{Value: "prototype"}, decoderProto)
+	decoderProto.Set(&String{Value: "constructor"}, decoder)
+	i.env.Set("TextDecoder", decoder)
+}
@@ -0,0 +1,169 @@
+package interpreter
+
+// Environment represents a JavaScript scope.
+// Environments are used to implement variable scoping and closures.
+// They form a chain (like a linked list) where each environment
+// has a reference to its outer (parent) environment.
+//
+// Blocks get their own environment for let and const declarations, while
+// function bodies get a "function scope" environment that also collects the
+// function's var declarations.
+type Environment struct {
+	store     map[string]Object
+	constants map[string]bool
+	outer     *Environment
+	function  bool
+
+	// imports holds the variables a module imports from other modules.
+	// They aren't copies: reading one reads the variable in the exporting
+	// module, so that the importer sees every change the exporter makes.
+	imports map[string]importedBinding
+}
+
+// importedBinding is an imported variable: the variable name at the top
+// level of the module whose environment is env.
+type importedBinding struct {
+	env  *Environment
+	name string
+}
+
+// NewEnvironment creates a new environment.
+// The outer parameter is used to create nested scopes.
+func NewEnvironment(outer *Environment) *Environment {
+	env := &Environment{store: make(map[string]Object), outer: outer}
+	return env
+}
+
+// NewFunctionEnvironment creates the environment for a function body (or for
+// the whole program). var declarations made anywhere inside the function are
+// stored here.
+func NewFunctionEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment(outer)
+	env.function = true
+	return env
+}
+
+// Get retrieves a variable from the environment.
+// If the variable isn't found in the current environment,
+// it looks in the outer environment (implementing variable shadowing).
+func (e *Environment) Get(name string) (Object, bool) {
+	obj, ok := e.getOwn(name)
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// getOwn looks up a variable declared or imported in this environment
+// itself. An imported variable isn't found until the module exporting it
+// has declared it.
+func (e *Environment) getOwn(name string) (Object, bool) {
+	if obj, ok := e.store[name]; ok {
+		return obj, true
+	}
+	if b, ok := e.imports[name]; ok {
+		return b.env.getOwn(b.name)
+	}
+	return nil, false
+}
+
+// HasOwn reports whether the variable is declared in this environment
+// itself, ignoring outer environments.
+func (e *Environment) HasOwn(name string) bool {
+	_, ok := e.store[name]
+	_, imported := e.imports[name]
+	return ok || imported
+}
+
+// Import binds name in this environment, a module's, to the variable
+// from in env, the environment of the module exporting it. Imported
+// variables can't be assigned to.
+func (e *Environment) Import(name string, env *Environment, from string) {
+	if e.imports == nil {
+		e.imports = make(map[string]importedBinding)
+	}
+	e.imports[name] = importedBinding{env: env, name: from}
+}
+
+// uninitialized reports whether looking up name finds an imported variable
+// that the module exporting it hasn't declared yet. That happens when
+// modules import each other, and one runs code using the other's
+// variables before the other has run.
+func (e *Environment) uninitialized(name string) bool {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return false
+		}
+		if _, ok := env.imports[name]; ok {
+			_, found := env.getOwn(name)
+			return !found
+		}
+	}
+	return false
+}
+
+// Set stores a variable in the current environment.
+// Note that this doesn't modify variables in outer environments.
+func (e *Environment) Set(name string, val Object) Object {
+	e.store[name] = val
+	delete(e.constants, name)
+	return val
+}
+
+// SetConst declares a constant in the current environment. Constants can't
+// be assigned to afterwards.
+func (e *Environment) SetConst(name string, val Object) Object {
+	e.store[name] = val
+	if e.constants == nil {
+		e.constants = make(map[string]bool)
+	}
+	e.constants[name] = true
+	return val
+}
+
+// Assign updates an existing variable in the nearest environment that
+// declares it. It reports whether the variable was found, and whether the
+// assignment was refused because the variable is a constant, which
+// imported variables count as.
+func (e *Environment) Assign(name string, val Object) (found bool, isConst bool) {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.imports[name]; ok {
+			return true, true
+		}
+		if _, ok := env.store[name]; ok {
+			if env.constants[name] {
+				return true, true
+			}
+			env.store[name] = val
+			return true, false
+		}
+	}
+	return false, false
+}
+
+// FunctionScope returns the nearest enclosing function environment, which is
+// where var declarations live.
+func (e *Environment) FunctionScope() *Environment {
+	env := e
+	for !env.function && env.outer != nil {
+		env = env.outer
+	}
+	return env
+}
+
+// Copy returns a new environment with the same outer environment and a copy
+// of the variables. Loops use it to give each iteration its own bindings.
+func (e *Environment) Copy() *Environment {
+	env := NewEnvironment(e.outer)
+	env.function = e.function
+	for name, val := range e.store {
+		env.store[name] = val
+	}
+	for name := range e.constants {
+		env.SetConst(name, env.store[name])
+	}
+	for name, b := range e.imports {
+		env.Import(name, b.env, b.name)
+	}
+	return env
+}
@@ -0,0 +1,134 @@
+package interpreter
+
+import "strings"
+
+// An error the interpreter raises, like the TypeError from calling
+// something that isn't a function, travels through its Go code as an
+// *Error, which only has a message. Once a script gets hold of the error,
+// as the reason a promise rejects with, it becomes an error object like
+// the one Node.js would have thrown:
+//
+//	Promise.resolve().then(() => null.x).catch(e => {
+//		console.log(e.name);    // TypeError
+//		console.log(e.message); // Cannot read properties of null (reading 'x')
+//		console.log(String(e)); // TypeError: Cannot read properties of ...
+//	});
+//
+// Its name comes from its prototype: TypeError.prototype and the others
+// inherit from Error.prototype, whose toString joins the name and the
+// message. There are no Error constructors for scripts to call, so error
+// objects are only made by the interpreter.
+
+// errorKinds are the kinds of error the interpreter raises, besides
+// plain errors and Promise.any's AggregateError.
+var errorKinds = []string{"TypeError", "RangeError", "ReferenceError", "SyntaxError"}
+
+// ErrorObject is an error object, as scripts see an error. Unlike *Error,
+// which carries an error through the interpreter, it's an ordinary object.
+type ErrorObject struct {
+	Hash
+}
+
+func (e *ErrorObject) Type() ObjectType { return ERROR_INSTANCE_OBJ }
+
+// Inspect shows the error as Node.js does without a stack trace, as its
+// name and message.
+func (e *ErrorObject) Inspect() string {
+	part := func(key, fallback string) string {
+		if value, ok := e.Get(&String{Value: key}); ok {
+			if s, ok := value.(*String); ok {
+				return s.Value
+			}
+		}
+		return fallback
+	}
+	name, message := part("name", "Error"), part("message", "")
+	switch {
+	case name == "":
+		return message
+	case message == "":
+		return name
+	}
+	return name + ": " + message
+}
+
+// setupErrors creates Error.prototype and the prototypes of the kinds of
+// error, which inherit from it.
+func (i *Interpreter) setupErrors() {
+	proto := i.errorPrototype
+	proto.Set(&String{Value: "name"}, &String{Value: "Error"})
+	proto.Set(&String{Value: "message"}, &String{Value: ""})
+	i.defineMethod(proto, "toString", i.errorToString)
+
+	i.errorPrototypes = map[string]*Hash{"Error": proto}
+	for _, name := range append(errorKinds, "AggregateError") {
+		kind := NewHash(proto)
+		kind.Set(&String{Value: "name"}, &String{Value: name})
+		kind.Set(&String{Value: "message"}, &String{Value: ""})
+		i.errorPrototypes[name] = kind
+	}
+}
+
+// newErrorObject creates an error object of the kind name, one of
+// errorKinds, "Error" or "AggregateError", with message.
+func (i *Interpreter) newErrorObject(name, message string) *ErrorObject {
+	err := &ErrorObject{Hash: Hash{Prototype: i.errorPrototypes[name]}}
+	err.Set(&String{Value: "message"}, &String{Value: message})
+	return err
+}
+
+// thrownValue returns the value a script sees for an error, the reverse of
+// thrownError: what was thrown, or for an error the interpreter raised, an
+// error object made from its message. A message such as "TypeError: x is
+// not a function" gives a TypeError whose message is "x is not a
+// function". The object is kept with the error, so that catching the
+// error again gives the same object.
+func (i *Interpreter) thrownValue(err *Error) Object {
+	if err.Value == nil {
+		name, message := "Error", err.Message
+		if prefix, rest, ok := strings.Cut(err.Message, ": "); ok {
+			if _, known := i.errorPrototypes[prefix]; known {
+				name, message = prefix, rest
+			}
+		}
+		err.Value = i.newErrorObject(name, message)
+	}
+	return err.Value
+}
+
+// errorToString is Error.prototype.toString: the error's name and message,
+// separated by a colon unless one of them is empty.
+func (i *Interpreter) errorToString(this Object, args ...Object) Object {
+	if !isObject(this) {
+		return newTypeError("Method Error.prototype.toString called on incompatible receiver %s", inspectReceiver(this))
+	}
+	part := func(key, fallback string) (string, *Error) {
+		value := i.getProperty(this, &String{Value: key})
+		if err, ok := value.(*Error); ok {
+			return "", err
+		}
+		if value == UNDEFINED {
+			return fallback, nil
+		}
+		s, err := i.toString(value)
+		if err != nil {
+			return "", err
+		}
+		return s.Value, nil
+	}
+	name, err := part("name", "Error")
+	if err != nil {
+		return err
+	}
+	message, err := part("message", "")
+	if err != nil {
+		return err
+	}
+	switch {
+	case name == "":
+		return &String{Value: message}
+	case message == "":
+		return &String{Value: name}
+	}
+	return &String{Value: name + ": " + message}
+}
@@ -0,0 +1,191 @@
+package interpreter
+
+import "context"
+
+// JavaScript runs one piece of code at a time, to completion. Work that
+// has to happen later, like the callback passed to a promise's then, is
+// put in a queue, and the event loop takes it from there once the code
+// running now has finished:
+//
+//	Promise.resolve().then(() => console.log("second"));
+//	console.log("first");
+//
+// There are two queues. Tasks are the big units of work: a script, a
+// timer firing, a FinalizationRegistry cleanup. Microtasks, also called
+// jobs, are the small ones promises and queueMicrotask create. After each
+// task the loop runs every microtask, including those queued by other
+// microtasks, before it moves on to the next task, so a promise chain
+// always settles before anything else gets a turn.
+//
+// Timers wait in a queue of their own, ordered by when they are due. Once
+// there are no tasks left, the loop fires the timers that are due, and if
+// the only thing left is timers that aren't, it sleeps on the
+// interpreter's clock until the first of them is.
+
+// eventLoop holds the queues of work waiting to run.
+type eventLoop struct {
+	tasks      []func() *Error
+	microtasks []func() *Error
+
+	// rejections holds the promises rejected with no handler to take the
+	// rejection, which are reported at the end of the microtask
+	// checkpoint unless a handler has been attached by then.
+	rejections []*Promise
+
+	// timers holds the timers waiting to fire, and timerNesting how deep
+	// the timer firing now was set from within other timers, or 0 when
+	// none is.
+	timers       timerQueue
+	timerNesting int
+
+	// onUnhandledRejection reports a promise rejected without a handler.
+	// See SetUnhandledRejectionHandler.
+	onUnhandledRejection func(reason Object)
+}
+
+// queueTask adds a task to the end of the task queue.
+func (i *Interpreter) queueTask(task func() *Error) {
+	i.loop.tasks = append(i.loop.tasks, task)
+}
+
+// queueMicrotask adds a job to the end of the microtask queue.
+func (i *Interpreter) queueMicrotask(job func() *Error) {
+	i.loop.microtasks = append(i.loop.microtasks, job)
+}
+
+// runMicrotasks performs a microtask checkpoint: it runs microtasks until
+// the queue is empty, then reports the promises still rejected without a
+// handler, and lets go of the WeakRef targets kept alive for the work that
+// just finished. It returns the first error a microtask failed with, or
+// the error that ended evaluation as soon as one has; see Limits.
+func (i *Interpreter) runMicrotasks() *Error {
+	var firstErr *Error
+	for len(i.loop.microtasks) > 0 {
+		job := i.loop.microtasks[0]
+		i.loop.microtasks = i.loop.microtasks[1:]
+		if err := job(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if i.usage.terminated != nil {
+			return i.usage.terminated
+		}
+	}
+
+	rejections := i.loop.rejections
+	i.loop.rejections = nil
+	for _, p := range rejections {
+		if !p.handled {
+			i.loop.onUnhandledRejection(p.result)
+		}
+	}
+
+	i.keptAlive = nil
+	return firstErr
+}
+
+// RunUntilIdle runs the event loop until there is nothing left to do:
+// every queued task, and after each one every microtask, then the timers
+// as they fall due. An error a task fails with doesn't stop the loop, as
+// one uncaught exception in a browser doesn't stop the page; the first is
+// returned once the loop is idle. The loop does stop once evaluation has
+// ended, because a limit was exceeded or the context is done.
+//
+// With the system clock, the loop waits for timers that aren't due yet,
+// so a setInterval that is never cleared keeps it running. With a
+// FakeClock it returns instead, leaving them for AdvanceBy and
+// RunAllTimers.
+//
+// Eval runs the loop itself once the script finishes, so embedders only
+// need this after adding work from outside a script.
+func (i *Interpreter) RunUntilIdle() *Error {
+	result := i.runWithin(context.Background(), func() Object {
+		if err := i.runUntilIdle(); err != nil {
+			return err
+		}
+		return UNDEFINED
+	})
+	err, _ := result.(*Error)
+	return err
+}
+
+// runUntilIdle does the work of RunUntilIdle, as part of the evaluation
+// under way.
+func (i *Interpreter) runUntilIdle() *Error {
+	var firstErr *Error
+	record := func(err *Error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	for {
+		if err := i.interrupted(); err != nil {
+			return err
+		}
+		record(i.runMicrotasks())
+		if i.cleanups.pending() {
+			i.queueTask(i.runFinalizations)
+		}
+		if len(i.loop.tasks) == 0 {
+			t := i.nextTimer()
+			if t == nil {
+				if err := i.interrupted(); err != nil {
+					return err
+				}
+				return firstErr
+			}
+			record(i.fireTimer(t))
+			continue
+		}
+		task := i.loop.tasks[0]
+		i.loop.tasks = i.loop.tasks[1:]
+		record(task())
+	}
+}
+
+// nextTimer returns the first timer, taken off the queue, once it's due.
+// It sleeps on the clock until then, and returns nil if there are no
+// timers, or the clock still hasn't reached the first after sleeping.
+func (i *Interpreter) nextTimer() *timer {
+	first := i.loop.timers.peek()
+	if first == nil {
+		return nil
+	}
+	if wait := first.due.Sub(i.clock.Now()); wait > 0 {
+		i.sleep(wait)
+	}
+	return i.loop.timers.nextDue(i.clock.Now())
+}
+
+// SetUnhandledRejectionHandler sets the function called with the reason of
+// each promise that is rejected with nothing to handle the rejection. By
+// default the reason is written to the console's standard error, as
+// browsers do:
+//
+//	Uncaught (in promise) TypeError: x is not a function
+func (i *Interpreter) SetUnhandledRejectionHandler(fn func(reason Object)) {
+	i.loop.onUnhandledRejection = fn
+}
+
+// setupEventLoop defines queueMicrotask, which queues a function to run
+// as a microtask, and the timer functions.
+func (i *Interpreter) setupEventLoop() {
+	i.loop.onUnhandledRejection = func(reason Object) {
+		i.consolePrint(true, "Uncaught (in promise) "+thrownError(reason).Message)
+	}
+
+	i.env.Set("queueMicrotask", i.newBuiltin("queueMicrotask", func(this Object, args ...Object) Object {
+		callback := argAt(args, 0)
+		if !isCallable(callback) {
+			return newTypeError("The \"callback\" argument must be of type function. Received %s", inspect(callback, 0))
+		}
+		i.queueMicrotask(func() *Error {
+			if err, ok := i.applyFunction(callback, UNDEFINED, nil).(*Error); ok {
+				return err
+			}
+			return nil
+		})
+		return UNDEFINED
+	}))
+
+	i.setupTimers()
+}
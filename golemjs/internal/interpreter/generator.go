@@ -0,0 +1,416 @@
+package interpreter
+
+import (
+	"runtime"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+)
+
+// Generators are functions that can pause. Calling function* gen() doesn't
+// run its body; it returns a generator object, and each call to the
+// generator's next method runs the body up to the following yield:
+//
+//	function* count() { yield 1; yield 2; }
+//	const g = count();
+//	g.next(); // { value: 1, done: false }
+//	g.next(); // { value: 2, done: false }
+//	g.next(); // { value: undefined, done: true }
+//
+// The interpreter evaluates a body by recursing through its AST, so "where
+// the body paused" is a Go call stack several evaluation functions deep.
+// To keep that stack around, each generator runs its body on a goroutine of
+// its own, and the two sides take turns over channels: the caller hands the
+// goroutine a value to resume with and waits, and the goroutine runs until
+// the next yield and hands back what was yielded. Only one side ever runs
+// at a time, so the interpreter's state needs no locking. Async functions
+// pause at await the same way; see async.go.
+
+// generatorState is where a generator is in its life.
+type generatorState int
+
+const (
+	generatorSuspendedStart generatorState = iota // created, body not started
+	generatorSuspendedYield                       // paused at a yield
+	generatorExecuting                            // body running
+	generatorCompleted                            // body finished
+)
+
+// Generator is the object returned by calling a generator function.
+type Generator struct {
+	Hash
+	co *coroutine
+}
+
+func (g *Generator) Type() ObjectType { return GENERATOR_OBJ }
+func (g *Generator) Inspect() string  { return "Object [Generator] {}" }
+
+// coroutine is the state of a body that can pause: a generator's, an async
+// function's, or a module's that uses await. It's kept apart from the
+// Generator object so that the goroutine running the body doesn't refer to
+// the object: when a script drops a generator that is paused half-way, the
+// object can still be garbage collected, and the goroutine is then stopped.
+// This only works if the body can't see the generator, though. A paused
+// goroutine keeps the scopes of the body alive, so a generator stored in a
+// variable the body could read lives as long as its goroutine does.
+type coroutine struct {
+	state generatorState
+
+	// body evaluates the code that runs in the coroutine and returns its
+	// result: the value it returns, or an error.
+	body func() Object
+
+	// asyncGenerator is set for the body of an async generator, where
+	// yield awaits; see asyncSuspend.
+	asyncGenerator bool
+
+	resume chan resumption    // from the caller to the body
+	yield  chan generatorStep // from the body to the caller
+}
+
+func newCoroutine(body func() Object) *coroutine {
+	return &coroutine{
+		body:   body,
+		resume: make(chan resumption),
+		yield:  make(chan generatorStep),
+	}
+}
+
+// resumeMode is the way a paused generator is resumed: by next(value),
+// return(value) or throw(value).
+type resumeMode int
+
+const (
+	resumeNext resumeMode = iota
+	resumeReturn
+	resumeThrow
+)
+
+type resumption struct {
+	mode  resumeMode
+	value Object
+}
+
+// generatorStep is what the body hands back to the caller: a yielded value,
+// a value to await if awaiting is set, or, once done is set, the body's
+// result, which may be an error.
+type generatorStep struct {
+	value    Object
+	done     bool
+	awaiting bool
+
+	// panicValue carries a Go panic in the body over to the caller's
+	// goroutine, where it can be recovered from.
+	panicValue any
+}
+
+// setupGenerator defines %GeneratorPrototype%, which holds the methods of
+// every generator object.
+func (i *Interpreter) setupGenerator() {
+	proto := i.generatorPrototype
+
+	method := func(name string, mode resumeMode) {
+		i.defineMethod(proto, name, func(this Object, args ...Object) Object {
+			g, ok := this.(*Generator)
+			if !ok {
+				return newTypeError("%s method called on incompatible receiver %s", name, inspectReceiver(this))
+			}
+			return i.resumeGenerator(g, mode, argAt(args, 0))
+		})
+	}
+	method("next", resumeNext)
+	method("return", resumeReturn)
+	method("throw", resumeThrow)
+	proto.Set(symbolToStringTag, &String{Value: "Generator"})
+}
+
+// newGeneratorFunction creates the function object for function* name()
+// { body }, or async function* name() { body } if async is set. Its
+// "prototype" property becomes the prototype of the generators it returns,
+// which inherit the generator methods from there.
+func (i *Interpreter) newGeneratorFunction(name string, params []ast.Expression, body *ast.BlockStatement, env *Environment, async bool) *Function {
+	fn := &Function{
+		Hash:       Hash{Prototype: i.functionPrototype},
+		Name:       name,
+		Parameters: params,
+		Body:       body,
+		Env:        env,
+		Generator:  true,
+		Async:      async,
+	}
+	proto := i.generatorPrototype
+	if async {
+		proto = i.asyncGeneratorPrototype
+	}
+	fn.Set(&String{Value: "prototype"}, NewHash(proto))
+	return fn
+}
+
+// newGenerator implements calling a generator function. The arguments are
+// bound straight away, but the body only starts with the first next().
+func (i *Interpreter) newGenerator(fn *Function, this Object, args []Object) Object {
+	proto := i.generatorPrototype
+	if fn.Async {
+		proto = i.asyncGeneratorPrototype
+	}
+	if p, ok := fn.Get(&String{Value: "prototype"}); ok {
+		if p, ok := p.(*Hash); ok {
+			proto = p
+		}
+	}
+	env, err := i.extendFunctionEnv(fn, this, args)
+	if err != nil {
+		return err
+	}
+	if fn.Async {
+		return i.newAsyncGenerator(proto, fn.Body, env)
+	}
+	body := fn.Body
+	co := newCoroutine(func() Object {
+		return bodyResult(i.evalBlockStatement(body, env))
+	})
+	g := &Generator{Hash: Hash{Prototype: proto}, co: co}
+	runtime.AddCleanup(g, (*coroutine).abandon, co)
+	return g
+}
+
+// abandon stops the goroutine of a generator that can no longer be
+// resumed because nothing refers to it. Closing the channel the goroutine
+// waits on makes it exit without running any more of the body.
+func (co *coroutine) abandon() {
+	close(co.resume)
+}
+
+// resumeGenerator runs a generator's body until it yields or finishes, and
+// returns the { value, done } result for next, return or throw.
+func (i *Interpreter) resumeGenerator(g *Generator, mode resumeMode, value Object) Object {
+	co := g.co
+	switch co.state {
+	case generatorExecuting:
+		return newTypeError("Generator is already running")
+	case generatorCompleted:
+		return i.completedGeneratorResult(mode, value)
+	case generatorSuspendedStart:
+		if mode != resumeNext {
+			// Returning from or throwing into a generator that never
+			// started finishes it without running any of the body.
+			co.state = generatorCompleted
+			return i.completedGeneratorResult(mode, value)
+		}
+	}
+
+	step := i.resumeCoroutine(co, resumption{mode: mode, value: value})
+	if isError(step.value) {
+		return step.value
+	}
+	return i.iteratorResult(step.value, step.done)
+}
+
+// resumeCoroutine runs co until it pauses or finishes, starting it if it
+// hasn't started yet, and returns the step it hands back.
+func (i *Interpreter) resumeCoroutine(co *coroutine, r resumption) generatorStep {
+	// The body reads i.running when it pauses, so it has to be set before
+	// the body's goroutine is let go.
+	outer := i.running
+	i.running = co
+	if co.state == generatorSuspendedStart {
+		co.state = generatorExecuting
+		go i.runCoroutine(co)
+	} else {
+		co.state = generatorExecuting
+		co.resume <- r
+	}
+	step := <-co.yield
+	i.running = outer
+
+	co.state = generatorSuspendedYield
+	if step.done {
+		co.state = generatorCompleted
+	}
+	if step.panicValue != nil {
+		panic(step.panicValue)
+	}
+	return step
+}
+
+// completedGeneratorResult is what a finished generator answers: next
+// reports that it's done, return hands back its argument and throw throws
+// its argument straight back.
+func (i *Interpreter) completedGeneratorResult(mode resumeMode, value Object) Object {
+	switch mode {
+	case resumeReturn:
+		return i.iteratorResult(value, true)
+	case resumeThrow:
+		return thrownError(value)
+	}
+	return i.iteratorResult(UNDEFINED, true)
+}
+
+// runCoroutine is the goroutine that evaluates a coroutine's body. For a
+// generator, the value the body returns becomes the value of the final
+// { done: true } result.
+func (i *Interpreter) runCoroutine(co *coroutine) {
+	defer func() {
+		// A goroutine stopped by abandon exits through runtime.Goexit,
+		// which runs this without a panic to recover.
+		if r := recover(); r != nil {
+			co.yield <- generatorStep{done: true, panicValue: r}
+		}
+	}()
+
+	co.yield <- generatorStep{value: co.body(), done: true}
+}
+
+// bodyResult turns the result of evaluating a function body into the
+// value the function returns, or the error it throws. The signal that
+// unwinds a generator on return(value) becomes a return of value.
+func bodyResult(result Object) Object {
+	switch result := result.(type) {
+	case *ReturnValue:
+		return result.Value
+	case *Error:
+		if result.returning != nil {
+			return result.returning.Value
+		}
+		return result
+	}
+	return UNDEFINED
+}
+
+// suspend pauses the running generator's body, handing value to the
+// caller, and returns how the generator was resumed.
+func (i *Interpreter) suspend(value Object) resumption {
+	return i.pause(generatorStep{value: value})
+}
+
+// pause hands step to whoever resumed the running coroutine and waits to
+// be resumed again.
+func (i *Interpreter) pause(step generatorStep) resumption {
+	co := i.running
+	co.yield <- step
+	r, ok := <-co.resume
+	if !ok {
+		runtime.Goexit()
+	}
+	return r
+}
+
+// evalYieldExpression evaluates yield and yield*. The value of a yield
+// expression is the argument of the next() call that resumes it. When the
+// generator is resumed with throw(value) instead, the yield throws value;
+// with return(value), the body returns value from where it paused.
+func (i *Interpreter) evalYieldExpression(node *ast.YieldExpression, env *Environment) Object {
+	var value Object = UNDEFINED
+	if node.Argument != nil {
+		value = i.eval(node.Argument, env)
+		if isError(value) {
+			return value
+		}
+	}
+	if node.Delegate {
+		return i.yieldDelegate(value, i.running.asyncGenerator)
+	}
+	if i.running.asyncGenerator {
+		// An async generator yields the value a promise is fulfilled
+		// with rather than the promise.
+		value = i.await(value)
+		if isError(value) {
+			return value
+		}
+		return resumptionValue(i.asyncSuspend(value))
+	}
+	return resumptionValue(i.suspend(value))
+}
+
+// resumptionValue turns how a generator was resumed into the result of the
+// yield it was paused at.
+func resumptionValue(r resumption) Object {
+	switch r.mode {
+	case resumeThrow:
+		return thrownError(r.value)
+	case resumeReturn:
+		return generatorReturn(r.value)
+	}
+	return r.value
+}
+
+// generatorReturn makes the signal that unwinds a generator's body when
+// return(value) is called on it. It has to make its way out through any
+// expressions the yield was nested in, and only errors do that, so it's an
+// error that runGenerator recognises and turns back into a return.
+func generatorReturn(value Object) *Error {
+	return &Error{Message: "generator returned", returning: &ReturnValue{Value: value}}
+}
+
+// yieldDelegate implements yield* iterable, which yields every value of
+// iterable in turn and evaluates to the value it finishes with. Whatever
+// the generator is resumed with is passed on to the inner iterator: next
+// values to its next method, and throw and return to its methods of the
+// same name. In an async generator, async is set, and iterable is iterated
+// over asynchronously, as for await...of does.
+func (i *Interpreter) yieldDelegate(iterable Object, async bool) Object {
+	getIterator, call, close, suspend := i.getIterator, i.callIteratorMethod, i.iteratorClose, i.suspend
+	if async {
+		getIterator, call, close, suspend = i.getAsyncIterator, i.asyncIteratorCall, i.asyncIteratorClose, i.asyncSuspend
+	}
+	it, err := getIterator(iterable)
+	if err != nil {
+		return err
+	}
+	received := resumption{mode: resumeNext, value: UNDEFINED}
+	for {
+		var result Object
+		switch received.mode {
+		case resumeNext:
+			result, err = call(it, it.next, received.value)
+		case resumeThrow:
+			method := i.getProperty(it.object, &String{Value: "throw"})
+			if isError(method) {
+				return method
+			}
+			if isNullish(method) {
+				if err := close(it); err != nil {
+					return err
+				}
+				return newTypeError("The iterator does not provide a 'throw' method")
+			}
+			result, err = call(it, method, received.value)
+		case resumeReturn:
+			method := i.getProperty(it.object, &String{Value: "return"})
+			if isError(method) {
+				return method
+			}
+			if isNullish(method) {
+				return generatorReturn(received.value)
+			}
+			result, err = call(it, method, received.value)
+		}
+		if err != nil {
+			return err
+		}
+
+		done, err := i.iteratorComplete(result)
+		if err != nil {
+			return err
+		}
+		value, err := i.iteratorValue(result)
+		if err != nil {
+			return err
+		}
+		if done {
+			if received.mode == resumeReturn {
+				return generatorReturn(value)
+			}
+			return value
+		}
+		received = suspend(value)
+	}
+}
+
+// callIteratorMethod calls method of an iterator and returns the
+// { value, done } result.
+func (i *Interpreter) callIteratorMethod(it *iterator, method Object, args ...Object) (Object, *Error) {
+	if !isCallable(method) {
+		return nil, newTypeError("%s is not a function", inspect(method, 0))
+	}
+	return i.checkIteratorResult(i.applyFunction(method, it.object, args))
+}
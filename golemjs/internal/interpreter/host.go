@@ -0,0 +1,71 @@
+package interpreter
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A program embedding the interpreter can give scripts objects whose
+// properties it keeps itself, such as the fields of a Go struct, so that
+// reading a property reads the field as it is now, and assigning to it
+// changes the field. Such an object is a HostObject, and the Host behind
+// it is asked for the properties it keeps before the object's own.
+
+// Host keeps the properties of a HostObject.
+type Host interface {
+	// Keys returns the names of the properties the host keeps, in the
+	// order they're listed in, as by inspection and JSON.stringify.
+	Keys() []string
+
+	// Get reads the property name. It reports false if the host doesn't
+	// keep such a property.
+	Get(name string) (Object, bool)
+
+	// Set writes the property name, returning an error if value can't be
+	// stored there. It reports false if the host doesn't keep such a
+	// property; a host that keeps the property but doesn't let scripts
+	// change it ignores the write and reports true.
+	Set(name string, value Object) (bool, *Error)
+}
+
+// HostObject is an object whose properties are kept by its Host. Any
+// other properties, such as methods inherited from its prototype, live in
+// the embedded Hash as usual.
+type HostObject struct {
+	Hash
+	Host Host
+}
+
+func (h *HostObject) Type() ObjectType { return HOST_OBJ }
+func (h *HostObject) Inspect() string {
+	pairs := []string{}
+	for _, name := range h.Host.Keys() {
+		value, _ := h.Host.Get(name)
+		pairs = append(pairs, fmt.Sprintf("%s: %s", name, value.Inspect()))
+	}
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
+// NewHostObject creates an object whose properties are kept by host, and
+// which inherits from proto, or from Object.prototype if proto is nil.
+func (i *Interpreter) NewHostObject(host Host, proto *Hash) *HostObject {
+	if proto == nil {
+		proto = i.objectPrototype
+	}
+	return &HostObject{Hash: Hash{Prototype: proto}, Host: host}
+}
+
+// accessor reads the properties the host keeps.
+func (h *HostObject) accessor(name string) (Object, bool) {
+	return h.Host.Get(name)
+}
+
+// hostKeys returns the names of the properties the host keeps, as keys.
+func (h *HostObject) hostKeys() []Object {
+	names := h.Host.Keys()
+	keys := make([]Object, len(names))
+	for idx, name := range names {
+		keys[idx] = &String{Value: name}
+	}
+	return keys
+}
@@ -0,0 +1,555 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// This file turns values into the human-readable descriptions console.log
+// prints, following the format of Node's util.inspect:
+//
+//	{ name: 'golem', tags: [ 'js', 'go' ], nested: { deeper: [Object] } }
+//
+// Unlike Inspect, which every Object implements for debugging the
+// interpreter itself, this output quotes nested strings, stops descending
+// after a few levels, marks circular references instead of looping
+// forever, and wraps long output over several lines.
+
+const (
+	// defaultInspectDepth is how many levels of nested objects are shown
+	// before they are abbreviated to [Object] or [Array].
+	defaultInspectDepth = 2
+
+	// inspectBreakLength is the width that output is kept within before
+	// it is split over several lines.
+	inspectBreakLength = 80
+
+	// maxArrayInspectLength is the number of array elements shown before
+	// the rest are summarised as "... n more items".
+	maxArrayInspectLength = 100
+)
+
+// inspector holds the state of a single inspect call.
+type inspector struct {
+	depth int
+
+	// seen holds the objects currently being formatted, from the outermost
+	// inwards. Meeting one of them again means the value is circular.
+	seen []Object
+
+	// circular numbers the objects that turned out to be referred to from
+	// inside themselves, so that they can be labelled <ref *1>.
+	circular map[Object]int
+
+	indentation int
+}
+
+// inspect describes v the way console.log shows values nested inside
+// objects, showing depth levels of nested objects.
+func inspect(v Object, depth int) string {
+	in := &inspector{depth: depth, circular: map[Object]int{}}
+	return in.format(v, 0)
+}
+
+func (in *inspector) format(v Object, level int) string {
+	switch v := v.(type) {
+	case nil:
+		return "undefined"
+	case *String:
+		return quoteJSString(v.Value)
+	case *Number:
+		return formatNumber(v.Value)
+	}
+	if !isObject(v) {
+		return v.Inspect()
+	}
+	return in.formatObject(v, level)
+}
+
+// formatNumber writes a number as JavaScript would, except that negative
+// zero is shown as -0 so that it can be told apart from 0.
+func formatNumber(f float64) string {
+	if f == 0 && math.Signbit(f) {
+		return "-0"
+	}
+	return numberToString(f)
+}
+
+// formatObject describes an object, its elements and its own properties.
+func (in *inspector) formatObject(v Object, level int) string {
+	for _, s := range in.seen {
+		if s == v {
+			idx, ok := in.circular[v]
+			if !ok {
+				idx = len(in.circular) + 1
+				in.circular[v] = idx
+			}
+			return fmt.Sprintf("[Circular *%d]", idx)
+		}
+	}
+
+	prefix, braces := describeObject(v)
+	holder := v.(propertyHolder).properties()
+	arr, isArray := v.(*Array)
+	keys := visibleKeys(v)
+	table := collectionTable(v)
+
+	internal := hasInternalSlots(v)
+
+	if len(keys) == 0 && (!isArray || arr.len() == 0) && (table == nil || table.size() == 0) && !hasInternalContents(v) {
+		if _, ok := v.(*WeakMap); ok {
+			return prefix + " { <items unknown> }"
+		}
+		if _, ok := v.(*WeakSet); ok {
+			return prefix + " { <items unknown> }"
+		}
+		if inspectsAsValue(v) || isCallable(v) {
+			return prefix
+		}
+		return joinPrefix(prefix, braces[0]+braces[1])
+	}
+	if level > in.depth {
+		if isArray {
+			return "[Array]"
+		}
+		if table != nil || internal {
+			return "[" + toStringTag(v) + "]"
+		}
+		if inspectsAsValue(v) || isCallable(v) {
+			return prefix
+		}
+		if name := constructorName(holder); name != "" {
+			return "[" + name + "]"
+		}
+		return "[Object]"
+	}
+
+	in.seen = append(in.seen, v)
+	in.indentation += 2
+	var entries []string
+	if isArray {
+		entries = in.formatArrayElements(arr, level)
+	}
+	if table != nil {
+		entries = in.formatTableEntries(v, table, level)
+	}
+	if internal {
+		entries = in.formatInternalSlots(v, level)
+	}
+	for _, key := range keys {
+		value, _ := holder.GetOwn(key)
+		entries = append(entries, formatPropertyKey(key)+": "+in.format(value, level+1))
+	}
+	in.indentation -= 2
+	in.seen = in.seen[:len(in.seen)-1]
+
+	out := in.reduceToSingleString(entries, prefix, braces)
+	if idx, ok := in.circular[v]; ok {
+		out = fmt.Sprintf("<ref *%d> %s", idx, out)
+	}
+	return out
+}
+
+// inspectsAsValue reports whether v is one of the objects that inspect as
+// their value alone, like /a/g, 2024-01-02T00:00:00.000Z or TypeError: x
+// is not a function, rather than as braces around their properties.
+func inspectsAsValue(v Object) bool {
+	switch v.(type) {
+	case *RegExp, *Date, *ErrorObject:
+		return true
+	}
+	return false
+}
+
+// formatTableEntries describes the contents of a map, as key => value
+// pairs, or of a set.
+func (in *inspector) formatTableEntries(v Object, table *orderedTable, level int) []string {
+	var entries []string
+	_, isMap := v.(*Map)
+	table.forEach(func(key, value Object) Object {
+		if isMap {
+			entries = append(entries, in.format(key, level+1)+" => "+in.format(value, level+1))
+		} else {
+			entries = append(entries, in.format(key, level+1))
+		}
+		return nil
+	})
+	return entries
+}
+
+// collectionTable returns the entries of a map or set, or nil for other
+// values.
+func collectionTable(v Object) *orderedTable {
+	switch v := v.(type) {
+	case *Map:
+		return v.table
+	case *Set:
+		return v.table
+	}
+	return nil
+}
+
+// hasInternalSlots reports whether v is an object whose state isn't kept
+// in properties, such as a promise or an ArrayBuffer, which is shown
+// before its properties.
+func hasInternalSlots(v Object) bool {
+	switch v.(type) {
+	case *ArrayBuffer, *TypedArray, *DataView, *Promise, *ModuleNamespace, *HostObject:
+		return true
+	}
+	return false
+}
+
+// hasInternalContents reports whether v has internal state to show. An
+// empty typed array has none, and is shown as [], as does the namespace of
+// a module exporting nothing.
+func hasInternalContents(v Object) bool {
+	switch v := v.(type) {
+	case *TypedArray:
+		return v.len() > 0
+	case *ModuleNamespace:
+		return len(v.names) > 0
+	case *HostObject:
+		return len(v.Host.Keys()) > 0
+	}
+	return hasInternalSlots(v)
+}
+
+// maxBufferInspectLength is the number of bytes of an ArrayBuffer shown
+// before the rest are summarised as "... n more bytes".
+const maxBufferInspectLength = 50
+
+// formatInternalSlots describes the internal state of an object: the
+// state of a promise, the elements of a typed array, the bytes of an
+// ArrayBuffer in hexadecimal, the part of the buffer a DataView covers,
+// the exports of a module, or the properties a host object's host keeps.
+func (in *inspector) formatInternalSlots(v Object, level int) []string {
+	var entries []string
+	switch v := v.(type) {
+	case *Promise:
+		switch v.state {
+		case promisePending:
+			entries = append(entries, "<pending>")
+		case promiseFulfilled:
+			entries = append(entries, in.format(v.result, level+1))
+		case promiseRejected:
+			entries = append(entries, "<rejected> "+in.format(v.result, level+1))
+		}
+	case *TypedArray:
+		length := v.len()
+		for idx := 0; idx < length && idx < maxArrayInspectLength; idx++ {
+			entries = append(entries, in.format(v.get(idx), level+1))
+		}
+		if rest := length - maxArrayInspectLength; rest > 0 {
+			entries = append(entries, fmt.Sprintf("... %d more item%s", rest, plural(rest)))
+		}
+	case *ArrayBuffer:
+		if v.detached {
+			entries = append(entries, "(detached)")
+		} else {
+			bytes := make([]string, 0, min(len(v.data), maxBufferInspectLength))
+			for idx, b := range v.data {
+				if idx == maxBufferInspectLength {
+					rest := len(v.data) - idx
+					bytes = append(bytes, fmt.Sprintf("... %d more byte%s", rest, plural(rest)))
+					break
+				}
+				bytes = append(bytes, fmt.Sprintf("%02x", b))
+			}
+			entries = append(entries, "[Uint8Contents]: <"+strings.Join(bytes, " ")+">")
+		}
+		entries = append(entries, fmt.Sprintf("byteLength: %d", len(v.data)))
+	case *DataView:
+		offset := v.byteOffset
+		if v.outOfBounds() {
+			offset = 0
+		}
+		entries = append(entries, fmt.Sprintf("byteLength: %d", v.len()))
+		entries = append(entries, fmt.Sprintf("byteOffset: %d", offset))
+		entries = append(entries, "buffer: "+in.format(v.buffer, level+1))
+	case *ModuleNamespace:
+		for _, name := range v.names {
+			value := v.get(name)
+			formatted := "<uninitialized>"
+			if !isError(value) {
+				formatted = in.format(value, level+1)
+			}
+			entries = append(entries, formatPropertyKey(&String{Value: name})+": "+formatted)
+		}
+	case *HostObject:
+		for _, key := range v.hostKeys() {
+			value, _ := v.Host.Get(key.(*String).Value)
+			entries = append(entries, formatPropertyKey(key)+": "+in.format(value, level+1))
+		}
+	}
+	return entries
+}
+
+// formatArrayElements describes the elements of an array. Runs of holes
+// are shown as "<n empty items>".
+func (in *inspector) formatArrayElements(arr *Array, level int) []string {
+	// Only the elements that are there are visited, and the holes counted
+	// from the gaps between them, so that a huge sparse array is quick to
+	// describe.
+	var entries []string
+	next := 0
+	w := arr.walk(0, arr.len(), false)
+	for len(entries) < maxArrayInspectLength {
+		idx, e, ok := w.next()
+		if !ok {
+			idx = arr.len()
+		}
+		if holes := idx - next; holes > 0 {
+			entries = append(entries, fmt.Sprintf("<%d empty item%s>", holes, plural(holes)))
+			next = idx
+			if len(entries) == maxArrayInspectLength {
+				break
+			}
+		}
+		if !ok {
+			break
+		}
+		entries = append(entries, in.format(e, level+1))
+		next = idx + 1
+	}
+	if rest := arr.len() - next; rest > 0 {
+		entries = append(entries, fmt.Sprintf("... %d more item%s", rest, plural(rest)))
+	}
+	return entries
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// reduceToSingleString puts the entries on one line if they fit, and
+// otherwise gives each entry its own indented line.
+func (in *inspector) reduceToSingleString(entries []string, prefix string, braces [2]string) string {
+	start := joinPrefix(prefix, braces[0])
+	// Node allows for the separators and a little slack on top of the
+	// entries themselves.
+	length := 2*len(entries) + in.indentation + len(start) + 10
+	multiline := false
+	for _, e := range entries {
+		length += len(e)
+		if strings.Contains(e, "\n") {
+			multiline = true
+		}
+	}
+	if !multiline && length <= inspectBreakLength {
+		return start + " " + strings.Join(entries, ", ") + " " + braces[1]
+	}
+	indent := strings.Repeat(" ", in.indentation)
+	return start + "\n" + indent + "  " + strings.Join(entries, ",\n"+indent+"  ") + "\n" + indent + braces[1]
+}
+
+func joinPrefix(prefix, s string) string {
+	if prefix == "" {
+		return s
+	}
+	return prefix + " " + s
+}
+
+// visibleKeys returns the own properties of v that inspection shows. The
+// properties the language itself sets up, such as a function's prototype
+// and a prototype's constructor, are left out, as are the static methods
+// of built-in functions, since JavaScript makes them non-enumerable.
+func visibleKeys(v Object) []Object {
+	if _, ok := v.(*Builtin); ok {
+		return nil
+	}
+	holder := v.(propertyHolder).properties()
+	var keys []Object
+	for _, key := range holder.OwnKeys() {
+		if s, ok := key.(*String); ok {
+			value, _ := holder.GetOwn(key)
+			if s.Value == "prototype" && isCallable(v) {
+				continue
+			}
+			if s.Value == "constructor" && isConstructorOf(value, holder) {
+				continue
+			}
+			if _, ok := v.(*ErrorObject); ok && s.Value == "message" {
+				// The message is already shown with the name.
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// isConstructorOf reports whether fn is a function whose prototype
+// property is proto.
+func isConstructorOf(fn Object, proto *Hash) bool {
+	if !isCallable(fn) {
+		return false
+	}
+	p, ok := fn.(propertyHolder).properties().GetOwn(&String{Value: "prototype"})
+	return ok && isObject(p) && p.(propertyHolder).properties() == proto
+}
+
+// describeObject returns the label that goes before an object's braces,
+// such as "[Function: f]" or "Point", and the braces themselves. Objects
+// with a [Symbol.toStringTag] have the tag added, as in
+// "Object [Generator]".
+func describeObject(v Object) (string, [2]string) {
+	switch v := v.(type) {
+	case *Array:
+		return "", [2]string{"[", "]"}
+	case *Function:
+		kind := "Function"
+		if v.Generator {
+			kind = "GeneratorFunction"
+		}
+		if v.Async {
+			kind = "Async" + kind
+		}
+		return functionLabel(kind, v.Name), [2]string{"{", "}"}
+	case *Builtin:
+		return functionLabel("Function", v.Name), [2]string{"{", "}"}
+	case *Map:
+		return fmt.Sprintf("Map(%d)", v.table.size()), [2]string{"{", "}"}
+	case *Set:
+		return fmt.Sprintf("Set(%d)", v.table.size()), [2]string{"{", "}"}
+	case *RegExp, *Date, *ErrorObject:
+		return v.Inspect(), [2]string{"{", "}"}
+	case *TypedArray:
+		return fmt.Sprintf("%sArray(%d)", v.kind.name, v.len()), [2]string{"[", "]"}
+	case *ArrayBuffer:
+		return "ArrayBuffer", [2]string{"{", "}"}
+	case *DataView:
+		return "DataView", [2]string{"{", "}"}
+	case *Promise:
+		return "Promise", [2]string{"{", "}"}
+	case *ModuleNamespace:
+		return "[Module: null prototype]", [2]string{"{", "}"}
+	}
+	holder := v.(propertyHolder).properties()
+	if holder.Prototype == nil {
+		return "[Object: null prototype]", [2]string{"{", "}"}
+	}
+	name := constructorName(holder)
+	if tag, ok := holder.Get(symbolToStringTag); ok {
+		if tag, ok := tag.(*String); ok && tag.Value != "" && tag.Value != name {
+			if name == "" {
+				name = "Object"
+			}
+			name += " [" + tag.Value + "]"
+		}
+	}
+	return name, [2]string{"{", "}"}
+}
+
+// functionLabel describes a function of the given kind, such as
+// "[Function: f]" or "[AsyncGeneratorFunction (anonymous)]".
+func functionLabel(kind, name string) string {
+	if name == "" {
+		return "[" + kind + " (anonymous)]"
+	}
+	return "[" + kind + ": " + name + "]"
+}
+
+// constructorName returns the name of the function that created an object
+// with new, or "" for plain objects.
+func constructorName(h *Hash) string {
+	if h.Prototype == nil {
+		return ""
+	}
+	ctor, ok := h.Prototype.GetOwn(&String{Value: "constructor"})
+	if !ok {
+		return ""
+	}
+	switch fn := ctor.(type) {
+	case *Function:
+		if fn.Name != "Object" {
+			return fn.Name
+		}
+	case *Builtin:
+		return fn.Name
+	}
+	return ""
+}
+
+// formatPropertyKey writes a property name, quoting it unless it is a
+// valid identifier.
+func formatPropertyKey(key Object) string {
+	s, ok := key.(*String)
+	if !ok {
+		return "[" + key.Inspect() + "]"
+	}
+	if isIdentifierName(s.Value) {
+		return s.Value
+	}
+	return quoteJSString(s.Value)
+}
+
+func isIdentifierName(s string) bool {
+	if s == "" {
+		return false
+	}
+	for idx, c := range s {
+		letter := c == '_' || c == '$' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+		if !letter && (idx == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// quoteJSString quotes a string for display. Single quotes are preferred;
+// if the string contains them, double quotes or backticks are used instead
+// so that as little as possible needs escaping.
+func quoteJSString(s string) string {
+	quote := '\''
+	if strings.ContainsRune(s, '\'') {
+		switch {
+		case !strings.ContainsRune(s, '"'):
+			quote = '"'
+		case !strings.ContainsRune(s, '`') && !strings.Contains(s, "${"):
+			quote = '`'
+		}
+	}
+
+	var out strings.Builder
+	out.WriteRune(quote)
+	units := utf16Units(s)
+	for idx := 0; idx < len(units); idx++ {
+		u := units[idx]
+		switch {
+		case rune(u) == quote || u == '\\':
+			out.WriteByte('\\')
+			out.WriteByte(byte(u))
+		case u == '\n':
+			out.WriteString(`\n`)
+		case u == '\t':
+			out.WriteString(`\t`)
+		case u == '\r':
+			out.WriteString(`\r`)
+		case u == '\b':
+			out.WriteString(`\b`)
+		case u == '\f':
+			out.WriteString(`\f`)
+		case u == '\v':
+			out.WriteString(`\v`)
+		case u < 0x20 || u == 0x7f:
+			fmt.Fprintf(&out, `\x%02X`, u)
+		case utf16.IsSurrogate(rune(u)):
+			if u < 0xDC00 && idx+1 < len(units) && 0xDC00 <= units[idx+1] && units[idx+1] < 0xE000 {
+				out.WriteRune(utf16.DecodeRune(rune(u), rune(units[idx+1])))
+				idx++
+				continue
+			}
+			fmt.Fprintf(&out, `\u%04X`, u)
+		default:
+			out.WriteRune(rune(u))
+		}
+	}
+	out.WriteRune(quote)
+	return out.String()
+}
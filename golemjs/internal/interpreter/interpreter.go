@@ -1,10 +1,18 @@
 package interpreter
 
 import (
+	"context"
 	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"math"
+	"math/big"
+	"math/rand/v2"
 	"strings"
+	"time"
 
 	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/regex"
 )
 
 // Object represents a JavaScript object in our interpreter.
@@ -24,16 +32,56 @@ type Object interface {
 type ObjectType string
 
 const (
-	NULL_OBJ  = "NULL"
-	ERROR_OBJ = "ERROR"
-	INTEGER_OBJ = "INTEGER"
-	STRING_OBJ = "STRING"
-	BOOLEAN_OBJ = "BOOLEAN"
-	RETURN_VALUE_OBJ = "RETURN_VALUE"
-	FUNCTION_OBJ = "FUNCTION"
-	BUILTIN_OBJ = "BUILTIN"
-	ARRAY_OBJ = "ARRAY"
-	HASH_OBJ = "HASH"
+	NULL_OBJ           = "NULL"
+	UNDEFINED_OBJ      = "UNDEFINED"
+	SYMBOL_OBJ         = "SYMBOL"
+	ERROR_OBJ          = "ERROR"
+	ERROR_INSTANCE_OBJ = "ERROR_INSTANCE"
+	NUMBER_OBJ         = "NUMBER"
+	STRING_OBJ         = "STRING"
+	BOOLEAN_OBJ        = "BOOLEAN"
+	RETURN_VALUE_OBJ   = "RETURN_VALUE"
+	BREAK_OBJ          = "BREAK"
+	CONTINUE_OBJ       = "CONTINUE"
+	FUNCTION_OBJ       = "FUNCTION"
+	BUILTIN_OBJ        = "BUILTIN"
+	ARRAY_OBJ          = "ARRAY"
+	ARRAY_ITERATOR_OBJ = "ARRAY_ITERATOR"
+	HASH_OBJ           = "HASH"
+
+	STRING_ITERATOR_OBJ = "STRING_ITERATOR"
+	GENERATOR_OBJ       = "GENERATOR"
+
+	MAP_OBJ                   = "MAP"
+	SET_OBJ                   = "SET"
+	MAP_ITERATOR_OBJ          = "MAP_ITERATOR"
+	SET_ITERATOR_OBJ          = "SET_ITERATOR"
+	WEAK_MAP_OBJ              = "WEAK_MAP"
+	WEAK_SET_OBJ              = "WEAK_SET"
+	WEAK_REF_OBJ              = "WEAK_REF"
+	FINALIZATION_REGISTRY_OBJ = "FINALIZATION_REGISTRY"
+
+	REGEXP_OBJ                 = "REGEXP"
+	REGEXP_STRING_ITERATOR_OBJ = "REGEXP_STRING_ITERATOR"
+
+	DATE_OBJ = "DATE"
+
+	BIGINT_OBJ = "BIGINT"
+
+	ARRAY_BUFFER_OBJ = "ARRAY_BUFFER"
+	TYPED_ARRAY_OBJ  = "TYPED_ARRAY"
+	DATA_VIEW_OBJ    = "DATA_VIEW"
+	TEXT_ENCODER_OBJ = "TEXT_ENCODER"
+	TEXT_DECODER_OBJ = "TEXT_DECODER"
+
+	PROMISE_OBJ         = "PROMISE"
+	ASYNC_GENERATOR_OBJ = "ASYNC_GENERATOR"
+
+	MODULE_NAMESPACE_OBJ = "MODULE_NAMESPACE"
+
+	HOST_OBJ = "HOST"
+
+	CELL_OBJ = "CELL"
 )
 
 // Null represents JavaScript's null value.
@@ -43,34 +91,70 @@ type Null struct{}
 func (n *Null) Type() ObjectType { return NULL_OBJ }
 func (n *Null) Inspect() string  { return "null" }
 
+// Undefined represents JavaScript's undefined value.
+// Where null is a value a program chooses to store, undefined is what you
+// get when there is no value at all: a variable that was declared but not
+// assigned, a parameter without an argument, a property that doesn't
+// exist, or the result of a function that doesn't return anything.
+type Undefined struct{}
+
+func (u *Undefined) Type() ObjectType { return UNDEFINED_OBJ }
+func (u *Undefined) Inspect() string  { return "undefined" }
+
 // Error represents a JavaScript error object.
 // Errors can occur during evaluation and need to be handled appropriately.
 type Error struct {
 	Message string
+
+	// Value is the value a script threw, for errors that didn't originate
+	// in the interpreter itself, such as one passed to generator.throw.
+	Value Object
+
+	// returning marks the signal that unwinds a generator body when
+	// return is called on the generator; see generatorReturn.
+	returning *ReturnValue
+
+	// Cause is set for an error raised because a limit was exceeded, or
+	// because the context evaluation was given is done; see Limits. An
+	// embedding program may also set it to the Go error that an error it
+	// raised stands for.
+	Cause error
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
 
-// Integer represents JavaScript numbers.
-// In our toy implementation, we only handle integers for simplicity.
-// A real JavaScript engine would handle floating-point numbers as well.
-type Integer struct {
-	Value int64
+// Number represents JavaScript numbers.
+// JavaScript has a single number type, a 64-bit floating-point value, so
+// integers and fractions alike are stored as float64. This includes the
+// special values NaN, Infinity and -Infinity, and negative zero.
+type Number struct {
+	Value float64
 }
 
-func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
-func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
+func (n *Number) Type() ObjectType { return NUMBER_OBJ }
+func (n *Number) Inspect() string  { return numberToString(n.Value) }
 
 // String represents JavaScript strings.
-// Strings are immutable sequences of characters.
+// Strings are immutable sequences of characters. JavaScript measures and
+// indexes strings in UTF-16 code units, so "😀".length is 2 even though it
+// is a single character. Value holds the text as UTF-8 (WTF-8 when it
+// contains unpaired surrogates); the UTF-16 form is computed on demand.
 type String struct {
 	Value string
+	utf16 []uint16 // cached result of units()
 }
 
 func (s *String) Type() ObjectType { return STRING_OBJ }
 func (s *String) Inspect() string  { return s.Value }
 
+// HashKey lets strings be used as property keys.
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 // Boolean represents JavaScript boolean values.
 // There are only two possible values: true and false.
 type Boolean struct {
@@ -90,16 +174,41 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break and Continue are signals, like ReturnValue, that travel up from a
+// break or continue statement to the loop that should handle them.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 // Function represents a JavaScript function.
 // Functions are objects that can be called with arguments.
 // They contain:
 // - Parameters: The function's formal parameters
 // - Body: The function's body (an AST node)
 // - Env: The environment where the function was defined (for closures)
+// Like every object, a function can also carry properties; its "prototype"
+// property becomes the prototype of objects created with new.
 type Function struct {
-	Parameters []*ast.Identifier
+	Hash
+	Name       string
+	Parameters []ast.Expression
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Arrow      bool // arrow functions take "this" from where they were defined
+	Generator  bool // calling a generator function returns a generator
+	Async      bool // calling an async function returns a promise
+
+	// compiled is set for a function compiled to bytecode, which the VM
+	// runs instead of evaluating Body, and free holds the cells of the
+	// variables it captured. Such a function has no Env. See vm.go.
+	compiled *vmFunction
+	free     []*cell
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -110,40 +219,37 @@ func (f *Function) Inspect() string {
 		params = append(params, p.String())
 	}
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
-	out.WriteString(") {\n")
+	out.WriteString(") ")
 	out.WriteString(f.Body.String())
-	out.WriteString("\n}")
 	return out.String()
 }
 
 // BuiltinFunction represents a built-in JavaScript function.
 // These are functions implemented in Go that provide core functionality
-// like console.log, parseInt, etc.
-type BuiltinFunction func(args ...Object) Object
+// like console.log, parseInt, etc. The receiver of the call ("this") is
+// passed first; it is nil when the function was not called as a method.
+type BuiltinFunction func(this Object, args ...Object) Object
 
 type Builtin struct {
-	Fn BuiltinFunction
-}
+	Hash
+	Name string
+	Fn   BuiltinFunction
 
-func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
-func (b *Builtin) Inspect() string  { return "builtin function" }
+	notConstructor bool // whether new refuses to call it, as with Symbol
+	requiresNew    bool // whether it can only be called with new, as with Map
 
-// Array represents JavaScript arrays.
-// Arrays are ordered collections of values that can be of any type.
-type Array struct {
-	Elements []Object
+	// call, if set, is what calling the function without new does, for
+	// constructors where that differs from Fn, as with Date.
+	call BuiltinFunction
 }
 
-func (ao *Array) Type() ObjectType { return ARRAY_OBJ }
-func (ao *Array) Inspect() string {
-	elements := []string{}
-	for _, e := range ao.Elements {
-		elements = append(elements, e.Inspect())
-	}
-	return fmt.Sprintf("[%s]", strings.Join(elements, ", "))
-}
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+func (b *Builtin) Inspect() string  { return "builtin function" }
 
 // HashKey represents a key in a JavaScript object.
 // In JavaScript, object keys are always strings.
@@ -152,24 +258,27 @@ type HashKey struct {
 	Value uint64
 }
 
-// HashPair represents a key-value pair in a JavaScript object.
-type HashPair struct {
-	Key   Object
-	Value Object
-}
-
 // Hash represents a JavaScript object (not to be confused with HashKey).
-// Objects are collections of properties (key-value pairs).
+// Objects are collections of properties (key-value pairs). When a property
+// isn't found on the object itself, the lookup continues on its Prototype,
+// which is how objects inherit methods.
+//
+// The values of the properties are kept in values, in the slots that the
+// object's shape assigns to their keys; see shape.go. The zero Hash is an
+// empty object without a prototype.
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	shape     *shape
+	values    []Object
+	Prototype *Hash
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
 func (h *Hash) Inspect() string {
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, key := range h.OwnKeys() {
+		value, _ := h.GetOwn(key)
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
-			pair.Key.Inspect(), pair.Value.Value.Inspect()))
+			key.Inspect(), value.Inspect()))
 	}
 	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
 }
@@ -180,161 +289,341 @@ type Hashable interface {
 	HashKey() HashKey
 }
 
-// Environment represents a JavaScript scope.
-// Environments are used to implement variable scoping and closures.
-// They form a chain (like a linked list) where each environment
-// has a reference to its outer (parent) environment.
-type Environment struct {
-	store map[string]Object
-	outer *Environment
-}
-
-// NewEnvironment creates a new environment.
-// The outer parameter is used to create nested scopes.
-func NewEnvironment(outer *Environment) *Environment {
-	env := &Environment{store: make(map[string]Object), outer: outer}
-	return env
-}
-
-// Get retrieves a variable from the environment.
-// If the variable isn't found in the current environment,
-// it looks in the outer environment (implementing variable shadowing).
-func (e *Environment) Get(name string) (Object, bool) {
-	obj, ok := e.store[name]
-	if !ok && e.outer != nil {
-		obj, ok = e.outer.Get(name)
-	}
-	return obj, ok
-}
-
-// Set stores a variable in the current environment.
-// Note that this doesn't modify variables in outer environments.
-func (e *Environment) Set(name string, val Object) Object {
-	e.store[name] = val
-	return val
-}
-
 // Interpreter represents our JavaScript interpreter.
 // It's responsible for evaluating AST nodes and producing JavaScript values.
+// Each interpreter has its own global environment and its own set of
+// built-in prototypes, so scripts can't affect each other.
 type Interpreter struct {
 	env *Environment
+
+	objectPrototype         *Hash
+	functionPrototype       *Hash
+	arrayPrototype          *Hash
+	iteratorPrototype       *Hash
+	arrayIteratorPrototype  *Hash
+	stringIteratorPrototype *Hash
+	generatorPrototype      *Hash
+	stringPrototype         *Hash
+	numberPrototype         *Hash
+	booleanPrototype        *Hash
+	symbolPrototype         *Hash
+
+	mapPrototype                  *Hash
+	setPrototype                  *Hash
+	mapIteratorPrototype          *Hash
+	setIteratorPrototype          *Hash
+	weakMapPrototype              *Hash
+	weakSetPrototype              *Hash
+	weakRefPrototype              *Hash
+	finalizationRegistryPrototype *Hash
+
+	regexpPrototype               *Hash
+	regexpStringIteratorPrototype *Hash
+	datePrototype                 *Hash
+	bigintPrototype               *Hash
+
+	arrayBufferPrototype *Hash
+	dataViewPrototype    *Hash
+	textEncoderPrototype *Hash
+	textDecoderPrototype *Hash
+
+	// typedArrayPrototype is %TypedArray%.prototype, which the prototypes
+	// of Int8Array, Uint8Array and the rest, held in typedArrayPrototypes,
+	// inherit from.
+	typedArrayPrototype  *Hash
+	typedArrayPrototypes map[*elementType]*Hash
+
+	promisePrototype        *Hash
+	asyncIteratorPrototype  *Hash
+	asyncGeneratorPrototype *Hash
+
+	// errorPrototype is Error.prototype, which the prototypes of the kinds
+	// of error, held in errorPrototypes by name, inherit from. See
+	// setupErrors.
+	errorPrototype  *Hash
+	errorPrototypes map[string]*Hash
+
+	// promiseConstructor is the Promise function, which promises created
+	// by the interpreter itself are made with.
+	promiseConstructor *Builtin
+
+	// loop holds the work queued to run after the current script; see
+	// eventloop.go.
+	loop eventLoop
+
+	// regexCache holds the compiled pattern of each regular expression
+	// literal, so a literal in a loop is compiled only once.
+	regexCache map[*ast.RegExpLiteral]*regex.Regexp
+
+	// symbolRegistry holds the symbols created by Symbol.for, by key.
+	symbolRegistry map[string]*Symbol
+
+	// joining holds the arrays currently being converted to strings, so
+	// that an array containing itself is joined as "" instead of looping
+	// forever.
+	joining map[*Array]bool
+
+	console console
+
+	// random is the source of Math.random's numbers.
+	random *rand.Rand
+
+	// running is the coroutine whose body is running, which a yield or
+	// await pauses.
+	running *coroutine
+
+	// keptAlive holds the targets of WeakRefs created or dereferenced by
+	// the running script, which stay alive until it finishes.
+	keptAlive []Object
+
+	// cleanups collects the FinalizationRegistry registrations whose
+	// targets have been collected.
+	cleanups *cleanupQueue
+
+	// clock gives the current time for Date and timers, and location the
+	// time zone Date uses for local time. See SetClock and SetTimeZone.
+	clock    Clock
+	location *time.Location
+
+	// loader finds the modules that scripts import, and modules holds
+	// those loaded, by name. moduleEnvs maps the environment of each
+	// module to the module, so import() can tell which module it's in.
+	// See module.go.
+	loader     ModuleLoader
+	modules    map[string]*moduleRecord
+	moduleEnvs map[*Environment]*moduleRecord
+
+	// requireFS holds the CommonJS modules require loads, and
+	// requireCache those it has loaded, by path. See require.go.
+	requireFS    fs.FS
+	requireCache map[string]*cjsModule
+
+	// backend is how Eval runs scripts. See SetBackend.
+	backend Backend
+
+	// limits bounds what scripts may use, and usage tracks what the
+	// script being evaluated has used. See limits.go.
+	limits Limits
+	usage  usage
 }
 
 // New creates a new interpreter with a fresh environment.
 func New() *Interpreter {
-	env := NewEnvironment(nil)
-	return &Interpreter{env: env}
+	env := NewFunctionEnvironment(nil)
+	i := &Interpreter{
+		env:        env,
+		modules:    map[string]*moduleRecord{},
+		moduleEnvs: map[*Environment]*moduleRecord{},
+		usage:      usage{ctx: context.Background()},
+	}
+	i.setupGlobals()
+	return i
 }
 
 // Eval evaluates an AST node and returns the resulting JavaScript value.
-// This is the main entry point for evaluation.
+// This is the main entry point for evaluation. Once the script itself has
+// finished, the event loop runs until idle, so that the promise callbacks
+// and other work the script queued run too; an error from those is
+// returned if the script itself succeeded.
+//
+// A module, which may use await at its top level, may only finish while
+// the event loop runs. Its result is known once the loop is idle; if the
+// module is still waiting then, for a promise nothing will ever settle,
+// the result is undefined.
 func (i *Interpreter) Eval(node ast.Node) Object {
+	return i.EvalContext(context.Background(), node)
+}
+
+// evalNode does the work of Eval.
+func (i *Interpreter) evalNode(node ast.Node) Object {
+	if program, ok := node.(*ast.Program); ok && program.Module {
+		return i.evalModule(program)
+	}
+	var result Object
+	if program, ok := node.(*ast.Program); ok && i.backend == BytecodeVM {
+		result = i.runProgram(program)
+	} else {
+		result = i.eval(node, i.env)
+	}
+	if err := i.runUntilIdle(); err != nil && !isError(result) {
+		return err
+	}
+	return result
+}
+
+// eval evaluates node in the given environment. The environment is passed
+// explicitly rather than stored on the interpreter so that closures, blocks
+// and function calls each see the scope they were created in.
+func (i *Interpreter) eval(node ast.Node, env *Environment) Object {
+	if err := i.tick(); err != nil {
+		return err
+	}
 	switch node := node.(type) {
 	case *ast.Program:
-		return i.evalProgram(node)
+		return i.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return i.Eval(node.Expression)
-	case *ast.IntegerLiteral:
-		return &Integer{Value: node.Value}
-	case *ast.StringLiteral:
-		return &String{Value: node.Value}
-	case *ast.Boolean:
-		return nativeBoolToBooleanObject(node.Value)
-	case *ast.PrefixExpression:
-		right := i.Eval(node.Right)
+		return i.eval(node.Expression, env)
+	case *ast.Literal:
+		return i.evalLiteral(node)
+	case *ast.UnaryExpression:
+		if node.Operator == "delete" {
+			return i.evalDeleteExpression(node.Argument, env)
+		}
+		if ident, ok := node.Argument.(*ast.Identifier); ok && node.Operator == "typeof" {
+			// typeof is the one place where an undeclared variable
+			// isn't a ReferenceError.
+			if _, ok := env.Get(ident.Value); !ok {
+				return &String{Value: "undefined"}
+			}
+		}
+		right := i.eval(node.Argument, env)
 		if isError(right) {
 			return right
 		}
 		return i.evalPrefixExpression(node.Operator, right)
-	case *ast.InfixExpression:
-		left := i.Eval(node.Left)
-		if isError(left) {
-			return left
+	case *ast.BinaryExpression:
+		return i.evalBinaryExpression(node, env)
+	case *ast.AssignmentExpression:
+		return i.evalAssignmentExpression(node, env)
+	case *ast.UpdateExpression:
+		return i.evalUpdateExpression(node, env)
+	case *ast.ConditionalExpression:
+		test := i.eval(node.Test, env)
+		if isError(test) {
+			return test
 		}
-		right := i.Eval(node.Right)
-		if isError(right) {
-			return right
+		if isTruthy(test) {
+			return i.eval(node.Consequent, env)
 		}
-		return i.evalInfixExpression(node.Operator, left, right)
+		return i.eval(node.Alternate, env)
 	case *ast.BlockStatement:
-		return i.evalBlockStatement(node)
-	case *ast.IfExpression:
-		return i.evalIfExpression(node)
+		return i.evalBlockStatement(node, NewEnvironment(env))
+	case *ast.IfStatement:
+		return i.evalIfStatement(node, env)
+	case *ast.WhileStatement:
+		return i.evalWhileStatement(node, env)
+	case *ast.ForStatement:
+		return i.evalForStatement(node, env)
+	case *ast.ForOfStatement:
+		return i.evalForOfStatement(node, env)
+	case *ast.BreakStatement:
+		return BREAK
+	case *ast.ContinueStatement:
+		return CONTINUE
 	case *ast.ReturnStatement:
-		val := i.Eval(node.ReturnValue)
-		if isError(val) {
-			return val
+		if node.ReturnValue == nil {
+			return &ReturnValue{Value: UNDEFINED}
 		}
-		return &ReturnValue{Value: val}
-	case *ast.LetStatement:
-		val := i.Eval(node.Value)
+		val := i.eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
-		i.env.Set(node.Name.Value, val)
+		return &ReturnValue{Value: val}
+	case *ast.VariableDeclaration:
+		return i.evalVariableDeclaration(node, env)
+	case *ast.FunctionDeclaration:
+		// Function declarations are hoisted: they were already bound when
+		// the enclosing block started.
+		return nil
 	case *ast.Identifier:
-		return i.evalIdentifier(node)
+		return i.evalIdentifier(node, env)
+	case *ast.RegExpLiteral:
+		return i.evalRegExpLiteral(node)
+	case *ast.ThisExpression:
+		if this, ok := env.Get("this"); ok {
+			return this
+		}
+		return UNDEFINED
 	case *ast.FunctionLiteral:
-		params := node.Parameters
-		body := node.Body
-		return &Function{Parameters: params, Body: body, Env: i.env}
+		return i.evalFunctionLiteral(node, env)
 	case *ast.CallExpression:
-		function := i.Eval(node.Function)
-		if isError(function) {
-			return function
+		return i.evalCallExpression(node, env)
+	case *ast.NewExpression:
+		callee := i.eval(node.Callee, env)
+		if isError(callee) {
+			return callee
 		}
-		args := i.evalExpressions(node.Arguments)
+		args := i.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return i.applyFunction(function, args)
+		return i.construct(callee, args)
 	case *ast.ArrayLiteral:
-		elements := i.evalExpressions(node.Elements)
-		if len(elements) == 1 && isError(elements[0]) {
-			return elements[0]
-		}
-		return &Array{Elements: elements}
-	case *ast.IndexExpression:
-		left := i.Eval(node.Left)
-		if isError(left) {
-			return left
+		return i.evalArrayLiteral(node, env)
+	case *ast.MemberExpression:
+		object := i.eval(node.Object, env)
+		if isError(object) {
+			return object
+		}
+		key := i.evalPropertyKey(node, env)
+		if isError(key) {
+			return key
 		}
-		index := i.Eval(node.Index)
-		if isError(index) {
-			return index
+		return i.getProperty(object, key)
+	case *ast.ObjectLiteral:
+		return i.evalObjectLiteral(node, env)
+	case *ast.YieldExpression:
+		return i.evalYieldExpression(node, env)
+	case *ast.AwaitExpression:
+		return i.evalAwaitExpression(node, env)
+	case *ast.ImportDeclaration, *ast.ExportAllDeclaration:
+		// Imports were bound when the module was linked.
+		return nil
+	case *ast.ExportNamedDeclaration:
+		if node.Declaration == nil {
+			return nil
 		}
-		return i.evalIndexExpression(left, index)
-	case *ast.HashLiteral:
-		return i.evalHashLiteral(node)
+		return i.eval(node.Declaration, env)
+	case *ast.ExportDefaultDeclaration:
+		return i.evalExportDefault(node, env)
+	case *ast.ImportCall:
+		return i.evalImportCall(node, env)
 	}
 	return nil
 }
 
 // evalProgram evaluates a program (the root node of the AST).
 // It evaluates each statement in sequence and returns the last value.
-func (i *Interpreter) evalProgram(program *ast.Program) Object {
-	var result Object
-	for _, statement := range program.Statements {
-		result = i.Eval(statement)
-		switch result := result.(type) {
-		case *ReturnValue:
-			return result.Value
-		case *Error:
-			return result
-		}
+func (i *Interpreter) evalProgram(program *ast.Program, env *Environment) Object {
+	return completionValue(i.evalStatements(program.Statements, env))
+}
+
+// completionValue turns the result of the statements at the top level of a
+// program into the program's result. A return or break there has nothing
+// to leave, and ends the program.
+func completionValue(result Object) Object {
+	switch result := result.(type) {
+	case *ReturnValue:
+		return result.Value
+	case *Break, *Continue:
+		return nil
 	}
 	return result
 }
 
-// evalBlockStatement evaluates a block of statements.
-// It creates a new environment for the block to implement proper scoping.
-func (i *Interpreter) evalBlockStatement(block *ast.BlockStatement) Object {
+// evalBlockStatement evaluates a block of statements in env, which the
+// caller creates so that let and const declarations stay inside the block.
+func (i *Interpreter) evalBlockStatement(block *ast.BlockStatement, env *Environment) Object {
+	return i.evalStatements(block.Statements, env)
+}
+
+// evalStatements runs a list of statements and returns the value of the last
+// one. It stops early on anything that should leave the list: a return,
+// break or continue signal, or an error.
+func (i *Interpreter) evalStatements(statements []ast.Statement, env *Environment) Object {
+	i.hoistFunctions(statements, env)
+	return i.runStatements(statements, env)
+}
+
+// runStatements runs a list of statements whose functions have been
+// hoisted already, as evalStatements does.
+func (i *Interpreter) runStatements(statements []ast.Statement, env *Environment) Object {
 	var result Object
-	for _, statement := range block.Statements {
-		result = i.Eval(statement)
+	for _, statement := range statements {
+		result = i.eval(statement, env)
 		if result != nil {
 			rt := result.Type()
-			if rt == RETURN_VALUE_OBJ || rt == ERROR_OBJ {
+			if rt == RETURN_VALUE_OBJ || rt == ERROR_OBJ || rt == BREAK_OBJ || rt == CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -342,106 +631,649 @@ func (i *Interpreter) evalBlockStatement(block *ast.BlockStatement) Object {
 	return result
 }
 
-// evalPrefixExpression evaluates prefix expressions like -5 or !true.
+// hoistFunctions binds every function declaration in statements before any
+// of them run. This is why JavaScript lets you call a function that is
+// declared further down in the same block.
+func (i *Interpreter) hoistFunctions(statements []ast.Statement, env *Environment) {
+	for _, statement := range statements {
+		decl, ok := statement.(*ast.FunctionDeclaration)
+		if !ok {
+			continue
+		}
+		fn := i.newFunctionOfKind(decl.Name.Value, decl.Parameters, decl.Body, env, false, decl.Generator, decl.Async)
+		env.Set(decl.Name.Value, fn)
+	}
+}
+
+func (i *Interpreter) evalLiteral(node *ast.Literal) Object {
+	switch value := node.Value.(type) {
+	case float64:
+		return &Number{Value: value}
+	case *big.Int:
+		return &BigInt{Value: value}
+	case string:
+		return &String{Value: value}
+	case bool:
+		return nativeBoolToBooleanObject(value)
+	}
+	return NULL
+}
+
+// evalVariableDeclaration evaluates var, let and const declarations.
+// var declarations belong to the enclosing function, while let and const
+// belong to the enclosing block. A declaration can also destructure its
+// value into several variables, as in const { a, b } = obj.
+func (i *Interpreter) evalVariableDeclaration(node *ast.VariableDeclaration, env *Environment) Object {
+	var val Object = UNDEFINED
+	if node.Value != nil {
+		val = i.eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+	}
+
+	name, ok := node.Name.(*ast.Identifier)
+	if !ok {
+		return i.bindPattern(node.Name, val, node.Token.Literal, env)
+	}
+	nameAnonymousFunction(val, name.Value)
+	// Declaring a var again without a value keeps its current value.
+	if node.Token.Literal == "var" && node.Value == nil && env.FunctionScope().HasOwn(name.Value) {
+		return nil
+	}
+	return i.bindName(name.Value, val, node.Token.Literal, env)
+}
+
+// evalPrefixExpression evaluates prefix expressions like -5, !true or
+// typeof x.
 func (i *Interpreter) evalPrefixExpression(operator string, right Object) Object {
 	switch operator {
 	case "!":
 		return i.evalBangOperatorExpression(right)
 	case "-":
 		return i.evalMinusPrefixOperatorExpression(right)
+	case "+":
+		n, err := i.toNumber(right)
+		if err != nil {
+			return err
+		}
+		return &Number{Value: n}
+	case "typeof":
+		return &String{Value: typeOf(right)}
+	case "void":
+		return UNDEFINED
 	default:
 		return newError("unknown operator: %s%s", operator, right.Type())
 	}
 }
 
-// evalInfixExpression evaluates infix expressions like 5 + 5 or true && false.
+// evalDeleteExpression evaluates delete, which removes a property from an
+// object: after delete a[1], a has a hole at 1. Variables can't be deleted,
+// so delete x is false if x is declared, and true if there's no x to
+// delete. Deleting anything else, as in delete f(), only evaluates it.
+func (i *Interpreter) evalDeleteExpression(node ast.Expression, env *Environment) Object {
+	switch target := node.(type) {
+	case *ast.Identifier:
+		_, declared := env.Get(target.Value)
+		return nativeBoolToBooleanObject(!declared)
+	case *ast.MemberExpression:
+		object := i.eval(target.Object, env)
+		if isError(object) {
+			return object
+		}
+		key := i.evalPropertyKey(target, env)
+		if isError(key) {
+			return key
+		}
+		return i.deleteMember(object, key)
+	}
+	if val := i.eval(node, env); isError(val) {
+		return val
+	}
+	return TRUE
+}
+
+// deleteMember deletes the property key of object, for delete object[key],
+// and returns whether it could.
+func (i *Interpreter) deleteMember(object, key Object) Object {
+	if object == NULL || object == UNDEFINED {
+		return newTypeError("Cannot convert undefined or null to object")
+	}
+	deleted, err := i.deleteProperty(object, key)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(deleted)
+}
+
+func (i *Interpreter) evalBangOperatorExpression(right Object) Object {
+	return nativeBoolToBooleanObject(!isTruthy(right))
+}
+
+func (i *Interpreter) evalMinusPrefixOperatorExpression(right Object) Object {
+	n, err := i.toNumeric(right)
+	if err != nil {
+		return err
+	}
+	if b, ok := n.(*BigInt); ok {
+		return &BigInt{Value: new(big.Int).Neg(b.Value)}
+	}
+	return &Number{Value: -n.(*Number).Value}
+}
+
+// evalBinaryExpression evaluates both sides of a binary expression and
+// combines them. The logical operators && and || are special: they only
+// evaluate their right side when the left side doesn't decide the result.
+func (i *Interpreter) evalBinaryExpression(node *ast.BinaryExpression, env *Environment) Object {
+	left := i.eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	switch node.Operator {
+	case "&&":
+		if !isTruthy(left) {
+			return left
+		}
+		return i.eval(node.Right, env)
+	case "||":
+		if isTruthy(left) {
+			return left
+		}
+		return i.eval(node.Right, env)
+	}
+	right := i.eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+	return i.evalInfixExpression(node.Operator, left, right)
+}
+
+// evalInfixExpression evaluates infix expressions like 5 + 5 or a < b.
+// Apart from the strict equality operators, the operands are converted
+// with the abstract operations in conversion.go, so the operators accept
+// values of any type.
 func (i *Interpreter) evalInfixExpression(operator string, left, right Object) Object {
+	switch operator {
+	case "===":
+		return nativeBoolToBooleanObject(strictEquals(left, right))
+	case "!==":
+		return nativeBoolToBooleanObject(!strictEquals(left, right))
+	case "==", "!=":
+		equal, err := i.looseEquals(left, right)
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(equal == (operator == "=="))
+	case "+":
+		return i.evalAdditionExpression(left, right)
+	case "<", ">", "<=", ">=":
+		return i.evalRelationalExpression(operator, left, right)
+	case "instanceof":
+		return i.instanceOf(left, right)
+	}
+	return i.evalNumericInfixExpression(operator, left, right)
+}
+
+// evalNumericInfixExpression evaluates an arithmetic or bitwise operator. Both
+// operands are converted to numbers or BigInts, which have to be the same:
+// mixing the two is a TypeError.
+func (i *Interpreter) evalNumericInfixExpression(operator string, left, right Object) Object {
+	leftNum, err := i.toNumeric(left)
+	if err != nil {
+		return err
+	}
+	rightNum, err := i.toNumeric(right)
+	if err != nil {
+		return err
+	}
+	l, leftBig := leftNum.(*BigInt)
+	r, rightBig := rightNum.(*BigInt)
 	switch {
-	case left.Type() == INTEGER_OBJ && right.Type() == INTEGER_OBJ:
-		return i.evalIntegerInfixExpression(operator, left, right)
-	case operator == "+":
+	case leftBig && rightBig:
+		return evalBigIntInfixExpression(operator, l.Value, r.Value)
+	case leftBig || rightBig:
+		return newTypeError("Cannot mix BigInt and other types, use explicit conversions")
+	}
+	return i.evalNumberInfixExpression(operator, leftNum.(*Number).Value, rightNum.(*Number).Value)
+}
+
+// evalAdditionExpression evaluates +, which adds numbers but concatenates
+// as soon as either operand is a string. Objects are converted to
+// primitives first, so [1] + 1 is "11" but true + 1 is 2.
+func (i *Interpreter) evalAdditionExpression(left, right Object) Object {
+	left = i.toPrimitive(left, "default")
+	if isError(left) {
+		return left
+	}
+	right = i.toPrimitive(right, "default")
+	if isError(right) {
+		return right
+	}
+	if left.Type() == STRING_OBJ || right.Type() == STRING_OBJ {
 		return i.evalStringInfixExpression(left, right)
-	case operator == "==":
-		return nativeBoolToBooleanObject(left == right)
-	case operator == "!=":
-		return nativeBoolToBooleanObject(left != right)
-	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s",
-			left.Type(), operator, right.Type())
+	}
+	return i.evalNumericInfixExpression("+", left, right)
+}
+
+// evalRelationalExpression evaluates <, >, <= and >=. Two strings are
+// compared by their UTF-16 code units, so "B" < "a"; anything else is
+// compared as numbers, and every comparison involving NaN is false.
+// BigInts are compared exactly with numbers and other BigInts.
+func (i *Interpreter) evalRelationalExpression(operator string, left, right Object) Object {
+	left = i.toPrimitive(left, "number")
+	if isError(left) {
+		return left
+	}
+	right = i.toPrimitive(right, "number")
+	if isError(right) {
+		return right
+	}
+	if l, ok := left.(*String); ok {
+		if r, ok := right.(*String); ok {
+			return compareResult(operator, compareUnits(l.units(), r.units()))
+		}
+	}
+	if left.Type() == BIGINT_OBJ || right.Type() == BIGINT_OBJ {
+		return i.evalBigIntComparison(operator, left, right)
+	}
+	leftVal, err := i.toNumber(left)
+	if err != nil {
+		return err
+	}
+	rightVal, err := i.toNumber(right)
+	if err != nil {
+		return err
+	}
+	return i.evalNumberInfixExpression(operator, leftVal, rightVal)
+}
+
+// compareResult gives the result of a relational operator from cmp, the
+// result of comparing its operands: negative, zero or positive.
+func compareResult(operator string, cmp int) Object {
+	switch operator {
+	case "<":
+		return nativeBoolToBooleanObject(cmp < 0)
+	case ">":
+		return nativeBoolToBooleanObject(cmp > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(cmp <= 0)
 	default:
-		return newError("unknown operator: %s %s %s",
-			left.Type(), operator, right.Type())
+		return nativeBoolToBooleanObject(cmp >= 0)
 	}
 }
 
-// evalIntegerInfixExpression evaluates arithmetic expressions between integers.
-func (i *Interpreter) evalIntegerInfixExpression(operator string, left, right Object) Object {
-	leftVal := left.(*Integer).Value
-	rightVal := right.(*Integer).Value
+// evalNumberInfixExpression evaluates arithmetic and comparisons between
+// numbers. Division by zero isn't an error: it gives Infinity, or NaN for
+// 0 / 0, as floating-point arithmetic does. The bitwise operators work on
+// the numbers as 32-bit integers, so 2 ** 32 | 0 is 0, and shift by the
+// bottom 5 bits of the right operand, so 1 << 33 is 2.
+func (i *Interpreter) evalNumberInfixExpression(operator string, leftVal, rightVal float64) Object {
 	switch operator {
+	case "**":
+		return &Number{Value: mathPow(leftVal, rightVal)}
+	case "&":
+		return &Number{Value: float64(toInt32(leftVal) & toInt32(rightVal))}
+	case "|":
+		return &Number{Value: float64(toInt32(leftVal) | toInt32(rightVal))}
+	case "^":
+		return &Number{Value: float64(toInt32(leftVal) ^ toInt32(rightVal))}
+	case "<<":
+		return &Number{Value: float64(toInt32(leftVal) << (toUint32(rightVal) & 31))}
+	case ">>":
+		return &Number{Value: float64(toInt32(leftVal) >> (toUint32(rightVal) & 31))}
+	case ">>>":
+		return &Number{Value: float64(toUint32(leftVal) >> (toUint32(rightVal) & 31))}
 	case "+":
-		return &Integer{Value: leftVal + rightVal}
+		return &Number{Value: leftVal + rightVal}
 	case "-":
-		return &Integer{Value: leftVal - rightVal}
+		return &Number{Value: leftVal - rightVal}
 	case "*":
-		return &Integer{Value: leftVal * rightVal}
+		return &Number{Value: leftVal * rightVal}
 	case "/":
-		return &Integer{Value: leftVal / rightVal}
+		return &Number{Value: leftVal / rightVal}
+	case "%":
+		return &Number{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
-	case "==":
-		return nativeBoolToBooleanObject(leftVal == rightVal)
-	case "!=":
-		return nativeBoolToBooleanObject(leftVal != rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	default:
 		return newError("unknown operator: %s %s %s",
-			left.Type(), operator, right.Type())
+			NUMBER_OBJ, operator, NUMBER_OBJ)
 	}
 }
 
-// evalStringInfixExpression evaluates string concatenation.
+// evalStringInfixExpression evaluates string concatenation. Either operand
+// may be a non-string primitive, which is converted to a string.
 func (i *Interpreter) evalStringInfixExpression(left, right Object) Object {
-	if left.Type() != STRING_OBJ || right.Type() != STRING_OBJ {
-		return newError("type mismatch: %s + %s", left.Type(), right.Type())
+	leftVal, err := i.toString(left)
+	if err != nil {
+		return err
+	}
+	rightVal, err := i.toString(right)
+	if err != nil {
+		return err
+	}
+	if err := i.allocateString(len(leftVal.Value) + len(rightVal.Value)); err != nil {
+		return err
+	}
+	return &String{Value: concatStrings(leftVal.Value, rightVal.Value)}
+}
+
+// evalAssignmentExpression evaluates "target = value" and compound forms
+// like "target += value". The target is either a variable or a property.
+// A compound assignment reads the target's current value before it
+// evaluates the right-hand side, as JavaScript does, so in "x += f()" a
+// change f makes to x is overwritten.
+func (i *Interpreter) evalAssignmentExpression(node *ast.AssignmentExpression, env *Environment) Object {
+	operator := strings.TrimSuffix(node.Operator, "=")
+
+	switch target := node.Left.(type) {
+	case *ast.Identifier:
+		var current Object
+		if operator != "" {
+			current = i.evalIdentifier(target, env)
+			if isError(current) {
+				return current
+			}
+		}
+		val := i.eval(node.Right, env)
+		if isError(val) {
+			return val
+		}
+		if operator != "" {
+			val = i.evalInfixExpression(operator, current, val)
+			if isError(val) {
+				return val
+			}
+		}
+		if fn, ok := val.(*Function); ok && fn.Name == "" {
+			fn.Name = target.Value
+		}
+		return i.assignVariable(target.Value, val, env)
+	case *ast.MemberExpression:
+		object := i.eval(target.Object, env)
+		if isError(object) {
+			return object
+		}
+		key := i.evalPropertyKey(target, env)
+		if isError(key) {
+			return key
+		}
+		var current Object
+		if operator != "" {
+			current = i.getProperty(object, key)
+			if isError(current) {
+				return current
+			}
+		}
+		val := i.eval(node.Right, env)
+		if isError(val) {
+			return val
+		}
+		if operator != "" {
+			val = i.evalInfixExpression(operator, current, val)
+			if isError(val) {
+				return val
+			}
+		}
+		if err := i.setProperty(object, key, val); err != nil {
+			return err
+		}
+		return val
+	case *ast.ArrayPattern, *ast.ObjectPattern:
+		// A destructuring assignment, [a, b] = [b, a], evaluates to its
+		// right-hand side.
+		val := i.eval(node.Right, env)
+		if isError(val) {
+			return val
+		}
+		if result := i.bindPattern(target, val, "", env); isError(result) {
+			return result
+		}
+		return val
+	}
+	return newError("invalid assignment target: %s", node.Left.String())
+}
+
+// assignVariable stores val in the nearest scope that declares name.
+// Assigning to a name that was never declared creates a global variable,
+// as JavaScript does outside of strict mode.
+func (i *Interpreter) assignVariable(name string, val Object, env *Environment) Object {
+	ok, isConst := env.Assign(name, val)
+	if isConst {
+		return newTypeError("Assignment to constant variable.")
+	}
+	if !ok {
+		i.env.Set(name, val)
 	}
-	leftVal := left.(*String).Value
-	rightVal := right.(*String).Value
-	return &String{Value: leftVal + rightVal}
+	return val
 }
 
-// evalIfExpression evaluates if expressions and their else clauses.
-func (i *Interpreter) evalIfExpression(ie *ast.IfExpression) Object {
-	condition := i.Eval(ie.Condition)
+// evalUpdateExpression evaluates ++ and --. The prefix form yields the new
+// value and the postfix form yields the value from before the update.
+func (i *Interpreter) evalUpdateExpression(node *ast.UpdateExpression, env *Environment) Object {
+	delta := 1.0
+	if node.Operator == "--" {
+		delta = -1
+	}
+
+	update := func(current Object) (Object, Object) {
+		n, err := i.toNumeric(current)
+		if err != nil {
+			return nil, err
+		}
+		if b, ok := n.(*BigInt); ok {
+			return b, &BigInt{Value: new(big.Int).Add(b.Value, big.NewInt(int64(delta)))}
+		}
+		return n, &Number{Value: n.(*Number).Value + delta}
+	}
+
+	var old, updated Object
+	switch target := node.Argument.(type) {
+	case *ast.Identifier:
+		current := i.evalIdentifier(target, env)
+		if isError(current) {
+			return current
+		}
+		old, updated = update(current)
+		if isError(updated) {
+			return updated
+		}
+		if result := i.assignVariable(target.Value, updated, env); isError(result) {
+			return result
+		}
+	case *ast.MemberExpression:
+		object := i.eval(target.Object, env)
+		if isError(object) {
+			return object
+		}
+		key := i.evalPropertyKey(target, env)
+		if isError(key) {
+			return key
+		}
+		current := i.getProperty(object, key)
+		if isError(current) {
+			return current
+		}
+		old, updated = update(current)
+		if isError(updated) {
+			return updated
+		}
+		if err := i.setProperty(object, key, updated); err != nil {
+			return err
+		}
+	default:
+		return newError("invalid update target: %s", node.Argument.String())
+	}
+
+	if node.Prefix {
+		return updated
+	}
+	return old
+}
+
+// evalIfStatement evaluates if statements and their else clauses.
+func (i *Interpreter) evalIfStatement(ie *ast.IfStatement, env *Environment) Object {
+	condition := i.eval(ie.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return i.Eval(ie.Consequence)
+		return i.eval(ie.Consequence, env)
 	} else if ie.Alternative != nil {
-		return i.Eval(ie.Alternative)
+		return i.eval(ie.Alternative, env)
 	} else {
-		return NULL
+		return UNDEFINED
+	}
+}
+
+// evalWhileStatement runs the loop body for as long as the condition holds.
+func (i *Interpreter) evalWhileStatement(node *ast.WhileStatement, env *Environment) Object {
+	for {
+		condition := i.eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+		result := i.eval(node.Body, env)
+		if stop, value := loopControl(result); stop {
+			return value
+		}
 	}
 }
 
+// evalForStatement runs a three-part for loop. When the loop variables are
+// declared with let, every iteration gets a fresh copy of them, so closures
+// created in the body each remember their own iteration's values.
+func (i *Interpreter) evalForStatement(node *ast.ForStatement, env *Environment) Object {
+	loopEnv := NewEnvironment(env)
+	if node.Init != nil {
+		if result := i.eval(node.Init, loopEnv); isError(result) {
+			return result
+		}
+	}
+	perIteration := false
+	if decl, ok := node.Init.(*ast.VariableDeclaration); ok && decl.Token.Literal != "var" {
+		perIteration = true
+	}
+
+	for {
+		if node.Condition != nil {
+			condition := i.eval(node.Condition, loopEnv)
+			if isError(condition) {
+				return condition
+			}
+			if !isTruthy(condition) {
+				return nil
+			}
+		}
+		result := i.eval(node.Body, loopEnv)
+		if stop, value := loopControl(result); stop {
+			return value
+		}
+		if perIteration {
+			loopEnv = loopEnv.Copy()
+		}
+		if node.Update != nil {
+			if result := i.eval(node.Update, loopEnv); isError(result) {
+				return result
+			}
+		}
+	}
+}
+
+// evalForOfStatement runs the body once for every value of an iterable.
+// Like a for loop with let, each iteration declares a fresh loop variable.
+// Leaving the loop early, with break, return or an error, closes the
+// iterator, which lets a generator know it won't be resumed.
+func (i *Interpreter) evalForOfStatement(node *ast.ForOfStatement, env *Environment) Object {
+	iterable := i.eval(node.Right, env)
+	if isError(iterable) {
+		return iterable
+	}
+	if node.Await {
+		return i.evalForAwaitOfStatement(node, iterable, env)
+	}
+	it, err := i.getIterator(iterable)
+	if err != nil {
+		return err
+	}
+	for {
+		value, done, err := i.iteratorStep(it)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		iterEnv := NewEnvironment(env)
+		if err := i.bindForOfValue(node.Left, value, iterEnv); err != nil {
+			return i.closeIterator(it, err)
+		}
+		result := i.eval(node.Body, iterEnv)
+		if stop, value := loopControl(result); stop {
+			return i.closeIterator(it, value)
+		}
+	}
+}
+
+// bindForOfValue stores one value of a for...of loop in the loop variable,
+// which is either declared in the loop's head or an existing target.
+func (i *Interpreter) bindForOfValue(left ast.Node, value Object, env *Environment) Object {
+	if decl, ok := left.(*ast.VariableDeclaration); ok {
+		return i.bindPattern(decl.Name, value, decl.Token.Literal, env)
+	}
+	return i.bindPattern(left.(ast.Expression), value, "", env)
+}
+
+// loopControl decides what a loop does with the result of its body: keep
+// going, or stop and hand a value (a return signal or an error) back up.
+func loopControl(result Object) (bool, Object) {
+	if result == nil {
+		return false, nil
+	}
+	switch result.Type() {
+	case BREAK_OBJ:
+		return true, nil
+	case RETURN_VALUE_OBJ, ERROR_OBJ:
+		return true, result
+	}
+	return false, nil
+}
+
 // evalIdentifier evaluates identifiers (variable names).
-func (i *Interpreter) evalIdentifier(node *ast.Identifier) Object {
-	if val, ok := i.env.Get(node.Value); ok {
+func (i *Interpreter) evalIdentifier(node *ast.Identifier, env *Environment) Object {
+	if val, ok := env.Get(node.Value); ok {
 		return val
 	}
-	if builtin, ok := builtins[node.Value]; ok {
-		return builtin
+	if env.uninitialized(node.Value) {
+		// An import of a variable the other module hasn't declared yet.
+		return newReferenceError("Cannot access '%s' before initialization", node.Value)
 	}
-	return newError("identifier not found: " + node.Value)
+	return newReferenceError("%s is not defined", node.Value)
 }
 
 // evalExpressions evaluates a list of expressions (used for function arguments).
-func (i *Interpreter) evalExpressions(exps []ast.Expression) []Object {
-	var result []Object
+// Spread elements are expanded in place.
+func (i *Interpreter) evalExpressions(exps []ast.Expression, env *Environment) []Object {
+	result := []Object{}
 	for _, e := range exps {
-		evaluated := i.Eval(e)
+		if spread, ok := e.(*ast.SpreadElement); ok {
+			values := i.evalSpread(spread, env)
+			if len(values) == 1 && isError(values[0]) {
+				return values
+			}
+			result = append(result, values...)
+			continue
+		}
+		evaluated := i.eval(e, env)
 		if isError(evaluated) {
 			return []Object{evaluated}
 		}
@@ -450,96 +1282,326 @@ func (i *Interpreter) evalExpressions(exps []ast.Expression) []Object {
 	return result
 }
 
+// evalSpread evaluates the argument of ...expr and returns the values it
+// expands to: every value produced by iterating over it.
+func (i *Interpreter) evalSpread(spread *ast.SpreadElement, env *Environment) []Object {
+	value := i.eval(spread.Argument, env)
+	if isError(value) {
+		return []Object{value}
+	}
+	values, err := i.iterableToList(value)
+	if err != nil {
+		return []Object{err}
+	}
+	return values
+}
+
+func (i *Interpreter) evalFunctionLiteral(node *ast.FunctionLiteral, env *Environment) Object {
+	name := ""
+	if node.Name != nil {
+		name = node.Name.Value
+		// A named function expression can refer to itself by name.
+		env = NewEnvironment(env)
+	}
+	fn := i.newFunctionOfKind(name, node.Parameters, node.Body, env, node.Arrow, node.Generator, node.Async)
+	if node.Name != nil {
+		env.Set(name, fn)
+	}
+	return fn
+}
+
+// newFunctionOfKind creates the function object for a function declaration
+// or literal, which may be an arrow function, a generator, async, or an
+// async generator.
+func (i *Interpreter) newFunctionOfKind(name string, params []ast.Expression, body *ast.BlockStatement, env *Environment, arrow, generator, async bool) *Function {
+	switch {
+	case generator:
+		return i.newGeneratorFunction(name, params, body, env, async)
+	case async:
+		return i.newAsyncFunction(name, params, body, env, arrow)
+	}
+	return i.newFunction(name, params, body, env, arrow)
+}
+
+// newFunction creates a function object. Ordinary functions get a
+// "prototype" object for the instances new will create; arrow functions
+// can't be used with new, so they don't.
+func (i *Interpreter) newFunction(name string, params []ast.Expression, body *ast.BlockStatement, env *Environment, arrow bool) *Function {
+	fn := &Function{
+		Hash:       Hash{Prototype: i.functionPrototype},
+		Name:       name,
+		Parameters: params,
+		Body:       body,
+		Env:        env,
+		Arrow:      arrow,
+	}
+	if !arrow {
+		proto := NewHash(i.objectPrototype)
+		proto.Set(&String{Value: "constructor"}, fn)
+		fn.Set(&String{Value: "prototype"}, proto)
+	}
+	return fn
+}
+
+// evalCallExpression evaluates a function call. When the callee is a
+// property access like obj.method(), obj becomes "this" inside the call.
+func (i *Interpreter) evalCallExpression(node *ast.CallExpression, env *Environment) Object {
+	var function, this Object
+	if member, ok := node.Function.(*ast.MemberExpression); ok {
+		this = i.eval(member.Object, env)
+		if isError(this) {
+			return this
+		}
+		key := i.evalPropertyKey(member, env)
+		if isError(key) {
+			return key
+		}
+		function = i.getProperty(this, key)
+	} else {
+		function = i.eval(node.Function, env)
+	}
+	if isError(function) {
+		return function
+	}
+	if !isCallable(function) {
+		return newTypeError("%s is not a function", node.Function.String())
+	}
+
+	args := i.evalExpressions(node.Arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	return i.applyFunction(function, this, args)
+}
+
 // applyFunction applies a function to its arguments.
 // This handles both user-defined functions and built-in functions.
-func (i *Interpreter) applyFunction(fn Object, args []Object) Object {
+func (i *Interpreter) applyFunction(fn Object, this Object, args []Object) Object {
+	if err := i.enterCall(); err != nil {
+		return err
+	}
+	result := i.callFunction(fn, this, args)
+	i.exitCall()
+	return result
+}
+
+// callFunction does the work of applyFunction.
+func (i *Interpreter) callFunction(fn Object, this Object, args []Object) Object {
 	switch fn := fn.(type) {
 	case *Function:
-		extendedEnv := i.extendFunctionEnv(fn, args)
-		evaluated := i.Eval(fn.Body)
+		if fn.compiled != nil {
+			return i.callCompiled(fn, this, args)
+		}
+		if fn.Generator {
+			return i.newGenerator(fn, this, args)
+		}
+		if fn.Async {
+			return i.callAsyncFunction(fn, this, args)
+		}
+		extendedEnv, err := i.extendFunctionEnv(fn, this, args)
+		if err != nil {
+			return err
+		}
+		evaluated := i.evalBlockStatement(fn.Body, extendedEnv)
 		return i.unwrapReturnValue(evaluated)
 	case *Builtin:
-		return fn.Fn(args...)
+		if fn.requiresNew {
+			return newTypeError("Constructor %s requires 'new'", fn.Name)
+		}
+		if fn.call != nil {
+			return fn.call(this, args...)
+		}
+		return fn.Fn(this, args...)
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newTypeError("%s is not a function", fn.Inspect())
 	}
 }
 
 // extendFunctionEnv creates a new environment for a function call.
 // This implements proper scoping for function parameters and local variables.
-func (i *Interpreter) extendFunctionEnv(fn *Function, args []Object) *Environment {
-	env := NewEnvironment(fn.Env)
+// Parameters without a matching argument start out as undefined, or as
+// their default value, which is evaluated in the new environment so that it
+// can refer to the parameters before it. A rest parameter, ...args,
+// collects the remaining arguments into an array.
+func (i *Interpreter) extendFunctionEnv(fn *Function, this Object, args []Object) (*Environment, *Error) {
+	env := NewFunctionEnvironment(fn.Env)
+	if !fn.Arrow {
+		if this == nil {
+			this = UNDEFINED
+		}
+		env.Set("this", this)
+	}
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		var value Object = UNDEFINED
+		if rest, ok := param.(*ast.RestElement); ok {
+			values := []Object{}
+			if paramIdx < len(args) {
+				values = append(values, args[paramIdx:]...)
+			}
+			value, param = i.newArray(values), rest.Argument
+		} else if paramIdx < len(args) {
+			value = args[paramIdx]
+		}
+		if result := i.bindPattern(param, value, "let", env); isError(result) {
+			return nil, result.(*Error)
+		}
 	}
-	return env
+	return env, nil
 }
 
-// unwrapReturnValue handles return values from functions.
+// unwrapReturnValue handles return values from functions. A function that
+// finishes without a return statement returns undefined.
 func (i *Interpreter) unwrapReturnValue(obj Object) Object {
-	if returnValue, ok := obj.(*ReturnValue); ok {
-		return returnValue.Value
+	switch obj := obj.(type) {
+	case *ReturnValue:
+		return obj.Value
+	case *Error:
+		return obj
 	}
-	return obj
+	return UNDEFINED
 }
 
-// evalIndexExpression evaluates array and object indexing expressions.
-func (i *Interpreter) evalIndexExpression(left, index Object) Object {
-	switch {
-	case left.Type() == ARRAY_OBJ:
-		return i.evalArrayIndexExpression(left, index)
-	case left.Type() == HASH_OBJ:
-		return i.evalHashIndexExpression(left, index)
-	default:
-		return newError("index operator not supported: %s", left.Type())
+// construct implements the new operator. A new object is created whose
+// prototype is the constructor's "prototype" property, and the constructor
+// is called with the new object as "this". If the constructor returns an
+// object of its own, that object is the result instead.
+func (i *Interpreter) construct(fn Object, args []Object) Object {
+	switch fn := fn.(type) {
+	case *Function:
+		if !isConstructor(fn) {
+			return newTypeError("%s is not a constructor", fn.Name)
+		}
+		proto := i.objectPrototype
+		if p, ok := fn.Get(&String{Value: "prototype"}); ok {
+			if p, ok := p.(*Hash); ok {
+				proto = p
+			}
+		}
+		obj := NewHash(proto)
+		result := i.applyFunction(fn, obj, args)
+		if isError(result) {
+			return result
+		}
+		if _, ok := result.(propertyHolder); ok {
+			return result
+		}
+		return obj
+	case *Builtin:
+		if fn.notConstructor {
+			return newTypeError("%s is not a constructor", fn.Name)
+		}
+		return fn.Fn(nil, args...)
 	}
+	return newTypeError("%s is not a constructor", fn.Inspect())
 }
 
-// evalArrayIndexExpression evaluates array indexing expressions.
-func (i *Interpreter) evalArrayIndexExpression(array, index Object) Object {
-	arrayObject := array.(*Array)
-	idx := index.(*Integer).Value
-	max := int64(len(arrayObject.Elements) - 1)
-	if idx < 0 || idx > max {
-		return NULL
+// evalArrayLiteral evaluates array literals. Holes stay holes, and spread
+// elements are expanded into the new array.
+func (i *Interpreter) evalArrayLiteral(node *ast.ArrayLiteral, env *Environment) Object {
+	elements := make([]Object, 0, len(node.Elements))
+	for _, e := range node.Elements {
+		switch e := e.(type) {
+		case nil:
+			elements = append(elements, nil)
+		case *ast.SpreadElement:
+			values := i.evalSpread(e, env)
+			if len(values) == 1 && isError(values[0]) {
+				return values[0]
+			}
+			elements = append(elements, values...)
+		default:
+			value := i.eval(e, env)
+			if isError(value) {
+				return value
+			}
+			elements = append(elements, value)
+		}
 	}
-	return arrayObject.Elements[idx]
+	return i.newArray(elements)
 }
 
-// evalHashIndexExpression evaluates object property access expressions.
-func (i *Interpreter) evalHashIndexExpression(hash, index Object) Object {
-	hashObject := hash.(*Hash)
-	key, ok := index.(Hashable)
-	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
-	}
-	pair, ok := hashObject.Pairs[key.HashKey()]
-	if !ok {
-		return NULL
+// evalPropertyKey evaluates the key of a property access: the name after the
+// dot, or the value inside the brackets.
+func (i *Interpreter) evalPropertyKey(node *ast.MemberExpression, env *Environment) Object {
+	if !node.Computed {
+		return &String{Value: node.Property.(*ast.Identifier).Value}
 	}
-	return pair.Value
+	return i.eval(node.Property, env)
 }
 
-// evalHashLiteral evaluates object literals.
-func (i *Interpreter) evalHashLiteral(node *ast.HashLiteral) Object {
-	pairs := make(map[HashKey]HashPair)
-	for keyNode, valueNode := range node.Pairs {
-		key := i.Eval(keyNode)
+// evalObjectLiteral evaluates object literals.
+func (i *Interpreter) evalObjectLiteral(node *ast.ObjectLiteral, env *Environment) Object {
+	hash := NewHash(i.objectPrototype)
+	for _, prop := range node.Properties {
+		if spread, ok := prop.Value.(*ast.SpreadElement); ok && prop.Key == nil {
+			if err := i.spreadProperties(hash, spread, env); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, ok := prop.Value.(*ast.AssignmentPattern); ok {
+			// { a = 1 } is only allowed as a destructuring pattern.
+			return newSyntaxError("Invalid shorthand property initializer")
+		}
+		var key Object
+		switch k := prop.Key.(type) {
+		case *ast.Identifier:
+			if prop.Computed {
+				key = i.eval(k, env)
+			} else {
+				key = &String{Value: k.Value}
+			}
+		default:
+			key = i.eval(k, env)
+		}
 		if isError(key) {
 			return key
 		}
-		hashKey, ok := key.(Hashable)
-		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
-		}
-		value := i.Eval(valueNode)
+		value := i.eval(prop.Value, env)
 		if isError(value) {
 			return value
 		}
-		hashed := hashKey.HashKey()
-		pairs[hashed] = HashPair{Key: key, Value: value}
+		name, err := i.toPropertyKey(key)
+		if err != nil {
+			return err
+		}
+		if fn, ok := value.(*Function); ok && fn.Name == "" {
+			fn.Name = functionNameForKey(name)
+		}
+		hash.Set(name, value)
 	}
-	return &Hash{Pairs: pairs}
+	return hash
+}
+
+// spreadProperties copies the own properties of the value of ...expr in an
+// object literal into hash. Spreading null or undefined copies nothing.
+func (i *Interpreter) spreadProperties(hash *Hash, spread *ast.SpreadElement, env *Environment) Object {
+	value := i.eval(spread.Argument, env)
+	if isError(value) {
+		return value
+	}
+	for _, key := range i.ownPropertyKeys(value) {
+		v := i.getProperty(value, key)
+		if isError(v) {
+			return v
+		}
+		hash.Set(key, v)
+	}
+	return nil
+}
+
+// functionNameForKey gives the name an anonymous function takes from the
+// property key it is stored under, as in { f: function () {} }. Functions
+// stored under symbols are named after the symbol's description in
+// brackets.
+func functionNameForKey(key Object) string {
+	if s, ok := key.(*Symbol); ok {
+		if !s.HasDescription {
+			return ""
+		}
+		return "[" + s.Description + "]"
+	}
+	return key.(*String).Value
 }
 
 // Helper functions for type conversion and error checking
@@ -550,19 +1612,54 @@ func nativeBoolToBooleanObject(input bool) *Boolean {
 	return FALSE
 }
 
+// isTruthy implements ToBoolean: undefined, null, false, 0, NaN and the
+// empty string are falsy, and every other value, including every object, is truthy.
 func isTruthy(obj Object) bool {
-	switch obj {
-	case NULL:
-		return false
-	case TRUE:
-		return true
-	case FALSE:
+	switch obj := obj.(type) {
+	case *Null, *Undefined:
 		return false
+	case *Boolean:
+		return obj.Value
+	case *Number:
+		return obj.Value != 0 && !math.IsNaN(obj.Value)
+	case *BigInt:
+		return obj.Value.Sign() != 0
+	case *String:
+		return obj.Value != ""
 	default:
 		return true
 	}
 }
 
+// strictEquals implements ===: values of different types are never equal,
+// primitives compare by value and objects by identity. Because numbers
+// follow floating-point rules, NaN is not equal to itself and 0 equals -0.
+func strictEquals(left, right Object) bool {
+	switch l := left.(type) {
+	case *Number:
+		r, ok := right.(*Number)
+		return ok && l.Value == r.Value
+	case *String:
+		r, ok := right.(*String)
+		return ok && l.Value == r.Value
+	case *Boolean:
+		r, ok := right.(*Boolean)
+		return ok && l.Value == r.Value
+	case *BigInt:
+		r, ok := right.(*BigInt)
+		return ok && l.Value.Cmp(r.Value) == 0
+	}
+	return left == right
+}
+
+func isCallable(obj Object) bool {
+	switch obj.(type) {
+	case *Function, *Builtin:
+		return true
+	}
+	return false
+}
+
 func isError(obj Object) bool {
 	if obj != nil {
 		return obj.Type() == ERROR_OBJ
@@ -574,108 +1671,37 @@ func newError(format string, a ...interface{}) *Error {
 	return &Error{Message: fmt.Sprintf(format, a...)}
 }
 
-// Built-in functions
+// newTypeError, newRangeError, newReferenceError and newSyntaxError create
+// errors named after the JavaScript error types they correspond to.
+func newTypeError(format string, a ...interface{}) *Error {
+	return newError("TypeError: "+format, a...)
+}
+
+func newRangeError(format string, a ...interface{}) *Error {
+	return newError("RangeError: "+format, a...)
+}
+
+func newReferenceError(format string, a ...interface{}) *Error {
+	return newError("ReferenceError: "+format, a...)
+}
+
+// thrownError wraps a value thrown by a script, such as the argument of
+// generator.throw, so that it travels like any other error.
+func thrownError(value Object) *Error {
+	message := inspect(value, defaultInspectDepth)
+	if s, ok := value.(*String); ok {
+		message = s.Value
+	}
+	return &Error{Message: message, Value: value}
+}
+
+func newSyntaxError(format string, a ...interface{}) *Error {
+	return newError("SyntaxError: "+format, a...)
+}
+
 var TRUE = &Boolean{Value: true}
 var FALSE = &Boolean{Value: false}
 var NULL = &Null{}
-
-var builtins = map[string]*Builtin{
-	"len": &Builtin{
-		Fn: func(args ...Object) Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
-			}
-			switch arg := args[0].(type) {
-			case *Array:
-				return &Integer{Value: int64(len(arg.Elements))}
-			case *String:
-				return &Integer{Value: int64(len(arg.Value))}
-			default:
-				return newError("argument to `len` not supported, got %s",
-					args[0].Type())
-			}
-		},
-	},
-	"first": &Builtin{
-		Fn: func(args ...Object) Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
-			}
-			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s",
-					args[0].Type())
-			}
-			arr := args[0].(*Array)
-			if len(arr.Elements) > 0 {
-				return arr.Elements[0]
-			}
-			return NULL
-		},
-	},
-	"last": &Builtin{
-		Fn: func(args ...Object) Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
-			}
-			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to `last` must be ARRAY, got %s",
-					args[0].Type())
-			}
-			arr := args[0].(*Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				return arr.Elements[length-1]
-			}
-			return NULL
-		},
-	},
-	"rest": &Builtin{
-		Fn: func(args ...Object) Object {
-			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
-					len(args))
-			}
-			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s",
-					args[0].Type())
-			}
-			arr := args[0].(*Array)
-			length := len(arr.Elements)
-			if length > 0 {
-				newElements := make([]Object, length-1, length-1)
-				copy(newElements, arr.Elements[1:length])
-				return &Array{Elements: newElements}
-			}
-			return NULL
-		},
-	},
-	"push": &Builtin{
-		Fn: func(args ...Object) Object {
-			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2",
-					len(args))
-			}
-			if args[0].Type() != ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s",
-					args[0].Type())
-			}
-			arr := args[0].(*Array)
-			length := len(arr.Elements)
-			newElements := make([]Object, length+1, length+1)
-			copy(newElements, arr.Elements)
-			newElements[length] = args[1]
-			return &Array{Elements: newElements}
-		},
-	},
-	"puts": &Builtin{
-		Fn: func(args ...Object) Object {
-			for _, arg := range args {
-				fmt.Println(arg.Inspect())
-			}
-			return NULL
-		},
-	},
-} 
\ No newline at end of file
+var UNDEFINED = &Undefined{}
+var BREAK = &Break{}
+var CONTINUE = &Continue{}
@@ -0,0 +1,168 @@
+package interpreter
+
+// This file implements the iterator protocol, the convention through which
+// for...of, spread syntax and Array.from read the values of any iterable:
+//
+//	const it = iterable[Symbol.iterator]();
+//	for (let r = it.next(); !r.done; r = it.next()) use(r.value);
+//
+// Arrays, strings and generators are iterable because their prototypes
+// have a [Symbol.iterator] method, and any object can become iterable by
+// defining one. When a loop stops before the iterator is done, it calls the
+// iterator's optional return method so that the iterator can clean up.
+
+// iterator is an iterator being driven from Go. The next method is looked up
+// once, when iteration starts, as the language specifies.
+type iterator struct {
+	object Object
+	next   Object
+}
+
+// setupIterator defines %IteratorPrototype%, the prototype shared by every
+// built-in iterator. Its [Symbol.iterator] method returns the iterator
+// itself, so iterators can be used wherever an iterable is expected.
+func (i *Interpreter) setupIterator() {
+	i.iteratorPrototype.Set(symbolIterator, i.newBuiltin("[Symbol.iterator]", func(this Object, args ...Object) Object {
+		return this
+	}))
+}
+
+// getIterator calls value's [Symbol.iterator] method and returns the
+// iterator it creates.
+func (i *Interpreter) getIterator(value Object) (*iterator, *Error) {
+	if isNullish(value) {
+		return nil, newTypeError("%s is not iterable", inspect(value, 0))
+	}
+	method := i.getProperty(value, symbolIterator)
+	if err, ok := method.(*Error); ok {
+		return nil, err
+	}
+	if !isCallable(method) {
+		return nil, newTypeError("%s is not iterable", inspect(value, 0))
+	}
+	object := i.applyFunction(method, value, nil)
+	if err, ok := object.(*Error); ok {
+		return nil, err
+	}
+	if !isObject(object) {
+		return nil, newTypeError("Result of the Symbol.iterator method is not an object")
+	}
+	next := i.getProperty(object, &String{Value: "next"})
+	if err, ok := next.(*Error); ok {
+		return nil, err
+	}
+	return &iterator{object: object, next: next}, nil
+}
+
+// iteratorNext calls the iterator's next method and returns the
+// { value, done } object it produces.
+func (i *Interpreter) iteratorNext(it *iterator, args ...Object) (Object, *Error) {
+	if !isCallable(it.next) {
+		return nil, newTypeError("%s is not a function", inspect(it.next, 0))
+	}
+	return i.checkIteratorResult(i.applyFunction(it.next, it.object, args))
+}
+
+// checkIteratorResult checks that what an iterator method returned can be
+// a { value, done } object.
+func (i *Interpreter) checkIteratorResult(result Object) (Object, *Error) {
+	if err, ok := result.(*Error); ok {
+		return nil, err
+	}
+	if !isObject(result) {
+		return nil, newTypeError("Iterator result %s is not an object", inspect(result, 0))
+	}
+	return result, nil
+}
+
+// iteratorComplete reads the done flag of an iterator result.
+func (i *Interpreter) iteratorComplete(result Object) (bool, *Error) {
+	done := i.getProperty(result, &String{Value: "done"})
+	if err, ok := done.(*Error); ok {
+		return false, err
+	}
+	return isTruthy(done), nil
+}
+
+// iteratorValue reads the value of an iterator result.
+func (i *Interpreter) iteratorValue(result Object) (Object, *Error) {
+	value := i.getProperty(result, &String{Value: "value"})
+	if err, ok := value.(*Error); ok {
+		return nil, err
+	}
+	return value, nil
+}
+
+// iteratorStep advances the iterator and returns the next value, or done
+// once the iterator is finished.
+func (i *Interpreter) iteratorStep(it *iterator) (value Object, done bool, err *Error) {
+	result, err := i.iteratorNext(it)
+	if err != nil {
+		return nil, false, err
+	}
+	if done, err = i.iteratorComplete(result); err != nil || done {
+		return nil, done, err
+	}
+	value, err = i.iteratorValue(result)
+	return value, false, err
+}
+
+// iteratorClose tells an iterator that no more values will be asked of it
+// by calling its return method, if it has one.
+func (i *Interpreter) iteratorClose(it *iterator) *Error {
+	method := i.getProperty(it.object, &String{Value: "return"})
+	if err, ok := method.(*Error); ok {
+		return err
+	}
+	if isNullish(method) {
+		return nil
+	}
+	if !isCallable(method) {
+		return newTypeError("%s is not a function", inspect(method, 0))
+	}
+	_, err := i.checkIteratorResult(i.applyFunction(method, it.object, nil))
+	return err
+}
+
+// closeIterator closes an iterator that a loop is leaving early and
+// returns what the loop should finish with. An error that made the loop
+// stop takes precedence over any error from closing the iterator.
+func (i *Interpreter) closeIterator(it *iterator, completion Object) Object {
+	err := i.iteratorClose(it)
+	if err != nil && !isError(completion) {
+		return err
+	}
+	return completion
+}
+
+// iterate calls fn with every value of iterable. If fn returns an error,
+// iteration stops and the iterator is closed.
+func (i *Interpreter) iterate(iterable Object, fn func(value Object) *Error) *Error {
+	it, err := i.getIterator(iterable)
+	if err != nil {
+		return err
+	}
+	for {
+		value, done, err := i.iteratorStep(it)
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+		if err := fn(value); err != nil {
+			i.iteratorClose(it)
+			return err
+		}
+	}
+}
+
+// iterableToList collects every value of iterable, as spread syntax does.
+func (i *Interpreter) iterableToList(iterable Object) ([]Object, *Error) {
+	values := []Object{}
+	err := i.iterate(iterable, func(value Object) *Error {
+		values = append(values, value)
+		return nil
+	})
+	return values, err
+}
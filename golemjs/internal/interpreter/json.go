@@ -0,0 +1,621 @@
+package interpreter
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// setupJSON defines the JSON object with its two functions: parse, which
+// turns JSON text into objects, arrays and primitives, and stringify, which
+// does the reverse.
+func (i *Interpreter) setupJSON() {
+	obj := NewHash(i.objectPrototype)
+	i.defineMethod(obj, "parse", i.jsonParse)
+	i.defineMethod(obj, "stringify", i.jsonStringify)
+	obj.Set(symbolToStringTag, &String{Value: "JSON"})
+	i.env.Set("JSON", obj)
+}
+
+// jsonParse implements JSON.parse(text, reviver). If a reviver function is
+// given, it is called for every value in the result, innermost first, with
+// the key and the value, and its return value replaces the value; returning
+// undefined removes the property.
+func (i *Interpreter) jsonParse(this Object, args ...Object) Object {
+	text, err := i.stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	p := &jsonParser{i: i, input: text.units()}
+	p.skipWhitespace()
+	value := p.parseValue()
+	if isError(value) {
+		return value
+	}
+	p.skipWhitespace()
+	if p.pos < len(p.input) {
+		return p.unexpected()
+	}
+
+	reviver := argAt(args, 1)
+	if !isCallable(reviver) {
+		return value
+	}
+	root := NewHash(i.objectPrototype)
+	root.Set(&String{Value: ""}, value)
+	return i.internalizeJSONProperty(root, &String{Value: ""}, reviver)
+}
+
+// internalizeJSONProperty applies the reviver to holder[name], after first
+// applying it to everything inside that value.
+func (i *Interpreter) internalizeJSONProperty(holder Object, name *String, reviver Object) Object {
+	value := i.getProperty(holder, name)
+	if isError(value) {
+		return value
+	}
+	if isObject(value) {
+		var keys []Object
+		if arr, ok := value.(*Array); ok {
+			for idx := range arr.len() {
+				keys = append(keys, &String{Value: strconv.Itoa(idx)})
+			}
+		} else {
+			keys = value.(propertyHolder).properties().OwnKeys()
+		}
+		for _, key := range keys {
+			k := key.(*String)
+			revived := i.internalizeJSONProperty(value, k, reviver)
+			if isError(revived) {
+				return revived
+			}
+			if revived == UNDEFINED {
+				if _, err := i.deleteProperty(value, k); err != nil {
+					return err
+				}
+			} else if err := i.setProperty(value, k, revived); err != nil {
+				return err
+			}
+		}
+	}
+	return i.applyFunction(reviver, holder, []Object{name, value})
+}
+
+// jsonParser is a recursive descent parser for JSON text. It works on
+// UTF-16 code units so that positions in error messages match JavaScript's
+// and so that escaped lone surrogates such as "\uD800" survive. Every
+// object or array it descends into counts as a call, so text nested too
+// deeply, like a million "["s, throws a RangeError rather than overflowing
+// the Go stack.
+type jsonParser struct {
+	i     *Interpreter
+	input []uint16
+	pos   int
+}
+
+func (p *jsonParser) skipWhitespace() {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// unexpected reports a syntax error at the current position.
+func (p *jsonParser) unexpected() *Error {
+	if p.pos >= len(p.input) {
+		return newSyntaxError("Unexpected end of JSON input")
+	}
+	c := p.input[p.pos]
+	if c == '"' {
+		return newSyntaxError("Unexpected string in JSON at position %d", p.pos)
+	}
+	if '0' <= c && c <= '9' || c == '-' {
+		return newSyntaxError("Unexpected number in JSON at position %d", p.pos)
+	}
+	return newSyntaxError("Unexpected token %s in JSON at position %d",
+		stringFromUnits(p.input[p.pos:p.pos+1]), p.pos)
+}
+
+// expect consumes the given character or reports an error.
+func (p *jsonParser) expect(c uint16) *Error {
+	if p.pos >= len(p.input) || p.input[p.pos] != c {
+		return p.unexpected()
+	}
+	p.pos++
+	return nil
+}
+
+func (p *jsonParser) parseValue() Object {
+	if p.pos >= len(p.input) {
+		return p.unexpected()
+	}
+	switch c := p.input[p.pos]; {
+	case c == '{', c == '[':
+		if err := p.i.enterCall(); err != nil {
+			return err
+		}
+		defer p.i.exitCall()
+		if c == '{' {
+			return p.parseObject()
+		}
+		return p.parseArray()
+	case c == '"':
+		s, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		return s
+	case c == '-' || ('0' <= c && c <= '9'):
+		return p.parseNumber()
+	}
+	for _, literal := range []struct {
+		text  string
+		value Object
+	}{{"true", TRUE}, {"false", FALSE}, {"null", NULL}} {
+		if p.consumeWord(literal.text) {
+			return literal.value
+		}
+	}
+	return p.unexpected()
+}
+
+// consumeWord consumes word if the input continues with it. On a partial
+// match the position is left at the first character that differs, so that
+// the error points there.
+func (p *jsonParser) consumeWord(word string) bool {
+	start := p.pos
+	for idx := 0; idx < len(word); idx++ {
+		if p.pos >= len(p.input) || p.input[p.pos] != uint16(word[idx]) {
+			if idx == 0 {
+				p.pos = start
+			}
+			return false
+		}
+		p.pos++
+	}
+	return true
+}
+
+func (p *jsonParser) parseObject() Object {
+	p.pos++ // {
+	obj := NewHash(p.i.objectPrototype)
+	p.skipWhitespace()
+	if p.pos < len(p.input) && p.input[p.pos] == '}' {
+		p.pos++
+		return obj
+	}
+	for {
+		p.skipWhitespace()
+		if p.pos >= len(p.input) || p.input[p.pos] != '"' {
+			return p.unexpected()
+		}
+		key, err := p.parseString()
+		if err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		if err := p.expect(':'); err != nil {
+			return err
+		}
+		p.skipWhitespace()
+		value := p.parseValue()
+		if isError(value) {
+			return value
+		}
+		obj.Set(key, value)
+		p.skipWhitespace()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect('}'); err != nil {
+			return err
+		}
+		return obj
+	}
+}
+
+func (p *jsonParser) parseArray() Object {
+	p.pos++ // [
+	var elements []Object
+	p.skipWhitespace()
+	if p.pos < len(p.input) && p.input[p.pos] == ']' {
+		p.pos++
+		return p.i.newArray(elements)
+	}
+	for {
+		p.skipWhitespace()
+		value := p.parseValue()
+		if isError(value) {
+			return value
+		}
+		elements = append(elements, value)
+		p.skipWhitespace()
+		if p.pos < len(p.input) && p.input[p.pos] == ',' {
+			p.pos++
+			continue
+		}
+		if err := p.expect(']'); err != nil {
+			return err
+		}
+		return p.i.newArray(elements)
+	}
+}
+
+// jsonEscapes maps the character after a backslash in a JSON string to the
+// character it stands for.
+var jsonEscapes = map[uint16]uint16{
+	'"': '"', '\\': '\\', '/': '/', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+}
+
+// parseString parses a double-quoted string. Control characters must be
+// escaped, and only the escapes \" \\ \/ \b \f \n \r \t and \uXXXX exist.
+func (p *jsonParser) parseString() (*String, *Error) {
+	p.pos++ // "
+	var units []uint16
+	for {
+		if p.pos >= len(p.input) {
+			return nil, newSyntaxError("Unterminated string in JSON at position %d", p.pos)
+		}
+		c := p.input[p.pos]
+		switch {
+		case c == '"':
+			p.pos++
+			return newStringFromUnits(units), nil
+		case c < 0x20:
+			return nil, newSyntaxError("Bad control character in string literal in JSON at position %d", p.pos)
+		case c != '\\':
+			units = append(units, c)
+			p.pos++
+			continue
+		}
+
+		p.pos++ // backslash
+		if p.pos >= len(p.input) {
+			return nil, newSyntaxError("Unterminated string in JSON at position %d", p.pos)
+		}
+		if unit, ok := jsonEscapes[p.input[p.pos]]; ok {
+			units = append(units, unit)
+			p.pos++
+			continue
+		}
+		if p.input[p.pos] != 'u' {
+			return nil, newSyntaxError("Bad escaped character in JSON at position %d", p.pos)
+		}
+		if p.pos+5 > len(p.input) {
+			return nil, newSyntaxError("Bad Unicode escape in JSON at position %d", p.pos-1)
+		}
+		n, err := strconv.ParseUint(stringFromUnits(p.input[p.pos+1:p.pos+5]), 16, 16)
+		if err != nil {
+			return nil, newSyntaxError("Bad Unicode escape in JSON at position %d", p.pos-1)
+		}
+		units = append(units, uint16(n))
+		p.pos += 5
+	}
+}
+
+// parseNumber parses a number, which JSON allows in a stricter form than
+// JavaScript: no leading zeros, no leading +, no leading or trailing dot,
+// and no hexadecimal.
+func (p *jsonParser) parseNumber() Object {
+	start := p.pos
+	digits := func() bool {
+		from := p.pos
+		for p.pos < len(p.input) && '0' <= p.input[p.pos] && p.input[p.pos] <= '9' {
+			p.pos++
+		}
+		return p.pos > from
+	}
+
+	if p.input[p.pos] == '-' {
+		p.pos++
+	}
+	if p.pos < len(p.input) && p.input[p.pos] == '0' {
+		p.pos++
+	} else if !digits() {
+		return p.unexpected()
+	}
+	if p.pos < len(p.input) && p.input[p.pos] == '.' {
+		p.pos++
+		if !digits() {
+			return p.unexpected()
+		}
+	}
+	if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		p.pos++
+		if p.pos < len(p.input) && (p.input[p.pos] == '+' || p.input[p.pos] == '-') {
+			p.pos++
+		}
+		if !digits() {
+			return p.unexpected()
+		}
+	}
+	return &Number{Value: stringToNumber(stringFromUnits(p.input[start:p.pos]))}
+}
+
+// jsonStringify implements JSON.stringify(value, replacer, space).
+//
+// replacer may be a function, which is called with each key and value and
+// returns the value to use instead, or an array naming the only properties
+// to include. space indents the output: a number gives that many spaces
+// (up to 10) and a string is used as it is (up to 10 characters).
+//
+// Objects with a toJSON method are replaced by what it returns, which is
+// how Date objects turn into strings. Functions and undefined are left out
+// of objects and become null in arrays. Circular structures are a
+// TypeError.
+func (i *Interpreter) jsonStringify(this Object, args ...Object) Object {
+	s := &jsonStringifier{i: i}
+
+	replacer := argAt(args, 1)
+	if isCallable(replacer) {
+		s.replacer = replacer
+	} else if arr, ok := replacer.(*Array); ok {
+		s.propertyList = []*String{}
+		seen := map[string]bool{}
+		for idx := range arr.len() {
+			var key *String
+			switch item := arr.element(idx).(type) {
+			case *String:
+				key = item
+			case *Number:
+				key = &String{Value: numberToString(item.Value)}
+			default:
+				continue
+			}
+			if !seen[key.Value] {
+				seen[key.Value] = true
+				s.propertyList = append(s.propertyList, key)
+			}
+		}
+	}
+
+	switch space := argAt(args, 2).(type) {
+	case *Number:
+		n := min(10, int(math.Max(0, math.Trunc(space.Value))))
+		s.gap = strings.Repeat(" ", n)
+	case *String:
+		units := space.units()
+		s.gap = stringFromUnits(units[:min(10, len(units))])
+	}
+
+	wrapper := NewHash(i.objectPrototype)
+	wrapper.Set(&String{Value: ""}, argAt(args, 0))
+	out, ok, err := s.serializeProperty(wrapper, &String{Value: ""})
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return UNDEFINED
+	}
+	return &String{Value: out}
+}
+
+// jsonStringifier holds the state of one JSON.stringify call.
+type jsonStringifier struct {
+	i            *Interpreter
+	replacer     Object
+	propertyList []*String
+	gap          string
+	indent       string
+
+	// stack holds the objects and arrays being serialized, to catch
+	// structures that contain themselves.
+	stack map[Object]bool
+}
+
+// serializeProperty serializes holder[key]. It reports false if the value
+// has no JSON representation, as with undefined and functions.
+func (s *jsonStringifier) serializeProperty(holder Object, key *String) (string, bool, *Error) {
+	i := s.i
+	value := i.getProperty(holder, key)
+	if err, ok := value.(*Error); ok {
+		return "", false, err
+	}
+	if isObject(value) || value.Type() == BIGINT_OBJ {
+		toJSON := i.getProperty(value, &String{Value: "toJSON"})
+		if err, ok := toJSON.(*Error); ok {
+			return "", false, err
+		}
+		if isCallable(toJSON) {
+			value = i.applyFunction(toJSON, value, []Object{key})
+			if err, ok := value.(*Error); ok {
+				return "", false, err
+			}
+		}
+	}
+	if s.replacer != nil {
+		value = i.applyFunction(s.replacer, holder, []Object{key, value})
+		if err, ok := value.(*Error); ok {
+			return "", false, err
+		}
+	}
+
+	switch value := value.(type) {
+	case *Null:
+		return "null", true, nil
+	case *Boolean:
+		return value.Inspect(), true, nil
+	case *String:
+		return quoteJSON(value.units()), true, nil
+	case *Number:
+		if math.IsNaN(value.Value) || math.IsInf(value.Value, 0) {
+			return "null", true, nil
+		}
+		return numberToString(value.Value), true, nil
+	case *BigInt:
+		return "", false, newTypeError("Do not know how to serialize a BigInt")
+	case *Array:
+		out, err := s.serializeArray(value)
+		return out, err == nil, err
+	}
+	if isObject(value) && !isCallable(value) {
+		out, err := s.serializeObject(value)
+		return out, err == nil, err
+	}
+	return "", false, nil
+}
+
+// enter adds value to the objects being serialized, failing if it is
+// already there. Serializing recurses for every level of nesting, so each
+// level counts as a call: a structure nested more deeply than calls may
+// nest fails as deep recursion would.
+func (s *jsonStringifier) enter(value Object) *Error {
+	if s.stack[value] {
+		return newTypeError("Converting circular structure to JSON")
+	}
+	if err := s.i.enterCall(); err != nil {
+		return err
+	}
+	if s.stack == nil {
+		s.stack = map[Object]bool{}
+	}
+	s.stack[value] = true
+	return nil
+}
+
+// leave removes value from the objects being serialized.
+func (s *jsonStringifier) leave(value Object) {
+	delete(s.stack, value)
+	s.i.exitCall()
+}
+
+// wrap joins the serialized members of an object or array between its
+// brackets, putting each member on its own line when there is a gap.
+func (s *jsonStringifier) wrap(open, close string, members []string, stepback string) string {
+	if len(members) == 0 {
+		return open + close
+	}
+	if s.gap == "" {
+		return open + strings.Join(members, ",") + close
+	}
+	return open + "\n" + s.indent + strings.Join(members, ",\n"+s.indent) + "\n" + stepback + close
+}
+
+func (s *jsonStringifier) serializeObject(value Object) (string, *Error) {
+	if err := s.enter(value); err != nil {
+		return "", err
+	}
+	defer s.leave(value)
+	stepback := s.indent
+	s.indent += s.gap
+
+	keys := s.propertyList
+	if keys == nil {
+		// A typed array's elements are serialized as properties named by
+		// their indices, and a host object's properties as its own.
+		if ta, ok := value.(*TypedArray); ok {
+			for _, key := range typedArrayKeys(ta) {
+				keys = append(keys, key.(*String))
+			}
+		}
+		if host, ok := value.(*HostObject); ok {
+			for _, key := range host.hostKeys() {
+				keys = append(keys, key.(*String))
+			}
+		}
+		for _, key := range value.(propertyHolder).properties().OwnKeys() {
+			if k, ok := key.(*String); ok {
+				keys = append(keys, k)
+			}
+		}
+	}
+	separator := ":"
+	if s.gap != "" {
+		separator = ": "
+	}
+	var members []string
+	for _, key := range keys {
+		out, ok, err := s.serializeProperty(value, key)
+		if err != nil {
+			return "", err
+		}
+		if ok {
+			members = append(members, quoteJSON(key.units())+separator+out)
+		}
+	}
+
+	out := s.wrap("{", "}", members, stepback)
+	s.indent = stepback
+	return out, nil
+}
+
+func (s *jsonStringifier) serializeArray(arr *Array) (string, *Error) {
+	if err := s.enter(arr); err != nil {
+		return "", err
+	}
+	defer s.leave(arr)
+	stepback := s.indent
+	s.indent += s.gap
+
+	// Every element takes at least a character and a comma, holes
+	// included, so a long enough array can't fit in a string.
+	if arr.len() > maxStringLength/2 {
+		return "", newRangeError("Invalid string length")
+	}
+	var members []string
+	for idx := 0; idx < arr.len(); idx++ {
+		out, ok, err := s.serializeProperty(arr, &String{Value: strconv.Itoa(idx)})
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			out = "null"
+		}
+		members = append(members, out)
+	}
+
+	out := s.wrap("[", "]", members, stepback)
+	s.indent = stepback
+	return out, nil
+}
+
+// quoteJSON writes a string as a JSON string literal. Besides quotes and
+// backslashes, control characters and lone surrogates are escaped, so the
+// output is always well-formed Unicode.
+func quoteJSON(units []uint16) string {
+	var out []uint16
+	out = append(out, '"')
+	for idx := 0; idx < len(units); idx++ {
+		u := units[idx]
+		switch u {
+		case '"', '\\':
+			out = append(out, '\\', u)
+			continue
+		case '\b':
+			out = append(out, '\\', 'b')
+			continue
+		case '\f':
+			out = append(out, '\\', 'f')
+			continue
+		case '\n':
+			out = append(out, '\\', 'n')
+			continue
+		case '\r':
+			out = append(out, '\\', 'r')
+			continue
+		case '\t':
+			out = append(out, '\\', 't')
+			continue
+		}
+		isHigh := 0xD800 <= u && u < 0xDC00
+		isLow := 0xDC00 <= u && u < 0xE000
+		switch {
+		case isHigh && idx+1 < len(units) && 0xDC00 <= units[idx+1] && units[idx+1] < 0xE000:
+			out = append(out, u, units[idx+1])
+			idx++
+		case u < 0x20 || isHigh || isLow:
+			for _, c := range fmt.Sprintf(`\u%04x`, u) {
+				out = append(out, uint16(c))
+			}
+		default:
+			out = append(out, u)
+		}
+	}
+	out = append(out, '"')
+	return stringFromUnits(out)
+}
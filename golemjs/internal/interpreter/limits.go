@@ -0,0 +1,292 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/metrics"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+)
+
+// A script from somewhere you don't trust can run forever, recurse until
+// the Go stack overflows, which crashes the whole process, or allocate
+// until the machine runs out of memory. Limits bound all three, and
+// EvalContext lets the host stop a script from outside, with a deadline
+// or by cancelling:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+//	defer cancel()
+//	interp.SetLimits(interpreter.Limits{MaxSteps: 1e6, Uncatchable: true})
+//	interp.EvalContext(ctx, program) // while (true) {} stops
+//
+// Checking the limits on every step would slow every script down, so the
+// interpreter only counts steps as it goes, and every checkInterval steps
+// it stops to look at the context, the step budget and the heap. The call
+// depth is checked on every call, since by the time a check came round
+// the stack could have overflowed.
+//
+// A builtin can do a lot in one step: [].concat or "x".repeat can copy a
+// billion elements, and sort can compare them. Builtins that loop count a
+// step for every turn of the loop, or charge for the work they're about to
+// do all at once, and builtins that allocate say how much, so that one
+// step can't run past the limits either.
+
+// Limits bounds what a script may use. The zero value sets no limits but
+// the default call depth.
+type Limits struct {
+	// MaxSteps is the most steps evaluation may take, or 0 for no limit.
+	// A step is a node of the AST evaluated by the tree-walker, or an
+	// instruction run by the bytecode VM, so the same script takes a
+	// different number of steps with each backend.
+	MaxSteps int64
+
+	// MaxCallDepth is how deeply function calls may nest, or 0 for
+	// DefaultMaxCallDepth. A call beyond it throws "RangeError: Maximum
+	// call stack size exceeded".
+	MaxCallDepth int
+
+	// MaxHeapBytes is roughly how much the Go heap may grow while a script
+	// runs, or 0 for no limit. The heap is shared by everything in the
+	// process, so this is only an approximation: it's the growth of the
+	// whole heap since evaluation began, including garbage not yet
+	// collected, and once it looks exceeded the interpreter collects
+	// garbage to make sure before it gives up.
+	MaxHeapBytes uint64
+
+	// Uncatchable makes exceeding a limit, or the context being done, end
+	// evaluation: the error goes straight to the host, no promise
+	// rejection handler sees it, and no more JavaScript runs until the
+	// next call to Eval. Otherwise the error is thrown like any other, and
+	// a script can handle it, though it will only get as far as the next
+	// check before the same limit stops it again.
+	Uncatchable bool
+}
+
+// DefaultMaxCallDepth is the call depth allowed when Limits doesn't set
+// one: deep enough for any reasonable recursion, and shallow enough for
+// the Go stack.
+const DefaultMaxCallDepth = 10000
+
+// The causes of the errors raised when a limit is exceeded; see
+// Error.Cause. An evaluation stopped by its context has the context's
+// error as the cause instead.
+var (
+	ErrStepLimit = errors.New("step limit exceeded")
+	ErrCallDepth = errors.New("maximum call stack size exceeded")
+	ErrHeapLimit = errors.New("heap limit exceeded")
+)
+
+// checkInterval is the number of steps between checks of the limits.
+const checkInterval = 10000
+
+// heapCheckBytes is how much builtins may allocate, all at once or bit by
+// bit, before the heap limit is checked rather than waiting for the next
+// check.
+const heapCheckBytes = 1 << 20
+
+// usage tracks what the script being evaluated has used so far.
+type usage struct {
+	ctx       context.Context
+	steps     int64
+	nextCheck int64 // the step at which to check the limits again
+	depth     int
+	heapBase  uint64 // the size of the heap when evaluation began
+	allocated uint64 // what builtins have allocated since the last check
+	running   bool   // whether evaluation is under way
+
+	// terminated holds the error that ended evaluation, once an
+	// uncatchable one has.
+	terminated *Error
+}
+
+// SetLimits bounds what scripts evaluated from now on may use.
+func (i *Interpreter) SetLimits(l Limits) {
+	i.limits = l
+}
+
+// EvalContext is Eval, stopping when ctx is done: the script, and the
+// event loop running the work it queued, end with an error whose Cause is
+// ctx.Err(). The event loop's sleep until the next timer is cut short too.
+func (i *Interpreter) EvalContext(ctx context.Context, node ast.Node) Object {
+	return i.runWithin(ctx, func() Object { return i.evalNode(node) })
+}
+
+// runWithin calls f, which evaluates something, with the limits counted
+// from zero and ctx to stop it. Evaluation started while a script is
+// running, by a Go function the script called, is part of that script: it
+// counts against the same limits, and stops with the same context.
+func (i *Interpreter) runWithin(ctx context.Context, f func() Object) Object {
+	if i.usage.running {
+		return f()
+	}
+	i.begin(ctx)
+	defer i.end()
+	result := f()
+	if i.usage.terminated != nil {
+		return i.usage.terminated
+	}
+	return result
+}
+
+// begin starts counting what an evaluation under ctx uses, from nothing,
+// and end stops. runWithin calls them around what it evaluates.
+func (i *Interpreter) begin(ctx context.Context) {
+	i.usage = usage{ctx: ctx, running: true}
+	if i.limits.MaxHeapBytes > 0 {
+		i.usage.heapBase = heapSize()
+	}
+}
+
+func (i *Interpreter) end() {
+	i.usage.ctx = context.Background()
+	i.usage.running = false
+}
+
+// tick counts a step, and every checkInterval steps checks the limits.
+func (i *Interpreter) tick() *Error {
+	i.usage.steps++
+	if i.usage.steps < i.usage.nextCheck {
+		return nil
+	}
+	return i.checkLimits()
+}
+
+// charge counts n steps at once, for a builtin about to do n steps' worth
+// of work without running any JavaScript, like copying n elements.
+func (i *Interpreter) charge(n int) *Error {
+	i.usage.steps += int64(n)
+	if i.usage.steps < i.usage.nextCheck {
+		return nil
+	}
+	return i.checkLimits()
+}
+
+// allocate is called by a builtin about to allocate about size bytes. It
+// fails if that would take the heap past its limit. Small allocations are
+// only counted, until they add up to heapCheckBytes; a large one is checked
+// before it's made, so "x".repeat(1e8) fails rather than taking the memory.
+func (i *Interpreter) allocate(size int) *Error {
+	if i.limits.MaxHeapBytes == 0 {
+		return nil
+	}
+	i.usage.allocated += uint64(size)
+	if i.usage.allocated < heapCheckBytes {
+		return nil
+	}
+	i.usage.allocated = 0
+	return i.checkHeap(uint64(size))
+}
+
+// allocated is allocate for allocations that can't fail, like making a new
+// array: once they've added up, the next step checks the limits.
+func (i *Interpreter) allocated(size int) {
+	if i.limits.MaxHeapBytes == 0 {
+		return
+	}
+	i.usage.allocated += uint64(size)
+	if i.usage.allocated >= heapCheckBytes {
+		i.usage.nextCheck = i.usage.steps
+	}
+}
+
+// checkLimits returns an error if the script has gone past its step
+// budget or its heap limit, or its context is done.
+func (i *Interpreter) checkLimits() *Error {
+	u := &i.usage
+	if err := i.interrupted(); err != nil {
+		return err
+	}
+	u.nextCheck = u.steps + checkInterval
+	if max := i.limits.MaxSteps; max > 0 {
+		if u.steps > max {
+			return i.limitExceeded(ErrStepLimit, newRangeError("Execution step limit of %d exceeded", max))
+		}
+		u.nextCheck = min(u.nextCheck, max+1)
+	}
+	if i.limits.MaxHeapBytes > 0 {
+		u.allocated = 0
+		return i.checkHeap(0)
+	}
+	return nil
+}
+
+// checkHeap returns an error if the heap, grown by size bytes more, would
+// be past its limit.
+func (i *Interpreter) checkHeap(size uint64) *Error {
+	max := i.limits.MaxHeapBytes
+	if heapSize()+size <= i.usage.heapBase+max {
+		return nil
+	}
+	runtime.GC()
+	if heapSize()+size <= i.usage.heapBase+max {
+		return nil
+	}
+	return i.limitExceeded(ErrHeapLimit, newRangeError("Heap limit of %d bytes exceeded", max))
+}
+
+// interrupted returns the error that ended evaluation, if an uncatchable
+// one has, or an error if the context is done.
+func (i *Interpreter) interrupted() *Error {
+	if i.usage.terminated != nil {
+		return i.usage.terminated
+	}
+	if err := i.usage.ctx.Err(); err != nil {
+		return i.limitExceeded(err, newError("Error: Execution interrupted: %s", err))
+	}
+	return nil
+}
+
+// limitExceeded marks err as raised for cause. An uncatchable error ends
+// evaluation: from then on, every step fails with it.
+func (i *Interpreter) limitExceeded(cause error, err *Error) *Error {
+	err.Cause = cause
+	if i.limits.Uncatchable {
+		i.usage.terminated = err
+		i.usage.nextCheck = 0
+	}
+	return err
+}
+
+// enterCall counts a function call about to be made, failing if calls
+// are nested too deeply already. exitCall counts it as returned.
+func (i *Interpreter) enterCall() *Error {
+	max := i.limits.MaxCallDepth
+	if max == 0 {
+		max = DefaultMaxCallDepth
+	}
+	if i.usage.depth >= max {
+		return i.limitExceeded(ErrCallDepth, newRangeError("Maximum call stack size exceeded"))
+	}
+	i.usage.depth++
+	return nil
+}
+
+func (i *Interpreter) exitCall() {
+	i.usage.depth--
+}
+
+// sleep waits on the interpreter's clock for d, or on the system clock
+// until the context is done, if that comes first.
+func (i *Interpreter) sleep(d time.Duration) {
+	done := i.usage.ctx.Done()
+	if _, ok := i.clock.(systemClock); !ok || done == nil {
+		i.clock.Sleep(d)
+		return
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-done:
+	}
+}
+
+// heapSize returns the size of the Go heap: the memory taken by objects,
+// live or not yet collected.
+func heapSize() uint64 {
+	sample := []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}
+	metrics.Read(sample)
+	return sample[0].Value.Uint64()
+}
@@ -0,0 +1,102 @@
+package interpreter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// ModuleLoader finds the modules that scripts import. The interpreter asks
+// it to resolve each specifier, the string after from in an import, to
+// the name of a module, and then to load the source of every module it
+// hasn't loaded before. Modules are cached by name, so two specifiers
+// resolving to the same name import the same module.
+//
+// FSLoader loads modules from a file system and MapLoader from memory.
+// Embedders wanting something else, such as modules fetched over HTTP,
+// can implement the interface themselves.
+type ModuleLoader interface {
+	// Resolve returns the name of the module that specifier refers to in
+	// the module called referrer. The referrer is "" for the code passed
+	// to Eval and for Import.
+	Resolve(specifier, referrer string) (string, error)
+
+	// Load returns the source code of the module called name.
+	Load(name string) (string, error)
+}
+
+// SetModuleLoader sets where the modules that scripts import come from.
+// Without a loader, only modules that import nothing can be evaluated.
+func (i *Interpreter) SetModuleLoader(loader ModuleLoader) {
+	i.loader = loader
+}
+
+// FSLoader loads modules from a file system, such as os.DirFS(dir) for a
+// directory on disk. Module names are paths in the file system, resolved
+// as resolveModulePath describes.
+type FSLoader struct {
+	FS fs.FS
+}
+
+// NewFSLoader creates a loader reading modules from fsys.
+func NewFSLoader(fsys fs.FS) *FSLoader {
+	return &FSLoader{FS: fsys}
+}
+
+func (l *FSLoader) Resolve(specifier, referrer string) (string, error) {
+	return resolveModulePath(specifier, referrer)
+}
+
+func (l *FSLoader) Load(name string) (string, error) {
+	data, err := fs.ReadFile(l.FS, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", fmt.Errorf("Cannot find module '%s'", name)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// MapLoader is a ModuleLoader holding the source of each module in
+// memory, by name. It resolves specifiers as FSLoader does, which makes it
+// handy for tests and for embedders whose scripts don't live in files:
+//
+//	interp.SetModuleLoader(interpreter.MapLoader{
+//		"main.js":  `import { greet } from "./lib/greet.js"; greet();`,
+//		"lib/greet.js": `export function greet() { console.log("hi"); }`,
+//	})
+type MapLoader map[string]string
+
+func (l MapLoader) Resolve(specifier, referrer string) (string, error) {
+	return resolveModulePath(specifier, referrer)
+}
+
+func (l MapLoader) Load(name string) (string, error) {
+	source, ok := l[name]
+	if !ok {
+		return "", fmt.Errorf("Cannot find module '%s'", name)
+	}
+	return source, nil
+}
+
+// resolveModulePath resolves a specifier to a slash-separated path without
+// a leading slash, the form fs.FS expects. Specifiers starting with ./ or
+// ../ are relative to the directory of the importing module; any other
+// specifier is a path from the root, whether or not it starts with a
+// slash. There are no packages to look up, so "lodash" is simply the file
+// called lodash at the root.
+func resolveModulePath(specifier, referrer string) (string, error) {
+	var name string
+	if strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+		name = path.Join(path.Dir(referrer), specifier)
+	} else {
+		name = path.Clean(strings.TrimPrefix(specifier, "/"))
+	}
+	if name == "." || !fs.ValidPath(name) {
+		return "", fmt.Errorf("Cannot find module '%s'", specifier)
+	}
+	return name, nil
+}
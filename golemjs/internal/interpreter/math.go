@@ -0,0 +1,247 @@
+package interpreter
+
+import (
+	"math"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// SetRandomSeed makes Math.random return the same sequence of numbers
+// every time the interpreter is given the same seed, so that tests of
+// scripts that use it can be deterministic. Without a seed, Math.random is
+// seeded randomly.
+func (i *Interpreter) SetRandomSeed(seed uint64) {
+	i.random = rand.New(rand.NewPCG(seed, 0))
+}
+
+// mathConstants are the constants of the Math object, in the order they're
+// defined in.
+var mathConstants = []struct {
+	name  string
+	value float64
+}{
+	{"E", math.E},
+	{"LN10", math.Ln10},
+	{"LN2", math.Ln2},
+	{"LOG10E", math.Log10E},
+	{"LOG2E", math.Log2E},
+	{"PI", math.Pi},
+	{"SQRT1_2", math.Sqrt2 / 2},
+	{"SQRT2", math.Sqrt2},
+}
+
+// mathObject is the Math object. Its constants are read-only, as they are
+// in JavaScript: Math.PI = 3 is ignored.
+type mathObject struct {
+	Hash
+}
+
+func (m *mathObject) accessor(name string) (Object, bool) {
+	for _, c := range mathConstants {
+		if c.name == name {
+			return &Number{Value: c.value}, true
+		}
+	}
+	return nil, false
+}
+
+// setupMath defines the Math object, which holds mathematical constants
+// and functions. Math isn't a constructor; it's a plain object used as a
+// namespace. Its properties are defined in a fixed order, so that it
+// looks the same every time it's logged.
+func (i *Interpreter) setupMath() {
+	i.random = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	m := &mathObject{Hash: Hash{Prototype: i.objectPrototype}}
+	obj := &m.Hash
+
+	for _, c := range mathConstants {
+		obj.Set(&String{Value: c.name}, &Number{Value: c.value})
+	}
+
+	// Most of Math is Go's math package applied to the first argument
+	// after converting it to a number.
+	unary := []struct {
+		name string
+		fn   func(float64) float64
+	}{
+		{"abs", math.Abs},
+		{"acos", math.Acos},
+		{"acosh", math.Acosh},
+		{"asin", math.Asin},
+		{"asinh", math.Asinh},
+		{"atan", math.Atan},
+		{"atanh", math.Atanh},
+		{"cbrt", math.Cbrt},
+		{"ceil", math.Ceil},
+		{"cos", math.Cos},
+		{"cosh", math.Cosh},
+		{"exp", math.Exp},
+		{"expm1", math.Expm1},
+		{"floor", math.Floor},
+		{"log", math.Log},
+		{"log1p", math.Log1p},
+		{"log10", math.Log10},
+		{"log2", math.Log2},
+		{"sin", math.Sin},
+		{"sinh", math.Sinh},
+		{"sqrt", math.Sqrt},
+		{"tan", math.Tan},
+		{"tanh", math.Tanh},
+		{"trunc", math.Trunc},
+		{"round", mathRound},
+		{"sign", mathSign},
+		{"fround", func(x float64) float64 { return float64(float32(x)) }},
+		{"clz32", func(x float64) float64 {
+			return float64(bits.LeadingZeros32(toUint32(x)))
+		}},
+	}
+	for _, u := range unary {
+		i.defineMethod(obj, u.name, func(this Object, args ...Object) Object {
+			x, err := i.toNumber(argAt(args, 0))
+			if err != nil {
+				return err
+			}
+			return &Number{Value: u.fn(x)}
+		})
+	}
+
+	binary := []struct {
+		name string
+		fn   func(x, y float64) float64
+	}{
+		{"atan2", math.Atan2},
+		{"pow", mathPow},
+		{"imul", func(x, y float64) float64 {
+			return float64(int32(toUint32(x) * toUint32(y)))
+		}},
+	}
+	for _, b := range binary {
+		i.defineMethod(obj, b.name, func(this Object, args ...Object) Object {
+			x, err := i.toNumber(argAt(args, 0))
+			if err != nil {
+				return err
+			}
+			y, err := i.toNumber(argAt(args, 1))
+			if err != nil {
+				return err
+			}
+			return &Number{Value: b.fn(x, y)}
+		})
+	}
+
+	i.defineMethod(obj, "max", i.mathVariadic(mathMax))
+	i.defineMethod(obj, "min", i.mathVariadic(mathMin))
+	i.defineMethod(obj, "hypot", i.mathVariadic(mathHypot))
+	i.defineMethod(obj, "random", func(this Object, args ...Object) Object {
+		return &Number{Value: i.random.Float64()}
+	})
+
+	obj.Set(symbolToStringTag, &String{Value: "Math"})
+	i.env.Set("Math", m)
+}
+
+// mathVariadic wraps a function of any number of arguments. Every argument
+// is converted to a number before fn is called, even if an earlier one
+// already decided the result.
+func (i *Interpreter) mathVariadic(fn func(xs []float64) float64) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		xs := make([]float64, len(args))
+		for idx, arg := range args {
+			x, err := i.toNumber(arg)
+			if err != nil {
+				return err
+			}
+			xs[idx] = x
+		}
+		return &Number{Value: fn(xs)}
+	}
+}
+
+// mathRound rounds to the nearest integer, rounding halves up towards
+// positive infinity: Math.round(2.5) is 3 but Math.round(-2.5) is -2.
+func mathRound(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return x
+	}
+	r := math.Floor(x)
+	if x-r >= 0.5 {
+		r++
+	}
+	if r == 0 && (x < 0 || math.Signbit(x)) {
+		return math.Copysign(0, -1)
+	}
+	return r
+}
+
+// mathSign returns 1 or -1 for positive and negative numbers, and the
+// argument itself for zeros and NaN.
+func mathSign(x float64) float64 {
+	switch {
+	case x > 0:
+		return 1
+	case x < 0:
+		return -1
+	}
+	return x
+}
+
+// mathPow differs from Go's math.Pow in two corners: 1 to the power of NaN
+// or ±Infinity is NaN in JavaScript, not 1.
+func mathPow(x, y float64) float64 {
+	if math.IsNaN(y) || (math.Abs(x) == 1 && math.IsInf(y, 0)) {
+		return math.NaN()
+	}
+	return math.Pow(x, y)
+}
+
+// mathMax returns the largest argument, treating 0 as larger than -0.
+// Any NaN makes the result NaN.
+func mathMax(xs []float64) float64 {
+	result := math.Inf(-1)
+	for _, x := range xs {
+		if math.IsNaN(x) {
+			return math.NaN()
+		}
+		if x > result || (x == 0 && result == 0 && !math.Signbit(x)) {
+			result = x
+		}
+	}
+	return result
+}
+
+// mathMin returns the smallest argument, treating -0 as smaller than 0.
+// Any NaN makes the result NaN.
+func mathMin(xs []float64) float64 {
+	result := math.Inf(1)
+	for _, x := range xs {
+		if math.IsNaN(x) {
+			return math.NaN()
+		}
+		if x < result || (x == 0 && result == 0 && math.Signbit(x)) {
+			result = x
+		}
+	}
+	return result
+}
+
+// mathHypot returns the square root of the sum of squares of its
+// arguments. Infinity wins over NaN, since the result is infinite whatever
+// the other arguments are.
+func mathHypot(xs []float64) float64 {
+	result := 0.0
+	isNaN := false
+	for _, x := range xs {
+		switch {
+		case math.IsInf(x, 0):
+			return math.Inf(1)
+		case math.IsNaN(x):
+			isNaN = true
+		default:
+			result = math.Hypot(result, x)
+		}
+	}
+	if isNaN {
+		return math.NaN()
+	}
+	return result
+}
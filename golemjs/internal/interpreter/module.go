@@ -0,0 +1,800 @@
+package interpreter
+
+import (
+	"context"
+	"sort"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// A module is a file of code with its own top-level scope, which shares
+// only what it exports, and sees of other modules only what it imports:
+//
+//	// lib.js
+//	export let count = 0;
+//	export function increment() { count++; }
+//
+//	// main.js
+//	import { count, increment } from "./lib.js";
+//	increment();
+//	console.log(count); // 1
+//
+// Imports aren't copies. An imported variable is a view of the exporting
+// module's variable, a live binding, so main.js sees count change, though
+// it can't assign to it itself.
+//
+// Modules are set up in three phases before any of their code runs. First
+// the module and everything it imports, directly or not, is loaded and
+// parsed, with the interpreter's ModuleLoader (see loader.go). Then the
+// modules are linked: each import is bound to the variable it names, or a
+// SyntaxError reports an import of a name the other module doesn't export.
+// Function declarations are created during linking too, so that modules
+// importing each other can call each other's functions whichever runs
+// first. Finally the modules are evaluated, each after the modules it
+// imports, and each only once, however many modules import it.
+//
+// Modules may import each other in a cycle. The cycle is broken where it
+// was entered: of main.js importing a.js importing b.js importing a.js,
+// b.js runs first, and reading one of a.js's variables before a.js has
+// run is a ReferenceError.
+//
+// A module may use await at its top level, and the modules importing it
+// wait for it to finish. Here modules are evaluated one after the other,
+// where the specification would let unrelated modules waiting for
+// promises run side by side; the order they start in is the same.
+
+// moduleStatus is how far a module has got towards having run.
+type moduleStatus int
+
+const (
+	moduleUnlinked moduleStatus = iota
+	moduleLinking
+	moduleLinked
+	moduleEvaluating
+	moduleEvaluated
+)
+
+const (
+	// defaultBinding is the variable holding the value of export default
+	// expression, which can't clash with a variable of the module.
+	defaultBinding = "*default*"
+
+	// namespaceImport stands for the namespace object of a module in
+	// imports and exports, which can't clash with the name of an export.
+	namespaceImport = "*"
+)
+
+// moduleRecord is a module, loaded and parsed, with what it imports and
+// exports.
+type moduleRecord struct {
+	name    string // the name the loader gave it, or "" for the code passed to Eval
+	program *ast.Program
+	env     *Environment
+	status  moduleStatus
+
+	// requests holds the specifiers the module imports from, in the order
+	// they first appear, and requested the module each one names.
+	requests  []string
+	requested map[string]*moduleRecord
+
+	imports []importEntry
+
+	// localExports maps the names the module exports from its own
+	// variables to the variables, indirectExports those it passes on
+	// from other modules to where they come from, and starExports holds
+	// the specifiers of the modules that export * passes every export
+	// of on.
+	localExports    map[string]string
+	indirectExports map[string]importEntry
+	starExports     []string
+
+	namespace *ModuleNamespace
+
+	// evaluation is settled once the module has run, successfully or
+	// not. The modules importing a module that is still running, because
+	// it awaits, wait for it.
+	evaluation *Promise
+
+	// err is the error the module failed to load, link or run with. An
+	// import of the module fails with it again.
+	err *Error
+}
+
+// importEntry is a variable, local, bound to the export name of the
+// module specifier names. For export { a as b } from "./m.js", local is
+// the exported name b.
+type importEntry struct {
+	specifier string
+	name      string
+	local     string
+}
+
+// resolvedBinding is where an export really comes from: the variable name
+// at the top level of module, or, if name is namespaceImport, the
+// module's namespace object.
+type resolvedBinding struct {
+	module *moduleRecord
+	name   string
+}
+
+// ModuleNamespace is the object that import * as ns binds ns to, and that
+// the promise import() returns is fulfilled with. Its properties are the
+// module's exports, in alphabetical order, and read the module's
+// variables each time, like the module's other imports. It has no
+// prototype, and can't be changed.
+type ModuleNamespace struct {
+	Hash
+	names    []string
+	bindings map[string]resolvedBinding
+
+	// namespaces holds the namespace objects exported by export * as.
+	namespaces map[string]*ModuleNamespace
+}
+
+func (n *ModuleNamespace) Type() ObjectType { return MODULE_NAMESPACE_OBJ }
+func (n *ModuleNamespace) Inspect() string  { return "[object Module]" }
+
+// has reports whether the module exports name.
+func (n *ModuleNamespace) has(name string) bool {
+	_, ok := n.bindings[name]
+	return ok
+}
+
+// get reads the export called name. Reading a variable of a module that
+// hasn't run yet is a ReferenceError.
+func (n *ModuleNamespace) get(name string) Object {
+	if ns, ok := n.namespaces[name]; ok {
+		return ns
+	}
+	b, ok := n.bindings[name]
+	if !ok {
+		return UNDEFINED
+	}
+	value, ok := b.module.env.getOwn(b.name)
+	if !ok {
+		return newReferenceError("Cannot access '%s' before initialization", name)
+	}
+	return value
+}
+
+// Import loads the module specifier names, resolved as if imported by the
+// code passed to Eval, and evaluates it and the modules it imports, then
+// runs the event loop until idle. It returns the module's namespace
+// object, or the error the module failed with, or undefined if the module
+// is still waiting for a promise that nothing will ever settle.
+func (i *Interpreter) Import(specifier string) Object {
+	return i.ImportContext(context.Background(), specifier)
+}
+
+// ImportContext is Import, stopping when ctx is done, as EvalContext does.
+func (i *Interpreter) ImportContext(ctx context.Context, specifier string) Object {
+	return i.runWithin(ctx, func() Object { return i.importModule(specifier) })
+}
+
+// importModule does the work of Import.
+func (i *Interpreter) importModule(specifier string) Object {
+	m, err := i.loadModule(specifier, "")
+	if err == nil {
+		err = i.link(m)
+	}
+	if err != nil {
+		return err
+	}
+	var result Object = UNDEFINED
+	i.evaluate(m, func(value Object) {
+		result = value
+		if !isError(value) {
+			result = i.namespaceOf(m)
+		}
+	})
+	if err := i.runUntilIdle(); err != nil && !isError(result) {
+		return err
+	}
+	return result
+}
+
+// evalModule evaluates a module passed to Eval. Its result is the value of
+// its last statement, as with a script. Its top level may await, so the
+// body runs as an async body does, and it may finish only while the event
+// loop runs.
+func (i *Interpreter) evalModule(program *ast.Program) Object {
+	m, err := i.newModuleRecord("", program)
+	if err == nil {
+		err = i.loadRequested(m)
+	}
+	if err == nil {
+		err = i.link(m)
+	}
+	if err != nil {
+		return err
+	}
+	var result Object = UNDEFINED
+	i.evaluate(m, func(value Object) {
+		result = value
+	})
+	if err := i.runUntilIdle(); err != nil && !isError(result) {
+		return err
+	}
+	return result
+}
+
+// evalImportCall evaluates import(specifier). It returns a promise that
+// is fulfilled with the module's namespace object once the module has
+// been loaded, linked and run, or rejected with the error any of that
+// failed with. That work is left to a job, so the code calling import()
+// runs to the end first, as it would while a browser fetched the module.
+func (i *Interpreter) evalImportCall(node *ast.ImportCall, env *Environment) Object {
+	value := i.eval(node.Source, env)
+	if isError(value) {
+		return value
+	}
+	referrer := ""
+	for e := env; e != nil; e = e.outer {
+		if m, ok := i.moduleEnvs[e]; ok {
+			referrer = m.name
+			break
+		}
+	}
+	return i.importDynamic(value, referrer)
+}
+
+// importDynamic starts importing the module value names, from the module
+// referrer, or from a script if referrer is "", for import(), and returns
+// the promise of its namespace object.
+func (i *Interpreter) importDynamic(value Object, referrer string) Object {
+	promise := i.newPromise()
+	specifier, err := i.toString(value)
+	if err != nil {
+		i.rejectPromise(promise, i.thrownValue(err))
+		return promise
+	}
+	i.queueMicrotask(func() *Error {
+		m, err := i.loadModule(specifier.Value, referrer)
+		if err == nil {
+			err = i.link(m)
+		}
+		if err != nil {
+			i.rejectPromise(promise, i.thrownValue(err))
+			return nil
+		}
+		i.evaluate(m, func(result Object) {
+			if err, ok := result.(*Error); ok {
+				i.rejectPromise(promise, i.thrownValue(err))
+				return
+			}
+			i.resolvePromise(promise, i.namespaceOf(m))
+		})
+		return nil
+	})
+	return promise
+}
+
+// loadModule loads the module specifier names in the module referrer, and
+// the modules it imports, unless it has been loaded already.
+func (i *Interpreter) loadModule(specifier, referrer string) (*moduleRecord, *Error) {
+	if i.loader == nil {
+		return nil, newError("Error: Cannot find module '%s': no module loader is set", specifier)
+	}
+	name, err := i.loader.Resolve(specifier, referrer)
+	if err != nil {
+		return nil, moduleLoadError(err, referrer)
+	}
+	if m, ok := i.modules[name]; ok {
+		return m, m.err
+	}
+	source, err := i.loader.Load(name)
+	if err != nil {
+		return nil, moduleLoadError(err, referrer)
+	}
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseModule()
+	if errs := p.Errors(); len(errs) > 0 {
+		return nil, newSyntaxError("%s (in %s)", errs[0], name)
+	}
+	m, e := i.newModuleRecord(name, program)
+	if e != nil {
+		return nil, e
+	}
+	// The module is cached before the modules it imports are loaded, so
+	// that an import leading back to it finds it.
+	i.modules[name] = m
+	if e := i.loadRequested(m); e != nil {
+		m.err = e
+		return nil, e
+	}
+	return m, nil
+}
+
+// moduleLoadError reports an error from the module loader.
+func moduleLoadError(err error, referrer string) *Error {
+	if referrer == "" {
+		return newError("Error: %s", err)
+	}
+	return newError("Error: %s imported from %s", err, referrer)
+}
+
+// loadRequested loads the modules m imports.
+func (i *Interpreter) loadRequested(m *moduleRecord) *Error {
+	for _, specifier := range m.requests {
+		dep, err := i.loadModule(specifier, m.name)
+		if err != nil {
+			return err
+		}
+		m.requested[specifier] = dep
+	}
+	return nil
+}
+
+// newModuleRecord collects what a parsed module imports and exports. An
+// export of the same name twice, or of a variable the module doesn't
+// declare, is a SyntaxError.
+func (i *Interpreter) newModuleRecord(name string, program *ast.Program) (*moduleRecord, *Error) {
+	m := &moduleRecord{
+		name:            name,
+		program:         program,
+		env:             NewFunctionEnvironment(i.env),
+		requested:       map[string]*moduleRecord{},
+		localExports:    map[string]string{},
+		indirectExports: map[string]importEntry{},
+	}
+	i.moduleEnvs[m.env] = m
+
+	request := func(specifier string) {
+		for _, s := range m.requests {
+			if s == specifier {
+				return
+			}
+		}
+		m.requests = append(m.requests, specifier)
+	}
+	declared := map[string]bool{}
+	declare := func(target ast.Expression) {
+		for _, name := range boundNames(target) {
+			declared[name] = true
+		}
+	}
+
+	// Imports are collected first, since an export may pass on a name
+	// imported further down.
+	imported := map[string]importEntry{}
+	for _, stmt := range program.Statements {
+		decl, ok := stmt.(*ast.ImportDeclaration)
+		if !ok {
+			continue
+		}
+		request(decl.Source)
+		add := func(name string, local *ast.Identifier) {
+			entry := importEntry{specifier: decl.Source, name: name, local: local.Value}
+			m.imports = append(m.imports, entry)
+			imported[local.Value] = entry
+			declared[local.Value] = true
+		}
+		if decl.Default != nil {
+			add("default", decl.Default)
+		}
+		if decl.Namespace != nil {
+			add(namespaceImport, decl.Namespace)
+		}
+		for _, spec := range decl.Specifiers {
+			add(spec.Imported, spec.Local)
+		}
+	}
+
+	exported := map[string]bool{}
+	var local []*ast.ExportSpecifier
+	export := func(name string) *Error {
+		if exported[name] {
+			return newSyntaxError("Duplicate export of '%s'", name)
+		}
+		exported[name] = true
+		return nil
+	}
+	for _, stmt := range program.Statements {
+		switch stmt := stmt.(type) {
+		case *ast.VariableDeclaration:
+			declare(stmt.Name)
+		case *ast.FunctionDeclaration:
+			declared[stmt.Name.Value] = true
+		case *ast.ForStatement:
+			if decl, ok := stmt.Init.(*ast.VariableDeclaration); ok && decl.Token.Literal == "var" {
+				declare(decl.Name)
+			}
+		case *ast.ForOfStatement:
+			if decl, ok := stmt.Left.(*ast.VariableDeclaration); ok && decl.Token.Literal == "var" {
+				declare(decl.Name)
+			}
+		case *ast.ExportNamedDeclaration:
+			var names []string
+			switch decl := stmt.Declaration.(type) {
+			case *ast.VariableDeclaration:
+				names = boundNames(decl.Name)
+			case *ast.FunctionDeclaration:
+				names = []string{decl.Name.Value}
+			}
+			for _, name := range names {
+				declared[name] = true
+				if err := export(name); err != nil {
+					return nil, err
+				}
+				m.localExports[name] = name
+			}
+			if stmt.Source != "" {
+				request(stmt.Source)
+			}
+			for _, spec := range stmt.Specifiers {
+				if err := export(spec.Exported); err != nil {
+					return nil, err
+				}
+				if stmt.Source != "" {
+					m.indirectExports[spec.Exported] = importEntry{specifier: stmt.Source, name: spec.Local, local: spec.Exported}
+				} else {
+					local = append(local, spec)
+				}
+			}
+		case *ast.ExportDefaultDeclaration:
+			if err := export("default"); err != nil {
+				return nil, err
+			}
+			m.localExports["default"] = defaultBinding
+			if fn, ok := stmt.Declaration.(*ast.FunctionDeclaration); ok && fn.Name != nil {
+				declared[fn.Name.Value] = true
+				m.localExports["default"] = fn.Name.Value
+			}
+		case *ast.ExportAllDeclaration:
+			request(stmt.Source)
+			if stmt.Exported == "" {
+				m.starExports = append(m.starExports, stmt.Source)
+				continue
+			}
+			if err := export(stmt.Exported); err != nil {
+				return nil, err
+			}
+			m.indirectExports[stmt.Exported] = importEntry{specifier: stmt.Source, name: namespaceImport, local: stmt.Exported}
+		}
+	}
+
+	// export { a } passes on an imported a as the module exporting it
+	// exports it, so that modules importing a from either module get the
+	// same variable.
+	for _, spec := range local {
+		if !declared[spec.Local] {
+			return nil, newSyntaxError("Export '%s' is not defined in module", spec.Local)
+		}
+		if entry, ok := imported[spec.Local]; ok && entry.name != namespaceImport {
+			m.indirectExports[spec.Exported] = importEntry{specifier: entry.specifier, name: entry.name, local: spec.Exported}
+			continue
+		}
+		m.localExports[spec.Exported] = spec.Local
+	}
+	return m, nil
+}
+
+// boundNames returns the names of the variables a declaration of target
+// declares: a name, or the names in a destructuring pattern.
+func boundNames(target ast.Expression) []string {
+	switch target := target.(type) {
+	case *ast.Identifier:
+		return []string{target.Value}
+	case *ast.AssignmentPattern:
+		return boundNames(target.Left)
+	case *ast.RestElement:
+		return boundNames(target.Argument)
+	case *ast.ArrayPattern:
+		var names []string
+		for _, element := range target.Elements {
+			if element != nil {
+				names = append(names, boundNames(element)...)
+			}
+		}
+		return names
+	case *ast.ObjectPattern:
+		var names []string
+		for _, prop := range target.Properties {
+			names = append(names, boundNames(prop.Value)...)
+		}
+		if target.Rest != nil {
+			names = append(names, boundNames(target.Rest)...)
+		}
+		return names
+	}
+	return nil
+}
+
+// link links m and the modules it imports: it binds each import to the
+// variable it names and creates the modules' functions.
+func (i *Interpreter) link(m *moduleRecord) *Error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.status != moduleUnlinked {
+		// Already linked, or being linked further up an import cycle.
+		return nil
+	}
+	m.status = moduleLinking
+	err := i.linkModule(m)
+	if err != nil {
+		m.err = err
+		return err
+	}
+	m.status = moduleLinked
+	return nil
+}
+
+func (i *Interpreter) linkModule(m *moduleRecord) *Error {
+	for _, specifier := range m.requests {
+		if err := i.link(m.requested[specifier]); err != nil {
+			return err
+		}
+	}
+
+	for _, entry := range m.indirectExports {
+		if _, err := i.resolveImport(m, entry); err != nil {
+			return err
+		}
+	}
+	for _, entry := range m.imports {
+		b, err := i.resolveImport(m, entry)
+		if err != nil {
+			return err
+		}
+		if b.name == namespaceImport {
+			m.env.SetConst(entry.local, i.namespaceOf(b.module))
+			continue
+		}
+		m.env.Import(entry.local, b.module.env, b.name)
+	}
+
+	for _, stmt := range m.program.Statements {
+		var decl *ast.FunctionDeclaration
+		switch stmt := stmt.(type) {
+		case *ast.FunctionDeclaration:
+			decl = stmt
+		case *ast.ExportNamedDeclaration:
+			decl, _ = stmt.Declaration.(*ast.FunctionDeclaration)
+		case *ast.ExportDefaultDeclaration:
+			decl, _ = stmt.Declaration.(*ast.FunctionDeclaration)
+		}
+		if decl == nil {
+			continue
+		}
+		name, binding := "default", defaultBinding
+		if decl.Name != nil {
+			name, binding = decl.Name.Value, decl.Name.Value
+		}
+		m.env.Set(binding, i.newFunctionOfKind(name, decl.Parameters, decl.Body, m.env, false, decl.Generator, decl.Async))
+	}
+	return nil
+}
+
+// resolveImport finds where the export an import entry of m names comes
+// from.
+func (i *Interpreter) resolveImport(m *moduleRecord, entry importEntry) (resolvedBinding, *Error) {
+	dep := m.requested[entry.specifier]
+	if entry.name == namespaceImport {
+		return resolvedBinding{module: dep, name: namespaceImport}, nil
+	}
+	b, found, ambiguous := i.resolveExport(dep, entry.name, map[resolvedBinding]bool{})
+	if ambiguous {
+		return b, newSyntaxError("The requested module '%s' contains conflicting star exports for name '%s'", entry.specifier, entry.name)
+	}
+	if !found {
+		return b, newSyntaxError("The requested module '%s' does not provide an export named '%s'", entry.specifier, entry.name)
+	}
+	return b, nil
+}
+
+// resolveExport finds where the export called name of m comes from,
+// following it through the modules that pass it on. It reports whether
+// the export was found, and whether it was ambiguous: passed on by two
+// export * of different variables. seen holds the exports already
+// followed, so that export * cycles end.
+func (i *Interpreter) resolveExport(m *moduleRecord, name string, seen map[resolvedBinding]bool) (b resolvedBinding, found, ambiguous bool) {
+	key := resolvedBinding{module: m, name: name}
+	if seen[key] {
+		return b, false, false
+	}
+	seen[key] = true
+
+	if local, ok := m.localExports[name]; ok {
+		return resolvedBinding{module: m, name: local}, true, false
+	}
+	if entry, ok := m.indirectExports[name]; ok {
+		dep := m.requested[entry.specifier]
+		if entry.name == namespaceImport {
+			return resolvedBinding{module: dep, name: namespaceImport}, true, false
+		}
+		return i.resolveExport(dep, entry.name, seen)
+	}
+	if name == "default" {
+		// export * passes on everything but the default export.
+		return b, false, false
+	}
+	for _, specifier := range m.starExports {
+		star, ok, ambiguous := i.resolveExport(m.requested[specifier], name, seen)
+		if ambiguous {
+			return star, false, true
+		}
+		if !ok {
+			continue
+		}
+		if found && star != b {
+			return b, false, true
+		}
+		b, found = star, true
+	}
+	return b, found, false
+}
+
+// exportedNames returns the names m exports, including those export *
+// passes on.
+func (i *Interpreter) exportedNames(m *moduleRecord, seen map[*moduleRecord]bool) []string {
+	if seen[m] {
+		return nil
+	}
+	seen[m] = true
+	var names []string
+	for name := range m.localExports {
+		names = append(names, name)
+	}
+	for name := range m.indirectExports {
+		names = append(names, name)
+	}
+	for _, specifier := range m.starExports {
+		for _, name := range i.exportedNames(m.requested[specifier], seen) {
+			if name != "default" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// namespaceOf returns the namespace object of m, creating it the first
+// time. Names that export * makes ambiguous are left out.
+func (i *Interpreter) namespaceOf(m *moduleRecord) *ModuleNamespace {
+	if m.namespace != nil {
+		return m.namespace
+	}
+	ns := &ModuleNamespace{bindings: map[string]resolvedBinding{}, namespaces: map[string]*ModuleNamespace{}}
+	m.namespace = ns
+	for _, name := range i.exportedNames(m, map[*moduleRecord]bool{}) {
+		if ns.has(name) {
+			continue
+		}
+		b, found, ambiguous := i.resolveExport(m, name, map[resolvedBinding]bool{})
+		if !found || ambiguous {
+			continue
+		}
+		ns.bindings[name] = b
+		ns.names = append(ns.names, name)
+		if b.name == namespaceImport {
+			ns.namespaces[name] = i.namespaceOf(b.module)
+		}
+	}
+	sort.Strings(ns.names)
+	return ns
+}
+
+// evaluationStep is a step of evaluating modules: running a module, or,
+// for a module already running, waiting for it to finish.
+type evaluationStep struct {
+	module *moduleRecord
+	wait   bool
+}
+
+// evaluate runs m, after the modules it imports that haven't run yet,
+// and calls done with the result of m's body, or the error m or one of
+// those failed with. If m has run already, done gets its error or
+// undefined.
+func (i *Interpreter) evaluate(m *moduleRecord, done func(result Object)) {
+	var steps []evaluationStep
+	i.evaluationSteps(m, &steps, map[*moduleRecord]bool{})
+
+	i.startAsync(func() Object {
+		var result Object = UNDEFINED
+		for idx, step := range steps {
+			if step.wait {
+				if err, ok := i.await(step.module.evaluation).(*Error); ok {
+					i.failModules(step.module, steps[idx+1:], err)
+					return err
+				}
+				continue
+			}
+			result = completionValue(i.runStatements(step.module.program.Statements, step.module.env))
+			if err, ok := result.(*Error); ok {
+				step.module.status = moduleEvaluated
+				step.module.err = err
+				i.rejectPromise(step.module.evaluation, i.thrownValue(err))
+				i.failModules(step.module, steps[idx+1:], err)
+				return err
+			}
+			step.module.status = moduleEvaluated
+			i.resolvePromise(step.module.evaluation, UNDEFINED)
+		}
+		if len(steps) == 0 || steps[len(steps)-1].module != m {
+			return UNDEFINED
+		}
+		return result
+	}, done)
+}
+
+// evaluationSteps lists the steps that evaluate m, in order: first those
+// for the modules it imports, depth first, then m itself. started holds
+// the modules this evaluation has started.
+func (i *Interpreter) evaluationSteps(m *moduleRecord, steps *[]evaluationStep, started map[*moduleRecord]bool) {
+	switch m.status {
+	case moduleEvaluating, moduleEvaluated:
+		// A module started by another evaluation, such as an import()
+		// while it awaits, is waited for. One started by this evaluation
+		// is further up an import cycle.
+		if !started[m] {
+			*steps = append(*steps, evaluationStep{module: m, wait: true})
+		}
+		return
+	}
+	m.status = moduleEvaluating
+	m.evaluation = i.newPromise()
+	// Only the modules importing this one wait for it; a failure is
+	// reported to whoever started the evaluation.
+	m.evaluation.handled = true
+	started[m] = true
+	for _, specifier := range m.requests {
+		i.evaluationSteps(m.requested[specifier], steps, started)
+	}
+	*steps = append(*steps, evaluationStep{module: m})
+}
+
+// failModules fails the modules waiting in steps that import, directly or
+// not, the module that failed with err. The rest haven't started, and
+// are put back to run later.
+func (i *Interpreter) failModules(failed *moduleRecord, steps []evaluationStep, err *Error) {
+	failing := map[*moduleRecord]bool{failed: true}
+	for changed := true; changed; {
+		changed = false
+		for _, step := range steps {
+			if step.wait || failing[step.module] {
+				continue
+			}
+			for _, dep := range step.module.requested {
+				if failing[dep] {
+					failing[step.module] = true
+					changed = true
+					break
+				}
+			}
+		}
+	}
+	for _, step := range steps {
+		if step.wait || step.module == failed {
+			continue
+		}
+		if failing[step.module] {
+			step.module.status = moduleEvaluated
+			step.module.err = err
+			i.rejectPromise(step.module.evaluation, i.thrownValue(err))
+			continue
+		}
+		step.module.status = moduleLinked
+		step.module.evaluation = nil
+	}
+}
+
+// evalExportDefault evaluates export default expression, storing the
+// value for other modules to import. A function declaration was created
+// when the module was linked, so there's nothing left to do for one.
+func (i *Interpreter) evalExportDefault(node *ast.ExportDefaultDeclaration, env *Environment) Object {
+	value, ok := node.Declaration.(ast.Expression)
+	if !ok {
+		return nil
+	}
+	val := i.eval(value, env)
+	if isError(val) {
+		return val
+	}
+	nameAnonymousFunction(val, "default")
+	env.SetConst(defaultBinding, val)
+	return nil
+}
@@ -0,0 +1,372 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maxSafeInteger is the largest integer that a float64 can hold exactly
+// along with all smaller integers, 2^53 - 1.
+const maxSafeInteger = 1<<53 - 1
+
+// setupNumber creates Number.prototype and the Number function, and defines
+// the global number constants and functions: NaN, Infinity, isNaN,
+// isFinite, parseInt and parseFloat.
+func (i *Interpreter) setupNumber() {
+	proto := i.numberPrototype
+
+	i.defineMethod(proto, "toFixed", i.numberToFixed)
+	i.defineMethod(proto, "toLocaleString", func(this Object, args ...Object) Object {
+		n, err := thisNumber(this, "toLocaleString")
+		if err != nil {
+			return err
+		}
+		return &String{Value: numberToLocaleString(n.Value)}
+	})
+	i.defineMethod(proto, "toString", i.numberToStringMethod)
+	i.defineMethod(proto, "valueOf", func(this Object, args ...Object) Object {
+		n, err := thisNumber(this, "valueOf")
+		if err != nil {
+			return err
+		}
+		return n
+	})
+
+	ctor := i.newBuiltin("Number", func(this Object, args ...Object) Object {
+		if len(args) == 0 {
+			return &Number{Value: 0}
+		}
+		// Number is the one conversion that accepts BigInts, rounding
+		// them to the nearest number.
+		n, err := i.toNumeric(args[0])
+		if err != nil {
+			return err
+		}
+		if b, ok := n.(*BigInt); ok {
+			return &Number{Value: bigIntToNumber(b.Value)}
+		}
+		return n
+	})
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+
+	constants := map[string]float64{
+		"EPSILON":           math.Nextafter(1, 2) - 1,
+		"MAX_SAFE_INTEGER":  maxSafeInteger,
+		"MIN_SAFE_INTEGER":  -maxSafeInteger,
+		"MAX_VALUE":         math.MaxFloat64,
+		"MIN_VALUE":         math.SmallestNonzeroFloat64,
+		"NaN":               math.NaN(),
+		"POSITIVE_INFINITY": math.Inf(1),
+		"NEGATIVE_INFINITY": math.Inf(-1),
+	}
+	for name, value := range constants {
+		ctor.Set(&String{Value: name}, &Number{Value: value})
+	}
+
+	// Unlike the global isNaN and isFinite, the Number versions don't
+	// convert their argument: Number.isNaN("abc") is false.
+	i.defineMethod(&ctor.Hash, "isNaN", func(this Object, args ...Object) Object {
+		n, ok := argAt(args, 0).(*Number)
+		return nativeBoolToBooleanObject(ok && math.IsNaN(n.Value))
+	})
+	i.defineMethod(&ctor.Hash, "isFinite", func(this Object, args ...Object) Object {
+		n, ok := argAt(args, 0).(*Number)
+		return nativeBoolToBooleanObject(ok && !math.IsNaN(n.Value) && !math.IsInf(n.Value, 0))
+	})
+	i.defineMethod(&ctor.Hash, "isInteger", func(this Object, args ...Object) Object {
+		n, ok := argAt(args, 0).(*Number)
+		return nativeBoolToBooleanObject(ok && isInteger(n.Value))
+	})
+	i.defineMethod(&ctor.Hash, "isSafeInteger", func(this Object, args ...Object) Object {
+		n, ok := argAt(args, 0).(*Number)
+		return nativeBoolToBooleanObject(ok && isInteger(n.Value) && math.Abs(n.Value) <= maxSafeInteger)
+	})
+
+	parseIntFn := i.newBuiltin("parseInt", i.parseInt)
+	parseFloatFn := i.newBuiltin("parseFloat", i.parseFloat)
+	ctor.Set(&String{Value: "parseInt"}, parseIntFn)
+	ctor.Set(&String{Value: "parseFloat"}, parseFloatFn)
+	i.env.Set("Number", ctor)
+
+	i.env.Set("NaN", &Number{Value: math.NaN()})
+	i.env.Set("Infinity", &Number{Value: math.Inf(1)})
+	i.env.Set("parseInt", parseIntFn)
+	i.env.Set("parseFloat", parseFloatFn)
+	i.env.Set("isNaN", i.newBuiltin("isNaN", func(this Object, args ...Object) Object {
+		n, err := i.toNumber(argAt(args, 0))
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(math.IsNaN(n))
+	}))
+	i.env.Set("isFinite", i.newBuiltin("isFinite", func(this Object, args ...Object) Object {
+		n, err := i.toNumber(argAt(args, 0))
+		if err != nil {
+			return err
+		}
+		return nativeBoolToBooleanObject(!math.IsNaN(n) && !math.IsInf(n, 0))
+	}))
+}
+
+// isInteger reports whether f is a finite number without a fractional part.
+func isInteger(f float64) bool {
+	return !math.IsInf(f, 0) && f == math.Trunc(f)
+}
+
+// thisNumber checks that a Number.prototype method was called on a number.
+func thisNumber(this Object, method string) (*Number, *Error) {
+	n, ok := this.(*Number)
+	if !ok {
+		return nil, newTypeError("Number.prototype.%s requires that 'this' be a Number", method)
+	}
+	return n, nil
+}
+
+// numberToStringMethod implements Number.prototype.toString, which takes
+// an optional radix between 2 and 36: (255).toString(16) is "ff".
+func (i *Interpreter) numberToStringMethod(this Object, args ...Object) Object {
+	n, err := thisNumber(this, "toString")
+	if err != nil {
+		return err
+	}
+	radix := 10.0
+	if argAt(args, 0) != UNDEFINED {
+		radix, err = i.toIntegerOrInfinity(args[0])
+		if err != nil {
+			return err
+		}
+	}
+	if radix < 2 || radix > 36 {
+		return newRangeError("toString() radix must be between 2 and 36")
+	}
+	if radix == 10 || math.IsNaN(n.Value) || math.IsInf(n.Value, 0) {
+		return &String{Value: numberToString(n.Value)}
+	}
+	return &String{Value: formatRadix(n.Value, int(radix))}
+}
+
+// formatRadix writes a finite number in the given radix. The integer part
+// is exact; the fraction is cut off after 52 digits, which is enough to
+// write any float64 fraction exactly in binary.
+func formatRadix(f float64, radix int) string {
+	sign := ""
+	if f < 0 {
+		sign = "-"
+		f = -f
+	}
+	intPart, frac := math.Modf(f)
+	whole, _ := new(big.Float).SetFloat64(intPart).Int(nil)
+	out := sign + whole.Text(radix)
+	if frac == 0 {
+		return out
+	}
+	var digits strings.Builder
+	for n := 0; frac > 0 && n < 52; n++ {
+		frac *= float64(radix)
+		digit := int(frac)
+		digits.WriteString(strconv.FormatInt(int64(digit), radix))
+		frac -= float64(digit)
+	}
+	return out + "." + digits.String()
+}
+
+// numberToLocaleString formats f the way the en-US locale does, whatever
+// locale is asked for: rounded to at most three decimal places, with
+// commas between the thousands, so 1234567.8912 is "1,234,567.891".
+func numberToLocaleString(f float64) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "∞"
+	case math.IsInf(f, -1):
+		return "-∞"
+	}
+	s := strings.TrimSuffix(strings.TrimRight(strconv.FormatFloat(f, 'f', 3, 64), "0"), ".")
+	sign := ""
+	if rest, ok := strings.CutPrefix(s, "-"); ok {
+		sign, s = "-", rest
+	}
+	whole, fraction, hasFraction := strings.Cut(s, ".")
+	var b strings.Builder
+	b.WriteString(sign)
+	for idx := range len(whole) {
+		if idx > 0 && (len(whole)-idx)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteByte(whole[idx])
+	}
+	if hasFraction {
+		b.WriteString("." + fraction)
+	}
+	return b.String()
+}
+
+// numberToFixed implements Number.prototype.toFixed, which writes a number
+// with a fixed number of digits after the decimal point. Halves round up,
+// based on the exact value of the float64: (1.005).toFixed(2) is "1.00"
+// because 1.005 is really stored as 1.00499999999999989...
+func (i *Interpreter) numberToFixed(this Object, args ...Object) Object {
+	n, err := thisNumber(this, "toFixed")
+	if err != nil {
+		return err
+	}
+	digits, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if digits < 0 || digits > 100 {
+		return newRangeError("toFixed() digits argument must be between 0 and 100")
+	}
+	x := n.Value
+	if math.IsNaN(x) || math.Abs(x) >= 1e21 {
+		return &String{Value: numberToString(x)}
+	}
+
+	sign := ""
+	if x < 0 {
+		sign = "-"
+		x = -x
+	}
+	// Scale by 10^digits and round. 2048 bits of precision keep every step
+	// exact.
+	scaled := new(big.Float).SetPrec(2048).SetFloat64(x)
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil)
+	scaled.Mul(scaled, new(big.Float).SetPrec(2048).SetInt(scale))
+	scaled.Add(scaled, big.NewFloat(0.5))
+	rounded, _ := scaled.Int(nil)
+
+	s := rounded.String()
+	if digits == 0 {
+		return &String{Value: sign + s}
+	}
+	if len(s) <= int(digits) {
+		s = strings.Repeat("0", int(digits)-len(s)+1) + s
+	}
+	point := len(s) - int(digits)
+	return &String{Value: sign + s[:point] + "." + s[point:]}
+}
+
+// parseInt implements the global parseInt(string, radix). Unlike Number(),
+// it reads as many digits as it can and ignores whatever follows, so
+// parseInt("42px") is 42. Without a radix, a 0x prefix means hexadecimal
+// and anything else is decimal.
+func (i *Interpreter) parseInt(this Object, args ...Object) Object {
+	input, err := i.stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	s := trimLeadingWhitespace(input.Value)
+	sign := 1.0
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+
+	radix := 0
+	if argAt(args, 1) != UNDEFINED {
+		n, err := i.toNumber(args[1])
+		if err != nil {
+			return err
+		}
+		radix = int(toInt32(n))
+	}
+	switch {
+	case radix == 0:
+		radix = 10
+		if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+			radix = 16
+			s = s[2:]
+		}
+	case radix < 2 || radix > 36:
+		return &Number{Value: math.NaN()}
+	case radix == 16:
+		if len(s) > 1 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+			s = s[2:]
+		}
+	}
+
+	end := 0
+	for end < len(s) && digitValue(s[end]) < radix {
+		end++
+	}
+	if end == 0 {
+		return &Number{Value: math.NaN()}
+	}
+	return &Number{Value: sign * parseIntegerDigits(s[:end], radix)}
+}
+
+// digitValue returns the value of a digit in bases up to 36, where the
+// letters a to z stand for 10 to 35. Other characters give 36.
+func digitValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'z':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'Z':
+		return int(c-'A') + 10
+	}
+	return 36
+}
+
+// parseFloat implements the global parseFloat(string), which reads the
+// longest prefix of the string that is a decimal number.
+func (i *Interpreter) parseFloat(this Object, args ...Object) Object {
+	input, err := i.stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	s := trimLeadingWhitespace(input.Value)
+
+	end := 0
+	if end < len(s) && (s[end] == '+' || s[end] == '-') {
+		end++
+	}
+	if strings.HasPrefix(s[end:], "Infinity") {
+		return &Number{Value: stringToNumber(s[:end+len("Infinity")])}
+	}
+	digits := 0
+	for end < len(s) && isDigitByte(s[end]) {
+		end++
+		digits++
+	}
+	if end < len(s) && s[end] == '.' {
+		end++
+		for end < len(s) && isDigitByte(s[end]) {
+			end++
+			digits++
+		}
+	}
+	if digits == 0 {
+		return &Number{Value: math.NaN()}
+	}
+	if end < len(s) && (s[end] == 'e' || s[end] == 'E') {
+		exp := end + 1
+		if exp < len(s) && (s[exp] == '+' || s[exp] == '-') {
+			exp++
+		}
+		if exp < len(s) && isDigitByte(s[exp]) {
+			for exp < len(s) && isDigitByte(s[exp]) {
+				exp++
+			}
+			end = exp
+		}
+	}
+	return &Number{Value: stringToNumber(s[:end])}
+}
+
+// trimLeadingWhitespace removes the JavaScript whitespace at the start of s.
+func trimLeadingWhitespace(s string) string {
+	units := utf16Units(s)
+	from := 0
+	for from < len(units) && isWhitespace(units[from]) {
+		from++
+	}
+	return stringFromUnits(units[from:])
+}
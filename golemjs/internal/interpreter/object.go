@@ -0,0 +1,205 @@
+package interpreter
+
+import "strings"
+
+// setupObject defines the methods every object inherits from
+// Object.prototype and Function.prototype. toString and valueOf are the
+// hooks toPrimitive calls when an object is used where a primitive is
+// needed, as in "" + obj or obj * 2; defining your own versions of them
+// changes how your objects convert.
+func (i *Interpreter) setupObject() {
+	i.defineMethod(i.objectPrototype, "toString", func(this Object, args ...Object) Object {
+		tag := toStringTag(this)
+		if !isNullish(this) {
+			custom := i.getProperty(this, symbolToStringTag)
+			if isError(custom) {
+				return custom
+			}
+			if s, ok := custom.(*String); ok {
+				tag = s.Value
+			}
+		}
+		return &String{Value: "[object " + tag + "]"}
+	})
+	// toLocaleString is there for objects to format themselves for a
+	// locale. Without a method of their own, they get toString.
+	i.defineMethod(i.objectPrototype, "toLocaleString", func(this Object, args ...Object) Object {
+		if isNullish(this) {
+			return newTypeError("Cannot convert undefined or null to object")
+		}
+		method := i.getProperty(this, &String{Value: "toString"})
+		if isError(method) {
+			return method
+		}
+		if !isCallable(method) {
+			return newTypeError("%s is not a function", inspect(method, 0))
+		}
+		return i.applyFunction(method, this, nil)
+	})
+	i.defineMethod(i.objectPrototype, "valueOf", func(this Object, args ...Object) Object {
+		if isNullish(this) {
+			return newTypeError("Cannot convert undefined or null to object")
+		}
+		return this
+	})
+
+	i.defineMethod(i.functionPrototype, "toString", func(this Object, args ...Object) Object {
+		switch fn := this.(type) {
+		case *Function:
+			return &String{Value: functionSource(fn)}
+		case *Builtin:
+			return &String{Value: "function " + fn.Name + "() { [native code] }"}
+		}
+		return newTypeError("Function.prototype.toString requires that 'this' be a Function")
+	})
+	i.functionPrototype.Set(symbolHasInstance, i.newBuiltin("[Symbol.hasInstance]", func(this Object, args ...Object) Object {
+		return i.ordinaryHasInstance(this, argAt(args, 0))
+	}))
+}
+
+// instanceOf implements value instanceof target. By default this asks
+// whether target.prototype is on value's prototype chain, but target can
+// answer the question itself with a [Symbol.hasInstance] method.
+func (i *Interpreter) instanceOf(value, target Object) Object {
+	if !isObject(target) {
+		return newTypeError("Right-hand side of 'instanceof' is not an object")
+	}
+	handler := i.getProperty(target, symbolHasInstance)
+	if isError(handler) {
+		return handler
+	}
+	if !isNullish(handler) {
+		result := i.applyFunction(handler, target, []Object{value})
+		if isError(result) {
+			return result
+		}
+		return nativeBoolToBooleanObject(isTruthy(result))
+	}
+	if !isCallable(target) {
+		return newTypeError("Right-hand side of 'instanceof' is not callable")
+	}
+	return i.ordinaryHasInstance(target, value)
+}
+
+// ordinaryHasInstance walks value's prototype chain looking for the
+// prototype property of ctor.
+func (i *Interpreter) ordinaryHasInstance(ctor, value Object) Object {
+	if !isCallable(ctor) || !isObject(value) {
+		return FALSE
+	}
+	proto := i.getProperty(ctor, &String{Value: "prototype"})
+	if isError(proto) {
+		return proto
+	}
+	if !isObject(proto) {
+		return newTypeError("Function has non-object prototype '%s' in instanceof check", inspect(proto, 0))
+	}
+	target := proto.(propertyHolder).properties()
+	for p := value.(propertyHolder).properties().Prototype; p != nil; p = p.Prototype {
+		if p == target {
+			return TRUE
+		}
+	}
+	return FALSE
+}
+
+// toStringTag returns the name Object.prototype.toString uses to describe
+// a value, as in "[object Array]".
+func toStringTag(v Object) string {
+	switch v := v.(type) {
+	case nil, *Undefined:
+		return "Undefined"
+	case *Null:
+		return "Null"
+	case *Array:
+		return "Array"
+	case *Function, *Builtin:
+		return "Function"
+	case *Number:
+		return "Number"
+	case *String:
+		return "String"
+	case *Boolean:
+		return "Boolean"
+	case *Symbol:
+		return "Symbol"
+	case *BigInt:
+		return "BigInt"
+	case *Map:
+		return "Map"
+	case *Set:
+		return "Set"
+	case *RegExp:
+		return "RegExp"
+	case *Date:
+		return "Date"
+	case *ErrorObject:
+		return "Error"
+	case *ArrayBuffer:
+		return "ArrayBuffer"
+	case *TypedArray:
+		return v.kind.name + "Array"
+	case *DataView:
+		return "DataView"
+	case *Promise:
+		return "Promise"
+	case *ModuleNamespace:
+		return "Module"
+	}
+	return "Object"
+}
+
+// functionSource rebuilds the source text of a function from its AST.
+func functionSource(fn *Function) string {
+	params := make([]string, len(fn.Parameters))
+	for idx, p := range fn.Parameters {
+		params[idx] = p.String()
+	}
+	async := ""
+	if fn.Async {
+		async = "async "
+	}
+	if fn.Arrow {
+		return async + "(" + strings.Join(params, ", ") + ") => " + fn.Body.String()
+	}
+	keyword := "function "
+	if fn.Generator {
+		keyword = "function* "
+	}
+	return async + keyword + fn.Name + "(" + strings.Join(params, ", ") + ") " + fn.Body.String()
+}
+
+// setupBoolean creates Boolean.prototype and the Boolean function, which
+// converts any value to a boolean using the same rules as if statements.
+func (i *Interpreter) setupBoolean() {
+	proto := i.booleanPrototype
+
+	thisBoolean := func(this Object, method string) (*Boolean, *Error) {
+		b, ok := this.(*Boolean)
+		if !ok {
+			return nil, newTypeError("Boolean.prototype.%s requires that 'this' be a Boolean", method)
+		}
+		return b, nil
+	}
+	i.defineMethod(proto, "toString", func(this Object, args ...Object) Object {
+		b, err := thisBoolean(this, "toString")
+		if err != nil {
+			return err
+		}
+		return &String{Value: b.Inspect()}
+	})
+	i.defineMethod(proto, "valueOf", func(this Object, args ...Object) Object {
+		b, err := thisBoolean(this, "valueOf")
+		if err != nil {
+			return err
+		}
+		return b
+	})
+
+	ctor := i.newBuiltin("Boolean", func(this Object, args ...Object) Object {
+		return nativeBoolToBooleanObject(len(args) > 0 && isTruthy(args[0]))
+	})
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("Boolean", ctor)
+}
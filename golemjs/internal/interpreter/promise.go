@@ -0,0 +1,501 @@
+package interpreter
+
+// A Promise stands for a value that isn't ready yet. It starts out
+// pending, and is settled once: fulfilled with a value, or rejected with a
+// reason. Callbacks registered with then run once it settles, and never
+// sooner than the next microtask, even if it already has:
+//
+//	const p = new Promise((resolve) => resolve(42));
+//	p.then((value) => console.log(value));  // 42, after the current code
+//
+// then returns a new promise for what its callback returns, so promises
+// chain. Resolving a promise with another promise, or any object with a
+// then method, makes it follow that one instead of being fulfilled with
+// it. A callback that fails rejects the promise then returned; if nothing
+// ever handles a rejection, the event loop reports it.
+
+type promiseState int
+
+const (
+	promisePending promiseState = iota
+	promiseFulfilled
+	promiseRejected
+)
+
+// Promise is the object created by new Promise(executor).
+type Promise struct {
+	Hash
+	state  promiseState
+	result Object // the value or reason, once settled
+
+	// reactions holds what to do once a pending promise settles.
+	reactions []*promiseReaction
+
+	// handled records that something is waiting for the promise, so that
+	// rejecting it isn't reported as unhandled.
+	handled bool
+}
+
+func (p *Promise) Type() ObjectType { return PROMISE_OBJ }
+func (p *Promise) Inspect() string {
+	switch p.state {
+	case promiseFulfilled:
+		return "Promise {" + p.result.Inspect() + "}"
+	case promiseRejected:
+		return "Promise {<rejected> " + p.result.Inspect() + "}"
+	}
+	return "Promise {<pending>}"
+}
+
+// promiseCapability is a promise together with the functions that resolve
+// and reject it, as new Promise hands them to its executor.
+type promiseCapability struct {
+	promise         Object
+	resolve, reject Object
+}
+
+// promiseReaction is a callback waiting for a promise to settle, and the
+// capability for the promise then returned, which gets the callback's
+// result. A missing callback passes the value or reason straight on.
+type promiseReaction struct {
+	capability  *promiseCapability
+	onFulfilled Object
+	onRejected  Object
+}
+
+// newPromise creates a pending promise.
+func (i *Interpreter) newPromise() *Promise {
+	return &Promise{Hash: Hash{Prototype: i.promisePrototype}}
+}
+
+// resolvingFunctions creates the resolve and reject functions for p. Only
+// the first call to either has any effect.
+func (i *Interpreter) resolvingFunctions(p *Promise) (resolve, reject *Builtin) {
+	alreadyResolved := false
+	resolve = i.newBuiltin("", func(this Object, args ...Object) Object {
+		if !alreadyResolved {
+			alreadyResolved = true
+			i.resolvePromise(p, argAt(args, 0))
+		}
+		return UNDEFINED
+	})
+	reject = i.newBuiltin("", func(this Object, args ...Object) Object {
+		if !alreadyResolved {
+			alreadyResolved = true
+			i.rejectPromise(p, argAt(args, 0))
+		}
+		return UNDEFINED
+	})
+	return resolve, reject
+}
+
+// resolvePromise resolves p with resolution. A thenable is followed, by
+// calling its then method in a microtask; anything else fulfills p.
+func (i *Interpreter) resolvePromise(p *Promise, resolution Object) {
+	if resolution == p {
+		i.rejectPromise(p, i.thrownValue(newTypeError("Chaining cycle detected for promise #<Promise>")))
+		return
+	}
+	if !isObject(resolution) {
+		i.settlePromise(p, promiseFulfilled, resolution)
+		return
+	}
+	then := i.getProperty(resolution, &String{Value: "then"})
+	if err, ok := then.(*Error); ok {
+		i.rejectPromise(p, i.thrownValue(err))
+		return
+	}
+	if !isCallable(then) {
+		i.settlePromise(p, promiseFulfilled, resolution)
+		return
+	}
+	i.queueMicrotask(func() *Error {
+		resolve, reject := i.resolvingFunctions(p)
+		if err, ok := i.applyFunction(then, resolution, []Object{resolve, reject}).(*Error); ok {
+			i.applyFunction(reject, UNDEFINED, []Object{i.thrownValue(err)})
+		}
+		return nil
+	})
+}
+
+// rejectPromise rejects p with reason, noting the rejection for the event
+// loop to report if nothing handles it.
+func (i *Interpreter) rejectPromise(p *Promise, reason Object) {
+	if !p.handled {
+		i.loop.rejections = append(i.loop.rejections, p)
+	}
+	i.settlePromise(p, promiseRejected, reason)
+}
+
+// settlePromise fulfills or rejects p and queues its reactions.
+func (i *Interpreter) settlePromise(p *Promise, state promiseState, result Object) {
+	if p.state != promisePending {
+		return
+	}
+	p.state, p.result = state, result
+	reactions := p.reactions
+	p.reactions = nil
+	for _, reaction := range reactions {
+		i.queueReaction(reaction, state, result)
+	}
+}
+
+// queueReaction queues the job that runs a reaction to a settled promise.
+func (i *Interpreter) queueReaction(reaction *promiseReaction, state promiseState, result Object) {
+	i.queueMicrotask(func() *Error {
+		handler := reaction.onFulfilled
+		if state == promiseRejected {
+			handler = reaction.onRejected
+		}
+		var outcome Object
+		rejected := false
+		switch {
+		case isCallable(handler):
+			outcome = i.applyFunction(handler, UNDEFINED, []Object{result})
+			if err, ok := outcome.(*Error); ok {
+				outcome, rejected = i.thrownValue(err), true
+			}
+		default:
+			outcome, rejected = result, state == promiseRejected
+		}
+		if reaction.capability == nil {
+			return nil
+		}
+		settle := reaction.capability.resolve
+		if rejected {
+			settle = reaction.capability.reject
+		}
+		if err, ok := i.applyFunction(settle, UNDEFINED, []Object{outcome}).(*Error); ok {
+			return err
+		}
+		return nil
+	})
+}
+
+// performPromiseThen registers callbacks for when p settles, with the
+// results going to capability, which may be nil.
+func (i *Interpreter) performPromiseThen(p *Promise, onFulfilled, onRejected Object, capability *promiseCapability) {
+	reaction := &promiseReaction{capability: capability, onFulfilled: onFulfilled, onRejected: onRejected}
+	if p.state == promisePending {
+		p.reactions = append(p.reactions, reaction)
+	} else {
+		i.queueReaction(reaction, p.state, p.result)
+	}
+	p.handled = true
+}
+
+// newPromiseCapability creates a promise with ctor, which is usually
+// Promise itself but can be any constructor that calls its executor the
+// way Promise does.
+func (i *Interpreter) newPromiseCapability(ctor Object) (*promiseCapability, *Error) {
+	if ctor == i.promiseConstructor {
+		p := i.newPromise()
+		resolve, reject := i.resolvingFunctions(p)
+		return &promiseCapability{promise: p, resolve: resolve, reject: reject}, nil
+	}
+	if !isConstructor(ctor) {
+		return nil, newTypeError("%s is not a constructor", inspect(ctor, 0))
+	}
+	capability := &promiseCapability{resolve: UNDEFINED, reject: UNDEFINED}
+	executor := i.newBuiltin("", func(this Object, args ...Object) Object {
+		if capability.resolve != UNDEFINED || capability.reject != UNDEFINED {
+			return newTypeError("Promise executor has already been invoked with non-undefined arguments")
+		}
+		capability.resolve, capability.reject = argAt(args, 0), argAt(args, 1)
+		return UNDEFINED
+	})
+	promise := i.construct(ctor, []Object{executor})
+	if err, ok := promise.(*Error); ok {
+		return nil, err
+	}
+	if !isCallable(capability.resolve) || !isCallable(capability.reject) {
+		return nil, newTypeError("Promise resolve or reject function is not callable")
+	}
+	capability.promise = promise
+	return capability, nil
+}
+
+// promiseResolve implements Promise.resolve(value) for ctor: a promise
+// made by ctor is returned as it is, and anything else is wrapped in a
+// new promise.
+func (i *Interpreter) promiseResolve(ctor, value Object) Object {
+	if p, ok := value.(*Promise); ok {
+		valueCtor := i.getProperty(p, &String{Value: "constructor"})
+		if isError(valueCtor) {
+			return valueCtor
+		}
+		if valueCtor == ctor {
+			return p
+		}
+	}
+	capability, err := i.newPromiseCapability(ctor)
+	if err != nil {
+		return err
+	}
+	if result := i.applyFunction(capability.resolve, UNDEFINED, []Object{value}); isError(result) {
+		return result
+	}
+	return capability.promise
+}
+
+// invokeThen calls the then method of a promise or thenable.
+func (i *Interpreter) invokeThen(p Object, onFulfilled, onRejected Object) Object {
+	then := i.getProperty(p, &String{Value: "then"})
+	if isError(then) {
+		return then
+	}
+	if !isCallable(then) {
+		return newTypeError("%s is not a function", inspect(then, 0))
+	}
+	return i.applyFunction(then, p, []Object{onFulfilled, onRejected})
+}
+
+// isConstructor reports whether new can be used with fn.
+func isConstructor(fn Object) bool {
+	switch fn := fn.(type) {
+	case *Function:
+		return !fn.Arrow && !fn.Generator && !fn.Async
+	case *Builtin:
+		return !fn.notConstructor
+	}
+	return false
+}
+
+// setupPromise creates Promise.prototype and the Promise constructor.
+func (i *Interpreter) setupPromise() {
+	proto := i.promisePrototype
+
+	i.defineMethod(proto, "then", func(this Object, args ...Object) Object {
+		p, ok := this.(*Promise)
+		if !ok {
+			return newTypeError("Method Promise.prototype.then called on incompatible receiver %s", inspectReceiver(this))
+		}
+		capability, err := i.newPromiseCapability(i.promiseConstructor)
+		if err != nil {
+			return err
+		}
+		i.performPromiseThen(p, argAt(args, 0), argAt(args, 1), capability)
+		return capability.promise
+	})
+	i.defineMethod(proto, "catch", func(this Object, args ...Object) Object {
+		if isNullish(this) {
+			return newTypeError("Cannot read properties of %s (reading 'then')", this.Inspect())
+		}
+		return i.invokeThen(this, UNDEFINED, argAt(args, 0))
+	})
+	// finally runs its callback however the promise settles, and passes
+	// the value or reason on, unless the callback fails or returns a
+	// promise that rejects.
+	i.defineMethod(proto, "finally", func(this Object, args ...Object) Object {
+		if !isObject(this) {
+			return newTypeError("Method Promise.prototype.finally called on incompatible receiver %s", inspectReceiver(this))
+		}
+		onFinally := argAt(args, 0)
+		if !isCallable(onFinally) {
+			return i.invokeThen(this, onFinally, onFinally)
+		}
+		ctor := i.getProperty(this, &String{Value: "constructor"})
+		if isError(ctor) {
+			return ctor
+		}
+		if ctor == UNDEFINED {
+			ctor = i.promiseConstructor
+		}
+		after := func(settle func() Object) BuiltinFunction {
+			return func(this Object, args ...Object) Object {
+				result := i.applyFunction(onFinally, UNDEFINED, nil)
+				if isError(result) {
+					return result
+				}
+				p := i.promiseResolve(ctor, result)
+				if isError(p) {
+					return p
+				}
+				return i.invokeThen(p, i.newBuiltin("", func(this Object, _ ...Object) Object {
+					return settle()
+				}), UNDEFINED)
+			}
+		}
+		thenFinally := i.newBuiltin("", func(this Object, args ...Object) Object {
+			value := argAt(args, 0)
+			return after(func() Object { return value })(this, args...)
+		})
+		catchFinally := i.newBuiltin("", func(this Object, args ...Object) Object {
+			reason := argAt(args, 0)
+			return after(func() Object { return thrownError(reason) })(this, args...)
+		})
+		return i.invokeThen(this, thenFinally, catchFinally)
+	})
+	proto.Set(symbolToStringTag, &String{Value: "Promise"})
+
+	ctor := i.newBuiltin("Promise", func(this Object, args ...Object) Object {
+		executor := argAt(args, 0)
+		if !isCallable(executor) {
+			return newTypeError("Promise resolver %s is not a function", inspect(executor, 0))
+		}
+		p := i.newPromise()
+		resolve, reject := i.resolvingFunctions(p)
+		if err, ok := i.applyFunction(executor, UNDEFINED, []Object{resolve, reject}).(*Error); ok {
+			i.applyFunction(reject, UNDEFINED, []Object{i.thrownValue(err)})
+		}
+		return p
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.promiseConstructor = ctor
+
+	i.defineMethod(&ctor.Hash, "resolve", func(this Object, args ...Object) Object {
+		if !isObject(this) {
+			return newTypeError("PromiseResolve called on non-object")
+		}
+		return i.promiseResolve(this, argAt(args, 0))
+	})
+	i.defineMethod(&ctor.Hash, "reject", func(this Object, args ...Object) Object {
+		capability, err := i.newPromiseCapability(this)
+		if err != nil {
+			return err
+		}
+		if result := i.applyFunction(capability.reject, UNDEFINED, []Object{argAt(args, 0)}); isError(result) {
+			return result
+		}
+		return capability.promise
+	})
+	i.defineMethod(&ctor.Hash, "withResolvers", func(this Object, args ...Object) Object {
+		capability, err := i.newPromiseCapability(this)
+		if err != nil {
+			return err
+		}
+		result := NewHash(i.objectPrototype)
+		result.Set(&String{Value: "promise"}, capability.promise)
+		result.Set(&String{Value: "resolve"}, capability.resolve)
+		result.Set(&String{Value: "reject"}, capability.reject)
+		return result
+	})
+	i.defineMethod(&ctor.Hash, "all", i.promiseCombinator("all"))
+	i.defineMethod(&ctor.Hash, "allSettled", i.promiseCombinator("allSettled"))
+	i.defineMethod(&ctor.Hash, "any", i.promiseCombinator("any"))
+	i.defineMethod(&ctor.Hash, "race", i.promiseCombinator("race"))
+
+	i.env.Set("Promise", ctor)
+}
+
+// promiseCombinator implements Promise.all, allSettled, any and race,
+// which combine the promises in an iterable into one:
+//
+//   - all fulfills with every value once all have fulfilled, and rejects
+//     as soon as one rejects.
+//   - allSettled waits for all of them, and fulfills with a description
+//     of how each settled.
+//   - any fulfills as soon as one fulfills, and rejects once all have
+//     rejected.
+//   - race settles the same way as the first to settle.
+//
+// Values that aren't promises are treated as already fulfilled ones.
+func (i *Interpreter) promiseCombinator(name string) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		if !isObject(this) {
+			return newTypeError("Promise.%s called on non-object", name)
+		}
+		capability, err := i.newPromiseCapability(this)
+		if err != nil {
+			return err
+		}
+		// Failures from here on reject the returned promise rather than
+		// being thrown.
+		rejectWith := func(err *Error) Object {
+			if result := i.applyFunction(capability.reject, UNDEFINED, []Object{i.thrownValue(err)}); isError(result) {
+				return result
+			}
+			return capability.promise
+		}
+		resolve := i.getProperty(this, &String{Value: "resolve"})
+		if err, ok := resolve.(*Error); ok {
+			return rejectWith(err)
+		}
+		if !isCallable(resolve) {
+			return rejectWith(newTypeError("Promise resolve or reject function is not callable"))
+		}
+
+		var values []Object
+		// remaining counts the promises still to settle, plus one for the
+		// iteration itself, so that the result can't be settled before
+		// every promise has been seen.
+		remaining := 1
+		finish := func() {
+			remaining--
+			if remaining > 0 {
+				return
+			}
+			if name == "any" {
+				i.applyFunction(capability.reject, UNDEFINED, []Object{i.newAggregateError(values)})
+				return
+			}
+			i.applyFunction(capability.resolve, UNDEFINED, []Object{i.newArray(values)})
+		}
+		// element returns a callback that records a result at idx, once.
+		element := func(idx int, record func(value Object) Object) *Builtin {
+			called := false
+			return i.newBuiltin("", func(this Object, args ...Object) Object {
+				if !called {
+					called = true
+					values[idx] = record(argAt(args, 0))
+					finish()
+				}
+				return UNDEFINED
+			})
+		}
+		settled := func(status, key string) func(Object) Object {
+			return func(value Object) Object {
+				obj := NewHash(i.objectPrototype)
+				obj.Set(&String{Value: "status"}, &String{Value: status})
+				obj.Set(&String{Value: key}, value)
+				return obj
+			}
+		}
+		same := func(value Object) Object { return value }
+
+		iterErr := i.iterate(argAt(args, 0), func(value Object) *Error {
+			next := i.applyFunction(resolve, this, []Object{value})
+			if err, ok := next.(*Error); ok {
+				return err
+			}
+			idx := len(values)
+			var onFulfilled, onRejected Object = capability.resolve, capability.reject
+			switch name {
+			case "all":
+				onFulfilled = element(idx, same)
+			case "allSettled":
+				onFulfilled = element(idx, settled("fulfilled", "value"))
+				onRejected = element(idx, settled("rejected", "reason"))
+			case "any":
+				onRejected = element(idx, same)
+			}
+			if name != "race" {
+				values = append(values, UNDEFINED)
+				remaining++
+			}
+			if err, ok := i.invokeThen(next, onFulfilled, onRejected).(*Error); ok {
+				return err
+			}
+			return nil
+		})
+		if iterErr != nil {
+			return rejectWith(iterErr)
+		}
+		if name != "race" {
+			finish()
+		}
+		return capability.promise
+	}
+}
+
+// newAggregateError creates the reason Promise.any rejects with when every
+// promise has rejected, which holds all of their reasons. Its name comes
+// from its prototype, so like any error it converts to a string such as
+// "AggregateError: All promises were rejected".
+func (i *Interpreter) newAggregateError(errors []Object) Object {
+	err := i.newErrorObject("AggregateError", "All promises were rejected")
+	err.Set(&String{Value: "errors"}, i.newArray(errors))
+	return err
+}
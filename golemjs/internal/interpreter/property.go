@@ -0,0 +1,387 @@
+package interpreter
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// propertyHolder is implemented by every value that can carry properties.
+// Arrays, functions and builtins embed a Hash for their properties, so they
+// get this method for free.
+type propertyHolder interface {
+	properties() *Hash
+}
+
+func (h *Hash) properties() *Hash { return h }
+
+// accessorHolder is implemented by objects with read-only properties that
+// are computed when read, like a regular expression's flags or a typed
+// array's length. accessor reports false for any other name.
+type accessorHolder interface {
+	accessor(name string) (Object, bool)
+}
+
+// NewHash creates an empty object that inherits from proto.
+func NewHash(proto *Hash) *Hash {
+	return &Hash{Prototype: proto}
+}
+
+// GetOwn looks up a property on the object itself, ignoring its prototype.
+func (h *Hash) GetOwn(key Object) (Object, bool) {
+	slot, ok := h.layout().lookup(key.(Hashable).HashKey())
+	if !ok {
+		return nil, false
+	}
+	return h.values[slot], true
+}
+
+// Get looks up a property on the object and then along its prototype chain.
+func (h *Hash) Get(key Object) (Object, bool) {
+	hashKey := key.(Hashable).HashKey()
+	for obj := h; obj != nil; obj = obj.Prototype {
+		if slot, ok := obj.layout().lookup(hashKey); ok {
+			return obj.values[slot], true
+		}
+	}
+	return nil, false
+}
+
+// Set creates or updates a property on the object itself. A new property
+// moves the object to the shape with one more slot.
+func (h *Hash) Set(key Object, value Object) {
+	hashKey := key.(Hashable).HashKey()
+	s := h.layout()
+	if slot, ok := s.lookup(hashKey); ok {
+		h.values[slot] = value
+		return
+	}
+	h.shape = s.withKey(key, hashKey)
+	h.values = append(h.values, value)
+}
+
+// Delete removes a property from the object itself. It reports whether the
+// property existed. An object that has had a property deleted keeps a
+// dictionary shape of its own from then on.
+func (h *Hash) Delete(key Object) bool {
+	s := h.layout()
+	slot, ok := s.lookup(key.(Hashable).HashKey())
+	if !ok {
+		return false
+	}
+	if !s.dictionary {
+		h.shape = s.toDictionary()
+	}
+	h.shape.remove(slot)
+	h.values = slices.Delete(h.values, slot, slot+1)
+	return true
+}
+
+// OwnKeys returns the object's own property keys in JavaScript's order:
+// keys that look like array indices come first in ascending numeric order,
+// followed by the remaining string keys in the order they were added, and
+// finally the symbol keys in the order they were added.
+func (h *Hash) OwnKeys() []Object {
+	var indices []*String
+	var others, symbols []Object
+	shapeKeys := h.layout().keys
+	for _, key := range shapeKeys {
+		s, ok := key.(*String)
+		if !ok {
+			symbols = append(symbols, key)
+			continue
+		}
+		if _, isIndex := arrayIndex(s.Value); isIndex {
+			indices = append(indices, s)
+			continue
+		}
+		others = append(others, key)
+	}
+	sort.Slice(indices, func(a, b int) bool {
+		x, _ := arrayIndex(indices[a].Value)
+		y, _ := arrayIndex(indices[b].Value)
+		return x < y
+	})
+	keys := make([]Object, 0, len(shapeKeys))
+	for _, s := range indices {
+		keys = append(keys, s)
+	}
+	keys = append(keys, others...)
+	return append(keys, symbols...)
+}
+
+// maxArrayIndex is the largest valid array index. Array lengths are limited
+// to 2^32 - 1, so indices run up to one less than that.
+const maxArrayIndex = 1<<32 - 2
+
+// arrayIndex reports whether key is the canonical string form of an array
+// index ("0", "1", ... but not "01" or "-1") and returns the index.
+func arrayIndex(key string) (int64, bool) {
+	if key == "" || len(key) > 10 || (len(key) > 1 && key[0] == '0') {
+		return 0, false
+	}
+	for idx := 0; idx < len(key); idx++ {
+		if key[idx] < '0' || key[idx] > '9' {
+			return 0, false
+		}
+	}
+	n, err := strconv.ParseInt(key, 10, 64)
+	if err != nil || n > maxArrayIndex {
+		return 0, false
+	}
+	return n, true
+}
+
+// getProperty reads the property key from obj, following the prototype
+// chain. Missing properties read as undefined.
+func (i *Interpreter) getProperty(obj Object, key Object) Object {
+	// arr[i] is common enough to be worth not turning i into a string
+	// and back.
+	if arr, ok := obj.(*Array); ok {
+		if n, ok := key.(*Number); ok {
+			idx := int(n.Value)
+			if float64(idx) == n.Value && idx >= 0 && idx < arr.len() {
+				if e := arr.get(idx); e != nil {
+					return e
+				}
+			}
+		}
+	}
+	k, err := i.toPropertyKey(key)
+	if err != nil {
+		return err
+	}
+	name := keyString(k)
+	switch obj := obj.(type) {
+	case *Null, *Undefined:
+		return newTypeError("Cannot read properties of %s (reading '%s')", obj.Inspect(), name)
+	case *Array:
+		if name == "length" {
+			return &Number{Value: float64(obj.len())}
+		}
+		if idx, ok := arrayIndex(name); ok && idx < int64(obj.len()) {
+			if e := obj.get(int(idx)); e != nil {
+				return e
+			}
+		}
+	case *String:
+		units := obj.units()
+		if name == "length" {
+			return &Number{Value: float64(len(units))}
+		}
+		if idx, ok := arrayIndex(name); ok && idx < int64(len(units)) {
+			return newStringFromUnits(units[idx : idx+1])
+		}
+		if value, ok := i.stringPrototype.Get(k); ok {
+			return value
+		}
+		return UNDEFINED
+	case *Number:
+		if value, ok := i.numberPrototype.Get(k); ok {
+			return value
+		}
+		return UNDEFINED
+	case *Boolean:
+		if value, ok := i.booleanPrototype.Get(k); ok {
+			return value
+		}
+		return UNDEFINED
+	case *BigInt:
+		if value, ok := i.bigintPrototype.Get(k); ok {
+			return value
+		}
+		return UNDEFINED
+	case *Map:
+		if name == "size" {
+			return &Number{Value: float64(obj.table.size())}
+		}
+	case *Set:
+		if name == "size" {
+			return &Number{Value: float64(obj.table.size())}
+		}
+	case *TypedArray:
+		if s, ok := k.(*String); ok {
+			if n, ok := canonicalNumericIndex(s.Value); ok {
+				if idx, ok := obj.index(n); ok {
+					return obj.get(idx)
+				}
+				return UNDEFINED
+			}
+		}
+	case *ModuleNamespace:
+		if k == symbolToStringTag {
+			return &String{Value: "Module"}
+		}
+		if _, ok := k.(*String); ok {
+			return obj.get(name)
+		}
+		return UNDEFINED
+	case *Symbol:
+		if name == "description" {
+			if !obj.HasDescription {
+				return UNDEFINED
+			}
+			return &String{Value: obj.Description}
+		}
+		if value, ok := i.symbolPrototype.Get(k); ok {
+			return value
+		}
+		return UNDEFINED
+	}
+	if holder, ok := obj.(accessorHolder); ok {
+		if value, ok := holder.accessor(name); ok {
+			return value
+		}
+	}
+	if holder, ok := obj.(propertyHolder); ok {
+		if value, ok := holder.properties().Get(k); ok {
+			return value
+		}
+	}
+	return UNDEFINED
+}
+
+// setProperty writes the property key on obj. Writes to primitives such as
+// numbers are silently ignored, as in JavaScript.
+func (i *Interpreter) setProperty(obj Object, key Object, value Object) *Error {
+	k, err := i.toPropertyKey(key)
+	if err != nil {
+		return err
+	}
+	name := keyString(k)
+	switch obj := obj.(type) {
+	case *Null, *Undefined:
+		return newTypeError("Cannot set properties of %s (setting '%s')", obj.Inspect(), name)
+	case *Array:
+		if name == "length" {
+			return i.setArrayLength(obj, value)
+		}
+		if idx, ok := arrayIndex(name); ok {
+			obj.setElement(idx, value)
+			return nil
+		}
+	case *RegExp:
+		if name == "lastIndex" {
+			obj.lastIndex = value
+			return nil
+		}
+	case *ModuleNamespace:
+		// Only the module itself can change its variables.
+		if _, ok := k.(*String); ok && obj.has(name) {
+			return newTypeError("Cannot assign to read only property '%s' of object '[object Module]'", name)
+		}
+		return newTypeError("Cannot add property %s, object is not extensible", name)
+	case *HostObject:
+		if _, ok := k.(*String); ok {
+			if handled, err := obj.Host.Set(name, value); handled {
+				return err
+			}
+		}
+	case *TypedArray:
+		// Every numeric name is an element index, so writes outside the
+		// array, and to names like "1.5", are ignored rather than creating
+		// properties.
+		if s, ok := k.(*String); ok {
+			if n, ok := canonicalNumericIndex(s.Value); ok {
+				converted, err := i.convertElement(obj.kind, value)
+				if err != nil {
+					return err
+				}
+				if idx, ok := obj.index(n); ok {
+					obj.set(idx, converted)
+				}
+				return nil
+			}
+		}
+	}
+	if holder, ok := obj.(accessorHolder); ok {
+		if _, ok := holder.accessor(name); ok {
+			// Accessors like a regular expression's flags can only be read.
+			return nil
+		}
+	}
+	if holder, ok := obj.(propertyHolder); ok {
+		holder.properties().Set(k, value)
+	}
+	return nil
+}
+
+// deleteProperty removes the own property key from obj. Deleting an array
+// element leaves a hole rather than shifting the elements after it. It
+// reports false if the property can't be deleted, like an array's length,
+// and true otherwise, whether or not there was such a property.
+func (i *Interpreter) deleteProperty(obj Object, key Object) (bool, *Error) {
+	k, err := i.toPropertyKey(key)
+	if err != nil {
+		return false, err
+	}
+	name := keyString(k)
+	switch obj := obj.(type) {
+	case *Array:
+		if idx, ok := arrayIndex(name); ok {
+			if idx < int64(obj.len()) {
+				obj.deleteElement(int(idx))
+			}
+			return true, nil
+		}
+		if name == "length" {
+			return false, nil
+		}
+	case *String:
+		// A string's characters and length are fixed.
+		if idx, ok := arrayIndex(name); (ok && idx < int64(len(obj.units()))) || name == "length" {
+			return false, nil
+		}
+	case *HostObject:
+		// The host's properties can't be deleted, only overwritten.
+		if _, ok := obj.Host.Get(name); ok {
+			return false, nil
+		}
+	case *TypedArray:
+		// Elements can't be deleted, only overwritten.
+		if s, ok := k.(*String); ok {
+			if _, ok := canonicalNumericIndex(s.Value); ok {
+				return false, nil
+			}
+		}
+	}
+	if holder, ok := obj.(accessorHolder); ok {
+		if _, ok := holder.accessor(name); ok {
+			return false, nil
+		}
+	}
+	if holder, ok := obj.(propertyHolder); ok {
+		holder.properties().Delete(k)
+	}
+	return true, nil
+}
+
+// ownPropertyKeys returns the keys of the own enumerable properties of
+// value, which object spread and rest copy. For arrays and strings these
+// start with the indices of their elements.
+func (i *Interpreter) ownPropertyKeys(value Object) []Object {
+	var keys []Object
+	switch value := value.(type) {
+	case *Array:
+		w := value.walk(0, value.len(), false)
+		for idx, _, ok := w.next(); ok; idx, _, ok = w.next() {
+			keys = append(keys, &String{Value: strconv.Itoa(idx)})
+		}
+	case *String:
+		for idx := range value.units() {
+			keys = append(keys, &String{Value: strconv.Itoa(idx)})
+		}
+	case *TypedArray:
+		keys = typedArrayKeys(value)
+	case *ModuleNamespace:
+		for _, name := range value.names {
+			keys = append(keys, &String{Value: name})
+		}
+	case *HostObject:
+		keys = value.hostKeys()
+	}
+	if _, ok := value.(propertyHolder); ok {
+		keys = append(keys, visibleKeys(value)...)
+	}
+	return keys
+}
@@ -0,0 +1,923 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/regex"
+)
+
+// Regular expressions are objects created by a literal such as /ab+c/gi or
+// by the RegExp constructor. The matching itself is done by the regex
+// package; this file gives it the interface scripts use.
+//
+// A RegExp with the g (global) or y (sticky) flag remembers where its last
+// match ended in its lastIndex property, and starts the next search there:
+//
+//	const re = /o/g;
+//	re.exec("foo").index;  // 1
+//	re.exec("foo").index;  // 2
+//	re.exec("foo");        // null, and lastIndex goes back to 0
+//
+// The string methods that take a pattern (match, matchAll, replace,
+// replaceAll, search and split) don't know about RegExp objects. Instead,
+// they look for a method under a well-known symbol on their argument, such
+// as [Symbol.replace], and hand the work over to it. RegExp.prototype
+// defines those methods, but any object can, which is how a script can
+// make its own kind of pattern.
+
+// RegExp is a regular expression object.
+type RegExp struct {
+	Hash
+	Source string // the pattern, escaped so it can appear between slashes
+	re     *regex.Regexp
+
+	// lastIndex is kept apart from the other properties, as it isn't
+	// enumerable: it doesn't show up when the object is inspected or
+	// spread.
+	lastIndex Object
+}
+
+func (r *RegExp) Type() ObjectType { return REGEXP_OBJ }
+func (r *RegExp) Inspect() string  { return "/" + r.Source + "/" + r.re.Flags().String() }
+
+// accessor returns the value of the properties that describe a regular
+// expression: its source, its flags, and one boolean per flag. In
+// JavaScript these are getters on RegExp.prototype.
+func (r *RegExp) accessor(name string) (Object, bool) {
+	flags := r.re.Flags()
+	switch name {
+	case "lastIndex":
+		return r.lastIndex, true
+	case "source":
+		return &String{Value: r.Source}, true
+	case "flags":
+		return &String{Value: flags.String()}, true
+	case "hasIndices":
+		return nativeBoolToBooleanObject(flags.HasIndices), true
+	case "global":
+		return nativeBoolToBooleanObject(flags.Global), true
+	case "ignoreCase":
+		return nativeBoolToBooleanObject(flags.IgnoreCase), true
+	case "multiline":
+		return nativeBoolToBooleanObject(flags.Multiline), true
+	case "dotAll":
+		return nativeBoolToBooleanObject(flags.DotAll), true
+	case "unicode":
+		return nativeBoolToBooleanObject(flags.Unicode), true
+	case "sticky":
+		return nativeBoolToBooleanObject(flags.Sticky), true
+	}
+	return nil, false
+}
+
+// RegExpStringIterator is returned by matchAll. Each step runs the
+// regular expression again and yields the next match.
+type RegExpStringIterator struct {
+	Hash
+	matcher Object
+	str     *String
+	global  bool
+	unicode bool
+	done    bool
+}
+
+func (it *RegExpStringIterator) Type() ObjectType { return REGEXP_STRING_ITERATOR_OBJ }
+func (it *RegExpStringIterator) Inspect() string  { return "RegExp String Iterator {}" }
+
+// newRegExp compiles pattern with the given flags into a RegExp object.
+func (i *Interpreter) newRegExp(pattern *String, flags string) (*RegExp, *Error) {
+	f, ok := regex.ParseFlags(flags)
+	if !ok {
+		return nil, newSyntaxError("Invalid flags supplied to RegExp constructor '%s'", flags)
+	}
+	source := escapeRegExpPattern(pattern.units())
+	re, err := regex.Compile(pattern.units(), f)
+	if err != nil {
+		return nil, newSyntaxError("Invalid regular expression: /%s/%s: %s", source, flags, err)
+	}
+	return &RegExp{Hash: Hash{Prototype: i.regexpPrototype}, Source: source, re: re, lastIndex: &Number{Value: 0}}, nil
+}
+
+// evalRegExpLiteral creates a RegExp object from a literal. The parser has
+// already checked the pattern, and its compiled form is shared by every
+// object the literal creates.
+func (i *Interpreter) evalRegExpLiteral(node *ast.RegExpLiteral) Object {
+	re, ok := i.regexCache[node]
+	if !ok {
+		flags, _ := regex.ParseFlags(node.Flags)
+		var err error
+		re, err = regex.Compile(utf16Units(node.Pattern), flags)
+		if err != nil {
+			return newSyntaxError("Invalid regular expression: %s: %s", node.String(), err)
+		}
+		if i.regexCache == nil {
+			i.regexCache = map[*ast.RegExpLiteral]*regex.Regexp{}
+		}
+		i.regexCache[node] = re
+	}
+	return &RegExp{Hash: Hash{Prototype: i.regexpPrototype}, Source: node.Pattern, re: re, lastIndex: &Number{Value: 0}}
+}
+
+// escapeRegExpPattern writes a pattern so that it reads back as the same
+// pattern between slashes: a / outside a character class is escaped, as
+// are line breaks, and the empty pattern becomes (?:) rather than the
+// comment //.
+func escapeRegExpPattern(pattern []uint16) string {
+	if len(pattern) == 0 {
+		return "(?:)"
+	}
+	out := []uint16{}
+	inClass := false
+	for idx := 0; idx < len(pattern); idx++ {
+		switch c := pattern[idx]; c {
+		case '\\':
+			out = append(out, c)
+			if idx+1 < len(pattern) {
+				idx++
+				out = append(out, pattern[idx])
+			}
+			continue
+		case '/':
+			if !inClass {
+				out = append(out, '\\')
+			}
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '\n':
+			out = append(out, utf16Units(`\n`)...)
+			continue
+		case '\r':
+			out = append(out, utf16Units(`\r`)...)
+			continue
+		case 0x2028:
+			out = append(out, utf16Units(`\u2028`)...)
+			continue
+		case 0x2029:
+			out = append(out, utf16Units(`\u2029`)...)
+			continue
+		}
+		out = append(out, pattern[idx])
+	}
+	return stringFromUnits(out)
+}
+
+// regExpCreate builds a RegExp from a pattern and flags given as any
+// values, as the RegExp constructor does. A RegExp pattern contributes its
+// source, and its flags too unless others are given.
+func (i *Interpreter) regExpCreate(pattern, flags Object) Object {
+	source := &String{}
+	if r, ok := pattern.(*RegExp); ok {
+		source = &String{Value: r.Source}
+		if flags == UNDEFINED {
+			flags = &String{Value: r.re.Flags().String()}
+		}
+	} else if pattern != UNDEFINED {
+		var err *Error
+		if source, err = i.toString(pattern); err != nil {
+			return err
+		}
+	}
+	flagString := &String{}
+	if flags != UNDEFINED {
+		var err *Error
+		if flagString, err = i.toString(flags); err != nil {
+			return err
+		}
+	}
+	r, err := i.newRegExp(source, flagString.Value)
+	if err != nil {
+		return err
+	}
+	return r
+}
+
+// isRegExp reports whether value should be treated as a regular
+// expression: an object whose [Symbol.match] property is truthy, or
+// without one, a RegExp.
+func (i *Interpreter) isRegExp(value Object) (bool, *Error) {
+	if !isObject(value) {
+		return false, nil
+	}
+	matcher := i.getProperty(value, symbolMatch)
+	if err, ok := matcher.(*Error); ok {
+		return false, err
+	}
+	if matcher != UNDEFINED {
+		return isTruthy(matcher), nil
+	}
+	_, ok := value.(*RegExp)
+	return ok, nil
+}
+
+// regExpExec runs a regular expression once, through its exec method, so
+// that an object replacing exec changes how every other method matches.
+// The result is a match array or null.
+func (i *Interpreter) regExpExec(rx Object, s *String) Object {
+	exec := i.getProperty(rx, &String{Value: "exec"})
+	if isError(exec) {
+		return exec
+	}
+	if isCallable(exec) {
+		result := i.applyFunction(exec, rx, []Object{s})
+		if isError(result) || result == NULL || isObject(result) {
+			return result
+		}
+		return newTypeError("%s is not an object or null", inspect(result, 0))
+	}
+	r, ok := rx.(*RegExp)
+	if !ok {
+		return newTypeError("Method RegExp.prototype.exec called on incompatible receiver %s", inspectReceiver(rx))
+	}
+	return i.regExpBuiltinExec(r, s)
+}
+
+// regExpBuiltinExec is the matching behind exec. Without the g or y flag,
+// it searches from the start of the string; with them, from lastIndex,
+// which it then moves to the end of the match, or back to 0 if there is
+// none. With y, the match must start exactly at lastIndex.
+func (i *Interpreter) regExpBuiltinExec(r *RegExp, s *String) Object {
+	flags := r.re.Flags()
+	lastIndex, err := i.toLength(r.lastIndex)
+	if err != nil {
+		return err
+	}
+	remembers := flags.Global || flags.Sticky
+	if !remembers {
+		lastIndex = 0
+	}
+	units := s.units()
+	var caps []int
+	if lastIndex <= len(units) {
+		// Every step of the match counts against the script's step budget,
+		// so a pattern that backtracks forever is stopped like a loop
+		// that runs forever.
+		var stopped *Error
+		step := func() bool {
+			stopped = i.tick()
+			return stopped == nil
+		}
+		var err error
+		if flags.Sticky {
+			caps, err = r.re.MatchAt(units, lastIndex, step)
+		} else {
+			caps, err = r.re.Find(units, lastIndex, step)
+		}
+		if stopped != nil {
+			return stopped
+		}
+		if err != nil {
+			return i.limitExceeded(ErrCallDepth, newRangeError("Maximum call stack size exceeded"))
+		}
+	}
+	if caps == nil {
+		if remembers {
+			r.lastIndex = &Number{Value: 0}
+		}
+		return NULL
+	}
+	if remembers {
+		r.lastIndex = &Number{Value: float64(caps[1])}
+	}
+	return i.matchArray(r.re, s, caps)
+}
+
+// matchArray builds the array exec returns: the matched text followed by
+// what each group captured, with properties for the match's index, the
+// input string and the named groups. With the d flag, it also has an
+// indices property giving the start and end of each of these.
+func (i *Interpreter) matchArray(re *regex.Regexp, s *String, caps []int) *Array {
+	units := s.units()
+	names := re.GroupNames()
+	hasNames := false
+	for _, name := range names {
+		hasNames = hasNames || name != ""
+	}
+
+	elements := make([]Object, len(caps)/2)
+	indices := make([]Object, len(caps)/2)
+	for n := range elements {
+		start, end := caps[2*n], caps[2*n+1]
+		if start < 0 {
+			elements[n], indices[n] = UNDEFINED, UNDEFINED
+			continue
+		}
+		elements[n] = newStringFromUnits(units[start:end])
+		indices[n] = i.newArray([]Object{&Number{Value: float64(start)}, &Number{Value: float64(end)}})
+	}
+
+	// The groups objects have no prototype, so a group can be called
+	// anything, even toString, without clashing with inherited names.
+	groups, indexGroups := Object(UNDEFINED), Object(UNDEFINED)
+	if hasNames {
+		g, ig := NewHash(nil), NewHash(nil)
+		for n, name := range names {
+			if name != "" {
+				g.Set(&String{Value: name}, elements[n+1])
+				ig.Set(&String{Value: name}, indices[n+1])
+			}
+		}
+		groups, indexGroups = g, ig
+	}
+
+	arr := i.newArray(elements)
+	arr.Set(&String{Value: "index"}, &Number{Value: float64(caps[0])})
+	arr.Set(&String{Value: "input"}, s)
+	arr.Set(&String{Value: "groups"}, groups)
+	if re.Flags().HasIndices {
+		indexArray := i.newArray(indices)
+		indexArray.Set(&String{Value: "groups"}, indexGroups)
+		arr.Set(&String{Value: "indices"}, indexArray)
+	}
+	return arr
+}
+
+func (i *Interpreter) setupRegExp() {
+	proto := i.regexpPrototype
+
+	thisRegExp := func(this Object, method string) (*RegExp, *Error) {
+		r, ok := this.(*RegExp)
+		if !ok {
+			return nil, newTypeError("Method RegExp.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return r, nil
+	}
+	// thisObject checks the receiver of the methods that work on any
+	// object behaving like a RegExp, and converts their string argument.
+	thisObject := func(this Object, method string, args []Object) (*String, *Error) {
+		if !isObject(this) {
+			return nil, newTypeError("Method RegExp.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return i.stringArg(args, 0)
+	}
+
+	i.defineMethod(proto, "exec", func(this Object, args ...Object) Object {
+		r, err := thisRegExp(this, "exec")
+		if err != nil {
+			return err
+		}
+		s, err := i.stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		return i.regExpBuiltinExec(r, s)
+	})
+	i.defineMethod(proto, "test", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "test", args)
+		if err != nil {
+			return err
+		}
+		result := i.regExpExec(this, s)
+		if isError(result) {
+			return result
+		}
+		return nativeBoolToBooleanObject(result != NULL)
+	})
+	i.defineMethod(proto, "toString", func(this Object, args ...Object) Object {
+		if !isObject(this) {
+			return newTypeError("Method RegExp.prototype.toString called on incompatible receiver %s", inspectReceiver(this))
+		}
+		source, err := i.stringProperty(this, "source")
+		if err != nil {
+			return err
+		}
+		flags, err := i.stringProperty(this, "flags")
+		if err != nil {
+			return err
+		}
+		return &String{Value: "/" + source + "/" + flags}
+	})
+	proto.Set(symbolMatch, i.newBuiltin("[Symbol.match]", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "@@match", args)
+		if err != nil {
+			return err
+		}
+		return i.regExpMatch(this, s)
+	}))
+	proto.Set(symbolMatchAll, i.newBuiltin("[Symbol.matchAll]", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "@@matchAll", args)
+		if err != nil {
+			return err
+		}
+		return i.regExpMatchAll(this, s)
+	}))
+	proto.Set(symbolReplace, i.newBuiltin("[Symbol.replace]", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "@@replace", args)
+		if err != nil {
+			return err
+		}
+		return i.regExpReplace(this, s, argAt(args, 1))
+	}))
+	proto.Set(symbolSearch, i.newBuiltin("[Symbol.search]", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "@@search", args)
+		if err != nil {
+			return err
+		}
+		return i.regExpSearch(this, s)
+	}))
+	proto.Set(symbolSplit, i.newBuiltin("[Symbol.split]", func(this Object, args ...Object) Object {
+		s, err := thisObject(this, "@@split", args)
+		if err != nil {
+			return err
+		}
+		return i.regExpSplit(this, s, argAt(args, 1))
+	}))
+
+	i.defineMethod(i.regexpStringIteratorPrototype, "next", func(this Object, args ...Object) Object {
+		it, ok := this.(*RegExpStringIterator)
+		if !ok {
+			return newTypeError("next method called on incompatible receiver %s", inspectReceiver(this))
+		}
+		return i.nextRegExpStringIteratorValue(it)
+	})
+	i.regexpStringIteratorPrototype.Set(symbolToStringTag, &String{Value: "RegExp String Iterator"})
+
+	// Unlike most constructors, RegExp can be called without new.
+	ctor := i.newBuiltin("RegExp", func(this Object, args ...Object) Object {
+		return i.regExpCreate(argAt(args, 0), argAt(args, 1))
+	})
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("RegExp", ctor)
+}
+
+// stringProperty reads a property and converts it to a Go string.
+func (i *Interpreter) stringProperty(obj Object, name string) (string, *Error) {
+	value := i.getProperty(obj, &String{Value: name})
+	if err, ok := value.(*Error); ok {
+		return "", err
+	}
+	s, err := i.toString(value)
+	if err != nil {
+		return "", err
+	}
+	return s.Value, nil
+}
+
+// getLastIndex and setLastIndex read and write the lastIndex property of an
+// object being used as a regular expression.
+func (i *Interpreter) getLastIndex(rx Object) (int, *Error) {
+	value := i.getProperty(rx, &String{Value: "lastIndex"})
+	if err, ok := value.(*Error); ok {
+		return 0, err
+	}
+	return i.toLength(value)
+}
+
+func (i *Interpreter) setLastIndex(rx Object, n int) *Error {
+	return i.setProperty(rx, &String{Value: "lastIndex"}, &Number{Value: float64(n)})
+}
+
+// advancePastEmptyMatch moves lastIndex past an empty match, so that
+// repeating a global search doesn't find the same empty match forever.
+func (i *Interpreter) advancePastEmptyMatch(rx Object, match Object, s *String, unicode bool) *Error {
+	matched, err := i.stringProperty(match, "0")
+	if err != nil || matched != "" {
+		return err
+	}
+	lastIndex, err := i.getLastIndex(rx)
+	if err != nil {
+		return err
+	}
+	return i.setLastIndex(rx, regex.AdvanceIndex(s.units(), lastIndex, unicode))
+}
+
+// regExpMatch implements RegExp.prototype[Symbol.match], used by
+// String.prototype.match. Without the g flag, it returns what exec does;
+// with it, an array of every matched string, or null if there are none.
+func (i *Interpreter) regExpMatch(rx Object, s *String) Object {
+	flags, err := i.stringProperty(rx, "flags")
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(flags, "g") {
+		return i.regExpExec(rx, s)
+	}
+	if err := i.setLastIndex(rx, 0); err != nil {
+		return err
+	}
+	matches := []Object{}
+	for {
+		result := i.regExpExec(rx, s)
+		if isError(result) {
+			return result
+		}
+		if result == NULL {
+			break
+		}
+		matched := i.getProperty(result, &String{Value: "0"})
+		if isError(matched) {
+			return matched
+		}
+		matchedString, err := i.toString(matched)
+		if err != nil {
+			return err
+		}
+		matches = append(matches, matchedString)
+		if err := i.advancePastEmptyMatch(rx, result, s, strings.Contains(flags, "u")); err != nil {
+			return err
+		}
+	}
+	if len(matches) == 0 {
+		return NULL
+	}
+	return i.newArray(matches)
+}
+
+// regExpMatchAll implements RegExp.prototype[Symbol.matchAll]. It runs a
+// copy of the regular expression, so iterating doesn't disturb the
+// original's lastIndex.
+func (i *Interpreter) regExpMatchAll(rx Object, s *String) Object {
+	flags, err := i.stringProperty(rx, "flags")
+	if err != nil {
+		return err
+	}
+	matcher := i.regExpCreate(rx, &String{Value: flags})
+	if isError(matcher) {
+		return matcher
+	}
+	lastIndex, err := i.getLastIndex(rx)
+	if err != nil {
+		return err
+	}
+	if err := i.setLastIndex(matcher, lastIndex); err != nil {
+		return err
+	}
+	return &RegExpStringIterator{
+		Hash:    Hash{Prototype: i.regexpStringIteratorPrototype},
+		matcher: matcher,
+		str:     s,
+		global:  strings.Contains(flags, "g"),
+		unicode: strings.Contains(flags, "u"),
+	}
+}
+
+func (i *Interpreter) nextRegExpStringIteratorValue(it *RegExpStringIterator) Object {
+	if it.done {
+		return i.iteratorResult(UNDEFINED, true)
+	}
+	match := i.regExpExec(it.matcher, it.str)
+	if isError(match) {
+		return match
+	}
+	if match == NULL {
+		it.done = true
+		return i.iteratorResult(UNDEFINED, true)
+	}
+	if !it.global {
+		it.done = true
+		return i.iteratorResult(match, false)
+	}
+	if err := i.advancePastEmptyMatch(it.matcher, match, it.str, it.unicode); err != nil {
+		return err
+	}
+	return i.iteratorResult(match, false)
+}
+
+// regExpReplace implements RegExp.prototype[Symbol.replace], used by
+// String.prototype.replace and replaceAll. It first collects the matches,
+// every one of them with the g flag, and then builds the result from the
+// text between them and their replacements.
+func (i *Interpreter) regExpReplace(rx Object, s *String, replaceValue Object) Object {
+	units := s.units()
+	functional := isCallable(replaceValue)
+	var template []uint16
+	if !functional {
+		replaceString, err := i.toString(replaceValue)
+		if err != nil {
+			return err
+		}
+		template = replaceString.units()
+	}
+	flags, err := i.stringProperty(rx, "flags")
+	if err != nil {
+		return err
+	}
+	global := strings.Contains(flags, "g")
+	if global {
+		if err := i.setLastIndex(rx, 0); err != nil {
+			return err
+		}
+	}
+
+	var results []Object
+	for {
+		result := i.regExpExec(rx, s)
+		if isError(result) {
+			return result
+		}
+		if result == NULL {
+			break
+		}
+		results = append(results, result)
+		if !global {
+			break
+		}
+		if err := i.advancePastEmptyMatch(rx, result, s, strings.Contains(flags, "u")); err != nil {
+			return err
+		}
+	}
+
+	accumulated := []uint16{}
+	nextSourcePosition := 0
+	for _, result := range results {
+		length := i.getProperty(result, &String{Value: "length"})
+		if isError(length) {
+			return length
+		}
+		nCaptures, err := i.toLength(length)
+		if err != nil {
+			return err
+		}
+		nCaptures = max(nCaptures-1, 0)
+		matched, err := i.stringProperty(result, "0")
+		if err != nil {
+			return err
+		}
+		matchedUnits := utf16Units(matched)
+		index := i.getProperty(result, &String{Value: "index"})
+		if isError(index) {
+			return index
+		}
+		position, err := i.toIntegerOrInfinity(index)
+		if err != nil {
+			return err
+		}
+		pos := int(math.Max(0, math.Min(position, float64(len(units)))))
+
+		captures := make([]Object, nCaptures)
+		for n := range captures {
+			capture := i.getProperty(result, &Number{Value: float64(n + 1)})
+			if isError(capture) {
+				return capture
+			}
+			if capture != UNDEFINED {
+				captureString, err := i.toString(capture)
+				if err != nil {
+					return err
+				}
+				capture = captureString
+			}
+			captures[n] = capture
+		}
+		namedCaptures := i.getProperty(result, &String{Value: "groups"})
+		if isError(namedCaptures) {
+			return namedCaptures
+		}
+
+		var replacement []uint16
+		if functional {
+			callArgs := append([]Object{newStringFromUnits(matchedUnits)}, captures...)
+			callArgs = append(callArgs, &Number{Value: float64(pos)}, s)
+			if namedCaptures != UNDEFINED {
+				callArgs = append(callArgs, namedCaptures)
+			}
+			value := i.applyFunction(replaceValue, UNDEFINED, callArgs)
+			if isError(value) {
+				return value
+			}
+			replaced, err := i.toString(value)
+			if err != nil {
+				return err
+			}
+			replacement = replaced.units()
+		} else {
+			if isNullish(namedCaptures) && namedCaptures != UNDEFINED {
+				return newTypeError("Cannot convert undefined or null to object")
+			}
+			replacement, err = i.getSubstitution(matchedUnits, units, pos, captures, namedCaptures, template)
+			if err != nil {
+				return err
+			}
+		}
+		// A match that overlaps an earlier one, which only a custom exec
+		// can produce, is skipped.
+		if pos >= nextSourcePosition {
+			accumulated = append(accumulated, units[nextSourcePosition:pos]...)
+			accumulated = append(accumulated, replacement...)
+			nextSourcePosition = pos + len(matchedUnits)
+		}
+	}
+	if nextSourcePosition < len(units) {
+		accumulated = append(accumulated, units[nextSourcePosition:]...)
+	}
+	return newStringFromUnits(accumulated)
+}
+
+// getSubstitution expands the $ patterns in a replacement string for a
+// match of matched at position in str:
+//
+//	$$       a dollar sign
+//	$&       the match
+//	$`       the text before the match
+//	$'       the text after the match
+//	$1..$99  what a group captured
+//	$<name>  what a named group captured
+//
+// Patterns that refer to groups that don't exist are left as they are.
+func (i *Interpreter) getSubstitution(matched, str []uint16, position int, captures []Object, namedCaptures Object, template []uint16) ([]uint16, *Error) {
+	result := []uint16{}
+	tailPos := min(position+len(matched), len(str))
+	appendCapture := func(capture Object) {
+		if s, ok := capture.(*String); ok {
+			result = append(result, s.units()...)
+		}
+	}
+	for idx := 0; idx < len(template); idx++ {
+		if template[idx] != '$' || idx+1 == len(template) {
+			result = append(result, template[idx])
+			continue
+		}
+		c := template[idx+1]
+		switch {
+		case c == '$':
+			result = append(result, '$')
+		case c == '&':
+			result = append(result, matched...)
+		case c == '`':
+			result = append(result, str[:position]...)
+		case c == '\'':
+			result = append(result, str[tailPos:]...)
+		case '0' <= c && c <= '9':
+			n := int(c - '0')
+			if idx+2 < len(template) && '0' <= template[idx+2] && template[idx+2] <= '9' {
+				if nn := n*10 + int(template[idx+2]-'0'); 1 <= nn && nn <= len(captures) {
+					appendCapture(captures[nn-1])
+					idx += 2
+					continue
+				}
+			}
+			if n < 1 || n > len(captures) {
+				result = append(result, '$')
+				continue
+			}
+			appendCapture(captures[n-1])
+		case c == '<':
+			end := -1
+			for j := idx + 2; j < len(template); j++ {
+				if template[j] == '>' {
+					end = j
+					break
+				}
+			}
+			if namedCaptures == UNDEFINED || end < 0 {
+				result = append(result, '$')
+				continue
+			}
+			capture := i.getProperty(namedCaptures, newStringFromUnits(template[idx+2:end]))
+			if err, ok := capture.(*Error); ok {
+				return nil, err
+			}
+			if capture != UNDEFINED {
+				captureString, err := i.toString(capture)
+				if err != nil {
+					return nil, err
+				}
+				result = append(result, captureString.units()...)
+			}
+			idx = end
+			continue
+		default:
+			result = append(result, '$')
+			continue
+		}
+		idx++
+	}
+	return result, nil
+}
+
+// regExpSearch implements RegExp.prototype[Symbol.search], used by
+// String.prototype.search. It always searches from the start, leaving
+// lastIndex as it found it.
+func (i *Interpreter) regExpSearch(rx Object, s *String) Object {
+	previous := i.getProperty(rx, &String{Value: "lastIndex"})
+	if isError(previous) {
+		return previous
+	}
+	if !isPositiveZero(previous) {
+		if err := i.setLastIndex(rx, 0); err != nil {
+			return err
+		}
+	}
+	result := i.regExpExec(rx, s)
+	if isError(result) {
+		return result
+	}
+	current := i.getProperty(rx, &String{Value: "lastIndex"})
+	if isError(current) {
+		return current
+	}
+	if !strictEquals(current, previous) {
+		if err := i.setProperty(rx, &String{Value: "lastIndex"}, previous); err != nil {
+			return err
+		}
+	}
+	if result == NULL {
+		return &Number{Value: -1}
+	}
+	return i.getProperty(result, &String{Value: "index"})
+}
+
+func isPositiveZero(v Object) bool {
+	n, ok := v.(*Number)
+	return ok && n.Value == 0 && !math.Signbit(n.Value)
+}
+
+// regExpSplit implements RegExp.prototype[Symbol.split], used by
+// String.prototype.split. It runs a sticky copy of the regular expression
+// at each position in turn, so it finds where each separator starts
+// itself; the text between separators becomes the parts, followed each
+// time by what the separator's groups captured:
+//
+//	"a1b2c".split(/(\d)/)  // ["a", "1", "b", "2", "c"]
+func (i *Interpreter) regExpSplit(rx Object, s *String, limitArg Object) Object {
+	flags, err := i.stringProperty(rx, "flags")
+	if err != nil {
+		return err
+	}
+	unicode := strings.Contains(flags, "u")
+	if !strings.Contains(flags, "y") {
+		flags += "y"
+	}
+	splitter := i.regExpCreate(rx, &String{Value: flags})
+	if isError(splitter) {
+		return splitter
+	}
+	limit := uint32(math.MaxUint32)
+	if limitArg != UNDEFINED {
+		n, err := i.toNumber(limitArg)
+		if err != nil {
+			return err
+		}
+		limit = toUint32(n)
+	}
+
+	parts := []Object{}
+	if limit == 0 {
+		return i.newArray(parts)
+	}
+	units := s.units()
+	if len(units) == 0 {
+		z := i.regExpExec(splitter, s)
+		if isError(z) {
+			return z
+		}
+		if z == NULL {
+			parts = append(parts, s)
+		}
+		return i.newArray(parts)
+	}
+
+	p := 0
+	for q := p; q < len(units); {
+		if err := i.setLastIndex(splitter, q); err != nil {
+			return err
+		}
+		z := i.regExpExec(splitter, s)
+		if isError(z) {
+			return z
+		}
+		if z == NULL {
+			q = regex.AdvanceIndex(units, q, unicode)
+			continue
+		}
+		e, err := i.getLastIndex(splitter)
+		if err != nil {
+			return err
+		}
+		e = min(e, len(units))
+		if e == p {
+			q = regex.AdvanceIndex(units, q, unicode)
+			continue
+		}
+		parts = append(parts, newStringFromUnits(units[p:q]))
+		if uint32(len(parts)) == limit {
+			return i.newArray(parts)
+		}
+		p = e
+		length := i.getProperty(z, &String{Value: "length"})
+		if isError(length) {
+			return length
+		}
+		nCaptures, err := i.toLength(length)
+		if err != nil {
+			return err
+		}
+		for n := 1; n < nCaptures; n++ {
+			capture := i.getProperty(z, &Number{Value: float64(n)})
+			if isError(capture) {
+				return capture
+			}
+			parts = append(parts, capture)
+			if uint32(len(parts)) == limit {
+				return i.newArray(parts)
+			}
+		}
+		q = p
+	}
+	parts = append(parts, newStringFromUnits(units[p:]))
+	return i.newArray(parts)
+}
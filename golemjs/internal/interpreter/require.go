@@ -0,0 +1,471 @@
+package interpreter
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// CommonJS is the module system Node.js had before import and export. A
+// module is an ordinary script that sets module.exports, or adds to
+// exports, and require runs another module and returns its exports:
+//
+//	// greet.js
+//	module.exports = name => "hello " + name;
+//
+//	// main.js
+//	const greet = require("./greet");
+//	console.log(greet("world"));
+//
+// Each module runs once, the first time it is required; requiring it again
+// returns the same exports from the cache. Modules that require each other
+// in a cycle see each other's exports as they are at the time, which for
+// the module that started the cycle is whatever it had exported before it
+// called require.
+//
+// Modules are files in the fs.FS given to SetRequireFS, shown to scripts
+// as absolute paths with the file system's root as "/". A specifier is
+// resolved the way Node.js resolves it:
+//
+//   - "./x" and "../x" name a file relative to the requiring module, and
+//     "/x" one relative to the root. The file is tried as given, then with
+//     .js and .json added, and then as a directory: the file the "main"
+//     field of its package.json names, or else its index.js or index.json.
+//   - Anything else names a package, looked for in the node_modules
+//     directory next to the requiring module, then in the one in its parent
+//     directory, and so on up to the root. A package whose package.json has
+//     an "exports" field can only be required through the paths it lists;
+//     of its conditions, "require", "node" and "default" match.
+//
+// There are no built-in modules such as fs or path.
+
+// cjsModule is a CommonJS module that has been, or is being, run.
+type cjsModule struct {
+	filename string // the module's absolute path, as scripts see it
+	object   *Hash  // the module object, whose exports property is what require returns
+	parent   *cjsModule
+}
+
+// SetRequireFS defines the global require function, which loads CommonJS
+// modules from fsys. Scripts require modules as if they were at the root of
+// fsys. Without it, require isn't defined, so scripts can't read files.
+func (i *Interpreter) SetRequireFS(fsys fs.FS) {
+	i.requireFS = fsys
+	i.requireCache = map[string]*cjsModule{}
+	i.env.Set("require", i.newRequireFunction(nil))
+}
+
+// Require requires the module specifier names, as a script at the root of
+// the file system given to SetRequireFS would, then runs the event loop
+// until idle. It returns the module's exports, or the error the module
+// failed with.
+func (i *Interpreter) Require(specifier string) Object {
+	return i.RequireContext(context.Background(), specifier)
+}
+
+// RequireContext is Require, stopping when ctx is done, as EvalContext
+// does.
+func (i *Interpreter) RequireContext(ctx context.Context, specifier string) Object {
+	if i.requireFS == nil {
+		return newError("Error: Cannot find module '%s': no file system is set for require", specifier)
+	}
+	return i.runWithin(ctx, func() Object {
+		result := i.require(&String{Value: specifier}, nil)
+		if err := i.runUntilIdle(); err != nil && !isError(result) {
+			return err
+		}
+		return result
+	})
+}
+
+// newRequireFunction creates the require function of parent, which is nil
+// for the require of scripts.
+func (i *Interpreter) newRequireFunction(parent *cjsModule) *Builtin {
+	require := i.newBuiltin("require", func(this Object, args ...Object) Object {
+		return i.require(argAt(args, 0), parent)
+	})
+	i.defineMethod(&require.Hash, "resolve", func(this Object, args ...Object) Object {
+		specifier, err := requireSpecifier(argAt(args, 0))
+		if err != nil {
+			return err
+		}
+		filename, err := i.resolveRequire(specifier, parent)
+		if err != nil {
+			return err
+		}
+		return &String{Value: filename}
+	})
+	return require
+}
+
+// requireSpecifier checks that the argument to require is a specifier.
+func requireSpecifier(arg Object) (string, *Error) {
+	s, ok := arg.(*String)
+	if !ok {
+		return "", newTypeError("The \"id\" argument must be of type string. Received %s", inspect(arg, 0))
+	}
+	if s.Value == "" {
+		return "", newTypeError("The argument 'id' must be a non-empty string. Received ''")
+	}
+	return s.Value, nil
+}
+
+// require implements require(specifier) in parent: it returns the exports
+// of the module from the cache, or else runs the module first.
+func (i *Interpreter) require(arg Object, parent *cjsModule) Object {
+	specifier, err := requireSpecifier(arg)
+	if err != nil {
+		return err
+	}
+	filename, err := i.resolveRequire(specifier, parent)
+	if err != nil {
+		return err
+	}
+	if m, ok := i.requireCache[filename]; ok {
+		return i.getProperty(m.object, &String{Value: "exports"})
+	}
+
+	data, readErr := fs.ReadFile(i.requireFS, fsPath(filename))
+	if readErr != nil {
+		return newError("Error: %s", readErr)
+	}
+	exports := NewHash(i.objectPrototype)
+	m := &cjsModule{filename: filename, object: NewHash(i.objectPrototype), parent: parent}
+	m.object.Set(&String{Value: "id"}, &String{Value: filename})
+	m.object.Set(&String{Value: "filename"}, &String{Value: filename})
+	m.object.Set(&String{Value: "path"}, &String{Value: path.Dir(filename)})
+	m.object.Set(&String{Value: "exports"}, exports)
+	m.object.Set(&String{Value: "loaded"}, FALSE)
+
+	// The module is cached before it runs, so that a require leading back
+	// to it returns what it has exported so far. If it fails, it's taken
+	// out again, so that requiring it again runs it again.
+	i.requireCache[filename] = m
+	if result := i.runCommonJS(m, string(data), exports); isError(result) {
+		delete(i.requireCache, filename)
+		return result
+	}
+	m.object.Set(&String{Value: "loaded"}, TRUE)
+	return i.getProperty(m.object, &String{Value: "exports"})
+}
+
+// runCommonJS runs the source of module m. A .json file is parsed as JSON
+// and becomes the exports. Anything else runs as a script, with
+// module, exports, require, __filename and __dirname defined, and this
+// set to exports, as if it were the body of a function taking them. Like
+// a function body, it can end early with return.
+func (i *Interpreter) runCommonJS(m *cjsModule, source string, exports *Hash) Object {
+	if path.Ext(m.filename) == ".json" {
+		value := i.jsonParse(UNDEFINED, &String{Value: source})
+		if err, ok := value.(*Error); ok {
+			return newSyntaxError("%s: %s", m.filename, strings.TrimPrefix(err.Message, "SyntaxError: "))
+		}
+		m.object.Set(&String{Value: "exports"}, value)
+		return value
+	}
+
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		return newSyntaxError("%s (in %s)", errs[0], m.filename)
+	}
+	env := NewFunctionEnvironment(i.env)
+	env.Set("this", exports)
+	env.Set("exports", exports)
+	env.Set("module", m.object)
+	env.Set("require", i.newRequireFunction(m))
+	env.Set("__filename", &String{Value: m.filename})
+	env.Set("__dirname", &String{Value: path.Dir(m.filename)})
+	result := i.evalStatements(program.Statements, env)
+	if isError(result) {
+		return result
+	}
+	return UNDEFINED
+}
+
+// fsPath turns the absolute path of a module into its name in the file
+// system.
+func fsPath(filename string) string {
+	if filename == "/" {
+		return "."
+	}
+	return strings.TrimPrefix(filename, "/")
+}
+
+// resolveRequire works out the absolute path of the module that specifier
+// names when parent requires it.
+func (i *Interpreter) resolveRequire(specifier string, parent *cjsModule) (string, *Error) {
+	dir := "/"
+	if parent != nil {
+		dir = path.Dir(parent.filename)
+	}
+
+	if specifier == "." || specifier == ".." || strings.HasPrefix(specifier, "/") ||
+		strings.HasPrefix(specifier, "./") || strings.HasPrefix(specifier, "../") {
+		target := path.Join(dir, specifier)
+		if strings.HasPrefix(specifier, "/") {
+			target = path.Clean(specifier)
+		}
+		if filename, ok, err := i.loadAsFileOrDirectory(target); ok || err != nil {
+			return filename, err
+		}
+		return "", moduleNotFound(specifier, parent)
+	}
+
+	for _, modules := range nodeModulesPaths(dir) {
+		filename, ok, err := i.loadPackageExports(specifier, modules)
+		if ok || err != nil {
+			return filename, err
+		}
+		target := path.Join(modules, specifier)
+		if filename, ok, err := i.loadAsFileOrDirectory(target); ok || err != nil {
+			return filename, err
+		}
+	}
+	return "", moduleNotFound(specifier, parent)
+}
+
+// moduleNotFound reports a module that can't be found, with the chain of
+// modules that required it, innermost first, as Node.js does.
+func moduleNotFound(specifier string, parent *cjsModule) *Error {
+	message := "Error: Cannot find module '" + specifier + "'"
+	if parent != nil {
+		message += "\nRequire stack:"
+		for m := parent; m != nil; m = m.parent {
+			message += "\n- " + m.filename
+		}
+	}
+	return newError("%s", message)
+}
+
+// nodeModulesPaths returns the node_modules directories that packages are
+// looked for in from dir: the one in dir, then in each directory above it,
+// skipping directories that are themselves node_modules.
+func nodeModulesPaths(dir string) []string {
+	var dirs []string
+	for {
+		if path.Base(dir) != "node_modules" {
+			dirs = append(dirs, path.Join(dir, "node_modules"))
+		}
+		if dir == "/" {
+			return dirs
+		}
+		dir = path.Dir(dir)
+	}
+}
+
+// isFile reports whether filename is a file, not a directory.
+func (i *Interpreter) isFile(filename string) bool {
+	info, err := fs.Stat(i.requireFS, fsPath(filename))
+	return err == nil && !info.IsDir()
+}
+
+// loadAsFileOrDirectory finds the module at target, as a file or as a
+// directory.
+func (i *Interpreter) loadAsFileOrDirectory(target string) (string, bool, *Error) {
+	if filename, ok := i.loadAsFile(target); ok {
+		return filename, true, nil
+	}
+	return i.loadAsDirectory(target)
+}
+
+// loadAsFile finds the file target names, as given, or with an extension
+// added.
+func (i *Interpreter) loadAsFile(target string) (string, bool) {
+	for _, filename := range []string{target, target + ".js", target + ".json"} {
+		if i.isFile(filename) {
+			return filename, true
+		}
+	}
+	return "", false
+}
+
+// loadIndex finds the index file of the directory dir.
+func (i *Interpreter) loadIndex(dir string) (string, bool) {
+	for _, filename := range []string{path.Join(dir, "index.js"), path.Join(dir, "index.json")} {
+		if i.isFile(filename) {
+			return filename, true
+		}
+	}
+	return "", false
+}
+
+// loadAsDirectory finds the module in the directory dir: the file the
+// "main" field of its package.json names, or else its index file.
+func (i *Interpreter) loadAsDirectory(dir string) (string, bool, *Error) {
+	pkg, err := i.readPackageJSON(dir)
+	if err != nil {
+		return "", false, err
+	}
+	if pkg != nil {
+		if main, ok := pkg.GetOwn(&String{Value: "main"}); ok {
+			if main, ok := main.(*String); ok && main.Value != "" {
+				target := path.Join(dir, main.Value)
+				if filename, ok := i.loadAsFile(target); ok {
+					return filename, true, nil
+				}
+				if filename, ok := i.loadIndex(target); ok {
+					return filename, true, nil
+				}
+			}
+		}
+	}
+	filename, ok := i.loadIndex(dir)
+	return filename, ok, nil
+}
+
+// readPackageJSON reads the package.json in dir, or returns nil if there
+// isn't one.
+func (i *Interpreter) readPackageJSON(dir string) (*Hash, *Error) {
+	filename := path.Join(dir, "package.json")
+	data, err := fs.ReadFile(i.requireFS, fsPath(filename))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) || i.isDirectory(filename) {
+			return nil, nil
+		}
+		return nil, newError("Error: %s", err)
+	}
+	pkg, ok := i.jsonParse(UNDEFINED, &String{Value: string(data)}).(*Hash)
+	if !ok {
+		return nil, newError("Error: Invalid package config %s.", filename)
+	}
+	return pkg, nil
+}
+
+// isDirectory reports whether name is a directory.
+func (i *Interpreter) isDirectory(name string) bool {
+	info, err := fs.Stat(i.requireFS, fsPath(name))
+	return err == nil && info.IsDir()
+}
+
+// requireConditions are the conditions in package.json "exports" that
+// match require.
+var requireConditions = map[string]bool{"require": true, "node": true, "default": true}
+
+// loadPackageExports finds the module specifier names in the node_modules
+// directory modules, if it names a package whose package.json has an
+// "exports" field. A path the field doesn't list is an error, rather than
+// being looked for as a file.
+func (i *Interpreter) loadPackageExports(specifier, modules string) (string, bool, *Error) {
+	name, subpath := specifier, ""
+	parts := strings.SplitN(specifier, "/", 3)
+	switch {
+	case strings.HasPrefix(specifier, "@") && len(parts) > 2:
+		name, subpath = parts[0]+"/"+parts[1], "/"+parts[2]
+	case !strings.HasPrefix(specifier, "@") && len(parts) > 1:
+		name, subpath = parts[0], specifier[len(parts[0]):]
+	}
+	dir := path.Join(modules, name)
+	pkg, err := i.readPackageJSON(dir)
+	if pkg == nil || err != nil {
+		return "", false, err
+	}
+	exports, ok := pkg.GetOwn(&String{Value: "exports"})
+	if !ok || exports == NULL {
+		return "", false, nil
+	}
+
+	filename, ok := resolvePackageExports(dir, "."+subpath, exports)
+	config := path.Join(dir, "package.json")
+	if !ok {
+		if subpath == "" {
+			return "", false, newError("Error: No \"exports\" main defined in %s", config)
+		}
+		return "", false, newError("Error: Package subpath '.%s' is not defined by \"exports\" in %s", subpath, config)
+	}
+	if !i.isFile(filename) {
+		return "", false, newError("Error: Cannot find module '%s'", filename)
+	}
+	return filename, true, nil
+}
+
+// resolvePackageExports finds what subpath, such as "." or "./utils",
+// maps to in the "exports" field of the package in dir. The field is
+// either a map from subpaths to targets, or, as a shorthand, the target of
+// "." alone. A subpath may contain a *, which matches any string, which
+// then replaces the * in the target; the longest pattern before the *
+// wins.
+func resolvePackageExports(dir, subpath string, exports Object) (string, bool) {
+	subpaths, ok := exports.(*Hash)
+	if ok {
+		for _, key := range subpaths.OwnKeys() {
+			if s, ok := key.(*String); ok && !strings.HasPrefix(s.Value, ".") {
+				// An object of conditions, which is the target of ".".
+				subpaths = nil
+				break
+			}
+		}
+	}
+	if subpaths == nil {
+		if subpath != "." {
+			return "", false
+		}
+		return resolvePackageTarget(dir, exports, "")
+	}
+
+	if target, ok := subpaths.GetOwn(&String{Value: subpath}); ok {
+		return resolvePackageTarget(dir, target, "")
+	}
+	var best *String
+	bestPrefix, match := -1, ""
+	for _, key := range subpaths.OwnKeys() {
+		s, ok := key.(*String)
+		if !ok {
+			continue
+		}
+		prefix, suffix, found := strings.Cut(s.Value, "*")
+		if !found || strings.Contains(suffix, "*") || len(prefix) <= bestPrefix {
+			continue
+		}
+		if len(subpath) >= len(prefix)+len(suffix) && strings.HasPrefix(subpath, prefix) && strings.HasSuffix(subpath, suffix) {
+			best, bestPrefix = s, len(prefix)
+			match = subpath[len(prefix) : len(subpath)-len(suffix)]
+		}
+	}
+	if best == nil {
+		return "", false
+	}
+	target, _ := subpaths.GetOwn(best)
+	return resolvePackageTarget(dir, target, match)
+}
+
+// resolvePackageTarget resolves a target in "exports": a path in the
+// package starting with "./", a list of targets of which the first valid
+// one is used, or an object whose first condition that matches require
+// gives the target. null means the subpath isn't exported.
+func resolvePackageTarget(dir string, target Object, match string) (string, bool) {
+	switch target := target.(type) {
+	case *String:
+		if !strings.HasPrefix(target.Value, "./") {
+			return "", false
+		}
+		filename := path.Join(dir, strings.ReplaceAll(target.Value, "*", match))
+		if filename != dir && !strings.HasPrefix(filename, dir+"/") {
+			// Targets can't reach outside the package.
+			return "", false
+		}
+		return filename, true
+	case *Array:
+		w := target.walk(0, target.len(), false)
+		for _, element, ok := w.next(); ok; _, element, ok = w.next() {
+			if filename, ok := resolvePackageTarget(dir, element, match); ok {
+				return filename, true
+			}
+		}
+	case *Hash:
+		for _, key := range target.OwnKeys() {
+			if s, ok := key.(*String); ok && requireConditions[s.Value] {
+				value, _ := target.GetOwn(key)
+				if filename, ok := resolvePackageTarget(dir, value, match); ok {
+					return filename, true
+				}
+			}
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,325 @@
+package interpreter
+
+import (
+	"slices"
+	"sync"
+	"weak"
+)
+
+// Objects don't each keep a map from property names to values. An object
+// keeps its values in a slice, and a shape (a "hidden class") that says
+// which property is in which slot:
+//
+//	p = {}        shape {}
+//	p.x = 1       shape {x: 0}       values [1]
+//	p.y = 2       shape {x: 0, y: 1} values [1, 2]
+//
+// Shapes are shared. Adding a property to an object moves it from its
+// shape to the next one along a transition, and every object that gets
+// the same properties in the same order ends up with the same shape, so
+// all the points made by one constructor share theirs. Shapes never
+// change once made, which is what makes them useful: having seen that an
+// object of some shape keeps x in slot 0, the code reading p.x can
+// remember that, and the next time it sees an object of that shape,
+// read slot 0 without looking anything up. That memory is an inline
+// cache; see propertyCache.
+//
+// Objects used as dictionaries, with many properties or with properties
+// being deleted, would only fill the tree of transitions with shapes that
+// no other object will share. Such an object gets a dictionary shape of
+// its own instead, which is changed in place and never cached.
+
+// maxShapeKeys is the most properties an object can have while its shape
+// is shared; one with more gets a dictionary shape.
+const maxShapeKeys = 64
+
+// shape is the layout of an object's properties. A shared shape has few
+// enough of them to be searched; a dictionary shape keeps an index.
+type shape struct {
+	keys   []Object  // the keys, in the order the properties were added
+	hashes []HashKey // the hash key of the key in each slot
+	index  map[HashKey]int
+
+	dictionary bool // whether it belongs to one object, which changes it
+
+	// extended is set once a transition has appended to keys and hashes
+	// in place. A shape's slots never change once it's made, so the
+	// first shape after it can share its arrays; the others copy them.
+	extended bool
+
+	// transitions leads to the shapes that adding a property gives. They
+	// are held weakly, so that a shape goes away with the last object of
+	// that shape, and so that every string ever used as a key doesn't
+	// stay in the tree forever; the ones that have gone are swept out
+	// whenever the map has doubled in size. Objects of every interpreter
+	// share the tree, so it's guarded by a mutex.
+	mu          sync.Mutex
+	transitions map[HashKey]weak.Pointer[shape]
+	swept       int // the size of transitions after the last sweep
+}
+
+// emptyShape is the shape of an object without properties, at the root of
+// the tree of transitions. A Hash whose shape is nil has this one.
+var emptyShape = &shape{}
+
+// lookup returns the slot of the property with the hash key k.
+func (s *shape) lookup(k HashKey) (int, bool) {
+	if s.index != nil {
+		slot, ok := s.index[k]
+		return slot, ok
+	}
+	for slot, h := range s.hashes {
+		if h == k {
+			return slot, true
+		}
+	}
+	return 0, false
+}
+
+// withKey returns the shape an object of shape s has once the property
+// key, whose hash key is k, has been added to it. The property goes in the
+// slot after the last.
+func (s *shape) withKey(key Object, k HashKey) *shape {
+	if s.dictionary {
+		s.add(key, k)
+		return s
+	}
+	if len(s.keys) >= maxShapeKeys {
+		d := s.toDictionary()
+		d.add(key, k)
+		return d
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if next := s.transitions[k].Value(); next != nil {
+		return next
+	}
+	keys, hashes := s.keys, s.hashes
+	if s.extended {
+		keys, hashes = slices.Clip(keys), slices.Clip(hashes)
+	}
+	s.extended = true
+	next := &shape{keys: append(keys, key), hashes: append(hashes, k)}
+	if s.transitions == nil {
+		s.transitions = map[HashKey]weak.Pointer[shape]{}
+	}
+	if len(s.transitions) >= 2*s.swept+8 {
+		for h, p := range s.transitions {
+			if p.Value() == nil {
+				delete(s.transitions, h)
+			}
+		}
+		s.swept = len(s.transitions)
+	}
+	s.transitions[k] = weak.Make(next)
+	return next
+}
+
+// toDictionary returns a dictionary shape with the same properties as s.
+func (s *shape) toDictionary() *shape {
+	d := &shape{
+		keys:       slices.Clone(s.keys),
+		hashes:     slices.Clone(s.hashes),
+		index:      make(map[HashKey]int, len(s.hashes)),
+		dictionary: true,
+	}
+	for slot, h := range d.hashes {
+		d.index[h] = slot
+	}
+	return d
+}
+
+// add adds a property to a dictionary shape.
+func (s *shape) add(key Object, k HashKey) {
+	s.index[k] = len(s.keys)
+	s.keys = append(s.keys, key)
+	s.hashes = append(s.hashes, k)
+}
+
+// remove removes the property in slot from a dictionary shape. The
+// properties after it move down a slot.
+func (s *shape) remove(slot int) {
+	delete(s.index, s.hashes[slot])
+	s.keys = slices.Delete(s.keys, slot, slot+1)
+	s.hashes = slices.Delete(s.hashes, slot, slot+1)
+	for idx := slot; idx < len(s.hashes); idx++ {
+		s.index[s.hashes[idx]] = idx
+	}
+}
+
+// layout returns the shape of the object.
+func (h *Hash) layout() *shape {
+	if h.shape == nil {
+		return emptyShape
+	}
+	return h.shape
+}
+
+// maxPolymorphism is the number of shapes an inline cache remembers. A
+// site that sees more is megamorphic: it gives up on caching, and looks
+// every property up.
+const maxPolymorphism = 4
+
+// propertyCache is the inline cache of one place in the bytecode that
+// reads or writes a property by name, like p.x. Most such places only
+// ever see objects of one shape (they're monomorphic), and a few see a
+// handful (polymorphic), so remembering where the property was found for
+// the last few shapes answers nearly every access without a lookup.
+type propertyCache struct {
+	entries     []cacheEntry
+	megamorphic bool
+}
+
+// cacheEntry says where the property is for objects of one shape.
+type cacheEntry struct {
+	shape *shape
+	slot  int
+
+	// holder is the object's prototype, if that is where the property
+	// was found, and holderShape the shape it had then. An entry for a
+	// property of the prototype only holds while the object still has
+	// that prototype, and the prototype that shape.
+	holder      *Hash
+	holderShape *shape
+
+	// next is set for a write that added the property: the shape the
+	// object has afterwards.
+	next *shape
+}
+
+// cacheable returns the properties of obj if reading or writing its
+// property name can go through an inline cache: if it's an object, array
+// or function, and the property isn't one computed on the fly, like an
+// array's length.
+func cacheable(obj Object, name string) *Hash {
+	switch obj := obj.(type) {
+	case *Hash:
+		return obj
+	case *Function:
+		return &obj.Hash
+	case *Builtin:
+		return &obj.Hash
+	case *Array:
+		if name != "length" {
+			return &obj.Hash
+		}
+	}
+	return nil
+}
+
+// get reads the property from h if the cache knows where it is.
+func (c *propertyCache) get(h *Hash) (Object, bool) {
+	for idx := range c.entries {
+		e := &c.entries[idx]
+		if e.shape != h.shape || e.next != nil {
+			continue
+		}
+		if e.holder == nil {
+			return h.values[e.slot], true
+		}
+		if h.Prototype == e.holder && e.holder.shape == e.holderShape {
+			return e.holder.values[e.slot], true
+		}
+	}
+	return nil, false
+}
+
+// set writes the property of h if the cache knows where it goes.
+func (c *propertyCache) set(h *Hash, value Object) bool {
+	for idx := range c.entries {
+		e := &c.entries[idx]
+		if e.shape != h.shape || e.holder != nil {
+			continue
+		}
+		if e.next != nil {
+			h.shape = e.next
+			h.values = append(h.values, value)
+		} else {
+			h.values[e.slot] = value
+		}
+		return true
+	}
+	return false
+}
+
+// recordGet remembers where the property key of h was found: on h
+// itself, or on its prototype. Properties further up the prototype chain
+// aren't cached.
+func (c *propertyCache) recordGet(h *Hash, key Object) {
+	if c.megamorphic || h.layout().dictionary {
+		return
+	}
+	k := key.(Hashable).HashKey()
+	if slot, ok := h.layout().lookup(k); ok {
+		c.add(cacheEntry{shape: h.shape, slot: slot})
+		return
+	}
+	proto := h.Prototype
+	if proto == nil || proto.layout().dictionary {
+		return
+	}
+	if slot, ok := proto.layout().lookup(k); ok {
+		c.add(cacheEntry{shape: h.shape, slot: slot, holder: proto, holderShape: proto.shape})
+	}
+}
+
+// recordSet remembers where writing the property key put it in h, whose
+// shape was before until then.
+func (c *propertyCache) recordSet(h *Hash, before *shape, key Object) {
+	if c.megamorphic || h.layout().dictionary || (before != nil && before.dictionary) {
+		return
+	}
+	slot, ok := h.layout().lookup(key.(Hashable).HashKey())
+	if !ok {
+		return
+	}
+	if h.shape == before {
+		c.add(cacheEntry{shape: before, slot: slot})
+	} else {
+		c.add(cacheEntry{shape: before, slot: slot, next: h.shape})
+	}
+}
+
+func (c *propertyCache) add(e cacheEntry) {
+	if len(c.entries) == maxPolymorphism {
+		c.entries = nil
+		c.megamorphic = true
+		return
+	}
+	c.entries = append(c.entries, e)
+}
+
+// getNamed reads the property key, a string, of obj for an instruction
+// with an inline cache.
+func (i *Interpreter) getNamed(obj Object, key *String, cache *propertyCache) Object {
+	h := cacheable(obj, key.Value)
+	if h == nil {
+		return i.getProperty(obj, key)
+	}
+	if value, ok := cache.get(h); ok {
+		return value
+	}
+	value := i.getProperty(obj, key)
+	if !isError(value) {
+		cache.recordGet(h, key)
+	}
+	return value
+}
+
+// setNamed writes the property key, a string, of obj for an instruction
+// with an inline cache.
+func (i *Interpreter) setNamed(obj Object, key *String, value Object, cache *propertyCache) *Error {
+	h := cacheable(obj, key.Value)
+	if h == nil {
+		return i.setProperty(obj, key, value)
+	}
+	if cache.set(h, value) {
+		return nil
+	}
+	before := h.shape
+	if err := i.setProperty(obj, key, value); err != nil {
+		return err
+	}
+	cache.recordSet(h, before, key)
+	return nil
+}
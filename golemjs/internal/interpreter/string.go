@@ -0,0 +1,941 @@
+package interpreter
+
+import (
+	"math"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+	"golang.org/x/text/unicode/norm"
+)
+
+// units returns the string as UTF-16 code units, the representation
+// JavaScript uses for length, indexing and most String.prototype methods.
+func (s *String) units() []uint16 {
+	if s.utf16 == nil {
+		s.utf16 = utf16Units(s.Value)
+	}
+	return s.utf16
+}
+
+// utf16Units decodes a WTF-8 string into UTF-16 code units. WTF-8 is UTF-8
+// extended to allow unpaired surrogates, which JavaScript strings may
+// contain but UTF-8 forbids.
+func utf16Units(s string) []uint16 {
+	units := make([]uint16, 0, len(s))
+	for idx := 0; idx < len(s); {
+		r, size := utf8.DecodeRuneInString(s[idx:])
+		if r == utf8.RuneError && size == 1 {
+			if surrogate, ok := decodeSurrogate(s[idx:]); ok {
+				units = append(units, surrogate)
+				idx += 3
+				continue
+			}
+		}
+		if r >= 0x10000 {
+			high, low := utf16.EncodeRune(r)
+			units = append(units, uint16(high), uint16(low))
+		} else {
+			units = append(units, uint16(r))
+		}
+		idx += size
+	}
+	return units
+}
+
+// decodeSurrogate decodes the three-byte WTF-8 form of an unpaired
+// surrogate at the start of s.
+func decodeSurrogate(s string) (uint16, bool) {
+	if len(s) < 3 || s[0] != 0xED || s[1] < 0xA0 || s[1] > 0xBF || s[2]&0xC0 != 0x80 {
+		return 0, false
+	}
+	return uint16(s[0]&0x0F)<<12 | uint16(s[1]&0x3F)<<6 | uint16(s[2]&0x3F), true
+}
+
+// stringFromUnits encodes UTF-16 code units as WTF-8. Surrogate pairs become
+// the character they stand for; unpaired surrogates are kept as they are.
+func stringFromUnits(units []uint16) string {
+	var out strings.Builder
+	for idx := 0; idx < len(units); idx++ {
+		u := units[idx]
+		if utf16.IsSurrogate(rune(u)) {
+			if u < 0xDC00 && idx+1 < len(units) && units[idx+1] >= 0xDC00 && units[idx+1] < 0xE000 {
+				out.WriteRune(utf16.DecodeRune(rune(u), rune(units[idx+1])))
+				idx++
+				continue
+			}
+			out.WriteByte(byte(0xE0 | u>>12))
+			out.WriteByte(byte(0x80 | (u>>6)&0x3F))
+			out.WriteByte(byte(0x80 | u&0x3F))
+			continue
+		}
+		out.WriteRune(rune(u))
+	}
+	return out.String()
+}
+
+// concatStrings joins two WTF-8 strings. If the first ends with the high
+// half of a surrogate pair and the second starts with the low half, the
+// halves are combined into the character they encode, so that
+// "\uD83D" + "\uDE00" is the same string as "😀".
+func concatStrings(a, b string) string {
+	if len(a) >= 3 && len(b) >= 3 {
+		high, highOK := decodeSurrogate(a[len(a)-3:])
+		low, lowOK := decodeSurrogate(b)
+		if highOK && lowOK && high < 0xDC00 && low >= 0xDC00 {
+			return a[:len(a)-3] + string(utf16.DecodeRune(rune(high), rune(low))) + b[3:]
+		}
+	}
+	return a + b
+}
+
+// allocateString is called before a string of size bytes is made by
+// joining others. Like V8, it fails with "Invalid string length" for one
+// too long to make at all, and it checks the heap limit.
+func (i *Interpreter) allocateString(size int) *Error {
+	if size > maxStringLength {
+		return newRangeError("Invalid string length")
+	}
+	return i.allocate(size)
+}
+
+// newStringFromUnits creates a string value from UTF-16 code units.
+func newStringFromUnits(units []uint16) *String {
+	return &String{Value: stringFromUnits(units), utf16: units}
+}
+
+// codePoints splits a string into its characters, the way the string
+// iterator (used by spread and Array.from) does: surrogate pairs stay
+// together, and unpaired surrogates come out on their own.
+func (s *String) codePoints() []Object {
+	units := s.units()
+	result := make([]Object, 0, len(units))
+	for idx := 0; idx < len(units); idx++ {
+		end := idx + 1
+		if units[idx] >= 0xD800 && units[idx] < 0xDC00 && end < len(units) && units[end] >= 0xDC00 && units[end] < 0xE000 {
+			end++
+		}
+		result = append(result, newStringFromUnits(units[idx:end]))
+		idx = end - 1
+	}
+	return result
+}
+
+// StringIterator is the object returned by String.prototype[Symbol.iterator].
+// It produces the characters of a string one at a time, split as codePoints
+// splits them.
+type StringIterator struct {
+	Hash
+	chars []Object
+	index int
+}
+
+func (it *StringIterator) Type() ObjectType { return STRING_ITERATOR_OBJ }
+func (it *StringIterator) Inspect() string  { return "String Iterator {}" }
+
+// indexOfUnits returns the index of the first occurrence of needle in
+// haystack at or after from, or -1.
+func indexOfUnits(haystack, needle []uint16, from int) int {
+	for idx := from; idx+len(needle) <= len(haystack); idx++ {
+		if unitsEqual(haystack[idx:idx+len(needle)], needle) {
+			return idx
+		}
+	}
+	return -1
+}
+
+func unitsEqual(a, b []uint16) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// isWhitespace reports whether a code unit is whitespace or a line
+// terminator as defined by JavaScript, which is what trim() removes.
+func isWhitespace(u uint16) bool {
+	switch u {
+	case '\t', '\n', '\v', '\f', '\r', ' ', 0xA0, 0x1680, 0x2028, 0x2029, 0x202F, 0x205F, 0x3000, 0xFEFF:
+		return true
+	}
+	return u >= 0x2000 && u <= 0x200A
+}
+
+// setupString creates String.prototype and the String function, and defines
+// String in the global environment.
+func (i *Interpreter) setupString() {
+	proto := i.stringPrototype
+
+	i.defineMethod(proto, "at", i.stringAt)
+	i.defineMethod(proto, "charAt", i.stringCharAt)
+	i.defineMethod(proto, "charCodeAt", i.stringCharCodeAt)
+	i.defineMethod(proto, "codePointAt", i.stringCodePointAt)
+	i.defineMethod(proto, "concat", i.stringConcat)
+	i.defineMethod(proto, "endsWith", i.stringEndsWith)
+	i.defineMethod(proto, "includes", i.stringIncludes)
+	i.defineMethod(proto, "indexOf", i.stringIndexOf)
+	i.defineMethod(proto, "lastIndexOf", i.stringLastIndexOf)
+	i.defineMethod(proto, "localeCompare", i.stringLocaleCompare)
+	i.defineMethod(proto, "match", i.stringMatcher("match", symbolMatch, false))
+	i.defineMethod(proto, "matchAll", i.stringMatcher("matchAll", symbolMatchAll, true))
+	i.defineMethod(proto, "normalize", i.stringNormalize)
+	i.defineMethod(proto, "padEnd", i.stringPad("padEnd", false))
+	i.defineMethod(proto, "padStart", i.stringPad("padStart", true))
+	i.defineMethod(proto, "repeat", i.stringRepeat)
+	i.defineMethod(proto, "replace", i.stringReplace("replace", false))
+	i.defineMethod(proto, "replaceAll", i.stringReplace("replaceAll", true))
+	i.defineMethod(proto, "search", i.stringMatcher("search", symbolSearch, false))
+	i.defineMethod(proto, "slice", i.stringSlice)
+	i.defineMethod(proto, "split", i.stringSplit)
+	i.defineMethod(proto, "startsWith", i.stringStartsWith)
+	i.defineMethod(proto, "substring", i.stringSubstring)
+	i.defineMethod(proto, "toLowerCase", i.stringChangeCase("toLowerCase", cases.Lower(language.Und)))
+	i.defineMethod(proto, "toString", i.stringValueOf)
+	i.defineMethod(proto, "toUpperCase", i.stringChangeCase("toUpperCase", cases.Upper(language.Und)))
+	i.defineMethod(proto, "trim", i.stringTrim("trim", true, true))
+	i.defineMethod(proto, "trimEnd", i.stringTrim("trimEnd", false, true))
+	i.defineMethod(proto, "trimStart", i.stringTrim("trimStart", true, false))
+	i.defineMethod(proto, "valueOf", i.stringValueOf)
+	proto.Set(symbolIterator, i.newBuiltin("[Symbol.iterator]", func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, "[Symbol.iterator]")
+		if err != nil {
+			return err
+		}
+		return &StringIterator{Hash: Hash{Prototype: i.stringIteratorPrototype}, chars: s.codePoints()}
+	}))
+
+	i.defineMethod(i.stringIteratorPrototype, "next", func(this Object, args ...Object) Object {
+		it, ok := this.(*StringIterator)
+		if !ok {
+			return newTypeError("next method called on incompatible receiver %s", inspectReceiver(this))
+		}
+		if it.index >= len(it.chars) {
+			return i.iteratorResult(UNDEFINED, true)
+		}
+		it.index++
+		return i.iteratorResult(it.chars[it.index-1], false)
+	})
+	i.stringIteratorPrototype.Set(symbolToStringTag, &String{Value: "String Iterator"})
+
+	ctor := i.newBuiltin("String", func(this Object, args ...Object) Object {
+		if len(args) == 0 {
+			return &String{Value: ""}
+		}
+		// String() is the one conversion that accepts symbols.
+		if s, ok := args[0].(*Symbol); ok {
+			return &String{Value: s.Inspect()}
+		}
+		s, err := i.toString(args[0])
+		if err != nil {
+			return err
+		}
+		return s
+	})
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.defineMethod(&ctor.Hash, "fromCharCode", i.stringFromCharCode)
+	i.defineMethod(&ctor.Hash, "fromCodePoint", i.stringFromCodePoint)
+	i.env.Set("String", ctor)
+}
+
+// thisString converts the receiver of a String.prototype method to a
+// string. The methods work on any value except null and undefined.
+func (i *Interpreter) thisString(this Object, method string) (*String, *Error) {
+	if isNullish(this) {
+		return nil, newTypeError("String.prototype.%s called on null or undefined", method)
+	}
+	return i.toString(this)
+}
+
+// stringArg converts the n-th argument to a string.
+func (i *Interpreter) stringArg(args []Object, n int) (*String, *Error) {
+	return i.toString(argAt(args, n))
+}
+
+// integerArg converts the n-th argument to a whole number, or returns def
+// when it is missing.
+func (i *Interpreter) integerArg(args []Object, n int, def float64) (float64, *Error) {
+	if argAt(args, n) == UNDEFINED {
+		return def, nil
+	}
+	return i.toIntegerOrInfinity(args[n])
+}
+
+// clamp limits n to the range [0, max].
+func clamp(n float64, max int) int {
+	return int(math.Min(math.Max(n, 0), float64(max)))
+}
+
+// compareUnits compares two strings by their UTF-16 code units, which is
+// how JavaScript orders strings. It returns -1, 0 or 1.
+func compareUnits(a, b []uint16) int {
+	for idx := 0; idx < len(a) && idx < len(b); idx++ {
+		if a[idx] != b[idx] {
+			if a[idx] < b[idx] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// unitAt returns the code unit at idx as a one-unit string, or nil if idx
+// is out of range.
+func unitAt(units []uint16, idx float64) *String {
+	if idx < 0 || idx >= float64(len(units)) {
+		return nil
+	}
+	return newStringFromUnits(units[int(idx) : int(idx)+1])
+}
+
+func (i *Interpreter) stringAt(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "at")
+	if err != nil {
+		return err
+	}
+	units := s.units()
+	idx, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if idx < 0 {
+		idx += float64(len(units))
+	}
+	if unit := unitAt(units, idx); unit != nil {
+		return unit
+	}
+	return UNDEFINED
+}
+
+func (i *Interpreter) stringCharAt(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "charAt")
+	if err != nil {
+		return err
+	}
+	idx, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if unit := unitAt(s.units(), idx); unit != nil {
+		return unit
+	}
+	return &String{Value: ""}
+}
+
+// stringCharCodeAt returns the UTF-16 code unit at an index, so for
+// characters outside the Basic Multilingual Plane it returns half of a
+// surrogate pair. Out of range indices give NaN.
+func (i *Interpreter) stringCharCodeAt(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "charCodeAt")
+	if err != nil {
+		return err
+	}
+	units := s.units()
+	idx, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if idx < 0 || idx >= float64(len(units)) {
+		return &Number{Value: math.NaN()}
+	}
+	return &Number{Value: float64(units[int(idx)])}
+}
+
+// stringCodePointAt returns the full code point starting at an index,
+// combining a surrogate pair into one character.
+func (i *Interpreter) stringCodePointAt(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "codePointAt")
+	if err != nil {
+		return err
+	}
+	units := s.units()
+	n, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if n < 0 || n >= float64(len(units)) {
+		return UNDEFINED
+	}
+	idx := int(n)
+	u := units[idx]
+	if u >= 0xD800 && u < 0xDC00 && idx+1 < len(units) {
+		if low := units[idx+1]; low >= 0xDC00 && low < 0xE000 {
+			return &Number{Value: float64(utf16.DecodeRune(rune(u), rune(low)))}
+		}
+	}
+	return &Number{Value: float64(u)}
+}
+
+func (i *Interpreter) stringConcat(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "concat")
+	if err != nil {
+		return err
+	}
+	result := s.Value
+	for idx := range args {
+		arg, err := i.stringArg(args, idx)
+		if err != nil {
+			return err
+		}
+		if err := i.allocateString(len(result) + len(arg.Value)); err != nil {
+			return err
+		}
+		result = concatStrings(result, arg.Value)
+	}
+	return &String{Value: result}
+}
+
+// searchArgs converts the receiver, the search string and the position
+// argument shared by endsWith, startsWith, includes and indexOf. The
+// position is clamped to the string, defaulting to def.
+func (i *Interpreter) searchArgs(this Object, args []Object, method string, def func(length int) int) ([]uint16, []uint16, int, *Error) {
+	s, err := i.thisString(this, method)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	search, err := i.stringArg(args, 0)
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	units := s.units()
+	pos, err := i.integerArg(args, 1, float64(def(len(units))))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	return units, search.units(), clamp(pos, len(units)), nil
+}
+
+func atStart(length int) int { return 0 }
+func atEnd(length int) int   { return length }
+
+func (i *Interpreter) stringEndsWith(this Object, args ...Object) Object {
+	units, search, end, err := i.searchArgs(this, args, "endsWith", atEnd)
+	if err != nil {
+		return err
+	}
+	start := end - len(search)
+	return nativeBoolToBooleanObject(start >= 0 && unitsEqual(units[start:end], search))
+}
+
+func (i *Interpreter) stringStartsWith(this Object, args ...Object) Object {
+	units, search, start, err := i.searchArgs(this, args, "startsWith", atStart)
+	if err != nil {
+		return err
+	}
+	end := start + len(search)
+	return nativeBoolToBooleanObject(end <= len(units) && unitsEqual(units[start:end], search))
+}
+
+func (i *Interpreter) stringIncludes(this Object, args ...Object) Object {
+	units, search, start, err := i.searchArgs(this, args, "includes", atStart)
+	if err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(indexOfUnits(units, search, start) >= 0)
+}
+
+func (i *Interpreter) stringIndexOf(this Object, args ...Object) Object {
+	units, search, start, err := i.searchArgs(this, args, "indexOf", atStart)
+	if err != nil {
+		return err
+	}
+	return &Number{Value: float64(indexOfUnits(units, search, start))}
+}
+
+func (i *Interpreter) stringLastIndexOf(this Object, args ...Object) Object {
+	units, search, start, err := i.searchArgs(this, args, "lastIndexOf", atEnd)
+	if err != nil {
+		return err
+	}
+	if start > len(units)-len(search) {
+		start = len(units) - len(search)
+	}
+	for idx := start; idx >= 0; idx-- {
+		if unitsEqual(units[idx:idx+len(search)], search) {
+			return &Number{Value: float64(idx)}
+		}
+	}
+	return &Number{Value: -1}
+}
+
+// stringLocaleCompare compares two strings. Without locale data we compare
+// code points, which gives the expected order for most ASCII text.
+func (i *Interpreter) stringLocaleCompare(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "localeCompare")
+	if err != nil {
+		return err
+	}
+	other, err := i.stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	return &Number{Value: float64(strings.Compare(s.Value, other.Value))}
+}
+
+// stringNormalize returns the Unicode normalization form of the string, so
+// that, for example, "é" written as one character and as "e" plus a
+// combining accent compare equal after normalizing.
+func (i *Interpreter) stringNormalize(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "normalize")
+	if err != nil {
+		return err
+	}
+	form := "NFC"
+	if argAt(args, 0) != UNDEFINED {
+		arg, err := i.stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		form = arg.Value
+	}
+	forms := map[string]norm.Form{"NFC": norm.NFC, "NFD": norm.NFD, "NFKC": norm.NFKC, "NFKD": norm.NFKD}
+	f, ok := forms[form]
+	if !ok {
+		return newRangeError("The normalization form should be one of NFC, NFD, NFKC, NFKD.")
+	}
+	return &String{Value: f.String(s.Value)}
+}
+
+// stringPad implements padStart and padEnd, which repeat a filler string
+// until the result reaches the target length.
+func (i *Interpreter) stringPad(name string, atStart bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, name)
+		if err != nil {
+			return err
+		}
+		units := s.units()
+		target, err := i.integerArg(args, 0, 0)
+		if err != nil {
+			return err
+		}
+		filler := []uint16{' '}
+		if argAt(args, 1) != UNDEFINED {
+			arg, err := i.stringArg(args, 1)
+			if err != nil {
+				return err
+			}
+			filler = arg.units()
+		}
+		if target <= float64(len(units)) || len(filler) == 0 {
+			return s
+		}
+		if target > maxStringLength {
+			return newRangeError("Invalid string length")
+		}
+		if err := i.charge(int(target) - len(units)); err != nil {
+			return err
+		}
+		if err := i.allocate(4 * int(target)); err != nil {
+			return err
+		}
+		padding := make([]uint16, 0, int(target)-len(units))
+		for len(padding) < int(target)-len(units) {
+			padding = append(padding, filler[len(padding)%len(filler)])
+		}
+		if atStart {
+			return newStringFromUnits(append(padding, units...))
+		}
+		return newStringFromUnits(append(append([]uint16{}, units...), padding...))
+	}
+}
+
+func (i *Interpreter) stringRepeat(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "repeat")
+	if err != nil {
+		return err
+	}
+	count, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	if count < 0 || math.IsInf(count, 1) {
+		return newRangeError("Invalid count value: %s", numberToString(count))
+	}
+	if float64(len(s.Value))*count > maxStringLength {
+		return newRangeError("Invalid string length")
+	}
+	if err := i.charge(int(count)); err != nil {
+		return err
+	}
+	if err := i.allocate(len(s.Value) * int(count)); err != nil {
+		return err
+	}
+	return &String{Value: strings.Repeat(s.Value, int(count))}
+}
+
+// maxStringLength bounds the strings repeat, padding and concatenation can
+// create, like the limit every JavaScript engine has.
+const maxStringLength = 1<<29 - 24
+
+// stringReplace implements replace and replaceAll. A pattern with a
+// [Symbol.replace] method, such as a RegExp, does the replacing itself.
+// Otherwise the pattern is a string, and the replacement is either a
+// function, called with the match, its position and the whole string, or
+// a string in which $& stands for the match, $` and $' for the text before
+// and after it, and $$ for a dollar sign.
+func (i *Interpreter) stringReplace(name string, all bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, name)
+		if err != nil {
+			return err
+		}
+		if all {
+			if err := i.requireGlobal(argAt(args, 0), "replaceAll must be called with a global RegExp"); err != nil {
+				return err
+			}
+		}
+		if result, ok := i.callPatternMethod(argAt(args, 0), symbolReplace, s, argAt(args, 1)); ok {
+			return result
+		}
+		units := s.units()
+		patternString, err := i.stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		pattern := patternString.units()
+		replacement := argAt(args, 1)
+		var template []uint16
+		if !isCallable(replacement) {
+			replacementString, err := i.toString(replacement)
+			if err != nil {
+				return err
+			}
+			template = replacementString.units()
+		}
+
+		var positions []int
+		for from := 0; from <= len(units); {
+			idx := indexOfUnits(units, pattern, from)
+			if idx < 0 {
+				break
+			}
+			positions = append(positions, idx)
+			if !all {
+				break
+			}
+			from = idx + len(pattern)
+			if len(pattern) == 0 {
+				from++
+			}
+		}
+
+		result := []uint16{}
+		last := 0
+		for _, pos := range positions {
+			result = append(result, units[last:pos]...)
+			if template != nil {
+				replaced, err := i.getSubstitution(pattern, units, pos, nil, UNDEFINED, template)
+				if err != nil {
+					return err
+				}
+				result = append(result, replaced...)
+			} else {
+				value := i.applyFunction(replacement, UNDEFINED, []Object{
+					newStringFromUnits(pattern), &Number{Value: float64(pos)}, s,
+				})
+				if isError(value) {
+					return value
+				}
+				replaced, err := i.toString(value)
+				if err != nil {
+					return err
+				}
+				result = append(result, replaced.units()...)
+			}
+			last = pos + len(pattern)
+		}
+		result = append(result, units[last:]...)
+		return newStringFromUnits(result)
+	}
+}
+
+// stringMatcher implements match, matchAll and search, which do nothing
+// themselves but call the method under symbol on their argument. An
+// argument without one, such as a string, is first made into a RegExp,
+// with the g flag for matchAll, which finds every match.
+func (i *Interpreter) stringMatcher(name string, symbol *Symbol, all bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, name)
+		if err != nil {
+			return err
+		}
+		pattern := argAt(args, 0)
+		flags := Object(UNDEFINED)
+		if all {
+			if err := i.requireGlobal(pattern, "String.prototype.matchAll called with a non-global RegExp argument"); err != nil {
+				return err
+			}
+			flags = &String{Value: "g"}
+		}
+		if result, ok := i.callPatternMethod(pattern, symbol, s); ok {
+			return result
+		}
+		rx := i.regExpCreate(pattern, flags)
+		if isError(rx) {
+			return rx
+		}
+		result, _ := i.callPatternMethod(rx, symbol, s)
+		return result
+	}
+}
+
+// callPatternMethod calls the method under symbol, such as
+// [Symbol.replace], on the pattern argument of a string method, if it has
+// one. It reports whether it did.
+func (i *Interpreter) callPatternMethod(pattern Object, symbol *Symbol, args ...Object) (Object, bool) {
+	if isNullish(pattern) {
+		return nil, false
+	}
+	method := i.getProperty(pattern, symbol)
+	if isError(method) {
+		return method, true
+	}
+	if isNullish(method) {
+		return nil, false
+	}
+	if !isCallable(method) {
+		return newTypeError("%s is not a function", inspect(method, 0)), true
+	}
+	return i.applyFunction(method, pattern, args), true
+}
+
+// requireGlobal checks that a regular expression passed to matchAll or
+// replaceAll has the g flag; without it, those methods would find only
+// the first match despite their names.
+func (i *Interpreter) requireGlobal(pattern Object, message string) *Error {
+	isRegExp, err := i.isRegExp(pattern)
+	if err != nil || !isRegExp {
+		return err
+	}
+	flags := i.getProperty(pattern, &String{Value: "flags"})
+	if err, ok := flags.(*Error); ok {
+		return err
+	}
+	if isNullish(flags) {
+		return newTypeError("%s", message)
+	}
+	flagString, err := i.toString(flags)
+	if err != nil {
+		return err
+	}
+	if !strings.Contains(flagString.Value, "g") {
+		return newTypeError("%s", message)
+	}
+	return nil
+}
+
+func (i *Interpreter) stringSlice(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "slice")
+	if err != nil {
+		return err
+	}
+	units := s.units()
+	start, err := i.relativeIndex(argAt(args, 0), len(units), 0)
+	if err != nil {
+		return err
+	}
+	end, err := i.relativeIndex(argAt(args, 1), len(units), len(units))
+	if err != nil {
+		return err
+	}
+	if end < start {
+		end = start
+	}
+	return newStringFromUnits(units[start:end])
+}
+
+// stringSubstring differs from slice in how it treats odd arguments:
+// negative indices count as 0, and the two indices are swapped if the start
+// is after the end.
+func (i *Interpreter) stringSubstring(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "substring")
+	if err != nil {
+		return err
+	}
+	units := s.units()
+	startArg, err := i.integerArg(args, 0, 0)
+	if err != nil {
+		return err
+	}
+	endArg, err := i.integerArg(args, 1, float64(len(units)))
+	if err != nil {
+		return err
+	}
+	start, end := clamp(startArg, len(units)), clamp(endArg, len(units))
+	if start > end {
+		start, end = end, start
+	}
+	return newStringFromUnits(units[start:end])
+}
+
+// stringSplit splits the string at every occurrence of a separator. An
+// empty separator splits it into UTF-16 code units, and a missing one
+// returns the whole string as the only element. A separator with a
+// [Symbol.split] method, such as a RegExp, does the splitting itself.
+func (i *Interpreter) stringSplit(this Object, args ...Object) Object {
+	s, err := i.thisString(this, "split")
+	if err != nil {
+		return err
+	}
+	if result, ok := i.callPatternMethod(argAt(args, 0), symbolSplit, s, argAt(args, 1)); ok {
+		return result
+	}
+	limit := uint32(math.MaxUint32)
+	if argAt(args, 1) != UNDEFINED {
+		n, err := i.toNumber(args[1])
+		if err != nil {
+			return err
+		}
+		limit = toUint32(n)
+	}
+
+	parts := []Object{}
+	add := func(units []uint16) bool {
+		if uint32(len(parts)) >= limit {
+			return false
+		}
+		i.allocated(elementSize + 2*len(units))
+		parts = append(parts, newStringFromUnits(units))
+		return true
+	}
+
+	units := s.units()
+	if argAt(args, 0) == UNDEFINED {
+		add(units)
+		return i.newArray(parts)
+	}
+	sepString, err := i.stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	sep := sepString.units()
+	if len(sep) == 0 {
+		for idx := range units {
+			if !add(units[idx : idx+1]) {
+				break
+			}
+		}
+		return i.newArray(parts)
+	}
+
+	start := 0
+	for {
+		idx := indexOfUnits(units, sep, start)
+		if idx < 0 {
+			add(units[start:])
+			break
+		}
+		if !add(units[start:idx]) {
+			break
+		}
+		start = idx + len(sep)
+	}
+	return i.newArray(parts)
+}
+
+// stringChangeCase implements toUpperCase and toLowerCase with full Unicode
+// case mapping, so "ß".toUpperCase() is "SS".
+func (i *Interpreter) stringChangeCase(name string, caser cases.Caser) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, name)
+		if err != nil {
+			return err
+		}
+		if utf8.ValidString(s.Value) {
+			return &String{Value: caser.String(s.Value)}
+		}
+		// Unpaired surrogates are left alone; only the text around them
+		// changes case.
+		var out strings.Builder
+		for _, part := range s.codePoints() {
+			part := part.(*String)
+			if utf8.ValidString(part.Value) {
+				out.WriteString(caser.String(part.Value))
+			} else {
+				out.WriteString(part.Value)
+			}
+		}
+		return &String{Value: out.String()}
+	}
+}
+
+func (i *Interpreter) stringTrim(name string, start, end bool) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		s, err := i.thisString(this, name)
+		if err != nil {
+			return err
+		}
+		units := s.units()
+		from, to := 0, len(units)
+		for start && from < to && isWhitespace(units[from]) {
+			from++
+		}
+		for end && to > from && isWhitespace(units[to-1]) {
+			to--
+		}
+		return newStringFromUnits(units[from:to])
+	}
+}
+
+func (i *Interpreter) stringValueOf(this Object, args ...Object) Object {
+	s, ok := this.(*String)
+	if !ok {
+		return newTypeError("String.prototype.valueOf requires that 'this' be a String")
+	}
+	return s
+}
+
+// stringFromCharCode builds a string from UTF-16 code units. Each argument
+// is wrapped around to 16 bits, as in JavaScript.
+func (i *Interpreter) stringFromCharCode(this Object, args ...Object) Object {
+	units := make([]uint16, len(args))
+	for idx := range args {
+		n, err := i.toNumber(args[idx])
+		if err != nil {
+			return err
+		}
+		units[idx] = uint16(toUint32(n))
+	}
+	return newStringFromUnits(units)
+}
+
+// stringFromCodePoint builds a string from code points, which may be
+// outside the 16-bit range.
+func (i *Interpreter) stringFromCodePoint(this Object, args ...Object) Object {
+	units := []uint16{}
+	for idx := range args {
+		cp, err := i.toNumber(args[idx])
+		if err != nil {
+			return err
+		}
+		if cp != math.Trunc(cp) || cp < 0 || cp > utf8.MaxRune {
+			s, err := i.toString(args[idx])
+			if err != nil {
+				return err
+			}
+			return newRangeError("Invalid code point %s", s.Value)
+		}
+		if cp >= 0x10000 {
+			high, low := utf16.EncodeRune(rune(cp))
+			units = append(units, uint16(high), uint16(low))
+		} else {
+			units = append(units, uint16(cp))
+		}
+	}
+	return newStringFromUnits(units)
+}
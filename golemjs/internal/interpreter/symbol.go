@@ -0,0 +1,143 @@
+package interpreter
+
+import "sync/atomic"
+
+// Symbol represents JavaScript symbols: unique values mostly used as
+// property keys that can't clash with any other key. Symbol("x") creates a
+// new symbol every time it is called, so two symbols are only equal if
+// they are the same symbol. The description is only there for debugging.
+type Symbol struct {
+	Description    string
+	HasDescription bool // Symbol() has no description, unlike Symbol("")
+	id             uint64
+}
+
+// symbolCount gives every symbol a distinct id for its HashKey.
+var symbolCount atomic.Uint64
+
+// NewSymbol creates a new, unique symbol.
+func NewSymbol(description string, hasDescription bool) *Symbol {
+	return &Symbol{Description: description, HasDescription: hasDescription, id: symbolCount.Add(1)}
+}
+
+func (s *Symbol) Type() ObjectType { return SYMBOL_OBJ }
+func (s *Symbol) Inspect() string  { return "Symbol(" + s.Description + ")" }
+
+// HashKey lets symbols be used as property keys. Symbols are compared by
+// identity, so the key is the symbol's id rather than its description.
+func (s *Symbol) HashKey() HashKey {
+	return HashKey{Type: s.Type(), Value: s.id}
+}
+
+// The well-known symbols name the hooks through which objects customise
+// how the language treats them. For example, an object with a
+// [Symbol.iterator] method can be used in for...of, and one with a
+// [Symbol.toStringTag] property changes what Object.prototype.toString
+// says about it. They are the same in every interpreter.
+var (
+	symbolAsyncIterator = NewSymbol("Symbol.asyncIterator", true)
+	symbolHasInstance   = NewSymbol("Symbol.hasInstance", true)
+	symbolIterator      = NewSymbol("Symbol.iterator", true)
+	symbolMatch         = NewSymbol("Symbol.match", true)
+	symbolMatchAll      = NewSymbol("Symbol.matchAll", true)
+	symbolReplace       = NewSymbol("Symbol.replace", true)
+	symbolSearch        = NewSymbol("Symbol.search", true)
+	symbolSpecies       = NewSymbol("Symbol.species", true)
+	symbolSplit         = NewSymbol("Symbol.split", true)
+	symbolToPrimitive   = NewSymbol("Symbol.toPrimitive", true)
+	symbolToStringTag   = NewSymbol("Symbol.toStringTag", true)
+)
+
+// setupSymbol creates Symbol.prototype and the Symbol function, along with
+// Symbol.for and Symbol.keyFor, which share symbols across a script
+// through a registry keyed by strings.
+func (i *Interpreter) setupSymbol() {
+	proto := i.symbolPrototype
+	i.symbolRegistry = map[string]*Symbol{}
+
+	thisSymbol := func(this Object, method string) (*Symbol, *Error) {
+		s, ok := this.(*Symbol)
+		if !ok {
+			return nil, newTypeError("Symbol.prototype.%s requires that 'this' be a Symbol", method)
+		}
+		return s, nil
+	}
+	i.defineMethod(proto, "toString", func(this Object, args ...Object) Object {
+		s, err := thisSymbol(this, "toString")
+		if err != nil {
+			return err
+		}
+		return &String{Value: s.Inspect()}
+	})
+	i.defineMethod(proto, "valueOf", func(this Object, args ...Object) Object {
+		s, err := thisSymbol(this, "valueOf")
+		if err != nil {
+			return err
+		}
+		return s
+	})
+	proto.Set(symbolToPrimitive, i.newBuiltin("[Symbol.toPrimitive]", func(this Object, args ...Object) Object {
+		s, err := thisSymbol(this, "[Symbol.toPrimitive]")
+		if err != nil {
+			return err
+		}
+		return s
+	}))
+	proto.Set(symbolToStringTag, &String{Value: "Symbol"})
+
+	ctor := i.newBuiltin("Symbol", func(this Object, args ...Object) Object {
+		if argAt(args, 0) == UNDEFINED {
+			return NewSymbol("", false)
+		}
+		description, err := i.toString(args[0])
+		if err != nil {
+			return err
+		}
+		return NewSymbol(description.Value, true)
+	})
+	ctor.notConstructor = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+
+	wellKnown := map[string]*Symbol{
+		"asyncIterator": symbolAsyncIterator,
+		"hasInstance":   symbolHasInstance,
+		"iterator":      symbolIterator,
+		"match":         symbolMatch,
+		"matchAll":      symbolMatchAll,
+		"replace":       symbolReplace,
+		"search":        symbolSearch,
+		"species":       symbolSpecies,
+		"split":         symbolSplit,
+		"toPrimitive":   symbolToPrimitive,
+		"toStringTag":   symbolToStringTag,
+	}
+	for name, symbol := range wellKnown {
+		ctor.Set(&String{Value: name}, symbol)
+	}
+
+	i.defineMethod(&ctor.Hash, "for", func(this Object, args ...Object) Object {
+		key, err := i.stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		if s, ok := i.symbolRegistry[key.Value]; ok {
+			return s
+		}
+		s := NewSymbol(key.Value, true)
+		i.symbolRegistry[key.Value] = s
+		return s
+	})
+	i.defineMethod(&ctor.Hash, "keyFor", func(this Object, args ...Object) Object {
+		s, ok := argAt(args, 0).(*Symbol)
+		if !ok {
+			return newTypeError("%s is not a symbol", inspect(argAt(args, 0), 0))
+		}
+		if registered, ok := i.symbolRegistry[s.Description]; ok && registered == s {
+			return &String{Value: s.Description}
+		}
+		return UNDEFINED
+	})
+
+	i.env.Set("Symbol", ctor)
+}
@@ -0,0 +1,226 @@
+package interpreter
+
+import (
+	"container/heap"
+	"math"
+	"time"
+)
+
+// Timers run a function once a delay has passed, or again and again at an
+// interval:
+//
+//	const id = setInterval(() => console.log("tick"), 1000);
+//	setTimeout(() => clearInterval(id), 3500); // ticks three times
+//
+// A timer that falls due doesn't interrupt the code running now. The event
+// loop fires it once the task queue is empty, in the order the timers are
+// due, and timers due at the same time in the order they were set. The
+// time comes from the interpreter's clock, so with a FakeClock timers fire
+// only when the clock is moved on, without waiting.
+//
+// Like browsers, the delay of a timer set from within a timer that was
+// itself set from a timer, and so on more than five deep, is at least
+// 4ms. This stops a chain of zero-delay timers, or a zero-delay interval,
+// from keeping the loop busy without the clock ever moving.
+
+const (
+	// maxTimerNesting is how deep timers can be set from within timers
+	// before their delays are clamped to minNestedTimerDelay.
+	maxTimerNesting     = 5
+	minNestedTimerDelay = 4 * time.Millisecond
+
+	// maxTimerDelay is the longest delay a timer can have, in
+	// milliseconds. Longer ones fire straight away, as in browsers,
+	// where the delay is a signed 32-bit integer.
+	maxTimerDelay = math.MaxInt32
+)
+
+// timer is a function waiting for its time to run.
+type timer struct {
+	id       int
+	due      time.Time
+	seq      int     // the order timers were scheduled in, to break ties
+	delay    float64 // the delay asked for, in milliseconds
+	interval time.Duration
+	repeat   bool
+	callback Object
+	args     []Object
+
+	// nesting is how deep the timer was set from within other timers.
+	nesting int
+
+	// index is the timer's place in the queue's heap.
+	index int
+}
+
+// timerQueue holds the timers waiting to fire, as a heap with the first
+// one due on top.
+type timerQueue struct {
+	heap   []*timer
+	byID   map[int]*timer
+	nextID int
+	seq    int
+}
+
+func (q *timerQueue) Len() int { return len(q.heap) }
+func (q *timerQueue) Less(a, b int) bool {
+	if !q.heap[a].due.Equal(q.heap[b].due) {
+		return q.heap[a].due.Before(q.heap[b].due)
+	}
+	return q.heap[a].seq < q.heap[b].seq
+}
+func (q *timerQueue) Swap(a, b int) {
+	q.heap[a], q.heap[b] = q.heap[b], q.heap[a]
+	q.heap[a].index = a
+	q.heap[b].index = b
+}
+func (q *timerQueue) Push(x any) {
+	t := x.(*timer)
+	t.index = len(q.heap)
+	q.heap = append(q.heap, t)
+}
+func (q *timerQueue) Pop() any {
+	t := q.heap[len(q.heap)-1]
+	q.heap[len(q.heap)-1] = nil
+	q.heap = q.heap[:len(q.heap)-1]
+	return t
+}
+
+// peek returns the first timer due, or nil if there are none.
+func (q *timerQueue) peek() *timer {
+	if len(q.heap) == 0 {
+		return nil
+	}
+	return q.heap[0]
+}
+
+// schedule adds t to the queue, to fire after its interval.
+func (q *timerQueue) schedule(t *timer, now time.Time) {
+	q.seq++
+	t.seq = q.seq
+	t.due = now.Add(t.interval)
+	heap.Push(q, t)
+}
+
+// cancel removes the timer with the given id, if it's still waiting.
+func (q *timerQueue) cancel(id int) {
+	if t, ok := q.byID[id]; ok {
+		delete(q.byID, id)
+		if t.index >= 0 {
+			heap.Remove(q, t.index)
+		}
+	}
+}
+
+// nextDue removes and returns the first timer if it's due by now.
+func (q *timerQueue) nextDue(now time.Time) *timer {
+	t := q.peek()
+	if t == nil || t.due.After(now) {
+		return nil
+	}
+	heap.Pop(q)
+	t.index = -1
+	return t
+}
+
+// timerDelay works out how long a timer waits from the delay it was set
+// with, in milliseconds, and how deep it is nested.
+func timerDelay(ms float64, nesting int) time.Duration {
+	if math.IsNaN(ms) || ms < 0 || ms > maxTimerDelay {
+		ms = 0
+	}
+	delay := time.Duration(ms * float64(time.Millisecond))
+	if nesting > maxTimerNesting && delay < minNestedTimerDelay {
+		delay = minNestedTimerDelay
+	}
+	return delay
+}
+
+// setTimer schedules callback to be called with args after the delay given
+// in milliseconds, and again at that interval if repeat is set. It returns
+// the timer's id.
+func (i *Interpreter) setTimer(callback Object, delay Object, args []Object, repeat bool) Object {
+	if !isCallable(callback) {
+		return newTypeError("The \"callback\" argument must be of type function. Received %s", inspect(callback, 0))
+	}
+	ms := 0.0
+	if delay != UNDEFINED {
+		n, err := i.toNumber(delay)
+		if err != nil {
+			return err
+		}
+		ms = n
+	}
+
+	q := &i.loop.timers
+	if q.byID == nil {
+		q.byID = map[int]*timer{}
+	}
+	q.nextID++
+	t := &timer{
+		id:       q.nextID,
+		delay:    ms,
+		repeat:   repeat,
+		callback: callback,
+		args:     append([]Object(nil), args...),
+		nesting:  i.loop.timerNesting + 1,
+	}
+	t.interval = timerDelay(t.delay, t.nesting)
+	q.byID[t.id] = t
+	q.schedule(t, i.clock.Now())
+	return &Number{Value: float64(t.id)}
+}
+
+// clearTimer cancels the timer whose id is given, if there is one.
+func (i *Interpreter) clearTimer(id Object) Object {
+	if n, ok := id.(*Number); ok && n.Value == math.Trunc(n.Value) {
+		i.loop.timers.cancel(int(n.Value))
+	}
+	return UNDEFINED
+}
+
+// fireTimer calls a timer's function. An interval is scheduled again
+// before its function runs, so that the function can clear it.
+func (i *Interpreter) fireTimer(t *timer) *Error {
+	if t.repeat {
+		t.nesting++
+		t.interval = timerDelay(t.delay, t.nesting)
+		i.loop.timers.schedule(t, i.clock.Now())
+	} else {
+		delete(i.loop.timers.byID, t.id)
+	}
+
+	outer := i.loop.timerNesting
+	i.loop.timerNesting = t.nesting
+	defer func() { i.loop.timerNesting = outer }()
+	if err, ok := i.applyFunction(t.callback, UNDEFINED, t.args).(*Error); ok {
+		return err
+	}
+	return nil
+}
+
+// setupTimers defines setTimeout, setInterval and the functions clearing
+// them. The two kinds share their ids, so clearTimeout can clear an
+// interval and clearInterval a timeout.
+func (i *Interpreter) setupTimers() {
+	i.env.Set("setTimeout", i.newBuiltin("setTimeout", func(this Object, args ...Object) Object {
+		return i.setTimer(argAt(args, 0), argAt(args, 1), restArgs(args, 2), false)
+	}))
+	i.env.Set("setInterval", i.newBuiltin("setInterval", func(this Object, args ...Object) Object {
+		return i.setTimer(argAt(args, 0), argAt(args, 1), restArgs(args, 2), true)
+	}))
+	i.env.Set("clearTimeout", i.newBuiltin("clearTimeout", func(this Object, args ...Object) Object {
+		return i.clearTimer(argAt(args, 0))
+	}))
+	i.env.Set("clearInterval", i.newBuiltin("clearInterval", func(this Object, args ...Object) Object {
+		return i.clearTimer(argAt(args, 0))
+	}))
+}
+
+// restArgs returns the arguments from the nth on.
+func restArgs(args []Object, n int) []Object {
+	if len(args) <= n {
+		return nil
+	}
+	return args[n:]
+}
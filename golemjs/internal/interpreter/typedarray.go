@@ -0,0 +1,1214 @@
+package interpreter
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Typed arrays are arrays of fixed-size numbers stored as raw bytes in an
+// ArrayBuffer, the way C arrays are: a Uint8Array holds bytes, an
+// Int32Array 32-bit signed integers, a Float64Array doubles, and so on.
+// Several views can share a buffer, so writing through one is visible
+// through the others:
+//
+//	const bytes = new Uint8Array(4);
+//	const words = new Uint32Array(bytes.buffer);
+//	words[0] = 0x01020304;
+//	bytes;  // Uint8Array(4) [ 4, 3, 2, 1 ]
+//
+// Values are converted on the way in. Integers wrap around, so storing 257
+// in a Uint8Array stores 1, except in a Uint8ClampedArray, which clamps
+// them to 0..255 as image data wants. Typed arrays store their elements
+// little-endian; a DataView can read and write either byte order.
+
+// elementType describes the kind of number a typed array holds, or a
+// DataView reads and writes: its size in bytes, and how it is encoded.
+type elementType struct {
+	name   string // as in Int8Array and DataView.prototype.getInt8
+	size   int
+	bigint bool // whether elements are BigInts rather than numbers
+
+	// read decodes an element from b, and write encodes value, which is
+	// a *Number or, for BigInt types, a *BigInt, into b.
+	read  func(b []byte, order binary.ByteOrder) Object
+	write func(b []byte, value Object, order binary.ByteOrder)
+}
+
+// elementTypes lists the element types in the order the typed array
+// constructors are usually listed.
+var elementTypes = []*elementType{
+	{
+		name: "Int8", size: 1,
+		read: func(b []byte, _ binary.ByteOrder) Object { return &Number{Value: float64(int8(b[0]))} },
+		write: func(b []byte, v Object, _ binary.ByteOrder) {
+			b[0] = byte(toInt32(v.(*Number).Value))
+		},
+	},
+	{
+		name: "Uint8", size: 1,
+		read: func(b []byte, _ binary.ByteOrder) Object { return &Number{Value: float64(b[0])} },
+		write: func(b []byte, v Object, _ binary.ByteOrder) {
+			b[0] = byte(toUint32(v.(*Number).Value))
+		},
+	},
+	{
+		name: "Uint8Clamped", size: 1,
+		read: func(b []byte, _ binary.ByteOrder) Object { return &Number{Value: float64(b[0])} },
+		write: func(b []byte, v Object, _ binary.ByteOrder) {
+			f := v.(*Number).Value
+			if math.IsNaN(f) {
+				f = 0
+			}
+			b[0] = byte(math.RoundToEven(math.Min(math.Max(f, 0), 255)))
+		},
+	},
+	{
+		name: "Int16", size: 2,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: float64(int16(order.Uint16(b)))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint16(b, uint16(toUint32(v.(*Number).Value)))
+		},
+	},
+	{
+		name: "Uint16", size: 2,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: float64(order.Uint16(b))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint16(b, uint16(toUint32(v.(*Number).Value)))
+		},
+	},
+	{
+		name: "Int32", size: 4,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: float64(int32(order.Uint32(b)))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint32(b, toUint32(v.(*Number).Value))
+		},
+	},
+	{
+		name: "Uint32", size: 4,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: float64(order.Uint32(b))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint32(b, toUint32(v.(*Number).Value))
+		},
+	},
+	{
+		name: "Float32", size: 4,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: float64(math.Float32frombits(order.Uint32(b)))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint32(b, math.Float32bits(float32(v.(*Number).Value)))
+		},
+	},
+	{
+		name: "Float64", size: 8,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &Number{Value: math.Float64frombits(order.Uint64(b))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint64(b, math.Float64bits(v.(*Number).Value))
+		},
+	},
+	{
+		name: "BigInt64", size: 8, bigint: true,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &BigInt{Value: big.NewInt(int64(order.Uint64(b)))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint64(b, bigIntToUint64(v.(*BigInt).Value))
+		},
+	},
+	{
+		name: "BigUint64", size: 8, bigint: true,
+		read: func(b []byte, order binary.ByteOrder) Object {
+			return &BigInt{Value: new(big.Int).SetUint64(order.Uint64(b))}
+		},
+		write: func(b []byte, v Object, order binary.ByteOrder) {
+			order.PutUint64(b, bigIntToUint64(v.(*BigInt).Value))
+		},
+	},
+}
+
+// uint8Type is the element type of Uint8Array, the type of the bytes
+// TextEncoder produces.
+var uint8Type = elementTypes[1]
+
+// bigIntToUint64 returns the low 64 bits of n in two's complement, which
+// is how BigInt64Array and BigUint64Array wrap BigInts around.
+func bigIntToUint64(n *big.Int) uint64 {
+	return new(big.Int).And(n, new(big.Int).SetUint64(math.MaxUint64)).Uint64()
+}
+
+// convertElement converts value to the kind of number t holds: a BigInt
+// for BigInt64 and BigUint64, and a number for the rest.
+func (i *Interpreter) convertElement(t *elementType, value Object) (Object, *Error) {
+	if t.bigint {
+		n, err := i.toBigInt(value)
+		if err != nil {
+			return nil, err
+		}
+		return &BigInt{Value: n}, nil
+	}
+	n, err := i.toNumber(value)
+	if err != nil {
+		return nil, err
+	}
+	return &Number{Value: n}, nil
+}
+
+// TypedArray is an Int8Array, a Uint8Array, or any of the other typed
+// arrays. It is a view of length elements of buffer starting at
+// byteOffset. A typed array created over a resizable buffer without a
+// length tracks the buffer's size instead, growing and shrinking with it.
+type TypedArray struct {
+	Hash
+	kind       *elementType
+	buffer     *ArrayBuffer
+	byteOffset int
+	length     int
+	tracking   bool
+}
+
+func (ta *TypedArray) Type() ObjectType { return TYPED_ARRAY_OBJ }
+func (ta *TypedArray) Inspect() string {
+	elements := make([]string, ta.len())
+	for idx := range elements {
+		elements[idx] = ta.get(idx).Inspect()
+	}
+	return fmt.Sprintf("%sArray(%d) [%s]", ta.kind.name, len(elements), strings.Join(elements, ", "))
+}
+
+// outOfBounds reports whether the view no longer fits in its buffer,
+// because the buffer was detached or shrunk.
+func (ta *TypedArray) outOfBounds() bool {
+	if ta.buffer.detached {
+		return true
+	}
+	size := len(ta.buffer.data)
+	if ta.tracking {
+		return ta.byteOffset > size
+	}
+	return ta.byteOffset+ta.length*ta.kind.size > size
+}
+
+// len returns the number of elements, which is 0 for an out-of-bounds
+// view.
+func (ta *TypedArray) len() int {
+	if ta.outOfBounds() {
+		return 0
+	}
+	if ta.tracking {
+		return (len(ta.buffer.data) - ta.byteOffset) / ta.kind.size
+	}
+	return ta.length
+}
+
+func (ta *TypedArray) byteLength() int {
+	return ta.len() * ta.kind.size
+}
+
+// bytes returns the bytes of element idx, which must be in range.
+func (ta *TypedArray) bytes(idx int) []byte {
+	start := ta.byteOffset + idx*ta.kind.size
+	return ta.buffer.data[start : start+ta.kind.size]
+}
+
+func (ta *TypedArray) get(idx int) Object {
+	return ta.kind.read(ta.bytes(idx), binary.LittleEndian)
+}
+
+// set stores value, already converted with convertElement, at idx. Writes
+// outside the array are ignored, as they are from scripts.
+func (ta *TypedArray) set(idx int, value Object) {
+	if idx >= 0 && idx < ta.len() {
+		ta.kind.write(ta.bytes(idx), value, binary.LittleEndian)
+	}
+}
+
+// element reads the element at idx, or undefined past the end, which lets
+// an ArrayIterator walk a typed array.
+func (ta *TypedArray) element(idx int) Object {
+	if idx >= ta.len() {
+		return UNDEFINED
+	}
+	return ta.get(idx)
+}
+
+// values returns a copy of the elements.
+func (ta *TypedArray) values() []Object {
+	values := make([]Object, ta.len())
+	for idx := range values {
+		values[idx] = ta.get(idx)
+	}
+	return values
+}
+
+// accessor returns the value of the accessor properties typed arrays
+// inherit from %TypedArray%.prototype.
+func (ta *TypedArray) accessor(name string) (Object, bool) {
+	switch name {
+	case "length":
+		return &Number{Value: float64(ta.len())}, true
+	case "byteLength":
+		return &Number{Value: float64(ta.byteLength())}, true
+	case "byteOffset":
+		if ta.outOfBounds() {
+			return &Number{Value: 0}, true
+		}
+		return &Number{Value: float64(ta.byteOffset)}, true
+	case "buffer":
+		return ta.buffer, true
+	}
+	return nil, false
+}
+
+// canonicalNumericIndex reports whether a property name is a number in
+// its canonical form, like "1", "-1" or "1.5" but not "01", and returns
+// the number. Typed arrays treat all such names as element indices, so
+// that ta[-1] and ta[1.5] are always undefined rather than properties.
+func canonicalNumericIndex(name string) (float64, bool) {
+	if name == "-0" {
+		return math.Copysign(0, -1), true
+	}
+	n := stringToNumber(name)
+	return n, numberToString(n) == name
+}
+
+// index converts a numeric property name to an element index of ta,
+// reporting false if it isn't one.
+func (ta *TypedArray) index(n float64) (int, bool) {
+	if !isInteger(n) || n == 0 && math.Signbit(n) || n < 0 || n >= float64(ta.len()) {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// newTypedArray creates a typed array of kind t with a new buffer of
+// length elements.
+func (i *Interpreter) newTypedArray(t *elementType, length int) (*TypedArray, *Error) {
+	buffer, err := i.newArrayBuffer(length*t.size, -1)
+	if err != nil {
+		return nil, err
+	}
+	return &TypedArray{Hash: Hash{Prototype: i.typedArrayPrototypes[t]}, kind: t, buffer: buffer, length: length}, nil
+}
+
+// newTypedArrayFrom creates a typed array of kind t holding values, which
+// are converted first.
+func (i *Interpreter) newTypedArrayFrom(t *elementType, values []Object) (*TypedArray, *Error) {
+	converted := make([]Object, len(values))
+	for idx, value := range values {
+		v, err := i.convertElement(t, value)
+		if err != nil {
+			return nil, err
+		}
+		converted[idx] = v
+	}
+	ta, err := i.newTypedArray(t, len(values))
+	if err != nil {
+		return nil, err
+	}
+	for idx, v := range converted {
+		ta.set(idx, v)
+	}
+	return ta, nil
+}
+
+// thisTypedArray checks that a %TypedArray%.prototype method was called on
+// a typed array that is still in bounds.
+func thisTypedArray(this Object, method string) (*TypedArray, *Error) {
+	ta, ok := this.(*TypedArray)
+	if !ok {
+		return nil, newTypeError("this is not a typed array.")
+	}
+	if ta.buffer.detached {
+		return nil, newTypeError("Cannot perform %%TypedArray%%.prototype.%s on a detached ArrayBuffer", method)
+	}
+	if ta.outOfBounds() {
+		return nil, newTypeError("Cannot perform %%TypedArray%%.prototype.%s on an out of bounds TypedArray", method)
+	}
+	return ta, nil
+}
+
+// typedArrayConstructor implements new Int8Array(...) and the other typed
+// array constructors, which accept:
+//
+//	new Uint8Array(length)                  // zero-filled
+//	new Uint8Array(typedArray)              // a copy, converted
+//	new Uint8Array(iterableOrArrayLike)     // a copy, converted
+//	new Uint8Array(buffer, byteOffset, length)  // a view of buffer
+func (i *Interpreter) typedArrayConstructor(t *elementType) BuiltinFunction {
+	name := t.name + "Array"
+	return func(this Object, args ...Object) Object {
+		first := argAt(args, 0)
+		switch source := first.(type) {
+		case *ArrayBuffer:
+			ta, err := i.typedArrayOverBuffer(t, source, argAt(args, 1), argAt(args, 2))
+			if err != nil {
+				return err
+			}
+			return ta
+		case *TypedArray:
+			if source.buffer.detached || source.outOfBounds() {
+				return newTypeError("Cannot perform Construct on a detached ArrayBuffer")
+			}
+			if source.kind.bigint != t.bigint {
+				return newTypeError("Cannot mix BigInt and other types, use explicit conversions")
+			}
+			ta, err := i.newTypedArrayFrom(t, source.values())
+			if err != nil {
+				return err
+			}
+			return ta
+		}
+		if !isObject(first) {
+			length, err := i.toIndex(first, "Invalid typed array length: %s", first.Inspect())
+			if err != nil {
+				return err
+			}
+			ta, err := i.newTypedArray(t, length)
+			if err != nil {
+				return err
+			}
+			return ta
+		}
+		values, err := i.listFromIterableOrArrayLike(first, name)
+		if err != nil {
+			return err
+		}
+		ta, err := i.newTypedArrayFrom(t, values)
+		if err != nil {
+			return err
+		}
+		return ta
+	}
+}
+
+// typedArrayOverBuffer creates a typed array of kind t viewing buffer.
+func (i *Interpreter) typedArrayOverBuffer(t *elementType, buffer *ArrayBuffer, offsetArg, lengthArg Object) (*TypedArray, *Error) {
+	name := t.name + "Array"
+	offset, err := i.toIndex(offsetArg, "Start offset %s is outside the bounds of the buffer", offsetArg.Inspect())
+	if err != nil {
+		return nil, err
+	}
+	if offset%t.size != 0 {
+		return nil, newRangeError("start offset of %s should be a multiple of %d", name, t.size)
+	}
+	length := 0
+	if lengthArg != UNDEFINED {
+		if length, err = i.toIndex(lengthArg, "Invalid typed array length: %s", lengthArg.Inspect()); err != nil {
+			return nil, err
+		}
+	}
+	if buffer.detached {
+		return nil, newTypeError("Cannot perform Construct on a detached ArrayBuffer")
+	}
+	ta := &TypedArray{Hash: Hash{Prototype: i.typedArrayPrototypes[t]}, kind: t, buffer: buffer, byteOffset: offset}
+	size := len(buffer.data)
+	switch {
+	case lengthArg == UNDEFINED && buffer.resizable:
+		if offset > size {
+			return nil, newRangeError("Start offset %d is outside the bounds of the buffer", offset)
+		}
+		ta.tracking = true
+	case lengthArg == UNDEFINED:
+		if size%t.size != 0 {
+			return nil, newRangeError("byte length of %s should be a multiple of %d", name, t.size)
+		}
+		if offset > size {
+			return nil, newRangeError("Start offset %d is outside the bounds of the buffer", offset)
+		}
+		ta.length = (size - offset) / t.size
+	default:
+		if offset+length*t.size > size {
+			return nil, newRangeError("Invalid typed array length: %d", length)
+		}
+		ta.length = length
+	}
+	return ta, nil
+}
+
+// listFromIterableOrArrayLike reads the values of an object that is
+// either iterable, like an array or a Set, or array-like, with a length
+// and indexed properties.
+func (i *Interpreter) listFromIterableOrArrayLike(source Object, name string) ([]Object, *Error) {
+	var values []Object
+	usingIterator := i.getProperty(source, symbolIterator)
+	if err, ok := usingIterator.(*Error); ok {
+		return nil, err
+	}
+	if !isNullish(usingIterator) {
+		if !isCallable(usingIterator) {
+			return nil, newTypeError("%s is not iterable", inspect(source, 0))
+		}
+		err := i.iterate(source, func(value Object) *Error {
+			values = append(values, value)
+			return nil
+		})
+		return values, err
+	}
+	lengthValue := i.getProperty(source, &String{Value: "length"})
+	if err, ok := lengthValue.(*Error); ok {
+		return nil, err
+	}
+	length, err := i.toLength(lengthValue)
+	if err != nil {
+		return nil, err
+	}
+	if length > maxArrayBufferLength {
+		return nil, newRangeError("Invalid typed array length: %d", length)
+	}
+	for idx := 0; idx < length; idx++ {
+		value := i.getProperty(source, &Number{Value: float64(idx)})
+		if err, ok := value.(*Error); ok {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// setupTypedArrays creates %TypedArray%.prototype, which holds the methods
+// every typed array shares, and a constructor and prototype for each
+// element type.
+func (i *Interpreter) setupTypedArrays() {
+	proto := i.typedArrayPrototype
+
+	i.defineMethod(proto, "at", i.typedArrayAt)
+	i.defineMethod(proto, "copyWithin", i.typedArrayCopyWithin)
+	i.defineMethod(proto, "entries", i.typedArrayIteratorMethod("entries"))
+	i.defineMethod(proto, "every", i.typedArrayEvery)
+	i.defineMethod(proto, "fill", i.typedArrayFill)
+	i.defineMethod(proto, "filter", i.typedArrayFilter)
+	i.defineMethod(proto, "find", i.typedArrayFind(false, false))
+	i.defineMethod(proto, "findIndex", i.typedArrayFind(false, true))
+	i.defineMethod(proto, "findLast", i.typedArrayFind(true, false))
+	i.defineMethod(proto, "findLastIndex", i.typedArrayFind(true, true))
+	i.defineMethod(proto, "forEach", i.typedArrayForEach)
+	i.defineMethod(proto, "includes", i.typedArraySearch("includes"))
+	i.defineMethod(proto, "indexOf", i.typedArraySearch("indexOf"))
+	i.defineMethod(proto, "join", i.typedArrayJoin)
+	i.defineMethod(proto, "keys", i.typedArrayIteratorMethod("keys"))
+	i.defineMethod(proto, "lastIndexOf", i.typedArraySearch("lastIndexOf"))
+	i.defineMethod(proto, "map", i.typedArrayMap)
+	i.defineMethod(proto, "reduce", i.typedArrayReduce(false))
+	i.defineMethod(proto, "reduceRight", i.typedArrayReduce(true))
+	i.defineMethod(proto, "reverse", i.typedArrayReverse)
+	i.defineMethod(proto, "set", i.typedArraySet)
+	i.defineMethod(proto, "slice", i.typedArraySlice)
+	i.defineMethod(proto, "some", i.typedArraySome)
+	i.defineMethod(proto, "sort", i.typedArraySort(false))
+	i.defineMethod(proto, "subarray", i.typedArraySubarray)
+	i.defineMethod(proto, "toReversed", i.typedArrayToReversed)
+	i.defineMethod(proto, "toSorted", i.typedArraySort(true))
+	i.defineMethod(proto, "toString", i.typedArrayToString)
+	i.defineMethod(proto, "values", i.typedArrayIteratorMethod("values"))
+	i.defineMethod(proto, "with", i.typedArrayWith)
+	values, _ := proto.GetOwn(&String{Value: "values"})
+	proto.Set(symbolIterator, values)
+
+	i.typedArrayPrototypes = map[*elementType]*Hash{}
+	for _, t := range elementTypes {
+		name := t.name + "Array"
+		kindProto := NewHash(proto)
+		i.typedArrayPrototypes[t] = kindProto
+
+		ctor := i.newBuiltin(name, i.typedArrayConstructor(t))
+		ctor.requiresNew = true
+		ctor.Set(&String{Value: "prototype"}, kindProto)
+		ctor.Set(&String{Value: "BYTES_PER_ELEMENT"}, &Number{Value: float64(t.size)})
+		kindProto.Set(&String{Value: "constructor"}, ctor)
+		kindProto.Set(&String{Value: "BYTES_PER_ELEMENT"}, &Number{Value: float64(t.size)})
+		kindProto.Set(symbolToStringTag, &String{Value: name})
+
+		i.defineMethod(&ctor.Hash, "from", i.typedArrayFromMethod(t))
+		i.defineMethod(&ctor.Hash, "of", func(this Object, args ...Object) Object {
+			ta, err := i.newTypedArrayFrom(t, args)
+			if err != nil {
+				return err
+			}
+			return ta
+		})
+		i.env.Set(name, ctor)
+	}
+}
+
+// typedArrayFromMethod implements Uint8Array.from(source, mapFn, thisArg)
+// and its siblings.
+func (i *Interpreter) typedArrayFromMethod(t *elementType) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		mapFn := argAt(args, 1)
+		if mapFn != UNDEFINED && !isCallable(mapFn) {
+			return newTypeError("%s is not a function", mapFn.Inspect())
+		}
+		source := argAt(args, 0)
+		if isNullish(source) {
+			return newTypeError("%s is not iterable", source.Inspect())
+		}
+		values, err := i.listFromIterableOrArrayLike(source, t.name+"Array")
+		if err != nil {
+			return err
+		}
+		if mapFn != UNDEFINED {
+			for idx, value := range values {
+				mapped := i.applyFunction(mapFn, argAt(args, 2), []Object{value, &Number{Value: float64(idx)}})
+				if isError(mapped) {
+					return mapped
+				}
+				values[idx] = mapped
+			}
+		}
+		ta, err := i.newTypedArrayFrom(t, values)
+		if err != nil {
+			return err
+		}
+		return ta
+	}
+}
+
+func (i *Interpreter) typedArrayIteratorMethod(kind string) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		ta, err := thisTypedArray(this, kind)
+		if err != nil {
+			return err
+		}
+		return &ArrayIterator{Hash: Hash{Prototype: i.arrayIteratorPrototype}, array: ta, kind: kind}
+	}
+}
+
+func (i *Interpreter) typedArrayAt(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "at")
+	if err != nil {
+		return err
+	}
+	idx, err := i.toIntegerOrInfinity(argAt(args, 0))
+	if err != nil {
+		return err
+	}
+	length := float64(ta.len())
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return UNDEFINED
+	}
+	return ta.get(int(idx))
+}
+
+// typedArrayRange reads the start and end arguments of methods like fill
+// and slice, at positions n and n+1.
+func (i *Interpreter) typedArrayRange(args []Object, n int, length int) (int, int, *Error) {
+	start, err := i.relativeIndex(argAt(args, n), length, 0)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := i.relativeIndex(argAt(args, n+1), length, length)
+	if err != nil {
+		return 0, 0, err
+	}
+	return start, end, nil
+}
+
+func (i *Interpreter) typedArrayCopyWithin(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "copyWithin")
+	if err != nil {
+		return err
+	}
+	length := ta.len()
+	target, err := i.relativeIndex(argAt(args, 0), length, 0)
+	if err != nil {
+		return err
+	}
+	start, end, err := i.typedArrayRange(args, 1, length)
+	if err != nil {
+		return err
+	}
+	// The arguments may have shrunk the buffer.
+	if ta.outOfBounds() {
+		return newTypeError("Cannot perform %%TypedArray%%.prototype.copyWithin on a detached ArrayBuffer")
+	}
+	count := min(end-start, length-target, ta.len()-start, ta.len()-target)
+	if count > 0 {
+		size := ta.kind.size
+		data := ta.buffer.data[ta.byteOffset:]
+		copy(data[target*size:(target+count)*size], data[start*size:(start+count)*size])
+	}
+	return ta
+}
+
+// typedArrayVisit calls fn(element, index, array) for each element, from
+// the last if backward is set, stopping early if stop returns true for a
+// callback result. Elements are read as they are reached, so the callback
+// sees changes made earlier in the loop.
+func (i *Interpreter) typedArrayVisit(ta *TypedArray, args []Object, backward bool, stop func(result Object, value Object, idx int) bool) *Error {
+	fn, err := callbackArg(args)
+	if err != nil {
+		return err
+	}
+	thisArg := argAt(args, 1)
+	length := ta.len()
+	for n := 0; n < length; n++ {
+		idx := n
+		if backward {
+			idx = length - 1 - n
+		}
+		value := ta.element(idx)
+		result := i.applyFunction(fn, thisArg, []Object{value, &Number{Value: float64(idx)}, ta})
+		if err, ok := result.(*Error); ok {
+			return err
+		}
+		if stop(result, value, idx) {
+			return nil
+		}
+	}
+	return nil
+}
+
+func (i *Interpreter) typedArrayEvery(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "every")
+	if err != nil {
+		return err
+	}
+	every := true
+	if err := i.typedArrayVisit(ta, args, false, func(result, value Object, idx int) bool {
+		every = isTruthy(result)
+		return !every
+	}); err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(every)
+}
+
+func (i *Interpreter) typedArraySome(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "some")
+	if err != nil {
+		return err
+	}
+	some := false
+	if err := i.typedArrayVisit(ta, args, false, func(result, value Object, idx int) bool {
+		some = isTruthy(result)
+		return some
+	}); err != nil {
+		return err
+	}
+	return nativeBoolToBooleanObject(some)
+}
+
+func (i *Interpreter) typedArrayForEach(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "forEach")
+	if err != nil {
+		return err
+	}
+	if err := i.typedArrayVisit(ta, args, false, func(result, value Object, idx int) bool {
+		return false
+	}); err != nil {
+		return err
+	}
+	return UNDEFINED
+}
+
+// typedArrayFind implements find, findIndex, findLast and findLastIndex.
+func (i *Interpreter) typedArrayFind(last, index bool) BuiltinFunction {
+	name := map[[2]bool]string{{false, false}: "find", {false, true}: "findIndex", {true, false}: "findLast", {true, true}: "findLastIndex"}[[2]bool{last, index}]
+	return func(this Object, args ...Object) Object {
+		ta, err := thisTypedArray(this, name)
+		if err != nil {
+			return err
+		}
+		var found Object = UNDEFINED
+		if index {
+			found = &Number{Value: -1}
+		}
+		if err := i.typedArrayVisit(ta, args, last, func(result, value Object, idx int) bool {
+			if !isTruthy(result) {
+				return false
+			}
+			found = value
+			if index {
+				found = &Number{Value: float64(idx)}
+			}
+			return true
+		}); err != nil {
+			return err
+		}
+		return found
+	}
+}
+
+func (i *Interpreter) typedArrayFill(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "fill")
+	if err != nil {
+		return err
+	}
+	value, err := i.convertElement(ta.kind, argAt(args, 0))
+	if err != nil {
+		return err
+	}
+	start, end, err := i.typedArrayRange(args, 1, ta.len())
+	if err != nil {
+		return err
+	}
+	if ta.outOfBounds() {
+		return newTypeError("Cannot perform %%TypedArray%%.prototype.fill on a detached ArrayBuffer")
+	}
+	if err := i.charge(end - start); err != nil {
+		return err
+	}
+	for idx := start; idx < end; idx++ {
+		ta.set(idx, value)
+	}
+	return ta
+}
+
+func (i *Interpreter) typedArrayFilter(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "filter")
+	if err != nil {
+		return err
+	}
+	var kept []Object
+	if err := i.typedArrayVisit(ta, args, false, func(result, value Object, idx int) bool {
+		if isTruthy(result) {
+			kept = append(kept, value)
+		}
+		return false
+	}); err != nil {
+		return err
+	}
+	out, err := i.newTypedArrayFrom(ta.kind, kept)
+	if err != nil {
+		return err
+	}
+	return out
+}
+
+func (i *Interpreter) typedArrayMap(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "map")
+	if err != nil {
+		return err
+	}
+	out, err := i.newTypedArray(ta.kind, ta.len())
+	if err != nil {
+		return err
+	}
+	var mapErr *Error
+	if err := i.typedArrayVisit(ta, args, false, func(result, value Object, idx int) bool {
+		converted, err := i.convertElement(out.kind, result)
+		if err != nil {
+			mapErr = err
+			return true
+		}
+		out.set(idx, converted)
+		return false
+	}); err != nil {
+		return err
+	}
+	if mapErr != nil {
+		return mapErr
+	}
+	return out
+}
+
+// typedArraySearch implements includes, indexOf and lastIndexOf. Like
+// their array versions, includes finds NaN and the others don't.
+func (i *Interpreter) typedArraySearch(name string) BuiltinFunction {
+	return func(this Object, args ...Object) Object {
+		ta, err := thisTypedArray(this, name)
+		if err != nil {
+			return err
+		}
+		length := ta.len()
+		notFound := Object(&Number{Value: -1})
+		if name == "includes" {
+			notFound = FALSE
+		}
+		if length == 0 {
+			return notFound
+		}
+		if err := i.charge(length); err != nil {
+			return err
+		}
+		target := argAt(args, 0)
+		equal := strictEquals
+		if name == "includes" {
+			equal = sameValueZero
+		}
+		if name == "lastIndexOf" {
+			from := float64(length - 1)
+			if len(args) > 1 {
+				if from, err = i.toIntegerOrInfinity(args[1]); err != nil {
+					return err
+				}
+			}
+			if from < 0 {
+				from += float64(length)
+			}
+			for idx := int(math.Min(from, float64(length-1))); idx >= 0; idx-- {
+				if equal(ta.element(idx), target) {
+					return &Number{Value: float64(idx)}
+				}
+			}
+			return notFound
+		}
+		from, err := i.relativeIndex(argAt(args, 1), length, 0)
+		if err != nil {
+			return err
+		}
+		for idx := from; idx < length; idx++ {
+			// The fromIndex argument may have shrunk the array, and
+			// includes reads the missing elements as undefined.
+			if equal(ta.element(idx), target) {
+				if name == "includes" {
+					return TRUE
+				}
+				return &Number{Value: float64(idx)}
+			}
+		}
+		return notFound
+	}
+}
+
+func (i *Interpreter) typedArrayJoin(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "join")
+	if err != nil {
+		return err
+	}
+	sep := ","
+	if argAt(args, 0) != UNDEFINED {
+		s, err := i.toString(args[0])
+		if err != nil {
+			return err
+		}
+		sep = s.Value
+	}
+	if err := i.charge(ta.len()); err != nil {
+		return err
+	}
+	return &String{Value: joinTypedArray(ta, sep)}
+}
+
+func (i *Interpreter) typedArrayToString(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "toString")
+	if err != nil {
+		return err
+	}
+	return &String{Value: joinTypedArray(ta, ",")}
+}
+
+func joinTypedArray(ta *TypedArray, sep string) string {
+	parts := make([]string, ta.len())
+	for idx := range parts {
+		switch v := ta.get(idx).(type) {
+		case *Number:
+			parts[idx] = numberToString(v.Value)
+		case *BigInt:
+			parts[idx] = v.Value.String()
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// typedArrayReduce implements reduce and reduceRight.
+func (i *Interpreter) typedArrayReduce(right bool) BuiltinFunction {
+	name := "reduce"
+	if right {
+		name = "reduceRight"
+	}
+	return func(this Object, args ...Object) Object {
+		ta, err := thisTypedArray(this, name)
+		if err != nil {
+			return err
+		}
+		fn, err := callbackArg(args)
+		if err != nil {
+			return err
+		}
+		length := ta.len()
+		indices := make([]int, length)
+		for n := range indices {
+			indices[n] = n
+			if right {
+				indices[n] = length - 1 - n
+			}
+		}
+		var acc Object
+		if len(args) > 1 {
+			acc = args[1]
+		} else {
+			if length == 0 {
+				return newTypeError("Reduce of empty array with no initial value")
+			}
+			acc = ta.get(indices[0])
+			indices = indices[1:]
+		}
+		for _, idx := range indices {
+			acc = i.applyFunction(fn, UNDEFINED, []Object{acc, ta.element(idx), &Number{Value: float64(idx)}, ta})
+			if isError(acc) {
+				return acc
+			}
+		}
+		return acc
+	}
+}
+
+func (i *Interpreter) typedArrayReverse(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "reverse")
+	if err != nil {
+		return err
+	}
+	values := ta.values()
+	for idx, value := range values {
+		ta.set(len(values)-1-idx, value)
+	}
+	return ta
+}
+
+func (i *Interpreter) typedArrayToReversed(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "toReversed")
+	if err != nil {
+		return err
+	}
+	values := ta.values()
+	for left, right := 0, len(values)-1; left < right; left, right = left+1, right-1 {
+		values[left], values[right] = values[right], values[left]
+	}
+	out, err := i.newTypedArrayFrom(ta.kind, values)
+	if err != nil {
+		return err
+	}
+	return out
+}
+
+// typedArraySet implements set(source, offset), which copies an array or
+// typed array into this one, starting at offset. When source is a typed
+// array sharing the same buffer, it is read in full before anything is
+// written, so overlapping copies work.
+func (i *Interpreter) typedArraySet(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "set")
+	if err != nil {
+		return err
+	}
+	offset, err := i.toIntegerOrInfinity(argAt(args, 1))
+	if err != nil {
+		return err
+	}
+	if offset < 0 {
+		return newRangeError("offset is out of bounds")
+	}
+	var values []Object
+	switch source := argAt(args, 0).(type) {
+	case *TypedArray:
+		if source.outOfBounds() {
+			return newTypeError("Cannot perform %%TypedArray%%.prototype.set on a detached ArrayBuffer")
+		}
+		if source.kind.bigint != ta.kind.bigint {
+			return newTypeError("Cannot mix BigInt and other types, use explicit conversions")
+		}
+		values = source.values()
+	default:
+		if isNullish(source) {
+			return newTypeError("Cannot convert undefined or null to object")
+		}
+		lengthValue := i.getProperty(source, &String{Value: "length"})
+		if isError(lengthValue) {
+			return lengthValue
+		}
+		length, err := i.toLength(lengthValue)
+		if err != nil {
+			return err
+		}
+		if offset+float64(length) > float64(ta.len()) {
+			return newRangeError("offset is out of bounds")
+		}
+		for idx := 0; idx < length; idx++ {
+			value := i.getProperty(source, &Number{Value: float64(idx)})
+			if isError(value) {
+				return value
+			}
+			converted, err := i.convertElement(ta.kind, value)
+			if err != nil {
+				return err
+			}
+			ta.set(int(offset)+idx, converted)
+		}
+		return UNDEFINED
+	}
+	if offset+float64(len(values)) > float64(ta.len()) {
+		return newRangeError("offset is out of bounds")
+	}
+	for idx, value := range values {
+		ta.set(int(offset)+idx, value)
+	}
+	return UNDEFINED
+}
+
+func (i *Interpreter) typedArraySlice(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "slice")
+	if err != nil {
+		return err
+	}
+	start, end, err := i.typedArrayRange(args, 0, ta.len())
+	if err != nil {
+		return err
+	}
+	if ta.outOfBounds() {
+		return newTypeError("Cannot perform %%TypedArray%%.prototype.slice on a detached ArrayBuffer")
+	}
+	end = min(end, ta.len())
+	var values []Object
+	for idx := start; idx < end; idx++ {
+		values = append(values, ta.get(idx))
+	}
+	out, err := i.newTypedArrayFrom(ta.kind, values)
+	if err != nil {
+		return err
+	}
+	return out
+}
+
+// typedArraySubarray implements subarray(begin, end), which unlike slice
+// doesn't copy: it creates a new view of the same buffer.
+func (i *Interpreter) typedArraySubarray(this Object, args ...Object) Object {
+	ta, ok := this.(*TypedArray)
+	if !ok {
+		return newTypeError("this is not a typed array.")
+	}
+	length := ta.len()
+	start, end, err := i.typedArrayRange(args, 0, length)
+	if err != nil {
+		return err
+	}
+	sub := &TypedArray{
+		Hash:       Hash{Prototype: i.typedArrayPrototypes[ta.kind]},
+		kind:       ta.kind,
+		buffer:     ta.buffer,
+		byteOffset: ta.byteOffset + start*ta.kind.size,
+		length:     max(end-start, 0),
+	}
+	if ta.tracking && argAt(args, 1) == UNDEFINED {
+		sub.tracking = true
+	}
+	return sub
+}
+
+// typedArraySort implements sort and, if copy is set, toSorted. Without a
+// comparison function, the elements are sorted numerically rather than as
+// strings, with -0 before +0 and NaN at the end.
+func (i *Interpreter) typedArraySort(copy bool) BuiltinFunction {
+	name := "sort"
+	if copy {
+		name = "toSorted"
+	}
+	return func(this Object, args ...Object) Object {
+		compareFn := argAt(args, 0)
+		if compareFn != UNDEFINED && !isCallable(compareFn) {
+			return newTypeError("The comparison function must be either a function or undefined")
+		}
+		ta, err := thisTypedArray(this, name)
+		if err != nil {
+			return err
+		}
+		values := ta.values()
+		var sortErr *Error
+		sort.SliceStable(values, func(a, b int) bool {
+			if sortErr != nil {
+				return false
+			}
+			if compareFn == UNDEFINED {
+				return lessElement(values[a], values[b])
+			}
+			result := i.applyFunction(compareFn, UNDEFINED, []Object{values[a], values[b]})
+			if err, ok := result.(*Error); ok {
+				sortErr = err
+				return false
+			}
+			n, err := i.toNumber(result)
+			if err != nil {
+				sortErr = err
+				return false
+			}
+			return n < 0
+		})
+		if sortErr != nil {
+			return sortErr
+		}
+		target := ta
+		if copy {
+			if target, err = i.newTypedArray(ta.kind, len(values)); err != nil {
+				return err
+			}
+		}
+		for idx, value := range values {
+			target.set(idx, value)
+		}
+		return target
+	}
+}
+
+// lessElement is the default order of typed array elements.
+func lessElement(a, b Object) bool {
+	if x, ok := a.(*BigInt); ok {
+		return x.Value.Cmp(b.(*BigInt).Value) < 0
+	}
+	x, y := a.(*Number).Value, b.(*Number).Value
+	switch {
+	case math.IsNaN(x):
+		return false
+	case math.IsNaN(y):
+		return true
+	case x == 0 && y == 0:
+		return math.Signbit(x) && !math.Signbit(y)
+	}
+	return x < y
+}
+
+// typedArrayWith implements with(index, value), which returns a copy with
+// one element replaced.
+func (i *Interpreter) typedArrayWith(this Object, args ...Object) Object {
+	ta, err := thisTypedArray(this, "with")
+	if err != nil {
+		return err
+	}
+	length := ta.len()
+	idx, err := i.toIntegerOrInfinity(argAt(args, 0))
+	if err != nil {
+		return err
+	}
+	if idx < 0 {
+		idx += float64(length)
+	}
+	value, err := i.convertElement(ta.kind, argAt(args, 1))
+	if err != nil {
+		return err
+	}
+	if idx < 0 || idx >= float64(ta.len()) {
+		return newRangeError("Invalid typed array index")
+	}
+	out, err := i.newTypedArrayFrom(ta.kind, ta.values())
+	if err != nil {
+		return err
+	}
+	out.set(int(idx), value)
+	return out
+}
+
+// typedArrayKeys returns the indices of a typed array's elements as
+// property keys.
+func typedArrayKeys(ta *TypedArray) []Object {
+	keys := make([]Object, ta.len())
+	for idx := range keys {
+		keys[idx] = &String{Value: strconv.Itoa(idx)}
+	}
+	return keys
+}
@@ -0,0 +1,702 @@
+package interpreter
+
+import (
+	"math"
+	"math/big"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/bytecode"
+	"github.com/biosbuddha/golemjs/internal/compiler"
+)
+
+// Backend selects how the interpreter runs scripts.
+type Backend int
+
+const (
+	// TreeWalker evaluates the AST directly, node by node.
+	TreeWalker Backend = iota
+	// BytecodeVM compiles scripts to bytecode first, and runs that on a
+	// stack-based virtual machine. Statements the compiler can't
+	// translate are still run by the tree-walker; see the compiler
+	// package.
+	BytecodeVM
+)
+
+func (b Backend) String() string {
+	if b == BytecodeVM {
+		return "bytecode VM"
+	}
+	return "tree-walker"
+}
+
+// SetBackend selects how Eval runs scripts. Modules, and the CommonJS
+// modules require loads, are always run by the tree-walker.
+func (i *Interpreter) SetBackend(b Backend) {
+	i.backend = b
+}
+
+// cell holds a local variable that a closure captures. The function
+// declaring the variable and every closure capturing it share the cell,
+// so that they all see each other's assignments. An empty cell holds a
+// variable that hasn't been declared yet.
+type cell struct {
+	value Object
+}
+
+func (c *cell) Type() ObjectType { return CELL_OBJ }
+func (c *cell) Inspect() string  { return "cell" }
+
+// vmFunction is a compiled function ready to run: its bytecode, with the
+// constants that are JavaScript values converted once and for all.
+type vmFunction struct {
+	code      *compiler.CompiledFunction
+	constants []Object      // the constants that are values, by index
+	functions []*vmFunction // the functions defined in it, by constant index
+	caches    []propertyCache
+}
+
+func newVMFunction(code *compiler.CompiledFunction) *vmFunction {
+	f := &vmFunction{
+		code:      code,
+		constants: make([]Object, len(code.Constants)),
+		functions: make([]*vmFunction, len(code.Constants)),
+		caches:    make([]propertyCache, code.NumCaches),
+	}
+	for idx, constant := range code.Constants {
+		switch c := constant.(type) {
+		case float64:
+			f.constants[idx] = newNumber(c)
+		case string:
+			f.constants[idx] = &String{Value: c}
+		case *big.Int:
+			f.constants[idx] = &BigInt{Value: c}
+		case *compiler.CompiledFunction:
+			f.functions[idx] = newVMFunction(c)
+		}
+	}
+	return f
+}
+
+// smallNumbers holds the numbers for the integers from -128 to 1023, which
+// are shared instead of allocated anew for every result.
+var smallNumbers [1152]*Number
+
+func init() {
+	for idx := range smallNumbers {
+		smallNumbers[idx] = &Number{Value: float64(idx - 128)}
+	}
+}
+
+// newNumber returns a number holding v. Numbers are never modified, so
+// the small integers that loop counters and indexes mostly are can be
+// shared.
+func newNumber(v float64) *Number {
+	if v >= -128 && v < 1024 && v == math.Trunc(v) && !(v == 0 && math.Signbit(v)) {
+		return smallNumbers[int(v)+128]
+	}
+	return &Number{Value: v}
+}
+
+// runProgram compiles a script and runs it.
+func (i *Interpreter) runProgram(program *ast.Program) Object {
+	main := newVMFunction(compiler.Compile(program))
+	return i.run(main, nil, make([]Object, main.code.NumLocals()))
+}
+
+// callCompiled calls a function compiled to bytecode. The arguments go
+// into the first slots of the new frame; parameters without one start out
+// undefined, and a rest parameter gets an array of those left over.
+func (i *Interpreter) callCompiled(fn *Function, this Object, args []Object) Object {
+	code := fn.compiled.code
+	locals := make([]Object, code.NumLocals())
+	params := code.NumParams
+	if code.Rest {
+		params--
+	}
+	copy(locals[:params], args)
+	for idx := len(args); idx < params; idx++ {
+		locals[idx] = UNDEFINED
+	}
+	if code.Rest {
+		rest := []Object{}
+		if params < len(args) {
+			rest = append(rest, args[params:]...)
+		}
+		locals[params] = i.newArray(rest)
+	}
+	if code.ThisSlot >= 0 {
+		if this == nil {
+			this = UNDEFINED
+		}
+		locals[code.ThisSlot] = this
+	}
+	if code.SelfSlot >= 0 {
+		locals[code.SelfSlot] = fn
+	}
+	return i.run(fn.compiled, fn.free, locals)
+}
+
+// binaryOperators gives the operator of each operator instruction, for
+// operands without a fast path, which go through evalInfixExpression.
+var binaryOperators = map[bytecode.Opcode]string{
+	bytecode.OpAdd:            "+",
+	bytecode.OpSub:            "-",
+	bytecode.OpMul:            "*",
+	bytecode.OpDiv:            "/",
+	bytecode.OpMod:            "%",
+	bytecode.OpLess:           "<",
+	bytecode.OpGreater:        ">",
+	bytecode.OpLessEqual:      "<=",
+	bytecode.OpGreaterEqual:   ">=",
+	bytecode.OpStrictEqual:    "===",
+	bytecode.OpStrictNotEqual: "!==",
+}
+
+// run executes the bytecode of f in a frame whose slots are locals, with
+// free holding the cells f closed over. It returns what the function
+// returns, or the error it throws. For the program itself, that is its
+// completion value: the value of the last statement that had one.
+//
+// The operations themselves are those of the tree-walker, so both
+// backends behave the same; what the VM saves is walking the tree, and
+// looking variables up by name.
+//
+// Leaving the frame in the middle of a for...of loop closes the loop's
+// iterator, as leaving it early any other way does.
+func (i *Interpreter) run(f *vmFunction, free []*cell, locals []Object) Object {
+	var iterators []*iterator
+	result := i.execute(f, free, locals, &iterators)
+	for idx := len(iterators) - 1; idx >= 0; idx-- {
+		result = i.closeIterator(iterators[idx], result)
+	}
+	return result
+}
+
+// execute runs the instructions of a frame for run, keeping the iterators
+// of the for...of loops running in it in iterators.
+func (i *Interpreter) execute(f *vmFunction, free []*cell, locals []Object, iterators *[]*iterator) Object {
+	ins := f.code.Instructions
+	stack := make([]Object, 0, 16)
+	var completion Object
+
+	for ip := 0; ; {
+		if err := i.tick(); err != nil {
+			return err
+		}
+		op := bytecode.Opcode(ins[ip])
+		ip++
+		switch op {
+		case bytecode.OpConstant:
+			stack = append(stack, f.constants[bytecode.ReadUint16(ins[ip:])])
+			ip += 2
+		case bytecode.OpUndefined:
+			stack = append(stack, UNDEFINED)
+		case bytecode.OpNull:
+			stack = append(stack, NULL)
+		case bytecode.OpTrue:
+			stack = append(stack, TRUE)
+		case bytecode.OpFalse:
+			stack = append(stack, FALSE)
+		case bytecode.OpHole:
+			stack = append(stack, nil)
+
+		case bytecode.OpPop:
+			stack = stack[:len(stack)-1]
+		case bytecode.OpDup:
+			stack = append(stack, stack[len(stack)-1])
+		case bytecode.OpDup2:
+			stack = append(stack, stack[len(stack)-2], stack[len(stack)-1])
+
+		case bytecode.OpAdd, bytecode.OpSub, bytecode.OpMul, bytecode.OpDiv, bytecode.OpMod,
+			bytecode.OpLess, bytecode.OpGreater, bytecode.OpLessEqual, bytecode.OpGreaterEqual,
+			bytecode.OpStrictEqual, bytecode.OpStrictNotEqual:
+			right := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			left := stack[len(stack)-1]
+			result := numberOperation(op, left, right)
+			if result == nil {
+				result = i.evalInfixExpression(binaryOperators[op], left, right)
+				if isError(result) {
+					return result
+				}
+			}
+			stack[len(stack)-1] = result
+		case bytecode.OpBinary:
+			operator := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			right := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			result := i.evalInfixExpression(operator, stack[len(stack)-1], right)
+			if isError(result) {
+				return result
+			}
+			stack[len(stack)-1] = result
+		case bytecode.OpNot:
+			stack[len(stack)-1] = nativeBoolToBooleanObject(!isTruthy(stack[len(stack)-1]))
+		case bytecode.OpNegate, bytecode.OpPlus:
+			var result Object
+			if n, ok := stack[len(stack)-1].(*Number); ok {
+				if op == bytecode.OpNegate {
+					result = newNumber(-n.Value)
+				} else {
+					result = n
+				}
+			} else if op == bytecode.OpNegate {
+				result = i.evalMinusPrefixOperatorExpression(stack[len(stack)-1])
+			} else {
+				result = i.evalPrefixExpression("+", stack[len(stack)-1])
+			}
+			if isError(result) {
+				return result
+			}
+			stack[len(stack)-1] = result
+		case bytecode.OpTypeof:
+			stack[len(stack)-1] = &String{Value: typeOf(stack[len(stack)-1])}
+		case bytecode.OpVoid:
+			stack[len(stack)-1] = UNDEFINED
+		case bytecode.OpUpdate:
+			flags := int(ins[ip])
+			ip++
+			result, updated := i.update(stack[len(stack)-1], flags)
+			if isError(updated) {
+				return updated
+			}
+			stack[len(stack)-1] = result
+			stack = append(stack, updated)
+		case bytecode.OpUpdateProperty:
+			flags := int(ins[ip])
+			ip++
+			object, key := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			current := i.getProperty(object, key)
+			if isError(current) {
+				return current
+			}
+			result, updated := i.update(current, flags)
+			if isError(updated) {
+				return updated
+			}
+			if err := i.setProperty(object, key, updated); err != nil {
+				return err
+			}
+			stack = append(stack, result)
+
+		case bytecode.OpJump:
+			ip = int(bytecode.ReadUint16(ins[ip:]))
+		case bytecode.OpJumpIfFalse:
+			condition := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if !isTruthy(condition) {
+				ip = int(bytecode.ReadUint16(ins[ip:]))
+			} else {
+				ip += 2
+			}
+		case bytecode.OpJumpIfFalseKeep, bytecode.OpJumpIfTrueKeep:
+			if isTruthy(stack[len(stack)-1]) == (op == bytecode.OpJumpIfTrueKeep) {
+				ip = int(bytecode.ReadUint16(ins[ip:]))
+			} else {
+				stack = stack[:len(stack)-1]
+				ip += 2
+			}
+
+		case bytecode.OpGetGlobal:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			value, ok := i.env.Get(name)
+			if !ok {
+				return i.evalIdentifier(&ast.Identifier{Value: name}, i.env)
+			}
+			stack = append(stack, value)
+		case bytecode.OpSetGlobal:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			if result := i.assignVariable(name, stack[len(stack)-1], i.env); isError(result) {
+				return result
+			}
+		case bytecode.OpDefineGlobal:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			kind := bytecode.DeclarationKinds[ins[ip+2]]
+			ip += 3
+			value := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if result := i.bindName(name, value, kind, i.env); isError(result) {
+				return result
+			}
+		case bytecode.OpDeclareGlobalVar:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			if !i.env.FunctionScope().HasOwn(name) {
+				i.bindName(name, UNDEFINED, "var", i.env)
+			}
+		case bytecode.OpTypeofGlobal:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			value, ok := i.env.Get(name)
+			if !ok {
+				value = UNDEFINED
+			}
+			stack = append(stack, &String{Value: typeOf(value)})
+		case bytecode.OpDeleteGlobal:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			_, declared := i.env.Get(name)
+			stack = append(stack, nativeBoolToBooleanObject(!declared))
+		case bytecode.OpGetLocal:
+			slot := bytecode.ReadUint16(ins[ip:])
+			ip += 2
+			value := locals[slot]
+			if value == nil {
+				return newReferenceError("%s is not defined", f.code.LocalNames[slot])
+			}
+			stack = append(stack, value)
+		case bytecode.OpSetLocal:
+			locals[bytecode.ReadUint16(ins[ip:])] = stack[len(stack)-1]
+			ip += 2
+		case bytecode.OpInitLocal:
+			slot := bytecode.ReadUint16(ins[ip:])
+			ip += 2
+			if locals[slot] == nil {
+				locals[slot] = UNDEFINED
+			}
+		case bytecode.OpGetCell:
+			slot := bytecode.ReadUint16(ins[ip:])
+			ip += 2
+			value := locals[slot].(*cell).value
+			if value == nil {
+				return newReferenceError("%s is not defined", f.code.LocalNames[slot])
+			}
+			stack = append(stack, value)
+		case bytecode.OpSetCell:
+			locals[bytecode.ReadUint16(ins[ip:])].(*cell).value = stack[len(stack)-1]
+			ip += 2
+		case bytecode.OpInitCell:
+			c := locals[bytecode.ReadUint16(ins[ip:])].(*cell)
+			ip += 2
+			if c.value == nil {
+				c.value = UNDEFINED
+			}
+		case bytecode.OpGetFree:
+			idx := ins[ip]
+			ip++
+			value := free[idx].value
+			if value == nil {
+				return newReferenceError("%s is not defined", f.code.FreeNames[idx])
+			}
+			stack = append(stack, value)
+		case bytecode.OpSetFree:
+			free[ins[ip]].value = stack[len(stack)-1]
+			ip++
+		case bytecode.OpNewCell:
+			locals[bytecode.ReadUint16(ins[ip:])] = &cell{}
+			ip += 2
+		case bytecode.OpBoxLocal:
+			slot := bytecode.ReadUint16(ins[ip:])
+			ip += 2
+			locals[slot] = &cell{value: locals[slot]}
+		case bytecode.OpCopyCell:
+			slot := bytecode.ReadUint16(ins[ip:])
+			ip += 2
+			locals[slot] = &cell{value: locals[slot].(*cell).value}
+		case bytecode.OpLoadCell:
+			stack = append(stack, locals[bytecode.ReadUint16(ins[ip:])])
+			ip += 2
+		case bytecode.OpLoadFreeCell:
+			stack = append(stack, free[ins[ip]])
+			ip++
+		case bytecode.OpConstAssign:
+			return newTypeError("Assignment to constant variable.")
+		case bytecode.OpSetFunctionName:
+			name := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			nameAnonymousFunction(stack[len(stack)-1], name)
+
+		case bytecode.OpArray:
+			count := int(bytecode.ReadUint16(ins[ip:]))
+			ip += 2
+			elements := make([]Object, count)
+			copy(elements, stack[len(stack)-count:])
+			stack = stack[:len(stack)-count]
+			stack = append(stack, i.newArray(elements))
+		case bytecode.OpAppend:
+			value := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			array := stack[len(stack)-1].(*Array)
+			array.Elements = append(array.Elements, value)
+		case bytecode.OpAppendSpread:
+			iterable := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			values, err := i.iterableToList(iterable)
+			if err != nil {
+				return err
+			}
+			array := stack[len(stack)-1].(*Array)
+			array.Elements = append(array.Elements, values...)
+		case bytecode.OpObject:
+			stack = append(stack, NewHash(i.objectPrototype))
+		case bytecode.OpDefineProperty:
+			key, value := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			name, err := i.toPropertyKey(key)
+			if err != nil {
+				return err
+			}
+			if fn, ok := value.(*Function); ok && fn.Name == "" {
+				fn.Name = functionNameForKey(name)
+			}
+			stack[len(stack)-1].(*Hash).Set(name, value)
+		case bytecode.OpGetProperty:
+			key := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			value := i.getProperty(stack[len(stack)-1], key)
+			if isError(value) {
+				return value
+			}
+			stack[len(stack)-1] = value
+		case bytecode.OpGetNamed:
+			key := f.constants[bytecode.ReadUint16(ins[ip:])].(*String)
+			cache := &f.caches[bytecode.ReadUint16(ins[ip+2:])]
+			ip += 4
+			value := i.getNamed(stack[len(stack)-1], key, cache)
+			if isError(value) {
+				return value
+			}
+			stack[len(stack)-1] = value
+		case bytecode.OpSetProperty:
+			object, key, value := stack[len(stack)-3], stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-3]
+			if err := i.setProperty(object, key, value); err != nil {
+				return err
+			}
+			stack = append(stack, value)
+		case bytecode.OpSetNamed:
+			key := f.constants[bytecode.ReadUint16(ins[ip:])].(*String)
+			cache := &f.caches[bytecode.ReadUint16(ins[ip+2:])]
+			ip += 4
+			object, value := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			if err := i.setNamed(object, key, value, cache); err != nil {
+				return err
+			}
+			stack = append(stack, value)
+		case bytecode.OpDeleteProperty:
+			object, key := stack[len(stack)-2], stack[len(stack)-1]
+			stack = stack[:len(stack)-2]
+			result := i.deleteMember(object, key)
+			if isError(result) {
+				return result
+			}
+			stack = append(stack, result)
+		case bytecode.OpPeekProperty:
+			value := i.getProperty(stack[len(stack)-2], stack[len(stack)-1])
+			if isError(value) {
+				return value
+			}
+			stack = append(stack, value)
+		case bytecode.OpGetMethod:
+			key := f.constants[bytecode.ReadUint16(ins[ip:])].(*String)
+			cache := &f.caches[bytecode.ReadUint16(ins[ip+2:])]
+			ip += 4
+			function := i.getNamed(stack[len(stack)-1], key, cache)
+			if isError(function) {
+				return function
+			}
+			stack = append(stack, function)
+		case bytecode.OpGetMethodKey:
+			key := stack[len(stack)-1]
+			function := i.getProperty(stack[len(stack)-2], key)
+			if isError(function) {
+				return function
+			}
+			stack[len(stack)-1] = function
+		case bytecode.OpRegExp:
+			node := f.code.Constants[bytecode.ReadUint16(ins[ip:])].(*ast.RegExpLiteral)
+			ip += 2
+			re := i.evalRegExpLiteral(node)
+			if isError(re) {
+				return re
+			}
+			stack = append(stack, re)
+
+		case bytecode.OpImport:
+			// Only scripts are compiled, so the module is imported
+			// from a script.
+			stack[len(stack)-1] = i.importDynamic(stack[len(stack)-1], "")
+
+		case bytecode.OpGetIterator:
+			it, err := i.getIterator(stack[len(stack)-1])
+			if err != nil {
+				return err
+			}
+			stack = stack[:len(stack)-1]
+			*iterators = append(*iterators, it)
+		case bytecode.OpIterNext:
+			it := (*iterators)[len(*iterators)-1]
+			value, done, err := i.iteratorStep(it)
+			if err != nil || done {
+				// An iterator that failed or finished isn't closed.
+				*iterators = (*iterators)[:len(*iterators)-1]
+				if err != nil {
+					return err
+				}
+				ip = int(bytecode.ReadUint16(ins[ip:]))
+				continue
+			}
+			ip += 2
+			stack = append(stack, value)
+		case bytecode.OpIterClose:
+			it := (*iterators)[len(*iterators)-1]
+			*iterators = (*iterators)[:len(*iterators)-1]
+			if err := i.iteratorClose(it); err != nil {
+				return err
+			}
+
+		case bytecode.OpCheckCallable:
+			callee := f.constants[bytecode.ReadUint16(ins[ip:])].(*String).Value
+			ip += 2
+			if !isCallable(stack[len(stack)-1]) {
+				return newTypeError("%s is not a function", callee)
+			}
+		case bytecode.OpCall, bytecode.OpNew:
+			argc := int(ins[ip])
+			ip++
+			args := make([]Object, argc)
+			copy(args, stack[len(stack)-argc:])
+			stack = stack[:len(stack)-argc]
+			var result Object
+			if op == bytecode.OpNew {
+				result = i.construct(stack[len(stack)-1], args)
+				stack = stack[:len(stack)-1]
+			} else {
+				result = i.applyFunction(stack[len(stack)-1], stack[len(stack)-2], args)
+				stack = stack[:len(stack)-2]
+			}
+			if isError(result) {
+				return result
+			}
+			stack = append(stack, result)
+		case bytecode.OpCallArray, bytecode.OpNewArray:
+			args := stack[len(stack)-1].(*Array).Elements
+			stack = stack[:len(stack)-1]
+			var result Object
+			if op == bytecode.OpNewArray {
+				result = i.construct(stack[len(stack)-1], args)
+				stack = stack[:len(stack)-1]
+			} else {
+				result = i.applyFunction(stack[len(stack)-1], stack[len(stack)-2], args)
+				stack = stack[:len(stack)-2]
+			}
+			if isError(result) {
+				return result
+			}
+			stack = append(stack, result)
+		case bytecode.OpClosure:
+			idx := bytecode.ReadUint16(ins[ip:])
+			count := int(ins[ip+2])
+			ip += 3
+			code := f.functions[idx]
+			fn := i.newFunction(code.code.Name, code.code.Parameters, code.code.Body, nil, code.code.Arrow)
+			fn.compiled = code
+			fn.free = make([]*cell, count)
+			for j, c := range stack[len(stack)-count:] {
+				fn.free[j] = c.(*cell)
+			}
+			stack = stack[:len(stack)-count]
+			stack = append(stack, fn)
+		case bytecode.OpReturn:
+			return stack[len(stack)-1]
+		case bytecode.OpReturnUndefined:
+			return UNDEFINED
+
+		case bytecode.OpSetCompletion:
+			completion = stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+		case bytecode.OpClearCompletion:
+			completion = nil
+		case bytecode.OpReturnCompletion:
+			return completion
+		case bytecode.OpEvalNode:
+			node := f.code.Constants[bytecode.ReadUint16(ins[ip:])].(ast.Node)
+			ip += 2
+			result := i.eval(node, i.env)
+			switch result := result.(type) {
+			case *Error:
+				return result
+			case *ReturnValue:
+				return result.Value
+			case *Break, *Continue:
+				return nil
+			}
+			completion = result
+		case bytecode.OpHoistNode:
+			decl := f.code.Constants[bytecode.ReadUint16(ins[ip:])].(*ast.FunctionDeclaration)
+			ip += 2
+			i.hoistFunctions([]ast.Statement{decl}, i.env)
+
+		default:
+			return newError("unknown opcode %d", op)
+		}
+	}
+}
+
+// numberOperation applies an operator instruction to two numbers without
+// any of the conversions evalInfixExpression would try first. It returns
+// nil if either operand isn't a number.
+func numberOperation(op bytecode.Opcode, left, right Object) Object {
+	l, ok := left.(*Number)
+	if !ok {
+		return nil
+	}
+	r, ok := right.(*Number)
+	if !ok {
+		return nil
+	}
+	switch op {
+	case bytecode.OpAdd:
+		return newNumber(l.Value + r.Value)
+	case bytecode.OpSub:
+		return newNumber(l.Value - r.Value)
+	case bytecode.OpMul:
+		return newNumber(l.Value * r.Value)
+	case bytecode.OpDiv:
+		return newNumber(l.Value / r.Value)
+	case bytecode.OpMod:
+		return newNumber(math.Mod(l.Value, r.Value))
+	case bytecode.OpLess:
+		return nativeBoolToBooleanObject(l.Value < r.Value)
+	case bytecode.OpGreater:
+		return nativeBoolToBooleanObject(l.Value > r.Value)
+	case bytecode.OpLessEqual:
+		return nativeBoolToBooleanObject(l.Value <= r.Value)
+	case bytecode.OpGreaterEqual:
+		return nativeBoolToBooleanObject(l.Value >= r.Value)
+	case bytecode.OpStrictEqual:
+		return nativeBoolToBooleanObject(l.Value == r.Value)
+	case bytecode.OpStrictNotEqual:
+		return nativeBoolToBooleanObject(l.Value != r.Value)
+	}
+	return nil
+}
+
+// update applies ++ or -- to current, as evalUpdateExpression does. It
+// returns the result of the expression, which is the old value converted
+// to a number unless the update is a prefix one, and the updated value.
+func (i *Interpreter) update(current Object, flags int) (Object, Object) {
+	delta := 1.0
+	if flags&bytecode.UpdateDecrement != 0 {
+		delta = -1
+	}
+	n, err := i.toNumeric(current)
+	if err != nil {
+		return nil, err
+	}
+	var updated Object
+	if b, ok := n.(*BigInt); ok {
+		updated = &BigInt{Value: new(big.Int).Add(b.Value, big.NewInt(int64(delta)))}
+	} else {
+		updated = newNumber(n.(*Number).Value + delta)
+	}
+	if flags&bytecode.UpdatePrefix != 0 {
+		return updated, updated
+	}
+	return n, updated
+}
@@ -0,0 +1,522 @@
+package interpreter
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"unsafe"
+	"weak"
+)
+
+// WeakMap, WeakSet, WeakRef and FinalizationRegistry refer to objects
+// without keeping them alive:
+//
+//	const metadata = new WeakMap();
+//	metadata.set(node, { visited: true }); // gone once node is
+//
+// The interpreter's values are ordinary Go values, so it's Go's garbage
+// collector that decides when an object is no longer reachable, and these
+// collections are built on the weak pointers and cleanups the Go runtime
+// provides. Cleanups run on a goroutine of the runtime's, not the
+// interpreter's, so anything they touch is guarded by a mutex, and work
+// that has to run JavaScript, such as a FinalizationRegistry callback, is
+// queued for the event loop to run as a task.
+//
+// One guarantee of the language can't be given this way: a WeakMap is
+// supposed to hold a value only as long as its key is alive, even when the
+// value refers back to the key. Here the map holds its values strongly, so
+// an entry whose value refers to its own key is never collected.
+
+// weakObject is a weak reference to any object. Objects are pointers of
+// many different Go types, so the reference keeps the pointer's type next
+// to an untyped weak pointer to rebuild the object from. Two weakObjects
+// made from the same object are equal, which lets them be map keys.
+type weakObject struct {
+	ptr weak.Pointer[byte]
+	typ reflect.Type
+}
+
+func makeWeak(obj Object) weakObject {
+	return weakObject{ptr: weak.Make(objectPointer(obj)), typ: reflect.TypeOf(obj)}
+}
+
+// objectPointer returns the address of obj, which the runtime uses to
+// track its lifetime.
+func objectPointer(obj Object) *byte {
+	return (*byte)(reflect.ValueOf(obj).UnsafePointer())
+}
+
+// value returns the object, or nil if it has been collected.
+func (w weakObject) value() Object {
+	p := w.ptr.Value()
+	if p == nil {
+		return nil
+	}
+	return reflect.NewAt(w.typ.Elem(), unsafe.Pointer(p)).Interface().(Object)
+}
+
+// canBeHeldWeakly reports whether v can be the key of a WeakMap or the
+// target of a WeakRef: any object, or a symbol that isn't shared through
+// Symbol.for, since those can be recreated from their key at any time.
+func (i *Interpreter) canBeHeldWeakly(v Object) bool {
+	if s, ok := v.(*Symbol); ok {
+		registered, ok := i.symbolRegistry[s.Description]
+		return !ok || registered != s
+	}
+	return isObject(v)
+}
+
+// keepDuringJob keeps obj alive until the running script finishes, so that
+// a script that has just created or dereferenced a WeakRef can rely on its
+// target for the rest of the script.
+func (i *Interpreter) keepDuringJob(obj Object) {
+	i.keptAlive = append(i.keptAlive, obj)
+}
+
+// weakTable holds the entries of a WeakMap or WeakSet. When a key is
+// collected, a cleanup removes its entry, letting the value go too.
+type weakTable struct {
+	mu      sync.Mutex
+	entries map[weakObject]*weakEntry
+}
+
+type weakEntry struct {
+	value   Object
+	cleanup runtime.Cleanup
+}
+
+// weakTableCleanup is what the cleanup for a key needs to find its entry.
+// It refers to the table weakly, so that a table doesn't stay alive just
+// because some of its keys do.
+type weakTableCleanup struct {
+	table weak.Pointer[weakTable]
+	key   weakObject
+}
+
+func newWeakTable() *weakTable {
+	return &weakTable{entries: map[weakObject]*weakEntry{}}
+}
+
+func (t *weakTable) get(key Object) (Object, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	e, ok := t.entries[makeWeak(key)]
+	if !ok {
+		return nil, false
+	}
+	return e.value, true
+}
+
+func (t *weakTable) set(key, value Object) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := makeWeak(key)
+	if e, ok := t.entries[k]; ok {
+		e.value = value
+		return
+	}
+	e := &weakEntry{value: value}
+	e.cleanup = runtime.AddCleanup(objectPointer(key), removeWeakEntry, weakTableCleanup{table: weak.Make(t), key: k})
+	t.entries[k] = e
+}
+
+func (t *weakTable) delete(key Object) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	k := makeWeak(key)
+	e, ok := t.entries[k]
+	if !ok {
+		return false
+	}
+	e.cleanup.Stop()
+	delete(t.entries, k)
+	return true
+}
+
+// removeWeakEntry is the cleanup that runs once a key has been collected.
+func removeWeakEntry(c weakTableCleanup) {
+	t := c.table.Value()
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, c.key)
+}
+
+// WeakMap is the object created by new WeakMap(): a map whose keys are
+// held weakly. Its entries can't be listed, since which of them still
+// exist depends on when the garbage collector runs.
+type WeakMap struct {
+	Hash
+	table *weakTable
+}
+
+func (m *WeakMap) Type() ObjectType { return WEAK_MAP_OBJ }
+func (m *WeakMap) Inspect() string  { return "WeakMap { <items unknown> }" }
+
+// WeakSet is the object created by new WeakSet(): a set of weakly held
+// values.
+type WeakSet struct {
+	Hash
+	table *weakTable
+}
+
+func (s *WeakSet) Type() ObjectType { return WEAK_SET_OBJ }
+func (s *WeakSet) Inspect() string  { return "WeakSet { <items unknown> }" }
+
+// WeakRef is the object created by new WeakRef(target). Its deref method
+// returns the target, or undefined once the target has been collected.
+type WeakRef struct {
+	Hash
+	target weakObject
+}
+
+func (r *WeakRef) Type() ObjectType { return WEAK_REF_OBJ }
+func (r *WeakRef) Inspect() string  { return "WeakRef {}" }
+
+// FinalizationRegistry is the object created by new
+// FinalizationRegistry(callback). Objects registered with it have callback
+// called with a value of the script's choosing after they are collected.
+type FinalizationRegistry struct {
+	Hash
+	callback Object
+	cells    map[*finalizationCell]bool
+}
+
+func (r *FinalizationRegistry) Type() ObjectType { return FINALIZATION_REGISTRY_OBJ }
+func (r *FinalizationRegistry) Inspect() string  { return "FinalizationRegistry {}" }
+
+// finalizationCell is one registration: the value to pass to the
+// registry's callback, and the token that unregisters it.
+type finalizationCell struct {
+	registry weak.Pointer[FinalizationRegistry]
+	held     Object
+	token    weakObject
+	hasToken bool
+	cleanup  runtime.Cleanup
+	queue    *cleanupQueue
+}
+
+// cleanupQueue collects the cells whose targets have been collected, until
+// the interpreter gets round to calling their registries' callbacks.
+type cleanupQueue struct {
+	mu    sync.Mutex
+	cells []*finalizationCell
+}
+
+// queueFinalization is the cleanup that runs once a registered target has
+// been collected.
+func queueFinalization(cell *finalizationCell) {
+	cell.queue.mu.Lock()
+	defer cell.queue.mu.Unlock()
+	cell.queue.cells = append(cell.queue.cells, cell)
+}
+
+// pending reports whether any cells are waiting for their callbacks.
+func (q *cleanupQueue) pending() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.cells) > 0
+}
+
+// runFinalizations calls the registry callbacks for targets collected so
+// far. The event loop runs it as a task whenever targets have been
+// collected. It returns the first error a callback fails with.
+func (i *Interpreter) runFinalizations() *Error {
+	i.cleanups.mu.Lock()
+	cells := i.cleanups.cells
+	i.cleanups.cells = nil
+	i.cleanups.mu.Unlock()
+
+	var firstErr *Error
+	for _, cell := range cells {
+		registry := cell.registry.Value()
+		if registry == nil || !registry.cells[cell] {
+			// The registry is gone, or the cell was unregistered after
+			// its target was collected.
+			continue
+		}
+		delete(registry.cells, cell)
+		if err, ok := i.applyFunction(registry.callback, UNDEFINED, []Object{cell.held}).(*Error); ok && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// setupWeakCollections creates WeakMap, WeakSet, WeakRef and
+// FinalizationRegistry with their prototypes.
+func (i *Interpreter) setupWeakCollections() {
+	i.cleanups = &cleanupQueue{}
+	i.setupWeakMap()
+	i.setupWeakSet()
+	i.setupWeakRef()
+	i.setupFinalizationRegistry()
+}
+
+func (i *Interpreter) setupWeakMap() {
+	proto := i.weakMapPrototype
+
+	thisWeakMap := func(this Object, method string) (*WeakMap, *Error) {
+		m, ok := this.(*WeakMap)
+		if !ok {
+			return nil, newTypeError("Method WeakMap.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return m, nil
+	}
+	i.defineMethod(proto, "get", func(this Object, args ...Object) Object {
+		m, err := thisWeakMap(this, "get")
+		if err != nil {
+			return err
+		}
+		key := argAt(args, 0)
+		if !i.canBeHeldWeakly(key) {
+			return UNDEFINED
+		}
+		if value, ok := m.table.get(key); ok {
+			return value
+		}
+		return UNDEFINED
+	})
+	i.defineMethod(proto, "set", func(this Object, args ...Object) Object {
+		m, err := thisWeakMap(this, "set")
+		if err != nil {
+			return err
+		}
+		key := argAt(args, 0)
+		if !i.canBeHeldWeakly(key) {
+			return newTypeError("Invalid value used as weak map key")
+		}
+		m.table.set(key, argAt(args, 1))
+		return m
+	})
+	i.defineMethod(proto, "has", func(this Object, args ...Object) Object {
+		m, err := thisWeakMap(this, "has")
+		if err != nil {
+			return err
+		}
+		key := argAt(args, 0)
+		if !i.canBeHeldWeakly(key) {
+			return FALSE
+		}
+		_, ok := m.table.get(key)
+		return nativeBoolToBooleanObject(ok)
+	})
+	i.defineMethod(proto, "delete", func(this Object, args ...Object) Object {
+		m, err := thisWeakMap(this, "delete")
+		if err != nil {
+			return err
+		}
+		key := argAt(args, 0)
+		if !i.canBeHeldWeakly(key) {
+			return FALSE
+		}
+		return nativeBoolToBooleanObject(m.table.delete(key))
+	})
+	proto.Set(symbolToStringTag, &String{Value: "WeakMap"})
+
+	ctor := i.newBuiltin("WeakMap", func(this Object, args ...Object) Object {
+		m := &WeakMap{Hash: Hash{Prototype: proto}, table: newWeakTable()}
+		iterable := argAt(args, 0)
+		if isNullish(iterable) {
+			return m
+		}
+		err := i.iterate(iterable, func(entry Object) *Error {
+			if !isObject(entry) {
+				return newTypeError("Iterator value %s is not an entry object", inspect(entry, 0))
+			}
+			key := i.getProperty(entry, &String{Value: "0"})
+			if err, ok := key.(*Error); ok {
+				return err
+			}
+			value := i.getProperty(entry, &String{Value: "1"})
+			if err, ok := value.(*Error); ok {
+				return err
+			}
+			if !i.canBeHeldWeakly(key) {
+				return newTypeError("Invalid value used as weak map key")
+			}
+			m.table.set(key, value)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return m
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("WeakMap", ctor)
+}
+
+func (i *Interpreter) setupWeakSet() {
+	proto := i.weakSetPrototype
+
+	thisWeakSet := func(this Object, method string) (*WeakSet, *Error) {
+		s, ok := this.(*WeakSet)
+		if !ok {
+			return nil, newTypeError("Method WeakSet.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return s, nil
+	}
+	i.defineMethod(proto, "add", func(this Object, args ...Object) Object {
+		s, err := thisWeakSet(this, "add")
+		if err != nil {
+			return err
+		}
+		value := argAt(args, 0)
+		if !i.canBeHeldWeakly(value) {
+			return newTypeError("Invalid value used in weak set")
+		}
+		s.table.set(value, TRUE)
+		return s
+	})
+	i.defineMethod(proto, "has", func(this Object, args ...Object) Object {
+		s, err := thisWeakSet(this, "has")
+		if err != nil {
+			return err
+		}
+		value := argAt(args, 0)
+		if !i.canBeHeldWeakly(value) {
+			return FALSE
+		}
+		_, ok := s.table.get(value)
+		return nativeBoolToBooleanObject(ok)
+	})
+	i.defineMethod(proto, "delete", func(this Object, args ...Object) Object {
+		s, err := thisWeakSet(this, "delete")
+		if err != nil {
+			return err
+		}
+		value := argAt(args, 0)
+		if !i.canBeHeldWeakly(value) {
+			return FALSE
+		}
+		return nativeBoolToBooleanObject(s.table.delete(value))
+	})
+	proto.Set(symbolToStringTag, &String{Value: "WeakSet"})
+
+	ctor := i.newBuiltin("WeakSet", func(this Object, args ...Object) Object {
+		s := &WeakSet{Hash: Hash{Prototype: proto}, table: newWeakTable()}
+		iterable := argAt(args, 0)
+		if isNullish(iterable) {
+			return s
+		}
+		err := i.iterate(iterable, func(value Object) *Error {
+			if !i.canBeHeldWeakly(value) {
+				return newTypeError("Invalid value used in weak set")
+			}
+			s.table.set(value, TRUE)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return s
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("WeakSet", ctor)
+}
+
+func (i *Interpreter) setupWeakRef() {
+	proto := i.weakRefPrototype
+
+	i.defineMethod(proto, "deref", func(this Object, args ...Object) Object {
+		r, ok := this.(*WeakRef)
+		if !ok {
+			return newTypeError("Method WeakRef.prototype.deref called on incompatible receiver %s", inspectReceiver(this))
+		}
+		target := r.target.value()
+		if target == nil {
+			return UNDEFINED
+		}
+		i.keepDuringJob(target)
+		return target
+	})
+	proto.Set(symbolToStringTag, &String{Value: "WeakRef"})
+
+	ctor := i.newBuiltin("WeakRef", func(this Object, args ...Object) Object {
+		target := argAt(args, 0)
+		if !i.canBeHeldWeakly(target) {
+			return newTypeError("WeakRef: invalid target")
+		}
+		i.keepDuringJob(target)
+		return &WeakRef{Hash: Hash{Prototype: proto}, target: makeWeak(target)}
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("WeakRef", ctor)
+}
+
+func (i *Interpreter) setupFinalizationRegistry() {
+	proto := i.finalizationRegistryPrototype
+
+	thisRegistry := func(this Object, method string) (*FinalizationRegistry, *Error) {
+		r, ok := this.(*FinalizationRegistry)
+		if !ok {
+			return nil, newTypeError("Method FinalizationRegistry.prototype.%s called on incompatible receiver %s", method, inspectReceiver(this))
+		}
+		return r, nil
+	}
+	i.defineMethod(proto, "register", func(this Object, args ...Object) Object {
+		r, err := thisRegistry(this, "register")
+		if err != nil {
+			return err
+		}
+		target, held, token := argAt(args, 0), argAt(args, 1), argAt(args, 2)
+		if !i.canBeHeldWeakly(target) {
+			return newTypeError("FinalizationRegistry.prototype.register: invalid target")
+		}
+		if strictEquals(target, held) {
+			return newTypeError("FinalizationRegistry.prototype.register: target and holdings must not be same")
+		}
+		cell := &finalizationCell{registry: weak.Make(r), held: held, queue: i.cleanups}
+		if token != UNDEFINED {
+			if !i.canBeHeldWeakly(token) {
+				return newTypeError("FinalizationRegistry.prototype.register: invalid unregister token")
+			}
+			cell.token, cell.hasToken = makeWeak(token), true
+		}
+		cell.cleanup = runtime.AddCleanup(objectPointer(target), queueFinalization, cell)
+		r.cells[cell] = true
+		return UNDEFINED
+	})
+	i.defineMethod(proto, "unregister", func(this Object, args ...Object) Object {
+		r, err := thisRegistry(this, "unregister")
+		if err != nil {
+			return err
+		}
+		token := argAt(args, 0)
+		if !i.canBeHeldWeakly(token) {
+			return newTypeError("Invalid unregisterToken ('%s')", inspect(token, 0))
+		}
+		removed := false
+		k := makeWeak(token)
+		for cell := range r.cells {
+			if cell.hasToken && cell.token == k {
+				cell.cleanup.Stop()
+				delete(r.cells, cell)
+				removed = true
+			}
+		}
+		return nativeBoolToBooleanObject(removed)
+	})
+	proto.Set(symbolToStringTag, &String{Value: "FinalizationRegistry"})
+
+	ctor := i.newBuiltin("FinalizationRegistry", func(this Object, args ...Object) Object {
+		callback := argAt(args, 0)
+		if !isCallable(callback) {
+			return newTypeError("FinalizationRegistry: cleanup must be callable")
+		}
+		return &FinalizationRegistry{Hash: Hash{Prototype: proto}, callback: callback, cells: map[*finalizationCell]bool{}}
+	})
+	ctor.requiresNew = true
+	ctor.Set(&String{Value: "prototype"}, proto)
+	proto.Set(&String{Value: "constructor"}, ctor)
+	i.env.Set("FinalizationRegistry", ctor)
+}
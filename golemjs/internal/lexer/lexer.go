@@ -12,37 +12,92 @@ const (
 
 	// Identifiers + literals
 	IDENT  TokenType = "IDENT"  // Variable names, function names, etc. (e.g., "x", "add", "foobar")
-	INT    TokenType = "INT"    // Integer literals (e.g., "123", "42")
+	INT    TokenType = "INT"    // Number literals (e.g., "123", "4.2", "1e3", "0xFF")
+	BIGINT TokenType = "BIGINT" // BigInt literals (e.g., "123n", "0xFFn")
 	STRING TokenType = "STRING" // String literals (e.g., "hello", "world")
+	REGEXP TokenType = "REGEXP" // Regular expression literals (e.g., /ab+c/gi)
 
 	// Operators
-	ASSIGN   TokenType = "="  // Assignment operator (e.g., x = 42)
-	PLUS     TokenType = "+"  // Addition operator
-	MINUS    TokenType = "-"  // Subtraction operator
-	BANG     TokenType = "!"  // Logical NOT operator
-	ASTERISK TokenType = "*"  // Multiplication operator
-	SLASH    TokenType = "/"  // Division operator
-	LT       TokenType = "<"  // Less than operator
-	GT       TokenType = ">"  // Greater than operator
-	EQ       TokenType = "==" // Equality operator
-	NOT_EQ   TokenType = "!=" // Inequality operator
+	ASSIGN          TokenType = "="    // Assignment operator (e.g., x = 42)
+	PLUS            TokenType = "+"    // Addition operator
+	MINUS           TokenType = "-"    // Subtraction operator
+	BANG            TokenType = "!"    // Logical NOT operator
+	ASTERISK        TokenType = "*"    // Multiplication operator
+	EXPONENT        TokenType = "**"   // Exponentiation operator (e.g., 2 ** 10)
+	SLASH           TokenType = "/"    // Division operator
+	PERCENT         TokenType = "%"    // Remainder operator
+	LT              TokenType = "<"    // Less than operator
+	GT              TokenType = ">"    // Greater than operator
+	LT_EQ           TokenType = "<="   // Less than or equal operator
+	GT_EQ           TokenType = ">="   // Greater than or equal operator
+	EQ              TokenType = "=="   // Equality operator
+	NOT_EQ          TokenType = "!="   // Inequality operator
+	STRICT_EQ       TokenType = "==="  // Strict equality operator
+	STRICT_NOT_EQ   TokenType = "!=="  // Strict inequality operator
+	AND             TokenType = "&&"   // Logical AND operator
+	OR              TokenType = "||"   // Logical OR operator
+	BIT_AND         TokenType = "&"    // Bitwise AND operator
+	BIT_OR          TokenType = "|"    // Bitwise OR operator
+	BIT_XOR         TokenType = "^"    // Bitwise XOR operator
+	SHL             TokenType = "<<"   // Left shift operator
+	SHR             TokenType = ">>"   // Sign-propagating right shift operator
+	USHR            TokenType = ">>>"  // Unsigned right shift operator
+	PLUS_ASSIGN     TokenType = "+="   // Compound addition assignment (e.g., x += 1)
+	MINUS_ASSIGN    TokenType = "-="   // Compound subtraction assignment
+	ASTERISK_ASSIGN TokenType = "*="   // Compound multiplication assignment
+	SLASH_ASSIGN    TokenType = "/="   // Compound division assignment
+	PERCENT_ASSIGN  TokenType = "%="   // Compound remainder assignment
+	EXPONENT_ASSIGN TokenType = "**="  // Compound exponentiation assignment
+	BIT_AND_ASSIGN  TokenType = "&="   // Compound bitwise AND assignment
+	BIT_OR_ASSIGN   TokenType = "|="   // Compound bitwise OR assignment
+	BIT_XOR_ASSIGN  TokenType = "^="   // Compound bitwise XOR assignment
+	SHL_ASSIGN      TokenType = "<<="  // Compound left shift assignment
+	SHR_ASSIGN      TokenType = ">>="  // Compound right shift assignment
+	USHR_ASSIGN     TokenType = ">>>=" // Compound unsigned right shift assignment
+	INCREMENT       TokenType = "++"   // Increment operator (e.g., i++)
+	DECREMENT       TokenType = "--"   // Decrement operator (e.g., i--)
+	ARROW           TokenType = "=>"   // Arrow used by arrow functions (e.g., x => x * 2)
+	QUESTION        TokenType = "?"    // Conditional (ternary) operator
 
 	// Delimiters
-	COMMA     TokenType = ","  // Separates items in lists (e.g., function arguments)
-	SEMICOLON TokenType = ";"  // Statement terminator
-	LPAREN    TokenType = "("  // Left parenthesis - used for grouping and function calls
-	RPAREN    TokenType = ")"  // Right parenthesis
-	LBRACE    TokenType = "{"  // Left brace - starts a block of code
-	RBRACE    TokenType = "}"  // Right brace - ends a block of code
+	COMMA     TokenType = ","   // Separates items in lists (e.g., function arguments)
+	SEMICOLON TokenType = ";"   // Statement terminator
+	COLON     TokenType = ":"   // Separates keys from values in object literals
+	DOT       TokenType = "."   // Property access (e.g., arr.length)
+	ELLIPSIS  TokenType = "..." // Spread and rest syntax (e.g., [...a, ...b])
+	LPAREN    TokenType = "("   // Left parenthesis - used for grouping and function calls
+	RPAREN    TokenType = ")"   // Right parenthesis
+	LBRACE    TokenType = "{"   // Left brace - starts a block of code
+	RBRACE    TokenType = "}"   // Right brace - ends a block of code
+	LBRACKET  TokenType = "["   // Left bracket - starts an array literal or index access
+	RBRACKET  TokenType = "]"   // Right bracket
 
 	// Keywords
 	FUNCTION TokenType = "FUNCTION" // "function" keyword for function declarations
 	LET      TokenType = "LET"      // "let" keyword for variable declarations
+	VAR      TokenType = "VAR"      // "var" keyword for function-scoped variable declarations
+	CONST    TokenType = "CONST"    // "const" keyword for constant declarations
 	TRUE     TokenType = "TRUE"     // Boolean literal "true"
 	FALSE    TokenType = "FALSE"    // Boolean literal "false"
+	NULL     TokenType = "NULL"     // The "null" literal
 	IF       TokenType = "IF"       // "if" keyword for conditional statements
 	ELSE     TokenType = "ELSE"     // "else" keyword for else clauses
 	RETURN   TokenType = "RETURN"   // "return" keyword for returning values from functions
+	WHILE    TokenType = "WHILE"    // "while" keyword for loops
+	FOR      TokenType = "FOR"      // "for" keyword for loops
+	BREAK    TokenType = "BREAK"    // "break" keyword for leaving a loop
+	CONTINUE TokenType = "CONTINUE" // "continue" keyword for skipping to the next iteration
+	NEW      TokenType = "NEW"      // "new" keyword for constructing objects
+	THIS     TokenType = "THIS"     // "this" keyword referring to the receiver of a call
+	TYPEOF   TokenType = "TYPEOF"   // "typeof" operator giving the type of a value as a string
+	VOID     TokenType = "VOID"     // "void" operator evaluating an expression and giving undefined
+	DELETE   TokenType = "DELETE"   // "delete" operator removing a property from an object
+	YIELD    TokenType = "YIELD"    // "yield" operator pausing a generator function
+	AWAIT    TokenType = "AWAIT"    // "await" operator waiting for a promise in an async function
+	IMPORT   TokenType = "IMPORT"   // "import" keyword for importing from other modules
+	EXPORT   TokenType = "EXPORT"   // "export" keyword for making names available to other modules
+
+	INSTANCEOF TokenType = "INSTANCEOF" // "instanceof" operator testing an object's prototype chain
 )
 
 // Token represents a single token in the input.
@@ -55,7 +110,10 @@ type Token struct {
 
 // Lexer represents the lexer interface.
 // The lexer is responsible for breaking down the input string into tokens.
-// It provides a NextToken() method that returns the next token in the input.
+// It provides a NextToken() method that returns the next token in the input,
+// and ReadRegExp() for the parser to read a / token again as the start of a
+// regular expression literal.
 type Lexer interface {
 	NextToken() Token
+	ReadRegExp() Token
 }
@@ -1,5 +1,7 @@
 package lexer
 
+import "strings"
+
 // LexerImpl represents our concrete lexer implementation.
 // The lexer is the first step in processing JavaScript code. It takes the raw source code
 // and breaks it down into tokens - the smallest meaningful units of the language.
@@ -9,6 +11,12 @@ type LexerImpl struct {
 	position     int    // Current position in input (points to current char)
 	readPosition int    // Current reading position in input (after current char)
 	ch           byte   // Current char under examination
+
+	// tokenStart and prevTokenStart are where the last two tokens
+	// NextToken returned began, so ReadRegExp can go back and read one of
+	// them again.
+	tokenStart     int
+	prevTokenStart int
 }
 
 // New creates a new Lexer instance.
@@ -46,36 +54,100 @@ func (l *LexerImpl) NextToken() Token {
 	var tok Token
 
 	l.skipWhitespace()
+	l.prevTokenStart, l.tokenStart = l.tokenStart, l.position
 
 	switch l.ch {
 	case '=':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = Token{Type: EQ, Literal: string(ch) + string(l.ch)}
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = Token{Type: STRICT_EQ, Literal: "==="}
+			} else {
+				tok = Token{Type: EQ, Literal: "=="}
+			}
+		} else if l.peekChar() == '>' {
+			l.readChar()
+			tok = Token{Type: ARROW, Literal: "=>"}
 		} else {
 			tok = Token{Type: ASSIGN, Literal: string(l.ch)}
 		}
 	case '+':
-		tok = Token{Type: PLUS, Literal: string(l.ch)}
+		tok = l.readOperator(PLUS, PLUS_ASSIGN, INCREMENT)
 	case '-':
-		tok = Token{Type: MINUS, Literal: string(l.ch)}
+		tok = l.readOperator(MINUS, MINUS_ASSIGN, DECREMENT)
 	case '!':
 		if l.peekChar() == '=' {
-			ch := l.ch
 			l.readChar()
-			tok = Token{Type: NOT_EQ, Literal: string(ch) + string(l.ch)}
+			if l.peekChar() == '=' {
+				l.readChar()
+				tok = Token{Type: STRICT_NOT_EQ, Literal: "!=="}
+			} else {
+				tok = Token{Type: NOT_EQ, Literal: "!="}
+			}
 		} else {
 			tok = Token{Type: BANG, Literal: string(l.ch)}
 		}
 	case '/':
-		tok = Token{Type: SLASH, Literal: string(l.ch)}
+		if l.peekChar() == '/' {
+			l.skipComment()
+			// The comment isn't a token, so it mustn't count as one.
+			l.tokenStart = l.prevTokenStart
+			return l.NextToken()
+		}
+		tok = l.readOperator(SLASH, SLASH_ASSIGN, "")
 	case '*':
-		tok = Token{Type: ASTERISK, Literal: string(l.ch)}
+		if l.peekChar() == '*' {
+			l.readChar()
+			tok = l.readOperator(EXPONENT, EXPONENT_ASSIGN, "")
+			tok.Literal = "*" + tok.Literal
+		} else {
+			tok = l.readOperator(ASTERISK, ASTERISK_ASSIGN, "")
+		}
+	case '%':
+		tok = l.readOperator(PERCENT, PERCENT_ASSIGN, "")
 	case '<':
-		tok = Token{Type: LT, Literal: string(l.ch)}
+		if l.peekChar() == '<' {
+			l.readChar()
+			tok = l.readOperator(SHL, SHL_ASSIGN, "")
+			tok.Literal = "<" + tok.Literal
+		} else {
+			tok = l.readOperator(LT, LT_EQ, "")
+		}
 	case '>':
-		tok = Token{Type: GT, Literal: string(l.ch)}
+		switch {
+		case l.peekChar() == '>' && l.peekCharAt(1) == '>':
+			l.readChar()
+			l.readChar()
+			tok = l.readOperator(USHR, USHR_ASSIGN, "")
+			tok.Literal = ">>" + tok.Literal
+		case l.peekChar() == '>':
+			l.readChar()
+			tok = l.readOperator(SHR, SHR_ASSIGN, "")
+			tok.Literal = ">" + tok.Literal
+		default:
+			tok = l.readOperator(GT, GT_EQ, "")
+		}
+	case '&':
+		tok = l.readOperator(BIT_AND, BIT_AND_ASSIGN, AND)
+	case '|':
+		tok = l.readOperator(BIT_OR, BIT_OR_ASSIGN, OR)
+	case '^':
+		tok = l.readOperator(BIT_XOR, BIT_XOR_ASSIGN, "")
+	case '?':
+		tok = Token{Type: QUESTION, Literal: string(l.ch)}
+	case ':':
+		tok = Token{Type: COLON, Literal: string(l.ch)}
+	case '.':
+		if l.peekChar() == '.' && l.peekCharAt(1) == '.' {
+			l.readChar()
+			l.readChar()
+			tok = Token{Type: ELLIPSIS, Literal: "..."}
+		} else if isDigit(l.peekChar()) {
+			return l.readNumberToken()
+		} else {
+			tok = Token{Type: DOT, Literal: string(l.ch)}
+		}
 	case ';':
 		tok = Token{Type: SEMICOLON, Literal: string(l.ch)}
 	case '(':
@@ -88,6 +160,16 @@ func (l *LexerImpl) NextToken() Token {
 		tok = Token{Type: LBRACE, Literal: string(l.ch)}
 	case '}':
 		tok = Token{Type: RBRACE, Literal: string(l.ch)}
+	case '[':
+		tok = Token{Type: LBRACKET, Literal: string(l.ch)}
+	case ']':
+		tok = Token{Type: RBRACKET, Literal: string(l.ch)}
+	case '"', '\'':
+		literal, ok := l.readString(l.ch)
+		if !ok {
+			return Token{Type: ILLEGAL, Literal: literal}
+		}
+		tok = Token{Type: STRING, Literal: literal}
 	case 0:
 		tok.Literal = ""
 		tok.Type = EOF
@@ -97,9 +179,7 @@ func (l *LexerImpl) NextToken() Token {
 			tok.Type = lookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = INT
-			tok.Literal = l.readNumber()
-			return tok
+			return l.readNumberToken()
 		} else {
 			tok = Token{Type: ILLEGAL, Literal: string(l.ch)}
 		}
@@ -120,6 +200,194 @@ func (l *LexerImpl) peekChar() byte {
 	return l.input[l.readPosition]
 }
 
+// peekCharAt looks further ahead than peekChar. An offset of 0 is the same
+// character peekChar returns; it is used for the three-character "..." token.
+func (l *LexerImpl) peekCharAt(offset int) byte {
+	if l.readPosition+offset >= len(l.input) {
+		return 0
+	}
+	return l.input[l.readPosition+offset]
+}
+
+// readOperator reads an operator that may be followed by "=" (compound
+// assignment like +=) or doubled (like ++). Passing an empty doubled type
+// means the operator has no doubled form.
+func (l *LexerImpl) readOperator(single, assign, doubled TokenType) Token {
+	ch := l.ch
+	switch {
+	case l.peekChar() == '=':
+		l.readChar()
+		return Token{Type: assign, Literal: string(ch) + "="}
+	case doubled != "" && l.peekChar() == ch:
+		l.readChar()
+		return Token{Type: doubled, Literal: string(ch) + string(ch)}
+	}
+	return Token{Type: single, Literal: string(ch)}
+}
+
+// skipComment skips a // line comment. Comments, like whitespace, carry no
+// meaning for the program.
+func (l *LexerImpl) skipComment() {
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+}
+
+// ReadRegExp reads the token before the last one NextToken returned again,
+// this time as a regular expression literal. Whether a / starts a regular
+// expression or is the division operator depends on where it appears:
+// in "a / b / c" it divides, while in "x = /b/g" it starts a literal. The
+// lexer can't tell these apart, but the parser can, so it lexes / as
+// SLASH (or /= as SLASH_ASSIGN) and calls ReadRegExp when the token turns
+// up where an expression should start. By then the parser has already
+// read the token after it, which is why this goes back two tokens.
+//
+// The literal runs to the next / that isn't escaped or inside a character
+// class like [/], and is followed by its flags. The returned token's
+// literal is the whole of it, such as "/a+b/gi". A literal that reaches the
+// end of the line first gives an ILLEGAL token.
+func (l *LexerImpl) ReadRegExp() Token {
+	start := l.prevTokenStart
+	l.readPosition = start + 1
+	l.readChar()
+	inClass := false
+	for l.ch != '/' || inClass {
+		switch l.ch {
+		case 0, '\n', '\r':
+			return Token{Type: ILLEGAL, Literal: l.input[start:l.position]}
+		case '\\':
+			l.readChar()
+			if l.ch == 0 || l.ch == '\n' || l.ch == '\r' {
+				return Token{Type: ILLEGAL, Literal: l.input[start:l.position]}
+			}
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		}
+		l.readChar()
+	}
+	l.readChar() // the closing /
+	for isLetter(l.ch) || isDigit(l.ch) {
+		l.readChar()
+	}
+	l.prevTokenStart, l.tokenStart = l.tokenStart, start
+	return Token{Type: REGEXP, Literal: l.input[start:l.position]}
+}
+
+// readString reads a string literal delimited by quote (either ' or ").
+// Escape sequences such as \n and \u0041 are decoded, so the returned
+// literal is the string's actual value. The second result is false when the
+// string is not terminated before the end of the line or input.
+func (l *LexerImpl) readString(quote byte) (string, bool) {
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.ch {
+		case quote:
+			return out.String(), true
+		case 0, '\n':
+			return out.String(), false
+		case '\\':
+			l.readChar()
+			l.readEscape(&out)
+		default:
+			out.WriteByte(l.ch)
+		}
+	}
+}
+
+// readEscape decodes the escape sequence whose first character (after the
+// backslash) is the current character.
+func (l *LexerImpl) readEscape(out *strings.Builder) {
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case 'b':
+		out.WriteByte('\b')
+	case 'f':
+		out.WriteByte('\f')
+	case 'v':
+		out.WriteByte('\v')
+	case '0':
+		out.WriteByte(0)
+	case 'x':
+		out.WriteRune(rune(l.readHex(2)))
+	case 'u':
+		if l.peekChar() == '{' {
+			l.readChar()
+			code := 0
+			for l.peekChar() != '}' && l.peekChar() != 0 {
+				l.readChar()
+				code = code*16 + hexValue(l.ch)
+			}
+			l.readChar()
+			writeCodePoint(out, code)
+		} else {
+			code := l.readHex(4)
+			if 0xD800 <= code && code < 0xDC00 && l.peekChar() == '\\' && l.peekCharAt(1) == 'u' {
+				// A surrogate pair written as two escapes, like "\uD83D\uDE00",
+				// is one character.
+				if low := l.peekHex(2, 4); 0xDC00 <= low && low < 0xE000 {
+					l.readChar()
+					l.readChar()
+					l.readHex(4)
+					code = 0x10000 + (code-0xD800)<<10 + (low - 0xDC00)
+				}
+			}
+			writeCodePoint(out, code)
+		}
+	case '\n':
+		// A backslash before a newline continues the string on the next line.
+	default:
+		out.WriteByte(l.ch)
+	}
+}
+
+// peekHex decodes n hexadecimal digits starting offset characters after
+// the next one, without consuming them. It returns -1 if they aren't all
+// hexadecimal digits.
+func (l *LexerImpl) peekHex(offset, n int) int {
+	code := 0
+	for j := 0; j < n; j++ {
+		ch := l.peekCharAt(offset + j)
+		if !isHexDigit(ch) {
+			return -1
+		}
+		code = code*16 + hexValue(ch)
+	}
+	return code
+}
+
+// writeCodePoint writes a character given by its code point. JavaScript
+// strings may contain unpaired surrogates (code points D800-DFFF), which
+// UTF-8 can't represent; they are written in the same three-byte form
+// UTF-8 uses for their neighbours (an encoding known as WTF-8), which the
+// interpreter understands.
+func writeCodePoint(out *strings.Builder, code int) {
+	if 0xD800 <= code && code < 0xE000 {
+		out.WriteByte(byte(0xE0 | code>>12))
+		out.WriteByte(byte(0x80 | (code>>6)&0x3F))
+		out.WriteByte(byte(0x80 | code&0x3F))
+		return
+	}
+	out.WriteRune(rune(code))
+}
+
+// readHex reads n hexadecimal digits following the current character.
+func (l *LexerImpl) readHex(n int) int {
+	code := 0
+	for j := 0; j < n; j++ {
+		l.readChar()
+		code = code*16 + hexValue(l.ch)
+	}
+	return code
+}
+
 // skipWhitespace skips over any whitespace characters.
 // Whitespace is not significant in JavaScript (except in strings),
 // so we can safely skip over spaces, tabs, newlines, and carriage returns.
@@ -135,28 +403,67 @@ func (l *LexerImpl) skipWhitespace() {
 // but must start with a letter, underscore, or dollar sign.
 func (l *LexerImpl) readIdentifier() string {
 	position := l.position
-	for isLetter(l.ch) {
+	for isLetter(l.ch) || isDigit(l.ch) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
+// readNumberToken reads a number literal, which is a BigInt literal if it
+// ends with n, as in 123n.
+func (l *LexerImpl) readNumberToken() Token {
+	literal := l.readNumber()
+	if l.ch == 'n' {
+		l.readChar()
+		return Token{Type: BIGINT, Literal: literal + "n"}
+	}
+	return Token{Type: INT, Literal: literal}
+}
+
 // readNumber reads a number and advances the lexer's position.
-// Currently handles only integer numbers. In a full JavaScript implementation,
-// this would need to handle floating-point numbers, scientific notation, etc.
+// JavaScript numbers can be written in several ways: as integers (42),
+// with a fraction (4.2 or .5), with an exponent (1e3, 2.5E-4), or in
+// hexadecimal, octal or binary with a prefix (0xFF, 0o17, 0b101). Digits may
+// be grouped with underscores (1_000_000). The parser works out the value.
 func (l *LexerImpl) readNumber() string {
 	position := l.position
-	for isDigit(l.ch) {
+	if l.ch == '0' && strings.ContainsRune("xXoObB", rune(l.peekChar())) {
+		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return l.input[position:l.position]
+	}
+
+	l.readDigits()
+	if l.ch == '.' {
+		l.readChar()
+		l.readDigits()
+	}
+	if (l.ch == 'e' || l.ch == 'E') &&
+		(isDigit(l.peekChar()) || (l.peekChar() == '+' || l.peekChar() == '-') && isDigit(l.peekCharAt(1))) {
 		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
 	}
 	return l.input[position:l.position]
 }
 
+// readDigits reads a run of decimal digits and numeric separators.
+func (l *LexerImpl) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' && isDigit(l.peekChar()) {
+		l.readChar()
+	}
+}
+
 // isLetter checks if the character is a letter.
 // In JavaScript, identifiers can contain letters (a-z, A-Z),
 // underscores (_), and dollar signs ($).
 func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '$'
 }
 
 // isDigit checks if the character is a digit.
@@ -165,25 +472,77 @@ func isDigit(ch byte) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+// isHexDigit checks if the character is a hexadecimal digit.
+func isHexDigit(ch byte) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+// hexValue returns the numeric value of a hexadecimal digit.
+func hexValue(ch byte) int {
+	switch {
+	case '0' <= ch && ch <= '9':
+		return int(ch - '0')
+	case 'a' <= ch && ch <= 'f':
+		return int(ch-'a') + 10
+	case 'A' <= ch && ch <= 'F':
+		return int(ch-'A') + 10
+	}
+	return 0
+}
+
 // lookupIdent checks if the identifier is a keyword.
 // Keywords are special identifiers that have specific meaning in JavaScript.
 // Examples include: let, function, if, else, return, etc.
 func lookupIdent(ident string) TokenType {
 	switch ident {
-	case "fn":
+	case "fn", "function":
 		return FUNCTION
 	case "let":
 		return LET
+	case "var":
+		return VAR
+	case "const":
+		return CONST
 	case "true":
 		return TRUE
 	case "false":
 		return FALSE
+	case "null":
+		return NULL
 	case "if":
 		return IF
 	case "else":
 		return ELSE
 	case "return":
 		return RETURN
+	case "while":
+		return WHILE
+	case "for":
+		return FOR
+	case "break":
+		return BREAK
+	case "continue":
+		return CONTINUE
+	case "new":
+		return NEW
+	case "this":
+		return THIS
+	case "typeof":
+		return TYPEOF
+	case "void":
+		return VOID
+	case "delete":
+		return DELETE
+	case "instanceof":
+		return INSTANCEOF
+	case "yield":
+		return YIELD
+	case "await":
+		return AWAIT
+	case "import":
+		return IMPORT
+	case "export":
+		return EXPORT
 	default:
 		return IDENT
 	}
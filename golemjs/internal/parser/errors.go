@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+)
+
+// Errors returns the syntax errors found while parsing.
+// The parser keeps going after an error so that it can report as many
+// problems as possible in one pass.
+func (p *Parser) Errors() []string {
+	return p.errors
+}
+
+func (p *Parser) peekError(t lexer.TokenType) {
+	if p.tooDeep {
+		return
+	}
+	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
+		t, p.peekToken.Type)
+	p.errors = append(p.errors, msg)
+}
+
+func (p *Parser) noPrefixParseFnError(t lexer.TokenType) {
+	if p.tooDeep {
+		return
+	}
+	msg := fmt.Sprintf("no prefix parse function for %s found", t)
+	p.errors = append(p.errors, msg)
+}
+
+func (p *Parser) errorf(format string, a ...interface{}) {
+	if p.tooDeep {
+		return
+	}
+	p.errors = append(p.errors, fmt.Sprintf(format, a...))
+}
+
+// errorShowing reports message, followed by the source of node, the code
+// it's about. After an earlier error node may be nil, or have parts
+// missing, so the source is only shown when this is the first error.
+func (p *Parser) errorShowing(message string, node ast.Node) {
+	if node == nil || len(p.errors) > 0 {
+		p.errorf("%s", message)
+		return
+	}
+	p.errorf("%s: %s", message, node.String())
+}
+
+// enter counts a level of nesting about to be parsed, and leave counts it
+// as done. Past maxNestingDepth, enter reports the error, skips the rest of
+// the input, so that every parse function unwinds at the end of it, and
+// returns false. The errors the unwinding would cause aren't reported.
+func (p *Parser) enter() bool {
+	if p.depth >= maxNestingDepth && !p.tooDeep {
+		p.errorf("nesting too deep: expressions and statements may only nest %d levels", maxNestingDepth)
+		p.tooDeep = true
+		for !p.curTokenIs(lexer.EOF) {
+			p.nextToken()
+		}
+	}
+	if p.tooDeep {
+		return false
+	}
+	p.depth++
+	return true
+}
+
+func (p *Parser) leave() {
+	p.depth--
+}
@@ -0,0 +1,316 @@
+package parser
+
+import (
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+)
+
+// Imports and exports may only appear at the top level of a module, so
+// parseProgram looks for them itself rather than leaving them to
+// parseStatement, which reports them as errors anywhere else. Only import
+// and export are keywords; as, from and default are ordinary names that
+// mean something special in these places alone.
+//
+// The names a module exports don't have to be identifiers: a string works
+// too, as in export { x as "kebab-case" }, and the other module then
+// imports it with import { "kebab-case" as x }.
+
+// parseModuleItem parses one statement at the top level of a module,
+// where imports and exports are allowed.
+func (p *Parser) parseModuleItem() ast.Statement {
+	switch {
+	case p.curTokenIs(lexer.IMPORT) && !p.peekTokenIs(lexer.LPAREN):
+		return p.parseImportDeclaration()
+	case p.curTokenIs(lexer.EXPORT):
+		return p.parseExportDeclaration()
+	}
+	return p.parseStatement()
+}
+
+// parseImportDeclaration parses the forms of import, starting at the
+// import keyword:
+//
+//	import "./m.js";
+//	import d from "./m.js";
+//	import * as ns from "./m.js";
+//	import { a, b as c } from "./m.js";
+//	import d, { a } from "./m.js";
+//	import d, * as ns from "./m.js";
+func (p *Parser) parseImportDeclaration() ast.Statement {
+	decl := &ast.ImportDeclaration{Token: p.token()}
+
+	if p.peekTokenIs(lexer.STRING) {
+		p.nextToken()
+		decl.Source = p.curToken.Literal
+		p.skipSemicolon()
+		return decl
+	}
+
+	if p.peekTokenIs(lexer.IDENT) {
+		p.nextToken()
+		decl.Default = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+		if !p.peekTokenIs(lexer.COMMA) {
+			return p.finishImportDeclaration(decl)
+		}
+		p.nextToken()
+	}
+
+	switch {
+	case p.peekTokenIs(lexer.ASTERISK):
+		p.nextToken()
+		if !p.expectContextual("as") || !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		decl.Namespace = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	case p.peekTokenIs(lexer.LBRACE):
+		p.nextToken()
+		decl.Specifiers = []*ast.ImportSpecifier{}
+		for !p.peekTokenIs(lexer.RBRACE) {
+			p.nextToken()
+			spec := p.parseImportSpecifier()
+			if spec == nil {
+				return nil
+			}
+			decl.Specifiers = append(decl.Specifiers, spec)
+			if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+	default:
+		p.errorf("unexpected %s in import declaration", p.peekToken.Type)
+		return nil
+	}
+	return p.finishImportDeclaration(decl)
+}
+
+// finishImportDeclaration parses the from "specifier" that ends an import.
+func (p *Parser) finishImportDeclaration(decl *ast.ImportDeclaration) ast.Statement {
+	source, ok := p.parseFromClause()
+	if !ok {
+		return nil
+	}
+	decl.Source = source
+	p.skipSemicolon()
+	return decl
+}
+
+// parseImportSpecifier parses one entry in the braces of an import: a
+// name, or a name followed by as and the variable to bind it to. Only a
+// name that could be a variable can appear on its own.
+func (p *Parser) parseImportSpecifier() *ast.ImportSpecifier {
+	token := p.curToken
+	imported, ok := p.parseModuleExportName()
+	if !ok {
+		return nil
+	}
+	if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "as" {
+		p.nextToken()
+		if !p.expectPeek(lexer.IDENT) {
+			return nil
+		}
+		return &ast.ImportSpecifier{Imported: imported, Local: &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}}
+	}
+	if token.Type != lexer.IDENT {
+		p.errorf("expected as after %s in import declaration", token.Literal)
+		return nil
+	}
+	local := &ast.Identifier{Token: ast.Token{Type: string(token.Type), Literal: token.Literal}, Value: token.Literal}
+	return &ast.ImportSpecifier{Imported: imported, Local: local}
+}
+
+// parseExportDeclaration parses the forms of export, starting at the
+// export keyword:
+//
+//	export let x = 1;
+//	export function f() {}
+//	export { x, f as g };
+//	export { a, b as c } from "./m.js";
+//	export * from "./m.js";
+//	export * as ns from "./m.js";
+//	export default expression;
+//	export default function () {}
+func (p *Parser) parseExportDeclaration() ast.Statement {
+	token := p.token()
+
+	switch {
+	case p.peekTokenIs(lexer.ASTERISK):
+		p.nextToken()
+		decl := &ast.ExportAllDeclaration{Token: token}
+		if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "as" {
+			p.nextToken()
+			p.nextToken()
+			name, ok := p.parseModuleExportName()
+			if !ok {
+				return nil
+			}
+			decl.Exported = name
+		}
+		source, ok := p.parseFromClause()
+		if !ok {
+			return nil
+		}
+		decl.Source = source
+		p.skipSemicolon()
+		return decl
+
+	case p.peekTokenIs(lexer.LBRACE):
+		p.nextToken()
+		decl := &ast.ExportNamedDeclaration{Token: token, Specifiers: []*ast.ExportSpecifier{}}
+		quoted := ""
+		for !p.peekTokenIs(lexer.RBRACE) {
+			p.nextToken()
+			if p.curTokenIs(lexer.STRING) && quoted == "" {
+				quoted = p.curToken.Literal
+			}
+			spec := &ast.ExportSpecifier{}
+			local, ok := p.parseModuleExportName()
+			if !ok {
+				return nil
+			}
+			spec.Local, spec.Exported = local, local
+			if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "as" {
+				p.nextToken()
+				p.nextToken()
+				if spec.Exported, ok = p.parseModuleExportName(); !ok {
+					return nil
+				}
+			}
+			decl.Specifiers = append(decl.Specifiers, spec)
+			if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+				return nil
+			}
+		}
+		p.nextToken()
+		if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "from" {
+			source, ok := p.parseFromClause()
+			if !ok {
+				return nil
+			}
+			decl.Source = source
+		} else if quoted != "" {
+			// Without a from, the names are variables of this module.
+			p.errorf("string literal %q can only be exported from another module", quoted)
+			return nil
+		}
+		p.skipSemicolon()
+		return decl
+
+	case p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "default":
+		p.nextToken()
+		p.nextToken()
+		return p.parseExportDefault(token)
+
+	case p.peekTokenIs(lexer.LET) || p.peekTokenIs(lexer.CONST) || p.peekTokenIs(lexer.VAR):
+		p.nextToken()
+		stmt := p.parseVariableDeclaration()
+		if stmt == nil {
+			return nil
+		}
+		return &ast.ExportNamedDeclaration{Token: token, Declaration: stmt}
+
+	case p.peekTokenIs(lexer.FUNCTION):
+		p.nextToken()
+		stmt := p.parseFunctionDeclaration(false)
+		if stmt == nil {
+			return nil
+		}
+		return &ast.ExportNamedDeclaration{Token: token, Declaration: stmt}
+
+	case p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "async":
+		p.nextToken()
+		if !p.expectPeek(lexer.FUNCTION) {
+			return nil
+		}
+		stmt := p.parseFunctionDeclaration(true)
+		if stmt == nil {
+			return nil
+		}
+		return &ast.ExportNamedDeclaration{Token: token, Declaration: stmt}
+	}
+
+	p.errorf("unexpected %s after export", p.peekToken.Type)
+	return nil
+}
+
+// parseExportDefault parses what follows export default. A function here
+// is a declaration, hoisted like any other, even without a name; anything
+// else is an expression, evaluated when the statement runs.
+func (p *Parser) parseExportDefault(token ast.Token) ast.Statement {
+	decl := &ast.ExportDefaultDeclaration{Token: token}
+
+	async := p.curTokenIs(lexer.IDENT) && p.curToken.Literal == "async" && p.peekTokenIs(lexer.FUNCTION)
+	if async {
+		p.nextToken()
+	}
+	if p.curTokenIs(lexer.FUNCTION) {
+		lit, ok := p.parseFunctionExpression(async).(*ast.FunctionLiteral)
+		if !ok {
+			return nil
+		}
+		decl.Declaration = &ast.FunctionDeclaration{
+			Token:      lit.Token,
+			Name:       lit.Name,
+			Parameters: lit.Parameters,
+			Body:       lit.Body,
+			Generator:  lit.Generator,
+			Async:      lit.Async,
+		}
+		p.skipSemicolon()
+		return decl
+	}
+
+	value := p.parseExpression(ASSIGN - 1)
+	if value == nil {
+		return nil
+	}
+	decl.Declaration = value
+	p.skipSemicolon()
+	return decl
+}
+
+// parseModuleExportName parses the name of an export: an identifier, any
+// other word, such as default, or a string.
+func (p *Parser) parseModuleExportName() (string, bool) {
+	if p.curTokenIs(lexer.STRING) || isIdentifierName(p.curToken) {
+		return p.curToken.Literal, true
+	}
+	p.errorf("unexpected %s in module export name", p.curToken.Type)
+	return "", false
+}
+
+// parseFromClause parses from "specifier", which follows the current
+// token, and returns the specifier.
+func (p *Parser) parseFromClause() (string, bool) {
+	if !p.expectContextual("from") || !p.expectPeek(lexer.STRING) {
+		return "", false
+	}
+	return p.curToken.Literal, true
+}
+
+// expectContextual moves on to the next token if it's the word, such as
+// as or from, that only has a meaning in this place, and reports an error
+// otherwise.
+func (p *Parser) expectContextual(word string) bool {
+	if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == word {
+		p.nextToken()
+		return true
+	}
+	p.errorf("expected %s, got %s instead", word, p.peekToken.Type)
+	return false
+}
+
+// parseImportCall parses import(specifier), which can appear in any
+// expression, in scripts as well as modules.
+func (p *Parser) parseImportCall() ast.Expression {
+	call := &ast.ImportCall{Token: p.token()}
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	call.Source = p.parseExpression(ASSIGN - 1)
+	if call.Source == nil || !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+	return call
+}
@@ -0,0 +1,1347 @@
+package parser
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/regex"
+)
+
+type (
+	prefixParseFn func() ast.Expression
+	infixParseFn  func(ast.Expression) ast.Expression
+)
+
+// Parser turns the token stream produced by the lexer into an AST.
+// It is a Pratt parser: every token type that can start an expression has a
+// prefix parse function, and every token that can continue one (operators,
+// calls, property access) has an infix parse function. Operator precedence
+// decides how far each infix function reaches.
+type Parser struct {
+	l      lexer.Lexer
+	errors []string
+
+	curToken  lexer.Token // the token under examination
+	peekToken lexer.Token // the token after curToken
+
+	prefixParseFns map[lexer.TokenType]prefixParseFn
+	infixParseFns  map[lexer.TokenType]infixParseFn
+
+	// inGenerator is set while parsing the body of a generator function,
+	// the only place where yield may appear.
+	inGenerator bool
+
+	// inAsync is set while parsing the body of an async function or the
+	// top level of a module, the places where await may appear.
+	inAsync bool
+
+	// module is set while parsing a module, where imports and exports
+	// may appear at the top level.
+	module bool
+
+	// depth is how deeply the expressions and statements being parsed
+	// are nested; see enter.
+	depth int
+	// tooDeep is set once they've nested more deeply than
+	// maxNestingDepth, after which parsing ends.
+	tooDeep bool
+}
+
+// maxNestingDepth is how deeply expressions and statements may nest. The
+// parser, and after it the compiler and the interpreter, recurse once for
+// every level, so without a limit a script of a million "["s would
+// overflow the Go stack and crash the process.
+const maxNestingDepth = 10000
+
+// New creates a parser reading tokens from l.
+func New(l lexer.Lexer) *Parser {
+	p := &Parser{
+		l:      l,
+		errors: []string{},
+	}
+
+	p.prefixParseFns = make(map[lexer.TokenType]prefixParseFn)
+	p.registerPrefix(lexer.IDENT, p.parseIdentifier)
+	p.registerPrefix(lexer.INT, p.parseNumberLiteral)
+	p.registerPrefix(lexer.BIGINT, p.parseBigIntLiteral)
+	p.registerPrefix(lexer.STRING, p.parseStringLiteral)
+	p.registerPrefix(lexer.TRUE, p.parseBoolean)
+	p.registerPrefix(lexer.FALSE, p.parseBoolean)
+	p.registerPrefix(lexer.NULL, p.parseNull)
+	p.registerPrefix(lexer.THIS, p.parseThis)
+	p.registerPrefix(lexer.BANG, p.parseUnaryExpression)
+	p.registerPrefix(lexer.MINUS, p.parseUnaryExpression)
+	p.registerPrefix(lexer.PLUS, p.parseUnaryExpression)
+	p.registerPrefix(lexer.TYPEOF, p.parseUnaryExpression)
+	p.registerPrefix(lexer.VOID, p.parseUnaryExpression)
+	p.registerPrefix(lexer.DELETE, p.parseUnaryExpression)
+	p.registerPrefix(lexer.INCREMENT, p.parsePrefixUpdateExpression)
+	p.registerPrefix(lexer.DECREMENT, p.parsePrefixUpdateExpression)
+	p.registerPrefix(lexer.LPAREN, p.parseGroupedExpression)
+	p.registerPrefix(lexer.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(lexer.LBRACE, p.parseObjectLiteral)
+	p.registerPrefix(lexer.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(lexer.NEW, p.parseNewExpression)
+	p.registerPrefix(lexer.YIELD, p.parseYieldExpression)
+	p.registerPrefix(lexer.AWAIT, p.parseAwaitExpression)
+	p.registerPrefix(lexer.IMPORT, p.parseImportCall)
+	p.registerPrefix(lexer.SLASH, p.parseRegExpLiteral)
+	p.registerPrefix(lexer.SLASH_ASSIGN, p.parseRegExpLiteral)
+
+	p.infixParseFns = make(map[lexer.TokenType]infixParseFn)
+	for _, t := range []lexer.TokenType{
+		lexer.PLUS, lexer.MINUS, lexer.SLASH, lexer.ASTERISK, lexer.PERCENT,
+		lexer.EQ, lexer.NOT_EQ, lexer.STRICT_EQ, lexer.STRICT_NOT_EQ,
+		lexer.LT, lexer.GT, lexer.LT_EQ, lexer.GT_EQ, lexer.AND, lexer.OR,
+		lexer.INSTANCEOF, lexer.EXPONENT, lexer.BIT_AND, lexer.BIT_OR,
+		lexer.BIT_XOR, lexer.SHL, lexer.SHR, lexer.USHR,
+	} {
+		p.registerInfix(t, p.parseBinaryExpression)
+	}
+	for _, t := range []lexer.TokenType{
+		lexer.ASSIGN, lexer.PLUS_ASSIGN, lexer.MINUS_ASSIGN,
+		lexer.ASTERISK_ASSIGN, lexer.SLASH_ASSIGN, lexer.PERCENT_ASSIGN,
+		lexer.EXPONENT_ASSIGN, lexer.BIT_AND_ASSIGN, lexer.BIT_OR_ASSIGN,
+		lexer.BIT_XOR_ASSIGN, lexer.SHL_ASSIGN, lexer.SHR_ASSIGN, lexer.USHR_ASSIGN,
+	} {
+		p.registerInfix(t, p.parseAssignmentExpression)
+	}
+	p.registerInfix(lexer.QUESTION, p.parseConditionalExpression)
+	p.registerInfix(lexer.INCREMENT, p.parsePostfixUpdateExpression)
+	p.registerInfix(lexer.DECREMENT, p.parsePostfixUpdateExpression)
+	p.registerInfix(lexer.LPAREN, p.parseCallExpression)
+	p.registerInfix(lexer.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(lexer.DOT, p.parseDotExpression)
+
+	// Read two tokens, so curToken and peekToken are both set
+	p.nextToken()
+	p.nextToken()
+
+	return p
+}
+
+func (p *Parser) nextToken() {
+	p.curToken = p.peekToken
+	p.peekToken = p.l.NextToken()
+}
+
+// token converts the current lexer token into the token stored in AST nodes.
+func (p *Parser) token() ast.Token {
+	return ast.Token{Type: string(p.curToken.Type), Literal: p.curToken.Literal}
+}
+
+// ParseProgram parses the whole input as a script and returns the root of
+// the AST.
+func (p *Parser) ParseProgram() *ast.Program {
+	return p.parseProgram(false)
+}
+
+// ParseModule parses the whole input as an ES module. Unlike a script, a
+// module may import from and export to other modules, and may use await
+// outside of any function, which pauses the module until the promise
+// settles.
+func (p *Parser) ParseModule() *ast.Program {
+	p.inAsync = true
+	p.module = true
+	return p.parseProgram(true)
+}
+
+func (p *Parser) parseProgram(module bool) (program *ast.Program) {
+	program = &ast.Program{Module: module}
+	program.Statements = []ast.Statement{}
+
+	// The code being parsed may come from anyone, so a bug in the parser
+	// mustn't crash the program embedding the interpreter. The panic is
+	// reported as a syntax error instead.
+	defer func() {
+		if r := recover(); r != nil {
+			p.errors = append(p.errors, fmt.Sprintf("internal parser error: %v", r))
+		}
+	}()
+
+	for p.curToken.Type != lexer.EOF {
+		var stmt ast.Statement
+		if module {
+			stmt = p.parseModuleItem()
+		} else {
+			stmt = p.parseStatement()
+		}
+		if stmt != nil {
+			program.Statements = append(program.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	return program
+}
+
+// parseStatement parses one statement. Like every parse function, it leaves
+// curToken on the last token belonging to the statement.
+func (p *Parser) parseStatement() ast.Statement {
+	if !p.enter() {
+		return nil
+	}
+	defer p.leave()
+	switch p.curToken.Type {
+	case lexer.LET, lexer.VAR, lexer.CONST:
+		return p.parseVariableDeclaration()
+	case lexer.RETURN:
+		return p.parseReturnStatement()
+	case lexer.IF:
+		return p.parseIfStatement()
+	case lexer.WHILE:
+		return p.parseWhileStatement()
+	case lexer.FOR:
+		return p.parseForStatement()
+	case lexer.BREAK:
+		stmt := &ast.BreakStatement{Token: p.token()}
+		p.skipSemicolon()
+		return stmt
+	case lexer.CONTINUE:
+		stmt := &ast.ContinueStatement{Token: p.token()}
+		p.skipSemicolon()
+		return stmt
+	case lexer.LBRACE:
+		return p.parseBlockStatement()
+	case lexer.SEMICOLON:
+		return nil
+	case lexer.FUNCTION:
+		if p.peekTokenIs(lexer.IDENT) || p.peekTokenIs(lexer.ASTERISK) {
+			return p.parseFunctionDeclaration(false)
+		}
+	case lexer.IMPORT, lexer.EXPORT:
+		// import(specifier) is an expression; anything else here is an
+		// import or export declaration out of place.
+		if p.curTokenIs(lexer.IMPORT) && p.peekTokenIs(lexer.LPAREN) {
+			break
+		}
+		if !p.module {
+			p.errorf("Cannot use %s statement outside a module", p.curToken.Literal)
+		} else {
+			p.errorf("%s declarations may only appear at the top level of a module", p.curToken.Literal)
+		}
+		return nil
+	case lexer.IDENT:
+		// async is only a keyword in front of function; elsewhere it's an
+		// ordinary name.
+		if p.curToken.Literal == "async" && p.peekTokenIs(lexer.FUNCTION) {
+			token := p.token()
+			p.nextToken()
+			if p.peekTokenIs(lexer.IDENT) || p.peekTokenIs(lexer.ASTERISK) {
+				return p.parseFunctionDeclaration(true)
+			}
+			return p.finishExpressionStatement(token, p.parseFunctionExpression(true))
+		}
+	}
+	return p.parseExpressionStatement()
+}
+
+func (p *Parser) skipSemicolon() {
+	if p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+	}
+}
+
+func (p *Parser) parseVariableDeclaration() ast.Statement {
+	stmt := p.parseVariableBinding()
+	if stmt == nil || !p.checkDestructuringInitializer(stmt) {
+		return nil
+	}
+	p.skipSemicolon()
+	return stmt
+}
+
+// parseVariableBinding parses a declaration up to the end of its value,
+// if it has one. The name may be a destructuring pattern.
+func (p *Parser) parseVariableBinding() *ast.VariableDeclaration {
+	stmt := &ast.VariableDeclaration{Token: p.token()}
+
+	p.nextToken()
+	stmt.Name = p.parseBindingTarget()
+	if stmt.Name == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(lexer.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		stmt.Value = p.parseExpression(LOWEST)
+	}
+	return stmt
+}
+
+// checkDestructuringInitializer reports an error for a destructuring
+// declaration without a value, which would have nothing to destructure.
+func (p *Parser) checkDestructuringInitializer(stmt *ast.VariableDeclaration) bool {
+	if _, ok := stmt.Name.(*ast.Identifier); !ok && stmt.Value == nil {
+		p.errorf("missing initializer in destructuring declaration")
+		return false
+	}
+	return true
+}
+
+func (p *Parser) parseReturnStatement() ast.Statement {
+	stmt := &ast.ReturnStatement{Token: p.token()}
+
+	if p.peekTokenIs(lexer.SEMICOLON) || p.peekTokenIs(lexer.RBRACE) || p.peekTokenIs(lexer.EOF) {
+		p.skipSemicolon()
+		return stmt
+	}
+
+	p.nextToken()
+	stmt.ReturnValue = p.parseExpression(LOWEST)
+
+	p.skipSemicolon()
+	return stmt
+}
+
+func (p *Parser) parseExpressionStatement() ast.Statement {
+	stmt := &ast.ExpressionStatement{Token: p.token()}
+
+	stmt.Expression = p.parseExpression(LOWEST)
+	if stmt.Expression == nil {
+		return nil
+	}
+
+	p.skipSemicolon()
+	return stmt
+}
+
+// finishExpressionStatement parses the rest of an expression statement
+// starting at token, whose first operand, left, has already been parsed.
+func (p *Parser) finishExpressionStatement(token ast.Token, left ast.Expression) ast.Statement {
+	if left == nil {
+		return nil
+	}
+	stmt := &ast.ExpressionStatement{Token: token, Expression: p.continueExpression(left, LOWEST)}
+	p.skipSemicolon()
+	return stmt
+}
+
+func (p *Parser) parseIfStatement() ast.Statement {
+	stmt := &ast.IfStatement{Token: p.token()}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Consequence = p.parseBody()
+
+	if p.peekTokenIs(lexer.ELSE) {
+		p.nextToken()
+		p.nextToken()
+		if p.curTokenIs(lexer.IF) {
+			stmt.Alternative = p.parseIfStatement()
+		} else {
+			stmt.Alternative = p.parseBody()
+		}
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.token()}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Body = p.parseBody()
+	return stmt
+}
+
+// parseForStatement parses both kinds of for loop. Which one it is only
+// becomes clear after the first part: "for (let x of xs)" continues with
+// "of" where "for (let x = 0; ...)" has a semicolon.
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.token()}
+
+	await := false
+	if p.peekTokenIs(lexer.AWAIT) {
+		p.nextToken()
+		if !p.inAsync {
+			p.errorf("for await is only valid in async functions and the top level bodies of modules")
+			return nil
+		}
+		await = true
+	}
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+
+	if await && p.curTokenIs(lexer.SEMICOLON) {
+		p.errorf("for await must be a for...of loop")
+		return nil
+	}
+	if !p.curTokenIs(lexer.SEMICOLON) {
+		if p.curTokenIs(lexer.LET) || p.curTokenIs(lexer.CONST) || p.curTokenIs(lexer.VAR) {
+			decl := p.parseVariableBinding()
+			if decl == nil {
+				return nil
+			}
+			if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "of" {
+				return p.parseForOfStatement(stmt.Token, decl, await)
+			}
+			if !p.checkDestructuringInitializer(decl) {
+				return nil
+			}
+			p.skipSemicolon()
+			stmt.Init = decl
+		} else {
+			stmt.Init = p.parseStatement()
+			if p.peekTokenIs(lexer.IDENT) && p.peekToken.Literal == "of" {
+				return p.parseForOfStatement(stmt.Token, stmt.Init, await)
+			}
+		}
+		if await {
+			p.errorf("for await must be a for...of loop")
+			return nil
+		}
+		if !p.curTokenIs(lexer.SEMICOLON) {
+			p.errorf("expected ; after for loop initializer, got %s instead", p.curToken.Type)
+			return nil
+		}
+	}
+
+	if !p.peekTokenIs(lexer.SEMICOLON) {
+		p.nextToken()
+		stmt.Condition = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(lexer.SEMICOLON) {
+		return nil
+	}
+
+	if !p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		stmt.Update = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Body = p.parseBody()
+	return stmt
+}
+
+// parseForOfStatement parses the rest of a for...of loop once its left
+// side has been parsed as init, with the current token just before "of".
+// await is set for a for await...of loop.
+func (p *Parser) parseForOfStatement(token ast.Token, init ast.Statement, await bool) ast.Statement {
+	stmt := &ast.ForOfStatement{Token: token, Await: await}
+
+	switch init := init.(type) {
+	case *ast.VariableDeclaration:
+		if init.Value != nil {
+			p.errorf("for...of loop variable %s may not have an initializer", init.Name.String())
+			return nil
+		}
+		stmt.Left = init
+	case *ast.ExpressionStatement:
+		switch init.Expression.(type) {
+		case *ast.Identifier, *ast.MemberExpression, *ast.ArrayLiteral, *ast.ObjectLiteral:
+			stmt.Left = p.toAssignmentTarget(init.Expression, false)
+			if stmt.Left == nil {
+				return nil
+			}
+		default:
+			p.errorShowing("invalid for...of loop variable", init.Expression)
+			return nil
+		}
+	default:
+		p.errorf("invalid for...of loop variable")
+		return nil
+	}
+
+	p.nextToken()
+	p.nextToken()
+	stmt.Right = p.parseExpression(ASSIGN - 1)
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Body = p.parseBody()
+	return stmt
+}
+
+// parseYieldExpression parses yield, yield value and yield* iterable.
+// A bare yield is recognised by what follows it: anything that can't start
+// an expression, such as a closing bracket or semicolon.
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.token()}
+	if !p.inGenerator {
+		p.errorf("yield is only valid in generator functions")
+		return nil
+	}
+
+	if p.peekTokenIs(lexer.ASTERISK) {
+		p.nextToken()
+		exp.Delegate = true
+	}
+	if !exp.Delegate {
+		switch p.peekToken.Type {
+		case lexer.SEMICOLON, lexer.RPAREN, lexer.RBRACKET, lexer.RBRACE,
+			lexer.COMMA, lexer.COLON, lexer.EOF:
+			return exp
+		}
+	}
+
+	p.nextToken()
+	exp.Argument = p.parseExpression(ASSIGN - 1)
+	return exp
+}
+
+// parseAwaitExpression parses await value. Like the other unary operators,
+// await binds tighter than any binary operator, so await a + b adds b to
+// the awaited value of a.
+func (p *Parser) parseAwaitExpression() ast.Expression {
+	exp := &ast.AwaitExpression{Token: p.token()}
+	if !p.inAsync {
+		p.errorf("await is only valid in async functions and the top level bodies of modules")
+		return nil
+	}
+
+	p.nextToken()
+	exp.Argument = p.parseExpression(PREFIX)
+	if exp.Argument == nil {
+		return nil
+	}
+	return exp
+}
+
+// parseBody parses the body of an if, while or for statement. Braces are
+// optional in JavaScript, so a single statement is wrapped in a block.
+func (p *Parser) parseBody() *ast.BlockStatement {
+	if p.curTokenIs(lexer.LBRACE) {
+		return p.parseBlockStatement()
+	}
+	block := &ast.BlockStatement{Token: p.token()}
+	if stmt := p.parseStatement(); stmt != nil {
+		block.Statements = []ast.Statement{stmt}
+	}
+	return block
+}
+
+func (p *Parser) parseBlockStatement() *ast.BlockStatement {
+	block := &ast.BlockStatement{Token: p.token()}
+	block.Statements = []ast.Statement{}
+
+	p.nextToken()
+
+	for !p.curTokenIs(lexer.RBRACE) && !p.curTokenIs(lexer.EOF) {
+		stmt := p.parseStatement()
+		if stmt != nil {
+			block.Statements = append(block.Statements, stmt)
+		}
+		p.nextToken()
+	}
+
+	if !p.curTokenIs(lexer.RBRACE) {
+		p.errorf("expected } to close block, got %s instead", p.curToken.Type)
+	}
+
+	return block
+}
+
+// parseFunctionDeclaration parses a function declaration, starting at the
+// function keyword. async is set when the keyword follows async.
+func (p *Parser) parseFunctionDeclaration(async bool) ast.Statement {
+	stmt := &ast.FunctionDeclaration{Token: p.token(), Async: async}
+
+	if p.peekTokenIs(lexer.ASTERISK) {
+		p.nextToken()
+		stmt.Generator = true
+	}
+	if !p.expectPeek(lexer.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	stmt.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	stmt.Body = p.parseFunctionBody(stmt.Generator, stmt.Async)
+
+	return stmt
+}
+
+func (p *Parser) parseExpression(precedence int) ast.Expression {
+	if !p.enter() {
+		return nil
+	}
+	defer p.leave()
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	return p.continueExpression(prefix(), precedence)
+}
+
+// continueExpression parses the operators that follow leftExp, for as long
+// as they bind tighter than precedence.
+func (p *Parser) continueExpression(leftExp ast.Expression, precedence int) ast.Expression {
+	for !p.peekTokenIs(lexer.SEMICOLON) && precedence < p.peekPrecedence() {
+		infix := p.infixParseFns[p.peekToken.Type]
+		if infix == nil {
+			return leftExp
+		}
+
+		p.nextToken()
+
+		leftExp = infix(leftExp)
+	}
+
+	return leftExp
+}
+
+func (p *Parser) parseIdentifier() ast.Expression {
+	ident := &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	if p.peekTokenIs(lexer.ARROW) {
+		p.nextToken()
+		return p.parseArrowFunction([]ast.Expression{ident}, false)
+	}
+	if ident.Value == "async" {
+		return p.parseAsync(ident)
+	}
+	return ident
+}
+
+// parseAsync parses what follows the word async, which makes the function
+// after it async: async function () {}, async x => x or async (x) => x.
+// Anywhere else async is just a name, and async(x) may well be a call of a
+// function called async. That only becomes clear at the "=>", so the
+// parentheses are parsed as arguments and then turned into parameters if
+// one follows.
+func (p *Parser) parseAsync(ident *ast.Identifier) ast.Expression {
+	switch {
+	case p.peekTokenIs(lexer.FUNCTION):
+		p.nextToken()
+		return p.parseFunctionExpression(true)
+	case p.peekTokenIs(lexer.IDENT):
+		p.nextToken()
+		param := &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+		if !p.expectPeek(lexer.ARROW) {
+			return nil
+		}
+		return p.parseArrowFunction([]ast.Expression{param}, true)
+	case p.peekTokenIs(lexer.LPAREN):
+		p.nextToken()
+		call := p.parseCallExpression(ident).(*ast.CallExpression)
+		if !p.peekTokenIs(lexer.ARROW) {
+			return call
+		}
+		p.nextToken()
+		params := []ast.Expression{}
+		for idx, arg := range call.Arguments {
+			if spread, ok := arg.(*ast.SpreadElement); ok {
+				if idx != len(call.Arguments)-1 {
+					p.errorf("rest parameter must be last formal parameter")
+					return nil
+				}
+				target := p.toAssignmentTarget(spread.Argument, true)
+				if target == nil {
+					return nil
+				}
+				params = append(params, &ast.RestElement{Token: spread.Token, Argument: target})
+				continue
+			}
+			param := p.toAssignmentTarget(arg, true)
+			if param == nil {
+				return nil
+			}
+			params = append(params, param)
+		}
+		return p.parseArrowFunction(params, true)
+	}
+	return ident
+}
+
+// parseNumberLiteral parses a number literal. All JavaScript numbers are
+// floating point, so the value is always a float64.
+func (p *Parser) parseNumberLiteral() ast.Expression {
+	lit := &ast.Literal{Token: p.token()}
+
+	value, err := parseNumber(p.curToken.Literal)
+	if err != nil {
+		p.errorf("could not parse %q as number", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+// parseNumber converts the text of a number literal to its value.
+// Prefixed integers may be too large for an int64, so they're parsed as
+// big integers and then rounded to the nearest float64. A leading zero
+// followed by digits is a legacy octal literal (017 is 15) unless one of
+// the digits is 8 or 9.
+func parseNumber(literal string) (float64, error) {
+	literal = strings.ReplaceAll(literal, "_", "")
+	base := 0
+	digits := literal
+	if len(literal) > 2 && literal[0] == '0' {
+		switch literal[1] {
+		case 'x', 'X':
+			base, digits = 16, literal[2:]
+		case 'o', 'O':
+			base, digits = 8, literal[2:]
+		case 'b', 'B':
+			base, digits = 2, literal[2:]
+		}
+	}
+	if base == 0 && len(literal) > 1 && literal[0] == '0' && !strings.ContainsAny(literal, ".eE89") {
+		base, digits = 8, literal[1:]
+	}
+	if base != 0 {
+		n, ok := new(big.Int).SetString(digits, base)
+		if !ok {
+			return 0, fmt.Errorf("invalid number %q", literal)
+		}
+		f, _ := new(big.Float).SetInt(n).Float64()
+		return f, nil
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if numErr, ok := err.(*strconv.NumError); ok && numErr.Err == strconv.ErrRange {
+		// Too large becomes Infinity and too small becomes 0, as in
+		// JavaScript.
+		return f, nil
+	}
+	return f, err
+}
+
+// parseBigIntLiteral parses a BigInt literal like 123n, whose value is a
+// *big.Int. Only integers can be BigInts, so 1.5n and 1e3n are errors, as
+// are legacy octal literals like 017n.
+func (p *Parser) parseBigIntLiteral() ast.Expression {
+	lit := &ast.Literal{Token: p.token()}
+
+	value, ok := parseBigInt(p.curToken.Literal)
+	if !ok {
+		p.errorf("could not parse %q as BigInt", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+	return lit
+}
+
+func parseBigInt(literal string) (*big.Int, bool) {
+	digits := strings.ReplaceAll(strings.TrimSuffix(literal, "n"), "_", "")
+	base := 10
+	if len(digits) > 2 && digits[0] == '0' {
+		switch digits[1] {
+		case 'x', 'X':
+			base, digits = 16, digits[2:]
+		case 'o', 'O':
+			base, digits = 8, digits[2:]
+		case 'b', 'B':
+			base, digits = 2, digits[2:]
+		}
+	}
+	if base == 10 && (len(digits) > 1 && digits[0] == '0' || strings.ContainsAny(digits, ".eE")) {
+		return nil, false
+	}
+	return new(big.Int).SetString(digits, base)
+}
+
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.Literal{Token: p.token(), Value: p.curToken.Literal}
+}
+
+// parseRegExpLiteral parses a regular expression literal. The lexer reads
+// a / as the division operator, so finding one where an expression starts
+// means it really begins a literal, and the lexer is asked to read it
+// again as one. The pattern is compiled here, as JavaScript reports a
+// malformed pattern when the script is parsed rather than when it runs.
+func (p *Parser) parseRegExpLiteral() ast.Expression {
+	p.curToken = p.l.ReadRegExp()
+	p.peekToken = p.l.NextToken()
+	if p.curToken.Type != lexer.REGEXP {
+		p.errorf("Invalid regular expression: missing /")
+		return nil
+	}
+	literal := p.curToken.Literal
+	end := strings.LastIndexByte(literal, '/')
+	expr := &ast.RegExpLiteral{Token: p.token(), Pattern: literal[1:end], Flags: literal[end+1:]}
+	flags, ok := regex.ParseFlags(expr.Flags)
+	if !ok {
+		p.errorf("Invalid regular expression flags")
+		return nil
+	}
+	if _, err := regex.Compile(utf16.Encode([]rune(expr.Pattern)), flags); err != nil {
+		p.errorf("Invalid regular expression: %s: %s", literal, err)
+		return nil
+	}
+	return expr
+}
+
+func (p *Parser) parseBoolean() ast.Expression {
+	return &ast.Literal{Token: p.token(), Value: p.curTokenIs(lexer.TRUE)}
+}
+
+func (p *Parser) parseNull() ast.Expression {
+	return &ast.Literal{Token: p.token(), Value: nil}
+}
+
+func (p *Parser) parseThis() ast.Expression {
+	return &ast.ThisExpression{Token: p.token()}
+}
+
+func (p *Parser) parseUnaryExpression() ast.Expression {
+	expression := &ast.UnaryExpression{
+		Token:    p.token(),
+		Operator: p.curToken.Literal,
+	}
+
+	p.nextToken()
+	expression.Argument = p.parseExpression(PREFIX)
+	if p.peekTokenIs(lexer.EXPONENT) {
+		// -2 ** 2 could mean (-2) ** 2 or -(2 ** 2), so JavaScript makes
+		// you write the parentheses.
+		p.errorf("unary operator used immediately before exponentiation expression; parentheses must be used to disambiguate")
+	}
+
+	return expression
+}
+
+func (p *Parser) parsePrefixUpdateExpression() ast.Expression {
+	expression := &ast.UpdateExpression{
+		Token:    p.token(),
+		Operator: p.curToken.Literal,
+		Prefix:   true,
+	}
+
+	p.nextToken()
+	expression.Argument = p.parseExpression(PREFIX)
+
+	return expression
+}
+
+func (p *Parser) parsePostfixUpdateExpression(left ast.Expression) ast.Expression {
+	return &ast.UpdateExpression{
+		Token:    p.token(),
+		Operator: p.curToken.Literal,
+		Argument: left,
+	}
+}
+
+func (p *Parser) parseBinaryExpression(left ast.Expression) ast.Expression {
+	expression := &ast.BinaryExpression{
+		Token:    p.token(),
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	precedence := p.curPrecedence()
+	if precedence == EXPONENT {
+		// ** is right-associative: 2 ** 3 ** 2 means 2 ** (3 ** 2).
+		precedence--
+	}
+	p.nextToken()
+	expression.Right = p.parseExpression(precedence)
+
+	return expression
+}
+
+// parseAssignmentExpression parses "left = right" and the compound forms.
+// Assignment is right-associative: a = b = c means a = (b = c), so the right
+// side is parsed with a precedence just below ASSIGN.
+func (p *Parser) parseAssignmentExpression(left ast.Expression) ast.Expression {
+	expression := &ast.AssignmentExpression{
+		Token:    p.token(),
+		Operator: p.curToken.Literal,
+		Left:     left,
+	}
+
+	switch left.(type) {
+	case *ast.Identifier, *ast.MemberExpression:
+	case *ast.ArrayLiteral, *ast.ObjectLiteral:
+		// [a, b] = [b, a] destructures instead.
+		if expression.Operator != "=" {
+			p.errorShowing("invalid assignment target", left)
+		}
+		expression.Left = p.toAssignmentTarget(left, false)
+	default:
+		p.errorShowing("invalid assignment target", left)
+	}
+
+	p.nextToken()
+	expression.Right = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+func (p *Parser) parseConditionalExpression(test ast.Expression) ast.Expression {
+	expression := &ast.ConditionalExpression{Token: p.token(), Test: test}
+
+	p.nextToken()
+	expression.Consequent = p.parseExpression(ASSIGN - 1)
+	if !p.expectPeek(lexer.COLON) {
+		return nil
+	}
+	p.nextToken()
+	expression.Alternate = p.parseExpression(ASSIGN - 1)
+
+	return expression
+}
+
+// parseGroupedExpression parses a parenthesized expression. An opening
+// parenthesis may also start the parameter list of an arrow function, which
+// we only learn once we see the "=>" after the closing parenthesis. The
+// expressions read until then are turned into parameters; a rest parameter,
+// which isn't an expression, tells us early that this must be an arrow
+// function.
+func (p *Parser) parseGroupedExpression() ast.Expression {
+	if p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		if !p.expectPeek(lexer.ARROW) {
+			return nil
+		}
+		return p.parseArrowFunction([]ast.Expression{}, false)
+	}
+
+	exps := []ast.Expression{}
+	var rest ast.Expression
+	for {
+		p.nextToken()
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			rest = p.parseRestElement(lexer.RPAREN)
+			if rest == nil {
+				return nil
+			}
+			break
+		}
+		exps = append(exps, p.parseExpression(LOWEST))
+		if !p.peekTokenIs(lexer.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(lexer.RPAREN) {
+		return nil
+	}
+
+	if p.peekTokenIs(lexer.ARROW) {
+		p.nextToken()
+		params := []ast.Expression{}
+		for _, e := range exps {
+			param := p.toAssignmentTarget(e, true)
+			if param == nil {
+				return nil
+			}
+			params = append(params, param)
+		}
+		if rest != nil {
+			params = append(params, rest)
+		}
+		return p.parseArrowFunction(params, false)
+	}
+
+	if rest != nil {
+		p.peekError(lexer.ARROW)
+		return nil
+	}
+	if len(exps) > 1 {
+		p.errorf("unexpected , in parenthesized expression")
+		return nil
+	}
+	return exps[0]
+}
+
+// parseArrowFunction parses what follows "=>". The body is either a block or
+// a single expression, which is treated as if it were returned.
+func (p *Parser) parseArrowFunction(params []ast.Expression, async bool) ast.Expression {
+	fn := &ast.FunctionLiteral{Token: p.token(), Parameters: params, Arrow: true, Async: async}
+
+	p.nextToken()
+	if p.curTokenIs(lexer.LBRACE) {
+		fn.Body = p.parseFunctionBody(false, async)
+		return fn
+	}
+
+	bodyToken := p.token()
+	outerGenerator, outerAsync := p.inGenerator, p.inAsync
+	p.inGenerator, p.inAsync = false, async
+	value := p.parseExpression(ASSIGN - 1)
+	p.inGenerator, p.inAsync = outerGenerator, outerAsync
+	fn.Body = &ast.BlockStatement{
+		Token:      bodyToken,
+		Statements: []ast.Statement{&ast.ReturnStatement{Token: bodyToken, ReturnValue: value}},
+	}
+	return fn
+}
+
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.token()}
+	array.Elements = p.parseExpressionList(lexer.RBRACKET, true)
+	return array
+}
+
+// parseExpressionList parses comma-separated expressions up to the end token.
+// Spread elements are allowed; holes (as in [1, , 3]) only when allowHoles is
+// set, in which case they are returned as nil.
+func (p *Parser) parseExpressionList(end lexer.TokenType, allowHoles bool) []ast.Expression {
+	list := []ast.Expression{}
+
+	for !p.peekTokenIs(end) {
+		p.nextToken()
+		switch {
+		case p.curTokenIs(lexer.COMMA) && allowHoles:
+			list = append(list, nil)
+			continue
+		case p.curTokenIs(lexer.ELLIPSIS):
+			spread := &ast.SpreadElement{Token: p.token()}
+			p.nextToken()
+			spread.Argument = p.parseExpression(ASSIGN - 1)
+			list = append(list, spread)
+		default:
+			list = append(list, p.parseExpression(ASSIGN-1))
+		}
+
+		if p.peekTokenIs(end) {
+			break
+		}
+		if !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return list
+}
+
+func (p *Parser) parseObjectLiteral() ast.Expression {
+	obj := &ast.ObjectLiteral{Token: p.token()}
+	obj.Properties = []*ast.Property{}
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		prop := p.parseProperty()
+		if prop == nil {
+			return nil
+		}
+		obj.Properties = append(obj.Properties, prop)
+
+		if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return obj
+}
+
+// parseProperty parses one entry of an object literal: "key: value",
+// the shorthand "key" (meaning key: key), a method "key(params) { ... }",
+// a generator method "*key(params) { ... }", the async forms of both, or
+// a spread "...obj".
+func (p *Parser) parseProperty() *ast.Property {
+	prop := &ast.Property{}
+
+	if p.curTokenIs(lexer.ELLIPSIS) {
+		spread := &ast.SpreadElement{Token: p.token()}
+		p.nextToken()
+		spread.Argument = p.parseExpression(ASSIGN - 1)
+		prop.Value = spread
+		return prop
+	}
+
+	// async is a modifier only when a key follows it; { async: 1 },
+	// { async() {} } and { async } use it as a name.
+	async := false
+	if p.curTokenIs(lexer.IDENT) && p.curToken.Literal == "async" {
+		switch p.peekToken.Type {
+		case lexer.COLON, lexer.LPAREN, lexer.COMMA, lexer.RBRACE, lexer.ASSIGN:
+		default:
+			async = true
+			p.nextToken()
+		}
+	}
+
+	generator := false
+	if p.curTokenIs(lexer.ASTERISK) {
+		generator = true
+		p.nextToken()
+	}
+
+	if !p.parsePropertyKey(prop) {
+		return nil
+	}
+
+	if (generator || async) && !p.peekTokenIs(lexer.LPAREN) {
+		p.peekError(lexer.LPAREN)
+		return nil
+	}
+
+	switch {
+	case p.peekTokenIs(lexer.COLON):
+		p.nextToken()
+		p.nextToken()
+		prop.Value = p.parseExpression(ASSIGN - 1)
+	case p.peekTokenIs(lexer.LPAREN):
+		fn := &ast.FunctionLiteral{Token: p.token(), Generator: generator, Async: async}
+		if ident, ok := prop.Key.(*ast.Identifier); ok {
+			fn.Name = ident
+		}
+		p.nextToken()
+		fn.Parameters = p.parseFunctionParameters()
+		if !p.expectPeek(lexer.LBRACE) {
+			return nil
+		}
+		fn.Body = p.parseFunctionBody(generator, async)
+		prop.Value = fn
+	case p.peekTokenIs(lexer.ASSIGN):
+		// { a = 1 } is only valid as a destructuring pattern, where it
+		// gives a a default. The evaluator rejects it anywhere else.
+		ident, ok := prop.Key.(*ast.Identifier)
+		if !ok || prop.Computed {
+			p.errorShowing("expected : after property key", prop.Key)
+			return nil
+		}
+		p.nextToken()
+		pattern := &ast.AssignmentPattern{Token: p.token(), Left: ident}
+		p.nextToken()
+		pattern.Right = p.parseExpression(ASSIGN - 1)
+		prop.Value = pattern
+	default:
+		ident, ok := prop.Key.(*ast.Identifier)
+		if !ok || prop.Computed {
+			p.errorShowing("expected : after property key", prop.Key)
+			return nil
+		}
+		prop.Value = ident
+	}
+
+	return prop
+}
+
+// parsePropertyKey parses the key of an object literal entry or of an
+// object pattern property into prop.
+func (p *Parser) parsePropertyKey(prop *ast.Property) bool {
+	switch {
+	case p.curTokenIs(lexer.LBRACKET):
+		p.nextToken()
+		prop.Key = p.parseExpression(LOWEST)
+		prop.Computed = true
+		if !p.expectPeek(lexer.RBRACKET) {
+			return false
+		}
+	case p.curTokenIs(lexer.STRING):
+		prop.Key = &ast.Literal{Token: p.token(), Value: p.curToken.Literal}
+	case p.curTokenIs(lexer.INT):
+		// Numeric keys are normalized when evaluated: { 1.50: x } has
+		// the key "1.5".
+		prop.Key = p.parseNumberLiteral()
+		if prop.Key == nil {
+			return false
+		}
+	case p.curTokenIs(lexer.BIGINT):
+		prop.Key = p.parseBigIntLiteral()
+		if prop.Key == nil {
+			return false
+		}
+	case isIdentifierName(p.curToken):
+		prop.Key = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	default:
+		p.errorf("unexpected %s in object literal", p.curToken.Type)
+		return false
+	}
+	return true
+}
+
+func (p *Parser) parseFunctionLiteral() ast.Expression {
+	return p.parseFunctionExpression(false)
+}
+
+// parseFunctionExpression parses a function expression, starting at the
+// function keyword. async is set when the keyword follows async.
+func (p *Parser) parseFunctionExpression(async bool) ast.Expression {
+	lit := &ast.FunctionLiteral{Token: p.token(), Async: async}
+
+	if p.peekTokenIs(lexer.ASTERISK) {
+		p.nextToken()
+		lit.Generator = true
+	}
+	if p.peekTokenIs(lexer.IDENT) {
+		p.nextToken()
+		lit.Name = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(lexer.LPAREN) {
+		return nil
+	}
+	lit.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(lexer.LBRACE) {
+		return nil
+	}
+	lit.Body = p.parseFunctionBody(lit.Generator, lit.Async)
+
+	return lit
+}
+
+// parseFunctionBody parses the block that is the body of a function.
+// Whether yield and await are allowed depends only on the innermost
+// function, so the settings for the enclosing function are put back
+// afterwards.
+func (p *Parser) parseFunctionBody(generator, async bool) *ast.BlockStatement {
+	outerGenerator, outerAsync := p.inGenerator, p.inAsync
+	p.inGenerator, p.inAsync = generator, async
+	defer func() { p.inGenerator, p.inAsync = outerGenerator, outerAsync }()
+	return p.parseBlockStatement()
+}
+
+// parseFunctionParameters parses a parameter list. Parameters may be
+// destructuring patterns and have defaults, and the last one may be a rest
+// parameter, as in function f({ a }, b = 1, ...others).
+func (p *Parser) parseFunctionParameters() []ast.Expression {
+	params := []ast.Expression{}
+
+	for !p.peekTokenIs(lexer.RPAREN) {
+		p.nextToken()
+		var param ast.Expression
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			param = p.parseRestElement(lexer.RPAREN)
+		} else {
+			param = p.parseBindingElement()
+		}
+		if param == nil {
+			return nil
+		}
+		params = append(params, param)
+
+		if !p.peekTokenIs(lexer.RPAREN) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return params
+}
+
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.token(), Function: function}
+	exp.Arguments = p.parseExpressionList(lexer.RPAREN, false)
+	return exp
+}
+
+func (p *Parser) parseIndexExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.token(), Object: object, Computed: true}
+
+	p.nextToken()
+	exp.Property = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(lexer.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+// parseDotExpression parses obj.name. Any identifier-like word may follow
+// the dot, including keywords, so obj.new and obj.for are valid.
+func (p *Parser) parseDotExpression(object ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.token(), Object: object}
+
+	p.nextToken()
+	if !isIdentifierName(p.curToken) {
+		p.errorf("unexpected %s after .", p.curToken.Type)
+		return nil
+	}
+	exp.Property = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	return exp
+}
+
+// parseNewExpression parses "new Callee(args)". The callee may be a dotted
+// path such as new lib.Widget(), but a call ends it: the first argument list
+// belongs to new.
+func (p *Parser) parseNewExpression() ast.Expression {
+	exp := &ast.NewExpression{Token: p.token()}
+
+	p.nextToken()
+	prefix := p.prefixParseFns[p.curToken.Type]
+	if prefix == nil {
+		p.noPrefixParseFnError(p.curToken.Type)
+		return nil
+	}
+	callee := prefix()
+	for p.peekTokenIs(lexer.DOT) || p.peekTokenIs(lexer.LBRACKET) {
+		p.nextToken()
+		if p.curTokenIs(lexer.DOT) {
+			callee = p.parseDotExpression(callee)
+		} else {
+			callee = p.parseIndexExpression(callee)
+		}
+	}
+	exp.Callee = callee
+
+	exp.Arguments = []ast.Expression{}
+	if p.peekTokenIs(lexer.LPAREN) {
+		p.nextToken()
+		exp.Arguments = p.parseExpressionList(lexer.RPAREN, false)
+	}
+	return exp
+}
+
+// isIdentifierName reports whether tok is a word that can name a property:
+// an identifier or any keyword.
+func isIdentifierName(tok lexer.Token) bool {
+	if tok.Literal == "" {
+		return false
+	}
+	ch := tok.Literal[0]
+	return tok.Type != lexer.STRING &&
+		('a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' || ch == '$')
+}
+
+func (p *Parser) curTokenIs(t lexer.TokenType) bool {
+	return p.curToken.Type == t
+}
+
+func (p *Parser) peekTokenIs(t lexer.TokenType) bool {
+	return p.peekToken.Type == t
+}
+
+func (p *Parser) expectPeek(t lexer.TokenType) bool {
+	if p.peekTokenIs(t) {
+		p.nextToken()
+		return true
+	}
+	p.peekError(t)
+	return false
+}
+
+func (p *Parser) registerPrefix(tokenType lexer.TokenType, fn prefixParseFn) {
+	p.prefixParseFns[tokenType] = fn
+}
+
+func (p *Parser) registerInfix(tokenType lexer.TokenType, fn infixParseFn) {
+	p.infixParseFns[tokenType] = fn
+}
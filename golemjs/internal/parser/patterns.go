@@ -0,0 +1,223 @@
+package parser
+
+import (
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+)
+
+// Destructuring patterns reach the parser in two ways. In declarations and
+// parameter lists, a [ or { can only start a pattern, so the pattern is
+// parsed directly:
+//
+//	const { a, b: [c = 1, ...rest] } = obj;
+//
+// In an assignment or the parameters of an arrow function, the parser
+// doesn't know it has been reading a pattern until it reaches the = or =>
+// after it. By then the pattern has been parsed as an array or object
+// literal, so it is converted afterwards:
+//
+//	[a, b] = [b, a];
+//	({ x, y }) => x + y
+
+// parseBindingTarget parses what a declaration or parameter binds: a name,
+// or an array or object pattern.
+func (p *Parser) parseBindingTarget() ast.Expression {
+	switch p.curToken.Type {
+	case lexer.IDENT:
+		return &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+	case lexer.LBRACKET:
+		return p.parseArrayBindingPattern()
+	case lexer.LBRACE:
+		return p.parseObjectBindingPattern()
+	}
+	p.errorf("unexpected %s in binding pattern", p.curToken.Type)
+	return nil
+}
+
+// parseBindingElement parses a binding target with an optional default
+// value, as in the b = 2 of function f(a, b = 2).
+func (p *Parser) parseBindingElement() ast.Expression {
+	target := p.parseBindingTarget()
+	if target == nil || !p.peekTokenIs(lexer.ASSIGN) {
+		return target
+	}
+	p.nextToken()
+	pattern := &ast.AssignmentPattern{Token: p.token(), Left: target}
+	p.nextToken()
+	pattern.Right = p.parseExpression(ASSIGN - 1)
+	return pattern
+}
+
+// parseRestElement parses ...target, which must be the last entry before
+// the closing token end.
+func (p *Parser) parseRestElement(end lexer.TokenType) ast.Expression {
+	rest := &ast.RestElement{Token: p.token()}
+	p.nextToken()
+	rest.Argument = p.parseBindingTarget()
+	if rest.Argument == nil {
+		return nil
+	}
+	if !p.peekTokenIs(end) {
+		p.errorf("rest element must be last element")
+		return nil
+	}
+	return rest
+}
+
+func (p *Parser) parseArrayBindingPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.token()}
+
+	for !p.peekTokenIs(lexer.RBRACKET) {
+		p.nextToken()
+		var element ast.Expression
+		switch {
+		case p.curTokenIs(lexer.COMMA):
+			pattern.Elements = append(pattern.Elements, nil)
+			continue
+		case p.curTokenIs(lexer.ELLIPSIS):
+			element = p.parseRestElement(lexer.RBRACKET)
+		default:
+			element = p.parseBindingElement()
+		}
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(lexer.RBRACKET) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return pattern
+}
+
+func (p *Parser) parseObjectBindingPattern() ast.Expression {
+	pattern := &ast.ObjectPattern{Token: p.token()}
+
+	for !p.peekTokenIs(lexer.RBRACE) {
+		p.nextToken()
+		if p.curTokenIs(lexer.ELLIPSIS) {
+			p.nextToken()
+			if !p.curTokenIs(lexer.IDENT) {
+				p.errorf("`...` must be followed by an identifier in declaration contexts")
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.token(), Value: p.curToken.Literal}
+			if !p.peekTokenIs(lexer.RBRACE) {
+				p.errorf("rest element must be last element")
+				return nil
+			}
+			break
+		}
+
+		prop := &ast.Property{}
+		if !p.parsePropertyKey(prop) {
+			return nil
+		}
+		if p.peekTokenIs(lexer.COLON) {
+			p.nextToken()
+			p.nextToken()
+			prop.Value = p.parseBindingElement()
+		} else {
+			// The shorthand { a } binds a variable named after the key.
+			ident, ok := prop.Key.(*ast.Identifier)
+			if !ok || prop.Computed || p.curToken.Type != lexer.IDENT {
+				p.errorShowing("expected : after property key", prop.Key)
+				return nil
+			}
+			prop.Value = p.parseBindingElement()
+			prop.Key = ident
+		}
+		if prop.Value == nil {
+			return nil
+		}
+		pattern.Properties = append(pattern.Properties, prop)
+
+		if !p.peekTokenIs(lexer.RBRACE) && !p.expectPeek(lexer.COMMA) {
+			return nil
+		}
+	}
+	p.nextToken()
+
+	return pattern
+}
+
+// toAssignmentTarget converts an expression that turned out to be the
+// target of an assignment into a pattern. Array and object literals become
+// patterns, and assignments inside them become defaults. When binding is
+// set the target declares variables, as arrow function parameters do, so
+// property accesses like obj.x aren't allowed.
+func (p *Parser) toAssignmentTarget(exp ast.Expression, binding bool) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp
+	case *ast.MemberExpression:
+		if !binding {
+			return exp
+		}
+	case *ast.AssignmentExpression:
+		if exp.Operator != "=" {
+			break
+		}
+		left := p.toAssignmentTarget(exp.Left, binding)
+		if left == nil {
+			return nil
+		}
+		return &ast.AssignmentPattern{Token: exp.Token, Left: left, Right: exp.Right}
+	case *ast.AssignmentPattern:
+		// A shorthand property with a default, { a = 1 }, is already
+		// parsed as a pattern.
+		return exp
+	case *ast.ArrayLiteral:
+		pattern := &ast.ArrayPattern{Token: exp.Token}
+		for idx, e := range exp.Elements {
+			if e == nil {
+				pattern.Elements = append(pattern.Elements, nil)
+				continue
+			}
+			if spread, ok := e.(*ast.SpreadElement); ok {
+				if idx != len(exp.Elements)-1 {
+					p.errorf("rest element must be last element")
+					return nil
+				}
+				target := p.toAssignmentTarget(spread.Argument, binding)
+				if target == nil {
+					return nil
+				}
+				pattern.Elements = append(pattern.Elements, &ast.RestElement{Token: spread.Token, Argument: target})
+				continue
+			}
+			target := p.toAssignmentTarget(e, binding)
+			if target == nil {
+				return nil
+			}
+			pattern.Elements = append(pattern.Elements, target)
+		}
+		return pattern
+	case *ast.ObjectLiteral:
+		pattern := &ast.ObjectPattern{Token: exp.Token}
+		for idx, prop := range exp.Properties {
+			if spread, ok := prop.Value.(*ast.SpreadElement); ok && prop.Key == nil {
+				if idx != len(exp.Properties)-1 {
+					p.errorf("rest element must be last element")
+					return nil
+				}
+				pattern.Rest = p.toAssignmentTarget(spread.Argument, binding)
+				if pattern.Rest == nil {
+					return nil
+				}
+				continue
+			}
+			target := p.toAssignmentTarget(prop.Value, binding)
+			if target == nil {
+				return nil
+			}
+			pattern.Properties = append(pattern.Properties, &ast.Property{Key: prop.Key, Value: target, Computed: prop.Computed})
+		}
+		return pattern
+	}
+	p.errorShowing("invalid destructuring target", exp)
+	return nil
+}
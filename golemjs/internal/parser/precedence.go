@@ -0,0 +1,88 @@
+package parser
+
+import "github.com/biosbuddha/golemjs/internal/lexer"
+
+// Operator precedence levels, from loosest to tightest binding.
+// When the parser sees "1 + 2 * 3" it uses these levels to decide that
+// the multiplication must be grouped first: 1 + (2 * 3).
+const (
+	_ int = iota
+	LOWEST
+	ASSIGN      // = += -= ...
+	CONDITIONAL // a ? b : c
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
+	BITWISE_OR  // |
+	BITWISE_XOR // ^
+	BITWISE_AND // &
+	EQUALS      // == != === !==
+	LESSGREATER // < > <= >= instanceof
+	SHIFT       // << >> >>>
+	SUM         // + -
+	PRODUCT     // * / %
+	EXPONENT    // **
+	PREFIX      // -x !x ++x
+	POSTFIX     // x++ x--
+	CALL        // fn(x) obj.x obj[x]
+)
+
+// precedences maps each infix operator token to its precedence level.
+// Tokens missing from this table never continue an expression.
+var precedences = map[lexer.TokenType]int{
+	lexer.ASSIGN:          ASSIGN,
+	lexer.PLUS_ASSIGN:     ASSIGN,
+	lexer.MINUS_ASSIGN:    ASSIGN,
+	lexer.ASTERISK_ASSIGN: ASSIGN,
+	lexer.SLASH_ASSIGN:    ASSIGN,
+	lexer.PERCENT_ASSIGN:  ASSIGN,
+	lexer.EXPONENT_ASSIGN: ASSIGN,
+	lexer.BIT_AND_ASSIGN:  ASSIGN,
+	lexer.BIT_OR_ASSIGN:   ASSIGN,
+	lexer.BIT_XOR_ASSIGN:  ASSIGN,
+	lexer.SHL_ASSIGN:      ASSIGN,
+	lexer.SHR_ASSIGN:      ASSIGN,
+	lexer.USHR_ASSIGN:     ASSIGN,
+	lexer.QUESTION:        CONDITIONAL,
+	lexer.OR:              LOGICAL_OR,
+	lexer.AND:             LOGICAL_AND,
+	lexer.BIT_OR:          BITWISE_OR,
+	lexer.BIT_XOR:         BITWISE_XOR,
+	lexer.BIT_AND:         BITWISE_AND,
+	lexer.EQ:              EQUALS,
+	lexer.NOT_EQ:          EQUALS,
+	lexer.STRICT_EQ:       EQUALS,
+	lexer.STRICT_NOT_EQ:   EQUALS,
+	lexer.LT:              LESSGREATER,
+	lexer.GT:              LESSGREATER,
+	lexer.LT_EQ:           LESSGREATER,
+	lexer.GT_EQ:           LESSGREATER,
+	lexer.INSTANCEOF:      LESSGREATER,
+	lexer.SHL:             SHIFT,
+	lexer.SHR:             SHIFT,
+	lexer.USHR:            SHIFT,
+	lexer.PLUS:            SUM,
+	lexer.MINUS:           SUM,
+	lexer.SLASH:           PRODUCT,
+	lexer.ASTERISK:        PRODUCT,
+	lexer.PERCENT:         PRODUCT,
+	lexer.EXPONENT:        EXPONENT,
+	lexer.INCREMENT:       POSTFIX,
+	lexer.DECREMENT:       POSTFIX,
+	lexer.LPAREN:          CALL,
+	lexer.LBRACKET:        CALL,
+	lexer.DOT:             CALL,
+}
+
+func (p *Parser) peekPrecedence() int {
+	if p, ok := precedences[p.peekToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
+
+func (p *Parser) curPrecedence() int {
+	if p, ok := precedences[p.curToken.Type]; ok {
+		return p
+	}
+	return LOWEST
+}
@@ -0,0 +1,207 @@
+package regex
+
+import (
+	"strings"
+	"unicode"
+)
+
+// charSet is the set of characters a character class matches. A
+// character is in it if it falls in one of the ranges or satisfies one of
+// the predicates; negate inverts the whole set, as in [^a-z] or \D. A
+// class like [\D_] holds \D as a predicate, so each escape keeps its own
+// negation.
+type charSet struct {
+	negate bool
+	ranges []charRange
+	preds  []func(rune) bool
+}
+
+type charRange struct{ lo, hi rune }
+
+func (s *charSet) addRange(lo, hi rune) {
+	s.ranges = append(s.ranges, charRange{lo, hi})
+}
+
+// add adds a single character, or if inner is not nil, the set an escape
+// like \d stands for.
+func (s *charSet) add(c rune, inner *charSet) {
+	if inner == nil {
+		s.addRange(c, c)
+		return
+	}
+	s.preds = append(s.preds, inner.matches)
+}
+
+// contains reports whether c is in the set, ignoring negate.
+func (s *charSet) contains(c rune) bool {
+	for _, r := range s.ranges {
+		if r.lo <= c && c <= r.hi {
+			return true
+		}
+	}
+	for _, pred := range s.preds {
+		if pred(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// matches reports whether c is in the set.
+func (s *charSet) matches(c rune) bool {
+	return s.contains(c) != s.negate
+}
+
+// isWhiteSpace reports whether c is matched by \s: JavaScript's white
+// space and line terminators.
+func isWhiteSpace(c rune) bool {
+	switch c {
+	case '\t', '\n', '\v', '\f', '\r', ' ', 0xA0, 0x1680, 0x2028, 0x2029, 0x202F, 0x205F, 0x3000, 0xFEFF:
+		return true
+	}
+	return 0x2000 <= c && c <= 0x200A
+}
+
+// isWordChar reports whether c is matched by \w.
+func isWordChar(c rune) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c) || c == '_'
+}
+
+func isLineTerminator(c rune) bool {
+	return c == '\n' || c == '\r' || c == 0x2028 || c == 0x2029
+}
+
+// canonicalize maps a character to the form case-insensitive matching
+// compares. Without the u flag, that is its upper case, as long as upper
+// casing gives a single character and doesn't turn a non-ASCII character
+// into an ASCII one (so the long s, ſ, doesn't match S). With the u flag,
+// it is its simple case folding, which Go doesn't expose directly; the
+// smallest character in its fold orbit identifies the same class.
+func canonicalize(c rune, unicodeMode bool) rune {
+	if !unicodeMode {
+		upper := unicode.ToUpper(c)
+		if c >= 128 && upper < 128 {
+			return c
+		}
+		return upper
+	}
+	least := c
+	for f := unicode.SimpleFold(c); f != c; f = unicode.SimpleFold(f) {
+		if f < least {
+			least = f
+		}
+	}
+	return least
+}
+
+// foldMatches reports whether c matches chars when case is ignored: some
+// character in chars must canonicalize to the same form as c. Characters
+// that canonicalize alike are always in the same fold orbit, so only the
+// orbit of c needs checking.
+func foldMatches(chars *charSet, c rune, unicodeMode bool) bool {
+	canon := canonicalize(c, unicodeMode)
+	found := chars.contains(c)
+	for f := unicode.SimpleFold(c); !found && f != c; f = unicode.SimpleFold(f) {
+		found = canonicalize(f, unicodeMode) == canon && chars.contains(f)
+	}
+	return found != chars.negate
+}
+
+// categoryAliases maps the long names of general categories, which
+// \p{...} accepts alongside the short ones Go uses, to the short ones.
+var categoryAliases = map[string]string{
+	"Letter": "L", "Cased_Letter": "LC", "Uppercase_Letter": "Lu", "Lowercase_Letter": "Ll",
+	"Titlecase_Letter": "Lt", "Modifier_Letter": "Lm", "Other_Letter": "Lo",
+	"Mark": "M", "Combining_Mark": "M", "Nonspacing_Mark": "Mn", "Spacing_Mark": "Mc", "Enclosing_Mark": "Me",
+	"Number": "N", "Decimal_Number": "Nd", "digit": "Nd", "Letter_Number": "Nl", "Other_Number": "No",
+	"Punctuation": "P", "punct": "P", "Connector_Punctuation": "Pc", "Dash_Punctuation": "Pd",
+	"Open_Punctuation": "Ps", "Close_Punctuation": "Pe", "Initial_Punctuation": "Pi",
+	"Final_Punctuation": "Pf", "Other_Punctuation": "Po",
+	"Symbol": "S", "Math_Symbol": "Sm", "Currency_Symbol": "Sc", "Modifier_Symbol": "Sk", "Other_Symbol": "So",
+	"Separator": "Z", "Space_Separator": "Zs", "Line_Separator": "Zl", "Paragraph_Separator": "Zp",
+	"Other": "C", "Control": "Cc", "cntrl": "Cc", "Format": "Cf", "Surrogate": "Cs",
+	"Private_Use": "Co", "Unassigned": "Cn",
+}
+
+// unicodeProperty returns the predicate for the body of a \p{...} escape:
+// a general category (L, Letter, gc=Lu, General_Category=Lu), a script
+// (Script=Greek, sc=Greek; Script_Extensions is treated as Script), or a
+// binary property (Alphabetic, White_Space, ...). It returns nil for
+// anything else.
+func unicodeProperty(spec string) func(rune) bool {
+	if name, value, ok := strings.Cut(spec, "="); ok {
+		switch name {
+		case "General_Category", "gc":
+			return generalCategory(value)
+		case "Script", "sc", "Script_Extensions", "scx":
+			if table, ok := unicode.Scripts[value]; ok {
+				return inTable(table)
+			}
+		}
+		return nil
+	}
+	if pred := generalCategory(spec); pred != nil {
+		return pred
+	}
+	return binaryProperty(spec)
+}
+
+func generalCategory(name string) func(rune) bool {
+	if short, ok := categoryAliases[name]; ok {
+		name = short
+	}
+	switch name {
+	case "LC":
+		return func(c rune) bool { return unicode.In(c, unicode.Lu, unicode.Ll, unicode.Lt) }
+	case "Cn":
+		return func(c rune) bool { return !isAssigned(c) }
+	case "C":
+		// Go's C doesn't include unassigned code points; JavaScript's
+		// does.
+		return func(c rune) bool { return unicode.Is(unicode.C, c) || !isAssigned(c) }
+	}
+	if table, ok := unicode.Categories[name]; ok {
+		return inTable(table)
+	}
+	return nil
+}
+
+func binaryProperty(name string) func(rune) bool {
+	switch name {
+	case "Any":
+		return func(rune) bool { return true }
+	case "ASCII":
+		return func(c rune) bool { return c < 128 }
+	case "Assigned":
+		return isAssigned
+	case "Alphabetic", "Alpha":
+		return func(c rune) bool {
+			return unicode.In(c, unicode.L, unicode.Nl, unicode.Other_Alphabetic)
+		}
+	case "Lowercase", "Lower":
+		return func(c rune) bool { return unicode.In(c, unicode.Ll, unicode.Other_Lowercase) }
+	case "Uppercase", "Upper":
+		return func(c rune) bool { return unicode.In(c, unicode.Lu, unicode.Other_Uppercase) }
+	}
+	if table, ok := unicode.Properties[name]; ok {
+		return inTable(table)
+	}
+	return nil
+}
+
+func inTable(table *unicode.RangeTable) func(rune) bool {
+	return func(c rune) bool { return unicode.Is(table, c) }
+}
+
+// isAssigned reports whether c has been given a general category other
+// than Cn (unassigned).
+func isAssigned(c rune) bool {
+	for _, table := range unicode.Categories {
+		if len(table.R16) > 0 || len(table.R32) > 0 {
+			if unicode.Is(table, c) {
+				return true
+			}
+		}
+	}
+	return false
+}
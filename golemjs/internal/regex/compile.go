@@ -0,0 +1,353 @@
+package regex
+
+// A matcher tries to match part of a pattern at pos. Each way it finds to
+// match, it passes the position it reached to the continuation k, which
+// matches the rest of the pattern; it returns true as soon as k does, and
+// false once it runs out of ways to match. That is how backtracking
+// happens: returning false from a continuation sends the matcher before it
+// on to its next choice.
+type matcher func(m *machine, pos int, k cont) bool
+
+type cont func(pos int) bool
+
+// machine is the state of one match: the input, and where each group has
+// matched so far. caps[2n] and caps[2n+1] are the start and end of group
+// n, or -1.
+//
+// A pattern like /(a+)+b/ can take exponential time to fail, and every
+// character matched is another continuation on the Go stack, so the
+// machine also counts the steps it takes, asking step whether to go on,
+// and how deeply continuations are nested. Once either says stop, err is
+// set and every matcher fails, unwinding the match.
+type machine struct {
+	input []uint16
+	caps  []int
+
+	step  func() bool
+	depth int
+	err   error
+}
+
+// maxDepth is how deeply continuations may nest: how many characters a
+// match can have consumed with each still open to backtracking. Each takes
+// a few frames of the Go stack, so this keeps a match within a few hundred
+// megabytes of the billion-byte limit at which Go crashes.
+const maxDepth = 1 << 16
+
+// advance counts a step of the match, reporting false if the match has to
+// stop.
+func (m *machine) advance() bool {
+	if m.err == nil && m.step != nil && !m.step() {
+		m.err = ErrStopped
+	}
+	return m.err == nil
+}
+
+// descend calls k with pos, counting the continuation as one level deeper.
+func (m *machine) descend(k cont, pos int) bool {
+	if m.depth >= maxDepth {
+		m.err = ErrTooDeep
+		return false
+	}
+	m.depth++
+	matched := k(pos)
+	m.depth--
+	return matched
+}
+
+// charAt reads the character after pos, or before it if backward is set,
+// and returns it with the position on its other side.
+func (m *machine) charAt(pos int, backward, unicodeMode bool) (rune, int, bool) {
+	if backward {
+		if pos <= 0 {
+			return 0, 0, false
+		}
+		c := rune(m.input[pos-1])
+		if unicodeMode && isLowSurrogate(c) && pos >= 2 && isHighSurrogate(rune(m.input[pos-2])) {
+			return combineSurrogates(rune(m.input[pos-2]), c), pos - 2, true
+		}
+		return c, pos - 1, true
+	}
+	if pos >= len(m.input) {
+		return 0, 0, false
+	}
+	c := rune(m.input[pos])
+	if unicodeMode && isHighSurrogate(c) && pos+1 < len(m.input) && isLowSurrogate(rune(m.input[pos+1])) {
+		return combineSurrogates(c, rune(m.input[pos+1])), pos + 2, true
+	}
+	return c, pos + 1, true
+}
+
+// saveCaps copies the positions of groups first to first+count-1, so they
+// can be put back when a match that changed them fails.
+func (m *machine) saveCaps(first, count int) []int {
+	return append([]int(nil), m.caps[2*first:2*(first+count)]...)
+}
+
+func (m *machine) restoreCaps(first int, saved []int) {
+	copy(m.caps[2*first:], saved)
+}
+
+// compiler turns a syntax tree into matchers. Inside a lookbehind, backward
+// is set: the matchers it builds read the input right to left, so
+// (?<=ab) checks for b just before the position and then for a before
+// that.
+type compiler struct {
+	flags    Flags
+	backward bool
+}
+
+func (c *compiler) compile(n node) matcher {
+	switch n := n.(type) {
+	case *disjunction:
+		return c.compileDisjunction(n)
+	case *sequence:
+		return c.compileSequence(n.terms)
+	case *char:
+		want := n.c
+		if c.flags.IgnoreCase {
+			canon := canonicalize(want, c.flags.Unicode)
+			return c.compileChar(func(r rune) bool { return canonicalize(r, c.flags.Unicode) == canon })
+		}
+		return c.compileChar(func(r rune) bool { return r == want })
+	case *set:
+		chars := n.chars
+		if c.flags.IgnoreCase {
+			return c.compileChar(func(r rune) bool { return foldMatches(chars, r, c.flags.Unicode) })
+		}
+		return c.compileChar(chars.matches)
+	case *dot:
+		if c.flags.DotAll {
+			return c.compileChar(func(rune) bool { return true })
+		}
+		return c.compileChar(func(r rune) bool { return !isLineTerminator(r) })
+	case *assertion:
+		return c.compileAssertion(n.kind)
+	case *group:
+		return c.compileGroup(n)
+	case *look:
+		return c.compileLook(n)
+	case *backreference:
+		return c.compileBackreference(n)
+	case *repeat:
+		return c.compileRepeat(n)
+	}
+	panic("regex: unknown node")
+}
+
+func (c *compiler) compileDisjunction(n *disjunction) matcher {
+	alternatives := make([]matcher, len(n.alternatives))
+	for idx, alt := range n.alternatives {
+		alternatives[idx] = c.compile(alt)
+	}
+	return func(m *machine, pos int, k cont) bool {
+		for _, alt := range alternatives {
+			if alt(m, pos, k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// compileSequence chains the matchers of terms, each one's continuation
+// running the next. Read backward, the last term is matched first.
+func (c *compiler) compileSequence(terms []node) matcher {
+	if len(terms) == 0 {
+		return func(m *machine, pos int, k cont) bool { return k(pos) }
+	}
+	matchers := make([]matcher, len(terms))
+	for idx, term := range terms {
+		matchers[idx] = c.compile(term)
+	}
+	if c.backward {
+		for left, right := 0, len(matchers)-1; left < right; left, right = left+1, right-1 {
+			matchers[left], matchers[right] = matchers[right], matchers[left]
+		}
+	}
+	seq := matchers[len(matchers)-1]
+	for idx := len(matchers) - 2; idx >= 0; idx-- {
+		first, rest := matchers[idx], seq
+		seq = func(m *machine, pos int, k cont) bool {
+			return first(m, pos, func(next int) bool { return rest(m, next, k) })
+		}
+	}
+	return seq
+}
+
+func (c *compiler) compileChar(pred func(rune) bool) matcher {
+	backward, unicodeMode := c.backward, c.flags.Unicode
+	return func(m *machine, pos int, k cont) bool {
+		if !m.advance() {
+			return false
+		}
+		r, next, ok := m.charAt(pos, backward, unicodeMode)
+		return ok && pred(r) && m.descend(k, next)
+	}
+}
+
+func (c *compiler) compileAssertion(kind byte) matcher {
+	multiline := c.flags.Multiline
+	isWord := func(m *machine, pos int) bool {
+		return pos >= 0 && pos < len(m.input) && isWordChar(rune(m.input[pos]))
+	}
+	var test func(m *machine, pos int) bool
+	switch kind {
+	case '^':
+		test = func(m *machine, pos int) bool {
+			return pos == 0 || multiline && isLineTerminator(rune(m.input[pos-1]))
+		}
+	case '$':
+		test = func(m *machine, pos int) bool {
+			return pos == len(m.input) || multiline && isLineTerminator(rune(m.input[pos]))
+		}
+	case 'b':
+		test = func(m *machine, pos int) bool { return isWord(m, pos-1) != isWord(m, pos) }
+	case 'B':
+		test = func(m *machine, pos int) bool { return isWord(m, pos-1) == isWord(m, pos) }
+	}
+	return func(m *machine, pos int, k cont) bool {
+		return test(m, pos) && k(pos)
+	}
+}
+
+// compileGroup records where a capturing group matched before going on,
+// and puts back what it replaced if the rest of the pattern fails.
+func (c *compiler) compileGroup(n *group) matcher {
+	body := c.compile(n.body)
+	if n.index == 0 {
+		return body
+	}
+	index, backward := n.index, c.backward
+	return func(m *machine, pos int, k cont) bool {
+		return body(m, pos, func(end int) bool {
+			saved := m.saveCaps(index, 1)
+			start, stop := pos, end
+			if backward {
+				start, stop = end, pos
+			}
+			m.caps[2*index], m.caps[2*index+1] = start, stop
+			if k(end) {
+				return true
+			}
+			m.restoreCaps(index, saved)
+			return false
+		})
+	}
+}
+
+// compileLook builds a lookaround. Its body is matched on its own, with a
+// continuation that accepts any position, and never backtracked into: once
+// a lookahead has matched, the rest of the pattern can't make it match
+// differently. Groups in a positive lookaround keep what they captured;
+// those in a negative one never capture anything.
+func (c *compiler) compileLook(n *look) matcher {
+	inner := &compiler{flags: c.flags, backward: n.behind}
+	body := inner.compile(n.body)
+	negate := n.negate
+	return func(m *machine, pos int, k cont) bool {
+		saved := m.saveCaps(0, len(m.caps)/2)
+		matched := body(m, pos, func(int) bool { return true })
+		if m.err != nil {
+			// A negative lookaround must not take a stopped match for
+			// a failed one.
+			return false
+		}
+		if negate {
+			m.restoreCaps(0, saved)
+			return !matched && k(pos)
+		}
+		if !matched {
+			return false
+		}
+		if k(pos) {
+			return true
+		}
+		m.restoreCaps(0, saved)
+		return false
+	}
+}
+
+// compileBackreference matches the text a group last matched. A group that
+// hasn't matched matches the empty string.
+func (c *compiler) compileBackreference(n *backreference) matcher {
+	ref, backward := n.index, c.backward
+	ignoreCase, unicodeMode := c.flags.IgnoreCase, c.flags.Unicode
+	return func(m *machine, pos int, k cont) bool {
+		start, end := m.caps[2*ref], m.caps[2*ref+1]
+		if start < 0 || end < 0 {
+			return k(pos)
+		}
+		length := end - start
+		from := pos
+		if backward {
+			from = pos - length
+		}
+		if from < 0 || from+length > len(m.input) {
+			return false
+		}
+		for j := 0; j < length; j++ {
+			a, b := rune(m.input[start+j]), rune(m.input[from+j])
+			if a != b && (!ignoreCase || canonicalize(a, unicodeMode) != canonicalize(b, unicodeMode)) {
+				return false
+			}
+		}
+		if backward {
+			return m.descend(k, from)
+		}
+		return m.descend(k, pos+length)
+	}
+}
+
+// compileRepeat follows the specification's RepeatMatcher. Each repetition
+// resets the groups inside the body, so /(a|b)*/ reports only what the
+// last repetition captured. A repetition past the minimum that matches the
+// empty string is rejected, which stops /(a*)*/ looping forever.
+func (c *compiler) compileRepeat(n *repeat) matcher {
+	body := c.compile(n.body)
+	greedy, firstGroup, groups := n.greedy, n.firstGroup, n.groups
+	var rep func(m *machine, pos int, k cont, min, max int) bool
+	rep = func(m *machine, pos int, k cont, min, max int) bool {
+		if !m.advance() {
+			return false
+		}
+		if max == 0 {
+			return k(pos)
+		}
+		again := func(next int) bool {
+			if min == 0 && next == pos {
+				return false
+			}
+			nextMin, nextMax := min, max
+			if nextMin > 0 {
+				nextMin--
+			}
+			if nextMax > 0 {
+				nextMax--
+			}
+			return rep(m, next, k, nextMin, nextMax)
+		}
+		tryBody := func() bool {
+			saved := m.saveCaps(firstGroup, groups)
+			for idx := 2 * firstGroup; idx < 2*(firstGroup+groups); idx++ {
+				m.caps[idx] = -1
+			}
+			if body(m, pos, again) {
+				return true
+			}
+			m.restoreCaps(firstGroup, saved)
+			return false
+		}
+		if min > 0 {
+			return tryBody()
+		}
+		if greedy {
+			return tryBody() || k(pos)
+		}
+		return k(pos) || tryBody()
+	}
+	min, max := n.min, n.max
+	return func(m *machine, pos int, k cont) bool {
+		return rep(m, pos, k, min, max)
+	}
+}
@@ -0,0 +1,205 @@
+// Package regex implements JavaScript's regular expressions.
+//
+// Go's regexp package can't be used for them: it guarantees linear-time
+// matching, which rules out features JavaScript has, such as backreferences
+// (/(a+)\1/) and lookbehind (/(?<=\$)\d+/). This package is instead a
+// backtracking matcher built the way the ECMAScript specification describes
+// one: the pattern is parsed into a tree, and the tree is compiled into
+// matcher functions. A matcher tries to match its part of the pattern at a
+// position and then calls a continuation with the position it reached,
+// which tries to match the rest of the pattern. When the continuation
+// fails, the matcher tries its next alternative, such as one repetition
+// fewer of a greedy a*, and calls the continuation again.
+//
+// Like JavaScript strings, the input is a sequence of UTF-16 code units,
+// and positions are code unit indices. Without the u flag, a pattern
+// matches code unit by code unit; with it, a surrogate pair is one
+// character.
+package regex
+
+import (
+	"errors"
+	"strings"
+)
+
+// Flags are the flags that change how a pattern matches, written after
+// the closing slash of a literal such as /a/gi.
+type Flags struct {
+	HasIndices bool // d: report where each group matched
+	Global     bool // g: find every match, not just the first
+	IgnoreCase bool // i: match regardless of case
+	Multiline  bool // m: ^ and $ match at line breaks
+	DotAll     bool // s: . matches line breaks too
+	Unicode    bool // u: match code points, with stricter syntax
+	Sticky     bool // y: match only at lastIndex
+}
+
+// ParseFlags reads a flags string such as "gi". It reports false if the
+// string contains an unknown flag or the same flag twice.
+func ParseFlags(s string) (Flags, bool) {
+	var f Flags
+	for _, c := range s {
+		var flag *bool
+		switch c {
+		case 'd':
+			flag = &f.HasIndices
+		case 'g':
+			flag = &f.Global
+		case 'i':
+			flag = &f.IgnoreCase
+		case 'm':
+			flag = &f.Multiline
+		case 's':
+			flag = &f.DotAll
+		case 'u':
+			flag = &f.Unicode
+		case 'y':
+			flag = &f.Sticky
+		default:
+			return Flags{}, false
+		}
+		if *flag {
+			return Flags{}, false
+		}
+		*flag = true
+	}
+	return f, true
+}
+
+// String returns the flags in the order JavaScript lists them.
+func (f Flags) String() string {
+	var out strings.Builder
+	for _, flag := range []struct {
+		set bool
+		c   byte
+	}{
+		{f.HasIndices, 'd'}, {f.Global, 'g'}, {f.IgnoreCase, 'i'}, {f.Multiline, 'm'},
+		{f.DotAll, 's'}, {f.Unicode, 'u'}, {f.Sticky, 'y'},
+	} {
+		if flag.set {
+			out.WriteByte(flag.c)
+		}
+	}
+	return out.String()
+}
+
+// Error is a syntax error in a pattern. Its message is the one V8 gives,
+// such as "Unterminated group".
+type Error struct {
+	Message string
+}
+
+func (e *Error) Error() string { return e.Message }
+
+// The errors a match fails with when it's cut short: ErrStopped when the
+// step function said to stop, and ErrTooDeep when the match would have
+// needed more backtracking state than the matcher keeps. JavaScript engines
+// report the latter as a stack overflow.
+var (
+	ErrStopped = errors.New("regex: match stopped")
+	ErrTooDeep = errors.New("regex: match too deep")
+)
+
+// Regexp is a compiled pattern. It holds no state between matches, so one
+// Regexp can be shared by any number of RegExp objects.
+type Regexp struct {
+	prog  matcher
+	flags Flags
+	names []string // names[n-1] is the name of group n, or ""
+}
+
+// Compile compiles pattern, given as UTF-16 code units.
+func Compile(pattern []uint16, flags Flags) (*Regexp, error) {
+	tree, names, err := parse(patternChars(pattern, flags.Unicode), flags.Unicode)
+	if err != nil {
+		return nil, err
+	}
+	c := &compiler{flags: flags}
+	return &Regexp{prog: c.compile(tree), flags: flags, names: names}, nil
+}
+
+// patternChars splits a pattern into the characters the parser reads: code
+// points with the u flag, and code units without it.
+func patternChars(pattern []uint16, unicode bool) []rune {
+	chars := make([]rune, 0, len(pattern))
+	for idx := 0; idx < len(pattern); idx++ {
+		c := rune(pattern[idx])
+		if unicode && isHighSurrogate(c) && idx+1 < len(pattern) && isLowSurrogate(rune(pattern[idx+1])) {
+			c = combineSurrogates(c, rune(pattern[idx+1]))
+			idx++
+		}
+		chars = append(chars, c)
+	}
+	return chars
+}
+
+// Flags returns the flags the pattern was compiled with.
+func (re *Regexp) Flags() Flags { return re.flags }
+
+// Groups returns the number of capturing groups in the pattern.
+func (re *Regexp) Groups() int { return len(re.names) }
+
+// GroupNames returns the name of each capturing group, or "" for groups
+// without one. The name of group n is at index n-1.
+func (re *Regexp) GroupNames() []string { return re.names }
+
+// MatchAt matches the pattern starting exactly at pos. It returns the
+// start and end of the whole match followed by those of each group, with
+// -1 for groups that didn't take part in the match, or nil if there is no
+// match.
+//
+// Unless step is nil, it's called at every step of the match, that is,
+// for every character tried and every repetition; returning false stops
+// the match with ErrStopped. A host running untrusted patterns uses it to
+// bound how long one may take.
+func (re *Regexp) MatchAt(input []uint16, pos int, step func() bool) ([]int, error) {
+	m := &machine{input: input, caps: make([]int, 2*(len(re.names)+1)), step: step}
+	return re.matchAt(m, pos)
+}
+
+func (re *Regexp) matchAt(m *machine, pos int) ([]int, error) {
+	for idx := range m.caps {
+		m.caps[idx] = -1
+	}
+	matched := re.prog(m, pos, func(end int) bool {
+		m.caps[0], m.caps[1] = pos, end
+		return true
+	})
+	if m.err != nil {
+		return nil, m.err
+	}
+	if !matched {
+		return nil, nil
+	}
+	return m.caps, nil
+}
+
+// Find returns the first match starting at or after from, as MatchAt
+// reports it. With the u flag, it never starts a match in the middle of a
+// surrogate pair it has stepped over.
+func (re *Regexp) Find(input []uint16, from int, step func() bool) ([]int, error) {
+	m := &machine{input: input, caps: make([]int, 2*(len(re.names)+1)), step: step}
+	for pos := from; pos <= len(input); pos = AdvanceIndex(input, pos, re.flags.Unicode) {
+		caps, err := re.matchAt(m, pos)
+		if caps != nil || err != nil {
+			return caps, err
+		}
+	}
+	return nil, nil
+}
+
+// AdvanceIndex returns the position after pos: the next code unit, or with
+// unicode set, the position after the code point at pos.
+func AdvanceIndex(input []uint16, pos int, unicode bool) int {
+	if unicode && pos+1 < len(input) && isHighSurrogate(rune(input[pos])) && isLowSurrogate(rune(input[pos+1])) {
+		return pos + 2
+	}
+	return pos + 1
+}
+
+func isHighSurrogate(c rune) bool { return 0xD800 <= c && c < 0xDC00 }
+func isLowSurrogate(c rune) bool  { return 0xDC00 <= c && c < 0xE000 }
+
+func combineSurrogates(high, low rune) rune {
+	return 0x10000 + (high-0xD800)<<10 + (low - 0xDC00)
+}
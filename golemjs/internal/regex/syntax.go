@@ -0,0 +1,751 @@
+package regex
+
+import (
+	"math"
+	"unicode"
+)
+
+// The parser turns a pattern into a tree of nodes, following the grammar
+// of the specification. Without the u flag, the grammar is the lenient one
+// browsers have always accepted (Annex B of the specification): a stray
+// ] or { is an ordinary character, and an escape such as \q that means
+// nothing is simply the character after the backslash. With the u flag,
+// these are syntax errors.
+
+type node interface{}
+
+type (
+	// disjunction matches any one of its alternatives, trying them from
+	// left to right: a|b.
+	disjunction struct{ alternatives []node }
+
+	// sequence matches its terms one after the other.
+	sequence struct{ terms []node }
+
+	// char matches a single character.
+	char struct{ c rune }
+
+	// set matches a character in a character class, such as [a-z] or \d.
+	set struct{ chars *charSet }
+
+	// dot matches any character, except line terminators without the s
+	// flag.
+	dot struct{}
+
+	// assertion matches a position rather than characters: ^, $, \b or
+	// \B.
+	assertion struct{ kind byte }
+
+	// group matches its body, recording where it matched if index is not
+	// 0: (...), (?<name>...) or (?:...).
+	group struct {
+		index int
+		body  node
+	}
+
+	// look matches if its body matches (or, if negate is set, doesn't)
+	// just after the current position, or with behind set, just before
+	// it: (?=...), (?!...), (?<=...) and (?<!...).
+	look struct {
+		behind, negate bool
+		body           node
+	}
+
+	// backreference matches the text group index last matched: \1 or
+	// \k<name>.
+	backreference struct {
+		index int
+		name  string
+	}
+
+	// repeat matches body between min and max times, or at least min
+	// times if max is -1. Before each repetition, the groups inside body,
+	// numbered firstGroup onwards, are reset.
+	repeat struct {
+		body               node
+		min, max           int
+		greedy             bool
+		firstGroup, groups int
+	}
+)
+
+type parser struct {
+	src     []rune
+	pos     int
+	unicode bool
+
+	// groupTotal is the number of capturing groups in the whole pattern,
+	// which decides whether \2 is a backreference or, without the u flag,
+	// an octal escape.
+	groupTotal int
+
+	// namedGroups is set if the pattern has a named group, which makes \k
+	// a backreference even without the u flag.
+	namedGroups bool
+
+	groupCount int      // capturing groups opened so far
+	names      []string // names[n-1] is the name of group n
+	namedRefs  []*backreference
+}
+
+// parse parses a pattern and returns its tree and the names of its groups.
+func parse(src []rune, unicode bool) (tree node, names []string, err error) {
+	p := &parser{src: src, unicode: unicode}
+	p.groupTotal, p.namedGroups = countGroups(src)
+	p.names = make([]string, p.groupTotal)
+
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*Error)
+			if !ok {
+				panic(r)
+			}
+			tree, names, err = nil, nil, e
+		}
+	}()
+
+	tree = p.parseDisjunction()
+	if p.more() {
+		// parseDisjunction only stops early at a ) without a (.
+		p.fail("Unmatched ')'")
+	}
+	for _, ref := range p.namedRefs {
+		ref.index = p.groupNamed(ref.name)
+		if ref.index == 0 {
+			p.fail("Invalid named capture referenced")
+		}
+	}
+	return tree, p.names, nil
+}
+
+// countGroups counts the capturing groups in a pattern before it is
+// parsed, and reports whether any of them are named.
+func countGroups(src []rune) (count int, named bool) {
+	inClass := false
+	for idx := 0; idx < len(src); idx++ {
+		switch src[idx] {
+		case '\\':
+			idx++
+		case '[':
+			inClass = true
+		case ']':
+			inClass = false
+		case '(':
+			if inClass {
+				continue
+			}
+			if idx+1 < len(src) && src[idx+1] == '?' {
+				if idx+3 < len(src) && src[idx+2] == '<' && src[idx+3] != '=' && src[idx+3] != '!' {
+					count++
+					named = true
+				}
+				continue
+			}
+			count++
+		}
+	}
+	return count, named
+}
+
+func (p *parser) fail(message string) {
+	panic(&Error{Message: message})
+}
+
+func (p *parser) more() bool { return p.pos < len(p.src) }
+
+func (p *parser) peek() rune {
+	if p.pos < len(p.src) {
+		return p.src[p.pos]
+	}
+	return -1
+}
+
+func (p *parser) peekAt(offset int) rune {
+	if p.pos+offset < len(p.src) {
+		return p.src[p.pos+offset]
+	}
+	return -1
+}
+
+func (p *parser) next() rune {
+	c := p.peek()
+	p.pos++
+	return c
+}
+
+// eat consumes s if the pattern continues with it.
+func (p *parser) eat(s string) bool {
+	idx := p.pos
+	for _, c := range s {
+		if idx >= len(p.src) || p.src[idx] != c {
+			return false
+		}
+		idx++
+	}
+	p.pos = idx
+	return true
+}
+
+func (p *parser) groupNamed(name string) int {
+	for idx, n := range p.names {
+		if n == name {
+			return idx + 1
+		}
+	}
+	return 0
+}
+
+func (p *parser) parseDisjunction() node {
+	alternatives := []node{p.parseAlternative()}
+	for p.peek() == '|' {
+		p.pos++
+		alternatives = append(alternatives, p.parseAlternative())
+	}
+	if len(alternatives) == 1 {
+		return alternatives[0]
+	}
+	return &disjunction{alternatives: alternatives}
+}
+
+func (p *parser) parseAlternative() node {
+	seq := &sequence{}
+	for p.more() && p.peek() != '|' && p.peek() != ')' {
+		seq.terms = append(seq.terms, p.parseTerm())
+	}
+	return seq
+}
+
+func (p *parser) parseTerm() node {
+	switch {
+	case p.peek() == '^':
+		p.pos++
+		return p.unquantifiable(&assertion{kind: '^'})
+	case p.peek() == '$':
+		p.pos++
+		return p.unquantifiable(&assertion{kind: '$'})
+	case p.eat(`\b`):
+		return p.unquantifiable(&assertion{kind: 'b'})
+	case p.eat(`\B`):
+		return p.unquantifiable(&assertion{kind: 'B'})
+	case p.eat("(?="), p.eat("(?!"):
+		negate := p.src[p.pos-1] == '!'
+		node := &look{negate: negate, body: p.parseGroupBody()}
+		if p.unicode {
+			return p.unquantifiable(node)
+		}
+		// Lookaheads could be repeated before the u flag existed.
+		return p.parseQuantifier(node, p.groupCount)
+	case p.eat("(?<="), p.eat("(?<!"):
+		negate := p.src[p.pos-1] == '!'
+		return p.unquantifiable(&look{behind: true, negate: negate, body: p.parseGroupBody()})
+	}
+	groupsBefore := p.groupCount
+	return p.parseQuantifier(p.parseAtom(), groupsBefore)
+}
+
+// unquantifiable checks that an assertion isn't followed by a quantifier.
+func (p *parser) unquantifiable(n node) node {
+	switch p.peek() {
+	case '*', '+', '?':
+		p.fail("Nothing to repeat")
+	case '{':
+		if _, _, ok := p.braceQuantifier(); ok || p.unicode {
+			p.fail("Nothing to repeat")
+		}
+	}
+	return n
+}
+
+// parseGroupBody parses what follows the opening of a group up to and
+// including its closing parenthesis.
+func (p *parser) parseGroupBody() node {
+	body := p.parseDisjunction()
+	if p.peek() != ')' {
+		p.fail("Unterminated group")
+	}
+	p.pos++
+	return body
+}
+
+func (p *parser) parseAtom() node {
+	c := p.peek()
+	switch c {
+	case '.':
+		p.pos++
+		return &dot{}
+	case '(':
+		return p.parseGroup()
+	case '[':
+		return &set{chars: p.parseClass()}
+	case '\\':
+		return p.parseAtomEscape()
+	case '*', '+', '?':
+		p.fail("Nothing to repeat")
+	case '{':
+		if p.unicode {
+			p.fail("Lone quantifier brackets")
+		}
+		if _, _, ok := p.braceQuantifier(); ok {
+			p.fail("Nothing to repeat")
+		}
+	case '}', ']':
+		if p.unicode {
+			p.fail("Lone quantifier brackets")
+		}
+	}
+	p.pos++
+	return &char{c: c}
+}
+
+func (p *parser) parseGroup() node {
+	p.pos++ // (
+	if p.eat("?:") {
+		return &group{body: p.parseGroupBody()}
+	}
+	name := ""
+	if p.eat("?<") {
+		name = p.parseGroupName()
+		if p.groupNamed(name) != 0 {
+			p.fail("Duplicate capture group name")
+		}
+	} else if p.peek() == '?' {
+		p.fail("Invalid group")
+	}
+	p.groupCount++
+	index := p.groupCount
+	p.names[index-1] = name
+	return &group{index: index, body: p.parseGroupBody()}
+}
+
+// parseGroupName reads the name of a named group or reference up to and
+// including the closing >.
+func (p *parser) parseGroupName() string {
+	start := p.pos
+	for p.more() && p.peek() != '>' {
+		c := p.next()
+		valid := c == '$' || c == '_' || unicode.IsLetter(c) || unicode.Is(unicode.Nl, c)
+		if p.pos-1 > start {
+			valid = valid || c == '‌' || c == '‍' ||
+				unicode.In(c, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc)
+		}
+		if !valid {
+			p.fail("Invalid capture group name")
+		}
+	}
+	if !p.more() || p.pos == start {
+		p.fail("Invalid capture group name")
+	}
+	p.pos++ // >
+	return string(p.src[start : p.pos-1])
+}
+
+// parseQuantifier parses the quantifier after atom, if there is one.
+// groupsBefore is the number of groups opened before atom, so the groups
+// inside it are the ones after that.
+func (p *parser) parseQuantifier(atom node, groupsBefore int) node {
+	min, max := 0, -1
+	switch p.peek() {
+	case '*':
+		p.pos++
+	case '+':
+		min = 1
+		p.pos++
+	case '?':
+		max = 1
+		p.pos++
+	case '{':
+		lo, hi, ok := p.braceQuantifier()
+		if !ok {
+			if p.unicode {
+				p.fail("Incomplete quantifier")
+			}
+			// Without the u flag, the { is an ordinary character, left
+			// for the next term.
+			return atom
+		}
+		min, max = lo, hi
+	default:
+		return atom
+	}
+	greedy := true
+	if p.peek() == '?' {
+		p.pos++
+		greedy = false
+	}
+	if max != -1 && min > max {
+		p.fail("numbers out of order in {} quantifier")
+	}
+	return &repeat{body: atom, min: min, max: max, greedy: greedy, firstGroup: groupsBefore + 1, groups: p.groupCount - groupsBefore}
+}
+
+// braceQuantifier reads {n}, {n,} or {n,m}. If the text at the current
+// position isn't one of these, it reports false and consumes nothing.
+func (p *parser) braceQuantifier() (min, max int, ok bool) {
+	start := p.pos
+	p.pos++ // {
+	min, ok = p.decimal()
+	if !ok {
+		p.pos = start
+		return 0, 0, false
+	}
+	max = min
+	if p.peek() == ',' {
+		p.pos++
+		max = -1
+		if n, ok := p.decimal(); ok {
+			max = n
+		}
+	}
+	if p.peek() != '}' {
+		p.pos = start
+		return 0, 0, false
+	}
+	p.pos++
+	return min, max, true
+}
+
+// decimal reads a run of decimal digits. Numbers too large to matter are
+// capped.
+func (p *parser) decimal() (int, bool) {
+	start := p.pos
+	n := 0
+	for p.more() && '0' <= p.peek() && p.peek() <= '9' {
+		n = n*10 + int(p.next()-'0')
+		if n > math.MaxInt32 {
+			n = math.MaxInt32
+		}
+	}
+	return n, p.pos > start
+}
+
+func (p *parser) parseAtomEscape() node {
+	p.pos++ // \
+	if !p.more() {
+		p.fail(`\ at end of pattern`)
+	}
+	c := p.peek()
+	switch {
+	case '1' <= c && c <= '9':
+		start := p.pos
+		n, _ := p.decimal()
+		if n <= p.groupTotal {
+			return &backreference{index: n}
+		}
+		if p.unicode {
+			p.fail("Invalid escape")
+		}
+		p.pos = start
+		if c >= '8' {
+			p.pos++
+			return &char{c: c}
+		}
+		return &char{c: p.legacyOctal()}
+	case c == '0':
+		p.pos++
+		if isDigit(p.peek()) {
+			if p.unicode {
+				p.fail("Invalid decimal escape")
+			}
+			p.pos--
+			return &char{c: p.legacyOctal()}
+		}
+		return &char{c: 0}
+	case c == 'k':
+		p.pos++
+		if !p.unicode && !p.namedGroups {
+			return &char{c: 'k'}
+		}
+		if !p.eat("<") {
+			p.fail("Invalid named reference")
+		}
+		ref := &backreference{name: p.parseGroupName()}
+		p.namedRefs = append(p.namedRefs, ref)
+		return ref
+	}
+	if chars := p.classEscape(); chars != nil {
+		return &set{chars: chars}
+	}
+	return &char{c: p.characterEscape(false)}
+}
+
+// classEscape parses \d, \D, \s, \S, \w and \W, and with the u flag
+// \p{...} and \P{...}, whose backslash has been read. It returns nil for
+// other escapes.
+func (p *parser) classEscape() *charSet {
+	c := p.peek()
+	switch c {
+	case 'd', 'D', 's', 'S', 'w', 'W':
+		p.pos++
+		chars := &charSet{}
+		switch c {
+		case 'd', 'D':
+			chars.addRange('0', '9')
+		case 's', 'S':
+			chars.preds = append(chars.preds, isWhiteSpace)
+		case 'w', 'W':
+			chars.preds = append(chars.preds, isWordChar)
+		}
+		chars.negate = c == 'D' || c == 'S' || c == 'W'
+		return chars
+	case 'p', 'P':
+		if !p.unicode {
+			return nil
+		}
+		p.pos++
+		chars := p.parseProperty()
+		chars.negate = c == 'P'
+		return chars
+	}
+	return nil
+}
+
+// parseProperty parses the {...} of \p{...}.
+func (p *parser) parseProperty() *charSet {
+	if !p.eat("{") {
+		p.fail("Invalid property name")
+	}
+	start := p.pos
+	for p.more() && p.peek() != '}' {
+		p.pos++
+	}
+	if !p.more() {
+		p.fail("Invalid property name")
+	}
+	spec := string(p.src[start:p.pos])
+	p.pos++ // }
+	pred := unicodeProperty(spec)
+	if pred == nil {
+		p.fail("Invalid property name")
+	}
+	return &charSet{preds: []func(rune) bool{pred}}
+}
+
+// characterEscape parses an escape that stands for a single character,
+// whose backslash has been read.
+func (p *parser) characterEscape(inClass bool) rune {
+	c := p.next()
+	switch c {
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'v':
+		return '\v'
+	case 'c':
+		letter := p.peek()
+		if 'a' <= letter && letter <= 'z' || 'A' <= letter && letter <= 'Z' ||
+			inClass && !p.unicode && (isDigit(letter) || letter == '_') {
+			p.pos++
+			return letter % 32
+		}
+		if p.unicode {
+			p.fail("Invalid unicode escape")
+		}
+		// A \c that isn't a control escape is a backslash, and the c is
+		// read again as an ordinary character.
+		p.pos--
+		return '\\'
+	case 'x':
+		if n, ok := p.hexDigits(2); ok {
+			return n
+		}
+		if p.unicode {
+			p.fail("Invalid escape")
+		}
+		return 'x'
+	case 'u':
+		if n, ok := p.unicodeEscape(); ok {
+			return n
+		}
+		if p.unicode {
+			p.fail("Invalid Unicode escape")
+		}
+		return 'u'
+	}
+	if p.unicode {
+		if isSyntaxChar(c) || c == '/' || inClass && c == '-' {
+			return c
+		}
+		p.fail("Invalid escape")
+	}
+	return c
+}
+
+// unicodeEscape reads what follows \u: four hexadecimal digits, or with the
+// u flag, a code point in braces like \u{1F600}. With the u flag, a
+// surrogate pair written as two escapes is read as one character.
+func (p *parser) unicodeEscape() (rune, bool) {
+	if p.unicode && p.peek() == '{' {
+		start := p.pos
+		p.pos++
+		var n rune
+		digits := 0
+		for p.more() && isHexDigit(p.peek()) {
+			n = n*16 + hexValue(p.next())
+			digits++
+			if n > unicode.MaxRune {
+				p.fail("Invalid Unicode escape")
+			}
+		}
+		if digits == 0 || !p.eat("}") {
+			p.pos = start
+			return 0, false
+		}
+		return n, true
+	}
+	n, ok := p.hexDigits(4)
+	if !ok {
+		return 0, false
+	}
+	if p.unicode && isHighSurrogate(n) && p.peek() == '\\' && p.peekAt(1) == 'u' {
+		start := p.pos
+		p.pos += 2
+		if low, ok := p.hexDigits(4); ok && isLowSurrogate(low) {
+			return combineSurrogates(n, low), true
+		}
+		p.pos = start
+	}
+	return n, true
+}
+
+// hexDigits reads n hexadecimal digits, consuming nothing if they aren't
+// all there.
+func (p *parser) hexDigits(n int) (rune, bool) {
+	var value rune
+	for j := 0; j < n; j++ {
+		c := p.peekAt(j)
+		if !isHexDigit(c) {
+			return 0, false
+		}
+		value = value*16 + hexValue(c)
+	}
+	p.pos += n
+	return value, true
+}
+
+// legacyOctal reads an octal escape such as \101 ("A"), which patterns
+// without the u flag still accept. Values go up to \377.
+func (p *parser) legacyOctal() rune {
+	first := p.next() - '0'
+	value := first
+	maxDigits := 2
+	if first > 3 {
+		maxDigits = 1
+	}
+	for j := 0; j < maxDigits && '0' <= p.peek() && p.peek() <= '7'; j++ {
+		value = value*8 + p.next() - '0'
+	}
+	return value
+}
+
+// parseClass parses a character class such as [a-z_] or [^\d\s].
+func (p *parser) parseClass() *charSet {
+	p.pos++ // [
+	chars := &charSet{}
+	if p.peek() == '^' {
+		p.pos++
+		chars.negate = true
+	}
+	for {
+		if !p.more() {
+			p.fail("Unterminated character class")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return chars
+		}
+		lo, loSet := p.parseClassAtom()
+		if p.peek() != '-' || p.peekAt(1) == ']' || p.peekAt(1) == -1 {
+			chars.add(lo, loSet)
+			continue
+		}
+		p.pos++ // -
+		hi, hiSet := p.parseClassAtom()
+		if loSet != nil || hiSet != nil {
+			// A range needs a single character at each end. Without the
+			// u flag, [\d-z] is \d, - and z.
+			if p.unicode {
+				p.fail("Invalid character class")
+			}
+			chars.add(lo, loSet)
+			chars.add('-', nil)
+			chars.add(hi, hiSet)
+			continue
+		}
+		if lo > hi {
+			p.fail("Range out of order in character class")
+		}
+		chars.addRange(lo, hi)
+	}
+}
+
+// parseClassAtom parses one element of a character class: either a single
+// character, or a class escape like \d, returned as a set.
+func (p *parser) parseClassAtom() (rune, *charSet) {
+	if !p.more() {
+		p.fail("Unterminated character class")
+	}
+	c := p.next()
+	if c != '\\' {
+		return c, nil
+	}
+	if !p.more() {
+		p.fail(`\ at end of pattern`)
+	}
+	switch e := p.peek(); {
+	case e == 'b':
+		p.pos++
+		return '\b', nil
+	case e == '-' && p.unicode:
+		p.pos++
+		return '-', nil
+	case isDigit(e):
+		if p.unicode {
+			if e == '0' && !isDigit(p.peekAt(1)) {
+				p.pos++
+				return 0, nil
+			}
+			p.fail("Invalid class escape")
+		}
+		if e >= '8' {
+			p.pos++
+			return e, nil
+		}
+		return p.legacyOctal(), nil
+	case e == 'k' && p.unicode:
+		p.fail("Invalid escape")
+	}
+	if chars := p.classEscape(); chars != nil {
+		return 0, chars
+	}
+	return p.characterEscape(true), nil
+}
+
+func isDigit(c rune) bool { return '0' <= c && c <= '9' }
+
+func isHexDigit(c rune) bool {
+	return isDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func hexValue(c rune) rune {
+	switch {
+	case isDigit(c):
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
+// isSyntaxChar reports whether c has a meaning of its own in patterns.
+func isSyntaxChar(c rune) bool {
+	switch c {
+	case '^', '$', '\\', '.', '*', '+', '?', '(', ')', '[', ']', '{', '}', '|':
+		return true
+	}
+	return false
+}
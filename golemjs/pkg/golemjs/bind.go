@@ -0,0 +1,252 @@
+package golemjs
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"weak"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+)
+
+// A struct isn't copied into a script, but bound: the script gets an
+// object whose properties are the struct's fields, read and written in
+// place, and whose methods are the struct's methods. Given a pointer, the
+// script works on the struct the pointer points to, so Go code sees what
+// the script changes, and the script sees what Go code changes:
+//
+//	type Account struct {
+//		Owner   string  `js:"owner"`
+//		Balance float64 `js:"balance"`
+//		secret  string
+//	}
+//
+//	func (a *Account) Deposit(amount float64) error { ... }
+//
+//	acct := &Account{Owner: "ann"}
+//	rt.Set("acct", acct)
+//	rt.RunString(`acct.Deposit(10); acct.balance += 5`) // acct.Balance == 15
+//
+// The exported fields are the properties, named after the fields unless
+// a js tag names them; a field tagged js:"-" is left out, and the fields
+// of embedded structs are promoted, as they are in Go. The exported
+// methods, those of the pointer, so that methods can change the struct,
+// are kept on a prototype shared by every object of the struct type, and
+// called as Go functions are; see call.go. A struct value, rather than a
+// pointer, is copied first, and the script gets the copy.
+
+// binding is how the objects bound to structs of one type are made.
+type binding struct {
+	fields []boundField
+	byName map[string]int // the index in fields of each property
+
+	// proto is the prototype of the objects, which holds the methods.
+	proto *interpreter.Hash
+}
+
+// boundField is a field of the struct, and the name of the property it
+// becomes.
+type boundField struct {
+	name  string
+	index []int // the index of the field, as FieldByIndex takes it
+	typ   reflect.Type
+}
+
+// structHost keeps the properties of an object bound to a struct. v is
+// the struct itself, which is addressable.
+type structHost struct {
+	rt *Runtime
+	v  reflect.Value
+	b  *binding
+}
+
+func (h *structHost) Keys() []string {
+	names := make([]string, len(h.b.fields))
+	for idx, f := range h.b.fields {
+		names[idx] = f.name
+	}
+	return names
+}
+
+func (h *structHost) Get(name string) (interpreter.Object, bool) {
+	idx, ok := h.b.byName[name]
+	if !ok {
+		return nil, false
+	}
+	field := h.v.FieldByIndex(h.b.fields[idx].index)
+	// A struct inside the struct is bound in place too, so that a script
+	// assigning to one of its fields changes it, rather than a copy.
+	if field.Kind() == reflect.Struct && !isOptional(field.Type()) {
+		obj, err := h.rt.bind(field.Addr())
+		if err != nil {
+			return h.rt.throw(err), true
+		}
+		return obj, true
+	}
+	return h.rt.returned(field), true
+}
+
+func (h *structHost) Set(name string, value interpreter.Object) (bool, *interpreter.Error) {
+	idx, ok := h.b.byName[name]
+	if !ok {
+		return false, nil
+	}
+	f := h.b.fields[idx]
+	converted, err := h.rt.toGo(value, f.typ)
+	if err != nil {
+		return true, &interpreter.Error{Message: fmt.Sprintf("TypeError: Cannot set property %s: %s", name, err)}
+	}
+	h.v.FieldByIndex(f.index).Set(converted)
+	return true, nil
+}
+
+// bind returns an object bound to the struct ptr points to. The object
+// is kept, weakly, so that binding the same pointer again, as reading a
+// field that points back to the struct does, returns the same object for
+// as long as the script holds on to it; otherwise a struct that refers to
+// itself would look to JSON.stringify like one that never ends.
+func (r *Runtime) bind(ptr reflect.Value) (interpreter.Object, error) {
+	key := visit{ptr: ptr.Pointer(), typ: ptr.Type()}
+	if obj := r.objects[key].Value(); obj != nil {
+		return obj, nil
+	}
+	b, err := r.binding(ptr.Type().Elem())
+	if err != nil {
+		return nil, err
+	}
+	obj := r.interp.NewHostObject(&structHost{rt: r, v: ptr.Elem(), b: b}, b.proto)
+	r.sweep()
+	r.objects[key] = weak.Make(obj)
+	return obj, nil
+}
+
+// minSweep is the fewest entries r.objects holds before it's swept.
+const minSweep = 64
+
+// sweep removes the entries for objects that have been collected, so that
+// binding a stream of short-lived structs doesn't fill r.objects with
+// them. It only looks once the map has doubled since the last sweep, so
+// that the work is spread over the binds that grew it.
+func (r *Runtime) sweep() {
+	if len(r.objects) < max(r.sweepAt, minSweep) {
+		return
+	}
+	for key, ref := range r.objects {
+		if ref.Value() == nil {
+			delete(r.objects, key)
+		}
+	}
+	r.sweepAt = 2 * len(r.objects)
+}
+
+// boundTo returns a pointer to the struct obj is bound to, if it's bound
+// to one.
+func (r *Runtime) boundTo(obj interpreter.Object) (reflect.Value, bool) {
+	host, ok := obj.(*interpreter.HostObject)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	h, ok := host.Host.(*structHost)
+	if !ok || h.rt != r {
+		return reflect.Value{}, false
+	}
+	return h.v.Addr(), true
+}
+
+// binding returns the binding of the struct type t, which it makes the
+// first time it's asked for.
+func (r *Runtime) binding(t reflect.Type) (*binding, error) {
+	if b, ok := r.bindings[t]; ok {
+		return b, nil
+	}
+	b := &binding{byName: map[string]int{}, proto: r.interp.NewObject()}
+	r.bindings[t] = b
+	b.fields = fieldsOf(t)
+	for idx, f := range b.fields {
+		b.byName[f.name] = idx
+	}
+	ptrType := reflect.PointerTo(t)
+	for idx := range ptrType.NumMethod() {
+		m := ptrType.Method(idx)
+		if !m.IsExported() {
+			continue
+		}
+		method, err := r.method(t, m)
+		if err != nil {
+			delete(r.bindings, t)
+			return nil, err
+		}
+		b.proto.Set(&interpreter.String{Value: m.Name}, method)
+	}
+	return b, nil
+}
+
+// method wraps the method m of the struct type t as a JavaScript function,
+// which calls it on the struct its receiver is bound to.
+func (r *Runtime) method(t reflect.Type, m reflect.Method) (interpreter.Object, error) {
+	// The type of m.Func takes the receiver first, which the script doesn't
+	// pass, so a context.Context comes after it.
+	sig, err := newSignature(m.Func.Type())
+	if err != nil {
+		return nil, fmt.Errorf("golemjs: cannot bind method %s of %s: %w", m.Name, t, err)
+	}
+	sig.params = sig.params[1:]
+	if len(sig.params) > 0 && sig.params[0] == contextType {
+		sig.context = true
+		sig.params = sig.params[1:]
+	}
+	return r.interp.NewBuiltin(m.Name, func(this interpreter.Object, args ...interpreter.Object) interpreter.Object {
+		receiver, ok := r.boundTo(this)
+		if !ok || receiver.Type().Elem() != t {
+			return &interpreter.Error{Message: fmt.Sprintf("TypeError: %s called on an object that isn't a %s", m.Name, t)}
+		}
+		return r.call(receiver.Method(m.Index), sig, args)
+	}), nil
+}
+
+// fieldsOf returns the fields of the struct type t that objects bound to
+// it have as properties.
+func fieldsOf(t reflect.Type) []boundField {
+	var fields []boundField
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || (f.Anonymous && indirect(f.Type).Kind() == reflect.Struct) {
+			continue
+		}
+		// The fields of a struct embedded by pointer are left out, since
+		// the pointer may be nil.
+		if throughPointer(t, f.Index) {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("js"); ok {
+			tag, _, _ = strings.Cut(tag, ",")
+			if tag == "-" {
+				continue
+			}
+			if tag != "" {
+				name = tag
+			}
+		}
+		fields = append(fields, boundField{name: name, index: f.Index, typ: f.Type})
+	}
+	return fields
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// throughPointer reports whether reaching the field at index in t goes
+// through an embedded pointer.
+func throughPointer(t reflect.Type, index []int) bool {
+	for _, idx := range index[:len(index)-1] {
+		t = t.Field(idx).Type
+		if t.Kind() == reflect.Pointer {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,271 @@
+package golemjs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+)
+
+// A script calls a Go function, or a method of a bound struct, through a
+// builtin that converts the arguments to the types of the parameters, and
+// the results back. Besides the arguments the script passes, the function
+// may take a context.Context first, which is given the context of the
+// script calling it, so that a slow call can stop when the script is
+// cancelled. Parameters can be variadic, and left out when they're
+// pointers, interfaces, slices, maps, functions or Optional.
+//
+// Errors go both ways. An error the function returns is thrown to the
+// script: an Exception it got calling back into JavaScript as it was, an
+// Error as the JavaScript error it names, and anything else as an Error
+// with the same message. When the exception reaches the Go code that ran
+// the script, errors.Is and errors.As find the original error in it. A
+// panic in the function is thrown to the script too, rather than crashing
+// the program.
+
+// FunctionCall holds the receiver and the arguments of a call to a Go
+// function of type func(FunctionCall) (Value, error), for functions that
+// want to take whatever arguments they're given, as they are.
+type FunctionCall struct {
+	This      Value
+	Arguments []Value
+}
+
+// Argument returns the n-th argument, or undefined if there are fewer.
+func (c FunctionCall) Argument(n int) Value {
+	if n < len(c.Arguments) {
+		return c.Arguments[n]
+	}
+	return Value{obj: interpreter.UNDEFINED, rt: c.This.rt}
+}
+
+// Optional is a parameter, or a struct field, that scripts may leave out.
+// Set reports whether they gave a value; undefined counts as leaving it
+// out, as it does for a JavaScript default parameter.
+//
+//	rt.Set("greet", func(name string, greeting golemjs.Optional[string]) string {
+//		if !greeting.Set {
+//			greeting.Value = "Hello"
+//		}
+//		return greeting.Value + ", " + name
+//	})
+type Optional[T any] struct {
+	Value T
+	Set   bool
+}
+
+// optionalValue is implemented by pointers to every kind of Optional, so
+// that reflection can fill them in.
+type optionalValue interface {
+	elem() reflect.Type
+	set(v reflect.Value)
+	get() (reflect.Value, bool)
+}
+
+func (o *Optional[T]) elem() reflect.Type { return reflect.TypeFor[T]() }
+
+func (o *Optional[T]) set(v reflect.Value) {
+	o.Value = v.Interface().(T)
+	o.Set = true
+}
+
+func (o *Optional[T]) get() (reflect.Value, bool) {
+	return reflect.ValueOf(&o.Value).Elem(), o.Set
+}
+
+// isOptional reports whether t is an Optional.
+func isOptional(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && reflect.PointerTo(t).Implements(optionalType)
+}
+
+// Error is an error for a Go function to return when a script should see
+// a particular JavaScript error thrown, such as a TypeError, rather than
+// an Error.
+type Error struct {
+	Name    string // the name of the error, such as "TypeError"
+	Message string
+}
+
+func (e *Error) Error() string {
+	return e.Name + ": " + e.Message
+}
+
+// NewTypeError returns an Error that scripts see as a TypeError, the error
+// for a value of the wrong type.
+func NewTypeError(format string, a ...any) error {
+	return &Error{Name: "TypeError", Message: fmt.Sprintf(format, a...)}
+}
+
+// NewRangeError returns an Error that scripts see as a RangeError, the
+// error for a value out of the range allowed.
+func NewRangeError(format string, a ...any) error {
+	return &Error{Name: "RangeError", Message: fmt.Sprintf(format, a...)}
+}
+
+var (
+	errorType    = reflect.TypeFor[error]()
+	contextType  = reflect.TypeFor[context.Context]()
+	rawFuncType  = reflect.TypeFor[func(FunctionCall) (Value, error)]()
+	optionalType = reflect.TypeFor[optionalValue]()
+)
+
+// signature describes the parameters and results of a Go function that
+// scripts can call.
+type signature struct {
+	context  bool           // whether it takes a context.Context first
+	params   []reflect.Type // the parameters the arguments go to
+	variadic reflect.Type   // the type of the variadic arguments, if any
+	results  funcResults
+}
+
+// newSignature checks that scripts can call a function of type t, and
+// describes it. A method's receiver isn't part of t.
+func newSignature(t reflect.Type) (*signature, error) {
+	results, ok := resultTypes(t)
+	if !ok {
+		return nil, fmt.Errorf("golemjs: cannot convert %s to a JavaScript value: it returns more than a value and an error", t)
+	}
+	sig := &signature{results: results}
+	for idx := range t.NumIn() {
+		sig.params = append(sig.params, t.In(idx))
+	}
+	if len(sig.params) > 0 && sig.params[0] == contextType {
+		sig.context = true
+		sig.params = sig.params[1:]
+	}
+	if t.IsVariadic() {
+		sig.variadic = sig.params[len(sig.params)-1].Elem()
+		sig.params = sig.params[:len(sig.params)-1]
+	}
+	return sig, nil
+}
+
+// funcResults says which of a function's results is the value it returns
+// and which the error, or -1 for none.
+type funcResults struct {
+	value, err int
+}
+
+// resultTypes reports which results of the function type t are what, if
+// t returns nothing, a value, an error, or a value and an error.
+func resultTypes(t reflect.Type) (funcResults, bool) {
+	switch {
+	case t.NumOut() == 0:
+		return funcResults{value: -1, err: -1}, true
+	case t.NumOut() == 1 && t.Out(0) == errorType:
+		return funcResults{value: -1, err: 0}, true
+	case t.NumOut() == 1:
+		return funcResults{value: 0, err: -1}, true
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+		return funcResults{value: 0, err: 1}, true
+	}
+	return funcResults{}, false
+}
+
+// function wraps the Go function fn as a JavaScript function called name.
+func (r *Runtime) function(name string, fn reflect.Value) (interpreter.Object, error) {
+	if fn.Type() == rawFuncType {
+		f := fn.Interface().(func(FunctionCall) (Value, error))
+		return r.interp.NewBuiltin(name, func(this interpreter.Object, args ...interpreter.Object) interpreter.Object {
+			call := FunctionCall{This: r.value(this), Arguments: make([]Value, len(args))}
+			for idx, arg := range args {
+				call.Arguments[idx] = r.value(arg)
+			}
+			return r.protect(func() interpreter.Object {
+				result, err := f(call)
+				if err != nil {
+					return r.throw(err)
+				}
+				return r.returned(reflect.ValueOf(result))
+			})
+		}), nil
+	}
+	sig, err := newSignature(fn.Type())
+	if err != nil {
+		return nil, err
+	}
+	return r.interp.NewBuiltin(name, func(this interpreter.Object, args ...interpreter.Object) interpreter.Object {
+		return r.call(fn, sig, args)
+	}), nil
+}
+
+// call calls fn, whose signature is sig, with the arguments a script
+// passed.
+func (r *Runtime) call(fn reflect.Value, sig *signature, args []interpreter.Object) interpreter.Object {
+	var in []reflect.Value
+	if sig.context {
+		in = append(in, reflect.ValueOf(r.interp.Context()))
+	}
+	for idx, t := range sig.params {
+		arg := interpreter.Object(interpreter.UNDEFINED)
+		if idx < len(args) {
+			arg = args[idx]
+		}
+		v, err := r.toGo(arg, t)
+		if err != nil {
+			return &interpreter.Error{Message: fmt.Sprintf("TypeError: argument %d: %s", idx+1, err)}
+		}
+		in = append(in, v)
+	}
+	if sig.variadic != nil {
+		for idx := len(sig.params); idx < len(args); idx++ {
+			v, err := r.toGo(args[idx], sig.variadic)
+			if err != nil {
+				return &interpreter.Error{Message: fmt.Sprintf("TypeError: argument %d: %s", idx+1, err)}
+			}
+			in = append(in, v)
+		}
+	}
+	return r.protect(func() interpreter.Object {
+		out := fn.Call(in)
+		if sig.results.err >= 0 && !out[sig.results.err].IsNil() {
+			return r.throw(out[sig.results.err].Interface().(error))
+		}
+		if sig.results.value < 0 {
+			return interpreter.UNDEFINED
+		}
+		return r.returned(out[sig.results.value])
+	})
+}
+
+// returned converts what a Go function returned for the script that called
+// it.
+func (r *Runtime) returned(v reflect.Value) interpreter.Object {
+	obj, err := toJS{rt: r, visiting: map[visit]bool{}}.convert(v)
+	if err != nil {
+		return r.throw(err)
+	}
+	return obj
+}
+
+// protect calls f, which calls a Go function, turning a panic into an
+// error thrown to the script.
+func (r *Runtime) protect(f func() interpreter.Object) (result interpreter.Object) {
+	defer func() {
+		if p := recover(); p != nil {
+			err, ok := p.(error)
+			if !ok {
+				err = fmt.Errorf("%v", p)
+			}
+			result = r.throw(err)
+		}
+	}()
+	return f()
+}
+
+// throw returns the error a script sees thrown for err. An Exception from
+// a script is thrown again as it was.
+func (r *Runtime) throw(err error) *interpreter.Error {
+	var exc *Exception
+	if errors.As(err, &exc) && exc.rt == r {
+		return exc.err
+	}
+	message := "Error: " + err.Error()
+	var jsErr *Error
+	if errors.As(err, &jsErr) {
+		message = jsErr.Error()
+	}
+	return &interpreter.Error{Message: message, Cause: err}
+}
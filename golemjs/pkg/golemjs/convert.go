@@ -0,0 +1,579 @@
+package golemjs
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"slices"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+)
+
+// Values cross between Go and JavaScript by reflection. Going into
+// JavaScript, a Go value is copied into the nearest JavaScript value: a
+// slice becomes an array, a map an object, and so on. Coming out, a
+// JavaScript value is copied into whatever Go type it's wanted as, and
+// conversions that would lose something, like 1.5 to an int, fail. Go
+// functions and structs are the exceptions. A script gets a function that
+// calls the Go function, converting its arguments from JavaScript and its
+// results back; see call.go. And it gets an object bound to the struct,
+// whose properties are its fields and whose methods are its methods; see
+// bind.go.
+
+var (
+	valueType  = reflect.TypeFor[Value]()
+	bigIntType = reflect.TypeFor[*big.Int]()
+)
+
+// ToValue converts x to a JavaScript value:
+//
+//   - nil, and nil pointers, slices, maps and functions, as null
+//   - a bool as a boolean, any integer or float as a number, a string as a
+//     string, and a *big.Int as a BigInt
+//   - a slice or an array as an array, and a map with string keys as an
+//     object with a property for each key
+//   - a pointer to a struct as an object bound to the struct, with a
+//     property for each exported field and a method for each exported
+//     method; a struct as an object bound to a copy of it; see below
+//   - an Optional as its value if it's set, and undefined if it isn't
+//   - any other pointer, or an interface, as the value it points to
+//   - a function as a JavaScript function calling it; see below
+//   - a Value as itself
+//
+// A function is called with the arguments it's given converted to the
+// types of its parameters, as ExportTo converts them, and a script calling
+// it with arguments that don't convert gets a TypeError. Arguments that
+// weren't passed are undefined, which converts only to a pointer, slice,
+// map, function, interface or Optional, as nil or unset. A variadic
+// function takes the arguments left over, and a function whose first
+// parameter is a context.Context is given the context of the script
+// calling it. The function may return nothing, a value, an error, or a
+// value and an error; a non-nil error is thrown to the script, as the
+// JavaScript error it names if it's an Error, and with "Error: " before
+// its message otherwise. A panic is thrown as an error too. A function of
+// type func(FunctionCall) (Value, error) is given its arguments as they
+// are.
+//
+// A struct's properties are its exported fields, named after them unless
+// a `js:"name"` tag names them; `js:"-"` leaves a field out. Reading one
+// converts the field as it is now, and assigning to one converts the value
+// assigned as ExportTo would, and sets the field. Its methods, those of
+// the pointer to it, are called as functions are. The same pointer always
+// converts to the same object, as long as the script holds on to it.
+func (r *Runtime) ToValue(x any) (Value, error) {
+	c := toJS{rt: r, visiting: map[visit]bool{}}
+	obj, err := c.convert(reflect.ValueOf(x))
+	if err != nil {
+		return Value{}, err
+	}
+	return r.value(obj), nil
+}
+
+// toObjects converts the arguments of a call made from Go.
+func (r *Runtime) toObjects(xs []any) ([]interpreter.Object, error) {
+	objs := make([]interpreter.Object, len(xs))
+	for idx, x := range xs {
+		v, err := r.ToValue(x)
+		if err != nil {
+			return nil, err
+		}
+		objs[idx] = v.object()
+	}
+	return objs, nil
+}
+
+// toJS converts Go values to JavaScript. It keeps track of the pointers
+// and maps it's in the middle of converting, so that a value that refers
+// to itself fails instead of recursing forever.
+type toJS struct {
+	rt       *Runtime
+	visiting map[visit]bool
+}
+
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func (c toJS) convert(v reflect.Value) (interpreter.Object, error) {
+	if !v.IsValid() {
+		return interpreter.NULL, nil
+	}
+	switch v.Type() {
+	case valueType:
+		val := v.Interface().(Value)
+		if val.rt != nil && val.rt != c.rt {
+			return nil, errors.New("golemjs: cannot use a value of another Runtime")
+		}
+		return val.object(), nil
+	case bigIntType:
+		if v.IsNil() {
+			return interpreter.NULL, nil
+		}
+		return &interpreter.BigInt{Value: new(big.Int).Set(v.Interface().(*big.Int))}, nil
+	}
+	if isOptional(v.Type()) {
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		value, set := ptr.Interface().(optionalValue).get()
+		if !set {
+			return interpreter.UNDEFINED, nil
+		}
+		return c.convert(value)
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return interpreter.TRUE, nil
+		}
+		return interpreter.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &interpreter.Number{Value: float64(v.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &interpreter.Number{Value: float64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &interpreter.Number{Value: v.Float()}, nil
+	case reflect.String:
+		return &interpreter.String{Value: v.String()}, nil
+	case reflect.Interface:
+		return c.convert(v.Elem())
+	case reflect.Pointer:
+		if v.IsNil() {
+			return interpreter.NULL, nil
+		}
+		if v.Elem().Kind() == reflect.Struct && !isOptional(v.Elem().Type()) {
+			return c.rt.bind(v)
+		}
+		return c.visit(v, func() (interpreter.Object, error) { return c.convert(v.Elem()) })
+	case reflect.Slice:
+		if v.IsNil() {
+			return interpreter.NULL, nil
+		}
+		return c.array(v)
+	case reflect.Array:
+		return c.array(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return interpreter.NULL, nil
+		}
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("golemjs: cannot convert %s to a JavaScript value: its keys aren't strings", v.Type())
+		}
+		return c.visit(v, func() (interpreter.Object, error) { return c.mapObject(v) })
+	case reflect.Struct:
+		ptr := reflect.New(v.Type())
+		ptr.Elem().Set(v)
+		return c.rt.bind(ptr)
+	case reflect.Func:
+		if v.IsNil() {
+			return interpreter.NULL, nil
+		}
+		return c.rt.function("", v)
+	}
+	return nil, fmt.Errorf("golemjs: cannot convert %s to a JavaScript value", v.Type())
+}
+
+// visit converts v, a pointer or a map, with f, unless it's already being
+// converted.
+func (c toJS) visit(v reflect.Value, f func() (interpreter.Object, error)) (interpreter.Object, error) {
+	key := visit{ptr: v.Pointer(), typ: v.Type()}
+	if c.visiting[key] {
+		return nil, fmt.Errorf("golemjs: cannot convert %s to a JavaScript value: it refers to itself", v.Type())
+	}
+	c.visiting[key] = true
+	defer delete(c.visiting, key)
+	return f()
+}
+
+func (c toJS) array(v reflect.Value) (interpreter.Object, error) {
+	elements := make([]interpreter.Object, v.Len())
+	for idx := range elements {
+		element, err := c.convert(v.Index(idx))
+		if err != nil {
+			return nil, err
+		}
+		elements[idx] = element
+	}
+	return c.rt.interp.NewArray(elements), nil
+}
+
+// mapObject converts a map to an object, whose properties are in the
+// order of the keys, since a map has none of its own.
+func (c toJS) mapObject(v reflect.Value) (interpreter.Object, error) {
+	keys := v.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		if a.String() < b.String() {
+			return -1
+		}
+		if a.String() > b.String() {
+			return 1
+		}
+		return 0
+	})
+	obj := c.rt.interp.NewObject()
+	for _, key := range keys {
+		value, err := c.convert(v.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+		obj.Set(&interpreter.String{Value: key.String()}, value)
+	}
+	return obj, nil
+}
+
+// ExportTo copies v into the Go value target points to, converting it to
+// the type of that value:
+//
+//   - to a bool from a boolean, to a string from a string, and to a
+//     *big.Int from a BigInt
+//   - to any integer type from a number that is an integer in its range,
+//     and to a float from any number
+//   - to a slice from an array, and to a Go array from an array of the
+//     same length, converting each element
+//   - to a map with string keys from an object, converting the value of
+//     each own property, and to a struct from an object, converting the
+//     properties named after its exported fields, as ToValue names them;
+//     fields without a property are left as they are
+//   - to a pointer to a struct from an object bound to the struct, as the
+//     same pointer, and to a struct from it as a copy of the struct
+//   - to a pointer from what it points to, and from null or undefined as
+//     nil; likewise nil for slices, maps, functions and interfaces
+//   - to an Optional from what its value converts from, as set, and from
+//     undefined as unset
+//   - to any, as Export returns it, and to Value as it is
+//   - to a function type from a function; see below
+//
+// The Go function calling a JavaScript function converts its arguments
+// as ToValue does, and the function's result to its result type, if it
+// has one. It may also return an error, the exception the function threw;
+// if it doesn't, it panics with the exception instead.
+func (r *Runtime) ExportTo(v Value, target any) error {
+	ptr := reflect.ValueOf(target)
+	if ptr.Kind() != reflect.Pointer || ptr.IsNil() {
+		return fmt.Errorf("golemjs: ExportTo needs a non-nil pointer, not %T", target)
+	}
+	converted, err := r.toGo(v.object(), ptr.Type().Elem())
+	if err != nil {
+		return fmt.Errorf("golemjs: %w", err)
+	}
+	ptr.Elem().Set(converted)
+	return nil
+}
+
+// export converts obj to the Go value Export returns. seen holds the
+// arrays and objects already exported, so that one appearing twice, or
+// inside itself, becomes the same slice or map.
+func (r *Runtime) export(obj interpreter.Object, seen map[interpreter.Object]any) any {
+	switch obj := obj.(type) {
+	case *interpreter.Undefined, *interpreter.Null:
+		return nil
+	case *interpreter.Boolean:
+		return obj.Value
+	case *interpreter.Number:
+		return obj.Value
+	case *interpreter.String:
+		return obj.Value
+	case *interpreter.BigInt:
+		return new(big.Int).Set(obj.Value)
+	case *interpreter.Array:
+		if s, ok := seen[obj]; ok {
+			return s
+		}
+		s := make([]any, obj.Len())
+		seen[obj] = s
+		for idx := range s {
+			if element := obj.At(idx); element != nil {
+				s[idx] = r.export(element, seen)
+			}
+		}
+		return s
+	case *interpreter.Hash:
+		if m, ok := seen[obj]; ok {
+			return m
+		}
+		m := map[string]any{}
+		seen[obj] = m
+		for _, key := range obj.OwnKeys() {
+			if s, ok := key.(*interpreter.String); ok {
+				value, _ := obj.GetOwn(key)
+				m[s.Value] = r.export(value, seen)
+			}
+		}
+		return m
+	case *interpreter.HostObject:
+		if h, ok := obj.Host.(*structHost); ok && h.rt == r {
+			return h.v.Addr().Interface()
+		}
+	case *interpreter.Function, *interpreter.Builtin:
+		fn := r.value(obj)
+		return func(args ...any) (any, error) {
+			result, err := r.Call(fn, Value{}, args...)
+			if err != nil {
+				return nil, err
+			}
+			return result.Export(), nil
+		}
+	}
+	return r.value(obj)
+}
+
+// typeName describes the type of obj in conversion errors.
+func typeName(obj interpreter.Object) string {
+	switch obj.(type) {
+	case *interpreter.Undefined:
+		return "undefined"
+	case *interpreter.Null:
+		return "null"
+	case *interpreter.Boolean:
+		return "boolean"
+	case *interpreter.Number:
+		return "number"
+	case *interpreter.String:
+		return "string"
+	case *interpreter.BigInt:
+		return "BigInt"
+	case *interpreter.Array:
+		return "array"
+	case *interpreter.Function, *interpreter.Builtin:
+		return "function"
+	}
+	return "object"
+}
+
+func isNullish(obj interpreter.Object) bool {
+	switch obj.(type) {
+	case *interpreter.Undefined, *interpreter.Null:
+		return true
+	}
+	return false
+}
+
+// toGo converts obj to the Go type t; see ExportTo.
+func (r *Runtime) toGo(obj interpreter.Object, t reflect.Type) (reflect.Value, error) {
+	mismatch := func() (reflect.Value, error) {
+		return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", typeName(obj), t)
+	}
+	if isOptional(t) {
+		ptr := reflect.New(t)
+		if _, ok := obj.(*interpreter.Undefined); ok {
+			return ptr.Elem(), nil
+		}
+		opt := ptr.Interface().(optionalValue)
+		value, err := r.toGo(obj, opt.elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		opt.set(value)
+		return ptr.Elem(), nil
+	}
+	switch t {
+	case valueType:
+		return reflect.ValueOf(r.value(obj)), nil
+	case bigIntType:
+		if isNullish(obj) {
+			return reflect.Zero(t), nil
+		}
+		if n, ok := obj.(*interpreter.BigInt); ok {
+			return reflect.ValueOf(new(big.Int).Set(n.Value)), nil
+		}
+		return mismatch()
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() > 0 {
+			if ptr, ok := r.boundTo(obj); ok && ptr.Type().Implements(t) {
+				return ptr.Convert(t), nil
+			}
+			if valueType.Implements(t) {
+				return reflect.ValueOf(r.value(obj)).Convert(t), nil
+			}
+			return mismatch()
+		}
+		x := r.export(obj, map[interpreter.Object]any{})
+		if x == nil {
+			return reflect.Zero(t), nil
+		}
+		return reflect.ValueOf(x).Convert(t), nil
+	case reflect.Pointer:
+		if isNullish(obj) {
+			return reflect.Zero(t), nil
+		}
+		if ptr, ok := r.boundTo(obj); ok && ptr.Type() == t {
+			return ptr, nil
+		}
+		elem, err := r.toGo(obj, t.Elem())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		ptr := reflect.New(t.Elem())
+		ptr.Elem().Set(elem)
+		return ptr, nil
+	case reflect.Bool:
+		if b, ok := obj.(*interpreter.Boolean); ok {
+			return reflect.ValueOf(b.Value).Convert(t), nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n, ok := obj.(*interpreter.Number); ok {
+			v := reflect.New(t).Elem()
+			if n.Value != math.Trunc(n.Value) || n.Value < math.MinInt64 || n.Value >= math.MaxInt64 ||
+				v.OverflowInt(int64(n.Value)) {
+				return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", n.Inspect(), t)
+			}
+			v.SetInt(int64(n.Value))
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if n, ok := obj.(*interpreter.Number); ok {
+			v := reflect.New(t).Elem()
+			if n.Value != math.Trunc(n.Value) || n.Value < 0 || n.Value >= math.MaxUint64 ||
+				v.OverflowUint(uint64(n.Value)) {
+				return reflect.Value{}, fmt.Errorf("cannot convert %s to %s", n.Inspect(), t)
+			}
+			v.SetUint(uint64(n.Value))
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if n, ok := obj.(*interpreter.Number); ok {
+			return reflect.ValueOf(n.Value).Convert(t), nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*interpreter.String); ok {
+			return reflect.ValueOf(s.Value).Convert(t), nil
+		}
+	case reflect.Slice:
+		if isNullish(obj) {
+			return reflect.Zero(t), nil
+		}
+		if arr, ok := obj.(*interpreter.Array); ok {
+			s := reflect.MakeSlice(t, arr.Len(), arr.Len())
+			return s, r.toGoElements(arr, s)
+		}
+	case reflect.Array:
+		if arr, ok := obj.(*interpreter.Array); ok {
+			if arr.Len() != t.Len() {
+				return reflect.Value{}, fmt.Errorf("cannot convert array of length %d to %s", arr.Len(), t)
+			}
+			a := reflect.New(t).Elem()
+			return a, r.toGoElements(arr, a)
+		}
+	case reflect.Map:
+		if isNullish(obj) {
+			return reflect.Zero(t), nil
+		}
+		if h, ok := obj.(*interpreter.Hash); ok && t.Key().Kind() == reflect.String {
+			return r.toGoMap(h, t)
+		}
+	case reflect.Struct:
+		if ptr, ok := r.boundTo(obj); ok && ptr.Type().Elem() == t {
+			return ptr.Elem(), nil
+		}
+		if h, ok := obj.(*interpreter.Hash); ok {
+			return r.toGoStruct(h, t)
+		}
+	case reflect.Func:
+		if isNullish(obj) {
+			return reflect.Zero(t), nil
+		}
+		if r.value(obj).IsFunction() {
+			return r.goFunc(obj, t)
+		}
+	}
+	return mismatch()
+}
+
+// toGoElements converts the elements of arr into the slice or array v.
+func (r *Runtime) toGoElements(arr *interpreter.Array, v reflect.Value) error {
+	for idx := range arr.Len() {
+		element := arr.At(idx)
+		if element == nil {
+			element = interpreter.UNDEFINED
+		}
+		converted, err := r.toGo(element, v.Type().Elem())
+		if err != nil {
+			return fmt.Errorf("element %d: %w", idx, err)
+		}
+		v.Index(idx).Set(converted)
+	}
+	return nil
+}
+
+func (r *Runtime) toGoMap(h *interpreter.Hash, t reflect.Type) (reflect.Value, error) {
+	m := reflect.MakeMap(t)
+	for _, key := range h.OwnKeys() {
+		s, ok := key.(*interpreter.String)
+		if !ok {
+			continue
+		}
+		value, _ := h.GetOwn(key)
+		converted, err := r.toGo(value, t.Elem())
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("property %s: %w", s.Value, err)
+		}
+		m.SetMapIndex(reflect.ValueOf(s.Value).Convert(t.Key()), converted)
+	}
+	return m, nil
+}
+
+func (r *Runtime) toGoStruct(h *interpreter.Hash, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	for _, field := range fieldsOf(t) {
+		value, ok := h.GetOwn(&interpreter.String{Value: field.name})
+		if !ok {
+			continue
+		}
+		converted, err := r.toGo(value, field.typ)
+		if err != nil {
+			return reflect.Value{}, fmt.Errorf("property %s: %w", field.name, err)
+		}
+		v.FieldByIndex(field.index).Set(converted)
+	}
+	return v, nil
+}
+
+// goFunc makes a Go function of type t that calls the JavaScript function
+// fn; see ExportTo.
+func (r *Runtime) goFunc(fn interpreter.Object, t reflect.Type) (reflect.Value, error) {
+	results, ok := resultTypes(t)
+	if !ok {
+		return reflect.Value{}, fmt.Errorf("cannot convert function to %s: it returns more than a value and an error", t)
+	}
+	f := r.value(fn)
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for idx := range out {
+			out[idx] = reflect.Zero(t.Out(idx))
+		}
+		fail := func(err error) []reflect.Value {
+			if results.err < 0 {
+				panic(err)
+			}
+			out[results.err] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+		if t.IsVariadic() {
+			last := in[len(in)-1]
+			in = in[:len(in)-1]
+			for idx := range last.Len() {
+				in = append(in, last.Index(idx))
+			}
+		}
+		args := make([]any, len(in))
+		for idx, arg := range in {
+			args[idx] = arg.Interface()
+		}
+		result, err := r.Call(f, Value{}, args...)
+		if err != nil {
+			return fail(err)
+		}
+		if results.value >= 0 {
+			converted, err := r.toGo(result.object(), t.Out(results.value))
+			if err != nil {
+				return fail(fmt.Errorf("golemjs: result: %w", err))
+			}
+			out[results.value] = converted
+		}
+		return out
+	}), nil
+}
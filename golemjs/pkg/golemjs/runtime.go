@@ -0,0 +1,255 @@
+// Package golemjs embeds the GolemJS JavaScript interpreter in Go programs.
+//
+// A Runtime runs scripts, and Go code exchanges values with them: it can
+// read and define global variables, call the functions scripts define, and
+// give scripts Go functions to call. Go values are converted to JavaScript
+// and back by reflection:
+//
+//	rt := golemjs.New(golemjs.WithOutput(os.Stdout, os.Stderr))
+//	rt.Set("greet", func(name string) string { return "Hello, " + name })
+//	rt.Set("config", map[string]any{"retries": 3})
+//	v, err := rt.RunString(`greet("world") + " x" + config.retries`)
+//	// v.String() == "Hello, world x3"
+//
+//	var add func(a, b int) (int, error)
+//	rt.RunString(`function add(a, b) { return a + b; }`)
+//	rt.ExportTo(rt.Get("add"), &add)
+//	sum, err := add(1, 2) // 3
+//
+// A Runtime isn't safe for concurrent use: scripts, and the Go functions
+// they call, run on the goroutine that called into the Runtime. Runtimes
+// share nothing, so separate goroutines can each use their own.
+package golemjs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"reflect"
+	"time"
+	"weak"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// Runtime is an instance of the interpreter, with its own global
+// variables and its own built-in objects.
+type Runtime struct {
+	interp *interpreter.Interpreter
+
+	bindings map[reflect.Type]*binding // how structs of each type are bound
+	objects  map[visit]weak.Pointer[interpreter.HostObject]
+	sweepAt  int // the size objects may grow to before dead entries are removed
+}
+
+// Option configures a Runtime when New creates it.
+type Option func(*Runtime)
+
+// Limits bounds what scripts may use; see WithLimits.
+type Limits = interpreter.Limits
+
+// Backend selects how scripts are run; see WithBackend.
+type Backend = interpreter.Backend
+
+// The backends: TreeWalker evaluates the AST directly, and BytecodeVM
+// compiles scripts to bytecode and runs that on a virtual machine.
+const (
+	TreeWalker = interpreter.TreeWalker
+	BytecodeVM = interpreter.BytecodeVM
+)
+
+// Clock is where a Runtime gets the time from; see WithClock.
+type Clock = interpreter.Clock
+
+// The causes of the exceptions raised when a limit is exceeded, for
+// errors.Is. An evaluation stopped by its context has the context's error
+// as the cause instead.
+var (
+	ErrStepLimit = interpreter.ErrStepLimit
+	ErrCallDepth = interpreter.ErrCallDepth
+	ErrHeapLimit = interpreter.ErrHeapLimit
+)
+
+// New creates a Runtime. Without options, console output goes to os.Stdout
+// and os.Stderr, and scripts run without limits but the default call
+// depth, on the tree-walker.
+func New(opts ...Option) *Runtime {
+	r := &Runtime{
+		interp:   interpreter.New(),
+		bindings: map[reflect.Type]*binding{},
+		objects:  map[visit]weak.Pointer[interpreter.HostObject]{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// WithOutput sends what scripts write with console.log and the like to
+// stdout, and what they write with console.error and console.warn to
+// stderr.
+func WithOutput(stdout, stderr io.Writer) Option {
+	return func(r *Runtime) { r.interp.SetOutput(stdout, stderr) }
+}
+
+// WithLimits bounds the steps, call depth and heap that scripts may use.
+func WithLimits(l Limits) Option {
+	return func(r *Runtime) { r.interp.SetLimits(l) }
+}
+
+// WithBackend selects how scripts are run.
+func WithBackend(b Backend) Option {
+	return func(r *Runtime) { r.interp.SetBackend(b) }
+}
+
+// WithClock makes Date and timers take the time from c instead of the
+// system clock.
+func WithClock(c Clock) Option {
+	return func(r *Runtime) { r.interp.SetClock(c) }
+}
+
+// WithTimeZone sets the time zone Date uses for local time.
+func WithTimeZone(loc *time.Location) Option {
+	return func(r *Runtime) { r.interp.SetTimeZone(loc) }
+}
+
+// WithRandomSeed makes Math.random return the same numbers every time.
+func WithRandomSeed(seed uint64) Option {
+	return func(r *Runtime) { r.interp.SetRandomSeed(seed) }
+}
+
+// WithModules lets scripts import ES modules from fsys, and RunModule run
+// them.
+func WithModules(fsys fs.FS) Option {
+	return func(r *Runtime) { r.interp.SetModuleLoader(interpreter.NewFSLoader(fsys)) }
+}
+
+// WithRequire defines the global require function, which loads CommonJS
+// modules from fsys.
+func WithRequire(fsys fs.FS) Option {
+	return func(r *Runtime) { r.interp.SetRequireFS(fsys) }
+}
+
+// Exception is the error a script threw, or that running it raised, such
+// as a TypeError or a limit being exceeded.
+type Exception struct {
+	err *interpreter.Error
+	rt  *Runtime
+}
+
+// Error returns the message of the exception, such as "TypeError: x is not
+// a function".
+func (e *Exception) Error() string {
+	return e.err.Message
+}
+
+// Value returns what the script threw.
+func (e *Exception) Value() Value {
+	return e.rt.value(e.rt.interp.Thrown(e.err))
+}
+
+// Unwrap returns the cause of an exception raised because a limit was
+// exceeded, or the context was done; see ErrStepLimit.
+func (e *Exception) Unwrap() error {
+	return e.err.Cause
+}
+
+// RunString runs the script src, and the work it queues, such as promise
+// callbacks and timers, and returns the value of its last statement.
+func (r *Runtime) RunString(src string) (Value, error) {
+	return r.RunStringContext(context.Background(), src)
+}
+
+// RunStringContext is RunString, stopping when ctx is done.
+func (r *Runtime) RunStringContext(ctx context.Context, src string) (Value, error) {
+	return r.run(ctx, src, "")
+}
+
+// RunFile runs the script in the file called name, as RunString does.
+func (r *Runtime) RunFile(name string) (Value, error) {
+	src, err := os.ReadFile(name)
+	if err != nil {
+		return Value{}, err
+	}
+	return r.run(context.Background(), string(src), name)
+}
+
+// RunModule imports the module specifier names from the file system given
+// to WithModules, and returns its namespace object, whose properties are
+// its exports.
+func (r *Runtime) RunModule(specifier string) (Value, error) {
+	return r.RunModuleContext(context.Background(), specifier)
+}
+
+// RunModuleContext is RunModule, stopping when ctx is done.
+func (r *Runtime) RunModuleContext(ctx context.Context, specifier string) (Value, error) {
+	return r.result(r.interp.ImportContext(ctx, specifier))
+}
+
+// run parses src, from the file called name, if any, and runs it.
+func (r *Runtime) run(ctx context.Context, src, name string) (Value, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		message := "SyntaxError: " + errs[0]
+		if name != "" {
+			message = fmt.Sprintf("%s (in %s)", message, name)
+		}
+		return Value{}, &Exception{err: &interpreter.Error{Message: message}, rt: r}
+	}
+	return r.result(r.interp.EvalContext(ctx, program))
+}
+
+// result turns what the interpreter returned into a Value, or an
+// Exception if it's an error.
+func (r *Runtime) result(obj interpreter.Object) (Value, error) {
+	if err, ok := obj.(*interpreter.Error); ok {
+		return Value{}, &Exception{err: err, rt: r}
+	}
+	return r.value(obj), nil
+}
+
+// Get returns the value of the global variable name, or undefined if
+// there is none.
+func (r *Runtime) Get(name string) Value {
+	obj, _ := r.interp.Global(name)
+	return r.value(obj)
+}
+
+// Set defines the global variable name with the value of x, converted as
+// ToValue converts it. A Go function is named name, as a script sees it.
+func (r *Runtime) Set(name string, x any) error {
+	v, err := r.ToValue(x)
+	if err != nil {
+		return err
+	}
+	if fn, ok := v.object().(*interpreter.Builtin); ok && fn.Name == "" {
+		fn.Name = name
+	}
+	r.interp.SetGlobal(name, v.object())
+	return nil
+}
+
+// Call calls the function fn with the arguments args, converted as
+// ToValue converts them, and returns its result. this is the receiver of
+// the call, or undefined for a call that isn't a method call.
+//
+// Called from a Go function that a script called, fn runs as part of the
+// script. Otherwise the work fn queues runs before Call returns, as it
+// does for RunString.
+func (r *Runtime) Call(fn Value, this Value, args ...any) (Value, error) {
+	return r.CallContext(context.Background(), fn, this, args...)
+}
+
+// CallContext is Call, stopping when ctx is done.
+func (r *Runtime) CallContext(ctx context.Context, fn Value, this Value, args ...any) (Value, error) {
+	values, err := r.toObjects(args)
+	if err != nil {
+		return Value{}, err
+	}
+	return r.result(r.interp.CallContext(ctx, fn.object(), this.obj, values))
+}
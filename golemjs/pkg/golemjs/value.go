@@ -0,0 +1,76 @@
+package golemjs
+
+import "github.com/biosbuddha/golemjs/internal/interpreter"
+
+// Value is a JavaScript value of a Runtime. The zero Value is undefined.
+//
+// A Value stays as it is in the script: an object or array it holds is
+// the one the script has, so changes either side makes are seen by the
+// other. Export copies it into Go values.
+type Value struct {
+	obj interpreter.Object
+	rt  *Runtime
+}
+
+// value wraps obj, which is undefined if nil.
+func (r *Runtime) value(obj interpreter.Object) Value {
+	if obj == nil {
+		obj = interpreter.UNDEFINED
+	}
+	return Value{obj: obj, rt: r}
+}
+
+// object returns the JavaScript value v holds.
+func (v Value) object() interpreter.Object {
+	if v.obj == nil {
+		return interpreter.UNDEFINED
+	}
+	return v.obj
+}
+
+// IsUndefined reports whether v is undefined.
+func (v Value) IsUndefined() bool {
+	_, ok := v.object().(*interpreter.Undefined)
+	return ok
+}
+
+// IsNull reports whether v is null.
+func (v Value) IsNull() bool {
+	_, ok := v.obj.(*interpreter.Null)
+	return ok
+}
+
+// IsFunction reports whether v is a function, which Call can call.
+func (v Value) IsFunction() bool {
+	switch v.obj.(type) {
+	case *interpreter.Function, *interpreter.Builtin:
+		return true
+	}
+	return false
+}
+
+// String returns v as the console shows it: a string as it is, and any
+// other value as it would be written in a script, like [1, 2] or
+// {a: 1}.
+func (v Value) String() string {
+	return v.object().Inspect()
+}
+
+// Export returns v as a Go value:
+//
+//   - undefined and null as nil
+//   - a boolean as a bool, a number as a float64, a string as a string,
+//     and a BigInt as a *big.Int
+//   - an array as a []any, and an object as a map[string]any of its own
+//     properties, whose elements and values are exported in turn
+//   - a function as a func(args ...any) (any, error), which calls it as
+//     Runtime.Call does and exports its result
+//
+// Any other value, such as a Map or a Promise, is returned as the Value
+// itself.
+func (v Value) Export() any {
+	if v.rt == nil {
+		return nil
+	}
+	return v.rt.export(v.object(), map[interpreter.Object]any{})
+}
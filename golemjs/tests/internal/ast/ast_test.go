@@ -44,9 +44,9 @@ func TestString(t *testing.T) {
 			node: &ast.FunctionDeclaration{
 				Token: ast.Token{Type: "FUNCTION", Literal: "function"},
 				Name:  &ast.Identifier{Token: ast.Token{Type: "IDENT", Literal: "add"}, Value: "add"},
-				Parameters: []*ast.Identifier{
-					{Token: ast.Token{Type: "IDENT", Literal: "a"}, Value: "a"},
-					{Token: ast.Token{Type: "IDENT", Literal: "b"}, Value: "b"},
+				Parameters: []ast.Expression{
+					&ast.Identifier{Token: ast.Token{Type: "IDENT", Literal: "a"}, Value: "a"},
+					&ast.Identifier{Token: ast.Token{Type: "IDENT", Literal: "b"}, Value: "b"},
 				},
 				Body: &ast.BlockStatement{
 					Token: ast.Token{Type: "LBRACE", Literal: "{"},
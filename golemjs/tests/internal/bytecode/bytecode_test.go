@@ -0,0 +1,92 @@
+package bytecode_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/bytecode"
+)
+
+func TestMake(t *testing.T) {
+	tests := []struct {
+		op       bytecode.Opcode
+		operands []int
+		expected []byte
+	}{
+		{bytecode.OpConstant, []int{65534}, []byte{byte(bytecode.OpConstant), 255, 254}},
+		{bytecode.OpAdd, nil, []byte{byte(bytecode.OpAdd)}},
+		{bytecode.OpGetFree, []int{255}, []byte{byte(bytecode.OpGetFree), 255}},
+		{bytecode.OpClosure, []int{65534, 255}, []byte{byte(bytecode.OpClosure), 255, 254, 255}},
+		{bytecode.OpDefineGlobal, []int{1, 2}, []byte{byte(bytecode.OpDefineGlobal), 0, 1, 2}},
+	}
+
+	for _, tt := range tests {
+		instruction := bytecode.Make(tt.op, tt.operands...)
+		if !bytes.Equal(instruction, tt.expected) {
+			t.Errorf("Make(%d, %v): expected %v, got %v", tt.op, tt.operands, tt.expected, instruction)
+		}
+	}
+}
+
+func TestReadOperands(t *testing.T) {
+	tests := []struct {
+		op        bytecode.Opcode
+		operands  []int
+		bytesRead int
+	}{
+		{bytecode.OpConstant, []int{65535}, 2},
+		{bytecode.OpGetFree, []int{255}, 1},
+		{bytecode.OpClosure, []int{65535, 255}, 3},
+		{bytecode.OpPop, []int{}, 0},
+	}
+
+	for _, tt := range tests {
+		instruction := bytecode.Make(tt.op, tt.operands...)
+		def, err := bytecode.Lookup(tt.op)
+		if err != nil {
+			t.Fatalf("definition not found: %s", err)
+		}
+		operands, n := bytecode.ReadOperands(def, instruction[1:])
+		if n != tt.bytesRead {
+			t.Errorf("%s: expected %d bytes read, got %d", def.Name, tt.bytesRead, n)
+		}
+		for idx, want := range tt.operands {
+			if operands[idx] != want {
+				t.Errorf("%s: operand %d: expected %d, got %d", def.Name, idx, want, operands[idx])
+			}
+		}
+	}
+}
+
+func TestInstructionsString(t *testing.T) {
+	instructions := []bytecode.Instructions{
+		bytecode.Make(bytecode.OpGetLocal, 1),
+		bytecode.Make(bytecode.OpConstant, 2),
+		bytecode.Make(bytecode.OpAdd),
+		bytecode.Make(bytecode.OpClosure, 65535, 255),
+		bytecode.Make(bytecode.OpReturn),
+	}
+	expected := `0000 OpGetLocal 1
+0003 OpConstant 2
+0006 OpAdd
+0007 OpClosure 65535 255
+0011 OpReturn
+`
+	concatted := bytecode.Instructions{}
+	for _, ins := range instructions {
+		concatted = append(concatted, ins...)
+	}
+	if concatted.String() != expected {
+		t.Errorf("instructions wrongly formatted.\nwant=%q\ngot=%q", expected, concatted.String())
+	}
+
+	noted := concatted.Format(func(op bytecode.Opcode, operands []int) string {
+		if op == bytecode.OpGetLocal {
+			return "x"
+		}
+		return ""
+	})
+	if want := "0000 OpGetLocal 1 ; x\n"; noted[:len(want)] != want {
+		t.Errorf("expected the note on the first line, got %q", noted)
+	}
+}
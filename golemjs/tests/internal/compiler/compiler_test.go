@@ -0,0 +1,250 @@
+package compiler_test
+
+import (
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/compiler"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func compile(t *testing.T, input string) *compiler.CompiledFunction {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	return compiler.Compile(program)
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Top-level variables are globals, looked up by name, and each
+		// statement's value goes into the completion register.
+		{`let x = 1; x + 2`, `== <program> ==
+0000 OpConstant 0 ; 1
+0003 OpDefineGlobal 1 1 ; "x"
+0007 OpClearCompletion
+0008 OpGetGlobal 1 ; "x"
+0011 OpConstant 2 ; 2
+0014 OpAdd
+0015 OpSetCompletion
+0016 OpReturnCompletion
+`},
+
+		// Parameters are in the first slots.
+		{`function add(a, b) { return a + b; }`, `== <program> ==
+0000 OpClosure 0 0 ; add
+0004 OpDefineGlobal 1 0 ; "add"
+0008 OpClearCompletion
+0009 OpReturnCompletion
+
+== add ==
+0000 OpGetLocal 0 ; a
+0003 OpGetLocal 1 ; b
+0006 OpAdd
+0007 OpReturn
+0008 OpReturnUndefined
+`},
+
+		// A variable a closure captures is kept in a cell.
+		{`function counter() { let n = 0; return () => n++; }`, `== <program> ==
+0000 OpClosure 0 0 ; counter
+0004 OpDefineGlobal 1 0 ; "counter"
+0008 OpClearCompletion
+0009 OpReturnCompletion
+
+== counter ==
+0000 OpNewCell 1 ; n
+0003 OpConstant 0 ; 0
+0006 OpSetCell 1 ; n
+0009 OpPop
+0010 OpLoadCell 1 ; n
+0013 OpClosure 1 1 ; <anonymous>
+0017 OpReturn
+0018 OpReturnUndefined
+
+== <anonymous> ==
+0000 OpGetFree 0 ; n
+0002 OpUpdate 0
+0004 OpSetFree 0 ; n
+0006 OpPop
+0007 OpReturn
+0008 OpReturnUndefined
+`},
+
+		// Each property access by name has an inline cache of its own.
+		{`function move(p) { p.x = p.x + p.dx; return p.norm(); }`, `== <program> ==
+0000 OpClosure 0 0 ; move
+0004 OpDefineGlobal 1 0 ; "move"
+0008 OpClearCompletion
+0009 OpReturnCompletion
+
+== move ==
+0000 OpGetLocal 0 ; p
+0003 OpGetLocal 0 ; p
+0006 OpGetNamed 0 0 ; "x"
+0011 OpGetLocal 0 ; p
+0014 OpGetNamed 1 1 ; "dx"
+0019 OpAdd
+0020 OpSetNamed 0 2 ; "x"
+0025 OpPop
+0026 OpGetLocal 0 ; p
+0029 OpGetMethod 2 3 ; "norm"
+0034 OpCheckCallable 3 ; "p.norm"
+0037 OpCall 0
+0039 OpReturn
+0040 OpReturnUndefined
+`},
+
+		// Variables declared in blocks at the top level are locals.
+		{`for (let i = 0; i < 2; i++) { i; }`, `== <program> ==
+0000 OpConstant 0 ; 0
+0003 OpSetLocal 0 ; i
+0006 OpPop
+0007 OpGetLocal 0 ; i
+0010 OpConstant 1 ; 2
+0013 OpLess
+0014 OpJumpIfFalse 34
+0017 OpGetLocal 0 ; i
+0020 OpSetCompletion
+0021 OpGetLocal 0 ; i
+0024 OpUpdate 0
+0026 OpSetLocal 0 ; i
+0029 OpPop
+0030 OpPop
+0031 OpJump 7
+0034 OpClearCompletion
+0035 OpReturnCompletion
+`},
+
+		// A compound assignment reads the property, leaving the object
+		// and key below it for the store, before the right-hand side.
+		{`let o = {n: 1}; o.n *= 2`, `== <program> ==
+0000 OpObject
+0001 OpConstant 0 ; "n"
+0004 OpConstant 1 ; 1
+0007 OpDefineProperty
+0008 OpDefineGlobal 2 1 ; "o"
+0012 OpClearCompletion
+0013 OpGetGlobal 2 ; "o"
+0016 OpConstant 0 ; "n"
+0019 OpPeekProperty
+0020 OpConstant 3 ; 2
+0023 OpMul
+0024 OpSetProperty
+0025 OpSetCompletion
+0026 OpReturnCompletion
+`},
+
+		// delete removes properties; a variable the compiler knows of
+		// can't be deleted, and a global may not exist.
+		{`function f(o, k) { return [delete o[k], delete o.x, delete k, delete g, delete 1]; }`, `== <program> ==
+0000 OpClosure 0 0 ; f
+0004 OpDefineGlobal 1 0 ; "f"
+0008 OpClearCompletion
+0009 OpReturnCompletion
+
+== f ==
+0000 OpGetLocal 0 ; o
+0003 OpGetLocal 1 ; k
+0006 OpDeleteProperty
+0007 OpGetLocal 0 ; o
+0010 OpConstant 0 ; "x"
+0013 OpDeleteProperty
+0014 OpFalse
+0015 OpDeleteGlobal 1 ; "g"
+0018 OpConstant 2 ; 1
+0021 OpPop
+0022 OpTrue
+0023 OpArray 5
+0026 OpReturn
+0027 OpReturnUndefined
+`},
+
+		{`import("./lib.js")`, `== <program> ==
+0000 OpConstant 0 ; "./lib.js"
+0003 OpImport
+0004 OpSetCompletion
+0005 OpReturnCompletion
+`},
+
+		// A statement the compiler can't translate is left to the
+		// tree-walker.
+		{`const [a, b] = [1, 2]; a`, `== <program> ==
+0000 OpEvalNode 0 ; const [a, b] = [1, 2];
+0003 OpGetGlobal 1 ; "a"
+0006 OpSetCompletion
+0007 OpReturnCompletion
+`},
+		{`function* gen() { yield 1; }`, `== <program> ==
+0000 OpHoistNode 0 ; function* gen() {
+  yield 1
+}
+0003 OpClearCompletion
+0004 OpReturnCompletion
+`},
+	}
+
+	for _, tt := range tests {
+		if got := compile(t, tt.input).Disassemble(); got != tt.expected {
+			t.Errorf("input %q:\nwant:\n%s\ngot:\n%s", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestCompiledFunction(t *testing.T) {
+	main := compile(t, `const f = function fact(n, ...rest) { return this; }`)
+	var fn *compiler.CompiledFunction
+	for _, constant := range main.Constants {
+		if c, ok := constant.(*compiler.CompiledFunction); ok {
+			fn = c
+		}
+	}
+	if fn == nil {
+		t.Fatal("expected a compiled function among the constants")
+	}
+	if fn.Name != "fact" || fn.NumParams != 2 || !fn.Rest {
+		t.Errorf("expected fact with 2 parameters and a rest parameter, got %s with %d (rest %t)", fn.Name, fn.NumParams, fn.Rest)
+	}
+	expected := []string{"n", "rest", "this", "fact"}
+	if len(fn.LocalNames) != len(expected) {
+		t.Fatalf("expected locals %v, got %v", expected, fn.LocalNames)
+	}
+	for idx, name := range expected {
+		if fn.LocalNames[idx] != name {
+			t.Errorf("expected locals %v, got %v", expected, fn.LocalNames)
+		}
+	}
+	if fn.ThisSlot != 2 || fn.SelfSlot != 3 {
+		t.Errorf("expected this in slot 2 and fact in slot 3, got %d and %d", fn.ThisSlot, fn.SelfSlot)
+	}
+}
+
+func TestTreeWalked(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = 1; function f() { return x; } f()`, nil},
+		{`let [a] = [1]; a + 1; function* g() {}`, []string{"function* g() {\n}", "let [a] = [1];"}},
+		{`for (const { n } of []) {}`, []string{"for (const {n: n} of []) {\n}"}},
+	}
+
+	for _, tt := range tests {
+		nodes := compile(t, tt.input).TreeWalked()
+		if len(nodes) != len(tt.expected) {
+			t.Errorf("input %q: expected %d statements left to the tree-walker, got %d", tt.input, len(tt.expected), len(nodes))
+			continue
+		}
+		for idx, node := range nodes {
+			if node.String() != tt.expected[idx] {
+				t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected[idx], node.String())
+			}
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package interpreter_test
+
+import "testing"
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2 * 2, 3 + 3]", "[1, 4, 6]"},
+		{"[1, , 3]", "[1, , 3]"},
+		{"let a = [2, 3]; [1, ...a, ...[4], ...\"ab\"]", "[1, 2, 3, 4, a, b]"},
+		{"[1, , 3].length", "3"},
+		{"[1, , 3][1]", "undefined"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayIndexAndLength(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; a.length = 1; a", "[1]"},
+		{"let a = [1]; a.length = 3; a", "[1, , ]"},
+		{"let a = []; a[2] = 5; a.length", "3"},
+		{`let a = [1, 2]; a["1"]`, "2"},
+		{`let a = [1, 2]; a["01"] = 9; a.length`, "2"},
+		{"let a = [1, 2]; a[-1] = 9; a.length", "2"},
+		{"let a = [1, 2]; a[-1] = 9; a[-1]", "9"},
+		{"let a = [1, 2]; a.name = 3; a.name + a.length", "5"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "let a = []; a.length = -1;"), "RangeError: Invalid array length")
+	testErrorObject(t, testEval(t, "new Array(-1)"), "RangeError: Invalid array length")
+}
+
+func TestArrayPrototypeMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2, 3].map(x => x * 2)", "[2, 4, 6]"},
+		{"[1, , 3].map(x => x * 2)", "[2, , 6]"},
+		{"[1, 2, 3, 4].filter(x => x % 2 == 0)", "[2, 4]"},
+		{"[1, 2, 3].reduce((acc, x) => acc + x)", "6"},
+		{"[1, 2, 3].reduce((acc, x) => acc + x, 10)", "16"},
+		{`["a", "b"].reduceRight((acc, x) => acc + x)`, "ba"},
+		{"let sum = 0; [1, 2, 3].forEach(x => { sum += x; }); sum", "6"},
+		{"[5, 12, 8].find(x => x > 6)", "12"},
+		{"[5, 12, 8].findIndex(x => x > 100)", "-1"},
+		{"[5, 12, 8].findLast(x => x > 6)", "8"},
+		{"[1, 2].some(x => x > 1)", "true"},
+		{"[1, 2].every(x => x > 1)", "false"},
+		{"[1, 2, 1].indexOf(1, 1)", "2"},
+		{"[1, 2, 1].lastIndexOf(1)", "2"},
+		{"[1, , 3].includes(undefined)", "true"},
+		{"[1, , 3].indexOf(undefined)", "-1"},
+		{"[1, 2, 3, 4].slice(1, -1)", "[2, 3]"},
+		{"let a = [1, 2, 3, 4]; let removed = a.splice(1, 2, 9); [removed, a]", "[[2, 3], [1, 9, 4]]"},
+		{"[10, 9, 1].sort()", "[1, 10, 9]"},
+		{"[3, , 1, 2].sort((a, b) => a - b)", "[1, 2, 3, ]"},
+		{"[1, [2, [3, [4]]]].flat()", "[1, 2, [3, [4]]]"},
+		{"[1, [2, [3, [4]]]].flat(10)", "[1, 2, 3, 4]"},
+		{"[1, 2].flatMap(x => [x, x * 10])", "[1, 10, 2, 20]"},
+		{`[1, null, undefined, "a"].join("-")`, "1---a"},
+		{"[1, 2, 3].reverse()", "[3, 2, 1]"},
+		{"[1, 2, 3].at(-1)", "3"},
+		{"let a = [1]; a.push(2, 3); a", "[1, 2, 3]"},
+		{"let a = [1]; a.push(2)", "2"},
+		{"let a = [1, 2]; a.unshift(0); [a.shift(), a.pop(), a]", "[0, 2, [1]]"},
+		{"[1, 2].concat([3], 4)", "[1, 2, 3, 4]"},
+		{"[1, 2, 3].fill(0, 1)", "[1, 0, 0]"},
+		{"[1, [2, 3]].toString()", "1,2,3"},
+		{"[3, undefined, 1].sort((a, b) => a - b)", "[1, 3, undefined]"},
+		{"[1, 2, 3, 4, 5].copyWithin(0, 3)", "[4, 5, 3, 4, 5]"},
+		{"[1, 2, 3, 4, 5].copyWithin(1, 0, 3)", "[1, 1, 2, 3, 5]"},
+		{"[1, 2, 3, 4, 5].copyWithin(-2, -3, -1)", "[1, 2, 3, 3, 4]"},
+		{"[1, , 3].copyWithin(2, 1)", "[1, , ]"},
+		{"[1234.5, null, [1e6, 0.12345]].toLocaleString()", "1,234.5,,1,000,000,0.123"},
+		{"let a = [3, 1, 2]; [a.toReversed(), a]", "[[2, 1, 3], [3, 1, 2]]"},
+		{"let a = [3, 1, 2]; [a.toSorted(), a.toSorted((x, y) => y - x), a]", "[[1, 2, 3], [3, 2, 1], [3, 1, 2]]"},
+		{"let a = [1, 2, 3]; [a.toSpliced(1, 1, 'a', 'b'), a.toSpliced(1), a]", "[[1, a, b, 3], [1], [1, 2, 3]]"},
+		{"let a = [1, 2, 3]; [a.with(0, 9), a.with(-1, 9), a]", "[[9, 2, 3], [1, 2, 9], [1, 2, 3]]"},
+		{"[1, , 3].toReversed().includes(undefined) && [, 'b', 'a'].toSorted().length", "3"},
+		{"[, 'z', undefined, 'a'].toSorted()", "[a, z, undefined, undefined]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSparseArrays(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"new Array(4294967295).length", "4294967295"},
+		{"let a = []; a.length = 4e9; a.length", "4000000000"},
+		{"let a = []; a[4294967294] = 1; [a.length, a[4294967294], a[0]]", "[4294967295, 1, undefined]"},
+		{"let a = new Array(1e9); a[5] = 1; a.push(2); [a.length, a.indexOf(2), a.lastIndexOf(1)]", "[1000000001, 1000000000, 5]"},
+		{"let a = new Array(1e9); a[3] = 'x'; a.join('').length + a.includes(undefined)", "2"},
+		{"let a = new Array(1e9); a[7] = 1; a.length = 5; a.length = 1e9; a[7]", "undefined"},
+		{"let a = new Array(1e8); a[1] = 3; a[9] = 1; a.sort(); [a[0], a[1], a[2], a.length]", "[1, 3, undefined, 100000000]"},
+		{"let a = new Array(1e8); a[9e7] = 'z'; let r = a.splice(8e7, 2e7, 'a'); [r.length, r[1e7], a.length, a[8e7]]", "[20000000, z, 80000001, a]"},
+		{"let a = new Array(1e8); a[0] = 1; a.reverse(); [a[0], a[99999999]]", "[undefined, 1]"},
+		{"let a = new Array(1e8); a[1] = 1; [a.shift(), a[0], a.unshift(2), a[1]]", "[undefined, 1, 100000000, 1]"},
+		{"let a = new Array(1e8); a[2] = 4; a.map(x => x * 2).slice(0, 3)", "[, , 8]"},
+		{"let a = new Array(1e8); a[2] = 4; a.filter(x => true).concat(a).length", "100000001"},
+		{"let a = new Array(1e8); a[2] = 4; [a].flat()", "[4]"},
+		{"let a = []; for (let k = 0; k < 8; k++) { a[k * 1000] = k; } a.reduce((s, x) => s + x)", "28"},
+		{"let a = new Array(1e8); a[5] = 1; a[99999990] = 2; a.copyWithin(0, 99999990); [a[0], a[5], a[99999990], a.length]", "[2, undefined, 2, 100000000]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayIterators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let it = [7, 8].values(); [it.next().value, it.next().value, it.next().done]", "[7, 8, true]"},
+		{"let it = [7, 8].keys(); it.next(); it.next().value", "1"},
+		{"let it = [7, 8].entries(); it.next().value", "[0, 7]"},
+		{"Array.from([1, 2].entries())", "[[0, 1], [1, 2]]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayConstructor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"new Array(3).length", "3"},
+		{"Array(1, 2)", "[1, 2]"},
+		{"Array.of(3)", "[3]"},
+		{`Array.from("abc")`, "[a, b, c]"},
+		{"Array.from([1, 2], x => x * 3)", "[3, 6]"},
+		{"Array.from({ length: 2, 0: 'a' })", "[a, undefined]"},
+		{"Array.isArray([]) && !Array.isArray({})", "true"},
+		{"[].constructor === Array", "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"[].reduce((a, b) => a)", "TypeError: Reduce of empty array with no initial value"},
+		{"[1].map(5)", "TypeError: 5 is not a function"},
+		{"[1].sort(5)", "TypeError: The comparison function must be either a function or undefined"},
+		{"[1].toSorted(5)", "TypeError: The comparison function must be either a function or undefined"},
+		{"[1].with(1, 0)", "RangeError: Invalid index : 1"},
+		{"[1].with(-2, 0)", "RangeError: Invalid index : -2"},
+		{"[{ toLocaleString: 1 }].toLocaleString()", "TypeError: 1 is not a function"},
+		{"[1].map(x => y)", "ReferenceError: y is not defined"},
+		{"let a = []; for (let k = 0; k < 1e5; k++) { a = [a]; } a.flat(Infinity)", "RangeError: Maximum call stack size exceeded"},
+		{"let a = [1]; a.push(a); a.flat(Infinity)", "RangeError: Maximum call stack size exceeded"},
+		{"let a = []; a[4294967294] = 1; a.push(2)", "TypeError: Pushing 1 elements on an array-like of length 4294967295 is disallowed, as the total surpasses 2**32-1"},
+		{"let a = []; a[4294967294] = 1; a.concat([1])", "RangeError: Invalid array length"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
@@ -0,0 +1,194 @@
+package interpreter_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestAsyncFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`async function f() { return 1; } f().then(v => console.log(v))`, "1\n"},
+		{`async function f(x) { return await x * 2; } f(Promise.resolve(21)).then(v => console.log(v))`, "42\n"},
+		{`(async () => { console.log(await 1, await Promise.resolve(2)); })()`, "1 2\n"},
+		{`let o = { v: 5, async m() { return this.v; } }; o.m().then(v => console.log(v))`, "5\n"},
+		{`const add = async (a, b = 2) => a + b; add(1).then(v => console.log(v))`, "3\n"},
+		{`async function f() { return Promise.resolve("followed"); } f().then(v => console.log(v))`, "followed\n"},
+		{`async function f() { null.x; } f().catch(e => console.log(e))`, "TypeError: Cannot read properties of null (reading 'x')\n"},
+		{`async function f() { await Promise.reject("bad"); console.log("not reached"); } f().catch(r => console.log("rejected", r))`, "rejected bad\n"},
+		{`async function f({ a }) {} f().catch(e => console.log("rejected"))`, "rejected\n"},
+		{`async function f() {} console.log(f(), f)`, "Promise { undefined } [AsyncFunction: f]\n"},
+		{`console.log(async () => {}, async function* g() {})`, "[AsyncFunction (anonymous)] [AsyncGeneratorFunction: g]\n"},
+		{`function async(x) { return x; } let o = { async: 1 }; console.log(async(2), o.async)`, "2 1\n"},
+		{`let sum = 0; async function add(p) { sum += await p; } Promise.all([add(1), add(2)]).then(() => console.log(sum))`, "2\n"},
+
+		// The body runs straight away up to the first await, and resumes
+		// in a microtask once the awaited promise settles.
+		{`
+async function a1() { console.log("a1 start"); await a2(); console.log("a1 end"); }
+async function a2() { console.log("a2"); }
+console.log("script start");
+a1();
+new Promise(r => { console.log("p1"); r(); }).then(() => console.log("p2"));
+console.log("script end");
+`, "script start\na1 start\na2\np1\nscript end\na1 end\np2\n"},
+
+		{`
+async function total(ids) {
+  let sum = 0;
+  for (const id of ids) sum += await Promise.resolve(id * 10);
+  return sum;
+}
+total([1, 2, 3]).then(v => console.log(v));
+`, "60\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestAsyncFunctionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`new (async function f() {})`, "TypeError: f is not a constructor"},
+		{`new (async function* g() {})`, "TypeError: g is not a constructor"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+
+	_, stderr := testConsole(t, `async function f() { missing; } f()`)
+	if expected := "Uncaught (in promise) ReferenceError: missing is not defined\n"; stderr != expected {
+		t.Errorf("expected %q on stderr, got %q", expected, stderr)
+	}
+}
+
+func TestAsyncGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`
+async function* g() { yield 1; yield await Promise.resolve(2); return 3; }
+const it = g();
+(async () => { console.log(await it.next(), await it.next(), await it.next(), await it.next()); })();
+`, "{ value: 1, done: false } { value: 2, done: false } { value: 3, done: true } { value: undefined, done: true }\n"},
+		// Yielding a promise yields the value it's fulfilled with.
+		{`async function* g() { yield Promise.resolve("v"); } g().next().then(r => console.log(r.value))`, "v\n"},
+		// Requests made before earlier ones finish wait their turn.
+		{`
+async function* g() { yield 1; yield 2; }
+const it = g();
+Promise.all([it.next(), it.next(), it.next()]).then(rs => console.log(rs.map(r => r.value)));
+`, "[ 1, 2, undefined ]\n"},
+		{`async function* g() { yield 1; console.log("not reached"); } const it = g(); it.next(); it.return("r").then(r => console.log(r))`, "{ value: 'r', done: true }\n"},
+		{`async function* g() { yield 1; } const it = g(); it.next(); it.throw("t").catch(r => console.log("rejected", r))`, "rejected t\n"},
+		{`async function* g() {} g().return(Promise.resolve(1)).then(r => console.log(r))`, "{ value: 1, done: true }\n"},
+		{`async function* g() { yield* [1, Promise.resolve(2)]; } (async () => { for await (const x of g()) console.log(x); })()`, "1\n2\n"},
+		{`
+async function* inner() { yield "a"; return "done"; }
+async function* outer() { const r = yield* inner(); yield r; }
+(async () => { for await (const x of outer()) console.log(x); })();
+`, "a\ndone\n"},
+		{`async function* g() {} console.log(g(), String(g()))`, "Object [AsyncGenerator] {} [object AsyncGenerator]\n"},
+		{`let next = (async function* () {})().next; next().catch(e => console.log(e))`, "TypeError: next method called on incompatible receiver null\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestForAwaitOf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`(async () => { for await (const x of [Promise.resolve(1), 2]) console.log(x); })()`, "1\n2\n"},
+		{`
+const source = {
+  [Symbol.asyncIterator]() {
+    let n = 0;
+    return {
+      next() { n++; return Promise.resolve({ value: n, done: n > 2 }); },
+      return() { console.log("closed"); return Promise.resolve({ done: true }); },
+    };
+  },
+};
+(async () => {
+  for await (const x of source) console.log(x);
+  for await (const x of source) { console.log("first", x); break; }
+})();
+`, "1\n2\nfirst 1\nclosed\n"},
+		{`(async () => { for await (const x of 5) {} })().catch(e => console.log(e))`, "TypeError: 5 is not async iterable\n"},
+		{`let s = { [Symbol.asyncIterator]() { return { next() { return 1; } }; } }; (async () => { for await (const x of s) {} })().catch(e => console.log(e))`, "TypeError: Iterator result 1 is not an object\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestTopLevelAwait(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let x = await Promise.resolve(5); x * await new Promise(r => queueMicrotask(() => r(2)))`, "10"},
+		{`let sum = 0; for await (const x of [1, Promise.resolve(2)]) sum += x; sum`, "3"},
+		// A module that is still waiting once the event loop is idle has no
+		// result yet.
+		{`await new Promise(() => {}); 1`, "undefined"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testModule(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testModule(t, `await Promise.reject("nope")`), "nope")
+}
+
+func TestAbandonedAsyncFunctionsStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	testEval(t, `for (let i = 0; i < 50; i++) { (async () => { await new Promise(() => {}); })(); }`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("paused async functions still running: %d goroutines, want %d", runtime.NumGoroutine(), before)
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// testModule parses input as a module and evaluates it.
+func testModule(t *testing.T, input string) interpreter.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseModule()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	return newInterpreter().Eval(program)
+}
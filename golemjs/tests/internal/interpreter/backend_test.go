@@ -0,0 +1,167 @@
+package interpreter_test
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/compiler"
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// backend is the backend the tests are running against. TestMain runs
+// every test once with each backend, so both have to pass the same tests.
+//
+// The VM leaves top-level statements it can't compile to the tree-walker,
+// so passing a test with it only shows the VM works if none were. Outside
+// the tests listed in treeWalked, checkCompiled makes sure of that.
+var backend interpreter.Backend
+
+// treeWalked lists the tests of constructs the compiler has no bytecode
+// for, which the VM leaves to the tree-walker. Their run with the VM
+// tests how the two backends work together, not the VM itself.
+var treeWalked = map[string]bool{
+	// generators and async functions
+	"TestGenerators":                  true,
+	"TestGeneratorErrors":             true,
+	"TestGeneratorReturnAndThrow":     true,
+	"TestYieldDelegation":             true,
+	"TestAbandonedGeneratorsStop":     true,
+	"TestAsyncFunctions":              true,
+	"TestAsyncFunctionErrors":         true,
+	"TestAsyncGenerators":             true,
+	"TestForAwaitOf":                  true,
+	"TestAbandonedAsyncFunctionsStop": true,
+
+	// destructuring, object spread, and for...of assigning to a property
+	"TestArrayDestructuring":      true,
+	"TestObjectDestructuring":     true,
+	"TestDestructuringAssignment": true,
+	"TestDestructuringErrors":     true,
+	"TestParameterPatterns":       true,
+	"TestForOfPatterns":           true,
+	"TestObjectSpread":            true,
+}
+
+func TestMain(m *testing.M) {
+	code := 0
+	for _, b := range []interpreter.Backend{interpreter.TreeWalker, interpreter.BytecodeVM} {
+		backend = b
+		if result := m.Run(); result != 0 {
+			fmt.Fprintf(os.Stderr, "tests failed with the %s backend\n", b)
+			code = result
+		}
+	}
+	os.Exit(code)
+}
+
+// newInterpreter creates an interpreter using the backend under test.
+func newInterpreter() *interpreter.Interpreter {
+	interp := interpreter.New()
+	interp.SetBackend(backend)
+	return interp
+}
+
+// checkCompiled fails the test if, running with the VM, it's not listed in
+// treeWalked but the VM would leave some of program to the tree-walker.
+// Modules are always evaluated by the tree-walker, so it passes them.
+func checkCompiled(t *testing.T, program *ast.Program) {
+	t.Helper()
+	if backend != interpreter.BytecodeVM || program.Module {
+		return
+	}
+	if name, _, _ := strings.Cut(t.Name(), "/"); treeWalked[name] {
+		return
+	}
+	for _, node := range compiler.Compile(program).TreeWalked() {
+		t.Errorf("the VM leaves %q to the tree-walker", node.String())
+	}
+}
+
+// BenchmarkBackends runs the same scripts with each backend. It compares
+// the two within one run of the tests, so it only runs in the first.
+func BenchmarkBackends(b *testing.B) {
+	if backend != interpreter.TreeWalker {
+		b.Skip("the backends are compared in the tree-walker's run")
+	}
+	scripts := []struct {
+		name   string
+		source string
+	}{
+		{"fib", `function fib(n) { return n < 2 ? n : fib(n - 1) + fib(n - 2); } fib(20)`},
+		{"loop", `let sum = 0; for (let i = 0; i < 100000; i++) { sum += i % 7; } sum`},
+		{"closures", `function counter() { let n = 0; return () => ++n; } const c = counter(); for (let i = 0; i < 50000; i++) { c(); } c()`},
+	}
+	for _, script := range scripts {
+		program := parser.New(lexer.New(script.source)).ParseProgram()
+		for _, backend := range []interpreter.Backend{interpreter.TreeWalker, interpreter.BytecodeVM} {
+			b.Run(script.name+"/"+backend.String(), func(b *testing.B) {
+				for range b.N {
+					interp := interpreter.New()
+					interp.SetBackend(backend)
+					if err, ok := interp.Eval(program).(*interpreter.Error); ok {
+						b.Fatal(err.Message)
+					}
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkPropertyAccess reads and writes properties at sites that see
+// objects of one shape, and at sites that see eight, which is more than an
+// inline cache holds, so that every access has to look the property up.
+func BenchmarkPropertyAccess(b *testing.B) {
+	if backend != interpreter.TreeWalker {
+		b.Skip("the backends are compared in the tree-walker's run")
+	}
+	const run = `
+		function norm() { return this.x * this.x + this.y * this.y; }
+		function run() {
+			let sum = 0;
+			for (let i = 0; i < 10000; i++) {
+				const p = points[i % 8];
+				p.x = p.x + 1;
+				sum += p.x * p.y + p.norm();
+			}
+			return sum;
+		}`
+	scripts := []struct {
+		name  string
+		setup string
+	}{
+		{"monomorphic", `
+			function Point(x, y) { this.x = x; this.y = y; }
+			Point.prototype.norm = norm;
+			const points = [];
+			for (let i = 0; i < 8; i++) { points.push(new Point(i, i)); }`},
+		{"megamorphic", `
+			const points = [];
+			for (let i = 0; i < 8; i++) {
+				const p = {};
+				p["pad" + i] = i;
+				p.x = i; p.y = i; p.norm = norm;
+				points.push(p);
+			}`},
+	}
+	call := parser.New(lexer.New("run()")).ParseProgram()
+	for _, script := range scripts {
+		setup := parser.New(lexer.New(run + script.setup)).ParseProgram()
+		for _, backend := range []interpreter.Backend{interpreter.TreeWalker, interpreter.BytecodeVM} {
+			b.Run(script.name+"/"+backend.String(), func(b *testing.B) {
+				interp := interpreter.New()
+				interp.SetBackend(backend)
+				interp.Eval(setup)
+				for range b.N {
+					if err, ok := interp.Eval(call).(*interpreter.Error); ok {
+						b.Fatal(err.Message)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package interpreter_test
+
+import "testing"
+
+func TestBigInt(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`123n`, "123n"},
+		{`typeof 1n`, "bigint"},
+		{`[0x1Fn, 0o17n, 0b101n, 1_000n]`, "[31n, 15n, 5n, 1000n]"},
+		{`9007199254740993n`, "9007199254740993n"},
+		{`9007199254740993n + 1n`, "9007199254740994n"},
+		{`123456789012345678901234567890n * 987654321098765432109876543210n`, "121932631137021795226185032733622923332237463801111263526900n"},
+		{`[10n - 20n, -5n, 7n / 2n, -7n / 2n, 7n % 3n, -7n % 3n]`, "[-10n, -5n, 3n, -3n, 1n, -1n]"},
+		{`let x = 5n; x++; x += 2n; x`, "8n"},
+		{`let x = 5n; [x--, x]`, "[5n, 4n]"},
+		{`1n + "a"`, "1a"},
+		{`String(10n)`, "10"},
+		{`[1n, 2n].join("-")`, "1-2"},
+		{`(255n).toString(16)`, "ff"},
+		{`(-255n).toString(2)`, "-11111111"},
+		{`(5n).valueOf()`, "5n"},
+		{`({ 1n: "a" })[1]`, "a"},
+		{`[!!0n, !!1n, 0n ? "yes" : "no"]`, "[false, true, no]"},
+		{`let m = new Map(); m.set(1n, "a"); [m.get(1n), m.has(2n)]`, "[a, false]"},
+		{`JSON.stringify({ a: 1 })`, `{"a":1}`},
+		{`[2n ** 64n, (-3n) ** 3n, 0n ** 0n, 1n ** 100000000000000000000n]`, "[18446744073709551616n, -27n, 1n, 1n]"},
+		{`[5n & 3n, 5n | 3n, 5n ^ 3n, -1n & 0xffn, -6n | 1n, -1n ^ 5n]`, "[1n, 7n, 6n, 255n, -5n, -6n]"},
+		{`[1n << 100n, 5n >> 1n, -5n >> 1n, 5n << -1n, -5n >> 1000n, 0n << 10000000000n]`, "[1267650600228229401496703205376n, 2n, -3n, 2n, -1n, 0n]"},
+		{`let x = 3n; x **= 2n; x <<= 1n; x |= 1n; x &= 7n; x ^= 1n; x >>= 1n; x`, "1n"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestBigIntComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`1n === 1n`, true},
+		{`1n === 1`, false},
+		{`1n == 1`, true},
+		{`1n == 1.5`, false},
+		{`1n == "1"`, true},
+		{`1n == "x"`, false},
+		{`0n == false`, true},
+		{`1n != 2n`, true},
+		{`2n > 1`, true},
+		{`1n < 1.5`, true},
+		{`2n < 1.5`, false},
+		{`1n < "2"`, true},
+		{`1n < "x"`, false},
+		{`1n >= "x"`, false},
+		{`1n < NaN`, false},
+		{`1n < Infinity`, true},
+		{`-1n > -Infinity`, true},
+		{`9007199254740993n > 9007199254740992`, true},
+		{`10n > 9n`, true},
+		{`"10" > 9n`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestBigIntConversions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`BigInt(10)`, "10n"},
+		{`BigInt(-0)`, "0n"},
+		{`BigInt(1e21)`, "1000000000000000000000n"},
+		{`BigInt("0x10")`, "16n"},
+		{`BigInt(" -42 ")`, "-42n"},
+		{`BigInt("")`, "0n"},
+		{`BigInt(true)`, "1n"},
+		{`BigInt(5n)`, "5n"},
+		{`BigInt({ valueOf() { return 7; } })`, "7n"},
+		{`Number(123n)`, "123"},
+		{`Number(9007199254740993n)`, "9007199254740992"},
+		{`BigInt.asUintN(8, 257n)`, "1n"},
+		{`BigInt.asUintN(8, -1n)`, "255n"},
+		{`BigInt.asUintN(64, -1n)`, "18446744073709551615n"},
+		{`BigInt.asUintN(0, 5n)`, "0n"},
+		{`BigInt.asIntN(8, 255n)`, "-1n"},
+		{`BigInt.asIntN(8, 127n)`, "127n"},
+		{`BigInt.asIntN(8, 128n)`, "-128n"},
+		{`BigInt.asIntN(64, 9223372036854775808n)`, "-9223372036854775808n"},
+		{`BigInt.asIntN(1000000, 5n)`, "5n"},
+		{`BigInt.asUintN(8, "256")`, "0n"},
+		{`BigInt.prototype.toJSON = function() { return this.toString(); }; JSON.stringify({ a: 1n })`, `{"a":"1"}`},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestBigIntErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`1n + 1`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`1 * 2n`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`let x = 1n; x += 1`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`+1n`, "TypeError: Cannot convert a BigInt value to a number"},
+		{`Math.abs(1n)`, "TypeError: Cannot convert a BigInt value to a number"},
+		{`1n / 0n`, "RangeError: Division by zero"},
+		{`1n % 0n`, "RangeError: Division by zero"},
+		{`2n ** -1n`, "RangeError: Exponent must be non-negative"},
+		{`2n ** 10000000000n`, "RangeError: Maximum BigInt size exceeded"},
+		{`1n << 10000000000n`, "RangeError: Maximum BigInt size exceeded"},
+		{`5n >>> 1n`, "TypeError: BigInts have no unsigned right shift, use >> instead"},
+		{`5n & 3`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`1 << 1n`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`new BigInt(1)`, "TypeError: BigInt is not a constructor"},
+		{`BigInt(1.5)`, "RangeError: The number 1.5 cannot be converted to a BigInt because it is not an integer"},
+		{`BigInt(NaN)`, "RangeError: The number NaN cannot be converted to a BigInt because it is not an integer"},
+		{`BigInt("1.5")`, "SyntaxError: Cannot convert 1.5 to a BigInt"},
+		{`BigInt("-0x10")`, "SyntaxError: Cannot convert -0x10 to a BigInt"},
+		{`BigInt("1_000")`, "SyntaxError: Cannot convert 1_000 to a BigInt"},
+		{`BigInt()`, "TypeError: Cannot convert undefined to a BigInt"},
+		{`BigInt(null)`, "TypeError: Cannot convert null to a BigInt"},
+		{`BigInt(Symbol("s"))`, "TypeError: Cannot convert Symbol(s) to a BigInt"},
+		{`BigInt.asIntN(8, 1)`, "TypeError: Cannot convert 1 to a BigInt"},
+		{`BigInt.asIntN(-1, 1n)`, "RangeError: Invalid value: not (convertible to) a safe integer"},
+		{`BigInt.asUintN(1099511627776, -1n)`, "RangeError: Maximum BigInt size exceeded"},
+		{`(1n).toString(37)`, "RangeError: toString() radix must be between 2 and 36"},
+		{`BigInt.prototype.valueOf()`, "TypeError: BigInt.prototype.valueOf requires that 'this' be a BigInt"},
+		{`JSON.stringify({ a: 1n })`, "TypeError: Do not know how to serialize a BigInt"},
+		{`JSON.stringify([10n])`, "TypeError: Do not know how to serialize a BigInt"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestBigIntConsole(t *testing.T) {
+	stdout, _ := testConsole(t, `console.log(1n, [-2n], { a: 3n })`)
+	expected := "1n [ -2n ] { a: 3n }\n"
+	if stdout != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, stdout)
+	}
+}
@@ -0,0 +1,170 @@
+package interpreter_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestMap(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = new Map(); m.set("a", 1).set("b", 2); [m.get("a"), m.get("b"), m.get("c"), m.size]`, "[1, 2, undefined, 2]"},
+		{`new Map([["a", 1], ["b", 2]])`, "Map(2) {a => 1, b => 2}"},
+		{`let k = {}; let m = new Map([[k, "obj"]]); [m.get(k), m.get({}), m.has(k)]`, "[obj, undefined, true]"},
+		{`let m = new Map([[NaN, 1]]); m.get(NaN)`, "1"},
+		{`let m = new Map([[-0, 1]]); [m.get(0), 1 / [...m.keys()][0]]`, "[1, Infinity]"},
+		{`let m = new Map([[1, "n"], ["1", "s"]]); [m.get(1), m.get("1")]`, "[n, s]"},
+		{`let m = new Map([["a", 1], ["b", 2]]); m.set("a", 3); [...m]`, "[[a, 3], [b, 2]]"},
+		{`let m = new Map([["a", 1], ["b", 2]]); m.delete("a"); m.set("a", 1); [...m.keys()]`, "[b, a]"},
+		{`let m = new Map([["a", 1]]); [m.delete("a"), m.delete("a"), m.size]`, "[true, false, 0]"},
+		{`let m = new Map([["a", 1]]); m.clear(); m.size`, "0"},
+		{`[...new Map([["a", 1]]).values()]`, "[1]"},
+		{`let out = []; new Map([["a", 1], ["b", 2]]).forEach(function (v, k, m) { out.push(k + v + m.size); }); out`, "[a12, b22]"},
+		{`let m = new Map([["a", 1]]); m[Symbol.iterator] === m.entries`, "true"},
+		{`String(new Map())`, "[object Map]"},
+		{`String(new Map().keys())`, "[object Map Iterator]"},
+		{`let s = Symbol(); new Map([[s, 1]]).get(s)`, "1"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`new Set([1, 2, 2, "2", NaN, NaN])`, "Set(4) {1, 2, 2, NaN}"},
+		{`let s = new Set(); s.add(1).add(1); [s.size, s.has(1), s.has(2)]`, "[1, true, false]"},
+		{`new Set("hello").size`, "4"},
+		{`let s = new Set([1, 2, 3]); s.delete(2); [...s]`, "[1, 3]"},
+		{`let s = new Set([1]); s.clear(); [s.size, [...s]]`, "[0, []]"},
+		{`[...new Set([1, 2]).entries()]`, "[[1, 1], [2, 2]]"},
+		{`let s = new Set(); [s.keys === s.values, s[Symbol.iterator] === s.values]`, "[true, true]"},
+		{`let out = []; new Set(["a"]).forEach(function (v, k) { out.push(v + k); }); out`, "[aa]"},
+		{`let o = {}; let s = new Set([o, {}]); [s.has(o), s.size]`, "[true, 2]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestCollectionsChangedWhileIterating(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = new Set([1, 2, 3]); let out = []; for (const x of s) { out.push(x); if (x === 1) { s.delete(2); s.add(4); } } out`, "[1, 3, 4]"},
+		{`let s = new Set([1, 2]); let out = []; for (const x of s) { out.push(x); s.delete(x); } [out, s.size]`, "[[1, 2], 0]"},
+		{`let s = new Set([1, 2]); let out = []; for (const x of s) { out.push(x); if (x === 1) { s.clear(); s.add(5); } } out`, "[1, 5]"},
+		{`let s = new Set([1, 2, 3]); let it = s.values(); it.next(); s.delete(1); s.delete(2); [...it]`, "[3]"},
+		{`let s = new Set([1]); let it = s.values(); it.next(); s.delete(1); s.add(2); [...it]`, "[2]"},
+		{`let m = new Map([["a", 1]]); let it = m.keys(); it.next(); it.next(); m.set("b", 2); it.next().done`, "true"},
+		{`let m = new Map([["a", 1]]); let out = []; m.forEach(function (v, k) { out.push(k); if (k === "a") m.set("b", 2); }); out`, "[a, b]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestWeakCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let k = {}; let m = new WeakMap([[k, 1]]); [m.get(k), m.has(k), m.has({}), m.get(1)]`, "[1, true, false, undefined]"},
+		{`let k = {}; let m = new WeakMap(); m.set(k, 1).set(k, 2); [m.get(k), m.delete(k), m.has(k), m.delete(k)]`, "[2, true, false, false]"},
+		{`let s = Symbol("s"); let m = new WeakMap(); m.set(s, 1); m.get(s)`, "1"},
+		{`let k = []; let s = new WeakSet([k]); [s.has(k), s.has([]), s.delete(k), s.has(k)]`, "[true, false, true, false]"},
+		{`let o = {}; new WeakRef(o).deref() === o`, "true"},
+		{`new WeakMap()`, "WeakMap { <items unknown> }"},
+		{`String(new WeakRef({}))`, "[object WeakRef]"},
+		{`let r = new FinalizationRegistry(function () {}); let t = {}; r.register(t, 1, t); [r.unregister(t), r.unregister(t)]`, "[true, false]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Map()`, "TypeError: Constructor Map requires 'new'"},
+		{`Set()`, "TypeError: Constructor Set requires 'new'"},
+		{`new Map([1])`, "TypeError: Iterator value 1 is not an entry object"},
+		{`new Map(5)`, "TypeError: 5 is not iterable"},
+		{`let get = new Map().get; get(1)`, "TypeError: Method Map.prototype.get called on incompatible receiver null"},
+		{`new Set().forEach(1)`, "TypeError: 1 is not a function"},
+		{`new WeakMap().set(1, 1)`, "TypeError: Invalid value used as weak map key"},
+		{`new WeakMap().set(Symbol.for("x"), 1)`, "TypeError: Invalid value used as weak map key"},
+		{`new WeakSet().add("a")`, "TypeError: Invalid value used in weak set"},
+		{`new WeakRef(1)`, "TypeError: WeakRef: invalid target"},
+		{`new FinalizationRegistry(1)`, "TypeError: FinalizationRegistry: cleanup must be callable"},
+		{`let t = {}; new FinalizationRegistry(function () {}).register(t, t)`, "TypeError: FinalizationRegistry.prototype.register: target and holdings must not be same"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+// evalUntil runs input in interp after collecting garbage, over and over,
+// until it evaluates to true. Whether an object has been collected is only
+// seen between scripts, since a script keeps the targets of the WeakRefs
+// it uses alive until it finishes.
+func evalUntil(t *testing.T, interp *interpreter.Interpreter, input string) {
+	t.Helper()
+	program := parser.New(lexer.New(input)).ParseProgram()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		runtime.GC()
+		if b, ok := interp.Eval(program).(*interpreter.Boolean); ok && b.Value {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%q never became true", input)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWeakReferencesDontKeepObjectsAlive(t *testing.T) {
+	interp := newInterpreter()
+	setup := `
+let collected = [];
+let registry = new FinalizationRegistry(function (held) { collected.push(held); });
+let map = new WeakMap();
+let ref;
+(function () {
+	let key = {};
+	let value = {};
+	map.set(key, value);
+	ref = new WeakRef(key);
+	registry.register(key, "key");
+	registry.register(value, "value");
+	registry.register({}, "unregistered", registry);
+})();
+registry.unregister(registry);
+`
+	interp.Eval(parser.New(lexer.New(setup)).ParseProgram())
+
+	evalUntil(t, interp, `ref.deref() === undefined`)
+	// Once the key is gone, so is the map's entry for it, and with it the
+	// only reference to the value.
+	evalUntil(t, interp, `collected.length === 2`)
+	testInspect(t, interp.Eval(parser.New(lexer.New(`collected.sort()`)).ParseProgram()), "[key, value]")
+}
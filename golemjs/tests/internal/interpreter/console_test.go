@@ -0,0 +1,157 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"regexp"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testConsole runs input and returns what it wrote to stdout and stderr.
+func testConsole(t *testing.T, input string) (string, string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	var stdout, stderr bytes.Buffer
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stderr)
+	if result, ok := interp.Eval(program).(*interpreter.Error); ok {
+		t.Fatalf("input %q: %s", input, result.Message)
+	}
+	return stdout.String(), stderr.String()
+}
+
+func TestConsoleLog(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`console.log("hello", 1, true, null, undefined)`, "hello 1 true null undefined\n"},
+		{`console.log()`, "\n"},
+		{`console.log(["a", 1, [2]])`, "[ 'a', 1, [ 2 ] ]\n"},
+		{`console.log({ a: 1, "b-c": "it's" })`, `{ a: 1, 'b-c': "it's" }` + "\n"},
+		{`console.log([1, , , 4], [], {})`, "[ 1, <2 empty items>, 4 ] [] {}\n"},
+		{`let a = []; a[4294967294] = 1; console.log(a, new Array(3e9))`, "[ <4294967294 empty items>, 1 ] [ <3000000000 empty items> ]\n"},
+		{`console.log(-0, [-0])`, "-0 [ -0 ]\n"},
+		{`console.log(function f() {}, () => 1, puts)`, "[Function: f] [Function (anonymous)] [Function: puts]\n"},
+		{`console.log({ a: { b: { c: { d: 1 } } } })`, "{ a: { b: { c: [Object] } } }\n"},
+		{`console.log([[[[1]]]])`, "[ [ [ [Array] ] ] ]\n"},
+		{`let o = { name: "o" }; o.self = o; console.log(o)`, "<ref *1> { name: 'o', self: [Circular *1] }\n"},
+		{`let a = [1]; a.push(a); console.log(a)`, "<ref *1> [ 1, [Circular *1] ]\n"},
+		{`let P = function Point(x) { this.x = x; }; console.log(new P(1))`, "Point { x: 1 }\n"},
+		{`console.log(Array.from({ length: 102 }, (_, i) => i)[101], ["a\nb"])`, "101 [ 'a\\nb' ]\n"},
+		{`console.log({ aaaaaaaaaaaa: 111111111, bbbbbbbbbbbbbb: 22222222222, ccccccccccccc: 3333333333333 })`,
+			"{\n  aaaaaaaaaaaa: 111111111,\n  bbbbbbbbbbbbbb: 22222222222,\n  ccccccccccccc: 3333333333333\n}\n"},
+		{`console.info("info"); console.debug("debug")`, "info\ndebug\n"},
+		{`console.dir({ a: [1] })`, "{ a: [ 1 ] }\n"},
+		{`puts("a", [1, 2]); console.log("b")`, "a\n[1, 2]\nb\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestConsoleFormatSpecifiers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`console.log("%s is %d", "Bob", "42")`, "Bob is 42\n"},
+		{`console.log("%i %f", 3.9, "2.5px")`, "3 2.5\n"},
+		{`console.log("%s", [1, [2]])`, "[ 1, [Array] ]\n"},
+		{`console.log("%s", { toString: function () { return "custom"; } })`, "custom\n"},
+		{`console.log("%d", {})`, "NaN\n"},
+		{`console.log("%o %O", [1], { a: 1 })`, "[ 1 ] { a: 1 }\n"},
+		{`let o = { a: [1] }; console.log("%j", o); o.o = o; console.log("%j", o)`, "{\"a\":[1]}\n[Circular]\n"},
+		{`console.log("%cstyled", "color: red")`, "styled\n"},
+		{`console.log("100%% %s", "sure")`, "100% sure\n"},
+		{`console.log("100%%")`, "100%%\n"},
+		{`console.log("%s and %s", "one")`, "one and %s\n"},
+		{`console.log("%s", "a", "b", 1)`, "a b 1\n"},
+		{`console.log(1, "%s", "x")`, "1 %s x\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestConsoleStreams(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedStdout string
+		expectedStderr string
+	}{
+		{`console.error("e"); console.warn("w %d", 1)`, "", "e\nw 1\n"},
+		{`console.assert(true, "no"); console.assert(0, "bad %s", "x"); console.assert(false)`,
+			"", "Assertion failed: bad x\nAssertion failed\n"},
+		{`console.group("G"); console.log("a\nb"); console.group(); console.error("c"); console.groupEnd(); console.groupEnd(); console.log("d")`,
+			"G\n  a\n  b\nd\n", "    c\n"},
+		{`console.count(); console.count(); console.count("x"); console.countReset(); console.count()`,
+			"default: 1\ndefault: 2\nx: 1\ndefault: 1\n", ""},
+		{`console.countReset("nope")`, "", "Count for 'nope' does not exist\n"},
+		{`console.timeEnd("t")`, "", "Warning: No such label 't' for console.timeEnd()\n"},
+		{`console.time(); console.time()`, "", "Warning: Label 'default' already exists for console.time()\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, stderr := testConsole(t, tt.input)
+		if stdout != tt.expectedStdout || stderr != tt.expectedStderr {
+			t.Errorf("input %q: expected stdout=%q stderr=%q, got stdout=%q stderr=%q",
+				tt.input, tt.expectedStdout, tt.expectedStderr, stdout, stderr)
+		}
+	}
+}
+
+func TestConsoleTime(t *testing.T) {
+	stdout, _ := testConsole(t, `console.time("t"); console.timeLog("t", "so far"); console.timeEnd("t")`)
+	pattern := regexp.MustCompile(`^t: \d+\.\d{3}ms so far\nt: \d+\.\d{3}ms\n$`)
+	if !pattern.MatchString(stdout) {
+		t.Errorf("unexpected timer output %q", stdout)
+	}
+}
+
+func TestConsoleTable(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`console.table([{ a: 1, b: "Y" }, { a: "Z" }, 5])`, `┌─────────┬─────┬─────┬────────┐
+│ (index) │  a  │  b  │ Values │
+├─────────┼─────┼─────┼────────┤
+│    0    │  1  │ 'Y' │        │
+│    1    │ 'Z' │     │        │
+│    2    │     │     │   5    │
+└─────────┴─────┴─────┴────────┘
+`},
+		{`console.table({ x: { a: 1, b: 2 }, y: { a: 3 } }, ["a"])`, `┌─────────┬───┐
+│ (index) │ a │
+├─────────┼───┤
+│    x    │ 1 │
+│    y    │ 3 │
+└─────────┴───┘
+`},
+		{`console.table("plain")`, "plain\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected=\n%s\ngot=\n%s", tt.input, tt.expected, stdout)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package interpreter_test
+
+import "testing"
+
+func TestAdditionCoercion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a" + 1`, "a1"},
+		{`1 + "a"`, "1a"},
+		{`1 + 2 + "3"`, "33"},
+		{`"1" + 2 + 3`, "123"},
+		{"true + 1", "2"},
+		{"null + 1", "1"},
+		{"[1, 2] + [3]", "1,23"},
+		{`({}) + ""`, "[object Object]"},
+		{`let s = "x"; s += 1; s`, "x1"},
+		{`"😀"[0] + 1`, "\xed\xa0\xbd1"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestNumericCoercion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"3" - 1`, "2"},
+		{`"3" * "4"`, "12"},
+		{`"8" / "2"`, "4"},
+		{`"abc" * 2`, "NaN"},
+		{`+"  42  "`, "42"},
+		{`+""`, "0"},
+		{`+"0x10"`, "16"},
+		{`+"1e3"`, "1000"},
+		{`+"Infinity"`, "Infinity"},
+		{`+"1_000"`, "NaN"},
+		{`+"inf"`, "NaN"},
+		{"+true", "1"},
+		{"+null", "0"},
+		{"+[]", "0"},
+		{"+[5]", "5"},
+		{"+[1, 2]", "NaN"},
+		{`-"5"`, "-5"},
+		{`let x = "5"; x++; x`, "6"},
+		{"1 / 0", "Infinity"},
+		{"-1 / 0", "-Infinity"},
+		{"0 / 0", "NaN"},
+		{"5 % 0", "NaN"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"1" == 1`, true},
+		{`"1" === 1`, false},
+		{`0 == ""`, true},
+		{`"0" == false`, true},
+		{"null == 0", false},
+		{"null == false", false},
+		{"null == null", true},
+		{"[1] == 1", true},
+		{`[1, 2] == "1,2"`, true},
+		{"[] == []", false},
+		{"let a = []; a == a", true},
+		{"NaN == NaN", false},
+		{"NaN === NaN", false},
+		{"0 === -0", true},
+		{`"a" != "b"`, true},
+		{`1 != "1"`, false},
+		{`1 !== "1"`, true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRelationalComparison(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{`"a" < "b"`, true},
+		{`"B" < "a"`, true},
+		{`"10" < "9"`, true},
+		{`"10" < 9`, false},
+		{`"abc" <= "abc"`, true},
+		{`"ab" < "abc"`, true},
+		{`"￿" < "😀"`, false},
+		{`1 < "x"`, false},
+		{`1 >= "x"`, false},
+		{"null >= 0", true},
+		{"[2] > 1", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestConversionHooks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let o = { valueOf() { return 42; } }; o + 1", "43"},
+		{"let o = { valueOf() { return 42; } }; o * 2", "84"},
+		{`let o = { toString() { return "T"; } }; o + "!"`, "T!"},
+		{`let o = { toString() { return "T"; } }; String(o)`, "T"},
+		{`let o = { toString() { return "T"; } }; [o, o].join("-")`, "T-T"},
+		{`let o = { toString() { return "k"; } }; let d = {}; d[o] = 5; d.k`, "5"},
+		{`let o = { valueOf() { return 1; }, toString() { return "s"; } }; o + ""`, "1"},
+		{`let o = { valueOf() { return 1; }, toString() { return "s"; } }; String(o)`, "s"},
+		{`let o = { valueOf() { return {}; } }; o + ""`, "[object Object]"},
+		{"let o = { valueOf() { return 2; } }; o == 2", "true"},
+		{"let o = { valueOf() { return 2; } }; o > 1", "true"},
+		{`let a = [1]; a.push(a); String(a)`, "1,"},
+		{`"" + function f(a) { return a; }`, "function f(a) {\n  return a;\n}"},
+		{`String(puts)`, "function puts() { [native code] }"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, `let o = { valueOf() { return x; } }; o + 1`), "ReferenceError: x is not defined")
+}
+
+func TestTypeof(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"typeof 1", "number"},
+		{"typeof NaN", "number"},
+		{`typeof "s"`, "string"},
+		{"typeof true", "boolean"},
+		{"typeof null", "object"},
+		{"typeof {}", "object"},
+		{"typeof []", "object"},
+		{"typeof puts", "function"},
+		{"typeof (() => 1)", "function"},
+		{"typeof function () {}", "function"},
+		{"typeof undeclared", "undefined"},
+		{"typeof typeof 1", "string"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,239 @@
+package interpreter_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testNow is the time the clock in testDateEval always reads.
+var testNow = time.Date(2024, time.March, 15, 12, 30, 45, 678e6, time.UTC)
+
+// testDateEval runs input with the clock stopped at testNow and local time
+// in the time zone named zone.
+func testDateEval(t *testing.T, input string, zone string) interpreter.Object {
+	t.Helper()
+	loc, err := time.LoadLocation(zone)
+	if err != nil {
+		t.Fatalf("loading time zone %q: %v", zone, err)
+	}
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	interp := newInterpreter()
+	interp.SetClock(interpreter.NewFakeClock(testNow))
+	interp.SetTimeZone(loc)
+	return interp.Eval(program)
+}
+
+func TestDateConstructor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`new Date()`, "2024-03-15T12:30:45.678Z"},
+		{`Date.now()`, "1710505845678"},
+		{`new Date(0)`, "1970-01-01T00:00:00.000Z"},
+		{`new Date(-1)`, "1969-12-31T23:59:59.999Z"},
+		{`new Date(1.9).getTime()`, "1"},
+		{`new Date(8.64e15)`, "+275760-09-13T00:00:00.000Z"},
+		{`new Date(8.64e15 + 1)`, "Invalid Date"},
+		{`new Date(-62198755200000)`, "-000001-01-01T00:00:00.000Z"},
+		{`new Date(NaN).getTime()`, "NaN"},
+		{`new Date("2024-01-02")`, "2024-01-02T00:00:00.000Z"},
+		{`new Date("nonsense")`, "Invalid Date"},
+		{`let d = new Date(5); let e = new Date(d); [d === e, e.getTime()]`, "[false, 5]"},
+		{`new Date(2024, 0, 2, 3, 4, 5, 6)`, "2024-01-02T03:04:05.006Z"},
+		{`new Date(2024, 0)`, "2024-01-01T00:00:00.000Z"},
+		{`new Date(2024, 0, 32)`, "2024-02-01T00:00:00.000Z"},
+		{`new Date(2024, 12, 1)`, "2025-01-01T00:00:00.000Z"},
+		{`new Date(2024, 0, 1, -1)`, "2023-12-31T23:00:00.000Z"},
+		{`new Date(99, 0)`, "1999-01-01T00:00:00.000Z"},
+		{`new Date(2024, NaN)`, "Invalid Date"},
+		{`Date.UTC(2024, 1, 29)`, "1709164800000"},
+		{`Date.UTC(2024)`, "1704067200000"},
+		{`Date.UTC()`, "NaN"},
+		{`typeof Date()`, "string"},
+		{`Date()`, "Fri Mar 15 2024 12:30:45 GMT+0000 (Coordinated Universal Time)"},
+		{`new Date(0) instanceof Date`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testDateEval(t, tt.input, "UTC"), tt.expected)
+	}
+}
+
+func TestDateParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{`Date.parse("2024-01-02T03:04:05.678Z")`, 1704164645678},
+		{`Date.parse("2024-01-02T03:04:05.6789Z")`, 1704164645678},
+		{`Date.parse("2024-01-02T03:04:05+01:00")`, 1704161045000},
+		{`Date.parse("2024-01-02T03:04:05-01:30")`, 1704170045000},
+		{`Date.parse("2024")`, 1704067200000},
+		{`Date.parse("2024-02")`, 1706745600000},
+		{`Date.parse("2024-01-02")`, 1704153600000},
+		{`Date.parse("+002024-01-02")`, 1704153600000},
+		{`Date.parse("2024-01-02T24:00Z")`, 1704240000000},
+		// Without an offset, a date-time is in local time, which the tests
+		// set to UTC+2 in winter; a date on its own is always UTC.
+		{`Date.parse("2024-01-02T03:04")`, 1704157440000},
+		{`Date.parse("2024-01-02T24:00")`, 1704232800000},
+		{`Date.parse("Tue, 02 Jan 2024 03:04:05 GMT")`, 1704164645000},
+		{`Date.parse("Tue, 02 Jan 2024 03:04:05 +0100")`, 1704161045000},
+		{`Date.parse("Tue Jan 02 2024 03:04:05 GMT+0100 (Central European Standard Time)")`, 1704161045000},
+		{`Date.parse("January 2, 2024")`, 1704146400000},
+		{`Date.parse("2 Jan 2024 3:04 PM UTC")`, 1704207840000},
+		{`Date.parse("1/2/2024 UTC")`, 1704153600000},
+		{`Date.parse("2024/01/02 UTC")`, 1704153600000},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testDateEval(t, tt.input, "Africa/Cairo"), tt.expected)
+	}
+}
+
+func TestDateParseInvalid(t *testing.T) {
+	tests := []string{
+		`Date.parse("")`,
+		`Date.parse("hello")`,
+		`Date.parse("2024-13-01")`,
+		`Date.parse("2024-02-30")`,
+		`Date.parse("2024-01-02T25:00")`,
+		`Date.parse("-000000-01-01T00:00Z")`,
+		`Date.parse("Jan 2024")`,
+		`Date.parse("2024-01-02T03:04Zjunk")`,
+		`Date.parse("13/45/2024")`,
+	}
+
+	for _, input := range tests {
+		testInspect(t, testDateEval(t, input, "UTC"), "NaN")
+	}
+}
+
+func TestDateGetters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let d = new Date(0); [d.getFullYear(), d.getMonth(), d.getDate(), d.getDay(), d.getHours(), d.getMinutes()]`, "[1969, 11, 31, 3, 19, 0]"},
+		{`let d = new Date(0); [d.getUTCFullYear(), d.getUTCMonth(), d.getUTCDate(), d.getUTCDay(), d.getUTCHours()]`, "[1970, 0, 1, 4, 0]"},
+		{`let d = new Date(1710505845678); [d.getSeconds(), d.getMilliseconds(), d.getUTCSeconds(), d.getUTCMilliseconds()]`, "[45, 678, 45, 678]"},
+		{`[new Date(0).getTimezoneOffset(), new Date(Date.UTC(2024, 6)).getTimezoneOffset()]`, "[300, 240]"},
+		{`new Date(2024, 6, 1).getHours()`, "0"},
+		{`new Date(2024, 6, 1).getUTCHours()`, "4"},
+		{`[new Date(0).valueOf(), new Date(0).getTime()]`, "[0, 0]"},
+		{`let d = new Date(NaN); [d.getFullYear(), d.getUTCDay(), d.getTimezoneOffset()]`, "[NaN, NaN, NaN]"},
+		// 2:30 on the night the clocks go forward doesn't exist, and is
+		// read as 3:30; 1:30 on the night they go back happens twice, and
+		// is read as the first.
+		{`new Date(2024, 2, 10, 2, 30).getHours()`, "3"},
+		{`new Date(2024, 10, 3, 1, 30).getTimezoneOffset()`, "240"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testDateEval(t, tt.input, "America/New_York"), tt.expected)
+	}
+}
+
+func TestDateSetters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let d = new Date(0); d.setUTCFullYear(2024); d`, "2024-01-01T00:00:00.000Z"},
+		{`let d = new Date(0); d.setUTCFullYear(2024, 5, 15)`, "1718409600000"},
+		{`let d = new Date(0); d.setUTCMonth(13); d`, "1971-02-01T00:00:00.000Z"},
+		{`let d = new Date(Date.UTC(2024, 0, 31)); d.setUTCMonth(1); d`, "2024-03-02T00:00:00.000Z"},
+		{`let d = new Date(0); d.setUTCDate(0); d`, "1969-12-31T00:00:00.000Z"},
+		{`let d = new Date(0); d.setUTCHours(25, 61); d`, "1970-01-02T02:01:00.000Z"},
+		{`let d = new Date(0); d.setUTCMinutes(1, 2, 3); d`, "1970-01-01T00:01:02.003Z"},
+		{`let d = new Date(0); d.setUTCSeconds(-1); d`, "1969-12-31T23:59:59.000Z"},
+		{`let d = new Date(0); d.setUTCMilliseconds(1500); d`, "1970-01-01T00:00:01.500Z"},
+		{`let d = new Date(0); d.setHours(12); d`, "1969-12-31T17:00:00.000Z"},
+		{`let d = new Date(0); d.setDate(1); [d.getDate(), d.getHours()]`, "[1, 19]"},
+		{`let d = new Date(0); d.setFullYear(2024, 6, 4); [d.getMonth(), d.getDate(), d.getHours(), d.getTimezoneOffset()]`, "[6, 4, 19, 240]"},
+		{`let d = new Date(0); d.setTime(86400000); d`, "1970-01-02T00:00:00.000Z"},
+		{`let d = new Date(0); [d.setTime("x"), d.getTime()]`, "[NaN, NaN]"},
+		{`let d = new Date(0); d.setUTCHours(NaN)`, "NaN"},
+		{`let d = new Date(NaN); [d.setUTCHours(1), d.getTime()]`, "[NaN, NaN]"},
+		{`let d = new Date(NaN); d.setUTCFullYear(2000); d`, "2000-01-01T00:00:00.000Z"},
+		{`let d = new Date(0); d.setUTCFullYear(300000)`, "NaN"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testDateEval(t, tt.input, "America/New_York"), tt.expected)
+	}
+}
+
+func TestDateFormatting(t *testing.T) {
+	tests := []struct {
+		input    string
+		zone     string
+		expected string
+	}{
+		{`new Date(0).toISOString()`, "UTC", "1970-01-01T00:00:00.000Z"},
+		{`new Date(0).toJSON()`, "UTC", "1970-01-01T00:00:00.000Z"},
+		{`new Date(NaN).toJSON()`, "UTC", "null"},
+		{`JSON.stringify({ at: new Date(0) })`, "UTC", `{"at":"1970-01-01T00:00:00.000Z"}`},
+		{`new Date(0).toString()`, "UTC", "Thu Jan 01 1970 00:00:00 GMT+0000 (Coordinated Universal Time)"},
+		{`new Date(0).toString()`, "Asia/Kolkata", "Thu Jan 01 1970 05:30:00 GMT+0530 (IST)"},
+		{`new Date(0).toString()`, "America/New_York", "Wed Dec 31 1969 19:00:00 GMT-0500 (EST)"},
+		{`new Date(Date.UTC(2024, 6, 4, 12)).toString()`, "America/New_York", "Thu Jul 04 2024 08:00:00 GMT-0400 (EDT)"},
+		{`new Date(0).toDateString()`, "America/New_York", "Wed Dec 31 1969"},
+		{`new Date(0).toTimeString()`, "America/New_York", "19:00:00 GMT-0500 (EST)"},
+		{`new Date(0).toUTCString()`, "America/New_York", "Thu, 01 Jan 1970 00:00:00 GMT"},
+		{`new Date(0).toGMTString()`, "UTC", "Thu, 01 Jan 1970 00:00:00 GMT"},
+		{`new Date(NaN).toString()`, "UTC", "Invalid Date"},
+		{`new Date(NaN).toUTCString()`, "UTC", "Invalid Date"},
+		{`new Date(Date.UTC(2024, 0, 2, 15, 4, 5)).toLocaleString()`, "UTC", "1/2/2024, 3:04:05 PM"},
+		{`new Date(Date.UTC(2024, 0, 2, 0, 4, 5)).toLocaleTimeString()`, "UTC", "12:04:05 AM"},
+		{`new Date(0).toLocaleDateString()`, "America/New_York", "12/31/1969"},
+		{`new Date(0).toLocaleDateString("en-US", { timeZone: "UTC" })`, "America/New_York", "1/1/1970"},
+		{`new Date(0).toLocaleTimeString(undefined, { timeZone: "Asia/Tokyo" })`, "UTC", "9:00:00 AM"},
+		{`new Date(0) + ""`, "UTC", "Thu Jan 01 1970 00:00:00 GMT+0000 (Coordinated Universal Time)"},
+		{`new Date(1000) - new Date(0)`, "UTC", "1000"},
+		{`+new Date(42)`, "UTC", "42"},
+		{`new Date(0) < new Date(1)`, "UTC", "true"},
+		{`new Date(0)[Symbol.toPrimitive]("number")`, "UTC", "0"},
+		{`new Date(0)[Symbol.toPrimitive]("default")`, "UTC", "Thu Jan 01 1970 00:00:00 GMT+0000 (Coordinated Universal Time)"},
+		{`String(new Date(-62198755200000))`, "UTC", "Fri Jan 01 -0001 00:00:00 GMT+0000 (Coordinated Universal Time)"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testDateEval(t, tt.input, tt.zone), tt.expected)
+	}
+}
+
+func TestDateErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`new Date(NaN).toISOString()`, "RangeError: Invalid time value"},
+		{`Date.prototype.getTime()`, "TypeError: this is not a Date object."},
+		{`let f = new Date(0).getTime; f()`, "TypeError: this is not a Date object."},
+		{`new Date(0)[Symbol.toPrimitive]("other")`, "TypeError: Invalid hint: other"},
+		{`new Date(0).toLocaleString("en-US", { timeZone: "Mars/Olympus" })`, "RangeError: Invalid time zone specified: Mars/Olympus"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testDateEval(t, tt.input, "UTC"), tt.expectedMessage)
+	}
+}
+
+func TestDateConsole(t *testing.T) {
+	stdout, _ := testConsole(t, `console.log(new Date(0), [new Date(NaN)], { at: new Date(0) })`)
+	expected := "1970-01-01T00:00:00.000Z [ Invalid Date ] { at: 1970-01-01T00:00:00.000Z }\n"
+	if stdout != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, stdout)
+	}
+}
@@ -0,0 +1,149 @@
+package interpreter_test
+
+import "testing"
+
+func TestArrayDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = [1, 2]; [a, b]`, "[1, 2]"},
+		{`const [a, , c] = [1, 2, 3]; [a, c]`, "[1, 3]"},
+		{`let [a, b, c] = [1]; [a, b, c]`, "[1, undefined, undefined]"},
+		{`let [a = 1, b = a + 1] = [undefined]; [a, b]`, "[1, 2]"},
+		{`let [a = 1] = [null]; a`, "null"},
+		{`let [first, ...rest] = [1, 2, 3]; [first, rest]`, "[1, [2, 3]]"},
+		{`let [...all] = []; all`, "[]"},
+		{`let [[a, b], [c]] = [[1, 2], [3]]; a + b + c`, "6"},
+		{`let [a, b] = "h😀"; b`, "😀"},
+		{`var [v] = [7]; v`, "7"},
+		{`let [x, ...rest] = new Uint8Array([1, 2, 3]); [x, rest]`, "[1, [2, 3]]"},
+		{counter + `let [x, y] = counter; [x, y, closed]`, "[1, 2, true]"},
+		{counter + `let [x, y, z, w] = counter; [w, closed]`, "[undefined, false]"},
+		{counter + `let [...xs] = counter; xs`, "[1, 2, 3]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestObjectDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let { a, b } = { a: 1, b: 2 }; a + b`, "3"},
+		{`let { a: x, b: y = 5 } = { a: 1 }; [x, y]`, "[1, 5]"},
+		{`let { a = 3 } = {}; a`, "3"},
+		{`let { a: { b: [c] } } = { a: { b: [4] } }; c`, "4"},
+		{`let k = "key"; let { [k]: v } = { key: 9 }; v`, "9"},
+		{`let { a, ...rest } = { a: 1, b: 2, c: 3 }; [a, rest.b, rest.c, rest.a]`, "[1, 2, 3, undefined]"},
+		{`let { length } = "abc"; length`, "3"},
+		{`let { 0: first, length } = [5, 6]; [first, length]`, "[5, 2]"},
+		{`let { toFixed } = 1; typeof toFixed`, "function"},
+		{`let { f = () => 1 } = {}; f()`, "1"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = 1; let b = 2; [a, b] = [b, a]; [a, b]`, "[2, 1]"},
+		{`let a; let b; ({ a, b } = { a: 3, b: 4 }); a * b`, "12"},
+		{`let o = {}; [o.x, o["y"]] = [1, 2]; o.x + o.y`, "3"},
+		{`let a; let r = [a] = [5, 6]; r`, "[5, 6]"},
+		{`let x; let rest; [x, ...rest] = [1, 2, 3]; rest`, "[2, 3]"},
+		{`let x; ({ x = 10 } = {}); x`, "10"},
+		{`let a = []; ({ p: a[0] } = { p: 8 }); a`, "[8]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestParameterPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function f(a, b = 2) { return a + b; } [f(1), f(1, 5)]`, "[3, 6]"},
+		{`function f(a, b = a * 2) { return b; } f(4)`, "8"},
+		{`function f(...args) { return args; } f(1, 2, 3)`, "[1, 2, 3]"},
+		{`function f(a, ...args) { return args; } f(1)`, "[]"},
+		{`function f({ x, y }) { return x + y; } f({ x: 1, y: 2 })`, "3"},
+		{`function f([a, b] = [1, 2]) { return a * b; } f()`, "2"},
+		{`let f = ({ name }) => name; f({ name: "n" })`, "n"},
+		{`let f = ([a, b], ...c) => [a + b, c]; f([1, 2], 3)`, "[3, [3]]"},
+		{`let f = (a = 1) => a; f()`, "1"},
+		{`let f = (...xs) => xs.length; f(1, 2)`, "2"},
+		{`function* g({ n }) { yield n; } g({ n: 3 }).next().value`, "3"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestForOfPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let out = []; for (const [k, v] of [["a", 1], ["b", 2]]) out.push(k + v); out`, "[a1, b2]"},
+		{`let sum = 0; for (const { n } of [{ n: 1 }, { n: 2 }]) sum += n; sum`, "3"},
+		{`let i; let c; for ([i, c] of ["xy"].entries()) {} [i, c]`, "[0, xy]"},
+		{`let out = []; for (const [i, c] of ["a", "b"].entries()) out.push(i + c); out`, "[0a, 1b]"},
+		{`let o = {}; for (o.p of [4, 5]) {} o.p`, "5"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestObjectSpread(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let o = { ...{ a: 1, b: 2 }, b: 3 }; [o.a, o.b]`, "[1, 3]"},
+		{`let o = { b: 3, ...{ a: 1, b: 2 } }; o.b`, "2"},
+		{`let o = { ...[7, 8] }; o[1]`, "8"},
+		{`let o = { ..."hi" }; o[0] + o[1]`, "hi"},
+		{`let o = { ...null, ...undefined, ...5 }; o`, "{}"},
+		{`let s = Symbol("s"); let src = {}; src[s] = 1; let o = { ...src }; o[s]`, "1"},
+		{`({ ...new Uint8Array([4, 5]) })`, "{0: 4, 1: 5}"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a] = 5;`, "TypeError: 5 is not iterable"},
+		{`let [a] = {};`, "TypeError: {} is not iterable"},
+		{`let { a } = null;`, "TypeError: Cannot destructure property 'a' of 'null' as it is null."},
+		{`let {} = undefined;`, "TypeError: Cannot destructure 'undefined' as it is undefined."},
+		{`function f({ a }) {} f()`, "TypeError: Cannot destructure property 'a' of 'undefined' as it is undefined."},
+		{`const a = 1; [a] = [2];`, "TypeError: Assignment to constant variable."},
+		{`let { a: { b } } = {};`, "TypeError: Cannot destructure property 'b' of 'undefined' as it is undefined."},
+		{`let o = { a = 1 };`, "SyntaxError: Invalid shorthand property initializer"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
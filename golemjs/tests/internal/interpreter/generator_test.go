@@ -0,0 +1,114 @@
+package interpreter_test
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function* g() { yield 1; yield 2; } let it = g(); [it.next(), it.next(), it.next()]`,
+			"[{value: 1, done: false}, {value: 2, done: false}, {value: undefined, done: true}]"},
+		{`function* g() { yield 1; return "end"; } let it = g(); it.next(); [it.next(), it.next()]`,
+			"[{value: end, done: true}, {value: undefined, done: true}]"},
+		{`function* g() { let x = yield 1; let y = yield x * 2; return x + y; } let it = g(); [it.next("ignored").value, it.next(5).value, it.next(10).value]`,
+			"[1, 10, 15]"},
+		{`function* g(n) { for (let i = 0; i < n; i++) yield i; } [...g(4)]`, "[0, 1, 2, 3]"},
+		{`function* g() { yield; } g().next()`, "{value: undefined, done: false}"},
+		{`let log = []; function* g() { log.push("started"); yield 1; } let it = g(); log.push("created"); it.next(); log`,
+			"[created, started]"},
+		{`function* g(a, b) { yield this.base + a + b; } let o = { base: 1, g }; o.g(2, 3).next().value`, "6"},
+		{`let g = function* () { yield 1; }; g().next().value`, "1"},
+		{`let o = { *items() { yield "x"; yield "y"; } }; [...o.items()]`, "[x, y]"},
+		{`function* g() { yield 1; } let it = g(); it[Symbol.iterator]() === it`, "true"},
+		{`function* g() {} [typeof g, g() instanceof g]`, "[function, true]"},
+		{`function* g() {} String(g())`, "[object Generator]"},
+		{`function* g() { yield 1; yield 2; } Array.from(g(), function (x) { return x * 3; })`, "[3, 6]"},
+		{`function* fib() { let a = 0; let b = 1; while (true) { yield a; let t = a + b; a = b; b = t; } }
+		  let out = []; for (const x of fib()) { if (x > 20) break; out.push(x); } out`, "[0, 1, 1, 2, 3, 5, 8, 13]"},
+		{`function* outer() { function* inner() { yield 1; } yield* inner(); } [...outer()]`, "[1]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestYieldDelegation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function* g() { yield 0; yield* [1, 2]; yield* "ab"; } [...g()]`, "[0, 1, 2, a, b]"},
+		{`function* inner() { yield 1; return "r"; } function* outer() { let r = yield* inner(); yield r; } [...outer()]`, "[1, r]"},
+		{`function* inner() { let x = yield 1; yield x; } function* outer() { yield* inner(); }
+		  let it = outer(); it.next(); it.next("sent").value`, "sent"},
+		{`function* inner() { yield 1; yield 2; } function* outer() { yield* inner(); yield 3; }
+		  let it = outer(); it.next(); [it.return("early"), it.next()]`,
+			"[{value: early, done: true}, {value: undefined, done: true}]"},
+		{counter + `function* g() { yield* counter; } let it = g(); it.next(); it.return(); closed`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestGeneratorReturnAndThrow(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function* g() { yield 1; yield 2; } let it = g(); it.next(); [it.return(42), it.next()]`,
+			"[{value: 42, done: true}, {value: undefined, done: true}]"},
+		{`let ran = false; function* g() { ran = true; yield 1; } let it = g(); [it.return(7), ran]`,
+			"[{value: 7, done: true}, false]"},
+		{`function* g() { yield 1; } let it = g(); it.next(); it.next(); it.return(3)`, "{value: 3, done: true}"},
+		{`function* g() { let x = 1 + (yield 1); yield x; } let it = g(); it.next(); it.return(5)`, "{value: 5, done: true}"},
+		{counter + `function* g() { for (const x of counter) yield x; } let it = g(); it.next(); it.return(); closed`, "true"},
+		{`function* g() { yield 1; yield 2; } let it = g(); it.next(); it.throw("boom"); it.next()`, "ERROR: boom"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`function* g() { yield 1; } let it = g(); it.next(); it.throw("boom")`, "boom"},
+		{`function* g() { yield 1; } g().throw("before start")`, "before start"},
+		{`function* g() { yield 1; } new g()`, "TypeError: g is not a constructor"},
+		{`let it; function* g() { it.next(); } it = g(); it.next()`, "TypeError: Generator is already running"},
+		{`function* g() { yield undefinedVariable; } g().next()`, "ReferenceError: undefinedVariable is not defined"},
+		{`function* g() { yield* 5; } g().next()`, "TypeError: 5 is not iterable"},
+		{`function* inner() { yield 1; } function* outer() { yield* inner(); } let it = outer(); it.next(); it.throw("x")`, "x"},
+		{counter + `function* g() { yield* counter; } let it = g(); it.next(); it.throw("x")`,
+			"TypeError: The iterator does not provide a 'throw' method"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestAbandonedGeneratorsStop(t *testing.T) {
+	before := runtime.NumGoroutine()
+	testEval(t, `for (let i = 0; i < 50; i++) { (function* () { yield 1; yield 2; })().next(); }`)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("paused generators still running: %d goroutines, want %d", runtime.NumGoroutine(), before)
+		}
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+}
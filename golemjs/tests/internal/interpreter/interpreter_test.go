@@ -0,0 +1,239 @@
+package interpreter_test
+
+import (
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestEvalNumberExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"5", 5},
+		{"-5", -5},
+		{"5 + 5 + 5 + 5 - 10", 10},
+		{"2 * (5 + 10)", 30},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"7 / 2", 3.5},
+		{"0.1 + 0.2", 0.30000000000000004},
+		{"1e3 + .5", 1000.5},
+		{"0xff + 0o17 + 0b11", 273},
+		{"1_000_000", 1000000},
+		{"let x = 1; x += 4; x", 5},
+		{"let x = 1; x++ + ++x", 4},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"(-2) ** 3", -8},
+		{"2 ** -1", 0.5},
+		{"5 & 3", 1},
+		{"5 | 3", 7},
+		{"5 ^ 3", 6},
+		{"1 << 33", 2},
+		{"-5 >> 1", -3},
+		{"-5 >>> 28", 15},
+		{"2 ** 32 + 1 | 0", 1},
+		{"1 | 2 ^ 3 & 4", 3},
+		{"let x = 3; x **= 2; x <<= 1; x |= 1; x ^= 2; x >>= 1; x &= 12; x >>>= 1; x", 4},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestEvalBooleanExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true", true},
+		{"1 < 2", true},
+		{"1 >= 2", false},
+		{"1 == 1", true},
+		{"!0", true},
+		{`"a" === "a"`, true},
+		{"[] === []", false},
+		{"true && false", false},
+		{"false || 1 > 0", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestScoping(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"let a = 1; { let a = 2; } a", 1},
+		{"var a = 1; { var a = 2; } a", 2},
+		{"let a = 1; function f() { a = 5; } f(); a", 5},
+		{"function outer() { let n = 0; return () => { n++; return n; }; } let c = outer(); c(); c()", 2},
+		{"let fns = []; for (let i = 0; i < 3; i++) { fns.push(() => i); } fns[0]() + fns[2]()", 2},
+		{"let total = 0; let i = 0; while (true) { i++; if (i > 10) { break; } if (i % 2 == 0) { continue; } total += i; } total", 25},
+		{"hoisted(); function hoisted() { return 7; } hoisted()", 7},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestCompoundAssignment(t *testing.T) {
+	// The target's current value is read before the right-hand side is
+	// evaluated, so a change the right-hand side makes is overwritten.
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"let x = 1; x += 2; x -= 4; x *= -6; x /= 3; x %= 4; x", 2},
+		{"let x = 1; function f() { x = 10; return 1; } x += f(); x", 2},
+		{"let o = { n: 1 }; function f() { o.n = 10; return 1; } o.n += f(); o.n", 2},
+		{"let a = [1]; function f() { a[0] = 10; return 1; } a[0] += f(); a[0]", 2},
+		{"let log = []; let o = { n: 1 }; function obj() { log.push(1); return o; } function key() { log.push(2); return \"n\"; } function rhs() { log.push(3); return 1; } obj()[key()] += rhs(); log.join(\"\") * 1", 123},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDelete(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let o = { a: 1, b: 2 }; [delete o.a, o]", "[true, {b: 2}]"},
+		{`let o = { a: 1 }; let k = "a"; [delete o[k], o.a, o]`, "[true, undefined, {}]"},
+		{"let o = {}; delete o.missing", "true"},
+		{"let a = [1, 2, 3]; [delete a[1], a, a.length]", "[true, [1, , 3], 3]"},
+		{"let a = [1, 2]; [delete a.length, a.length]", "[false, 2]"},
+		{"[delete Math.PI, Math.PI > 3]", "[false, true]"},
+		{`[delete "abc"[0], delete "abc".length, delete "abc".x]`, "[false, false, true]"},
+		{"let x = 1; [delete x, x]", "[false, 1]"},
+		{"function f(p) { let v; return [delete p, delete v, delete f, delete nope]; } f(1)", "[false, false, false, true]"},
+		{"let n = 0; [delete (n = 5), n]", "[true, 5]"},
+		{"function get(o) { return o.x; } let o = { x: 1, y: 2 }; get(o); get(o); delete o.x; [get(o), o.y]", "[undefined, 2]"},
+		{"function P() { this.x = 1; } P.prototype.x = 2; let p = new P(); delete p.x; p.x", "2"},
+		{"let m = new Map([[1, 2]]); [m.delete(1), { delete: 3 }.delete]", "[true, 3]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "let o = null; delete o.x"), "TypeError: Cannot convert undefined or null to object")
+	testErrorObject(t, testEval(t, "delete undefined[0]"), "TypeError: Cannot convert undefined or null to object")
+}
+
+func TestThisAndNew(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"let o = { n: 3, get() { return this.n; } }; o.get()", 3},
+		{"let o = { n: 3, get() { return (() => this.n)(); } }; o.get()", 3},
+		{"function Point(x) { this.x = x; } let p = new Point(4); p.x", 4},
+		{"function P() {} P.prototype.two = function () { return 2; }; new P().two()", 2},
+		{"let o = { n: 1, add(by) { this.n += by; return this.n; } }; o.add(2); o.add(3)", 6},
+		{`let o = { n: 5 }; let k = "n"; o[k] *= 2; o.n -= 3; o.n`, 7},
+		{"let a = [1, 7]; a[0] += 5; a[1] %= 4; a[0] + a[1]", 9},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestErrorHandling(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"foobar", "ReferenceError: foobar is not defined"},
+		{"const c = 1; c = 2;", "TypeError: Assignment to constant variable."},
+		{"let x = null; x.y", "TypeError: Cannot read properties of null (reading 'y')"},
+		{"let x = 5; x()", "TypeError: x is not a function"},
+		{"let o = { valueOf() { return {}; }, toString() { return {}; } }; o + 1", "TypeError: Cannot convert object to primitive value"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func testEval(t *testing.T, input string) interpreter.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	return newInterpreter().Eval(program)
+}
+
+func testNumberObject(t *testing.T, obj interpreter.Object, expected float64) bool {
+	t.Helper()
+	result, ok := obj.(*interpreter.Number)
+	if !ok {
+		t.Errorf("object is not Number. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testBooleanObject(t *testing.T, obj interpreter.Object, expected bool) bool {
+	t.Helper()
+	result, ok := obj.(*interpreter.Boolean)
+	if !ok {
+		t.Errorf("object is not Boolean. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%t, want=%t",
+			result.Value, expected)
+		return false
+	}
+	return true
+}
+
+func testErrorObject(t *testing.T, obj interpreter.Object, expected string) bool {
+	t.Helper()
+	errObj, ok := obj.(*interpreter.Error)
+	if !ok {
+		t.Errorf("no error object returned. got=%T(%+v)", obj, obj)
+		return false
+	}
+	if errObj.Message != expected {
+		t.Errorf("wrong error message. expected=%q, got=%q",
+			expected, errObj.Message)
+		return false
+	}
+	return true
+}
+
+func testInspect(t *testing.T, obj interpreter.Object, expected string) bool {
+	t.Helper()
+	if obj == nil {
+		t.Errorf("no object returned, want %q", expected)
+		return false
+	}
+	if got := obj.Inspect(); got != expected {
+		t.Errorf("wrong value. expected=%q, got=%q", expected, got)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,85 @@
+package interpreter_test
+
+import "testing"
+
+// counter is an iterable written by hand: it counts up to 3 and records
+// in closed whether its iterator was closed.
+const counter = `
+let closed = false;
+let counter = {};
+counter[Symbol.iterator] = function () {
+	let n = 0;
+	return {
+		next() { n++; return { value: n, done: n > 3 }; },
+		return() { closed = true; return {}; },
+	};
+};
+`
+
+func TestForOf(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let sum = 0; for (const x of [1, 2, 3]) { sum += x; } sum`, "6"},
+		{`let out = ""; for (let c of "héllo") out += c + "."; out`, "h.é.l.l.o."},
+		{`let n = 0; for (const c of "a😀b") n++; n`, "3"},
+		{`let out = []; for (const e of ["a", "b"].entries()) out.push(e[0] + e[1]); out`, "[0a, 1b]"},
+		{`let last; for (last of [1, 2, 3]) {} last`, "3"},
+		{`for (var v of [7]) {} v`, "7"},
+		{`let fns = []; for (const x of [1, 2]) fns.push(() => x); fns[0]() + fns[1]()`, "3"},
+		{`let out = []; for (const x of [1, 2, 3, 4]) { if (x === 2) continue; if (x === 4) break; out.push(x); } out`, "[1, 3]"},
+		{`let f = function () { for (const x of [1, 2, 3]) { if (x === 2) return x * 10; } }; f()`, "20"},
+		{`let a = [1]; let n = 0; for (const x of a) { if (a.length < 3) a.push(x); n++; } n`, "3"},
+		{counter + `let out = []; for (const x of counter) out.push(x); [out, closed]`, "[[1, 2, 3], false]"},
+		{counter + `for (const x of counter) { if (x === 2) break; } closed`, "true"},
+		{counter + `let f = function () { for (const x of counter) return x; }; [f(), closed]`, "[1, true]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSpreadAndArrayFromUseIterators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{counter + `[0, ...counter]`, "[0, 1, 2, 3]"},
+		{counter + `Math.max(...counter)`, "3"},
+		{counter + `Array.from(counter)`, "[1, 2, 3]"},
+		{counter + `Array.from(counter, function (x, i) { return x * 10 + i; })`, "[10, 21, 32]"},
+		{`[..."a😀"].length`, "2"},
+		{`[...[1, , 3]]`, "[1, undefined, 3]"},
+		{`[...[1, 2].keys()]`, "[0, 1]"},
+		{`Array.from({ length: 2, 0: "a", 1: "b" })`, "[a, b]"},
+		{`let a = [1, 2]; a[Symbol.iterator] = function () { return [9][Symbol.iterator](); }; [...a]`, "[9]"},
+		{`let it = "ab"[Symbol.iterator](); [it.next().value, it.next().value, it.next().done]`, "[a, b, true]"},
+		{`let it = "ab"[Symbol.iterator](); it[Symbol.iterator]() === it`, "true"},
+		{`"ab"[Symbol.iterator]().toString()`, "[object String Iterator]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestIteratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[...5]`, "TypeError: 5 is not iterable"},
+		{`Math.max(...{})`, "TypeError: {} is not iterable"},
+		{`for (const x of null) {}`, "TypeError: null is not iterable"},
+		{`let o = {}; o[Symbol.iterator] = function () { return 1; }; [...o]`, "TypeError: Result of the Symbol.iterator method is not an object"},
+		{`let o = {}; o[Symbol.iterator] = function () { return { next() { return 1; } }; }; [...o]`, "TypeError: Iterator result 1 is not an object"},
+		{`for (const x of [1]) { x = 2; }`, "TypeError: Assignment to constant variable."},
+		{counter + `Array.from(counter, function (x) { return x.y.z; })`, "TypeError: Cannot read properties of undefined (reading 'z')"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,109 @@
+package interpreter_test
+
+import "testing"
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`JSON.parse("1")`, "1"},
+		{`JSON.parse("-2.5e3")`, "-2500"},
+		{`JSON.parse('"a\\nb"') === "a\nb"`, "true"},
+		{`JSON.parse('"\\u0041\\/"')`, "A/"},
+		{`JSON.parse('"\\uD83D\\uDE00"') === "😀"`, "true"},
+		{`JSON.parse('"\\uD83D"').length`, "1"},
+		{`JSON.parse(" true ")`, "true"},
+		{`JSON.parse("null")`, "null"},
+		{`JSON.parse("[1, [2, []], {}]")`, "[1, [2, []], {}]"},
+		{`JSON.parse('{"a": 1, "b": {"c": [true]}}').b.c[0]`, "true"},
+		{`let o = JSON.parse('{"a": 1, "b": 2, "a": 3}'); [o.a, o.b]`, "[3, 2]"},
+		{`JSON.parse('{"a": 1, "b": [1, 2]}', function (k, v) { return typeof v === "number" ? v * 2 : v; }).b`, "[2, 4]"},
+		{`JSON.parse('{"a": 1, "b": 2}', function (k, v) { return k === "a" ? undefined : v; }).a`, "undefined"},
+		{`JSON.parse("[1, 2, 3]", (k, v) => k === "1" ? undefined : v)`, "[1, , 3]"},
+		{`JSON.parse("5", (k, v) => k === "" ? [k, v] : v)`, "[, 5]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestJSONParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`JSON.parse("")`, "SyntaxError: Unexpected end of JSON input"},
+		{`JSON.parse("[1,]")`, "SyntaxError: Unexpected token ] in JSON at position 3"},
+		{`JSON.parse("{'a': 1}")`, "SyntaxError: Unexpected token ' in JSON at position 1"},
+		{`JSON.parse('{"a" 1}')`, "SyntaxError: Unexpected number in JSON at position 5"},
+		{`JSON.parse("01")`, "SyntaxError: Unexpected number in JSON at position 1"},
+		{`JSON.parse("1.")`, "SyntaxError: Unexpected end of JSON input"},
+		{`JSON.parse("tru")`, "SyntaxError: Unexpected end of JSON input"},
+		{`JSON.parse("nul!")`, "SyntaxError: Unexpected token ! in JSON at position 3"},
+		{`JSON.parse('"abc')`, "SyntaxError: Unterminated string in JSON at position 4"},
+		{`JSON.parse('"\\x"')`, "SyntaxError: Bad escaped character in JSON at position 2"},
+		{`JSON.parse('"\\u12"')`, "SyntaxError: Bad Unicode escape in JSON at position 1"},
+		{`JSON.parse('"a\tb"')`, "SyntaxError: Bad control character in string literal in JSON at position 2"},
+		{`JSON.parse("1 2")`, "SyntaxError: Unexpected number in JSON at position 2"},
+		{`JSON.parse("[".repeat(2e6) + "]".repeat(2e6))`, "RangeError: Maximum call stack size exceeded"},
+		{`JSON.parse('{"a":'.repeat(1e5) + "1" + "}".repeat(1e5))`, "RangeError: Maximum call stack size exceeded"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`JSON.stringify({ a: 1, b: "x", c: [true, null] })`, `{"a":1,"b":"x","c":[true,null]}`},
+		{`JSON.stringify([undefined, function () {}, NaN, Infinity])`, "[null,null,null,null]"},
+		{`JSON.stringify({ a: undefined, b: function () {}, c: 1 })`, `{"c":1}`},
+		{`JSON.stringify("a\"b\\c\n\u0001")`, `"a\"b\\c\n\u0001"`},
+		{`JSON.stringify("\uD800 😀")`, `"\ud800 😀"`},
+		{`JSON.stringify(-0)`, "0"},
+		{`JSON.stringify(1e21)`, "1e+21"},
+		{`JSON.stringify(0.1 + 0.2)`, "0.30000000000000004"},
+		{`JSON.stringify(undefined)`, "undefined"},
+		{`JSON.stringify(function () {})`, "undefined"},
+		{`JSON.stringify({ 2: "b", 1: "a", z: 0 })`, `{"1":"a","2":"b","z":0}`},
+		{`JSON.stringify([1, , 3])`, "[1,null,3]"},
+		{`JSON.stringify({ a: [1, { b: 2 }], c: {} }, null, 2)`, "{\n  \"a\": [\n    1,\n    {\n      \"b\": 2\n    }\n  ],\n  \"c\": {}\n}"},
+		{`JSON.stringify([1], null, "--")`, "[\n--1\n]"},
+		{`JSON.stringify([1], null, 20) === JSON.stringify([1], null, 10)`, "true"},
+		{`JSON.stringify([1], null, "abcdefghijkl")`, "[\nabcdefghij1\n]"},
+		{`JSON.stringify({ a: 1, b: 2, c: { a: 3, d: 4 } }, ["a", "c"])`, `{"a":1,"c":{"a":3}}`},
+		{`JSON.stringify({ 1: "x", 2: "y" }, [1])`, `{"1":"x"}`},
+		{`JSON.stringify({ a: 1, b: "s" }, (k, v) => typeof v === "number" ? v + 1 : v)`, `{"a":2,"b":"s"}`},
+		{`JSON.stringify({ a: 1, b: 2 }, (k, v) => k === "a" ? undefined : v)`, `{"b":2}`},
+		{`JSON.stringify({ toJSON: function (key) { return "key:" + key; } })`, `"key:"`},
+		{`JSON.stringify({ x: { toJSON: function (key) { return key; } } })`, `{"x":"x"}`},
+		{`let o = { a: 1 }; JSON.stringify([o, o])`, `[{"a":1},{"a":1}]`},
+		{`let o = {}; JSON.stringify([o, { a: o }, [o]])`, `[{},{"a":{}},[{}]]`},
+		{`JSON.stringify(JSON.parse("[".repeat(1000) + "]".repeat(1000))).length`, "2000"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestJSONStringifyErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let o = {}; o.self = o; JSON.stringify(o)", "TypeError: Converting circular structure to JSON"},
+		{"let a = [1]; a.push([a]); JSON.stringify(a)", "TypeError: Converting circular structure to JSON"},
+		{"let a = []; for (let k = 0; k < 1e5; k++) { a = [a]; } JSON.stringify(a)", "RangeError: Maximum call stack size exceeded"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,253 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testLimits evaluates input under limits with ctx, and returns the
+// result and what the script wrote to stdout.
+func testLimits(t *testing.T, ctx context.Context, limits interpreter.Limits, input string) (interpreter.Object, string) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	var stdout, stderr bytes.Buffer
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stderr)
+	interp.SetLimits(limits)
+	return interp.EvalContext(ctx, program), stdout.String()
+}
+
+// testLimitError checks that obj is the error a limit raised for cause.
+func testLimitError(t *testing.T, obj interpreter.Object, expected string, cause error) {
+	t.Helper()
+	if !testErrorObject(t, obj, expected) {
+		return
+	}
+	if err := obj.(*interpreter.Error).Cause; !errors.Is(err, cause) {
+		t.Errorf("wrong cause. expected=%v, got=%v", cause, err)
+	}
+}
+
+func TestCallDepthLimit(t *testing.T) {
+	recurse := `function f(n) { return n == 0 ? 0 : 1 + f(n - 1); } `
+	background := context.Background()
+
+	result, _ := testLimits(t, background, interpreter.Limits{}, recurse+"f(5000)")
+	testInspect(t, result, "5000")
+	result, _ = testLimits(t, background, interpreter.Limits{}, `function f() { return f(); } f()`)
+	testLimitError(t, result, "RangeError: Maximum call stack size exceeded", interpreter.ErrCallDepth)
+
+	limits := interpreter.Limits{MaxCallDepth: 50}
+	result, _ = testLimits(t, background, limits, recurse+"f(40)")
+	testInspect(t, result, "40")
+	result, _ = testLimits(t, background, limits, recurse+"f(60)")
+	testLimitError(t, result, "RangeError: Maximum call stack size exceeded", interpreter.ErrCallDepth)
+
+	// A match that would need more of the Go stack than is safe to take
+	// overflows as deep recursion does.
+	result, _ = testLimits(t, background, interpreter.Limits{}, `/a*$/.test("a".repeat(1e6))`)
+	testLimitError(t, result, "RangeError: Maximum call stack size exceeded", interpreter.ErrCallDepth)
+
+	// Code nested as deeply as the parser allows doesn't overflow the Go
+	// stack either.
+	deep := strings.Repeat("[", 9000) + strings.Repeat("]", 9000) + ".length + " +
+		strings.Repeat("-(", 4000) + "1" + strings.Repeat(")", 4000)
+	result, _ = testLimits(t, background, interpreter.Limits{}, deep)
+	testInspect(t, result, "2")
+
+	// Callbacks called by builtins count too.
+	result, _ = testLimits(t, background, limits, `function f(n) { return [n].map(x => f(x + 1)); } f(0)`)
+	testLimitError(t, result, "RangeError: Maximum call stack size exceeded", interpreter.ErrCallDepth)
+
+	// Once the calls have unwound, the error can be handled like any
+	// other, unless it's uncatchable.
+	input := `function f() { return f(); }
+new Promise(() => f()).catch(e => console.log("caught", e));
+console.log("after")`
+	result, stdout := testLimits(t, background, limits, input)
+	testInspect(t, result, "undefined")
+	if expected := "after\ncaught RangeError: Maximum call stack size exceeded\n"; stdout != expected {
+		t.Errorf("expected=%q, got=%q", expected, stdout)
+	}
+	limits.Uncatchable = true
+	result, stdout = testLimits(t, background, limits, input)
+	testLimitError(t, result, "RangeError: Maximum call stack size exceeded", interpreter.ErrCallDepth)
+	if stdout != "" {
+		t.Errorf("expected no output, got %q", stdout)
+	}
+}
+
+func TestStepLimit(t *testing.T) {
+	background := context.Background()
+	limits := interpreter.Limits{MaxSteps: 100000}
+
+	result, _ := testLimits(t, background, limits, `let n = 0; for (let i = 0; i < 100; i++) { n += i; } n`)
+	testInspect(t, result, "4950")
+	for _, input := range []string{
+		`while (true) {}`,
+		`for (;;) { let x = [1, 2, 3].map(n => n * 2); }`,
+		`function f() { while (true) {} } f()`,
+		`setTimeout(() => { while (true) {} })`,
+		// Backtracking takes steps as well.
+		`/(a+)+b/.test("a".repeat(40) + "c")`,
+		// So do the loops inside builtins.
+		`new Array(1e7).fill(0)`,
+		`let a = new Array(6e4).fill(1); a.concat(a)`,
+		`"x".repeat(1e6)`,
+		`new Uint8Array(1e6).indexOf(1)`,
+	} {
+		result, _ := testLimits(t, background, limits, input)
+		testLimitError(t, result, "RangeError: Execution step limit of 100000 exceeded", interpreter.ErrStepLimit)
+	}
+
+	// A catchable error in a promise callback rejects the promise it
+	// returned, as any error there would.
+	result, _ = testLimits(t, background, limits, `Promise.resolve().then(() => { while (true) {} })`)
+	testInspect(t, result, "Promise {<rejected> RangeError: Execution step limit of 100000 exceeded}")
+
+	// An uncatchable error stops everything else the script queued.
+	limits.Uncatchable = true
+	result, _ = testLimits(t, background, limits, `Promise.resolve().then(() => { while (true) {} })`)
+	testLimitError(t, result, "RangeError: Execution step limit of 100000 exceeded", interpreter.ErrStepLimit)
+	result, stdout := testLimits(t, background, limits, `
+Promise.resolve().then(() => console.log("microtask"));
+setTimeout(() => console.log("timeout"));
+new Promise(() => { while (true) {} }).catch(e => console.log("caught", e));
+console.log("not reached")`)
+	testLimitError(t, result, "RangeError: Execution step limit of 100000 exceeded", interpreter.ErrStepLimit)
+	if stdout != "" {
+		t.Errorf("expected no output, got %q", stdout)
+	}
+}
+
+func TestContextCancellation(t *testing.T) {
+	for _, input := range []string{
+		`while (true) {}`,
+		`setInterval(() => {}, 1)`,
+		// The event loop doesn't sleep until the timer is due.
+		`setTimeout(() => {}, 60000)`,
+		`/(a+)+b/.test("a".repeat(40) + "c")`,
+		`new Array(1e8).fill(0)`,
+	} {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		start := time.Now()
+		result, _ := testLimits(t, ctx, interpreter.Limits{}, input)
+		cancel()
+		testLimitError(t, result, "Error: Execution interrupted: context deadline exceeded", context.DeadlineExceeded)
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("input %q: took %v to stop", input, elapsed)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, stdout := testLimits(t, ctx, interpreter.Limits{Uncatchable: true}, `console.log("not reached")`)
+	testLimitError(t, result, "Error: Execution interrupted: context canceled", context.Canceled)
+	if stdout != "" {
+		t.Errorf("expected no output, got %q", stdout)
+	}
+}
+
+func TestHeapLimit(t *testing.T) {
+	limits := interpreter.Limits{MaxHeapBytes: 16 << 20, Uncatchable: true}
+	for _, input := range []string{
+		`let keep = []; while (true) { keep.push("x".repeat(10000) + keep.length); }`,
+		// Builtins that allocate a lot in a few steps are caught before
+		// they make the allocation.
+		`let a = [1]; while (true) { a = a.concat(a); }`,
+		`let s = "x"; while (true) { s += s; }`,
+		`"x".repeat(1e8)`,
+		`new Array(1e8).fill(0)`,
+		`new ArrayBuffer(1e8)`,
+	} {
+		result, _ := testLimits(t, context.Background(), limits, input)
+		testLimitError(t, result, "RangeError: Heap limit of 16777216 bytes exceeded", interpreter.ErrHeapLimit)
+	}
+}
+
+// TestEntryPoints checks that every way of running code counts what it
+// uses from nothing, under its own context, however what ran before it
+// ended.
+func TestEntryPoints(t *testing.T) {
+	limit := "RangeError: Execution step limit of 100000 exceeded"
+	newLimited := func(t *testing.T) (*interpreter.Interpreter, *interpreter.FakeClock) {
+		t.Helper()
+		interp := newInterpreter()
+		clock := interpreter.NewFakeClock(testNow)
+		interp.SetClock(clock)
+		interp.SetLimits(interpreter.Limits{MaxSteps: 100000, Uncatchable: true})
+		interp.SetModuleLoader(interpreter.MapLoader{"ok.js": `export default 1`, "loop.js": `while (true) {}`})
+		interp.SetRequireFS(fstest.MapFS{
+			"ok.js":   {Data: []byte(`module.exports = 1`)},
+			"loop.js": {Data: []byte(`while (true) {}`)},
+		})
+		result := interp.Eval(parser.New(lexer.New(`while (true) {}`)).ParseProgram())
+		testLimitError(t, result, limit, interpreter.ErrStepLimit)
+		return interp, clock
+	}
+	eval := func(interp *interpreter.Interpreter, input string) {
+		interp.Eval(parser.New(lexer.New(input)).ParseProgram())
+	}
+
+	t.Run("Import", func(t *testing.T) {
+		interp, _ := newLimited(t)
+		testInspect(t, interp.Import("ok.js"), "[object Module]")
+		testLimitError(t, interp.Import("loop.js"), limit, interpreter.ErrStepLimit)
+	})
+	t.Run("Require", func(t *testing.T) {
+		interp, _ := newLimited(t)
+		testInspect(t, interp.Require("./ok.js"), "1")
+		testLimitError(t, interp.Require("./loop.js"), limit, interpreter.ErrStepLimit)
+	})
+	t.Run("RunUntilIdle", func(t *testing.T) {
+		// A script stopped by its context leaves its timer behind, for
+		// RunUntilIdle to run under the limits rather than the context.
+		interp := newInterpreter()
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		result := interp.EvalContext(ctx, parser.New(lexer.New(`setTimeout(() => { while (true) {} }, 20); for (;;) {}`)).ParseProgram())
+		testLimitError(t, result, "Error: Execution interrupted: context deadline exceeded", context.DeadlineExceeded)
+		interp.SetLimits(interpreter.Limits{MaxSteps: 100000, Uncatchable: true})
+		testLimitError(t, interp.RunUntilIdle(), limit, interpreter.ErrStepLimit)
+	})
+	t.Run("AdvanceBy", func(t *testing.T) {
+		interp, clock := newLimited(t)
+		eval(interp, `setTimeout(() => { while (true) {} }, 1)`)
+		testLimitError(t, clock.AdvanceBy(time.Millisecond), limit, interpreter.ErrStepLimit)
+
+		// The steps of all the timers one call fires add up.
+		interp, clock = newLimited(t)
+		eval(interp, `setInterval(() => { for (let i = 0; i < 100; i++) {} }, 1)`)
+		testLimitError(t, clock.AdvanceBy(time.Second), limit, interpreter.ErrStepLimit)
+	})
+	t.Run("RunAllTimers", func(t *testing.T) {
+		interp, clock := newLimited(t)
+		eval(interp, `setTimeout(() => {}, 1)`)
+		if err := clock.RunAllTimers(); err != nil {
+			t.Errorf("unexpected error: %s", err.Message)
+		}
+		eval(interp, `setTimeout(() => { while (true) {} }, 1)`)
+		testLimitError(t, clock.RunAllTimers(), limit, interpreter.ErrStepLimit)
+	})
+
+	// The context variants stop when their context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	interp, _ := newLimited(t)
+	testLimitError(t, interp.ImportContext(ctx, "ok.js"), "Error: Execution interrupted: context canceled", context.Canceled)
+	testLimitError(t, interp.RequireContext(ctx, "./ok.js"), "Error: Execution interrupted: context canceled", context.Canceled)
+}
@@ -0,0 +1,121 @@
+package interpreter_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestMathFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Math.abs(-2)", "2"},
+		{`Math.abs("-3")`, "3"},
+		{"Math.floor(-1.5)", "-2"},
+		{"Math.ceil(1.2)", "2"},
+		{"Math.round(2.5)", "3"},
+		{"Math.round(-2.5)", "-2"},
+		{"Math.round(0.49999999999999994)", "0"},
+		{"1 / Math.round(-0.2)", "-Infinity"},
+		{"Math.trunc(-4.7)", "-4"},
+		{"Math.sign(-3)", "-1"},
+		{"Math.sign(0)", "0"},
+		{"Math.max(1, 3, 2)", "3"},
+		{`Math.max(1, "5")`, "5"},
+		{"Math.max()", "-Infinity"},
+		{"Math.min()", "Infinity"},
+		{"Math.max(1, NaN, 3)", "NaN"},
+		{"1 / Math.min(0, -0)", "-Infinity"},
+		{"1 / Math.max(-0, 0)", "Infinity"},
+		{"Math.pow(2, 10)", "1024"},
+		{"Math.pow(1, Infinity)", "NaN"},
+		{"Math.pow(1, NaN)", "NaN"},
+		{"Math.sqrt(16)", "4"},
+		{"Math.sqrt(-1)", "NaN"},
+		{"Math.cbrt(-27)", "-3"},
+		{"Math.hypot(3, 4)", "5"},
+		{"Math.hypot()", "0"},
+		{"Math.hypot(NaN, Infinity)", "Infinity"},
+		{"Math.log(Math.E)", "1"},
+		{"Math.log10(1000)", "3"},
+		{"Math.log2(8)", "3"},
+		{"Math.exp(0)", "1"},
+		{"Math.sin(0)", "0"},
+		{"Math.cos(0)", "1"},
+		{"Math.atan2(0, -1) === Math.PI", "true"},
+		{"Math.clz32(1)", "31"},
+		{"Math.clz32(0)", "32"},
+		{"Math.fround(5.05)", "5.050000190734863"},
+		{"Math.imul(0xffffffff, 5)", "-5"},
+		{"Math.imul(3, 4)", "12"},
+		{"Math.abs()", "NaN"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestMathConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"Math.PI", math.Pi},
+		{"Math.E", math.E},
+		{"Math.LN2", math.Ln2},
+		{"Math.LN10", math.Ln10},
+		{"Math.LOG2E", math.Log2E},
+		{"Math.LOG10E", math.Log10E},
+		{"Math.SQRT2", math.Sqrt2},
+		{"Math.SQRT1_2", math.Sqrt2 / 2},
+		// The constants are read-only.
+		{"Math.PI = 3; Math.PI", math.Pi},
+		{`Math["E"] = 1; Math.E`, math.E},
+	}
+
+	for _, tt := range tests {
+		testNumberObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestMathRandom(t *testing.T) {
+	program := parser.New(lexer.New("[Math.random(), Math.random(), Math.random()]")).ParseProgram()
+	run := func(seed uint64) string {
+		interp := newInterpreter()
+		interp.SetRandomSeed(seed)
+		result, ok := interp.Eval(program).(*interpreter.Array)
+		if !ok {
+			t.Fatalf("expected an array, got %T", result)
+		}
+		for _, e := range result.Elements {
+			n := e.(*interpreter.Number).Value
+			if n < 0 || n >= 1 {
+				t.Errorf("Math.random() returned %g, want a number in [0, 1)", n)
+			}
+		}
+		return result.Inspect()
+	}
+
+	if first, second := run(42), run(42); first != second {
+		t.Errorf("same seed gave different numbers: %s and %s", first, second)
+	}
+	if first, second := run(1), run(2); first == second {
+		t.Errorf("different seeds gave the same numbers: %s", first)
+	}
+}
+
+func TestMathKeyOrder(t *testing.T) {
+	// Math is defined in the same order every time, so it logs the same.
+	expected := `{"E":2.718281828459045,"LN10":2.302585092994046,"LN2":0.6931471805599453,` +
+		`"LOG10E":0.4342944819032518,"LOG2E":1.4426950408889634,"PI":3.141592653589793,` +
+		`"SQRT1_2":0.7071067811865476,"SQRT2":1.4142135623730951}`
+	for range 10 {
+		testInspect(t, testEval(t, "JSON.stringify(Math)"), expected)
+	}
+}
@@ -0,0 +1,246 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testImport imports main.js from modules, and returns the result and what
+// the modules printed.
+func testImport(t *testing.T, modules interpreter.MapLoader) (interpreter.Object, string) {
+	t.Helper()
+	var stdout bytes.Buffer
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stdout)
+	interp.SetModuleLoader(modules)
+	return interp.Import("main.js"), stdout.String()
+}
+
+func TestModules(t *testing.T) {
+	tests := []struct {
+		modules  interpreter.MapLoader
+		expected string
+	}{
+		{interpreter.MapLoader{
+			"main.js": `import { a, b as c } from "./lib.js"; console.log(a, c)`,
+			"lib.js":  `export let a = 1; const b = 2; export { b }`,
+		}, "1 2\n"},
+		{interpreter.MapLoader{
+			"main.js": `import greet, { name } from "./lib.js"; console.log(greet(name), greet)`,
+			"lib.js":  `export const name = "x"; export default function (n) { return "hi " + n; }`,
+		}, "hi x [Function: default]\n"},
+		{interpreter.MapLoader{
+			"main.js": `import value from "./lib.js"; console.log(value)`,
+			"lib.js":  `export default 6 * 7`,
+		}, "42\n"},
+		{interpreter.MapLoader{
+			"main.js": `import * as ns from "./lib.js"; console.log(ns, ns.a, ns.missing, ns[Symbol.toStringTag])`,
+			"lib.js":  `export let b = 2; export let a = 1; export function f() {}`,
+		}, "[Module: null prototype] { a: 1, b: 2, f: [Function: f] } 1 undefined Module\n"},
+		{interpreter.MapLoader{
+			"main.js":  `import * as ns from "./empty.js"; console.log(ns)`,
+			"empty.js": `let hidden = 1`,
+		}, "[Module: null prototype] {}\n"},
+
+		// Imports are live views of the exporting module's variables.
+		{interpreter.MapLoader{
+			"main.js":    `import { count, increment } from "./counter.js"; import * as ns from "./counter.js"; increment(); increment(); console.log(count, ns.count)`,
+			"counter.js": `export let count = 0; export function increment() { count++; }`,
+		}, "2 2\n"},
+
+		// Re-exports.
+		{interpreter.MapLoader{
+			"main.js":  `import { a, renamed, b, ns } from "./index.js"; console.log(a, renamed, b, ns.a)`,
+			"index.js": `export { a, a as renamed } from "./a.js"; export * from "./b.js"; export * as ns from "./a.js"`,
+			"a.js":     `export const a = "a"; export default "not passed on"`,
+			"b.js":     `export const b = "b"; export default "not passed on"`,
+		}, "a a b a\n"},
+		{interpreter.MapLoader{
+			"main.js":  `import * as ns from "./index.js"; console.log(ns)`,
+			"index.js": `import { a } from "./a.js"; export { a }; export * from "./a.js"; export * from "./b.js"`,
+			"a.js":     `export const a = 1; export const shared = 1; export default 1`,
+			"b.js":     `export const shared = 2`,
+		}, "[Module: null prototype] { a: 1 }\n"},
+		{interpreter.MapLoader{
+			"main.js": `import { "kebab-name" as x } from "./lib.js"; console.log(x)`,
+			"lib.js":  `const y = "quoted"; export { y as "kebab-name" }`,
+		}, "quoted\n"},
+
+		// Each module runs once, after the modules it imports.
+		{interpreter.MapLoader{
+			"main.js":   `import "./a.js"; import "./b.js"; console.log("main")`,
+			"a.js":      `import "./shared.js"; console.log("a")`,
+			"b.js":      `import "./shared.js"; console.log("b")`,
+			"shared.js": `console.log("shared")`,
+		}, "shared\na\nb\nmain\n"},
+		{interpreter.MapLoader{
+			"main.js":      `import { f } from "./lib/a.js"; console.log(f())`,
+			"lib/a.js":     `import { g } from "../lib/sub/b.js"; export const f = () => g()`,
+			"lib/sub/b.js": `export function g() { return "nested"; }`,
+		}, "nested\n"},
+
+		// Cycles: b.js runs first, and can call a.js's hoisted function,
+		// but not yet read its variables.
+		{interpreter.MapLoader{
+			"main.js": `import "./a.js"`,
+			"a.js":    `import { fromB } from "./b.js"; console.log("a", fromB); export let value = "a value"; export function hello() { return "hello from a"; }`,
+			"b.js":    `import { hello, value } from "./a.js"; import * as a from "./a.js"; console.log("b", hello(), a); export const fromB = "b value"; export function later() { return value; }`,
+		}, "b hello from a [Module: null prototype] {\n  hello: [Function: hello],\n  value: <uninitialized>\n}\na b value\n"},
+
+		// Top-level await holds back the modules importing the module.
+		{interpreter.MapLoader{
+			"main.js": `import { value } from "./slow.js"; console.log("main", value)`,
+			"slow.js": `console.log("start"); export const value = await new Promise(resolve => setTimeout(() => resolve("done"), 10)); console.log("end")`,
+		}, "start\nend\nmain done\n"},
+
+		// Dynamic import.
+		{interpreter.MapLoader{
+			"main.js": `console.log("before"); import("./lib.js").then(ns => console.log(ns.default)); console.log("after")`,
+			"lib.js":  `console.log("lib"); export default "value"`,
+		}, "before\nafter\nlib\nvalue\n"},
+		{interpreter.MapLoader{
+			"main.js":      `const { x } = await import("./dir/lib.js"); console.log(x)`,
+			"dir/lib.js":   `export const x = await import("./other.js").then(ns => ns.y)`,
+			"dir/other.js": `export const y = "relative to dir"`,
+		}, "relative to dir\n"},
+		{interpreter.MapLoader{
+			"main.js": `import("./missing.js").catch(e => console.log(e)); import("./bad.js").catch(e => console.log(e))`,
+			"bad.js":  `export const x = missing`,
+		}, "Error: Cannot find module 'missing.js' imported from main.js\nReferenceError: missing is not defined\n"},
+	}
+
+	for _, tt := range tests {
+		result, stdout := testImport(t, tt.modules)
+		if err, ok := result.(*interpreter.Error); ok {
+			t.Errorf("modules %v: %s", tt.modules, err.Message)
+			continue
+		}
+		if stdout != tt.expected {
+			t.Errorf("modules %v: expected %q, got %q", tt.modules, tt.expected, stdout)
+		}
+	}
+}
+
+func TestModuleErrors(t *testing.T) {
+	tests := []struct {
+		modules         interpreter.MapLoader
+		expectedMessage string
+	}{
+		{interpreter.MapLoader{
+			"main.js": `import { nope } from "./lib.js"`,
+			"lib.js":  `export const yes = 1`,
+		}, "SyntaxError: The requested module './lib.js' does not provide an export named 'nope'"},
+		{interpreter.MapLoader{
+			"main.js":  `import x from "./lib.js"`,
+			"lib.js":   `export * from "./other.js"`,
+			"other.js": `export default 1`,
+		}, "SyntaxError: The requested module './lib.js' does not provide an export named 'default'"},
+		{interpreter.MapLoader{
+			"main.js":  `import { shared } from "./index.js"`,
+			"index.js": `export * from "./a.js"; export * from "./b.js"`,
+			"a.js":     `export const shared = 1`,
+			"b.js":     `export const shared = 2`,
+		}, "SyntaxError: The requested module './index.js' contains conflicting star exports for name 'shared'"},
+		{interpreter.MapLoader{
+			"main.js": `import "./missing.js"`,
+		}, "Error: Cannot find module 'missing.js' imported from main.js"},
+		{interpreter.MapLoader{}, "Error: Cannot find module 'main.js'"},
+		{interpreter.MapLoader{
+			"main.js": `import "./lib.js"`,
+			"lib.js":  `export let = 1`,
+		}, "SyntaxError: unexpected = in binding pattern (in lib.js)"},
+		{interpreter.MapLoader{
+			"main.js": `export const a = 1; export { a }`,
+		}, "SyntaxError: Duplicate export of 'a'"},
+		{interpreter.MapLoader{
+			"main.js": `export { undeclared }`,
+		}, "SyntaxError: Export 'undeclared' is not defined in module"},
+		{interpreter.MapLoader{
+			"main.js": `import { count } from "./lib.js"; count = 1`,
+			"lib.js":  `export let count = 0`,
+		}, "TypeError: Assignment to constant variable."},
+		{interpreter.MapLoader{
+			"main.js": `import * as ns from "./lib.js"; ns.count = 1`,
+			"lib.js":  `export let count = 0`,
+		}, "TypeError: Cannot assign to read only property 'count' of object '[object Module]'"},
+		{interpreter.MapLoader{
+			"main.js": `import * as ns from "./lib.js"; ns.other = 1`,
+			"lib.js":  `export let count = 0`,
+		}, "TypeError: Cannot add property other, object is not extensible"},
+		{interpreter.MapLoader{
+			"main.js": `import "./a.js"`,
+			"a.js":    `import { b } from "./b.js"; export const a = 1`,
+			"b.js":    `import { a } from "./a.js"; export const b = a`,
+		}, "ReferenceError: Cannot access 'a' before initialization"},
+		{interpreter.MapLoader{
+			"main.js": `import "./lib.js"; console.log("not run")`,
+			"lib.js":  `await null; null.x`,
+		}, "TypeError: Cannot read properties of null (reading 'x')"},
+	}
+
+	for _, tt := range tests {
+		result, stdout := testImport(t, tt.modules)
+		testErrorObject(t, result, tt.expectedMessage)
+		if stdout != "" {
+			t.Errorf("modules %v: expected no output, got %q", tt.modules, stdout)
+		}
+	}
+}
+
+func TestModuleEvaluatedOnce(t *testing.T) {
+	var stdout bytes.Buffer
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stdout)
+	interp.SetModuleLoader(interpreter.MapLoader{
+		"lib.js": `console.log("lib runs"); export let n = 0; export const inc = () => ++n`,
+	})
+
+	first := interp.Import("lib.js")
+	second := interp.Import("./lib.js")
+	if first != second {
+		t.Errorf("expected the same namespace object, got %v and %v", first, second)
+	}
+	p := parser.New(lexer.New(`import { inc, n } from "lib.js"; inc(); n`))
+	testInspect(t, interp.Eval(p.ParseModule()), "1")
+	testInspect(t, first, "[object Module]")
+	if stdout.String() != "lib runs\n" {
+		t.Errorf("expected lib.js to run once, got %q", stdout.String())
+	}
+
+	// A module that failed fails again with the same error.
+	interp.SetModuleLoader(interpreter.MapLoader{"bad.js": `missing`})
+	testErrorObject(t, interp.Import("bad.js"), "ReferenceError: missing is not defined")
+	testErrorObject(t, interp.Import("bad.js"), "ReferenceError: missing is not defined")
+}
+
+func TestModuleWithoutLoader(t *testing.T) {
+	testInspect(t, testModule(t, `export const x = 1; x + 1`), "2")
+	testErrorObject(t, testModule(t, `import "./lib.js"`), "Error: Cannot find module './lib.js': no module loader is set")
+	stdout, _ := testConsole(t, `import("./lib.js").catch(e => console.log(e))`)
+	if expected := "Error: Cannot find module './lib.js': no module loader is set\n"; stdout != expected {
+		t.Errorf("expected %q, got %q", expected, stdout)
+	}
+}
+
+func TestFSLoader(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app/main.js":     {Data: []byte(`import { join } from "../util/strings.js"; export default join(["a", "b"])`)},
+		"util/strings.js": {Data: []byte(`export const join = parts => parts.join("/")`)},
+	}
+	interp := newInterpreter()
+	interp.SetModuleLoader(interpreter.NewFSLoader(fsys))
+
+	ns := interp.Import("/app/main.js")
+	if err, ok := ns.(*interpreter.Error); ok {
+		t.Fatal(err.Message)
+	}
+	p := parser.New(lexer.New(`import value from "app/main.js"; value`))
+	testInspect(t, interp.Eval(p.ParseModule()), "a/b")
+	testErrorObject(t, interp.Import("app/nope.js"), "Error: Cannot find module 'app/nope.js'")
+	testErrorObject(t, interp.Import("../outside.js"), "Error: Cannot find module '../outside.js'")
+}
@@ -0,0 +1,88 @@
+package interpreter_test
+
+import "testing"
+
+func TestNumberToString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"1e21", "1e+21"},
+		{"1e20", "100000000000000000000"},
+		{"1e-7", "1e-7"},
+		{"0.000001", "0.000001"},
+		{"1.5e-10", "1.5e-10"},
+		{"-0", "0"},
+		{"123456789012345680000", "123456789012345680000"},
+		{"String(1.25)", "1.25"},
+		{"(255).toString(16)", "ff"},
+		{"(255).toString(2)", "11111111"},
+		{"(-255).toString(36)", "-73"},
+		{"(0.5).toString(2)", "0.1"},
+		{"(1.005).toFixed(2)", "1.00"},
+		{"(2.5).toFixed(0)", "3"},
+		{"(-0.0001).toFixed(2)", "-0.00"},
+		{"(1234.5678).toFixed(1)", "1234.6"},
+		{"(0).toFixed(2)", "0.00"},
+		{"(1e21).toFixed(2)", "1e+21"},
+		{"(1234567.8912).toLocaleString()", "1,234,567.891"},
+		{"(-1234.5).toLocaleString()", "-1,234.5"},
+		{"(0.0001).toLocaleString()", "0"},
+		{"(100).toLocaleString()", "100"},
+		{"[NaN.toLocaleString(), (-Infinity).toLocaleString()]", "[NaN, -∞]"},
+		{"({ toString() { return 'o'; } }).toLocaleString()", "o"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestNumberFunctions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Number("")`, "0"},
+		{`Number(" 12 ")`, "12"},
+		{`Number("0b101")`, "5"},
+		{`Number("-0x10")`, "NaN"},
+		{`Number("12px")`, "NaN"},
+		{"Number(null)", "0"},
+		{"Number()", "0"},
+		{`parseInt("42px")`, "42"},
+		{`parseInt("0x1F")`, "31"},
+		{`parseInt("ff", 16)`, "255"},
+		{`parseInt("  -12")`, "-12"},
+		{`parseInt("z", 37)`, "NaN"},
+		{`parseInt("")`, "NaN"},
+		{`parseFloat("3.14abc")`, "3.14"},
+		{`parseFloat(".5e1x")`, "5"},
+		{`parseFloat("-Infinityx")`, "-Infinity"},
+		{`parseFloat("e5")`, "NaN"},
+		{`Number.parseInt === parseInt`, "true"},
+		{`isNaN("abc")`, "true"},
+		{`Number.isNaN("abc")`, "false"},
+		{"Number.isInteger(5.0)", "true"},
+		{"Number.isInteger(5.5)", "false"},
+		{"Number.isSafeInteger(Number.MAX_SAFE_INTEGER + 1)", "false"},
+		{"Number.MAX_SAFE_INTEGER", "9007199254740991"},
+		{"Number.EPSILON > 0", "true"},
+		{"isFinite(Infinity)", "false"},
+		{`isFinite("12")`, "true"},
+		{`Boolean("")`, "false"},
+		{`Boolean("0")`, "true"},
+		{"Boolean(NaN)", "false"},
+		{"(true).toString()", "true"},
+		{"({}).toString()", "[object Object]"},
+		{"[1, [2, [3]]].toString()", "1,2,3"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+
+	testErrorObject(t, testEval(t, "(1).toString(1)"), "RangeError: toString() radix must be between 2 and 36")
+	testErrorObject(t, testEval(t, "(1).toFixed(101)"), "RangeError: toFixed() digits argument must be between 0 and 100")
+}
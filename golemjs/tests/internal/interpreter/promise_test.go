@@ -0,0 +1,147 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestPromises(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Promise.resolve().then(() => console.log("second")); console.log("first")`, "first\nsecond\n"},
+		{`new Promise(resolve => resolve(1)).then(v => v + 1).then(v => console.log(v))`, "2\n"},
+		{`new Promise((resolve, reject) => { reject("no"); resolve("yes"); }).then(null, r => console.log("rejected", r))`, "rejected no\n"},
+		{`new Promise(() => null.x).catch(e => console.log(e))`, "TypeError: Cannot read properties of null (reading 'x')\n"},
+		{`Promise.resolve(1).then(2).then(v => console.log(v))`, "1\n"},
+		{`Promise.reject("r").then(v => v).catch(r => console.log("caught", r))`, "caught r\n"},
+		{`Promise.resolve(Promise.resolve(5)).then(v => console.log(v))`, "5\n"},
+		{`Promise.resolve({ then(resolve) { resolve("thenable"); } }).then(v => console.log(v))`, "thenable\n"},
+		{`let p = Promise.resolve(1); console.log(Promise.resolve(p) === p)`, "true\n"},
+		{`let p = Promise.resolve().then(() => p); p.catch(e => console.log(e))`, "TypeError: Chaining cycle detected for promise #<Promise>\n"},
+		{`queueMicrotask(() => console.log("microtask")); console.log("script")`, "script\nmicrotask\n"},
+
+		// Reactions run in the order they were queued, a level at a time.
+		{`
+let order = [];
+Promise.resolve().then(() => order.push(1)).then(() => order.push(3));
+Promise.resolve().then(() => order.push(2)).then(() => order.push(4));
+Promise.resolve().then(() => Promise.resolve()).then(() => console.log(order));
+`, "[ 1, 2, 3, 4 ]\n"},
+
+		{`Promise.resolve(1).finally(() => console.log("finally")).then(v => console.log(v))`, "finally\n1\n"},
+		{`Promise.reject(1).finally(() => {}).catch(r => console.log("still", r))`, "still 1\n"},
+		{`Promise.resolve(1).finally(() => Promise.reject(2)).catch(r => console.log("replaced", r))`, "replaced 2\n"},
+		{`let r = Promise.withResolvers(); r.promise.then(v => console.log(v)); r.resolve("resolved")`, "resolved\n"},
+
+		{`Promise.all([1, Promise.resolve(2), new Promise(r => r(3))]).then(v => console.log(v))`, "[ 1, 2, 3 ]\n"},
+		{`Promise.all([]).then(v => console.log(v))`, "[]\n"},
+		{`Promise.all([1, Promise.reject("x")]).catch(r => console.log("rejected", r))`, "rejected x\n"},
+		{`Promise.all(5).catch(r => console.log(r))`, "TypeError: 5 is not iterable\n"},
+		{`Promise.allSettled([Promise.reject(1)]).then(v => console.log(v))`, "[ { status: 'rejected', reason: 1 } ]\n"},
+		{`Promise.any([Promise.reject(1), Promise.resolve(2)]).then(v => console.log(v))`, "2\n"},
+		{`Promise.any([Promise.reject(1)]).catch(e => console.log(e.name, e.message, e.errors))`, "AggregateError All promises were rejected [ 1 ]\n"},
+		{`Promise.any([]).catch(e => console.log(String(e), "" + e, e.toString()))`, "AggregateError: All promises were rejected AggregateError: All promises were rejected AggregateError: All promises were rejected\n"},
+		{`Promise.race([new Promise(() => {}), Promise.resolve("fast")]).then(v => console.log(v))`, "fast\n"},
+
+		// Errors the interpreter raises are caught as error objects.
+		{`new Promise(() => null.x).catch(e => console.log(e.name, "|", e.message, "|", String(e), "|", typeof e))`, "TypeError | Cannot read properties of null (reading 'x') | TypeError: Cannot read properties of null (reading 'x') | object\n"},
+		{`new Promise(() => "x".repeat(-1)).catch(e => console.log(e.name, e.message))`, "RangeError Invalid count value: -1\n"},
+		{`new Promise(() => missing).catch(e => console.log(e.name, e.message))`, "ReferenceError missing is not defined\n"},
+		{`let p = Promise.resolve().then(() => p); p.catch(e => console.log(e.name, e.message))`, "TypeError Chaining cycle detected for promise #<Promise>\n"},
+		{`function f() { return f(); } new Promise(f).catch(e => console.log(e.name, e.message))`, "RangeError Maximum call stack size exceeded\n"},
+		{`let a = new Promise(() => null.x); let b = a.catch(e => e); a.catch(e => b.then(f => console.log(e === f)))`, "true\n"},
+		{`Promise.allSettled([new Promise(() => null.x)]).then(v => console.log(v[0].reason, [v[0].reason]))`, "TypeError: Cannot read properties of null (reading 'x') [ TypeError: Cannot read properties of null (reading 'x') ]\n"},
+
+		{`console.log(Promise.resolve(1), Promise.reject(2).catch(() => {}), new Promise(() => {}))`, "Promise { 1 } Promise { <pending> } Promise { <pending> }\n"},
+		{`let p = Promise.reject("r"); p.catch(() => {}); console.log(p)`, "Promise { <rejected> 'r' }\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout)
+		}
+	}
+}
+
+func TestPromiseErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`Promise(() => {})`, "TypeError: Constructor Promise requires 'new'"},
+		{`new Promise(1)`, "TypeError: Promise resolver 1 is not a function"},
+		{`let then = Promise.prototype.then; then(() => {})`, "TypeError: Method Promise.prototype.then called on incompatible receiver null"},
+		{`let all = Promise.all; all([])`, "TypeError: Promise.all called on non-object"},
+		{`queueMicrotask(1)`, `TypeError: The "callback" argument must be of type function. Received 1`},
+		{`queueMicrotask(() => missing)`, "ReferenceError: missing is not defined"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestUnhandledRejections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`Promise.reject("boom")`, "Uncaught (in promise) boom\n"},
+		{`Promise.resolve().then(() => missing)`, "Uncaught (in promise) ReferenceError: missing is not defined\n"},
+		{`Promise.reject(1).catch(() => {})`, ""},
+		// A handler attached by a later microtask is still in time.
+		{`let p = Promise.reject(1); Promise.resolve().then(() => p.catch(() => {}))`, ""},
+		// Once handled, a rejection passes down the chain.
+		{`Promise.reject(1).then(v => v)`, "Uncaught (in promise) 1\n"},
+	}
+
+	for _, tt := range tests {
+		_, stderr := testConsole(t, tt.input)
+		if stderr != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stderr)
+		}
+	}
+}
+
+func TestUnhandledRejectionHandler(t *testing.T) {
+	interp := newInterpreter()
+	var stdout, stderr bytes.Buffer
+	interp.SetOutput(&stdout, &stderr)
+	var reasons []string
+	interp.SetUnhandledRejectionHandler(func(reason interpreter.Object) {
+		reasons = append(reasons, reason.Inspect())
+	})
+
+	interp.Eval(parser.New(lexer.New(`Promise.reject("a"); Promise.reject("b")`)).ParseProgram())
+	if len(reasons) != 2 || reasons[0] != "a" || reasons[1] != "b" {
+		t.Errorf("expected the reasons [a b], got %v", reasons)
+	}
+	if stderr.Len() != 0 {
+		t.Errorf("expected nothing on stderr, got %q", stderr.String())
+	}
+}
+
+func TestRunUntilIdle(t *testing.T) {
+	interp := newInterpreter()
+	var stdout, stderr bytes.Buffer
+	interp.SetOutput(&stdout, &stderr)
+
+	// Eval runs the loop until idle, so the promise chain has settled by
+	// the time it returns.
+	interp.Eval(parser.New(lexer.New(`
+let log = [];
+Promise.resolve(1).then(v => log.push(v)).then(() => log.push(2));
+`)).ParseProgram())
+	testInspect(t, interp.Eval(parser.New(lexer.New(`log`)).ParseProgram()), "[1, 2]")
+
+	if err := interp.RunUntilIdle(); err != nil {
+		t.Errorf("expected an idle loop to have nothing to do, got %s", err.Message)
+	}
+}
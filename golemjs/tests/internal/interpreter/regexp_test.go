@@ -0,0 +1,156 @@
+package interpreter_test
+
+import "testing"
+
+func TestRegExp(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/ab+c/gi`, "/ab+c/gi"},
+		{`new RegExp("a+", "g")`, "/a+/g"},
+		{`RegExp("a/b")`, `/a\/b/`},
+		{`new RegExp(/x/gi, "m")`, "/x/m"},
+		{`new RegExp(/x/gi).flags`, "gi"},
+		{`String(new RegExp(""))`, "/(?:)/"},
+		{`String(new RegExp("a\nb"))`, `/a\nb/`},
+		{`let r = /x/dgimsuy; [r.hasIndices, r.global, r.ignoreCase, r.multiline, r.dotAll, r.unicode, r.sticky]`, "[true, true, true, true, true, true, true]"},
+		{`let r = /x/; [r.global, r.flags, r.source, r.lastIndex]`, "[false, , x, 0]"},
+		{`/x/ydg.flags`, "dgy"},
+		{`typeof /x/`, "object"},
+		{`/x/ instanceof RegExp`, "true"},
+		{`/x/ === /x/`, "false"},
+		{`let f = () => /x/; f() === f()`, "false"},
+		{`/a/.test("cat")`, "true"},
+		{`/^a/.test("cat")`, "false"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRegExpExec(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/(\d+)-(\d+)/.exec("on 10-20")`, "[10-20, 10, 20]"},
+		{`let m = /(\d+)-(\d+)/.exec("on 10-20"); [m.index, m.input, m.groups]`, "[3, on 10-20, undefined]"},
+		{`/x/.exec("abc")`, "null"},
+		{`/(a)|(b)/.exec("b")`, "[b, undefined, b]"},
+		{`let m = /(?<year>\d{4})-(?<month>\d\d)/.exec("2024-05"); [m.groups.year, m.groups.month]`, "[2024, 05]"},
+		{`/(?<a>x)/.exec("x").groups`, "{a: x}"},
+		{`let m = /b(?<c>c)?/d.exec("abc"); [m.indices[0], m.indices[1], m.indices.groups.c]`, "[[1, 3], [2, 3], [2, 3]]"},
+		{`let r = /o/g; let s = "foo"; [r.exec(s).index, r.lastIndex, r.exec(s).index, r.lastIndex, r.exec(s), r.lastIndex]`, "[1, 2, 2, 3, null, 0]"},
+		{`let r = /o/; r.exec("foo"); r.exec("foo"); r.lastIndex`, "0"},
+		{`let r = /o/y; [r.test("foo"), r.lastIndex]`, "[false, 0]"},
+		{`let r = /o/y; r.lastIndex = 1; [r.test("foo"), r.lastIndex, r.test("foo"), r.test("foo")]`, "[true, 2, true, false]"},
+		{`let r = /a/g; r.lastIndex = 10; [r.test("aaa"), r.lastIndex]`, "[false, 0]"},
+		{`/(?<=\$)\d+/.exec("cost: $42")[0]`, "42"},
+		{`/(?<!\$)\b\d+/.exec("$4 or 5")[0]`, "5"},
+		{`/(a+)b\1/.exec("aabaa")`, "[aabaa, aa]"},
+		{`/(?<q>['"]).*?\k<q>/.exec("say 'hi' now")[0]`, "'hi'"},
+		{`/^b$/m.test("a\nb\nc")`, "true"},
+		{`/a.c/.test("a\nc")`, "false"},
+		{`/a.c/s.test("a\nc")`, "true"},
+		{`/ß/i.test("SS")`, "false"},
+		{`/HELLO/i.exec("say hello")[0]`, "hello"},
+		{`/^.$/.test("😀")`, "false"},
+		{`/^.$/u.test("😀")`, "true"},
+		{`/\p{Lu}+/u.exec("abCDe")[0]`, "CD"},
+		{`let r = /a/; r.exec = function (s) { return null; }; r.test("a")`, "false"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringRegExpMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a1b22c333".match(/\d+/g)`, "[1, 22, 333]"},
+		{`"abc".match(/\d/g)`, "null"},
+		{`let m = "a1b2".match(/[a-z](\d)/); [m[0], m[1], m.index]`, "[a1, 1, 0]"},
+		{`"a.b".match(".")[0]`, "a"},
+		{`"xAx".match("a")`, "null"},
+		{`"".match(/x*/g)`, "[]"},
+		{`"abc".match(/(?:)/g)`, "[, , , ]"},
+		{`[..."a1b2".matchAll(/[a-z](\d)/g)].map(m => m[1] + m.index)`, "[10, 22]"},
+		{`[..."a.b.c".matchAll(".")].length`, "5"},
+		{`let r = /a/g; r.lastIndex = 1; [..."aaa".matchAll(r)].length + "," + r.lastIndex`, "2,1"},
+		{`"hello".search(/l+/)`, "2"},
+		{`"hello".search(/z/)`, "-1"},
+		{`"a+b".search("+")`, "SyntaxError"},
+		{`let r = /l/g; r.lastIndex = 3; "hello".search(r) + "," + r.lastIndex`, "2,3"},
+		{`"10-20".replace(/(\d+)-(\d+)/, "$2-$1")`, "20-10"},
+		{`"aaa".replace(/a/, "b")`, "baa"},
+		{`"aaa".replace(/a/g, "b")`, "bbb"},
+		{`"2024-05".replace(/(?<y>\d+)-(?<m>\d+)/, "$<m>/$<y>")`, "05/2024"},
+		{`"abc".replace(/b/, "[$&|$` + "`" + `|$'|$$|$1|$<x>]")`, "a[b|a|c|$|$1|$<x>]c"},
+		{`"abc".replace(/(b)/, "$01$10")`, "abb0c"},
+		{`"a1b2".replace(/\d/g, (m, off, s) => "<" + m + off + s.length + ">")`, "a<114>b<234>"},
+		{`"x-y".replace(/(?<l>\w)-(?<r>\w)/, (...args) => args[args.length - 1].r)`, "y"},
+		{`"abc".replace(/x*/g, "-")`, "-a-b-c-"},
+		{`"abc".replaceAll(/b/g, "X")`, "aXc"},
+		{`"a.b.c".replaceAll(".", "!")`, "a!b!c"},
+		{`"a1b22".split(/\d+/)`, "[a, b, ]"},
+		{`"a1b22".split(/(\d)+/)`, "[a, 1, b, 2, ]"},
+		{`"abc".split(/(?:)/)`, "[a, b, c]"},
+		{`"a, b,c".split(/\s*,\s*/, 2)`, "[a, b]"},
+		{`"".split(/x/)`, "[]"},
+		{`"".split(/(?:)/)`, "[]"},
+		{`"😀😀".split(/(?:)/u).length`, "2"},
+		{`"a,b".split(",")`, "[a, b]"},
+	}
+
+	for _, tt := range tests {
+		result := testEval(t, tt.input)
+		if tt.expected == "SyntaxError" {
+			testErrorObject(t, result, "SyntaxError: Invalid regular expression: /+/: Nothing to repeat")
+			continue
+		}
+		testInspect(t, result, tt.expected)
+	}
+}
+
+func TestCustomPatternObjects(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".replace({ [Symbol.replace](s, r) { return s + r; } }, "!")`, "abc!"},
+		{`"abc".split({ [Symbol.split](s, limit) { return [s, limit]; } }, 3)`, "[abc, 3]"},
+		{`"abc".match({ [Symbol.match](s) { return s.length; } })`, "3"},
+		{`"abc".search({ [Symbol.search](s) { return 42; } })`, "42"},
+		{`let r = /b/; r[Symbol.match] = false; "x/b/y".replaceAll(r, "!")`, "x/!/y"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestRegExpErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`new RegExp("(")`, "SyntaxError: Invalid regular expression: /(/: Unterminated group"},
+		{`new RegExp("a", "gg")`, "SyntaxError: Invalid flags supplied to RegExp constructor 'gg'"},
+		{`new RegExp("a", "q")`, "SyntaxError: Invalid flags supplied to RegExp constructor 'q'"},
+		{`new RegExp("\\q", "u")`, "SyntaxError: Invalid regular expression: /\\q/u: Invalid escape"},
+		{`"a".matchAll(/a/)`, "TypeError: String.prototype.matchAll called with a non-global RegExp argument"},
+		{`"a".replaceAll(/a/, "b")`, "TypeError: replaceAll must be called with a global RegExp"},
+		{`let o = { exec: RegExp.prototype.exec }; o.exec("a")`, "TypeError: Method RegExp.prototype.exec called on incompatible receiver {exec: builtin function}"},
+		{`let o = { test: RegExp.prototype.test }; o.test("a")`, "TypeError: Method RegExp.prototype.exec called on incompatible receiver {test: builtin function}"},
+		{`let r = /a/; r.exec = () => 1; r.test("a")`, "TypeError: 1 is not an object or null"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
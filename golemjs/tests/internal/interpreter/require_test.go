@@ -0,0 +1,207 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"testing"
+	"testing/fstest"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testRequire evaluates input with require loading modules from files,
+// and returns the result and what the script and modules printed.
+func testRequire(t *testing.T, files map[string]string, input string) (interpreter.Object, string) {
+	t.Helper()
+	fsys := fstest.MapFS{}
+	for name, source := range files {
+		fsys[name] = &fstest.MapFile{Data: []byte(source)}
+	}
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	var stdout bytes.Buffer
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stdout)
+	interp.SetRequireFS(fsys)
+	return interp.Eval(program), stdout.String()
+}
+
+func TestRequire(t *testing.T) {
+	tests := []struct {
+		files    map[string]string
+		input    string
+		expected string
+	}{
+		{map[string]string{
+			"greet.js": `module.exports = name => "hello " + name`,
+		}, `require("./greet")("world")`, "hello world"},
+		{map[string]string{
+			"lib/math.js": `exports.add = (a, b) => a + b; this.sub = (a, b) => a - b`,
+		}, `const m = require("/lib/math.js"); [m.add(1, 2), m.sub(3, 1)]`, "[3, 2]"},
+		{map[string]string{
+			"data.json": `{ "name": "golem", "list": [1, 2] }`,
+		}, `require("./data.json").list`, "[1, 2]"},
+		{map[string]string{
+			"dir/info.js": `module.exports = [__filename, __dirname, module.id, module.loaded]`,
+		}, `require("./dir/info")`, "[/dir/info.js, /dir, /dir/info.js, false]"},
+		{map[string]string{
+			"a.js":     `module.exports = require("./sub/b")`,
+			"sub/b.js": `module.exports = require("../c.json")`,
+			"c.json":   `"relative"`,
+		}, `require("./a")`, "relative"},
+		{map[string]string{
+			"lib/a.js": `module.exports = require("/b")`,
+			"lib/b.js": `module.exports = "nested"`,
+			"b.js":     `module.exports = "root"`,
+		}, `require("./lib/a")`, "root"},
+
+		// Directories: package.json main, then index files.
+		{map[string]string{
+			"lib/package.json":  `{ "main": "./dist/entry" }`,
+			"lib/dist/entry.js": `module.exports = "main"`,
+			"other/index.json":  `"index"`,
+		}, `[require("./lib"), require("./other")]`, "[main, index]"},
+
+		// Each module runs once, and returns the same exports.
+		{map[string]string{
+			"counter.js": `console.log("runs"); module.exports = { n: 0 }`,
+		}, `require("./counter").n++; require("/counter.js").n`, "1"},
+
+		// Cycles see what the other module has exported so far.
+		{map[string]string{
+			"a.js": `exports.early = "a early"; const b = require("./b"); exports.late = "a late"; module.exports.fromB = b.seen`,
+			"b.js": `const a = require("./a"); exports.seen = [a.early, a.late]`,
+		}, `require("./a").fromB`, "[a early, undefined]"},
+
+		// A return ends a module early.
+		{map[string]string{
+			"early.js": `exports.x = 1; return; exports.x = 2`,
+		}, `require("./early").x`, "1"},
+
+		// Packages in node_modules, looked for up the directory tree.
+		{map[string]string{
+			"node_modules/left-pad/index.js": `module.exports = (s, n) => " ".repeat(n - s.length) + s`,
+			"app/src/main.js":                `module.exports = require("left-pad")("x", 3)`,
+		}, `require("./app/src/main")`, "  x"},
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "main": "lib/pkg.js" }`,
+			"node_modules/pkg/lib/pkg.js":   `module.exports = "outer"`,
+			"app/node_modules/pkg/index.js": `module.exports = "nearest"`,
+			"app/main.js":                   `module.exports = [require("pkg"), require("pkg/lib/pkg")]`,
+		}, `require("./app/main")`, "[nearest, outer]"},
+		{map[string]string{
+			"node_modules/@scope/pkg/index.js": `module.exports = "scoped"`,
+		}, `require("@scope/pkg")`, "scoped"},
+
+		// package.json exports, with subpaths, patterns and conditions.
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "exports": "./main.js" }`,
+			"node_modules/pkg/main.js":      `module.exports = "sugar"`,
+		}, `require("pkg")`, "sugar"},
+		{map[string]string{
+			"node_modules/pkg/package.json": `{
+				"main": "./ignored.js",
+				"exports": {
+					".": { "import": "./esm.js", "require": "./cjs.js" },
+					"./utils": { "browser": "./browser.js", "default": "./utils.js" },
+					"./features/*": "./src/features/*.js",
+					"./features/special/*": ["bad", "./special/*.js"]
+				}
+			}`,
+			"node_modules/pkg/cjs.js":            `module.exports = "cjs"`,
+			"node_modules/pkg/utils.js":          `module.exports = "utils"`,
+			"node_modules/pkg/src/features/a.js": `module.exports = "feature a"`,
+			"node_modules/pkg/special/b.js":      `module.exports = "special b"`,
+		}, `[require("pkg"), require("pkg/utils"), require("pkg/features/a"), require("pkg/features/special/b")]`,
+			"[cjs, utils, feature a, special b]"},
+
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "exports": { "./a": "./a.js" } }`,
+			"node_modules/pkg/a.js":         ``,
+			"lib/x.js":                      ``,
+		}, `[require.resolve("pkg/a"), require.resolve("./lib/x")]`, "[/node_modules/pkg/a.js, /lib/x.js]"},
+	}
+
+	for _, tt := range tests {
+		result, _ := testRequire(t, tt.files, tt.input)
+		if err, ok := result.(*interpreter.Error); ok {
+			t.Errorf("input %q: %s", tt.input, err.Message)
+			continue
+		}
+		testInspect(t, result, tt.expected)
+	}
+
+	_, stdout := testRequire(t, map[string]string{"counter.js": `console.log("runs")`}, `require("./counter"); require("./counter.js")`)
+	if stdout != "runs\n" {
+		t.Errorf("expected the module to run once, got %q", stdout)
+	}
+}
+
+func TestRequireErrors(t *testing.T) {
+	tests := []struct {
+		files           map[string]string
+		input           string
+		expectedMessage string
+	}{
+		{nil, `require("./missing")`, "Error: Cannot find module './missing'"},
+		{map[string]string{
+			"a.js": `require("./b")`,
+			"b.js": `require("left-pad")`,
+		}, `require("./a")`, "Error: Cannot find module 'left-pad'\nRequire stack:\n- /b.js\n- /a.js"},
+		{nil, `require(1)`, `TypeError: The "id" argument must be of type string. Received 1`},
+		{nil, `require("")`, "TypeError: The argument 'id' must be a non-empty string. Received ''"},
+		{map[string]string{
+			"bad.json": `{ "a": }`,
+		}, `require("./bad.json")`, "SyntaxError: /bad.json: Unexpected token } in JSON at position 7"},
+		{map[string]string{
+			"bad.js": `let = 1`,
+		}, `require("./bad")`, "SyntaxError: unexpected = in binding pattern (in /bad.js)"},
+		{map[string]string{
+			"throws.js": `null.x`,
+		}, `require("./throws")`, "TypeError: Cannot read properties of null (reading 'x')"},
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "exports": { "./a": "./a.js" } }`,
+			"node_modules/pkg/a.js":         ``,
+			"node_modules/pkg/b.js":         ``,
+		}, `require("pkg/b")`, `Error: Package subpath './b' is not defined by "exports" in /node_modules/pkg/package.json`},
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "exports": { "./a": "./a.js" } }`,
+		}, `require("pkg")`, `Error: No "exports" main defined in /node_modules/pkg/package.json`},
+		{map[string]string{
+			"node_modules/pkg/package.json": `{ "exports": "./gone.js" }`,
+		}, `require("pkg")`, "Error: Cannot find module '/node_modules/pkg/gone.js'"},
+		{map[string]string{
+			"node_modules/pkg/package.json": `not json`,
+		}, `require("pkg")`, "Error: Invalid package config /node_modules/pkg/package.json."},
+	}
+
+	for _, tt := range tests {
+		result, _ := testRequire(t, tt.files, tt.input)
+		testErrorObject(t, result, tt.expectedMessage)
+	}
+}
+
+func TestRequireFailedModuleRunsAgain(t *testing.T) {
+	fsys := fstest.MapFS{"flaky.js": {Data: []byte(`null.x`)}}
+	interp := newInterpreter()
+	interp.SetRequireFS(fsys)
+	testErrorObject(t, interp.Require("./flaky"), "TypeError: Cannot read properties of null (reading 'x')")
+
+	// A module that failed isn't cached, so requiring it runs it again.
+	fsys["flaky.js"] = &fstest.MapFile{Data: []byte(`module.exports = "fixed"`)}
+	testInspect(t, interp.Require("./flaky"), "fixed")
+}
+
+func TestRequireWithoutFS(t *testing.T) {
+	testErrorObject(t, testEval(t, `require("./x")`), "ReferenceError: require is not defined")
+	testErrorObject(t, newInterpreter().Require("x"), "Error: Cannot find module 'x': no file system is set for require")
+
+	interp := newInterpreter()
+	interp.SetRequireFS(fstest.MapFS{"lib.js": {Data: []byte(`exports.answer = 42`)}})
+	testInspect(t, interp.Require("/lib"), "{answer: 42}")
+}
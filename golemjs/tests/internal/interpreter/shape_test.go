@@ -0,0 +1,60 @@
+package interpreter_test
+
+import "testing"
+
+// TestPropertyCaches runs property accesses through the same sites with
+// objects of different shapes, so that the bytecode VM's inline caches
+// have to notice when what they remember no longer holds.
+func TestPropertyCaches(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		// Objects of one shape, and of several, at the same site.
+		{`function getX(o) { return o.x; }
+		  [getX({x: 1}), getX({x: 2}), getX({a: 0, x: 3}), getX({b: 0, x: 4}), getX({}), getX({x: 5})]`, "[1, 2, 3, 4, undefined, 5]"},
+		{`function getX(o) { return o.x; }
+		  let out = [];
+		  for (let r = 0; r < 2; r++) {
+		    for (let o of [{x: 1}, {a: 0, x: 2}, {b: 0, x: 3}, {c: 0, x: 4}, {d: 0, x: 5}, {e: 0, x: 6}]) out.push(getX(o));
+		  }
+		  out`, "[1, 2, 3, 4, 5, 6, 1, 2, 3, 4, 5, 6]"},
+		// Writes that add a property, and writes that change one.
+		{`function setX(o, v) { o.x = v; return o; }
+		  let a = setX({}, 1); let b = setX({}, 2); let c = setX({x: 0}, 3); setX(a, 4);
+		  [a.x, b.x, c.x, JSON.stringify(a), JSON.stringify(c)]`, `[4, 2, 3, {"x":4}, {"x":3}]`},
+		{`function P(x) { this.x = x; this.y = x * 2; }
+		  let ps = []; for (let i = 0; i < 3; i++) ps.push(new P(i));
+		  JSON.stringify(ps)`, `[{"x":0,"y":0},{"x":1,"y":2},{"x":2,"y":4}]`},
+		// Methods found on the prototype, which objects can shadow and
+		// which can be replaced.
+		{`function P() {}
+		  P.prototype.m = function () { return "proto"; };
+		  function call(o) { return o.m(); }
+		  let a = new P(); let b = new P();
+		  let out = [call(a), call(b)];
+		  b.m = function () { return "own"; };
+		  out.push(call(a), call(b));
+		  P.prototype.m = function () { return "replaced"; };
+		  out.push(call(a), call(b));
+		  out`, "[proto, proto, proto, own, replaced, own]"},
+		{`function A() {} A.prototype.m = () => "A";
+		  function B() {} B.prototype.m = () => "B";
+		  function call(o) { return o.m(); }
+		  [call(new A()), call(new B()), call(new A())]`, "[A, B, A]"},
+		// Arrays and functions have properties of their own too, though an
+		// array's length isn't one of them.
+		{`function getX(o) { return o.x; } function len(o) { return o.length; }
+		  let arr = [1, 2]; arr.x = 3; let f = function () {}; f.x = 4;
+		  [getX(arr), getX(f), len(arr), len("abc"), len([1]), arr.push(5), len(arr)]`, "[3, 4, 2, 3, 1, 3, 3]"},
+		// An object with more properties than a shape is shared for.
+		{`let o = {}; for (let i = 0; i < 100; i++) o["k" + i] = i;
+		  function get(o) { return o.k50 + o.k99; }
+		  o.k50 = -1;
+		  [get(o), get(o), JSON.stringify(o).slice(-18)]`, `[98, 98, "k98":98,"k99":99}]`},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
@@ -0,0 +1,110 @@
+package interpreter_test
+
+import "testing"
+
+func TestStringUTF16(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"héllo".length`, "5"},
+		{`"😀".length`, "2"},
+		{`"a😀b"[3]`, "b"},
+		{`"😀".charCodeAt(0)`, "55357"},
+		{`"😀".charCodeAt(1)`, "56832"},
+		{`"😀".codePointAt(0)`, "128512"},
+		{`"😀".codePointAt(1)`, "56832"},
+		{`"😀" === "😀"`, "true"},
+		{`"\uD83D".length`, "1"},
+		{`"😀"[0] + "😀"[1] === "😀"`, "true"},
+		{`"\uD83D".concat("\uDE00") === "😀"`, "true"},
+		{`[..."a😀b"].length`, "3"},
+		{`[..."a\uD83Db"].length`, "3"},
+		{`Array.from("😀😀").length`, "2"},
+		{`"a😀b".slice(1, 3) === "😀"`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringPrototypeMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"abc".at(-1)`, "c"},
+		{`"abc".charAt(1)`, "b"},
+		{`"abc".charAt(5)`, ""},
+		{`"ab".concat("c", 1)`, "abc1"},
+		{`"hello".endsWith("lo")`, "true"},
+		{`"hello".endsWith("l", 4)`, "true"},
+		{`"hello".startsWith("ell", 1)`, "true"},
+		{`"hello".includes("ll")`, "true"},
+		{`"hello".indexOf("l")`, "2"},
+		{`"hello".indexOf("z")`, "-1"},
+		{`"hello".lastIndexOf("l")`, "3"},
+		{`"5".padStart(3, "0")`, "005"},
+		{`"x".padStart(4, "ab")`, "abax"},
+		{`"x".padEnd(3)`, "x  "},
+		{`"ab".repeat(3)`, "ababab"},
+		{`"a-b-c".replace("-", "+")`, "a+b-c"},
+		{`"a-b-c".replaceAll("-", "+")`, "a+b+c"},
+		{`"abc".replace("b", "[$&|$` + "`" + `|$'|$$]")`, "a[b|a|c|$]c"},
+		{`"aXbX".replaceAll("X", (m, pos) => pos)`, "a1b3"},
+		{`"abc".slice(-2)`, "bc"},
+		{`"abc".substring(2, 0)`, "ab"},
+		{`"a,b,,c".split(",")`, "[a, b, , c]"},
+		{`"abc".split("")`, "[a, b, c]"},
+		{`"a,b,c".split(",", 2)`, "[a, b]"},
+		{`"abc".split()`, "[abc]"},
+		{`"Hello".toUpperCase()`, "HELLO"},
+		{`"Hello".toLowerCase()`, "hello"},
+		{`"straße".toUpperCase()`, "STRASSE"},
+		{`"  hi \n".trim() + "|"`, "hi|"},
+		{`"  hi ".trimStart() + "|"`, "hi |"},
+		{`"  hi ".trimEnd() + "|"`, "  hi|"},
+		{`"é".normalize() === "é"`, "true"},
+		{`"é".normalize("NFD").length`, "2"},
+		{`"a".localeCompare("b")`, "-1"},
+		{`"abc".toString()`, "abc"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringConstructor(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"String(12)", "12"},
+		{"String([1, 2])", "1,2"},
+		{"String.fromCharCode(72, 105)", "Hi"},
+		{"String.fromCharCode(65601)", "A"},
+		{`String.fromCodePoint(128512) === "😀"`, "true"},
+		{`"abc".constructor === String`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a".repeat(-1)`, "RangeError: Invalid count value: -1"},
+		{`"a".normalize("X")`, "RangeError: The normalization form should be one of NFC, NFD, NFKC, NFKD."},
+		{"String.fromCodePoint(-1)", "RangeError: Invalid code point -1"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
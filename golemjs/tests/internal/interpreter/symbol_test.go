@@ -0,0 +1,99 @@
+package interpreter_test
+
+import "testing"
+
+func TestSymbols(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`typeof Symbol()`, "symbol"},
+		{`Symbol("a") === Symbol("a")`, "false"},
+		{`Symbol("a") == Symbol("a")`, "false"},
+		{`let s = Symbol("a"); s === s`, "true"},
+		{`Symbol("desc").toString()`, "Symbol(desc)"},
+		{`String(Symbol("desc"))`, "Symbol(desc)"},
+		{`Symbol("desc").description`, "desc"},
+		{`Symbol().description`, "undefined"},
+		{`Symbol("").description === ""`, "true"},
+		{`let s = Symbol(); s.valueOf() === s`, "true"},
+		{`Symbol.for("app") === Symbol.for("app")`, "true"},
+		{`Symbol.for("app") === Symbol("app")`, "false"},
+		{`Symbol.keyFor(Symbol.for("app"))`, "app"},
+		{`Symbol.keyFor(Symbol("app"))`, "undefined"},
+		{`!!Symbol()`, "true"},
+		{`typeof Symbol.iterator`, "symbol"},
+		{`Symbol.iterator.description`, "Symbol.iterator"},
+		{`Symbol.asyncIterator === Symbol.asyncIterator`, "true"},
+		{`[Symbol.hasInstance, Symbol.species, Symbol.toPrimitive, Symbol.toStringTag].length`, "4"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSymbolKeyedProperties(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let s = Symbol("k"); let o = {}; o[s] = 1; o[s]`, "1"},
+		{`let s = Symbol("k"); let o = { [s]: 2 }; o[s]`, "2"},
+		{`let s = Symbol("k"); let o = { [s]: 2 }; o["Symbol(k)"]`, "undefined"},
+		{`let o = {}; o[Symbol("k")] = 1; o[Symbol("k")]`, "undefined"},
+		{`let s = Symbol("k"); let o = { b: 1 }; o[s] = 2; o.a = 3; o`, "{b: 1, a: 3, Symbol(k): 2}"},
+		{`let s = Symbol("k"); JSON.stringify({ [s]: 1, a: s })`, "{}"},
+		{`let s = Symbol("k"); let a = []; a[s] = 1; [a.length, a[s]]`, "[0, 1]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestWellKnownSymbols(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let o = {}; o[Symbol.toStringTag] = "Custom"; o.toString()`, "[object Custom]"},
+		{`Math.toString()`, "[object Math]"},
+		{`JSON.toString()`, "[object JSON]"},
+		{`[].values().toString()`, "[object Array Iterator]"},
+		{`let o = {}; o[Symbol.toPrimitive] = function (hint) { return hint; }; [+o, "" + o, String(o)]`, "[NaN, default, string]"},
+		{`let o = {}; o[Symbol.toPrimitive] = function () { return 7; }; o * 2`, "14"},
+		{`[1, 2][Symbol.iterator]().next().value`, "1"},
+		{`[].values === [][Symbol.iterator]`, "true"},
+		{`let it = [].keys(); it[Symbol.iterator]() === it`, "true"},
+		{`let Point = function () {}; new Point() instanceof Point`, "true"},
+		{`let Point = function () {}; ({}) instanceof Point`, "false"},
+		{`[] instanceof Array`, "true"},
+		{`1 instanceof Number`, "false"},
+		{`let Even = {}; Even[Symbol.hasInstance] = function (n) { return n % 2 === 0; }; [2 instanceof Even, 3 instanceof Even]`, "[true, false]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestSymbolErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`new Symbol()`, "TypeError: Symbol is not a constructor"},
+		{`Symbol() + ""`, "TypeError: Cannot convert a Symbol value to a string"},
+		{`Symbol() * 1`, "TypeError: Cannot convert a Symbol value to a number"},
+		{`Symbol.keyFor("app")`, "TypeError: 'app' is not a symbol"},
+		{`let o = {}; o[Symbol.toPrimitive] = function () { return {}; }; +o`, "TypeError: Cannot convert object to primitive value"},
+		{`1 instanceof 2`, "TypeError: Right-hand side of 'instanceof' is not an object"},
+		{`1 instanceof {}`, "TypeError: Right-hand side of 'instanceof' is not callable"},
+		{`let s = Symbol("k"); let u; u[s]`, "TypeError: Cannot read properties of undefined (reading 'Symbol(k)')"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
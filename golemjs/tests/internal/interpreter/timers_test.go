@@ -0,0 +1,167 @@
+package interpreter_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/biosbuddha/golemjs/internal/interpreter"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+// testTimers evaluates input with a FakeClock stopped at testNow, and
+// returns the clock, the interpreter and its standard output.
+func testTimers(t *testing.T, input string) (*interpreter.FakeClock, *interpreter.Interpreter, *bytes.Buffer) {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	checkCompiled(t, program)
+	var stdout, stderr bytes.Buffer
+	clock := interpreter.NewFakeClock(testNow)
+	interp := newInterpreter()
+	interp.SetOutput(&stdout, &stderr)
+	interp.SetClock(clock)
+	if result, ok := interp.Eval(program).(*interpreter.Error); ok {
+		t.Fatalf("input %q: %s", input, result.Message)
+	}
+	return clock, interp, &stdout
+}
+
+func TestTimers(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`setTimeout(() => console.log("timeout")); Promise.resolve().then(() => console.log("microtask")); console.log("script")`, "script\nmicrotask\ntimeout\n"},
+		{`setTimeout(() => console.log(2), 20); setTimeout(() => console.log(1), 10); setTimeout(() => console.log(3), 20)`, "1\n2\n3\n"},
+		{`setTimeout((a, b) => console.log(a, b), 5, "a", "b")`, "a b\n"},
+		{`setTimeout(function () { console.log(this); })`, "undefined\n"},
+		{`let id = setTimeout(() => console.log("not run"), 10); clearTimeout(id)`, ""},
+		{`clearTimeout(); clearTimeout(99); clearInterval("x")`, ""},
+		{`let n = 0; let id = setInterval(() => { console.log(++n); if (n == 3) clearInterval(id); }, 10)`, "1\n2\n3\n"},
+		// Timeouts and intervals share their ids.
+		{`let id = setInterval(() => console.log("not run"), 10); clearTimeout(id)`, ""},
+		{`let a = setTimeout(() => {}); let b = setInterval(() => {}); clearInterval(b); console.log(typeof a, b > a)`, "number true\n"},
+		{`let start = Date.now(); setTimeout(() => console.log(Date.now() - start), 1500)`, "1500\n"},
+		{`setTimeout(() => console.log("negative"), -5); setTimeout(() => console.log("later"), 1)`, "negative\nlater\n"},
+		{`setTimeout(() => { setTimeout(() => console.log("inner")); Promise.resolve().then(() => console.log("micro")); }); setTimeout(() => console.log("outer"))`, "micro\nouter\ninner\n"},
+
+		// Timers set from within timers more than five deep wait at least
+		// 4ms.
+		{`
+let start = Date.now();
+let times = [];
+function next() { times.push(Date.now() - start); if (times.length < 8) setTimeout(next); }
+setTimeout(next);
+setTimeout(() => console.log(times), 100);
+`, "[ 0, 0, 0, 0, 0, 4, 8, 12 ]\n"},
+	}
+
+	for _, tt := range tests {
+		clock, _, stdout := testTimers(t, tt.input)
+		if err := clock.RunAllTimers(); err != nil {
+			t.Errorf("input %q: %s", tt.input, err.Message)
+		}
+		if stdout.String() != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout.String())
+		}
+	}
+}
+
+func TestTimerErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`setTimeout("code")`, `TypeError: The "callback" argument must be of type function. Received 'code'`},
+		{`setInterval()`, `TypeError: The "callback" argument must be of type function. Received undefined`},
+		{`setTimeout(() => {}, Symbol())`, "TypeError: Cannot convert a Symbol value to a number"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+
+	clock, _, stdout := testTimers(t, `setTimeout(() => missing, 10); setTimeout(() => console.log("still runs"), 20)`)
+	err := clock.AdvanceBy(time.Second)
+	if err == nil || err.Message != "ReferenceError: missing is not defined" {
+		t.Errorf("expected the timer's error, got %v", err)
+	}
+	if stdout.String() != "still runs\n" {
+		t.Errorf("expected the later timer to run, got %q", stdout.String())
+	}
+}
+
+func TestFakeClockAdvanceBy(t *testing.T) {
+	clock, interp, stdout := testTimers(t, `
+let start = Date.now();
+const log = name => console.log(name, Date.now() - start);
+setTimeout(() => log("a"), 100);
+setTimeout(() => { log("b"); setTimeout(() => log("c"), 50); }, 200);
+setTimeout(() => log("d"), 1000);
+`)
+	steps := []struct {
+		advance  time.Duration
+		expected string
+	}{
+		{0, ""},
+		{99 * time.Millisecond, ""},
+		{time.Millisecond, "a 100\n"},
+		// A timer set by a timer fires if it falls due in the same step.
+		{200 * time.Millisecond, "a 100\nb 200\nc 250\n"},
+		{time.Second, "a 100\nb 200\nc 250\nd 1000\n"},
+	}
+
+	for _, step := range steps {
+		if err := clock.AdvanceBy(step.advance); err != nil {
+			t.Fatalf("advancing by %s: %s", step.advance, err.Message)
+		}
+		if stdout.String() != step.expected {
+			t.Errorf("after advancing by %s: expected %q, got %q", step.advance, step.expected, stdout.String())
+		}
+	}
+
+	// The clock ends where it was moved to, not at the last timer.
+	testInspect(t, interp.Eval(parser.New(lexer.New(`Date.now() - start`)).ParseProgram()), "1300")
+}
+
+func TestFakeClockRunAllTimersGivesUp(t *testing.T) {
+	clock, _, _ := testTimers(t, `setInterval(() => {}, 1000)`)
+	err := clock.RunAllTimers()
+	if expected := "Aborting after advancing the clock 100000 times, assuming an infinite loop"; err == nil || err.Message != expected {
+		t.Errorf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestFakeClockSharedByInterpreters(t *testing.T) {
+	clock := interpreter.NewFakeClock(testNow)
+	var out bytes.Buffer
+	for _, input := range []string{`setTimeout(() => console.log("first"), 20)`, `setTimeout(() => console.log("second"), 10)`} {
+		interp := newInterpreter()
+		interp.SetOutput(&out, &out)
+		interp.SetClock(clock)
+		interp.Eval(parser.New(lexer.New(input)).ParseProgram())
+	}
+
+	if err := clock.RunAllTimers(); err != nil {
+		t.Fatal(err.Message)
+	}
+	if expected := "second\nfirst\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestTimersWithSystemClock(t *testing.T) {
+	start := time.Now()
+	stdout, _ := testConsole(t, `setTimeout(() => console.log("done"), 30)`)
+	if stdout != "done\n" {
+		t.Errorf("expected the timer to have fired, got %q", stdout)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected Eval to wait for the timer, returned after %s", elapsed)
+	}
+}
@@ -0,0 +1,253 @@
+package interpreter_test
+
+import "testing"
+
+func TestTypedArrays(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`new Uint8Array(3)`, "Uint8Array(3) [0, 0, 0]"},
+		{`new Uint8Array([1, 256, 257, -1])`, "Uint8Array(4) [1, 0, 1, 255]"},
+		{`new Int8Array([127, 128, 255])`, "Int8Array(3) [127, -128, -1]"},
+		{`new Uint8ClampedArray([300, -5, 1.5, 2.5, NaN])`, "Uint8ClampedArray(5) [255, 0, 2, 2, 0]"},
+		{`new Int16Array([32768, -32769])`, "Int16Array(2) [-32768, 32767]"},
+		{`new Uint32Array([-1])`, "Uint32Array(1) [4294967295]"},
+		{`new Float32Array([0.1])[0]`, "0.10000000149011612"},
+		{`new Float64Array([0.1, "2", null])`, "Float64Array(3) [0.1, 2, 0]"},
+		{`new BigInt64Array([1n, -1n, 9223372036854775808n])`, "BigInt64Array(3) [1n, -1n, -9223372036854775808n]"},
+		{`new BigUint64Array([-1n])`, "BigUint64Array(1) [18446744073709551615n]"},
+		{`new Uint16Array(new Set([1, 2]))`, "Uint16Array(2) [1, 2]"},
+		{`new Uint8Array({ length: 2, 0: 7 })`, "Uint8Array(2) [7, 0]"},
+		{`new Int8Array(new Uint8Array([255]))`, "Int8Array(1) [-1]"},
+		{`Uint8Array.from([1, 2], x => x * 2)`, "Uint8Array(2) [2, 4]"},
+		{`Int32Array.of(1, -2)`, "Int32Array(2) [1, -2]"},
+		{`[Int8Array.BYTES_PER_ELEMENT, Float64Array.BYTES_PER_ELEMENT, new Uint16Array(1).BYTES_PER_ELEMENT]`, "[1, 8, 2]"},
+		{`let a = new Uint8Array(2); a[0] = 300; a[5] = 1; a[-1] = 1; a["1.5"] = 1; [a[0], a[5], a[-1], a["1.5"], a.length]`, "[44, undefined, undefined, undefined, 2]"},
+		{`let a = new Uint8Array(2); a.foo = 1; a.foo`, "1"},
+		{`let a = new Uint32Array(4); [a.length, a.byteLength, a.byteOffset, a.buffer.byteLength]`, "[4, 16, 0, 16]"},
+
+		// Views share their buffer.
+		{`let bytes = new Uint8Array(4); let words = new Uint32Array(bytes.buffer); words[0] = 0x01020304; bytes`, "Uint8Array(4) [4, 3, 2, 1]"},
+		{`let buf = new ArrayBuffer(8); let view = new Uint16Array(buf, 2, 2); view[1] = 0xFFFF; new Uint8Array(buf)`, "Uint8Array(8) [0, 0, 0, 0, 255, 255, 0, 0]"},
+		{`let a = new Uint8Array([1, 2, 3, 4]); let sub = a.subarray(1, 3); sub[0] = 9; [a[1], sub.length, sub.byteOffset]`, "[9, 2, 1]"},
+		{`let a = new Uint8Array([1, 2, 3, 4]); let s = a.slice(1, 3); s[0] = 9; [a[1], s]`, "[2, Uint8Array(2) [9, 3]]"},
+
+		{`let a = new Uint8Array([1, 2, 3]); [a.at(-1), a.indexOf(2), a.lastIndexOf(3), a.includes(4), a.join("-"), String(a)]`, "[3, 1, 2, false, 1-2-3, 1,2,3]"},
+		{`new Float64Array([NaN]).includes(NaN)`, "true"},
+		{`new Float64Array([NaN]).indexOf(NaN)`, "-1"},
+		{`new Uint8Array([1, 2, 3]).map(x => x * 100)`, "Uint8Array(3) [100, 200, 44]"},
+		{`new Uint8Array([1, 2, 3, 4]).filter(x => x % 2 == 0)`, "Uint8Array(2) [2, 4]"},
+		{`new Uint8Array([1, 2, 3]).reduce((sum, x) => sum + x)`, "6"},
+		{`new Uint8Array([1, 2, 3]).reduceRight((acc, x) => acc + x, "")`, "321"},
+		{`let a = new Uint8Array([1, 2, 3]); [a.find(x => x > 1), a.findIndex(x => x > 1), a.findLast(x => x < 3), a.findLastIndex(x => x > 5)]`, "[2, 1, 2, -1]"},
+		{`let a = new Uint8Array([1, 2, 3]); [a.every(x => x > 0), a.some(x => x > 2)]`, "[true, true]"},
+		{`let out = []; new Uint8Array([5, 6]).forEach((x, i) => out.push(i + ":" + x)); out`, "[0:5, 1:6]"},
+		{`new Uint8Array(4).fill(7, 1, -1)`, "Uint8Array(4) [0, 7, 7, 0]"},
+		{`new Uint8Array([1, 2, 3, 4, 5]).copyWithin(0, 3)`, "Uint8Array(5) [4, 5, 3, 4, 5]"},
+		{`new Uint8Array([1, 2, 3]).reverse()`, "Uint8Array(3) [3, 2, 1]"},
+		{`let a = new Uint8Array([1, 2, 3]); [a.toReversed(), a.with(-1, 9), a]`, "[Uint8Array(3) [3, 2, 1], Uint8Array(3) [1, 2, 9], Uint8Array(3) [1, 2, 3]]"},
+		{`let a = new Uint8Array(5); a.set([1, 2], 1); a.set(new Int8Array([-1]), 4); a`, "Uint8Array(5) [0, 1, 2, 0, 255]"},
+		{`let a = new Uint8Array([1, 2, 3, 4]); a.set(a.subarray(0, 3), 1); a`, "Uint8Array(4) [1, 1, 2, 3]"},
+		{`let a = new Float64Array([3, 1, NaN, 0, -0, 10, 2]).sort(); [1 / a[0], a]`, "[-Infinity, Float64Array(7) [0, 0, 1, 2, 3, 10, NaN]]"},
+		{`new Int8Array([1, 3, 2]).sort((a, b) => b - a)`, "Int8Array(3) [3, 2, 1]"},
+		{`let a = new BigInt64Array([3n, -1n, 2n]); [a.toSorted(), a]`, "[BigInt64Array(3) [-1n, 2n, 3n], BigInt64Array(3) [3n, -1n, 2n]]"},
+		{`[...new Uint8Array([1, 2])]`, "[1, 2]"},
+		{`[...new Uint8Array([7, 8]).entries()]`, "[[0, 7], [1, 8]]"},
+		{`[...new Uint8Array([7, 8]).keys()]`, "[0, 1]"},
+		{`JSON.stringify(new Uint8Array([1, 2]))`, `{"0":1,"1":2}`},
+		{`new Uint8Array(1) instanceof Uint8Array`, "true"},
+		{`String(new Uint8Array(1).toString === new Int8Array(1).toString)`, "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestTypedArrayErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`Uint8Array(2)`, "TypeError: Constructor Uint8Array requires 'new'"},
+		{`new Uint8Array(-1)`, "RangeError: Invalid typed array length: -1"},
+		{`new Uint32Array(new ArrayBuffer(5))`, "RangeError: byte length of Uint32Array should be a multiple of 4"},
+		{`new Uint32Array(new ArrayBuffer(8), 2)`, "RangeError: start offset of Uint32Array should be a multiple of 4"},
+		{`new Uint8Array(new ArrayBuffer(4), 8)`, "RangeError: Start offset 8 is outside the bounds of the buffer"},
+		{`new Uint8Array(new ArrayBuffer(4), 1, 4)`, "RangeError: Invalid typed array length: 4"},
+		{`new Uint8Array([1n])`, "TypeError: Cannot convert a BigInt value to a number"},
+		{`new BigInt64Array([1])`, "TypeError: Cannot convert 1 to a BigInt"},
+		{`new BigInt64Array(new Uint8Array(2))`, "TypeError: Cannot mix BigInt and other types, use explicit conversions"},
+		{`new Uint8Array(2).set([1, 2, 3])`, "RangeError: offset is out of bounds"},
+		{`new Uint8Array(2).with(2, 0)`, "RangeError: Invalid typed array index"},
+		{`new Uint8Array(0).reduce((a, b) => a + b)`, "TypeError: Reduce of empty array with no initial value"},
+		{`new Uint8Array(1).map(1)`, "TypeError: 1 is not a function"},
+		{`let f = new Uint8Array(1).at; f(0)`, "TypeError: this is not a typed array."},
+		{`let b = new ArrayBuffer(4); let a = new Uint8Array(b); b.transfer(); a.fill(1)`, "TypeError: Cannot perform %TypedArray%.prototype.fill on a detached ArrayBuffer"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestArrayBuffer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let b = new ArrayBuffer(8); [b.byteLength, b.maxByteLength, b.resizable, b.detached]`, "[8, 8, false, false]"},
+		{`let b = new ArrayBuffer(4); new Uint8Array(b).set([1, 2, 3, 4]); new Uint8Array(b.slice(1, -1))`, "Uint8Array(2) [2, 3]"},
+		{`[ArrayBuffer.isView(new Uint8Array(1)), ArrayBuffer.isView(new DataView(new ArrayBuffer(1))), ArrayBuffer.isView(new ArrayBuffer(1))]`, "[true, true, false]"},
+
+		// Resizable buffers, and the views that track their length.
+		{`let b = new ArrayBuffer(2, { maxByteLength: 8 }); [b.byteLength, b.maxByteLength, b.resizable]`, "[2, 8, true]"},
+		{`let b = new ArrayBuffer(2, { maxByteLength: 8 }); let a = new Uint8Array(b); b.resize(6); [a.length, b.byteLength]`, "[6, 6]"},
+		{`let b = new ArrayBuffer(4, { maxByteLength: 8 }); let a = new Uint8Array(b); a.fill(1); b.resize(2); b.resize(4); a`, "Uint8Array(4) [1, 1, 0, 0]"},
+		{`let b = new ArrayBuffer(4, { maxByteLength: 8 }); let fixed = new Uint8Array(b, 0, 4); b.resize(2); [fixed.length, fixed.byteLength, fixed[0]]`, "[0, 0, undefined]"},
+		{`let b = new ArrayBuffer(4, { maxByteLength: 8 }); let fixed = new Uint8Array(b, 0, 4); b.resize(2); b.resize(4); fixed.length`, "4"},
+
+		// Transferring detaches the original.
+		{`let b = new ArrayBuffer(2); new Uint8Array(b)[0] = 5; let t = b.transfer(); [b.detached, b.byteLength, new Uint8Array(t)[0]]`, "[true, 0, 5]"},
+		{`let b = new ArrayBuffer(2); let a = new Uint8Array(b); b.transfer(); [a.length, a.byteOffset, a[0]]`, "[0, 0, undefined]"},
+		{`let b = new ArrayBuffer(2); b.transfer(4).byteLength`, "4"},
+		{`let b = new ArrayBuffer(2, { maxByteLength: 4 }); [b.transfer().resizable, new ArrayBuffer(1, { maxByteLength: 4 }).transferToFixedLength().resizable]`, "[true, false]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestArrayBufferErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`ArrayBuffer(1)`, "TypeError: Constructor ArrayBuffer requires 'new'"},
+		{`new ArrayBuffer(-1)`, "RangeError: Invalid array buffer length"},
+		{`new ArrayBuffer(4, { maxByteLength: 2 })`, "RangeError: Invalid array buffer max length"},
+		{`new ArrayBuffer(2 * 1024 * 1024 * 1024)`, "RangeError: Array buffer allocation failed"},
+		{`new ArrayBuffer(2, { maxByteLength: 4 }).resize(5)`, "RangeError: ArrayBuffer.prototype.resize: Invalid length parameter"},
+		{`let b = new ArrayBuffer(2); b.transfer(); b.slice()`, "TypeError: Cannot perform ArrayBuffer.prototype.slice on a detached ArrayBuffer"},
+		{`let b = new ArrayBuffer(2); b.transfer(); new Uint8Array(b)`, "TypeError: Cannot perform Construct on a detached ArrayBuffer"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestDataView(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let v = new DataView(new Uint8Array([1, 2]).buffer); [v.getUint16(0), v.getUint16(0, true)]`, "[258, 513]"},
+		{`let v = new DataView(new ArrayBuffer(4)); v.setInt16(1, -2); [v.getUint8(1), v.getUint8(2), v.getInt16(1)]`, "[255, 254, -2]"},
+		{`let v = new DataView(new ArrayBuffer(4)); v.setFloat32(0, 1.5); new Uint8Array(v.buffer)`, "Uint8Array(4) [63, 192, 0, 0]"},
+		{`let v = new DataView(new ArrayBuffer(8)); v.setFloat64(0, Math.PI, true); v.getFloat64(0, true) == Math.PI`, "true"},
+		{`let v = new DataView(new ArrayBuffer(8)); v.setBigInt64(0, -2n); [v.getBigInt64(0), v.getBigUint64(0)]`, "[-2n, 18446744073709551614n]"},
+		{`let v = new DataView(new ArrayBuffer(8), 2, 4); [v.byteOffset, v.byteLength]`, "[2, 4]"},
+		{`let v = new DataView(new ArrayBuffer(8), 6); v.byteLength`, "2"},
+		{`let b = new ArrayBuffer(2, { maxByteLength: 8 }); let v = new DataView(b); b.resize(8); v.byteLength`, "8"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestDataViewErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`new DataView({})`, "TypeError: First argument to DataView constructor must be an ArrayBuffer"},
+		{`new DataView(new ArrayBuffer(2), 3)`, "RangeError: Start offset 3 is outside the bounds of the buffer"},
+		{`new DataView(new ArrayBuffer(2), 1, 2)`, "RangeError: Invalid DataView length 2"},
+		{`new DataView(new ArrayBuffer(2)).getUint32(0)`, "RangeError: Offset is outside the bounds of the DataView"},
+		{`new DataView(new ArrayBuffer(2)).setUint8(-1, 0)`, "RangeError: Offset is outside the bounds of the DataView"},
+		{`new DataView(new ArrayBuffer(8)).setBigInt64(0, 1)`, "TypeError: Cannot convert 1 to a BigInt"},
+		{`let b = new ArrayBuffer(2); let v = new DataView(b); b.transfer(); v.getUint8(0)`, "TypeError: Cannot perform DataView.prototype.getUint8 on a detached ArrayBuffer"},
+		{`let b = new ArrayBuffer(4, { maxByteLength: 4 }); let v = new DataView(b, 0, 4); b.resize(2); v.byteLength`, "TypeError: Cannot perform DataView.prototype.byteLength on an out of bounds DataView"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestTextEncoding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`new TextEncoder().encoding`, "utf-8"},
+		{`new TextEncoder().encode("aé€😀")`, "Uint8Array(10) [97, 195, 169, 226, 130, 172, 240, 159, 152, 128]"},
+		{`new TextEncoder().encode()`, "Uint8Array(0) []"},
+		{`new TextEncoder().encode("\uD800")`, "Uint8Array(3) [239, 191, 189]"},
+		{`let dest = new Uint8Array(4); let r = new TextEncoder().encodeInto("aé€", dest); [r.read, r.written, dest]`, "[2, 3, Uint8Array(4) [97, 195, 169, 0]]"},
+		{`new TextDecoder().decode(new Uint8Array([104, 195, 169]))`, "hé"},
+		{`new TextDecoder().decode(new Uint8Array([104, 105]).buffer)`, "hi"},
+		{`new TextDecoder().decode(new DataView(new Uint8Array([0, 104, 105]).buffer, 1))`, "hi"},
+		{`new TextDecoder().decode()`, ""},
+		{`new TextDecoder().decode(new Uint8Array([0xEF, 0xBB, 0xBF, 104]))`, "h"},
+		{`new TextDecoder("utf-8", { ignoreBOM: true }).decode(new Uint8Array([0xEF, 0xBB, 0xBF])).length`, "1"},
+
+		// Each maximal invalid sequence becomes a single U+FFFD.
+		{`new TextDecoder().decode(new Uint8Array([0xE2, 0x82, 0x41, 0xFF, 0xC0, 0x80]))`, "�A���"},
+		{`new TextDecoder().decode(new Uint8Array([0xED, 0xA0, 0x80]))`, "���"},
+		{`new TextDecoder().decode(new Uint8Array([0xF0, 0x9F, 0x98]))`, "�"},
+
+		// Streaming holds back a character split between chunks.
+		{`let d = new TextDecoder(); d.decode(new Uint8Array([0xE2, 0x82]), { stream: true }) + "|" + d.decode(new Uint8Array([0xAC]))`, "|€"},
+		{`let d = new TextDecoder(); d.decode(new Uint8Array([0xE2]), { stream: true }); d.decode()`, "�"},
+
+		{`let d = new TextDecoder(" UTF8 ", { fatal: true }); [d.encoding, d.fatal, d.ignoreBOM]`, "[utf-8, true, false]"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestTextEncodingErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{`TextEncoder()`, "TypeError: Constructor TextEncoder requires 'new'"},
+		{`new TextDecoder("latin1")`, `RangeError: The "latin1" encoding is not supported`},
+		{`new TextDecoder("utf-8", { fatal: true }).decode(new Uint8Array([0xFF]))`, "TypeError: The encoded data was not valid for encoding utf-8"},
+		{`new TextDecoder().decode("abc")`, `TypeError: The "input" argument must be an instance of ArrayBuffer or ArrayBufferView. Received 'abc'`},
+		{`new TextEncoder().encodeInto("a", [])`, `TypeError: The "dest" argument must be an instance of Uint8Array`},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expectedMessage)
+	}
+}
+
+func TestInspectBinaryData(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`console.log(new Uint8Array([1, 2]), new Float64Array(0))`, "Uint8Array(2) [ 1, 2 ] Float64Array(0) []\n"},
+		{`console.log(new BigInt64Array([1n]))`, "BigInt64Array(1) [ 1n ]\n"},
+		{`console.log(new Uint8Array([255, 0]).buffer)`, "ArrayBuffer { [Uint8Contents]: <ff 00>, byteLength: 2 }\n"},
+		{`let b = new ArrayBuffer(1); b.transfer(); console.log(b)`, "ArrayBuffer { (detached), byteLength: 0 }\n"},
+		{`console.log(new DataView(new ArrayBuffer(1)))`, "DataView {\n  byteLength: 1,\n  byteOffset: 0,\n  buffer: ArrayBuffer { [Uint8Contents]: <00>, byteLength: 1 }\n}\n"},
+		{`console.log({ a: { b: { c: new Uint8Array(1) } } })`, "{ a: { b: { c: [Uint8Array] } } }\n"},
+		{`let a = new Uint8Array(1); a.x = 1; console.log(a)`, "Uint8Array(1) [ 0, x: 1 ]\n"},
+	}
+
+	for _, tt := range tests {
+		stdout, _ := testConsole(t, tt.input)
+		if stdout != tt.expected {
+			t.Errorf("input %q: expected %q, got %q", tt.input, tt.expected, stdout)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package interpreter_test
+
+import "testing"
+
+func TestUndefinedValues(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"undefined", "undefined"},
+		{"let f = function () {}; f()", "undefined"},
+		{"let f = function () { return; }; f()", "undefined"},
+		{"let f = function (a, b) { return b; }; f(1)", "undefined"},
+		{"var x; x", "undefined"},
+		{"let x; x", "undefined"},
+		{"var x = 1; var x; x", "1"},
+		{"({ a: 1 }).b", "undefined"},
+		{"[1, 2][5]", "undefined"},
+		{"[].pop()", "undefined"},
+		{"void 1", "undefined"},
+		{"typeof undefined", "undefined"},
+		{"typeof void 0", "undefined"},
+		{"var x; typeof x", "undefined"},
+		{"undefined + 1", "NaN"},
+		{`"a" + undefined`, "aundefined"},
+		{"String(undefined)", "undefined"},
+		{"[undefined, null].join()", ","},
+		{"!undefined", "true"},
+	}
+
+	for _, tt := range tests {
+		testInspect(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestUndefinedEquality(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"undefined == undefined", true},
+		{"undefined === undefined", true},
+		{"undefined == null", true},
+		{"null == undefined", true},
+		{"undefined === null", false},
+		{"undefined != null", false},
+		{"undefined !== null", true},
+		{"undefined == 0", false},
+		{"undefined == false", false},
+		{`undefined == ""`, false},
+		{"var x; x === undefined", true},
+		{"({}).a === undefined", true},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
+
+func TestUndefinedErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x; x.y", "TypeError: Cannot read properties of undefined (reading 'y')"},
+		{"let x; x.y = 1", "TypeError: Cannot set properties of undefined (setting 'y')"},
+		{"Array.from(undefined)", "TypeError: undefined is not iterable"},
+	}
+
+	for _, tt := range tests {
+		testErrorObject(t, testEval(t, tt.input), tt.expected)
+	}
+}
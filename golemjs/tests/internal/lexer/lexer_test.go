@@ -124,3 +124,115 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestNumberLiterals(t *testing.T) {
+	input := `42 3.14 .5 1e3 2.5E-4 0xFF 0o17 0b101 1_000 1. a.b 123n 0xFFn 1_0n`
+
+	tests := []struct {
+		expectedType    lexer.TokenType
+		expectedLiteral string
+	}{
+		{lexer.INT, "42"},
+		{lexer.INT, "3.14"},
+		{lexer.INT, ".5"},
+		{lexer.INT, "1e3"},
+		{lexer.INT, "2.5E-4"},
+		{lexer.INT, "0xFF"},
+		{lexer.INT, "0o17"},
+		{lexer.INT, "0b101"},
+		{lexer.INT, "1_000"},
+		{lexer.INT, "1."},
+		{lexer.IDENT, "a"},
+		{lexer.DOT, "."},
+		{lexer.IDENT, "b"},
+		{lexer.BIGINT, "123n"},
+		{lexer.BIGINT, "0xFFn"},
+		{lexer.BIGINT, "1_0n"},
+		{lexer.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
+
+func TestReadRegExp(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected lexer.Token
+	}{
+		{"/ab+c/gi x", lexer.Token{Type: lexer.REGEXP, Literal: "/ab+c/gi"}},
+		{"/=/ x", lexer.Token{Type: lexer.REGEXP, Literal: "/=/"}},
+		{`/a\/b/ x`, lexer.Token{Type: lexer.REGEXP, Literal: `/a\/b/`}},
+		{"/[/]/ x", lexer.Token{Type: lexer.REGEXP, Literal: "/[/]/"}},
+		{"/\"/ x", lexer.Token{Type: lexer.REGEXP, Literal: "/\"/"}},
+		{"/abc", lexer.Token{Type: lexer.ILLEGAL, Literal: "/abc"}},
+		{"/ab\nc/", lexer.Token{Type: lexer.ILLEGAL, Literal: "/ab"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		// The parser has read the / and the token after it by the time
+		// it asks for a regular expression.
+		l.NextToken()
+		l.NextToken()
+		tok := l.ReadRegExp()
+		if tok != tt.expected {
+			t.Errorf("input %q: expected %+v, got %+v", tt.input, tt.expected, tok)
+			continue
+		}
+		if tok.Type == lexer.REGEXP {
+			if next := l.NextToken(); next.Literal != "x" {
+				t.Errorf("input %q: expected x after the literal, got %+v", tt.input, next)
+			}
+		}
+	}
+}
+
+func TestOperators(t *testing.T) {
+	input := `** **= & &= && | |= || ^ ^= << <<= < >> >>= >>> >>>= > >=`
+
+	tests := []struct {
+		expectedType    lexer.TokenType
+		expectedLiteral string
+	}{
+		{lexer.EXPONENT, "**"},
+		{lexer.EXPONENT_ASSIGN, "**="},
+		{lexer.BIT_AND, "&"},
+		{lexer.BIT_AND_ASSIGN, "&="},
+		{lexer.AND, "&&"},
+		{lexer.BIT_OR, "|"},
+		{lexer.BIT_OR_ASSIGN, "|="},
+		{lexer.OR, "||"},
+		{lexer.BIT_XOR, "^"},
+		{lexer.BIT_XOR_ASSIGN, "^="},
+		{lexer.SHL, "<<"},
+		{lexer.SHL_ASSIGN, "<<="},
+		{lexer.LT, "<"},
+		{lexer.SHR, ">>"},
+		{lexer.SHR_ASSIGN, ">>="},
+		{lexer.USHR, ">>>"},
+		{lexer.USHR_ASSIGN, ">>>="},
+		{lexer.GT, ">"},
+		{lexer.GT_EQ, ">="},
+		{lexer.EOF, ""},
+	}
+
+	l := lexer.New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - wrong token. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
@@ -0,0 +1,392 @@
+package parser_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/biosbuddha/golemjs/internal/ast"
+	"github.com/biosbuddha/golemjs/internal/lexer"
+	"github.com/biosbuddha/golemjs/internal/parser"
+)
+
+func TestOperatorPrecedenceParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"-a * b", "((-a) * b)"},
+		{"typeof a + b", "((typeof a) + b)"},
+		{"void a + b", "((void a) + b)"},
+		{"delete a.b + c", "((delete a.b) + c)"},
+		{"a instanceof b === true", "((a instanceof b) === true)"},
+		{"a + b instanceof c", "((a + b) instanceof c)"},
+		{"1.50 + 0x10", "(1.50 + 0x10)"},
+		{"-1n * 0x10n", "((-1n) * 0x10n)"},
+		{"a + b * c", "(a + (b * c))"},
+		{"a + b - c", "((a + b) - c)"},
+		{"a < b == c > d", "((a < b) == (c > d))"},
+		{"a || b && c", "(a || (b && c))"},
+		{"a = b = c", "a = b = c"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a.b.c(d)[e]", "a.b.c(d)[e]"},
+		{"x++ + ++y", "((x++) + (++y))"},
+		{"a ** b ** c", "(a ** (b ** c))"},
+		{"a * b ** c", "(a * (b ** c))"},
+		{"(-a) ** b", "((-a) ** b)"},
+		{"a << b + c", "(a << (b + c))"},
+		{"a < b >> c", "(a < (b >> c))"},
+		{"a >>> b << c", "((a >>> b) << c)"},
+		{"a | b ^ c & d", "(a | (b ^ (c & d)))"},
+		{"a & b == c", "(a & (b == c))"},
+		{"a || b | c", "(a || (b | c))"},
+		{"a && b | c", "(a && (b | c))"},
+		{"a **= b", "a **= b"},
+		{"a >>>= b", "a >>>= b"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestArrayLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		length   int
+	}{
+		{"[1, 2, 3]", "[1, 2, 3]", 3},
+		{"[1, , 3]", "[1, , 3]", 3},
+		{"[, ]", "[]", 1},
+		{"[1, 2, ]", "[1, 2]", 2},
+		{"[...a, ...b, 1]", "[...a, ...b, 1]", 3},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		array, ok := stmt.Expression.(*ast.ArrayLiteral)
+		if !ok {
+			t.Fatalf("expression is not ArrayLiteral. got=%T", stmt.Expression)
+		}
+		if len(array.Elements) != tt.length {
+			t.Errorf("input %q: wrong number of elements. expected=%d, got=%d",
+				tt.input, tt.length, len(array.Elements))
+		}
+		if array.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, array.String())
+		}
+	}
+}
+
+func TestFunctionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"function add(a, b) { return a + b; }", "function add(a, b) {\n  return (a + b);\n}"},
+		{"let f = function (x) { return x; };", "let f = function(x) {\n  return x;\n};"},
+		{"x => x * 2", "(x) => {\n  return (x * 2);\n}"},
+		{"(a, b) => a", "(a, b) => {\n  return a;\n}"},
+		{"() => {}", "() => {\n}"},
+		{"function* g() { yield 1; }", "function* g() {\n  yield 1\n}"},
+		{"let g = function* () { yield* a; };", "let g = function*() {\n  yield* a\n};"},
+		{"function* g() { let x = yield; }", "function* g() {\n  let x = yield;\n}"},
+		{"function* g() { f(yield a, yield b); }", "function* g() {\n  f(yield a, yield b)\n}"},
+		{"async function f() { await x; }", "async function f() {\n  await x\n}"},
+		{"let f = async function () { return await a + b; };", "let f = async function() {\n  return (await a + b);\n};"},
+		{"async x => await x", "async (x) => {\n  return await x;\n}"},
+		{"async (a, ...b) => {}", "async (a, ...b) => {\n}"},
+		{"async function* g() { yield await x; }", "async function* g() {\n  yield await x\n}"},
+		{"async(a, b)", "async(a, b)"},
+		{"let async = 1;", "let async = 1;"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestForOfParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"for (const x of xs) f(x);", "for (const x of xs) {\n  f(x)\n}"},
+		{"for (let x of [1, 2]) {}", "for (let x of [1, 2]) {\n}"},
+		{"for (x of xs) {}", "for (x of xs) {\n}"},
+		{"for (o.p of xs) {}", "for (o.p of xs) {\n}"},
+		{"for (let i = 0; i < n; i++) {}", "for (let i = 0; (i < n); (i++)) {\n}"},
+		{"async function f() { for await (const x of xs) {} }", "async function f() {\n  for await (const x of xs) {\n}\n}"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestAsyncMethodParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"({ async m() {} })", "{m: async function m() {\n}}"},
+		{"({ async *m() {} })", "{m: async function* m() {\n}}"},
+		{"({ async() {} })", "{async: function async() {\n}}"},
+		{"({ async: 1, async })", "{async: 1, async: async}"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestModuleParsing(t *testing.T) {
+	p := parser.New(lexer.New("let x = await f(); for await (const y of ys) {}"))
+	program := p.ParseModule()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("parser had %d errors: %v", len(errs), errs)
+	}
+	if !program.Module {
+		t.Errorf("expected the program to be a module")
+	}
+	if got := program.Statements[0].String(); got != "let x = await f();" {
+		t.Errorf("expected=%q, got=%q", "let x = await f();", got)
+	}
+}
+
+func TestImportExportParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "./setup.js"`, `import "./setup.js";`},
+		{`import d from "./m.js"`, `import d from "./m.js";`},
+		{`import * as ns from "./m.js";`, `import * as ns from "./m.js";`},
+		{`import { a, b as c, default as d, "x-y" as e } from "./m.js"`, `import {a, b as c, default as d, "x-y" as e} from "./m.js";`},
+		{`import d, { a } from "./m.js"`, `import d, {a} from "./m.js";`},
+		{`import d, * as ns from "./m.js"`, `import d, * as ns from "./m.js";`},
+		{`import {} from "./m.js"`, `import {} from "./m.js";`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`export const { a, b: [c] } = o;`, `export const {a: a, b: [c]} = o;`},
+		{`export function f() {}`, "export function f() {\n}"},
+		{`export async function* g() {}`, "export async function* g() {\n}"},
+		{`export { x, y as z, w as default };`, `export {x, y as z, w as default};`},
+		{`export { a, b as "b-c" } from "./m.js"`, `export {a, b as "b-c"} from "./m.js";`},
+		{`export * from "./m.js"`, `export * from "./m.js";`},
+		{`export * as ns from "./m.js"`, `export * as ns from "./m.js";`},
+		{`export default 1 + 2;`, `export default (1 + 2);`},
+		{`export default function () {}`, "export default function() {\n}"},
+		{`export default async function named() {}`, "export default async function named() {\n}"},
+		{`export default async x => x`, "export default async (x) => {\n  return x;\n};"},
+		{`const m = import("./m.js")`, `const m = import("./m.js");`},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseModule()
+		if errs := p.Errors(); len(errs) > 0 {
+			t.Fatalf("input %q: parser had %d errors: %v", tt.input, len(errs), errs)
+		}
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestImportExportErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		module   bool
+		expected string
+	}{
+		{`import x from "./m.js"`, false, "Cannot use import statement outside a module"},
+		{`export let x = 1`, false, "Cannot use export statement outside a module"},
+		{`if (x) { import "./m.js"; }`, true, "import declarations may only appear at the top level of a module"},
+		{`function f() { export { f }; }`, true, "export declarations may only appear at the top level of a module"},
+		{`import { "x-y" } from "./m.js"`, true, "expected as after x-y in import declaration"},
+		{`import x "./m.js"`, true, "expected from, got STRING instead"},
+		{`export { "x-y" }`, true, `string literal "x-y" can only be exported from another module`},
+		{`export 1`, true, "unexpected INT after export"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		if tt.module {
+			p.ParseModule()
+		} else {
+			p.ParseProgram()
+		}
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("input %q: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestDestructuringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, , b = 1, ...c] = xs;", "let [a, , b = 1, ...c] = xs;"},
+		{"const { a, b: [c], d = 2, [k]: e, ...f } = o;", "const {a: a, b: [c], d: d = 2, [k]: e, ...f} = o;"},
+		{"[a, b] = [b, a];", "[a, b] = [b, a]"},
+		{"({ a, b: o.c = 1 } = o);", "{a: a, b: o.c = 1} = o"},
+		{"function f({ a }, [b] = [], ...c) {}", "function f({a: a}, [b] = [], ...c) {\n}"},
+		{"(({ a }, ...b) => a);", "({a: a}, ...b) => {\n  return a;\n}"},
+		{"for (const [k, v] of m) {}", "for (const [k, v] of m) {\n}"},
+		{"for ([k, v] of m) {}", "for ([k, v] of m) {\n}"},
+		{"({ ...a, b });", "{...a, b: b}"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRegExpLiteralParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"/ab+c/gi;", "/ab+c/gi"},
+		{"x = /[/]/;", "x = /[/]/"},
+		{"a / b / c;", "((a / b) / c)"},
+		{"f(/=/, 1);", "f(/=/, 1)"},
+		{"[/a/, /b/];", "[/a/, /b/]"},
+		{"x = a ? /b/ : /c\\//;", "x = (a ? /b/ : /c\\//)"},
+		{"/x/.test(s) / 2;", "(/x/.test(s) / 2)"},
+	}
+
+	for _, tt := range tests {
+		program := parseProgram(t, tt.input)
+		if got := program.Statements[0].String(); got != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestRegExpLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"/(/;", "Invalid regular expression: /(/: Unterminated group"},
+		{"/a/gg;", "Invalid regular expression flags"},
+		{"/a/x;", "Invalid regular expression flags"},
+		{"/abc\n;", "Invalid regular expression: missing /"},
+		{"/[/;", "Invalid regular expression: missing /"},
+		{"/\\q/u;", "Invalid regular expression: /\\q/u: Invalid escape"},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := p.Errors()
+		if len(errs) == 0 || errs[0] != tt.expected {
+			t.Errorf("input %q: expected error %q, got %v", tt.input, tt.expected, errs)
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []string{
+		"let = 5;",
+		"if (x { }",
+		"[1, 2",
+		"5 = x",
+		"yield 1",
+		"function f() { yield 1; }",
+		"function* g() { let f = () => yield 1; }",
+		"for (let x = 1 of xs) {}",
+		"for (1 of xs) {}",
+		"let [a];",
+		"const { a };",
+		"let [...a, b] = xs;",
+		"let { ...a, b } = o;",
+		"let { ...[a] } = o;",
+		"[a, b] += xs;",
+		"[1] = xs;",
+		"((a.b) => a);",
+		"1.5n;",
+		"1e3n;",
+		"017n;",
+		"await x;",
+		"function f() { await x; }",
+		"async function f() { let g = () => await x; }",
+		"async function f() { function g() { await x; } }",
+		"function f() { for await (const x of xs) {} }",
+		"async function f() { for await (let i = 0; i < 1; i++) {} }",
+		"({ async m: 1 })",
+		"async x;",
+		// Invalid targets whose own parsing failed.
+		"a ??= 1",
+		"() = 1",
+		"function = 1",
+		"yield = 1",
+		"/+/ = 1",
+		"yield = = new",
+		"void void &&= 1",
+		"({ [ { : ] })",
+		"for (typeof < of xs) {}",
+		"[-- 1] = xs",
+		"-a ** b",
+		"typeof a ** b",
+		"a & = b",
+	}
+
+	for _, input := range tests {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("input %q: expected parser errors, got none", input)
+		}
+	}
+}
+
+func TestNestingLimit(t *testing.T) {
+	expected := "nesting too deep: expressions and statements may only nest 10000 levels"
+	for _, input := range []string{
+		strings.Repeat("[", 2000000),
+		strings.Repeat("{", 20000) + strings.Repeat("}", 20000),
+		strings.Repeat("x = ", 20000) + "1",
+		strings.Repeat("(() => ", 20000) + "1" + strings.Repeat(")", 20000),
+	} {
+		p := parser.New(lexer.New(input))
+		p.ParseProgram()
+		if errs := p.Errors(); len(errs) != 1 || errs[0] != expected {
+			t.Errorf("input %.20q...: expected error %q, got %.200v", input, expected, errs)
+		}
+	}
+
+	parseProgram(t, strings.Repeat("[", 5000)+strings.Repeat("]", 5000))
+}
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) > 0 {
+		t.Fatalf("input %q: parser had %d errors: %v", input, len(errs), errs)
+	}
+	if len(program.Statements) == 0 {
+		t.Fatalf("input %q: no statements parsed", input)
+	}
+	return program
+}
@@ -0,0 +1,242 @@
+package regex_test
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/biosbuddha/golemjs/internal/regex"
+)
+
+func compile(t *testing.T, pattern, flags string) *regex.Regexp {
+	t.Helper()
+	f, ok := regex.ParseFlags(flags)
+	if !ok {
+		t.Fatalf("invalid flags %q", flags)
+	}
+	re, err := regex.Compile(utf16.Encode([]rune(pattern)), f)
+	if err != nil {
+		t.Fatalf("Compile(/%s/%s) failed: %s", pattern, flags, err)
+	}
+	return re
+}
+
+// groups returns the text of the match and each group, with "<nil>" for
+// groups that didn't take part.
+func groups(input []uint16, caps []int) []string {
+	var out []string
+	for idx := 0; idx < len(caps); idx += 2 {
+		if caps[idx] < 0 {
+			out = append(out, "<nil>")
+			continue
+		}
+		out = append(out, string(utf16.Decode(input[caps[idx]:caps[idx+1]])))
+	}
+	return out
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		flags    string
+		input    string
+		expected []string // nil for no match
+	}{
+		{"abc", "", "xxabcxx", []string{"abc"}},
+		{"a|ab", "", "abc", []string{"a"}},
+		{"a*", "", "aaab", []string{"aaa"}},
+		{"a*?b", "", "aaab", []string{"aaab"}},
+		{"a{2,3}", "", "aaaa", []string{"aaa"}},
+		{"a{2,3}?", "", "aaaa", []string{"aa"}},
+		{"a{2}", "", "a", nil},
+		{"(a)|(b)", "", "b", []string{"b", "<nil>", "b"}},
+		{"(z)((a+)?(b+)?(c))*", "", "zaacbbbcac", []string{"zaacbbbcac", "z", "ac", "a", "<nil>", "c"}},
+		{"(a*)*", "", "b", []string{"", "<nil>"}},
+		{"(a*)+", "", "b", []string{"", ""}},
+		{"(.*?)a(?!(a+)b\\2c)\\2(.*)", "", "baaabaac", []string{"baaabaac", "ba", "<nil>", "abaac"}},
+		{"(a+)\\1", "", "aaaa", []string{"aaaa", "aa"}},
+		{"(?<year>\\d{4})-(?<month>\\d\\d)", "", "on 2024-05", []string{"2024-05", "2024", "05"}},
+		{"(?<c>.)\\k<c>", "", "abccd", []string{"cc", "c"}},
+		{"(?=(a+))a*b\\1", "", "baaabac", []string{"aba", "a"}},
+		{"(?<=\\$)\\d+", "", "cost $42", []string{"42"}},
+		{"(?<!\\$)\\b\\d+", "", "$4 and 5", []string{"5"}},
+		{"(?<=(\\d+)(\\d+))$", "", "1053", []string{"", "1", "053"}},
+		{"(?<=\\1(a))b", "", "aab", []string{"b", "a"}},
+		{"^b", "m", "a\nb", []string{"b"}},
+		{"^b", "", "a\nb", nil},
+		{"a$", "m", "a\nb", []string{"a"}},
+		{"a.b", "", "a\nb", nil},
+		{"a.b", "s", "a\nb", []string{"a\nb"}},
+		{"\\bfoo\\b", "", "a foo.", []string{"foo"}},
+		{"\\Boo", "", "foo", []string{"oo"}},
+		{"[a-c]+", "", "xxbcaz", []string{"bca"}},
+		{"[^a-c]+", "", "abxyc", []string{"xy"}},
+		{"[\\d_]+", "", "a1_2b", []string{"1_2"}},
+		{"[\\D]+", "", "12ab3", []string{"ab"}},
+		{"\\s+", "", "a \t b", []string{" \t "}},
+		{"\\w+", "", "--ab_1--", []string{"ab_1"}},
+		{"ABC", "i", "xabcx", []string{"abc"}},
+		{"[a-z]+", "i", "HeLLo", []string{"HeLLo"}},
+		{"\\u017f", "i", "s", nil},
+		{"\\u017f", "iu", "s", []string{"s"}},
+		{"(a)\\1", "i", "aA", []string{"aA", "a"}},
+		{"\\x41\\u0042\\103", "", "ABC", []string{"ABC"}},
+		{"\\cJ", "", "\n", []string{"\n"}},
+		{"a{", "", "a{", []string{"a{"}},
+		{"]", "", "]", []string{"]"}},
+		{"\\8", "", "8", []string{"8"}},
+		// Without the u flag, . matches half a surrogate pair, which
+		// decodes to U+FFFD.
+		{".", "", "😀", []string{"\uFFFD"}},
+		{".", "u", "😀", []string{"😀"}},
+		{"^.$", "u", "😀", []string{"😀"}},
+		{"\\u{1F600}", "u", "😀", []string{"😀"}},
+		{"\\p{Lu}+", "u", "abCDe", []string{"CD"}},
+		{"\\p{Script=Greek}+", "u", "abγδe", []string{"γδ"}},
+		{"\\P{L}+", "u", "ab12c", []string{"12"}},
+		{"\\p{L}", "", "p{L}", []string{"p{L}"}},
+	}
+
+	for _, tt := range tests {
+		re := compile(t, tt.pattern, tt.flags)
+		input := utf16.Encode([]rune(tt.input))
+		caps, err := re.Find(input, 0, nil)
+		if err != nil {
+			t.Errorf("/%s/%s on %q: %v", tt.pattern, tt.flags, tt.input, err)
+			continue
+		}
+		if tt.expected == nil {
+			if caps != nil {
+				t.Errorf("/%s/%s on %q: expected no match, got %q", tt.pattern, tt.flags, tt.input, groups(input, caps))
+			}
+			continue
+		}
+		if caps == nil {
+			t.Errorf("/%s/%s on %q: expected %q, got no match", tt.pattern, tt.flags, tt.input, tt.expected)
+			continue
+		}
+		got := groups(input, caps)
+		if !slices.Equal(got, tt.expected) {
+			t.Errorf("/%s/%s on %q: expected %q, got %q", tt.pattern, tt.flags, tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestMatchAtIsAnchored(t *testing.T) {
+	re := compile(t, "b", "y")
+	input := utf16.Encode([]rune("abb"))
+	if caps, _ := re.MatchAt(input, 0, nil); caps != nil {
+		t.Errorf("expected no match at 0, got %v", caps)
+	}
+	if caps, _ := re.MatchAt(input, 1, nil); !slices.Equal(caps, []int{1, 2}) {
+		t.Errorf("expected [1 2] at 1, got %v", caps)
+	}
+}
+
+func TestStep(t *testing.T) {
+	re := compile(t, "(a+)+b", "")
+	input := utf16.Encode([]rune(strings.Repeat("a", 30) + "c"))
+	steps := 0
+	caps, err := re.Find(input, 0, func() bool {
+		steps++
+		return steps < 10000
+	})
+	if caps != nil || !errors.Is(err, regex.ErrStopped) {
+		t.Errorf("expected ErrStopped, got %v, %v", caps, err)
+	}
+
+	// Nothing that fails a negative lookahead is taken as success.
+	re = compile(t, "(?!(a+)+b)", "")
+	steps = 0
+	if caps, err := re.MatchAt(input, 0, func() bool { steps++; return steps < 10000 }); !errors.Is(err, regex.ErrStopped) {
+		t.Errorf("expected ErrStopped, got %v, %v", caps, err)
+	}
+
+	re = compile(t, "a*$", "")
+	input = utf16.Encode([]rune(strings.Repeat("a", 1e6)))
+	if caps, err := re.Find(input, 0, nil); caps != nil || !errors.Is(err, regex.ErrTooDeep) {
+		t.Errorf("expected ErrTooDeep, got %v, %v", caps, err)
+	}
+	input = input[:1e4]
+	if caps, err := re.Find(input, 0, nil); !slices.Equal(caps, []int{0, 1e4}) || err != nil {
+		t.Errorf("expected [0 10000], got %v, %v", caps, err)
+	}
+}
+
+func TestGroupNames(t *testing.T) {
+	re := compile(t, "(?<a>x)(y)(?<b>z)", "")
+	if re.Groups() != 3 {
+		t.Fatalf("expected 3 groups, got %d", re.Groups())
+	}
+	if names := re.GroupNames(); !slices.Equal(names, []string{"a", "", "b"}) {
+		t.Errorf("wrong group names: %q", names)
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	tests := []struct {
+		input    string
+		valid    bool
+		expected string
+	}{
+		{"", true, ""},
+		{"gimsuyd", true, "dgimsuy"},
+		{"yg", true, "gy"},
+		{"gg", false, ""},
+		{"x", false, ""},
+	}
+
+	for _, tt := range tests {
+		flags, ok := regex.ParseFlags(tt.input)
+		if ok != tt.valid {
+			t.Errorf("ParseFlags(%q): expected valid=%t, got %t", tt.input, tt.valid, ok)
+			continue
+		}
+		if ok && flags.String() != tt.expected {
+			t.Errorf("ParseFlags(%q).String(): expected %q, got %q", tt.input, tt.expected, flags.String())
+		}
+	}
+}
+
+func TestSyntaxErrors(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		flags    string
+		expected string
+	}{
+		{"(", "", "Unterminated group"},
+		{"a)", "", "Unmatched ')'"},
+		{"[a", "", "Unterminated character class"},
+		{"*", "", "Nothing to repeat"},
+		{"a**", "", "Nothing to repeat"},
+		{"^*", "", "Nothing to repeat"},
+		{"{1}", "", "Nothing to repeat"},
+		{"a{2,1}", "", "numbers out of order in {} quantifier"},
+		{"[z-a]", "", "Range out of order in character class"},
+		{"\\", "", "\\ at end of pattern"},
+		{"(?<a>x)(?<a>y)", "", "Duplicate capture group name"},
+		{"(?<1a>x)", "", "Invalid capture group name"},
+		{"(?<a>x)\\k<b>", "", "Invalid named capture referenced"},
+		{"(?x)", "", "Invalid group"},
+		{"\\q", "u", "Invalid escape"},
+		{"a{", "u", "Incomplete quantifier"},
+		{"]", "u", "Lone quantifier brackets"},
+		{"\\p{Nope}", "u", "Invalid property name"},
+		{"[\\d-z]", "u", "Invalid character class"},
+		{"(?=a)*", "u", "Nothing to repeat"},
+	}
+
+	for _, tt := range tests {
+		flags, _ := regex.ParseFlags(tt.flags)
+		_, err := regex.Compile(utf16.Encode([]rune(tt.pattern)), flags)
+		if err == nil {
+			t.Errorf("/%s/%s: expected error %q, got none", tt.pattern, tt.flags, tt.expected)
+			continue
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("/%s/%s: expected error %q, got %q", tt.pattern, tt.flags, tt.expected, err.Error())
+		}
+	}
+}
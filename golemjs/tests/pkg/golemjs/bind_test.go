@@ -0,0 +1,283 @@
+package golemjs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/biosbuddha/golemjs/pkg/golemjs"
+)
+
+type address struct {
+	City string `js:"city"`
+}
+
+type audit struct {
+	Revision int `js:"revision"`
+}
+
+type account struct {
+	audit
+	Owner   string  `js:"owner"`
+	Balance float64 `js:"balance"`
+	Home    address `js:"home"`
+	Tags    []string
+	Secret  string `js:"-"`
+	pin     int
+	Parent  *account `js:"parent"`
+}
+
+func (a *account) Deposit(amount float64) (float64, error) {
+	if amount <= 0 {
+		return a.Balance, golemjs.NewRangeError("deposit of %v", amount)
+	}
+	a.Balance += amount
+	a.Revision++
+	return a.Balance, nil
+}
+
+func (a *account) Describe(prefix golemjs.Optional[string]) string {
+	if !prefix.Set {
+		prefix.Value = "account"
+	}
+	return fmt.Sprintf("%s of %s", prefix.Value, a.Owner)
+}
+
+func (a *account) Tag(tags ...string) int {
+	a.Tags = append(a.Tags, tags...)
+	return len(a.Tags)
+}
+
+func (a *account) Close() error {
+	return fmt.Errorf("closing %s: %w", a.Owner, fs.ErrPermission)
+}
+
+func (a *account) String() string { return "account " + a.Owner }
+
+func TestStructBinding(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout)
+	acct := &account{Owner: "ann", Balance: 10, Secret: "s", pin: 1234}
+	rt.Set("acct", acct)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"acct.owner", "ann"},
+		{"acct.revision", "0"},
+		{"acct.Secret", "undefined"},
+		{"acct.pin", "undefined"},
+		{"acct.parent", "null"},
+		{"JSON.stringify(acct)", `{"revision":0,"owner":"ann","balance":10,"home":{"city":""},"Tags":null,"parent":null}`},
+		{"acct.Deposit(5)", "15"},
+		{"acct.balance += 5; acct.balance", "20"},
+		{`acct.home.city = "Oslo"; acct.home.city`, "Oslo"},
+		{`acct.Tags = ["a"]; acct.Tag("b", "c")`, "3"},
+		{"acct.Describe()", "account of ann"},
+		{`acct.Describe("savings")`, "savings of ann"},
+		{"acct.Describe(undefined)", "account of ann"},
+		{"acct === acct", "true"},
+		{`acct.note = "x"; acct.note`, "x"},
+		{"typeof acct.Deposit", "function"},
+	}
+	for _, tt := range tests {
+		v := run(t, rt, tt.input)
+		if v.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, v.String())
+		}
+	}
+
+	stdout.Reset()
+	run(t, rt, "console.log(acct)")
+	if expected := "{\n  revision: 1,\n  owner: 'ann',\n  balance: 20,\n  home: { city: 'Oslo' },\n  Tags: [ 'a', 'b', 'c' ],\n  parent: null,\n  note: 'x'\n}\n"; stdout.String() != expected {
+		t.Errorf("expected console output %q, got %q", expected, stdout.String())
+	}
+
+	// What the script changed, Go sees, and the other way around.
+	if acct.Balance != 20 || acct.Revision != 1 || acct.Home.City != "Oslo" || strings.Join(acct.Tags, ",") != "a,b,c" {
+		t.Errorf("unexpected account after the script: %+v", acct)
+	}
+	acct.Owner = "bob"
+	if v := run(t, rt, "acct.owner"); v.String() != "bob" {
+		t.Errorf("expected bob, got %s", v)
+	}
+
+	errorTests := []struct {
+		input string
+		err   string
+	}{
+		{"acct.Deposit(-1)", "RangeError: deposit of -1"},
+		{`acct.balance = "lots"`, "TypeError: Cannot set property balance: cannot convert string to float64"},
+		{`acct.Deposit("5")`, "TypeError: argument 1: cannot convert string to float64"},
+		{"acct.Deposit.call ? 0 : ({f: acct.Deposit}).f(1)", "TypeError: Deposit called on an object that isn't a golemjs_test.account"},
+	}
+	for _, tt := range errorTests {
+		_, err := rt.RunString(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("input %q: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+
+	// A Go error reaches the Go code that ran the script.
+	_, err := rt.RunString("acct.Close()")
+	if !errors.Is(err, fs.ErrPermission) || err.Error() != "Error: closing bob: permission denied" {
+		t.Errorf("expected the error from Close, got %v", err)
+	}
+
+	// A struct value is copied, and exports as the pointer it's bound to.
+	rt.Set("copy", *acct)
+	run(t, rt, `copy.owner = "carol"`)
+	if acct.Owner != "bob" {
+		t.Errorf("expected the copy to be changed, not the account")
+	}
+	var exported *account
+	if err := rt.ExportTo(rt.Get("acct"), &exported); err != nil || exported != acct {
+		t.Errorf("expected the same pointer, got %p, %v", exported, err)
+	}
+	var copied account
+	if err := rt.ExportTo(rt.Get("copy"), &copied); err != nil || copied.Owner != "carol" {
+		t.Errorf("expected the copy, got %+v, %v", copied, err)
+	}
+	var stringer fmt.Stringer
+	if err := rt.ExportTo(rt.Get("acct"), &stringer); err != nil || stringer.String() != "account bob" {
+		t.Errorf("expected the account as a fmt.Stringer, got %v, %v", stringer, err)
+	}
+	if rt.Get("acct").Export() != any(acct) {
+		t.Errorf("expected Export to return the account, got %v", rt.Get("acct").Export())
+	}
+	var fromObject account
+	if err := rt.ExportTo(run(t, rt, `({owner: "dan", home: {city: "Rome"}, revision: 4})`), &fromObject); err != nil ||
+		fromObject.Owner != "dan" || fromObject.Home.City != "Rome" || fromObject.Revision != 4 {
+		t.Errorf("expected an account from the object, got %+v, %v", fromObject, err)
+	}
+
+	// A struct that refers to itself.
+	acct.Parent = acct
+	if v := run(t, rt, "acct.parent.parent === acct"); v.String() != "true" {
+		t.Errorf("expected the parent to be the account itself")
+	}
+	if _, err := rt.RunString("JSON.stringify(acct)"); err == nil || !strings.HasPrefix(err.Error(), "TypeError: Converting circular structure") {
+		t.Errorf("expected a circular structure error, got %v", err)
+	}
+
+	// The objects for structs the script has dropped are forgotten, but
+	// not those it still holds.
+	run(t, rt, "var home = acct.home")
+	for n := range 1000 {
+		rt.Set("other", &account{Owner: fmt.Sprint(n)})
+		run(t, rt, "other.home.city = other.owner")
+		if n%100 == 0 {
+			runtime.GC()
+		}
+	}
+	if v := run(t, rt, "acct.home === home && other.home === other.home"); v.String() != "true" {
+		t.Errorf("expected the objects still held to be the same")
+	}
+}
+
+func TestFunctionBinding(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout)
+	rt.Set("sum", func(first int, rest ...int) int {
+		for _, n := range rest {
+			first += n
+		}
+		return first
+	})
+	rt.Set("greet", func(name string, greeting golemjs.Optional[string]) string {
+		if !greeting.Set {
+			greeting.Value = "Hello"
+		}
+		return greeting.Value + ", " + name
+	})
+	rt.Set("maybe", func(set bool) golemjs.Optional[int] {
+		return golemjs.Optional[int]{Value: 5, Set: set}
+	})
+	rt.Set("check", func(n int) error {
+		if n < 0 {
+			return golemjs.NewTypeError("%d is negative", n)
+		}
+		return nil
+	})
+	rt.Set("explode", func() int { panic("boom") })
+	rt.Set("deadline", func(ctx context.Context) bool {
+		_, ok := ctx.Deadline()
+		return ok
+	})
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"sum(1)", "1"},
+		{"sum(1, 2, 3, 4)", "10"},
+		{`greet("ann")`, "Hello, ann"},
+		{`greet("ann", "Hi")`, "Hi, ann"},
+		{"maybe(true)", "5"},
+		{"maybe(false)", "undefined"},
+		{"deadline()", "false"},
+	}
+	for _, tt := range tests {
+		v := run(t, rt, tt.input)
+		if v.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, v.String())
+		}
+	}
+
+	errorTests := []struct {
+		input string
+		err   string
+	}{
+		{`sum(1, "2")`, "TypeError: argument 2: cannot convert string to int"},
+		{"check(-1)", "TypeError: -1 is negative"},
+		{"explode()", "Error: boom"},
+		{`greet("ann", 1)`, "TypeError: argument 2: cannot convert number to string"},
+	}
+	for _, tt := range errorTests {
+		_, err := rt.RunString(tt.input)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("input %q: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+	stdout.Reset()
+	run(t, rt, "console.log(sum, [greet])")
+	if expected := "[Function: sum] [ [Function: greet] ]\n"; stdout.String() != expected {
+		t.Errorf("expected console output %q, got %q", expected, stdout.String())
+	}
+	var typeErr *golemjs.Error
+	if _, err := rt.RunString("check(-2)"); !errors.As(err, &typeErr) || typeErr.Name != "TypeError" {
+		t.Errorf("expected the Error from check, got %v", err)
+	}
+
+	// The context of the script is passed to the functions it calls.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Hour)
+	defer cancel()
+	if v, err := rt.RunStringContext(ctx, "deadline()"); err != nil || v.String() != "true" {
+		t.Errorf("expected the script's context, got %v, %v", v, err)
+	}
+
+	// Optional fields, and exporting to an Optional.
+	var opt golemjs.Optional[int]
+	if err := rt.ExportTo(run(t, rt, "7"), &opt); err != nil || !opt.Set || opt.Value != 7 {
+		t.Errorf("expected a set Optional, got %+v, %v", opt, err)
+	}
+	if err := rt.ExportTo(run(t, rt, "undefined"), &opt); err != nil || opt.Set {
+		t.Errorf("expected an unset Optional, got %+v, %v", opt, err)
+	}
+
+	// A method that can't be bound makes the struct fail to convert.
+	if _, err := rt.ToValue(&unbindable{}); err == nil {
+		t.Errorf("expected an error for a method returning two values")
+	}
+}
+
+type unbindable struct{}
+
+func (unbindable) Pair() (int, int) { return 0, 0 }
@@ -0,0 +1,406 @@
+package golemjs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/biosbuddha/golemjs/pkg/golemjs"
+)
+
+// newRuntime creates a runtime whose console output goes to stdout.
+func newRuntime(stdout *bytes.Buffer, opts ...golemjs.Option) *golemjs.Runtime {
+	return golemjs.New(append([]golemjs.Option{golemjs.WithOutput(stdout, stdout)}, opts...)...)
+}
+
+// run runs src, failing the test if it throws.
+func run(t *testing.T, rt *golemjs.Runtime, src string) golemjs.Value {
+	t.Helper()
+	v, err := rt.RunString(src)
+	if err != nil {
+		t.Fatalf("input %q: unexpected error: %v", src, err)
+	}
+	return v
+}
+
+func TestRunString(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		err      string
+	}{
+		{"1 + 2", "3", ""},
+		{`"a" + "b"`, "ab", ""},
+		{"[1, 2].map(x => x * 2)", "[2, 4]", ""},
+		{"let p = Promise.resolve(5); let n = 0; p.then(x => { n = x; }); n", "0", ""},
+		{"undefinedVariable", "", "ReferenceError: undefinedVariable is not defined"},
+		{"null.x", "", "TypeError: Cannot read properties of null (reading 'x')"},
+		{"let = ;", "", "SyntaxError: "},
+		{"a ??= 1", "", "SyntaxError: "},
+		{"yield = = new", "", "SyntaxError: "},
+	}
+
+	for _, tt := range tests {
+		var stdout bytes.Buffer
+		v, err := newRuntime(&stdout).RunString(tt.input)
+		if tt.err != "" {
+			var exc *golemjs.Exception
+			if !errors.As(err, &exc) || !strings.HasPrefix(exc.Error(), tt.err) {
+				t.Errorf("input %q: expected exception %q, got %v", tt.input, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("input %q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		if v.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, v.String())
+		}
+	}
+
+	// An error the interpreter raised is thrown as an error object.
+	var exc *golemjs.Exception
+	_, err := newRuntime(&bytes.Buffer{}).RunString("null.x")
+	if !errors.As(err, &exc) || exc.Value().String() != "TypeError: Cannot read properties of null (reading 'x')" {
+		t.Errorf("expected a TypeError, got %v", err)
+	} else if _, ok := exc.Value().Export().(golemjs.Value); !ok {
+		t.Errorf("expected the error to export as itself, got %#v", exc.Value().Export())
+	}
+}
+
+func TestRunFileAndModules(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "script.js")
+	if err := os.WriteFile(name, []byte(`console.log("hi"); 6 * 7`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout, golemjs.WithModules(fstest.MapFS{
+		"math.js": {Data: []byte(`export function square(x) { return x * x; }`)},
+		"bad.js":  {Data: []byte(`export let x = 1; () = x;`)},
+	}))
+	v, err := rt.RunFile(name)
+	if err != nil || v.String() != "42" || stdout.String() != "hi\n" {
+		t.Errorf("RunFile: got %v, %v, output %q", v, err, stdout.String())
+	}
+	if _, err := rt.RunFile(filepath.Join(dir, "missing.js")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("RunFile of a missing file: expected ErrNotExist, got %v", err)
+	}
+
+	ns, err := rt.RunModule("./math.js")
+	if err != nil {
+		t.Fatalf("RunModule: %v", err)
+	}
+	rt.Set("math", ns)
+	if v := run(t, rt, "math.square(9)"); v.String() != "81" {
+		t.Errorf("expected 81, got %s", v)
+	}
+	if _, err := rt.RunModule("./bad.js"); err == nil || !strings.HasPrefix(err.Error(), "SyntaxError: ") {
+		t.Errorf("RunModule of a module with a syntax error: got %v", err)
+	}
+}
+
+type point struct {
+	X, Y   float64
+	Label  string
+	hidden int
+}
+
+func TestToValue(t *testing.T) {
+	n := 7
+	tests := []struct {
+		value    any
+		expected string
+	}{
+		{nil, "null"},
+		{true, "true"},
+		{42, "42"},
+		{uint8(255), "255"},
+		{2.5, "2.5"},
+		{"text", `"text"`},
+		{big.NewInt(10), "10n"},
+		{[]int{1, 2, 3}, "[1,2,3]"},
+		{[2]string{"a", "b"}, `["a","b"]`},
+		{[]int(nil), "null"},
+		{map[string]any{"b": 2, "a": []any{1, "x"}}, `{"a":[1,"x"],"b":2}`},
+		{point{X: 1, Y: 2, Label: "p", hidden: 3}, `{"X":1,"Y":2,"Label":"p"}`},
+		{&point{X: 1}, `{"X":1,"Y":0,"Label":""}`},
+		{&n, "7"},
+		{(*point)(nil), "null"},
+	}
+
+	for _, tt := range tests {
+		var stdout bytes.Buffer
+		rt := newRuntime(&stdout)
+		if err := rt.Set("value", tt.value); err != nil {
+			t.Errorf("value %#v: unexpected error: %v", tt.value, err)
+			continue
+		}
+		v := run(t, rt, `typeof value == "bigint" ? value + "n" : JSON.stringify(value)`)
+		if v.String() != tt.expected {
+			t.Errorf("value %#v: expected=%s, got=%s", tt.value, tt.expected, v)
+		}
+	}
+
+	loop := map[string]any{}
+	loop["self"] = loop
+	for _, value := range []any{make(chan int), map[int]string{1: "a"}, loop, func() (int, int) { return 0, 0 }} {
+		if _, err := golemjs.New().ToValue(value); err == nil {
+			t.Errorf("value %T: expected an error", value)
+		}
+	}
+}
+
+func TestExportTo(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout)
+
+	tests := []struct {
+		input    string
+		target   any // a pointer to the zero value of the type to convert to
+		expected any
+	}{
+		{"true", new(bool), true},
+		{"42", new(int), 42},
+		{"-3", new(int8), int8(-3)},
+		{"1.5", new(float32), float32(1.5)},
+		{`"s"`, new(string), "s"},
+		{"12345678901234567890n", new(*big.Int), func() *big.Int { n, _ := new(big.Int).SetString("12345678901234567890", 10); return n }()},
+		{"[1, 2, 3]", new([]int), []int{1, 2, 3}},
+		{"[1, 2]", new([2]float64), [2]float64{1, 2}},
+		{"({a: 1, b: 2})", new(map[string]int), map[string]int{"a": 1, "b": 2}},
+		{`({X: 3, Label: "q", Other: true})`, new(point), point{X: 3, Label: "q"}},
+		{"({X: 3})", new(*point), &point{X: 3}},
+		{"null", new(*point), (*point)(nil)},
+		{"undefined", new([]int), []int(nil)},
+		{`[1, "a", [true], {b: null}]`, new(any), []any{1.0, "a", []any{true}, map[string]any{"b": nil}}},
+	}
+
+	for _, tt := range tests {
+		v := run(t, rt, tt.input)
+		if err := rt.ExportTo(v, tt.target); err != nil {
+			t.Errorf("input %q: unexpected error: %v", tt.input, err)
+			continue
+		}
+		got := reflect.ValueOf(tt.target).Elem().Interface()
+		if !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("input %q: expected=%#v, got=%#v", tt.input, tt.expected, got)
+		}
+	}
+
+	errorTests := []struct {
+		input  string
+		target any
+		err    string
+	}{
+		{"1.5", new(int), "golemjs: cannot convert 1.5 to int"},
+		{"300", new(uint8), "golemjs: cannot convert 300 to uint8"},
+		{"-1", new(uint), "golemjs: cannot convert -1 to uint"},
+		{`"1"`, new(int), "golemjs: cannot convert string to int"},
+		{"undefined", new(int), "golemjs: cannot convert undefined to int"},
+		{`[1, "x"]`, new([]int), "golemjs: element 1: cannot convert string to int"},
+		{"[1, 2, 3]", new([2]int), "golemjs: cannot convert array of length 3 to [2]int"},
+		{`({X: "no"})`, new(point), "golemjs: property X: cannot convert string to float64"},
+	}
+	for _, tt := range errorTests {
+		err := rt.ExportTo(run(t, rt, tt.input), tt.target)
+		if err == nil || err.Error() != tt.err {
+			t.Errorf("input %q: expected error %q, got %v", tt.input, tt.err, err)
+		}
+	}
+	if err := rt.ExportTo(golemjs.Value{}, 0); err == nil {
+		t.Errorf("expected an error for a target that isn't a pointer")
+	}
+}
+
+func TestGoFunctions(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout)
+	calls := 0
+	rt.Set("add", func(a, b int) int { return a + b })
+	rt.Set("join", func(parts []string, sep *string) string {
+		if sep == nil {
+			return strings.Join(parts, ",")
+		}
+		return strings.Join(parts, *sep)
+	})
+	rt.Set("touch", func() { calls++ })
+	rt.Set("parse", func(s string) (int, error) {
+		if s == "" {
+			return 0, errors.New("empty input")
+		}
+		return len(s), nil
+	})
+	rt.Set("describe", func(call golemjs.FunctionCall) (golemjs.Value, error) {
+		return rt.ToValue(fmt.Sprintf("%d args, first %s, this %s", len(call.Arguments), call.Argument(0), call.This))
+	})
+	rt.Set("apply", func(f func(int) int, n int) int { return f(n) })
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"add(2, 3)", "5"},
+		{`join(["a", "b"])`, "a,b"},
+		{`join(["a", "b"], "-")`, "a-b"},
+		{"touch(); touch()", "undefined"},
+		{`parse("abc")`, "3"},
+		{`let p = new Promise(r => r(parse(""))); p`, "Promise {<rejected> Error: empty input}"},
+		{`describe(1, "x")`, "2 args, first 1, this undefined"},
+		{`({f: describe}).f()`, "0 args, first undefined, this {f: builtin function}"},
+		{"apply(x => x * 10, 4)", "40"},
+	}
+	for _, tt := range tests {
+		v := run(t, rt, tt.input)
+		if v.String() != tt.expected {
+			t.Errorf("input %q: expected=%q, got=%q", tt.input, tt.expected, v.String())
+		}
+	}
+	if calls != 2 {
+		t.Errorf("expected touch to be called twice, got %d", calls)
+	}
+
+	for input, expected := range map[string]string{
+		`add(1, "2")`: "TypeError: argument 2: cannot convert string to int",
+		"add(1)":      "TypeError: argument 2: cannot convert undefined to int",
+		`parse("")`:   "Error: empty input",
+	} {
+		_, err := rt.RunString(input)
+		if err == nil || err.Error() != expected {
+			t.Errorf("input %q: expected error %q, got %v", input, expected, err)
+		}
+	}
+}
+
+func TestCallingJavaScript(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout)
+	run(t, rt, `
+function add(a, b) { return a + b; }
+function fail() { return null.x; }
+let log = [];
+function later(x) { Promise.resolve().then(() => log.push(x)); return "queued"; }
+let counter = { n: 0, inc(by) { this.n += by; return this.n; } };`)
+
+	v, err := rt.Call(rt.Get("add"), golemjs.Value{}, 1, 2)
+	if err != nil || v.String() != "3" {
+		t.Errorf("add: got %v, %v", v, err)
+	}
+	v, err = rt.Call(rt.Get("later"), golemjs.Value{}, "x")
+	if err != nil || v.String() != "queued" || rt.Get("log").String() != "[x]" {
+		t.Errorf("later: got %v, %v, log %v", v, err, rt.Get("log"))
+	}
+	counter := rt.Get("counter")
+	var inc golemjs.Value
+	if err := rt.ExportTo(run(t, rt, "counter.inc"), &inc); err != nil {
+		t.Fatal(err)
+	}
+	v, err = rt.Call(inc, counter, 5)
+	if err != nil || v.String() != "5" {
+		t.Errorf("inc: got %v, %v", v, err)
+	}
+	if _, err := rt.Call(rt.Get("fail"), golemjs.Value{}); err == nil || !strings.HasPrefix(err.Error(), "TypeError") {
+		t.Errorf("fail: expected a TypeError, got %v", err)
+	}
+	if _, err := rt.Call(rt.Get("nothing"), golemjs.Value{}); err == nil || err.Error() != "TypeError: undefined is not a function" {
+		t.Errorf("undefined: expected a TypeError, got %v", err)
+	}
+
+	// JavaScript functions as Go functions.
+	var addInts func(a, b int) int
+	if err := rt.ExportTo(rt.Get("add"), &addInts); err != nil {
+		t.Fatal(err)
+	}
+	if sum := addInts(20, 22); sum != 42 {
+		t.Errorf("expected 42, got %d", sum)
+	}
+	var failing func() (string, error)
+	rt.ExportTo(rt.Get("fail"), &failing)
+	if _, err := failing(); err == nil {
+		t.Errorf("expected an error")
+	}
+	var concat func(parts ...string) string
+	run(t, rt, "function concat(...parts) { return parts.join(''); }")
+	rt.ExportTo(rt.Get("concat"), &concat)
+	if s := concat("a", "b", "c"); s != "abc" {
+		t.Errorf("expected abc, got %q", s)
+	}
+	exported, ok := rt.Get("add").Export().(func(...any) (any, error))
+	if !ok {
+		t.Fatalf("expected a function, got %T", rt.Get("add").Export())
+	}
+	if sum, err := exported(1.5, 2); err != nil || sum != 3.5 {
+		t.Errorf("expected 3.5, got %v, %v", sum, err)
+	}
+
+	// A Go function calling back into JavaScript, from a script.
+	rt.Set("twice", func(f golemjs.Value, x int) (golemjs.Value, error) {
+		once, err := rt.Call(f, golemjs.Value{}, x)
+		if err != nil {
+			return golemjs.Value{}, err
+		}
+		return rt.Call(f, golemjs.Value{}, once)
+	})
+	if v := run(t, rt, "twice(x => x * 3, 2)"); v.String() != "18" {
+		t.Errorf("expected 18, got %s", v)
+	}
+	// The exception passes through the Go function unchanged.
+	if _, err := rt.RunString("twice(fail, 1)"); err == nil || !strings.HasPrefix(err.Error(), "TypeError: Cannot read properties of null") {
+		t.Errorf("expected the TypeError from fail, got %v", err)
+	}
+}
+
+func TestRuntimeLimits(t *testing.T) {
+	var stdout bytes.Buffer
+	rt := newRuntime(&stdout, golemjs.WithLimits(golemjs.Limits{MaxSteps: 100000, Uncatchable: true}),
+		golemjs.WithModules(fstest.MapFS{
+			"m.js":    {Data: []byte(`export const x = 1`)},
+			"spin.js": {Data: []byte(`while (true) {}`)},
+		}))
+	_, err := rt.RunString("while (true) {}")
+	if !errors.Is(err, golemjs.ErrStepLimit) {
+		t.Errorf("expected the step limit, got %v", err)
+	}
+	if _, err := rt.RunModule("./m.js"); err != nil {
+		t.Errorf("expected a module run after the script to start afresh, got %v", err)
+	}
+	// Each run gets the whole budget, and a Go function calling back into
+	// JavaScript shares the budget of the script that called it.
+	run(t, rt, "function spin() { while (true) {} } 1")
+	rt.Set("callGo", func(f golemjs.Value) error {
+		_, err := rt.Call(f, golemjs.Value{})
+		return err
+	})
+	if _, err := rt.RunString("callGo(spin)"); !errors.Is(err, golemjs.ErrStepLimit) {
+		t.Errorf("expected the step limit, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := rt.CallContext(ctx, rt.Get("spin"), golemjs.Value{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context to stop the call, got %v", err)
+	}
+	if _, err := rt.RunStringContext(ctx, "1"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context to stop the script, got %v", err)
+	}
+
+	// Modules are held to the limits as scripts are.
+	if _, err := rt.RunModule("./spin.js"); !errors.Is(err, golemjs.ErrStepLimit) {
+		t.Errorf("expected the step limit, got %v", err)
+	}
+	if _, err := rt.RunModuleContext(ctx, "./m.js"); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the context to stop the module, got %v", err)
+	}
+	if v := run(t, rt, "1 + 1"); v.String() != "2" {
+		t.Errorf("expected 2, got %s", v)
+	}
+}